@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/edn"
 	"github.com/wbrown/janus-datalog/datalog/executor"
 	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
 	"github.com/wbrown/janus-datalog/datalog/storage"
 )
 
@@ -23,6 +27,12 @@ func main() {
 	var verbose bool
 	var queryStr string
 	var enableDecorrelation bool
+	var exportDir string
+	var importDir string
+	var shards int
+	var replayFile string
+	var replayConfigs string
+	var verify bool
 
 	flag.StringVar(&dbPath, "db", "", "database path")
 	flag.BoolVar(&interactive, "i", false, "interactive mode")
@@ -30,6 +40,12 @@ func main() {
 	flag.BoolVar(&verbose, "verbose", false, "verbose mode (show query annotations)")
 	flag.StringVar(&queryStr, "query", "", "run a single query and exit")
 	flag.BoolVar(&enableDecorrelation, "decorrelate", true, "enable subquery decorrelation optimization (default: true)")
+	flag.StringVar(&exportDir, "export", "", "export the database's datoms to a directory and exit")
+	flag.StringVar(&importDir, "import", "", "import an export directory into the database (created if needed) and exit")
+	flag.IntVar(&shards, "shards", 1, "number of files -export splits datoms into by entity")
+	flag.StringVar(&replayFile, "replay", "", "replay the queries in a capture file, comparing option sets, and exit")
+	flag.StringVar(&replayConfigs, "replay-configs", "", "comma-separated config files to compare with the current options under -replay")
+	flag.BoolVar(&verify, "verify", false, "check that all indexes hold the same datoms, report discrepancies, and exit (status 1 if any)")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] [database_path]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "A Datalog query engine with persistent storage.\n\n")
@@ -44,6 +60,10 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -verbose           # Verbose mode with query annotations\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -verbose -i        # Interactive mode with annotations\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -query '[:find ?x :where [?x :person/name _]]'  # Run single query\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -export dump -shards 8 mydata.db  # Export in 8 shards\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -import dump new.db  # Load an export, shards in parallel\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -verify mydata.db     # Check index consistency after a crash\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -replay queries.jsonl -replay-configs tuned.edn copy.db  # Compare options on captured queries\n", os.Args[0])
 	}
 	flag.Parse()
 
@@ -62,8 +82,8 @@ func main() {
 		dbPath = "datalog.db"
 	}
 
-	// Check if database exists
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+	// Check if database exists (an import creates it)
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) && importDir == "" {
 		log.Fatalf("Database does not exist: %s", dbPath)
 	}
 
@@ -81,7 +101,28 @@ func main() {
 		handler = annotations.Handler(formatter.Handle)
 	}
 
-	if queryStr != "" {
+	if exportDir != "" {
+		start := time.Now()
+		manifest, err := db.Export(exportDir, storage.ExportOptions{Shards: shards})
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		fmt.Printf("Exported %d datoms in %d shards to %s (%v)\n", manifest.Datoms, len(manifest.Shards), exportDir, time.Since(start))
+	} else if importDir != "" {
+		start := time.Now()
+		manifest, err := db.Import(importDir, storage.ImportOptions{})
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		fmt.Printf("Imported %d datoms from %d shards (%v)\n", manifest.Datoms, len(manifest.Shards), time.Since(start))
+	} else if replayFile != "" {
+		runReplay(db, replayFile, replayConfigs)
+	} else if verify {
+		if !runVerify(db) {
+			db.Close()
+			os.Exit(1)
+		}
+	} else if queryStr != "" {
 		// Run single query mode
 		runSingleQuery(db, handler, queryStr, enableDecorrelation)
 	} else if interactive {
@@ -209,13 +250,20 @@ func runInteractive(db *storage.Database, handler annotations.Handler, enableDec
 	fmt.Println("  .help    - Show help")
 	fmt.Println("  .exit    - Exit")
 	fmt.Println("  .add     - Start adding data")
-	fmt.Println("  [:find ...] - Run a query")
+	fmt.Println("  .dump    - Dump index keys, e.g. .dump avet :person/age 20")
+	fmt.Println(`  .set     - Bind a session variable, e.g. .set ?sym "AAPL"`)
+	fmt.Println("  .unset   - Remove a session variable, e.g. .unset ?sym")
+	fmt.Println("  .vars    - List session variables")
+	fmt.Println("  [:find ...] - Run a query; session variables fill its :in scalars")
+	fmt.Println("  {:query [:find ...] :limit 10} - Run a query with options")
+	fmt.Println("  Ctrl-C while a query runs cancels it")
 	fmt.Println()
 
 	scanner := bufio.NewScanner(os.Stdin)
 	opts := storage.DefaultPlannerOptions()
 	opts.EnableSubqueryDecorrelation = enableDecorrelation
 	exec := db.NewExecutorWithOptions(opts)
+	vars := sessionVars{}
 
 	for {
 		fmt.Print("> ")
@@ -235,10 +283,30 @@ func runInteractive(db *storage.Database, handler annotations.Handler, enableDec
 		case line == ".add":
 			addInteractiveData(db, scanner)
 
-		case strings.HasPrefix(line, "[:find"):
-			// Collect multi-line query
+		case line == ".dump", strings.HasPrefix(line, ".dump "):
+			dumpIndex(db, strings.Fields(line)[1:])
+
+		case line == ".set", strings.HasPrefix(line, ".set "):
+			if err := vars.set(strings.TrimPrefix(line, ".set")); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+
+		case line == ".unset", strings.HasPrefix(line, ".unset "):
+			for _, name := range strings.Fields(line)[1:] {
+				delete(vars, query.Symbol(name))
+			}
+
+		case line == ".vars":
+			vars.print()
+
+		case strings.HasPrefix(line, "[:find"), strings.HasPrefix(line, "{"):
+			// Collect multi-line query (vector form or {:query [...] :limit n} map form)
+			closing := "]"
+			if strings.HasPrefix(line, "{") {
+				closing = "}"
+			}
 			query := line
-			for !strings.HasSuffix(line, "]") {
+			for !strings.HasSuffix(strings.TrimSpace(line), closing) {
 				fmt.Print("  ")
 				if !scanner.Scan() {
 					return
@@ -254,13 +322,13 @@ func runInteractive(db *storage.Database, handler annotations.Handler, enableDec
 				continue
 			}
 
-			var result executor.Relation
-			if handler != nil {
-				ctx := executor.NewContext(handler)
-				result, err = exec.ExecuteWithContext(ctx, q)
-			} else {
-				result, err = exec.Execute(q)
+			inputs, err := vars.inputs(q)
+			if err != nil {
+				fmt.Printf("Input error: %v\n", err)
+				continue
 			}
+
+			result, err := executeInterruptible(exec, executor.NewContext(handler), q, inputs)
 			if err != nil {
 				fmt.Printf("Execution error: %v\n", err)
 				continue
@@ -275,14 +343,116 @@ func runInteractive(db *storage.Database, handler annotations.Handler, enableDec
 	}
 }
 
+// executeInterruptible runs a query, canceling it if the user presses Ctrl-C
+// before it finishes
+func executeInterruptible(exec *executor.Executor, ctx executor.Context, q *query.Query, inputs []executor.Relation) (executor.Relation, error) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	type outcome struct {
+		rel executor.Relation
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		rel, err := exec.ExecuteWithRelations(ctx, q, inputs)
+		done <- outcome{rel, err}
+	}()
+
+	for {
+		select {
+		case out := <-done:
+			return out.rel, out.err
+		case <-interrupt:
+			for _, active := range exec.ActiveQueries() {
+				fmt.Printf("\nCanceling query %d (phase %d of %d, running %v)\n",
+					active.ID, active.Phase, active.Phases, active.Elapsed.Round(time.Millisecond))
+				if err := exec.CancelQuery(active.ID); err != nil {
+					fmt.Printf("Cancel error: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// sessionVars holds the values bound with .set in interactive mode. They are
+// supplied to the scalar inputs of later queries, so that
+//
+//	.set ?sym "AAPL"
+//	[:find ?close :in $ ?sym :where [?s :symbol/ticker ?sym] ...]
+//
+// runs the query for AAPL without repeating the value.
+type sessionVars map[query.Symbol]interface{}
+
+// set binds a variable from ".set" arguments: a variable and a value with the
+// syntax of query constants, e.g. ?from #inst "2024-01-01"
+func (v sessionVars) set(args string) error {
+	name, literal, _ := strings.Cut(strings.TrimSpace(args), " ")
+	literal = strings.TrimSpace(literal)
+	if !strings.HasPrefix(name, "?") || literal == "" {
+		return fmt.Errorf(`usage: .set ?var value, e.g. .set ?sym "AAPL"`)
+	}
+	value, err := parser.ParseValue(literal)
+	if err != nil {
+		return err
+	}
+	v[query.Symbol(name)] = value
+	return nil
+}
+
+// print lists the session variables in name order
+func (v sessionVars) print() {
+	if len(v) == 0 {
+		fmt.Println("No session variables")
+		return
+	}
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s = %#v\n", name, v[query.Symbol(name)])
+	}
+}
+
+// inputs returns the input relations for q's :in clause, taking each scalar
+// input from the session. Other inputs can't be supplied interactively.
+func (v sessionVars) inputs(q *query.Query) ([]executor.Relation, error) {
+	var inputs []executor.Relation
+	for _, in := range q.In {
+		switch in := in.(type) {
+		case query.DatabaseInput:
+			continue
+		case query.ScalarInput:
+			value, ok := v[in.Symbol]
+			if !ok {
+				return nil, fmt.Errorf("no session variable for %s; bind one with .set %s <value>", in.Symbol, in.Symbol)
+			}
+			inputs = append(inputs, executor.NewMaterializedRelation(
+				[]query.Symbol{in.Symbol},
+				[]executor.Tuple{{value}},
+			))
+		default:
+			return nil, fmt.Errorf("input %s can't be supplied interactively; only scalar inputs take session variables", in)
+		}
+	}
+	return inputs, nil
+}
+
 func addInteractiveData(db *storage.Database, scanner *bufio.Scanner) {
 	fmt.Println("Adding data (empty line to finish):")
+	fmt.Println(`  alice :person/name "Ann B" :person/age 33`)
+	fmt.Println(`  alice {:person/name "Ann B" :person/age 33}`)
+	fmt.Println(`  {:db/id alice :person/name "Ann B"}`)
+	fmt.Println(`  values are query literals: 42, 1.5, "text", :keyword, true, #inst "2025-01-02T09:30:00Z"`)
 
 	tx := db.NewTransaction()
 	count := 0
 
 	for {
-		fmt.Print("  entity attribute value> ")
+		fmt.Print("  entity attribute value ...> ")
 		if !scanner.Scan() {
 			tx.Rollback()
 			return
@@ -293,22 +463,20 @@ func addInteractiveData(db *storage.Database, scanner *bufio.Scanner) {
 			break
 		}
 
-		parts := strings.Fields(line)
-		if len(parts) != 3 {
-			fmt.Println("Expected: <entity> <attribute> <value>")
-			continue
-		}
-
-		e := datalog.NewIdentity(parts[0])
-		a := datalog.NewKeyword(parts[1])
-		v := parseValue(parts[2])
-
-		if err := tx.Add(e, a, v); err != nil {
+		entity, entries, err := parseDatomLine(line)
+		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			continue
 		}
 
-		count++
+		e := datalog.NewIdentity(entity)
+		for _, entry := range entries {
+			if err := tx.Add(e, entry.attr, entry.value); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				break
+			}
+			count++
+		}
 	}
 
 	if count > 0 {
@@ -324,28 +492,147 @@ func addInteractiveData(db *storage.Database, scanner *bufio.Scanner) {
 	}
 }
 
-func parseValue(s string) interface{} {
-	// Try to parse as number
-	if n, err := fmt.Sscanf(s, "%d", new(int64)); err == nil && n == 1 {
-		var val int64
-		fmt.Sscanf(s, "%d", &val)
-		return val
+// dumpIndexLimit caps the number of keys printed by .dump
+const dumpIndexLimit = 100
+
+// dumpIndex prints the keys of an index starting with the given components,
+// e.g. ".dump avet :person/age 20" lists AVET keys for ages equal to 20
+func dumpIndex(db *storage.Database, args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: .dump <eavt|aevt|avet|vaet|taev> [component ...]")
+		return
+	}
+
+	index, err := storage.ParseIndexType(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Components follow the index order; entities and attributes are
+	// passed by name, values and transactions are parsed
+	order := strings.ToUpper(args[0])
+	var prefix []interface{}
+	for i, arg := range args[1:] {
+		if i >= len(order) {
+			fmt.Printf("Error: %s takes at most %d components\n", order, len(order))
+			return
+		}
+		switch order[i] {
+		case 'E', 'A':
+			prefix = append(prefix, arg)
+		case 'V', 'T':
+			v, err := parser.ParseValue(arg)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			prefix = append(prefix, v)
+		}
+	}
+
+	entries, err := db.DumpIndex(index, prefix, dumpIndexLimit+1)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	truncated := len(entries) > dumpIndexLimit
+	if truncated {
+		entries = entries[:dumpIndexLimit]
+	}
+	for _, entry := range entries {
+		if entry.DecodeError != nil {
+			fmt.Printf("%x  <decode error: %v>\n", entry.Key, entry.DecodeError)
+			continue
+		}
+		d := entry.Datom
+		fmt.Printf("%x\n    [%s %s %v %d]\n", entry.Key, d.E, d.A, d.V, d.Tx)
+	}
+	if truncated {
+		fmt.Printf("(first %d keys shown)\n", dumpIndexLimit)
+	} else {
+		fmt.Printf("(%d keys)\n", len(entries))
+	}
+}
+
+// datomEntry is one attribute and value entered for an entity
+type datomEntry struct {
+	attr  datalog.Keyword
+	value interface{}
+}
+
+// parseDatomLine parses one line of .add input, in one of the forms
+//
+//	alice :person/name "Ann B"
+//	alice :person/name "Ann B"	:person/age 33
+//	alice {:person/name "Ann B" :person/age 33}
+//	{:db/id alice :person/name "Ann B" :person/age 33}
+//
+// Attributes must be keywords and values are query literals (see
+// parser.ParseValue); tabs separate values like any other whitespace.
+func parseDatomLine(line string) (string, []datomEntry, error) {
+	line = strings.TrimSpace(line)
+
+	var entity, rest string
+	if strings.HasPrefix(line, "{") {
+		rest = line
+	} else {
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			return "", nil, fmt.Errorf("expected <entity> <attribute> <value>")
+		}
+		entity, rest = line[:i], line[i+1:]
 	}
 
-	// Try to parse as float
-	if n, err := fmt.Sscanf(s, "%f", new(float64)); err == nil && n == 1 {
-		var val float64
-		fmt.Sscanf(s, "%f", &val)
-		return val
+	lexer := edn.NewLexer(rest)
+	if err := lexer.Lex(); err != nil {
+		return "", nil, err
+	}
+	nodes, err := edn.NewParser(lexer).ParseAll()
+	if err != nil {
+		return "", nil, err
 	}
 
-	// Remove quotes if present
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		return s[1 : len(s)-1]
+	// A map supplies the attribute/value pairs, and the entity as :db/id
+	if len(nodes) == 1 && nodes[0].Type == edn.NodeMap {
+		nodes = nodes[0].Nodes
+	} else if entity == "" {
+		return "", nil, fmt.Errorf("expected a single map")
+	}
+	if len(nodes) == 0 || len(nodes)%2 != 0 {
+		return "", nil, fmt.Errorf("expected attribute/value pairs, got %d forms", len(nodes))
 	}
 
-	// Default to string
-	return s
+	var entries []datomEntry
+	for i := 0; i < len(nodes); i += 2 {
+		attr, value := nodes[i], nodes[i+1]
+		if attr.Type != edn.NodeKeyword {
+			return "", nil, fmt.Errorf("attribute %s is not a keyword", attr.String())
+		}
+		if attr.Value == ":db/id" {
+			if value.Type != edn.NodeSymbol && value.Type != edn.NodeString {
+				return "", nil, fmt.Errorf(":db/id must be a name, got %s", value.String())
+			}
+			if entity != "" {
+				return "", nil, fmt.Errorf("entity given twice: %s and %s", entity, value.Value)
+			}
+			entity = value.Value
+			continue
+		}
+		v, err := parser.ParseValueNode(&value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", attr.Value, err)
+		}
+		entries = append(entries, datomEntry{attr: datalog.NewKeyword(attr.Value), value: v})
+	}
+	if entity == "" {
+		return "", nil, fmt.Errorf("map needs a :db/id")
+	}
+	if len(entries) == 0 {
+		return "", nil, fmt.Errorf("no attributes given for %s", entity)
+	}
+	return entity, entries, nil
 }
 
 // isDatabaseEmpty checks if the database contains any data
@@ -415,3 +702,43 @@ func runSingleQuery(db *storage.Database, handler annotations.Handler, queryStr
 	}
 	fmt.Print(strings.Join(lines, "\n"))
 }
+
+// runReplay replays captured queries under the database's current options
+// and each config file, and prints the comparison
+func runReplay(db *storage.Database, replayFile, configs string) {
+	queries, err := storage.ReadReplayFile(replayFile)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	optionSets := []storage.ReplayOptionSet{{Name: "current", Config: db.Config()}}
+	for _, path := range strings.Split(configs, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		config, err := storage.ReadConfig(path)
+		if err != nil {
+			log.Fatalf("Replay failed: %v", err)
+		}
+		optionSets = append(optionSets, storage.ReplayOptionSet{Name: path, Config: config})
+	}
+
+	report, err := storage.Replay(db, queries, optionSets)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+	fmt.Print(report)
+}
+
+// runVerify prints the index consistency report, returning whether the
+// indexes are consistent
+func runVerify(db *storage.Database) bool {
+	start := time.Now()
+	report, err := db.VerifyIndexes()
+	if err != nil {
+		log.Fatalf("Verify failed: %v", err)
+	}
+	fmt.Println(report)
+	fmt.Printf("(%v)\n", time.Since(start))
+	return report.OK()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+func TestParseDatomLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		entity   string
+		expected string // Entries as attr=value
+	}{
+		{`alice :person/age 33`, "alice", "[:person/age=33]"},
+		{`alice :person/name "Ann B"`, "alice", "[:person/name=Ann B]"},
+		{"alice\t:person/name \"Ann B\"\t:person/age 33", "alice", "[:person/name=Ann B :person/age=33]"},
+		{`alice {:person/name "Ann B" :person/age 33}`, "alice", "[:person/name=Ann B :person/age=33]"},
+		{`{:db/id alice :person/name "Ann B"}`, "alice", "[:person/name=Ann B]"},
+		{`{:db/id "bob" :person/friend :person/alice}`, "bob", "[:person/friend=:person/alice]"},
+		{`e1 :event/at #inst "2025-01-02"`, "e1", "[:event/at=2025-01-02 00:00:00 +0000 UTC]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			entity, entries, err := parseDatomLine(tt.line)
+			if err != nil {
+				t.Fatalf("parseDatomLine failed: %v", err)
+			}
+			if entity != tt.entity {
+				t.Errorf("Expected entity %s, got %s", tt.entity, entity)
+			}
+			var pairs []string
+			for _, e := range entries {
+				pairs = append(pairs, fmt.Sprintf("%s=%v", e.attr, e.value))
+			}
+			if got := fmt.Sprint(pairs); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseDatomLineErrors(t *testing.T) {
+	lines := []string{
+		`alice`,
+		`alice :person/name`,
+		`alice person/name "Ann"`,
+		`alice :person/name Ann`,
+		`alice :person/age 123abc`,
+		`{:person/name "Ann"}`,
+		`alice {:db/id bob :person/name "Ann"}`,
+		`{:db/id alice}`,
+		`alice {:person/name "Ann"} :person/age 3`,
+	}
+
+	for _, line := range lines {
+		if entity, entries, err := parseDatomLine(line); err == nil {
+			t.Errorf("parseDatomLine(%q) = %s %v, expected an error", line, entity, entries)
+		}
+	}
+}
+
+func TestSessionVars(t *testing.T) {
+	vars := sessionVars{}
+	if err := vars.set(` ?sym "AAPL"`); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := vars.set(`?from #inst "2024-01-01"`); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	for _, args := range []string{``, `?sym`, `sym "AAPL"`, `?sym AAPL`} {
+		if err := vars.set(args); err == nil {
+			t.Errorf("set(%q) succeeded, expected an error", args)
+		}
+	}
+
+	q, err := parser.ParseQuery(`[:find ?p :in $ ?sym ?from :where [?p :price/symbol ?sym] [?p :price/time ?t] [(>= ?t ?from)]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := vars.inputs(q)
+	if err != nil {
+		t.Fatalf("inputs failed: %v", err)
+	}
+	if len(inputs) != 2 || inputs[0].Get(0)[0] != "AAPL" {
+		t.Fatalf("Expected ?sym and ?from inputs, got %v", inputs)
+	}
+	if from, ok := inputs[1].Get(0)[0].(time.Time); !ok || from.Year() != 2024 {
+		t.Errorf("Expected ?from to be a 2024 time, got %v", inputs[1].Get(0)[0])
+	}
+
+	delete(vars, "?from")
+	if _, err := vars.inputs(q); err == nil || !strings.Contains(err.Error(), "?from") {
+		t.Errorf("Expected an error naming ?from, got %v", err)
+	}
+
+	q, err = parser.ParseQuery(`[:find ?p :in $ [?sym ...] :where [?p :price/symbol ?sym]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vars.inputs(q); err == nil {
+		t.Error("Expected collection inputs to be rejected")
+	}
+}
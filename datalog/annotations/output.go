@@ -66,11 +66,17 @@ func (f *OutputFormatter) Format(event Event) string {
 				f.colorize("✗", color.FgRed),
 				event.Data["error"])
 		}
-		return fmt.Sprintf("%s %s Query done with %s with %s total.",
+		done := fmt.Sprintf("%s %s Query done with %s with %s total.",
 			latency,
 			f.colorize("===", color.FgGreen),
 			f.colorizeCount("Relations", event.Data["relations.count"].(int)),
 			f.colorizeCount("Tuples", event.Data["tuples.count"].(int)))
+		if columns, ok := event.Data["columns"].([]ColumnStatistics); ok {
+			for _, c := range columns {
+				done += "\n    " + c.String()
+			}
+		}
+		return done
 
 	case PhaseBegin:
 		phase := event.Data["phase"]
@@ -132,11 +138,11 @@ func (f *OutputFormatter) Format(event Event) string {
 			joinStr = f.renderer.RenderJoin(leftAttrs, left, rightAttrs, right, resultAttrs, result)
 		} else {
 			// Fallback to simple format
-			joinStr = fmt.Sprintf("%d × %d → %d tuples", left, right, result)
+			joinStr = fmt.Sprintf("%s × %s → %s tuples", countString(left), countString(right), countString(result))
 		}
 
-		// Check for explosive joins
-		if result > left*right/2 || result > 100000 {
+		// Check for explosive joins (sizes are -1 when unknown)
+		if (left >= 0 && right >= 0 && result > left*right/2) || result > 100000 {
 			return fmt.Sprintf("%s %s %s",
 				latency,
 				f.colorize("⚠️", color.FgYellow),
@@ -312,16 +318,39 @@ func (f *OutputFormatter) Format(event Event) string {
 			}
 			f.lastBound = strings.Join(boundParts, " ")
 		}
-		return ""
+
+		// Explain the choice when the event lists the indexes considered
+		sel, ok := event.Payload.(IndexSelectionEvent)
+		if !ok || len(sel.Candidates) == 0 {
+			return ""
+		}
+		lines := []string{fmt.Sprintf("%s Index([%s]) → %s", latency, sel.Pattern, sel.Index)}
+		for _, c := range sel.Candidates {
+			line := "    " + c.String()
+			if c.Selected {
+				line = f.colorize(line, color.FgGreen)
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n")
 
 	case PatternStorageScan:
 		// Format as Scan([pattern], index, bound) → X datoms in Yms
-		pattern := event.Data["pattern"].(string)
-		datoms := event.Data["datoms.scanned"].(int)
+		var pattern, scanIndex string
+		var datoms int
+		if scan, ok := event.Payload.(PatternScanEvent); ok {
+			pattern, datoms, scanIndex = scan.Pattern, scan.DatomsScanned, scan.Index
+		} else {
+			pattern = event.Data["pattern"].(string)
+			datoms = event.Data["datoms.scanned"].(int)
+		}
 		duration := event.Data["scan.duration"]
 
-		// Use stored index info if available
+		// Prefer the index reported by the scan, then stored index info
 		index := f.lastIndex
+		if scanIndex != "" {
+			index = scanIndex
+		}
 		bound := f.lastBound
 		if index == "" {
 			index = "?"
@@ -375,6 +404,14 @@ func (f *OutputFormatter) Format(event Event) string {
 		return fmt.Sprintf("%s %s → %d datoms in %v",
 			latency, scanStr, datoms, duration)
 
+	case PatternLimitReached:
+		text := fmt.Sprintf("WARNING: Pattern(%v) reached :max-datoms %v, result is partial",
+			event.Data["pattern"], event.Data["max.datoms"])
+		if f.useColor {
+			text = color.RedString(text)
+		}
+		return fmt.Sprintf("%s %s", latency, text)
+
 	case PatternFiltering:
 		// Skip - filtering info is redundant with Pattern output
 		return ""
@@ -475,16 +512,21 @@ func (f *OutputFormatter) Format(event Event) string {
 				f.colorizeCount("Tuples", resultSize))
 		}
 
-		return fmt.Sprintf("%s %s on %d Tuples → %d Tuples",
-			latency, exprStr, inputSize, resultSize)
+		return fmt.Sprintf("%s %s on %s Tuples → %s Tuples",
+			latency, exprStr, countString(inputSize), countString(resultSize))
 
 	case "filter/predicate":
 		// Format as Predicate(...) on X Tuples → Y Tuples (filtered Z)
 		pred := event.Data["predicate"].(string)
 		inputSize := event.Data["input.size"].(int)
 		outputSize := event.Data["output.size"].(int)
-		filtered := event.Data["filtered"].(int)
-		selectivity := event.Data["selectivity"].(float64)
+
+		// Selectivity is only reported when both sizes are known
+		var filterInfo string
+		if filtered, ok := event.Data["filtered"].(int); ok {
+			selectivity := event.Data["selectivity"].(float64)
+			filterInfo = fmt.Sprintf(" (filtered %d, %.1f%% selectivity)", filtered, selectivity*100)
+		}
 
 		var predStr string
 		if f.useColor {
@@ -498,7 +540,9 @@ func (f *OutputFormatter) Format(event Event) string {
 
 		if f.useColor {
 			arrow := color.YellowString(" → ")
-			filterInfo := color.RedString(fmt.Sprintf(" (filtered %d, %.1f%% selectivity)", filtered, selectivity*100))
+			if filterInfo != "" {
+				filterInfo = color.RedString(filterInfo)
+			}
 			return fmt.Sprintf("%s %s on %s%s%s%s",
 				latency,
 				predStr,
@@ -508,8 +552,8 @@ func (f *OutputFormatter) Format(event Event) string {
 				filterInfo)
 		}
 
-		return fmt.Sprintf("%s %s on %d Tuples → %d Tuples (filtered %d, %.1f%% selectivity)",
-			latency, predStr, inputSize, outputSize, filtered, selectivity*100)
+		return fmt.Sprintf("%s %s on %s Tuples → %s Tuples%s",
+			latency, predStr, countString(inputSize), countString(outputSize), filterInfo)
 
 	default:
 		// Generic format for unknown events
@@ -547,9 +591,18 @@ func (f *OutputFormatter) formatLatency(d time.Duration) string {
 	}
 }
 
+// countString formats a count, or "?" for a size the executor reported as
+// unknown (-1) rather than materialize the relation to find it
+func countString(count int) string {
+	if count < 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%d", count)
+}
+
 // colorizeCount formats a count with a label, using color based on the label type.
 func (f *OutputFormatter) colorizeCount(label string, count int) string {
-	text := fmt.Sprintf("%d %s", count, label)
+	text := fmt.Sprintf("%s %s", countString(count), label)
 
 	if !f.useColor {
 		return text
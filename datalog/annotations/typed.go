@@ -0,0 +1,286 @@
+package annotations
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Payload is a typed event body. Events built from a Payload carry it in
+// Event.Payload and also expose its fields through the generic Event.Data map,
+// so handlers written against map keys keep working unchanged.
+type Payload interface {
+	// Fill writes the payload's fields into the generic Data map
+	Fill(data map[string]interface{})
+}
+
+// NewEvent creates an event from a typed payload, populating both
+// Event.Payload and the generic Event.Data map.
+func NewEvent(name string, payload Payload) Event {
+	data := make(map[string]interface{}, 8)
+	payload.Fill(data)
+	return Event{
+		Name:    name,
+		Start:   time.Now(),
+		Data:    data,
+		Payload: payload,
+	}
+}
+
+// AddPayloadTiming records a timed event with a typed payload.
+// The generic Data map is taken from the collector's pool.
+func (c *Collector) AddPayloadTiming(name string, start time.Time, payload Payload) {
+	if !c.enabled {
+		return
+	}
+
+	data := c.GetDataMap()
+	payload.Fill(data)
+
+	end := time.Now()
+	c.Add(Event{
+		Name:    name,
+		Start:   start,
+		End:     end,
+		Latency: end.Sub(start),
+		Data:    data,
+		Payload: payload,
+	})
+}
+
+// PatternScanEvent reports storage scan statistics for a single pattern.
+// Emitted as "pattern/storage-scan" for unbound scans and
+// "pattern/iterator-reuse-complete" for binding-driven scans.
+type PatternScanEvent struct {
+	Pattern       string
+	Index         string // EAVT, AEVT, AVET, VAET or TAEV
+	DatomsScanned int    // Datoms read from the index
+	DatomsMatched int    // Datoms that matched the pattern
+	BindingSize   int    // Binding tuples driving the scan (0 for unbound scans)
+	Strategy      string // Scan strategy, e.g. "iterator-reuse" (empty for plain scans)
+	KeysFiltered  int    // Keys rejected by a key mask before decoding
+	FilterType    string // Key filter used, e.g. "key-mask" (empty if none)
+}
+
+// Fill implements Payload
+func (e PatternScanEvent) Fill(data map[string]interface{}) {
+	data["pattern"] = e.Pattern
+	data["index"] = e.Index
+	data["datoms.scanned"] = e.DatomsScanned
+	data["datoms.matched"] = e.DatomsMatched
+	if e.Strategy != "" {
+		data["strategy"] = e.Strategy
+		data["binding.size"] = e.BindingSize
+	}
+	if e.FilterType != "" {
+		data["filter.type"] = e.FilterType
+		data["keys.filtered"] = e.KeysFiltered
+	}
+}
+
+// HashJoinScanEvent reports statistics for a hash join scan of a pattern
+// against a binding relation. Emitted as "pattern/hash-join-complete".
+type HashJoinScanEvent struct {
+	Pattern       string
+	Index         string
+	BindingSize   int // Distinct binding keys in the hash set
+	DatomsScanned int
+	MatchesFound  int
+}
+
+// Fill implements Payload
+func (e HashJoinScanEvent) Fill(data map[string]interface{}) {
+	data["pattern"] = e.Pattern
+	data["index"] = e.Index
+	data["binding.size"] = e.BindingSize
+	data["datoms.scanned"] = e.DatomsScanned
+	data["matches.found"] = e.MatchesFound
+}
+
+// IndexSelectionEvent reports the index chosen for an unbound pattern scan.
+// Emitted as PatternIndexSelection.
+type IndexSelectionEvent struct {
+	Pattern    string
+	Index      string
+	Pinned     bool             // Index was pinned by the plan rather than chosen automatically
+	Bound      string           // Bound pattern positions, e.g. "AV" (empty if none)
+	Candidates []IndexCandidate // Every index considered, including the selected one
+}
+
+// Fill implements Payload
+func (e IndexSelectionEvent) Fill(data map[string]interface{}) {
+	data["pattern"] = e.Pattern
+	data["index"] = e.Index
+	data["pinned"] = e.Pinned
+	if len(e.Candidates) > 0 {
+		data["bound.e"] = strings.Contains(e.Bound, "E")
+		data["bound.a"] = strings.Contains(e.Bound, "A")
+		data["bound.v"] = strings.Contains(e.Bound, "V")
+		data["bound.t"] = strings.Contains(e.Bound, "T")
+		data["candidates"] = e.Candidates
+	}
+}
+
+// IndexCandidate explains why an index was or was not used for a pattern
+// scan. Selectivity is a heuristic estimate of the fraction of the index the
+// scan reads, from the bound components that form a key prefix; bound
+// components outside the prefix are checked against each datom instead.
+type IndexCandidate struct {
+	Index       string
+	Prefix      string  // Bound leading key components, e.g. "AV" (empty for a full index scan)
+	Selectivity float64 // Estimated fraction of the index scanned (1 = full scan)
+	Selected    bool
+	Reason      string // Why the index was selected or rejected
+}
+
+// String returns the candidate as e.g. "AEVT[A] ~0.1 rejected: ..."
+func (c IndexCandidate) String() string {
+	verdict := "rejected"
+	if c.Selected {
+		verdict = "selected"
+	}
+	return fmt.Sprintf("%s[%s] ~%g %s: %s", c.Index, c.Prefix, c.Selectivity, verdict, c.Reason)
+}
+
+// MatchEvent reports the result of matching a pattern into a relation.
+// Emitted as MatchesToRelations.
+type MatchEvent struct {
+	Pattern         string
+	MatchCount      int // -1 if the result is streaming and its size unknown
+	Success         bool
+	Error           string   // Error message when Success is false
+	BindingColumns  []string // Columns of the binding relation, if any
+	BindingSize     int      // -1 if unknown
+	SymbolOrder     []string // Output columns of the result relation
+	Constrained     bool     // Matched with storage constraints
+	ConstraintCount int
+	Index           string // Pinned index, if the pattern was matched on one
+}
+
+// Fill implements Payload
+func (e MatchEvent) Fill(data map[string]interface{}) {
+	data["pattern"] = e.Pattern
+	data["match.count"] = e.MatchCount
+	data["success"] = e.Success
+	if e.Error != "" {
+		data["error"] = e.Error
+	}
+	if len(e.BindingColumns) > 0 {
+		data["binding.columns"] = e.BindingColumns
+		data["binding.size"] = e.BindingSize
+	}
+	if e.SymbolOrder != nil {
+		data["symbol.order"] = e.SymbolOrder
+	}
+	if e.Constrained {
+		data["constraint.count"] = e.ConstraintCount
+	}
+	if e.Index != "" {
+		data["index"] = e.Index
+		data["index.pinned"] = true
+	}
+}
+
+// PatternLimitEvent warns that a pattern reached its :max-datoms hint, so the
+// query result is partial. Emitted as PatternLimitReached.
+type PatternLimitEvent struct {
+	Pattern   string
+	MaxDatoms int
+}
+
+// Fill implements Payload
+func (e PatternLimitEvent) Fill(data map[string]interface{}) {
+	data["pattern"] = e.Pattern
+	data["max.datoms"] = e.MaxDatoms
+	data["partial"] = true
+}
+
+// QueryResultEvent summarizes the final result of a query, after :offset and
+// :limit, so that pipelines can sanity-check it where it leaves the engine.
+// Emitted as QueryComplete when the executor computes column statistics.
+type QueryResultEvent struct {
+	Tuples  int
+	Columns []ColumnStatistics // One per result column, in column order
+}
+
+// Fill implements Payload
+func (e QueryResultEvent) Fill(data map[string]interface{}) {
+	data["relations.count"] = 1
+	data["tuples.count"] = e.Tuples
+	data["success"] = true
+	data["columns"] = e.Columns
+}
+
+// ColumnStatistics describes the values of one result column. Min and Max
+// are ordered as by datalog.CompareValues and are nil if the column holds
+// only nils.
+type ColumnStatistics struct {
+	Column string
+	Min    interface{}
+	Max    interface{}
+	Nulls  int // Tuples with a nil value in the column
+}
+
+// String returns the statistics as e.g. "?price min=0 max=412.5 nulls=3"
+func (c ColumnStatistics) String() string {
+	return fmt.Sprintf("%s min=%v max=%v nulls=%d", c.Column, c.Min, c.Max, c.Nulls)
+}
+
+// TypedHandler dispatches events to callbacks by payload type, so consumers
+// such as metric exporters do not depend on Data map keys. Events without a
+// typed payload, or whose callback is nil, are passed to Generic.
+//
+//	h := &annotations.TypedHandler{
+//	    OnPatternScan: func(ev annotations.Event, scan annotations.PatternScanEvent) {
+//	        scanned.Add(float64(scan.DatomsScanned))
+//	    },
+//	}
+//	ctx := executor.NewContext(h.Handle)
+type TypedHandler struct {
+	OnPatternScan    func(Event, PatternScanEvent)
+	OnHashJoinScan   func(Event, HashJoinScanEvent)
+	OnIndexSelection func(Event, IndexSelectionEvent)
+	OnMatch          func(Event, MatchEvent)
+	OnPatternLimit   func(Event, PatternLimitEvent)
+	OnQueryResult    func(Event, QueryResultEvent)
+	Generic          Handler
+}
+
+// Handle implements Handler
+func (h *TypedHandler) Handle(event Event) {
+	switch p := event.Payload.(type) {
+	case PatternScanEvent:
+		if h.OnPatternScan != nil {
+			h.OnPatternScan(event, p)
+			return
+		}
+	case HashJoinScanEvent:
+		if h.OnHashJoinScan != nil {
+			h.OnHashJoinScan(event, p)
+			return
+		}
+	case IndexSelectionEvent:
+		if h.OnIndexSelection != nil {
+			h.OnIndexSelection(event, p)
+			return
+		}
+	case MatchEvent:
+		if h.OnMatch != nil {
+			h.OnMatch(event, p)
+			return
+		}
+	case PatternLimitEvent:
+		if h.OnPatternLimit != nil {
+			h.OnPatternLimit(event, p)
+			return
+		}
+	case QueryResultEvent:
+		if h.OnQueryResult != nil {
+			h.OnQueryResult(event, p)
+			return
+		}
+	}
+	if h.Generic != nil {
+		h.Generic(event)
+	}
+}
@@ -36,6 +36,7 @@ const (
 	PatternStorageScan    = "pattern/storage-scan"
 	PatternFiltering      = "pattern/filtering"
 	PatternToRelation     = "pattern/to-relation"
+	PatternLimitReached   = "pattern/limit-reached"
 
 	// Join operations
 	JoinHash   = "join/hash"
@@ -60,6 +61,7 @@ type Event struct {
 	Latency time.Duration          // Duration (End - Start)
 	Data    map[string]interface{} // Additional event-specific data with grouped metrics
 	Caller  string                 // Optional: file:line where event occurred
+	Payload Payload                // Optional: typed event data (also rendered into Data)
 }
 
 // Handler processes annotation events as they occur.
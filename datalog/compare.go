@@ -18,6 +18,10 @@ import (
 // - Datalog types: Identity, Keyword
 // - Nil values (nil is less than any non-nil value)
 // - Type conversions between numeric types
+//
+// Booleans order false < true, matching their AVET index order. Comparing
+// values of unrelated types (e.g. a bool with a string) is a type mismatch
+// and returns -1; such values are never equal.
 func CompareValues(left, right interface{}) int {
 	// Handle nil
 	if left == nil && right == nil {
@@ -195,9 +199,50 @@ func compareFloats(a, b float64) int {
 	return 0
 }
 
+// collectionsEqual compares []interface{} and [][]interface{} values
+// element-wise. ok is false if neither value is a collection.
+func collectionsEqual(a, b interface{}) (equal bool, ok bool) {
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false, true
+		}
+		for i := range av {
+			if !ValuesEqual(av[i], bv[i]) {
+				return false, true
+			}
+		}
+		return true, true
+	case [][]interface{}:
+		bv, ok := b.([][]interface{})
+		if !ok || len(av) != len(bv) {
+			return false, true
+		}
+		for i := range av {
+			if equal, _ := collectionsEqual(av[i], bv[i]); !equal {
+				return false, true
+			}
+		}
+		return true, true
+	}
+
+	switch b.(type) {
+	case []interface{}, [][]interface{}:
+		return false, true
+	}
+	return false, false
+}
+
 // ValuesEqual checks if two values are equal.
 // It uses CompareValues for consistent equality checking.
 func ValuesEqual(a, b interface{}) bool {
+	// Collection values (e.g. a whole [?x ...] or [[?x ?y]] input carried in
+	// one variable) are compared element-wise; == would panic on them
+	if equal, ok := collectionsEqual(a, b); ok {
+		return equal
+	}
+
 	// Quick pointer equality check for interned values
 	if a == b {
 		return true
@@ -42,3 +42,18 @@ func TestValuesEqualWithPointers(t *testing.T) {
 		t.Error("Expected keyword pointers to be equal")
 	}
 }
+
+func TestValuesEqualCollections(t *testing.T) {
+	a := []interface{}{"x", int64(1), NewIdentity("e")}
+	b := []interface{}{"x", int64(1), InternIdentity(NewIdentity("e"))}
+
+	if !ValuesEqual(a, b) {
+		t.Error("Expected collections with equal elements to be equal")
+	}
+	if ValuesEqual(a, b[:2]) {
+		t.Error("Expected collections of different lengths to differ")
+	}
+	if ValuesEqual(a, "x") || ValuesEqual("x", a) {
+		t.Error("Expected collection and scalar to differ")
+	}
+}
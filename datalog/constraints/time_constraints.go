@@ -61,6 +61,22 @@ func ComposeTimeConstraint(
 	minute *int,
 	second *int,
 	position int,
+) *TimeRangeConstraint {
+	return ComposeTimeConstraintIn(year, month, day, hour, minute, second, position, time.UTC)
+}
+
+// ComposeTimeConstraintIn is ComposeTimeConstraint for components in the
+// given zone, e.g. a day in America/New_York, which starts and ends at
+// different UTC hours across daylight saving changes
+func ComposeTimeConstraintIn(
+	year *int,
+	month *int,
+	day *int,
+	hour *int,
+	minute *int,
+	second *int,
+	position int,
+	loc *time.Location,
 ) *TimeRangeConstraint {
 	// Start with most specific time, default unspecified parts
 	y := 1970
@@ -89,7 +105,7 @@ func ComposeTimeConstraint(
 		sec = *second
 	}
 
-	start := time.Date(y, time.Month(m), d, h, min, sec, 0, time.UTC)
+	start := time.Date(y, time.Month(m), d, h, min, sec, 0, loc)
 
 	// Calculate end based on least specific component
 	var end time.Time
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // NodeType represents the type of EDN node
@@ -122,6 +123,27 @@ func (n Node) AsKeyword() (string, error) {
 	return n.Value, nil
 }
 
+// instLayouts are the timestamp formats accepted by #inst, most precise first
+var instLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// AsInst returns the time of an #inst tagged string, e.g.
+// #inst "2025-01-02T09:30:00Z". Timestamps without an offset are UTC.
+func (n Node) AsInst() (time.Time, error) {
+	if n.Type != NodeTagged || n.Tag != "inst" || n.Tagged == nil || n.Tagged.Type != NodeString {
+		return time.Time{}, fmt.Errorf("node is not an #inst string")
+	}
+	for _, layout := range instLayouts {
+		if t, err := time.Parse(layout, n.Tagged.Value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid #inst timestamp %q", n.Tagged.Value)
+}
+
 // IsNil returns true if the node is nil
 func (n Node) IsNil() bool {
 	return n.Type == NodeNil
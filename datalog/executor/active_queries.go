@@ -0,0 +1,202 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueryCanceled is returned by a query canceled with CancelQuery
+var ErrQueryCanceled = errors.New("query canceled")
+
+// ErrQueryNotActive is returned by CancelQuery for an id that isn't running
+var ErrQueryNotActive = errors.New("query not active")
+
+// ErrQueriesClosed is returned for a query started after its executor's
+// QueryTracker was closed
+var ErrQueriesClosed = errors.New("not accepting queries")
+
+// ActiveQuery describes a query being executed
+type ActiveQuery struct {
+	ID      uint64
+	Query   string
+	Elapsed time.Duration
+	Phase   int // Plan phase executing (1-based); 0 while planning and on the legacy executor path
+	Phases  int // Phases in the plan; 0 until it is planned
+}
+
+// QueryTracker records the queries executing on the executors that share it,
+// so that they can be listed and canceled. Each executor has its own tracker
+// unless one is shared with SetQueryTracker.
+//
+// Cancellation is cooperative: a canceled query stops at its next clause or
+// phase, which for a long scan or join means once that finishes, and returns
+// ErrQueryCanceled.
+type QueryTracker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	running map[uint64]*queryRun
+	closed  bool          // New queries are rejected
+	idle    chan struct{} // Closed when no queries are running, for Wait
+}
+
+// NewQueryTracker creates an empty query tracker
+func NewQueryTracker() *QueryTracker {
+	return &QueryTracker{running: make(map[uint64]*queryRun)}
+}
+
+// queryRun is a query registered with a QueryTracker
+type queryRun struct {
+	id       uint64
+	query    string
+	start    time.Time
+	phase    atomic.Int64
+	phases   atomic.Int64
+	canceled atomic.Bool
+}
+
+// begin registers a query as executing, or returns ErrQueriesClosed
+func (t *QueryTracker) begin(q string) (*queryRun, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrQueriesClosed
+	}
+	t.nextID++
+	run := &queryRun{id: t.nextID, query: q, start: time.Now()}
+	t.running[run.id] = run
+	return run, nil
+}
+
+// end removes a query once it has finished
+func (t *QueryTracker) end(run *queryRun) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.running, run.id)
+	if len(t.running) == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Close stops the tracker accepting queries: executors sharing it fail new
+// queries with ErrQueriesClosed, while those executing run to completion
+func (t *QueryTracker) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+}
+
+// Wait waits until no queries are executing, or returns ctx's error if it is
+// done first
+func (t *QueryTracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.running) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Active returns the executing queries, oldest first
+func (t *QueryTracker) Active() []ActiveQuery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	active := make([]ActiveQuery, 0, len(t.running))
+	for _, run := range t.running {
+		active = append(active, ActiveQuery{
+			ID:      run.id,
+			Query:   run.query,
+			Elapsed: time.Since(run.start),
+			Phase:   int(run.phase.Load()),
+			Phases:  int(run.phases.Load()),
+		})
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].ID < active[j].ID })
+	return active
+}
+
+// Cancel cancels the executing query with the given id
+func (t *QueryTracker) Cancel(id uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	run, ok := t.running[id]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrQueryNotActive, id)
+	}
+	run.canceled.Store(true)
+	return nil
+}
+
+// activeQueryKey holds the executing query's *queryRun in the context metadata
+const activeQueryKey = "active_query"
+
+// activeQuery returns the tracked query ctx executes, or nil
+func activeQuery(ctx Context) *queryRun {
+	value, ok := ctx.GetMetadata(activeQueryKey)
+	if !ok {
+		return nil
+	}
+	run, _ := value.(*queryRun)
+	return run
+}
+
+// checkCanceled returns ErrQueryCanceled if the query ctx executes has been
+// canceled
+func checkCanceled(ctx Context) error {
+	if run := activeQuery(ctx); run != nil && run.canceled.Load() {
+		return fmt.Errorf("%w: query %d", ErrQueryCanceled, run.id)
+	}
+	return nil
+}
+
+// enterPhase records the plan phase (1-based) the tracked query is executing
+// and returns ErrQueryCanceled if it has been canceled
+func enterPhase(ctx Context, phase, phases int) error {
+	run := activeQuery(ctx)
+	if run == nil {
+		return nil
+	}
+	run.phase.Store(int64(phase))
+	run.phases.Store(int64(phases))
+	return checkCanceled(ctx)
+}
+
+// SetQueryTracker sets the tracker the executor registers its queries with,
+// to share one tracker between executors
+func (e *Executor) SetQueryTracker(tracker *QueryTracker) {
+	e.queries = tracker
+}
+
+// ActiveQueries returns the queries executing on the executor (and any
+// sharing its tracker), oldest first
+func (e *Executor) ActiveQueries() []ActiveQuery {
+	if e.queries == nil {
+		return nil
+	}
+	return e.queries.Active()
+}
+
+// CancelQuery cancels an executing query by its ActiveQuery id. The query
+// returns ErrQueryCanceled once it reaches its next clause or phase.
+func (e *Executor) CancelQuery(id uint64) error {
+	if e.queries == nil {
+		return fmt.Errorf("%w: %d", ErrQueryNotActive, id)
+	}
+	return e.queries.Cancel(id)
+}
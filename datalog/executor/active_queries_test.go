@@ -0,0 +1,185 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// blockingMatcher holds its first match until released, so a test can
+// inspect a query while it executes
+type blockingMatcher struct {
+	inner   PatternMatcher
+	entered chan struct{}
+	release chan struct{}
+	matches int
+}
+
+func (m *blockingMatcher) Match(pattern *query.DataPattern, bindings Relations) (Relation, error) {
+	m.matches++
+	if m.matches == 1 {
+		close(m.entered)
+		<-m.release
+	}
+	return m.inner.Match(pattern, bindings)
+}
+
+func TestActiveQueries(t *testing.T) {
+	age := datalog.NewKeyword(":user/age")
+	datoms := queryOptionsTestDatoms()
+	for i, d := range queryOptionsTestDatoms() {
+		datoms = append(datoms, datalog.Datom{E: d.E, A: age, V: int64(20 + i), Tx: 1})
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		t.Run(fmt.Sprintf("QueryExecutor=%v", useQueryExecutor), func(t *testing.T) {
+			matcher := &blockingMatcher{
+				inner:   NewMemoryPatternMatcher(datoms),
+				entered: make(chan struct{}),
+				release: make(chan struct{}),
+			}
+			exec := NewExecutor(matcher)
+			exec.SetUseQueryExecutor(useQueryExecutor)
+
+			q, err := parser.ParseQuery(`[:find ?name ?age :where [?e :user/name ?name] [?e :user/age ?age]]`)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := exec.Execute(q)
+				done <- err
+			}()
+			<-matcher.entered
+
+			active := exec.ActiveQueries()
+			if len(active) != 1 {
+				t.Fatalf("Expected one active query, got %v", active)
+			}
+			if active[0].Query != q.String() || active[0].Elapsed <= 0 {
+				t.Errorf("Expected the query and its elapsed time, got %+v", active[0])
+			}
+			if useQueryExecutor && (active[0].Phase != 1 || active[0].Phases == 0) {
+				t.Errorf("Expected the query in its first phase, got phase %d of %d", active[0].Phase, active[0].Phases)
+			}
+
+			if err := exec.CancelQuery(active[0].ID); err != nil {
+				t.Fatalf("CancelQuery failed: %v", err)
+			}
+			close(matcher.release)
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, ErrQueryCanceled) {
+					t.Fatalf("Expected ErrQueryCanceled, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Canceled query did not stop")
+			}
+
+			if active := exec.ActiveQueries(); len(active) != 0 {
+				t.Errorf("Expected no active queries after cancellation, got %v", active)
+			}
+			if err := exec.CancelQuery(active[0].ID); !errors.Is(err, ErrQueryNotActive) {
+				t.Errorf("Expected ErrQueryNotActive for a finished query, got %v", err)
+			}
+
+			// The executor runs later queries normally
+			result, err := exec.Execute(q)
+			if err != nil {
+				t.Fatalf("execution failed: %v", err)
+			}
+			if result.Size() != 5 {
+				t.Errorf("Expected 5 results, got %d", result.Size())
+			}
+		})
+	}
+}
+
+func TestQueryTrackerClose(t *testing.T) {
+	matcher := &blockingMatcher{
+		inner:   NewMemoryPatternMatcher(queryOptionsTestDatoms()),
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	tracker := NewQueryTracker()
+	exec := NewExecutor(matcher)
+	exec.SetQueryTracker(tracker)
+
+	q, err := parser.ParseQuery(`[:find ?name :where [?e :user/name ?name]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := exec.Execute(q)
+		done <- err
+	}()
+	<-matcher.entered
+
+	// New queries are rejected, the running one is waited for
+	tracker.Close()
+	if _, err := exec.Execute(q); !errors.Is(err, ErrQueriesClosed) {
+		t.Errorf("Expected ErrQueriesClosed, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out while the query runs, got %v", err)
+	}
+
+	close(matcher.release)
+	if err := tracker.Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected the running query to complete, got %v", err)
+	}
+}
+
+func TestQueryTimeoutAbandonsRun(t *testing.T) {
+	matcher := &blockingMatcher{
+		inner:   NewMemoryPatternMatcher(queryOptionsTestDatoms()),
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	tracker := NewQueryTracker()
+	exec := NewExecutor(matcher)
+	exec.SetQueryTracker(tracker)
+
+	q, err := parser.ParseQuery(`{:query [:find ?name :where [?e :user/name ?name]] :timeout 20}`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	// The timeout is reported at the deadline, while the match still blocks
+	if _, err := exec.Execute(q); !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("Expected ErrQueryTimeout, got %v", err)
+	}
+
+	// The abandoned query stays registered until its goroutine stops
+	if active := tracker.Active(); len(active) != 1 {
+		t.Fatalf("Expected the abandoned query to be active, got %v", active)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out while the query runs, got %v", err)
+	}
+
+	close(matcher.release)
+	if err := tracker.Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+	if active := tracker.Active(); len(active) != 0 {
+		t.Errorf("Expected no active queries, got %v", active)
+	}
+}
@@ -1,7 +1,9 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -18,6 +20,55 @@ const debugAggregation = false
 // For small relations, batch aggregation is faster due to lower overhead
 const StreamingAggregationThreshold = 100
 
+// ErrUnknownAggregateColumn is wrapped by AggregateColumnError
+var ErrUnknownAggregateColumn = errors.New("aggregate column not found")
+
+// AggregateColumnError reports a :find element that refers to a symbol the
+// aggregated relation has no column for, e.g. (max ?v) over [?s ?t]
+type AggregateColumnError struct {
+	Element string         // The :find element, e.g. "(max ?v)" or "?s"
+	Symbol  query.Symbol   // The missing symbol
+	Columns []query.Symbol // The columns of the aggregated relation
+}
+
+func (e *AggregateColumnError) Error() string {
+	return fmt.Sprintf("%v: %s in %s, relation has columns %v",
+		ErrUnknownAggregateColumn, e.Symbol, e.Element, e.Columns)
+}
+
+func (e *AggregateColumnError) Unwrap() error {
+	return ErrUnknownAggregateColumn
+}
+
+// CheckAggregateColumns returns an *AggregateColumnError for the first :find
+// element whose variable, aggregate argument or aggregate predicate is not
+// one of columns
+func CheckAggregateColumns(columns []query.Symbol, findElements []query.FindElement) error {
+	has := make(map[query.Symbol]bool, len(columns))
+	for _, col := range columns {
+		has[col] = true
+	}
+
+	for _, elem := range findElements {
+		var symbols []query.Symbol
+		switch e := elem.(type) {
+		case query.FindVariable:
+			symbols = []query.Symbol{e.Symbol}
+		case query.FindAggregate:
+			symbols = []query.Symbol{e.Arg}
+			if e.IsConditional() {
+				symbols = append(symbols, e.Predicate)
+			}
+		}
+		for _, sym := range symbols {
+			if !has[sym] {
+				return &AggregateColumnError{Element: elem.String(), Symbol: sym, Columns: columns}
+			}
+		}
+	}
+	return nil
+}
+
 // ExecuteAggregations applies aggregation operations to a relation
 // This is the main entry point for aggregation logic
 func ExecuteAggregations(rel Relation, findElements []query.FindElement) Relation {
@@ -26,8 +77,14 @@ func ExecuteAggregations(rel Relation, findElements []query.FindElement) Relatio
 
 // ExecuteAggregationsWithContext applies aggregation operations with annotation support
 func ExecuteAggregationsWithContext(ctx Context, rel Relation, findElements []query.FindElement) Relation {
+	return executeAggregations(ctx, rel, findElements, rel.Options().Summation)
+}
+
+// executeAggregations applies aggregation operations, adding float values
+// with summation
+func executeAggregations(ctx Context, rel Relation, findElements []query.FindElement, summation Summation) Relation {
 	if debugAggregation {
-		fmt.Printf("[ExecuteAggregations] Called with %d find elements, rel columns: %v\n", len(findElements), rel.Columns())
+		fmt.Printf("[ExecuteAggregations] Called with %d find elements, rel columns: %v\n", len(findElements), rel.Symbols())
 		for i, elem := range findElements {
 			switch e := elem.(type) {
 			case query.FindAggregate:
@@ -122,7 +179,9 @@ func ExecuteAggregationsWithContext(ctx Context, rel Relation, findElements []qu
 			fmt.Printf("[ExecuteAggregations] Using STREAMING aggregation (groupByVars=%v)\n", groupByVars)
 		}
 		// If no group-by variables, pass empty slice (single global group)
-		return NewStreamingAggregateRelation(rel, groupByVars, aggregates)
+		streaming := NewStreamingAggregateRelation(rel, groupByVars, aggregates)
+		streaming.options.Summation = summation
+		return streaming
 	}
 
 	if opts.EnableStreamingAggregationDebug {
@@ -135,29 +194,34 @@ func ExecuteAggregationsWithContext(ctx Context, rel Relation, findElements []qu
 		if debugAggregation {
 			fmt.Printf("[ExecuteAggregations] Calling executeSingleAggregation with %d aggregates, rel.Size()=%d\n", len(aggregates), rel.Size())
 		}
-		result := executeSingleAggregation(rel, aggregates)
+		result := executeSingleAggregation(rel, aggregates, summation)
 		if debugAggregation {
-			fmt.Printf("[ExecuteAggregations] executeSingleAggregation returned: Size=%d, Columns=%v\n", result.Size(), result.Columns())
-			if result.Size() > 0 {
-				fmt.Printf("[ExecuteAggregations] First tuple: %v\n", result.Get(0))
+			fmt.Printf("[ExecuteAggregations] executeSingleAggregation returned: Size=%d, Columns=%v\n", result.Size(), result.Symbols())
+			if m, ok := result.(RandomAccessRelation); ok {
+				if first, err := m.At(0); err == nil {
+					fmt.Printf("[ExecuteAggregations] First tuple: %v\n", first)
+				}
 			}
 		}
 		return result
 	}
 
 	// Otherwise, group by the variables and aggregate within groups
-	return executeGroupedAggregation(rel, groupByVars, aggregates)
+	return executeGroupedAggregation(rel, groupByVars, aggregates, summation)
 }
 
 // isStreamingEligible checks if all aggregates can be computed in streaming fashion
 func isStreamingEligible(aggregates []query.FindAggregate) bool {
 	for _, agg := range aggregates {
 		switch agg.Function {
-		case "count", "sum", "avg", "min", "max":
+		case "count", "sum", "avg", "min", "max", "histogram":
 			// These are streamable
 			continue
+		case "percentile":
+			// Streamed as an approximation (see tDigest)
+			continue
 		default:
-			// Unsupported aggregate function (e.g., median, percentile)
+			// Unsupported aggregate function (e.g., median)
 			return false
 		}
 	}
@@ -182,7 +246,7 @@ func shouldUseStreaming(rel Relation) bool {
 }
 
 // executeSingleAggregation computes aggregates over the entire relation
-func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Relation {
+func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate, summation Summation) Relation {
 	// Collect all values for each aggregate
 	aggValues := make([][]interface{}, len(aggregates))
 	for i := range aggValues {
@@ -192,7 +256,20 @@ func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Re
 	it := rel.Iterator()
 	defer it.Close()
 
-	columns := rel.Columns()
+	columns := rel.Symbols()
+
+	// Find argument indices; an aggregate over a missing column sees no values
+	// (see CheckAggregateColumns)
+	aggIndices := make([]int, len(aggregates))
+	for i, agg := range aggregates {
+		aggIndices[i] = -1
+		for j, col := range columns {
+			if col == agg.Arg {
+				aggIndices[i] = j
+				break
+			}
+		}
+	}
 
 	// Find predicate indices for conditional aggregates
 	predicateIndices := make([]int, len(aggregates))
@@ -210,7 +287,7 @@ func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Re
 
 	for it.Next() {
 		tuple := it.Tuple()
-		for i, agg := range aggregates {
+		for i := range aggregates {
 			// Check predicate for conditional aggregates
 			predicateIdx := predicateIndices[i]
 			if predicateIdx >= 0 {
@@ -225,20 +302,9 @@ func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Re
 				}
 			}
 
-			// Predicate passed (or no predicate), find column index for this aggregate
-			found := false
-			for j, col := range columns {
-				if col == agg.Arg {
-					if j < len(tuple) {
-						aggValues[i] = append(aggValues[i], tuple[j])
-						found = true
-					}
-					break
-				}
-			}
-			// DEBUG: Log when column not found
-			if !found && len(columns) > 0 {
-				fmt.Printf("AGGREGATE BUG: Column %v not found in columns %v for aggregate %d\n", agg.Arg, columns, i)
+			// Predicate passed (or no predicate), collect value
+			if idx := aggIndices[i]; idx >= 0 && idx < len(tuple) {
+				aggValues[i] = append(aggValues[i], tuple[idx])
 			}
 		}
 	}
@@ -250,7 +316,7 @@ func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Re
 		if len(aggValues[i]) > 0 {
 			hasAnyValues = true
 		}
-		results[i] = computeAggregateValues(aggValues[i], agg.Function)
+		results[i] = computeAggregateValues(aggValues[i], agg, summation)
 	}
 
 	// Build result columns (aggregate functions as column names)
@@ -264,6 +330,9 @@ func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Re
 	// If no aggregate has any values (all predicates failed or input was empty),
 	// return empty result set instead of a row with nil values
 	opts := rel.Options()
+	if err := it.Err(); err != nil {
+		return newFailedRelation(resultColumns, fmt.Errorf("aggregation input failed: %w", err), opts)
+	}
 	if !hasAnyValues {
 		return NewMaterializedRelationWithOptions(resultColumns, []Tuple{}, opts)
 	}
@@ -272,11 +341,12 @@ func executeSingleAggregation(rel Relation, aggregates []query.FindAggregate) Re
 }
 
 // executeGroupedAggregation performs aggregation with grouping
-func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggregates []query.FindAggregate) Relation {
+func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggregates []query.FindAggregate, summation Summation) Relation {
 	// Create column mapping
-	columns := rel.Columns()
+	columns := rel.Symbols()
 	groupIndices := make([]int, len(groupByVars))
 	for i, groupVar := range groupByVars {
+		groupIndices[i] = -1 // Missing columns group as nil (see CheckAggregateColumns)
 		for j, col := range columns {
 			if col == groupVar {
 				groupIndices[i] = j
@@ -287,6 +357,7 @@ func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggrega
 
 	aggIndices := make([]int, len(aggregates))
 	for i, agg := range aggregates {
+		aggIndices[i] = -1 // Missing columns see no values
 		for j, col := range columns {
 			if col == agg.Arg {
 				aggIndices[i] = j
@@ -324,7 +395,7 @@ func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggrega
 		groupKey := ""
 		groupTuple := make(Tuple, len(groupIndices))
 		for i, idx := range groupIndices {
-			if idx < len(tuple) {
+			if idx >= 0 && idx < len(tuple) {
 				groupTuple[i] = tuple[idx]
 				groupKey += stringifyValue(tuple[idx]) + "|"
 			}
@@ -341,7 +412,7 @@ func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggrega
 
 		// Collect values for aggregation (with predicate filtering for conditional aggregates)
 		for i, idx := range aggIndices {
-			if idx < len(tuple) {
+			if idx >= 0 && idx < len(tuple) {
 				// Check predicate for conditional aggregates
 				predicateIdx := predicateIndices[i]
 				if predicateIdx >= 0 {
@@ -385,7 +456,7 @@ func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggrega
 
 		// Add aggregate results
 		for i, agg := range aggregates {
-			resultTuple[len(groupByVars)+i] = computeAggregateValues(groupValues[groupKey][i], agg.Function)
+			resultTuple[len(groupByVars)+i] = computeAggregateValues(groupValues[groupKey][i], agg, summation)
 		}
 
 		resultTuples = append(resultTuples, resultTuple)
@@ -405,6 +476,9 @@ func executeGroupedAggregation(rel Relation, groupByVars []query.Symbol, aggrega
 	}
 
 	opts := rel.Options()
+	if err := it.Err(); err != nil {
+		return newFailedRelation(resultColumns, fmt.Errorf("aggregation input failed: %w", err), opts)
+	}
 	return NewMaterializedRelationWithOptions(resultColumns, resultTuples, opts)
 }
 
@@ -431,22 +505,37 @@ func stringifyValue(v interface{}) string {
 // ============================================================================
 
 // AggregateState maintains running aggregates for a single group
-// Supports incremental updates for: sum, count, min, max, avg
+// Supports incremental updates for: sum, count, min, max, avg, percentile
+// (approximate) and histogram
 type AggregateState struct {
 	count int64
-	sum   float64
+	sum   floatSum
 	min   interface{}
 	max   interface{}
+
+	param   float64         // Percentile quantile or histogram bucket width
+	digest  *tDigest        // Percentile values
+	buckets map[int64]int64 // Histogram counts by bucket number
 }
 
-// newAggregateState creates a new aggregate state
-func newAggregateState() *AggregateState {
-	return &AggregateState{
+// newAggregateState creates a new aggregate state for agg, adding float
+// values with summation
+func newAggregateState(agg query.FindAggregate, summation Summation) *AggregateState {
+	s := &AggregateState{
 		count: 0,
-		sum:   0,
+		sum:   floatSum{mode: summation},
 		min:   nil,
 		max:   nil,
 	}
+	switch agg.Function {
+	case "percentile":
+		s.param = aggregateParam(agg)
+		s.digest = &tDigest{}
+	case "histogram":
+		s.param = aggregateParam(agg)
+		s.buckets = make(map[int64]int64)
+	}
+	return s
 }
 
 // Update incrementally updates aggregate state with a new value
@@ -462,7 +551,7 @@ func (s *AggregateState) Update(function string, value interface{}) {
 
 	case "sum", "avg":
 		if num, ok := toFloat64(value); ok {
-			s.sum += num
+			s.sum.Add(num)
 			s.count++
 		}
 
@@ -477,6 +566,18 @@ func (s *AggregateState) Update(function string, value interface{}) {
 			s.max = value
 		}
 		s.count++
+
+	case "percentile":
+		if num, ok := toFloat64(value); ok {
+			s.digest.Add(num)
+			s.count++
+		}
+
+	case "histogram":
+		if num, ok := toFloat64(value); ok {
+			s.buckets[int64(math.Floor(num/s.param))]++
+			s.count++
+		}
 	}
 }
 
@@ -490,13 +591,13 @@ func (s *AggregateState) GetResult(function string) interface{} {
 		if s.count == 0 {
 			return nil
 		}
-		return s.sum
+		return s.sum.Value()
 
 	case "avg":
 		if s.count == 0 {
 			return nil
 		}
-		return s.sum / float64(s.count)
+		return s.sum.Value() / float64(s.count)
 
 	case "min":
 		if s.count == 0 {
@@ -510,6 +611,12 @@ func (s *AggregateState) GetResult(function string) interface{} {
 		}
 		return s.max
 
+	case "percentile":
+		return s.digest.Quantile(s.param)
+
+	case "histogram":
+		return newHistogram(s.buckets, s.param)
+
 	default:
 		return nil
 	}
@@ -541,6 +648,7 @@ type StreamingAggregateRelation struct {
 	// Lazy materialization
 	materializeOnce sync.Once
 	materialized    *MaterializedRelation
+	err             error // Source error; the materialized result is then empty
 }
 
 // NewStreamingAggregateRelation creates a streaming aggregate relation
@@ -556,8 +664,8 @@ func NewStreamingAggregateRelation(source Relation, groupByVars []query.Symbol,
 	}
 }
 
-// Columns returns the output columns
-func (r *StreamingAggregateRelation) Columns() []query.Symbol {
+// Symbols returns the output columns
+func (r *StreamingAggregateRelation) Symbols() []query.Symbol {
 	resultColumns := make([]query.Symbol, len(r.groupByVars)+len(r.aggregates))
 	copy(resultColumns, r.groupByVars)
 	for i, agg := range r.aggregates {
@@ -567,8 +675,15 @@ func (r *StreamingAggregateRelation) Columns() []query.Symbol {
 	return resultColumns
 }
 
-func (r *StreamingAggregateRelation) Symbols() []query.Symbol {
-	return r.Columns()
+// Schema returns the output columns with unknown types
+func (r *StreamingAggregateRelation) Schema() RelationSchema {
+	return NewRelationSchema(r.Symbols())
+}
+
+// Columns returns the output columns.
+// Deprecated: Use Symbols or Schema.
+func (r *StreamingAggregateRelation) Columns() []query.Symbol {
+	return r.Symbols()
 }
 
 // Options returns the executor options for this streaming aggregate relation
@@ -576,15 +691,29 @@ func (r *StreamingAggregateRelation) Options() ExecutorOptions {
 	return r.options
 }
 
+// ForEach calls fn for each aggregated tuple (see Relation.ForEach)
+func (r *StreamingAggregateRelation) ForEach(fn func(Tuple) (bool, error)) error {
+	return forEachTuple(r, fn)
+}
+
 // Iterator returns an iterator over the aggregated results
 // Uses lazy materialization: aggregates are computed on first call, cached for subsequent calls
 func (r *StreamingAggregateRelation) Iterator() Iterator {
 	r.materializeOnce.Do(func() {
 		r.materialized = r.materialize()
 	})
+	if r.err != nil {
+		return &sliceIterator{pos: -1, err: r.err}
+	}
 	return r.materialized.Iterator()
 }
 
+// Err returns the error that ended reading the source, once aggregated
+func (r *StreamingAggregateRelation) Err() error {
+	r.Iterator()
+	return r.err
+}
+
 // Size returns the number of groups (only known after materialization)
 func (r *StreamingAggregateRelation) Size() int {
 	// Trigger materialization to know size
@@ -598,15 +727,26 @@ func (r *StreamingAggregateRelation) IsEmpty() bool {
 }
 
 // Get returns a specific tuple by index (requires materialization)
+//
+// Deprecated: Get aggregates the whole input on first use. Iterate with
+// Iterator() instead.
 func (r *StreamingAggregateRelation) Get(i int) Tuple {
 	r.Iterator()
 	return r.materialized.Get(i)
 }
 
-// String returns a string representation (delegates to materialized result)
+func (r *StreamingAggregateRelation) knownSize() (int, bool) {
+	if r.materialized == nil {
+		return -1, false
+	}
+	return r.materialized.Size(), true
+}
+
+// String returns a string representation. The aggregation is not run, so
+// the size is only shown once it has been.
 func (r *StreamingAggregateRelation) String() string {
-	r.Iterator()
-	return r.materialized.String()
+	size, _ := r.knownSize()
+	return relationString(r.Symbols(), size)
 }
 
 // Table returns a table representation (delegates to materialized result)
@@ -702,7 +842,7 @@ func (r *StreamingAggregateRelation) Aggregate(findElements []query.FindElement)
 // materialize performs the actual streaming aggregation
 func (r *StreamingAggregateRelation) materialize() *MaterializedRelation {
 	// Build column index mappings
-	columns := r.source.Columns()
+	columns := r.source.Symbols()
 
 	if r.options.EnableStreamingAggregationDebug {
 		fmt.Printf("[StreamingAggregateRelation.materialize] Source columns: %v\n", columns)
@@ -787,7 +927,7 @@ func (r *StreamingAggregateRelation) materialize() *MaterializedRelation {
 		if !exists {
 			states = make([]*AggregateState, len(r.aggregates))
 			for i := range states {
-				states[i] = newAggregateState()
+				states[i] = newAggregateState(r.aggregates[i], r.options.Summation)
 			}
 			groups[keyStr] = states
 			groupKeys[keyStr] = key
@@ -832,6 +972,10 @@ func (r *StreamingAggregateRelation) materialize() *MaterializedRelation {
 	if r.options.EnableStreamingAggregationDebug {
 		fmt.Printf("[StreamingAggregateRelation.materialize] Processed %d tuples, %d groups\n", tupleCount, len(groups))
 	}
+	if err := it.Err(); err != nil {
+		r.err = fmt.Errorf("aggregation input failed: %w", err)
+		return NewMaterializedRelationWithOptions(r.Symbols(), nil, r.options)
+	}
 
 	// Convert groups to result tuples
 	resultTuples := make([]Tuple, 0, len(groups))
@@ -855,5 +999,5 @@ func (r *StreamingAggregateRelation) materialize() *MaterializedRelation {
 		resultTuples = append(resultTuples, resultTuple)
 	}
 
-	return NewMaterializedRelationWithOptions(r.Columns(), resultTuples, r.options)
+	return NewMaterializedRelationWithOptions(r.Symbols(), resultTuples, r.options)
 }
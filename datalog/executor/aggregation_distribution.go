@@ -0,0 +1,214 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// Distribution aggregates: (percentile q ?x) and (histogram ?x width).
+//
+// Batch aggregation computes both exactly. Streaming aggregation keeps
+// histograms exact (bucket counts are O(buckets)) but approximates percentiles
+// with a t-digest, whose error is smallest at the tails (p99, p999) that
+// latency analysis cares about.
+
+// tDigestCompression bounds the number of centroids a t-digest keeps
+const tDigestCompression = 100
+
+// Histogram is the result of the histogram aggregate: the number of values in
+// each fixed-width bucket, in ascending bucket order. Empty buckets are omitted.
+type Histogram struct {
+	BucketWidth float64
+	Buckets     []HistogramBucket
+}
+
+// HistogramBucket counts the values in [Start, Start+BucketWidth)
+type HistogramBucket struct {
+	Start float64
+	Count int64
+}
+
+// String formats the histogram as a map of bucket start to count
+func (h Histogram) String() string {
+	parts := make([]string, len(h.Buckets))
+	for i, b := range h.Buckets {
+		parts[i] = fmt.Sprintf("%v %d", b.Start, b.Count)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// aggregateParam returns an aggregate's constant argument as a float64
+func aggregateParam(agg query.FindAggregate) float64 {
+	f, _ := toFloat64(agg.Param)
+	return f
+}
+
+// numericValues returns the numeric values as float64, skipping others
+func numericValues(values []interface{}) []float64 {
+	nums := make([]float64, 0, len(values))
+	for _, v := range values {
+		if f, ok := toFloat64(v); ok {
+			nums = append(nums, f)
+		}
+	}
+	return nums
+}
+
+// computePercentile returns the q-th quantile of the numeric values, linearly
+// interpolating between the closest ranks. Returns nil if there are none.
+func computePercentile(values []interface{}, q float64) interface{} {
+	nums := numericValues(values)
+	if len(nums) == 0 {
+		return nil
+	}
+	sort.Float64s(nums)
+
+	rank := q * float64(len(nums)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return nums[lo] + (rank-float64(lo))*(nums[hi]-nums[lo])
+}
+
+// computeHistogram counts the numeric values in buckets of the given width.
+// Returns nil if there are no numeric values.
+func computeHistogram(values []interface{}, width float64) interface{} {
+	counts := make(map[int64]int64)
+	for _, v := range values {
+		if f, ok := toFloat64(v); ok {
+			counts[int64(math.Floor(f/width))]++
+		}
+	}
+	return newHistogram(counts, width)
+}
+
+// newHistogram builds a Histogram from counts keyed by bucket number
+func newHistogram(counts map[int64]int64, width float64) interface{} {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	keys := make([]int64, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	h := Histogram{BucketWidth: width, Buckets: make([]HistogramBucket, len(keys))}
+	for i, k := range keys {
+		h.Buckets[i] = HistogramBucket{Start: float64(k) * width, Count: counts[k]}
+	}
+	return h
+}
+
+// tDigest is a merging t-digest (Dunning & Ertl) for approximate quantiles
+// in bounded memory. Values are buffered and periodically merged into at most
+// about tDigestCompression centroids, which are kept small near the tails.
+type tDigest struct {
+	centroids []tDigestCentroid
+	buffer    []float64
+	count     float64
+	min, max  float64
+}
+
+type tDigestCentroid struct {
+	mean   float64
+	weight float64
+}
+
+// Add adds a value to the digest
+func (d *tDigest) Add(x float64) {
+	if d.count == 0 || x < d.min {
+		d.min = x
+	}
+	if d.count == 0 || x > d.max {
+		d.max = x
+	}
+	d.count++
+	d.buffer = append(d.buffer, x)
+	if len(d.buffer) >= 5*tDigestCompression {
+		d.compress()
+	}
+}
+
+// compress merges buffered values into the centroids. Adjacent centroids
+// are merged while they span at most one unit of the k1 scale function
+// k(q) = δ/2π · asin(2q-1), which keeps centroids near q=0 and q=1 small.
+func (d *tDigest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+
+	all := make([]tDigestCentroid, 0, len(d.centroids)+len(d.buffer))
+	all = append(all, d.centroids...)
+	for _, x := range d.buffer {
+		all = append(all, tDigestCentroid{mean: x, weight: 1})
+	}
+	d.buffer = d.buffer[:0]
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	k := func(q float64) float64 {
+		return tDigestCompression / (2 * math.Pi) * math.Asin(2*q-1)
+	}
+
+	merged := make([]tDigestCentroid, 0, tDigestCompression)
+	cur := all[0]
+	weightSoFar := 0.0
+	kLow := k(0)
+	for _, c := range all[1:] {
+		q := (weightSoFar + cur.weight + c.weight) / d.count
+		if k(q)-kLow <= 1 {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		weightSoFar += cur.weight
+		merged = append(merged, cur)
+		kLow = k(weightSoFar / d.count)
+		cur = c
+	}
+	d.centroids = append(merged, cur)
+}
+
+// Quantile returns the approximate q-th quantile, interpolating between
+// centroid centers. While every centroid holds a single value this matches
+// computePercentile exactly.
+func (d *tDigest) Quantile(q float64) interface{} {
+	d.compress()
+	if len(d.centroids) == 0 {
+		return nil
+	}
+
+	// Rank of the quantile on the same scale as computePercentile, offset to
+	// centroid centers (the i-th single value is centered at i+0.5)
+	rank := q*(d.count-1) + 0.5
+
+	cs := d.centroids
+	if rank <= cs[0].weight/2 {
+		return interpolate(d.min, cs[0].mean, rank/(cs[0].weight/2))
+	}
+
+	cumulative := 0.0
+	for i := 0; i < len(cs)-1; i++ {
+		left := cumulative + cs[i].weight/2
+		right := cumulative + cs[i].weight + cs[i+1].weight/2
+		if rank <= right {
+			return interpolate(cs[i].mean, cs[i+1].mean, (rank-left)/(right-left))
+		}
+		cumulative += cs[i].weight
+	}
+
+	last := cs[len(cs)-1]
+	left := d.count - last.weight/2
+	if rank <= left || last.weight <= 1 {
+		return last.mean
+	}
+	return interpolate(last.mean, d.max, (rank-left)/(last.weight/2))
+}
+
+func interpolate(a, b, t float64) float64 {
+	return a + t*(b-a)
+}
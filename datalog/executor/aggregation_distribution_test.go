@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestComputePercentile(t *testing.T) {
+	var values []interface{}
+	for i := 1; i <= 100; i++ {
+		values = append(values, int64(i))
+	}
+	values = append(values, "not a number", nil)
+
+	tests := map[float64]float64{0: 1, 0.5: 50.5, 0.99: 99.01, 1: 100}
+	for q, expected := range tests {
+		got, ok := computePercentile(values, q).(float64)
+		if !ok || math.Abs(got-expected) > 1e-9 {
+			t.Errorf("percentile %v: expected %v, got %v", q, expected, got)
+		}
+	}
+
+	if got := computePercentile(nil, 0.5); got != nil {
+		t.Errorf("expected nil percentile of no values, got %v", got)
+	}
+}
+
+func TestComputeHistogram(t *testing.T) {
+	values := []interface{}{int64(-3), 0.5, int64(9), int64(10), 19.9, int64(35), "x"}
+
+	h, ok := computeHistogram(values, 10).(Histogram)
+	if !ok {
+		t.Fatalf("expected a Histogram, got %T", computeHistogram(values, 10))
+	}
+	expected := []HistogramBucket{{-10, 1}, {0, 2}, {10, 2}, {30, 1}}
+	if fmt.Sprint(h.Buckets) != fmt.Sprint(expected) {
+		t.Errorf("expected buckets %v, got %v", expected, h.Buckets)
+	}
+	if s := h.String(); s != "{-10 1, 0 2, 10 2, 30 1}" {
+		t.Errorf("unexpected histogram string %s", s)
+	}
+
+	if got := computeHistogram(nil, 10); got != nil {
+		t.Errorf("expected nil histogram of no values, got %v", got)
+	}
+}
+
+func TestTDigestQuantile(t *testing.T) {
+	// Small inputs stay uncompressed and match the exact percentile
+	small := &tDigest{}
+	var smallValues []interface{}
+	for _, v := range []float64{5, 1, 9, 3, 7} {
+		small.Add(v)
+		smallValues = append(smallValues, v)
+	}
+	for _, q := range []float64{0, 0.25, 0.5, 0.9, 1} {
+		if got, want := small.Quantile(q), computePercentile(smallValues, q); got != want {
+			t.Errorf("q=%v: expected exact %v, got %v", q, want, got)
+		}
+	}
+
+	// Large skewed inputs are approximated within a small rank error
+	rng := rand.New(rand.NewSource(1))
+	digest := &tDigest{}
+	sorted := make([]float64, 100000)
+	for i := range sorted {
+		sorted[i] = rng.ExpFloat64() * 20
+		digest.Add(sorted[i])
+	}
+	sort.Float64s(sorted)
+
+	if len(digest.centroids) > 2*tDigestCompression {
+		t.Errorf("expected at most %d centroids, got %d", 2*tDigestCompression, len(digest.centroids))
+	}
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99, 0.999} {
+		got := digest.Quantile(q).(float64)
+		rank := float64(sort.SearchFloat64s(sorted, got)) / float64(len(sorted))
+		tolerance := 0.01
+		if q > 0.98 {
+			tolerance = 0.001
+		}
+		if math.Abs(rank-q) > tolerance {
+			t.Errorf("q=%v: estimate %v has rank %v", q, got, rank)
+		}
+	}
+}
+
+func TestDistributionAggregates(t *testing.T) {
+	columns := []query.Symbol{"?service", "?latency"}
+	var tuples []Tuple
+	for i := 0; i < 1000; i++ {
+		tuples = append(tuples, Tuple{fmt.Sprintf("svc%d", i%2), int64(i)})
+	}
+	find := []query.FindElement{
+		query.FindVariable{Symbol: "?service"},
+		query.FindAggregate{Function: "percentile", Arg: "?latency", Param: 0.99},
+		query.FindAggregate{Function: "histogram", Arg: "?latency", Param: int64(250)},
+	}
+
+	for _, streaming := range []bool{false, true} {
+		t.Run(fmt.Sprintf("streaming=%v", streaming), func(t *testing.T) {
+			rel := NewMaterializedRelationWithOptions(columns, tuples, ExecutorOptions{EnableStreamingAggregation: streaming})
+			result := ExecuteAggregations(rel, find)
+
+			expectedCols := "[?service (percentile 0.99 ?latency) (histogram ?latency 250)]"
+			if fmt.Sprint(result.Columns()) != expectedCols {
+				t.Errorf("expected columns %s, got %v", expectedCols, result.Columns())
+			}
+			if result.Size() != 2 {
+				t.Fatalf("expected 2 groups, got %d", result.Size())
+			}
+
+			for i := 0; i < result.Size(); i++ {
+				tuple := result.Get(i)
+
+				// svc0 holds the even latencies 0..998, svc1 the odd ones 1..999
+				expected := 988.02
+				if tuple[0] == "svc1" {
+					expected = 989.02
+				}
+				p99 := tuple[1].(float64)
+				if (!streaming && math.Abs(p99-expected) > 1e-9) || math.Abs(p99-expected) > 2 {
+					t.Errorf("%v: expected p99 %v, got %v", tuple[0], expected, p99)
+				}
+
+				h := tuple[2].(Histogram)
+				if len(h.Buckets) != 4 {
+					t.Fatalf("%v: expected 4 buckets, got %v", tuple[0], h)
+				}
+				for _, b := range h.Buckets {
+					if b.Count != 125 {
+						t.Errorf("%v: expected 125 values per bucket, got %v", tuple[0], h)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDistributionAggregatesQuery(t *testing.T) {
+	var datoms []datalog.Datom
+	for i := 0; i < 200; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("request:%d", i))
+		datoms = append(datoms,
+			datalog.Datom{E: e, A: datalog.NewKeyword(":request/service"), V: fmt.Sprintf("svc%d", i%2), Tx: 1},
+			datalog.Datom{E: e, A: datalog.NewKeyword(":request/latency"), V: float64(i), Tx: 1},
+		)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?svc (percentile 0.5 ?latency) (histogram ?latency 100)
+	                             :where [?r :request/service ?svc]
+	                                    [?r :request/latency ?latency]
+	                             :order-by [?svc]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+			UseQueryExecutor: useQueryExecutor,
+		})
+		result, err := exec.Execute(q)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+
+		expected := "[[svc0 99 {0 50, 100 50}] [svc1 100 {0 50, 100 50}]]"
+		var rows []Tuple
+		it := result.Iterator()
+		for it.Next() {
+			rows = append(rows, it.Tuple())
+		}
+		it.Close()
+		if fmt.Sprint(rows) != expected {
+			t.Errorf("QueryExecutor=%v: expected %s, got %v", useQueryExecutor, expected, rows)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package executor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// aggregateQueryResult applies the aggregates of find to rel, at each of the
+// query's grouping sets if it has any
+func aggregateQueryResult(ctx Context, rel Relation, q *query.Query, find []query.FindElement, opts ExecutorOptions) (Relation, error) {
+	if err := checkAggregateColumns(ctx, rel, find, opts); err != nil {
+		return nil, err
+	}
+	var result Relation
+	if len(q.GroupingSets) > 0 {
+		result = executeGroupingSets(ctx, rel, find, q.GroupingSets, opts.Summation)
+	} else {
+		result = executeAggregations(ctx, rel, find, opts.Summation)
+	}
+
+	// Aggregation consumes its whole input, so a failed scan is known here
+	if err := RelationErr(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkAggregateColumns fails with an *AggregateColumnError if find refers to
+// a symbol rel has no column for, or with LenientAggregation reports it as an
+// annotation and lets the aggregation continue
+func checkAggregateColumns(ctx Context, rel Relation, find []query.FindElement, opts ExecutorOptions) error {
+	err := CheckAggregateColumns(rel.Symbols(), find)
+	if err == nil || !opts.LenientAggregation {
+		return err
+	}
+
+	if ctx != nil && ctx.Collector() != nil {
+		colErr := err.(*AggregateColumnError)
+		ctx.Collector().AddTiming("aggregation/unknown_column", time.Now(), map[string]interface{}{
+			"element": colErr.Element,
+			"symbol":  colErr.Symbol,
+			"columns": colErr.Columns,
+		})
+	}
+	return nil
+}
+
+// groupingSetGroup accumulates the values of one group of one grouping set
+type groupingSetGroup struct {
+	tuple  Tuple           // Group-by values, nil for variables outside the set
+	values [][]interface{} // Values per aggregate
+}
+
+// ExecuteGroupingSets computes the aggregates of findElements for several
+// grouping levels in a single pass over rel, e.g. per (?symbol ?day) and per
+// ?symbol for the sets [[?symbol ?day] [?symbol]].
+//
+// The result has the same columns as ExecuteAggregations: the :find variables
+// followed by the aggregates. Each grouping set contributes one row per group,
+// with nil for the :find variables it does not group on; rows are ordered by
+// grouping set, then by first appearance of the group.
+func ExecuteGroupingSets(ctx Context, rel Relation, findElements []query.FindElement, sets [][]query.Symbol) Relation {
+	return executeGroupingSets(ctx, rel, findElements, sets, rel.Options().Summation)
+}
+
+// executeGroupingSets computes grouping set aggregates, adding float values
+// with summation
+func executeGroupingSets(ctx Context, rel Relation, findElements []query.FindElement, sets [][]query.Symbol, summation Summation) Relation {
+	var groupByVars []query.Symbol
+	var aggregates []query.FindAggregate
+	for _, elem := range findElements {
+		switch e := elem.(type) {
+		case query.FindVariable:
+			groupByVars = append(groupByVars, e.Symbol)
+		case query.FindAggregate:
+			aggregates = append(aggregates, e)
+		}
+	}
+
+	columns := rel.Symbols()
+	indexOf := func(sym query.Symbol) int {
+		for j, col := range columns {
+			if col == sym {
+				return j
+			}
+		}
+		return -1
+	}
+
+	groupIndices := make([]int, len(groupByVars))
+	for i, v := range groupByVars {
+		groupIndices[i] = indexOf(v)
+	}
+	aggIndices := make([]int, len(aggregates))
+	predicateIndices := make([]int, len(aggregates))
+	for i, agg := range aggregates {
+		aggIndices[i] = indexOf(agg.Arg)
+		predicateIndices[i] = -1 // -1 means no predicate (unconditional)
+		if agg.IsConditional() {
+			predicateIndices[i] = indexOf(agg.Predicate)
+		}
+	}
+
+	// Positions in groupByVars grouped on by each set
+	setPositions := make([][]int, len(sets))
+	for s, set := range sets {
+		for _, sym := range set {
+			for i, v := range groupByVars {
+				if v == sym {
+					setPositions[s] = append(setPositions[s], i)
+					break
+				}
+			}
+		}
+	}
+
+	groups := make([]map[string]*groupingSetGroup, len(sets))
+	order := make([][]string, len(sets))
+	for s := range sets {
+		groups[s] = make(map[string]*groupingSetGroup)
+	}
+
+	it := rel.Iterator()
+	defer it.Close()
+
+	for it.Next() {
+		tuple := it.Tuple()
+
+		// Collect the aggregate values once, shared by every grouping set
+		values := make([]interface{}, len(aggregates))
+		include := make([]bool, len(aggregates))
+		for i, idx := range aggIndices {
+			if idx < 0 || idx >= len(tuple) {
+				continue
+			}
+			if aggregates[i].IsConditional() {
+				// Conditional aggregate - predicate must be a boolean and true
+				predicateIdx := predicateIndices[i]
+				if predicateIdx < 0 || predicateIdx >= len(tuple) {
+					continue
+				}
+				if pred, ok := tuple[predicateIdx].(bool); !ok || !pred {
+					continue
+				}
+			}
+			values[i] = tuple[idx]
+			include[i] = true
+		}
+
+		for s, positions := range setPositions {
+			groupKey := ""
+			for _, pos := range positions {
+				if idx := groupIndices[pos]; idx >= 0 && idx < len(tuple) {
+					groupKey += stringifyValue(tuple[idx]) + "|"
+				}
+			}
+
+			group, exists := groups[s][groupKey]
+			if !exists {
+				group = &groupingSetGroup{
+					tuple:  make(Tuple, len(groupByVars)),
+					values: make([][]interface{}, len(aggregates)),
+				}
+				for _, pos := range positions {
+					if idx := groupIndices[pos]; idx >= 0 && idx < len(tuple) {
+						group.tuple[pos] = tuple[idx]
+					}
+				}
+				groups[s][groupKey] = group
+				order[s] = append(order[s], groupKey)
+			}
+
+			for i := range aggregates {
+				if include[i] {
+					group.values[i] = append(group.values[i], values[i])
+				}
+			}
+		}
+	}
+
+	var resultTuples []Tuple
+	for s := range sets {
+		for _, groupKey := range order[s] {
+			group := groups[s][groupKey]
+
+			// Relational theory: groups whose aggregates have no values
+			// (all filtered by predicates) are excluded
+			hasAnyValues := false
+			for i := range aggregates {
+				if len(group.values[i]) > 0 {
+					hasAnyValues = true
+					break
+				}
+			}
+			if !hasAnyValues {
+				continue
+			}
+
+			resultTuple := make(Tuple, len(groupByVars)+len(aggregates))
+			copy(resultTuple, group.tuple)
+			for i, agg := range aggregates {
+				resultTuple[len(groupByVars)+i] = computeAggregateValues(group.values[i], agg, summation)
+			}
+			resultTuples = append(resultTuples, resultTuple)
+		}
+	}
+
+	if ctx != nil && ctx.Collector() != nil {
+		data := ctx.Collector().GetDataMap()
+		data["aggregate_count"] = len(aggregates)
+		data["groupby_count"] = len(groupByVars)
+		data["groupby_vars"] = groupByVars
+		data["grouping_sets"] = len(sets)
+		data["aggregation_mode"] = "grouping-sets"
+		ctx.Collector().AddTiming("aggregation/executed", time.Now(), data)
+	}
+
+	resultColumns := make([]query.Symbol, len(groupByVars)+len(aggregates))
+	copy(resultColumns, groupByVars)
+	for i, agg := range aggregates {
+		resultColumns[len(groupByVars)+i] = query.Symbol(agg.String())
+	}
+	if err := it.Err(); err != nil {
+		return newFailedRelation(resultColumns, fmt.Errorf("aggregation input failed: %w", err), rel.Options())
+	}
+	return NewMaterializedRelationWithOptions(resultColumns, resultTuples, rel.Options())
+}
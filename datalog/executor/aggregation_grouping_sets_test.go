@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestExecuteGroupingSets(t *testing.T) {
+	columns := []query.Symbol{"?sym", "?day", "?vol"}
+	rel := NewMaterializedRelation(columns, []Tuple{
+		{"AAPL", int64(1), int64(10)},
+		{"AAPL", int64(1), int64(20)},
+		{"AAPL", int64(2), int64(30)},
+		{"MSFT", int64(1), int64(40)},
+	})
+	find := []query.FindElement{
+		query.FindVariable{Symbol: "?sym"},
+		query.FindVariable{Symbol: "?day"},
+		query.FindAggregate{Function: "sum", Arg: "?vol"},
+		query.FindAggregate{Function: "count", Arg: "?vol"},
+	}
+	sets := [][]query.Symbol{{"?sym", "?day"}, {"?sym"}, {}}
+
+	result := ExecuteGroupingSets(nil, rel, find, sets)
+
+	if fmt.Sprint(result.Columns()) != "[?sym ?day (sum ?vol) (count ?vol)]" {
+		t.Errorf("unexpected columns %v", result.Columns())
+	}
+	expected := []string{
+		"[AAPL 1 30 2]", "[AAPL 2 30 1]", "[MSFT 1 40 1]", // per (symbol, day)
+		"[AAPL <nil> 60 3]", "[MSFT <nil> 40 1]", // per symbol
+		"[<nil> <nil> 100 4]", // grand total
+	}
+	if result.Size() != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %v", len(expected), result.Size(), result)
+	}
+	for i, row := range expected {
+		if got := fmt.Sprint(result.Get(i)); got != row {
+			t.Errorf("row %d: expected %s, got %s", i, row, got)
+		}
+	}
+
+	// Empty input produces no rows, not even a grand total
+	empty := ExecuteGroupingSets(nil, NewMaterializedRelation(columns, nil), find, sets)
+	if empty.Size() != 0 {
+		t.Errorf("expected no rows for empty input, got %v", empty)
+	}
+}
+
+func TestRollupQuery(t *testing.T) {
+	var datoms []datalog.Datom
+	for i := 0; i < 12; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("bar:%d", i))
+		datoms = append(datoms,
+			datalog.Datom{E: e, A: datalog.NewKeyword(":bar/symbol"), V: []string{"AAPL", "MSFT"}[i%2], Tx: 1},
+			datalog.Datom{E: e, A: datalog.NewKeyword(":bar/day"), V: int64(i % 3), Tx: 1},
+			datalog.Datom{E: e, A: datalog.NewKeyword(":bar/volume"), V: int64(i), Tx: 1},
+		)
+	}
+
+	q, err := parser.ParseQuery(`{:query [:find ?sym ?day (sum ?vol)
+	                                      :where [?b :bar/symbol ?sym]
+	                                             [?b :bar/day ?day]
+	                                             [?b :bar/volume ?vol]
+	                                      :order-by [?sym ?day]]
+	                              :rollup true}`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	expected := "[[<nil> <nil> 66] [AAPL <nil> 30] [AAPL 0 6] [AAPL 1 14] [AAPL 2 10] " +
+		"[MSFT <nil> 36] [MSFT 0 12] [MSFT 1 8] [MSFT 2 16]]"
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+			UseQueryExecutor: useQueryExecutor,
+		})
+		result, err := exec.Execute(q)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+
+		var rows []Tuple
+		it := result.Iterator()
+		for it.Next() {
+			rows = append(rows, it.Tuple())
+		}
+		it.Close()
+		if fmt.Sprint(rows) != expected {
+			t.Errorf("QueryExecutor=%v: expected %s, got %v", useQueryExecutor, expected, rows)
+		}
+	}
+}
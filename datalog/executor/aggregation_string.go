@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// computeStringAgg joins the values of (string-agg ?x separator) into one
+// string. Values are joined in value order (descending for :desc) rather than
+// arrival order, which varies with join and parallel execution, so the result
+// is deterministic; :order-by orders the result rows as usual. Returns nil if
+// there are no values.
+func computeStringAgg(values []interface{}, agg query.FindAggregate) interface{} {
+	sorted := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if v != nil {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		cmp := datalog.CompareValues(sorted[i], sorted[j])
+		if agg.Descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	parts := make([]string, len(sorted))
+	for i, v := range sorted {
+		parts[i] = stringAggElement(v)
+	}
+	separator, _ := agg.Param.(string)
+	return strings.Join(parts, separator)
+}
+
+// stringAggElement formats a value for string-agg
+func stringAggElement(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case *datalog.Identity:
+		return val.String()
+	case *datalog.Keyword:
+		return val.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestComputeStringAgg(t *testing.T) {
+	values := []interface{}{"carol", nil, "alice", "bob"}
+
+	asc := query.FindAggregate{Function: "string-agg", Arg: "?name", Param: ", "}
+	if got := computeAggregateValues(values, asc, SummationFast); got != "alice, bob, carol" {
+		t.Errorf("expected ascending join, got %v", got)
+	}
+
+	desc := query.FindAggregate{Function: "string-agg", Arg: "?name", Param: "|", Descending: true}
+	if got := computeAggregateValues(values, desc, SummationFast); got != "carol|bob|alice" {
+		t.Errorf("expected descending join, got %v", got)
+	}
+
+	if got := computeAggregateValues([]interface{}{int64(10), int64(9)}, asc, SummationFast); got != "9, 10" {
+		t.Errorf("expected numbers joined in numeric order, got %v", got)
+	}
+	if got := computeAggregateValues([]interface{}{nil}, asc, SummationFast); got != nil {
+		t.Errorf("expected nil without values, got %v", got)
+	}
+}
+
+func TestStringAggQuery(t *testing.T) {
+	var datoms []datalog.Datom
+	for i, name := range []string{"dave", "alice", "erin", "carol", "bob", "frank"} {
+		e := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		datoms = append(datoms,
+			datalog.Datom{E: e, A: datalog.NewKeyword(":person/team"), V: fmt.Sprintf("team%d", i%2), Tx: 1},
+			datalog.Datom{E: e, A: datalog.NewKeyword(":person/name"), V: name, Tx: 1},
+		)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?team (string-agg ?name ", ") (string-agg ?name "/" :desc)
+	                             :where [?p :person/team ?team]
+	                                    [?p :person/name ?name]
+	                             :order-by [?team]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for _, streaming := range []bool{false, true} {
+			exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+				UseQueryExecutor:           useQueryExecutor,
+				EnableStreamingAggregation: streaming,
+			})
+			result, err := exec.Execute(q)
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+
+			expected := "[[team0 bob, dave, erin erin/dave/bob] [team1 alice, carol, frank frank/carol/alice]]"
+			var rows []Tuple
+			it := result.Iterator()
+			for it.Next() {
+				rows = append(rows, it.Tuple())
+			}
+			it.Close()
+			if fmt.Sprint(rows) != expected {
+				t.Errorf("QueryExecutor=%v streaming=%v: expected %s, got %v", useQueryExecutor, streaming, expected, rows)
+			}
+		}
+	}
+}
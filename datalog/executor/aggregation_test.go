@@ -1,9 +1,12 @@
 package executor
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/wbrown/janus-datalog/datalog/annotations"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -353,3 +356,72 @@ func TestEmptyRelationAggregation(t *testing.T) {
 		t.Fatalf("expected 0 rows (empty result), got %d", result.Size())
 	}
 }
+
+func TestUnknownAggregateColumn(t *testing.T) {
+	rel := NewMaterializedRelation(
+		[]query.Symbol{"?s", "?t"},
+		[]Tuple{{"a", 1}, {"a", 2}, {"b", 3}},
+	)
+	q := &query.Query{}
+
+	t.Run("check", func(t *testing.T) {
+		tests := []struct {
+			find    []query.FindElement
+			element string
+			symbol  query.Symbol
+		}{
+			{find: []query.FindElement{query.FindVariable{Symbol: "?s"}, query.FindAggregate{Function: "max", Arg: "?t"}}},
+			{find: []query.FindElement{query.FindAggregate{Function: "max", Arg: "?v"}}, element: "(max ?v)", symbol: "?v"},
+			{find: []query.FindElement{query.FindVariable{Symbol: "?x"}, query.FindAggregate{Function: "count", Arg: "?t"}}, element: "?x", symbol: "?x"},
+			{find: []query.FindElement{query.FindAggregate{Function: "min", Arg: "?t", Predicate: "?ok"}}, element: "(min ?t)", symbol: "?ok"},
+		}
+		for _, tt := range tests {
+			err := CheckAggregateColumns(rel.Columns(), tt.find)
+			if tt.symbol == "" {
+				if err != nil {
+					t.Errorf("%v: unexpected error %v", tt.find, err)
+				}
+				continue
+			}
+
+			var colErr *AggregateColumnError
+			if !errors.As(err, &colErr) || !errors.Is(err, ErrUnknownAggregateColumn) {
+				t.Fatalf("%v: expected an AggregateColumnError, got %v", tt.find, err)
+			}
+			if colErr.Element != tt.element || colErr.Symbol != tt.symbol || len(colErr.Columns) != 2 {
+				t.Errorf("%v: got element %s, symbol %s, columns %v", tt.find, colErr.Element, colErr.Symbol, colErr.Columns)
+			}
+		}
+	})
+
+	find := []query.FindElement{query.FindVariable{Symbol: "?s"}, query.FindAggregate{Function: "count", Arg: "?v"}}
+
+	t.Run("strict", func(t *testing.T) {
+		result, err := aggregateQueryResult(NewContext(nil), rel, q, find, ExecutorOptions{})
+		if !errors.Is(err, ErrUnknownAggregateColumn) || result != nil {
+			t.Fatalf("Expected an unknown column error, got %v, %v", result, err)
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		var event *annotations.Event
+		handler := func(e annotations.Event) {
+			if e.Name == "aggregation/unknown_column" {
+				event = &e
+			}
+		}
+		lenient := append(find, query.FindAggregate{Function: "max", Arg: "?t"})
+		result, err := aggregateQueryResult(NewContext(handler), rel, q, lenient, ExecutorOptions{LenientAggregation: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if event == nil || event.Data["symbol"] != query.Symbol("?v") {
+			t.Fatalf("Expected an unknown column annotation for ?v, got %v", event)
+		}
+
+		// (count ?v) sees no values instead of another column's
+		if got := fmt.Sprint(result.Sorted()); got != "[[a 0 2] [b 0 3]]" {
+			t.Errorf("Expected [[a 0 2] [b 0 3]], got %s", got)
+		}
+	})
+}
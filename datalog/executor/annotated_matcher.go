@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -56,12 +57,12 @@ func (m *AnnotatedMatcher) Match(pattern *query.DataPattern, bindings Relations)
 		// Find best binding relation for context
 		bindingRel := bindings.FindBestForPattern(pattern)
 		if bindingRel != nil {
-			bindingCols := bindingRel.Columns()
+			bindingCols := bindingRel.Symbols()
 			bindingColumns = make([]string, len(bindingCols))
 			for i, col := range bindingCols {
 				bindingColumns[i] = string(col)
 			}
-			bindingSize = bindingRel.Size()
+			bindingSize = annotationSize(bindingRel)
 		}
 	}
 
@@ -69,33 +70,8 @@ func (m *AnnotatedMatcher) Match(pattern *query.DataPattern, bindings Relations)
 	result, err := m.underlying.Match(pattern, bindings)
 
 	// Record completion with grouped metrics
-	data := m.collector.GetDataMap()
-	data["pattern"] = pattern.String()
-	data["match.count"] = 0
-	data["success"] = err == nil
-
-	// Add binding information if it was present
-	if len(bindingColumns) > 0 {
-		data["binding.columns"] = bindingColumns
-		data["binding.size"] = bindingSize
-	}
-
-	if result != nil {
-		data["match.count"] = result.Size()
-
-		// Add symbol order information for rendering
-		symbolOrder := make([]string, len(result.Columns()))
-		for i, col := range result.Columns() {
-			symbolOrder[i] = string(col)
-		}
-		data["symbol.order"] = symbolOrder
-	}
-
-	if err != nil {
-		data["error"] = err.Error()
-	}
-
-	m.collector.AddTiming(annotations.MatchesToRelations, start, data)
+	m.collector.AddPayloadTiming(annotations.MatchesToRelations, start,
+		matchEvent(pattern, bindingColumns, bindingSize, result, err))
 
 	return result, err
 }
@@ -118,12 +94,12 @@ func (m *AnnotatedMatcher) MatchWithConstraints(
 		if bindings != nil && len(bindings) > 0 {
 			bindingRel := bindings.FindBestForPattern(pattern)
 			if bindingRel != nil {
-				bindingCols := bindingRel.Columns()
+				bindingCols := bindingRel.Symbols()
 				bindingColumns = make([]string, len(bindingCols))
 				for i, col := range bindingCols {
 					bindingColumns[i] = string(col)
 				}
-				bindingSize = bindingRel.Size()
+				bindingSize = annotationSize(bindingRel)
 			}
 		}
 
@@ -131,39 +107,78 @@ func (m *AnnotatedMatcher) MatchWithConstraints(
 		result, err := pm.MatchWithConstraints(pattern, bindings, constraints)
 
 		// Record completion
-		data := m.collector.GetDataMap()
-		data["pattern"] = pattern.String()
-		data["constraint.count"] = len(constraints)
-		data["match.count"] = 0
-		data["success"] = err == nil
-
-		// Add binding information if it was present
-		if len(bindingColumns) > 0 {
-			data["binding.columns"] = bindingColumns
-			data["binding.size"] = bindingSize
-		}
+		event := matchEvent(pattern, bindingColumns, bindingSize, result, err)
+		event.Constrained = true
+		event.ConstraintCount = len(constraints)
+		m.collector.AddPayloadTiming(annotations.MatchesToRelations, start, event)
 
-		if result != nil {
-			data["match.count"] = result.Size()
+		return result, err
+	}
 
-			symbolOrder := make([]string, len(result.Columns()))
-			for i, col := range result.Columns() {
-				symbolOrder[i] = string(col)
-			}
-			data["symbol.order"] = symbolOrder
-		}
+	// Fall back to regular Match if constraints not supported
+	return m.Match(pattern, bindings)
+}
 
-		if err != nil {
-			data["error"] = err.Error()
-		}
+// MatchWithIndex implements IndexPinnedMatcher if the underlying matcher supports it.
+func (m *AnnotatedMatcher) MatchWithIndex(pattern *query.DataPattern, index planner.IndexType) (Relation, error) {
+	ipm, ok := m.underlying.(IndexPinnedMatcher)
+	if !ok {
+		// Fall back to regular Match if index pinning not supported
+		return m.Match(pattern, nil)
+	}
 
-		m.collector.AddTiming(annotations.MatchesToRelations, start, data)
+	start := time.Now()
+	result, err := ipm.MatchWithIndex(pattern, index)
 
-		return result, err
+	event := matchEvent(pattern, nil, 0, result, err)
+	event.Index = index.String()
+	m.collector.AddPayloadTiming(annotations.MatchesToRelations, start, event)
+
+	return result, err
+}
+
+// MatchOrdered implements OrderedMatcher if the underlying matcher supports it.
+func (m *AnnotatedMatcher) MatchOrdered(pattern *query.DataPattern, descending bool) (Relation, error) {
+	om, ok := m.underlying.(OrderedMatcher)
+	if !ok {
+		return nil, ErrOrderedScanUnsupported
 	}
 
-	// Fall back to regular Match if constraints not supported
-	return m.Match(pattern, bindings)
+	start := time.Now()
+	result, err := om.MatchOrdered(pattern, descending)
+	if err == ErrOrderedScanUnsupported {
+		return nil, err
+	}
+
+	m.collector.AddPayloadTiming(annotations.MatchesToRelations, start, matchEvent(pattern, nil, 0, result, err))
+
+	return result, err
+}
+
+// matchEvent builds the MatchesToRelations payload for a completed match
+func matchEvent(pattern *query.DataPattern, bindingColumns []string, bindingSize int, result Relation, err error) annotations.MatchEvent {
+	event := annotations.MatchEvent{
+		Pattern:        pattern.String(),
+		Success:        err == nil,
+		BindingColumns: bindingColumns,
+		BindingSize:    bindingSize,
+	}
+
+	if result != nil {
+		event.MatchCount = annotationSize(result)
+
+		// Add symbol order information for rendering
+		event.SymbolOrder = make([]string, len(result.Symbols()))
+		for i, col := range result.Symbols() {
+			event.SymbolOrder[i] = string(col)
+		}
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	return event
 }
 
 // Collector returns the underlying collector for context integration.
@@ -409,3 +409,57 @@ func BenchmarkWrapMatcher_Overhead(b *testing.B) {
 		}
 	})
 }
+
+func TestWrapMatcher_TypedPayload(t *testing.T) {
+	mock := &testPredicateMatcher{
+		testMatcher: testMatcher{
+			matchResult: NewMaterializedRelation(
+				[]query.Symbol{"?x", "?v"},
+				[]Tuple{{1, "a"}, {2, "b"}},
+			),
+		},
+	}
+
+	var matches []annotations.MatchEvent
+	var generic int
+	handler := &annotations.TypedHandler{
+		OnMatch: func(e annotations.Event, m annotations.MatchEvent) {
+			matches = append(matches, m)
+		},
+		Generic: func(e annotations.Event) {
+			generic++
+		},
+	}
+
+	wrapped := WrapMatcher(mock, handler.Handle)
+
+	pattern := &query.DataPattern{
+		Elements: []query.PatternElement{
+			query.Variable{Name: "?x"},
+			query.Constant{Value: datalog.NewKeyword(":attr")},
+			query.Variable{Name: "?v"},
+		},
+	}
+
+	if _, err := wrapped.(PredicateAwareMatcher).MatchWithConstraints(pattern, nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(matches) != 1 || generic != 0 {
+		t.Fatalf("Expected 1 typed match event and no generic events, got %d typed, %d generic", len(matches), generic)
+	}
+
+	m := matches[0]
+	if m.MatchCount != 2 || !m.Success || !m.Constrained || m.ConstraintCount != 0 {
+		t.Errorf("Unexpected match event: %+v", m)
+	}
+	if len(m.SymbolOrder) != 2 || m.SymbolOrder[0] != "?x" || m.SymbolOrder[1] != "?v" {
+		t.Errorf("Expected symbol order [?x ?v], got %v", m.SymbolOrder)
+	}
+
+	// Events without a typed payload fall through to the generic handler
+	handler.Handle(annotations.Event{Name: annotations.QueryInvoked})
+	if generic != 1 {
+		t.Errorf("Expected untyped event to reach the generic handler, got %d", generic)
+	}
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// matchAttributeSet matches a pattern restricted to an attribute set (see
+// query.DataPattern.Attributes) once per attribute, with the attribute as a
+// constant so each match scans only that attribute, and concatenates the
+// matches with the attribute filled back in as its variable's column.
+//
+// It returns false if the pattern isn't restricted, or if the bindings
+// already bind the attribute variable; the pattern is then matched as usual
+// and the in predicate filters the attribute.
+func matchAttributeSet(matcher PatternMatcher, pattern *query.DataPattern, bindings Relations, opts ExecutorOptions) (Relation, bool, error) {
+	if len(pattern.Attributes) == 0 {
+		return nil, false, nil
+	}
+	attr, ok := pattern.GetA().(query.Variable)
+	if !ok {
+		return nil, false, nil
+	}
+	for _, rel := range bindings {
+		for _, col := range rel.Symbols() {
+			if col == attr.Name {
+				return nil, false, nil
+			}
+		}
+	}
+
+	columns := pattern.Symbols()
+	iterators := make([]Iterator, 0, len(pattern.Attributes))
+	for _, kw := range pattern.Attributes {
+		elements := make([]query.PatternElement, len(pattern.Elements))
+		for i, elem := range pattern.Elements {
+			if v, ok := elem.(query.Variable); ok && v.Name == attr.Name {
+				elements[i] = query.Constant{Value: kw}
+			} else {
+				elements[i] = elem
+			}
+		}
+		// Copy the pattern so its hints (MaxDatoms) carry over to each match
+		sub := *pattern
+		sub.Elements = elements
+		sub.Attributes = nil
+		rel, err := matcher.Match(&sub, bindings)
+		if err != nil {
+			for _, it := range iterators {
+				it.Close()
+			}
+			return nil, true, err
+		}
+
+		// Map the match's columns to the pattern's, -1 for the attribute
+		positions := make([]int, len(columns))
+		for i, col := range columns {
+			positions[i] = -1
+			if col != attr.Name {
+				positions[i] = ColumnIndex(rel, col)
+			}
+		}
+		value := kw
+		iterators = append(iterators, NewTransformIterator(rel.Iterator(), func(tuple Tuple) Tuple {
+			out := make(Tuple, len(positions))
+			for i, pos := range positions {
+				if pos < 0 {
+					out[i] = value
+				} else {
+					out[i] = tuple[pos]
+				}
+			}
+			return out
+		}))
+	}
+	return NewStreamingRelationWithOptions(columns, NewConcatIterator(iterators...), opts), true, nil
+}
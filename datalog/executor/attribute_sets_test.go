@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// patternRecordingMatcher records the patterns that reach the underlying matcher
+type patternRecordingMatcher struct {
+	PatternMatcher
+	patterns []*query.DataPattern
+}
+
+func (m *patternRecordingMatcher) Match(pattern *query.DataPattern, bindings Relations) (Relation, error) {
+	m.patterns = append(m.patterns, pattern)
+	return m.PatternMatcher.Match(pattern, bindings)
+}
+
+func TestMatchAttributeSetKeepsHints(t *testing.T) {
+	name := datalog.NewKeyword(":person/name")
+	email := datalog.NewKeyword(":person/email")
+	alice := datalog.NewIdentity("person:alice")
+	matcher := &patternRecordingMatcher{PatternMatcher: NewMemoryPatternMatcher([]datalog.Datom{
+		{E: alice, A: name, V: "Alice", Tx: 1},
+		{E: alice, A: email, V: "alice@example.com", Tx: 1},
+	})}
+
+	pattern := &query.DataPattern{
+		Elements: []query.PatternElement{
+			query.Variable{Name: "?e"},
+			query.Variable{Name: "?a"},
+			query.Variable{Name: "?v"},
+		},
+		MaxDatoms:  10,
+		Attributes: []datalog.Keyword{name, email},
+	}
+
+	rel, ok, err := matchAttributeSet(matcher, pattern, nil, ExecutorOptions{})
+	if !ok || err != nil {
+		t.Fatalf("Expected the attribute set to be matched, got ok=%v err=%v", ok, err)
+	}
+	if n := len(rel.Materialize().Sorted()); n != 2 {
+		t.Errorf("Expected 2 tuples, got %d", n)
+	}
+
+	if len(matcher.patterns) != 2 {
+		t.Fatalf("Expected one match per attribute, got %d", len(matcher.patterns))
+	}
+	for _, p := range matcher.patterns {
+		if p.MaxDatoms != 10 {
+			t.Errorf("Expected %s to keep :max-datoms 10, got %d", p, p.MaxDatoms)
+		}
+		if len(p.Attributes) != 0 {
+			t.Errorf("Expected %s to have a constant attribute and no set, got %v", p, p.Attributes)
+		}
+		if _, ok := p.GetA().(query.Constant); !ok {
+			t.Errorf("Expected %s to match a constant attribute", p)
+		}
+	}
+}
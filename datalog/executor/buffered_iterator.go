@@ -11,7 +11,8 @@ type BufferedIterator struct {
 	buffer   []Tuple
 	position int
 	mu       sync.Mutex
-	consumed bool // true after source has been fully consumed
+	consumed bool  // true after source has been fully consumed
+	err      error // source's error, recorded when it was consumed
 }
 
 // NewBufferedIterator creates a new buffered iterator
@@ -52,6 +53,7 @@ func (it *BufferedIterator) Next() bool {
 
 	// Source is exhausted
 	it.consumed = true
+	it.err = it.source.Err()
 	it.source.Close()
 	return false
 }
@@ -85,6 +87,13 @@ func (it *BufferedIterator) Close() error {
 	return nil
 }
 
+// Err returns the source's error once it has been consumed
+func (it *BufferedIterator) Err() error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.err
+}
+
 // Size returns the number of tuples (requires full consumption)
 func (it *BufferedIterator) Size() int {
 	it.mu.Lock()
@@ -102,6 +111,7 @@ func (it *BufferedIterator) Size() int {
 			it.buffer = append(it.buffer, tupleCopy)
 		}
 		it.consumed = true
+		it.err = it.source.Err()
 		it.source.Close()
 		it.position = oldPos // Restore position
 	}
@@ -136,6 +146,7 @@ func (it *BufferedIterator) IsEmpty() bool {
 
 	// Source is empty
 	it.consumed = true
+	it.err = it.source.Err()
 	it.source.Close()
 	return true
 }
@@ -155,6 +166,7 @@ func (it *BufferedIterator) Clone() Iterator {
 			it.buffer = append(it.buffer, tupleCopy)
 		}
 		it.consumed = true
+		it.err = it.source.Err()
 		it.source.Close()
 	}
 
@@ -162,6 +174,7 @@ func (it *BufferedIterator) Clone() Iterator {
 	return &bufferedSliceIterator{
 		tuples:   it.buffer,
 		position: -1,
+		err:      it.err,
 	}
 }
 
@@ -169,6 +182,7 @@ func (it *BufferedIterator) Clone() Iterator {
 type bufferedSliceIterator struct {
 	tuples   []Tuple
 	position int
+	err      error
 }
 
 func (it *bufferedSliceIterator) Next() bool {
@@ -186,3 +200,7 @@ func (it *bufferedSliceIterator) Tuple() Tuple {
 func (it *bufferedSliceIterator) Close() error {
 	return nil
 }
+
+func (it *bufferedSliceIterator) Err() error {
+	return it.err
+}
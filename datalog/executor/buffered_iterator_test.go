@@ -258,6 +258,10 @@ func (it *countingMockIterator) Close() error {
 	return nil
 }
 
+func (it *countingMockIterator) Err() error {
+	return nil
+}
+
 func TestStreamingRelationWithBuffering(t *testing.T) {
 	// Test that StreamingRelation uses auto-materialization for multiple iterations
 	// EnableTrueStreaming=false allows multiple Iterator() calls via materialization
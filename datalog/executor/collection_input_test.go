@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// collectionInputDatoms returns n symbols, each with a price
+func collectionInputDatoms(n int) []datalog.Datom {
+	symbolAttr := datalog.NewKeyword(":stock/symbol")
+	priceAttr := datalog.NewKeyword(":stock/price")
+
+	var datoms []datalog.Datom
+	for i := 0; i < n; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("stock:%d", i))
+		datoms = append(datoms,
+			datalog.Datom{E: e, A: symbolAttr, V: fmt.Sprintf("SYM%04d", i), Tx: 1},
+			datalog.Datom{E: e, A: priceAttr, V: int64(i), Tx: 1},
+		)
+	}
+	return datoms
+}
+
+func collectionSymbols(from, to int) []interface{} {
+	var values []interface{}
+	for i := from; i < to; i++ {
+		values = append(values, fmt.Sprintf("SYM%04d", i))
+	}
+	return values
+}
+
+func TestExpandCollection(t *testing.T) {
+	if got := expandCollection([]interface{}{"a", "b", "c"}); len(got) != 3 || got[2][0] != "c" {
+		t.Errorf("Expected 3 rows from []interface{}, got %v", got)
+	}
+	if got := expandCollection([]string{"a", "b"}); len(got) != 2 || got[1][0] != "b" {
+		t.Errorf("Expected 2 rows from []string, got %v", got)
+	}
+	if got := expandCollection([2]int64{1, 2}); len(got) != 2 || got[0][0] != int64(1) {
+		t.Errorf("Expected 2 rows from array, got %v", got)
+	}
+	if got := expandCollection([]interface{}{}); len(got) != 0 {
+		t.Errorf("Expected no rows from empty collection, got %v", got)
+	}
+	if got := expandCollection("a"); len(got) != 1 || got[0][0] != "a" {
+		t.Errorf("Expected scalar to be a collection of one, got %v", got)
+	}
+	if got := expandCollection([]byte{1, 2}); len(got) != 1 {
+		t.Errorf("Expected []byte to be a single value, got %d rows", len(got))
+	}
+}
+
+func TestCollectionInputTopLevel(t *testing.T) {
+	datoms := collectionInputDatoms(1000)
+
+	q, err := parser.ParseQuery(`[:find ?sym ?price
+	                              :in $ [?sym ...]
+	                              :where [?e :stock/symbol ?sym]
+	                                     [?e :stock/price ?price]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	wanted := collectionSymbols(100, 400)
+
+	// One row per element, and a single row carrying the whole collection
+	rows := make([]Tuple, len(wanted))
+	for i, v := range wanted {
+		rows[i] = Tuple{v}
+	}
+	inputs := map[string]Relation{
+		"rows":  NewMaterializedRelation([]query.Symbol{"?sym"}, rows),
+		"slice": NewMaterializedRelation([]query.Symbol{"?sym"}, []Tuple{{wanted}}),
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for name, input := range inputs {
+			t.Run(fmt.Sprintf("QueryExecutor=%v/%s", useQueryExecutor, name), func(t *testing.T) {
+				exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+					UseQueryExecutor: useQueryExecutor,
+				})
+
+				result, err := exec.ExecuteWithRelations(NewContext(nil), q, []Relation{input})
+				if err != nil {
+					t.Fatalf("query failed: %v", err)
+				}
+				if result.Size() != len(wanted) {
+					t.Fatalf("Expected %d results, got %d", len(wanted), result.Size())
+				}
+
+				it := result.Iterator()
+				defer it.Close()
+				for it.Next() {
+					tuple := it.Tuple()
+					price := tuple[1].(int64)
+					if price < 100 || price >= 400 || tuple[0] != fmt.Sprintf("SYM%04d", price) {
+						t.Fatalf("Unexpected result tuple %v", tuple)
+					}
+				}
+			})
+		}
+
+		t.Run(fmt.Sprintf("QueryExecutor=%v/empty", useQueryExecutor), func(t *testing.T) {
+			exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+				UseQueryExecutor: useQueryExecutor,
+			})
+
+			empty := NewMaterializedRelation([]query.Symbol{"?sym"}, nil)
+			result, err := exec.ExecuteWithRelations(NewContext(nil), q, []Relation{empty})
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if result.Size() != 0 {
+				t.Errorf("Expected empty collection to match nothing, got %d results", result.Size())
+			}
+		})
+	}
+}
+
+func TestCollectionInputSubquery(t *testing.T) {
+	inputs := createInputRelationsFromValues(
+		&query.Query{In: []query.InputSpec{
+			query.DatabaseInput{},
+			query.CollectionInput{Symbol: "?sym"},
+		}},
+		[]interface{}{query.Symbol("$"), collectionSymbols(0, 500)},
+	)
+	if len(inputs) != 1 {
+		t.Fatalf("Expected 1 input relation, got %d", len(inputs))
+	}
+	if inputs[0].Size() != 500 {
+		t.Fatalf("Expected collection input to expand to 500 rows, got %d", inputs[0].Size())
+	}
+
+	datoms := collectionInputDatoms(1000)
+
+	// The outer query passes the whole collection as a scalar to a subquery
+	// that binds it with [?sym ...]
+	q, err := parser.ParseQuery(`[:find ?total
+	                              :in $ ?syms
+	                              :where [(q [:find (sum ?price)
+	                                          :in $ [?sym ...]
+	                                          :where [?e :stock/symbol ?sym]
+	                                                 [?e :stock/price ?price]]
+	                                        $ ?syms) [[?total]]]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	syms := collectionSymbols(200, 500)
+	var expected int64
+	for i := 200; i < 500; i++ {
+		expected += int64(i)
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		t.Run(fmt.Sprintf("QueryExecutor=%v", useQueryExecutor), func(t *testing.T) {
+			exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+				UseQueryExecutor: useQueryExecutor,
+			})
+
+			input := NewMaterializedRelation([]query.Symbol{"?syms"}, []Tuple{{syms}})
+			result, err := exec.ExecuteWithRelations(NewContext(nil), q, []Relation{input})
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if result.Size() != 1 {
+				t.Fatalf("Expected 1 result, got %d", result.Size())
+			}
+			if total := result.Get(0)[0]; total != expected && total != float64(expected) {
+				t.Errorf("Expected sum %d over the whole collection, got %v (%T)", expected, total, total)
+			}
+		})
+	}
+}
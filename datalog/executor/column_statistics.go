@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+)
+
+// ColumnStatistics computes the min, max and nil count of each column of rel
+// in one pass. rel is iterated, so streaming relations are consumed.
+func ColumnStatistics(rel Relation) ([]annotations.ColumnStatistics, int, error) {
+	columns := rel.Symbols()
+	stats := make([]annotations.ColumnStatistics, len(columns))
+	for i, col := range columns {
+		stats[i].Column = string(col)
+	}
+
+	tuples := 0
+	err := rel.ForEach(func(tuple Tuple) (bool, error) {
+		tuples++
+		for i := range stats {
+			var value interface{}
+			if i < len(tuple) {
+				value = tuple[i]
+			}
+			s := &stats[i]
+			if value == nil {
+				s.Nulls++
+				continue
+			}
+			if s.Min == nil || datalog.CompareValues(value, s.Min) < 0 {
+				s.Min = value
+			}
+			if s.Max == nil || datalog.CompareValues(value, s.Max) > 0 {
+				s.Max = value
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return stats, tuples, nil
+}
+
+// annotateColumnStatistics reports the column statistics of a query's final
+// result in a QueryComplete annotation, if ctx collects annotations. The
+// result is materialized first unless it can be iterated again, and is
+// returned for the caller to use in place of result.
+func annotateColumnStatistics(ctx Context, start time.Time, result Relation) (Relation, error) {
+	collector := ctx.Collector()
+	if collector == nil {
+		return result, nil
+	}
+	switch result.(type) {
+	case *MaterializedRelation, *SpooledRelation:
+	default:
+		result = result.Materialize()
+		if err := RelationErr(result); err != nil {
+			return nil, fmt.Errorf("query execution failed: %w", err)
+		}
+	}
+
+	columns, tuples, err := ColumnStatistics(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute column statistics: %w", err)
+	}
+	collector.AddPayloadTiming(annotations.QueryComplete, start, annotations.QueryResultEvent{
+		Tuples:  tuples,
+		Columns: columns,
+	})
+	return result, nil
+}
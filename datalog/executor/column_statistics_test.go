@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestColumnStatistics(t *testing.T) {
+	rel := NewMaterializedRelation(
+		[]query.Symbol{"?name", "?price"},
+		[]Tuple{
+			{"b", 12.5},
+			{"a", nil},
+			{"c", 0.0},
+			{nil, 7.0},
+		})
+
+	stats, tuples, err := ColumnStatistics(rel)
+	if err != nil {
+		t.Fatalf("ColumnStatistics failed: %v", err)
+	}
+	if tuples != 4 {
+		t.Errorf("Expected 4 tuples, got %d", tuples)
+	}
+	expected := []annotations.ColumnStatistics{
+		{Column: "?name", Min: "a", Max: "c", Nulls: 1},
+		{Column: "?price", Min: 0.0, Max: 12.5, Nulls: 1},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d columns, got %v", len(expected), stats)
+	}
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], stats[i])
+		}
+	}
+}
+
+func TestColumnStatisticsAnnotation(t *testing.T) {
+	datoms := []datalog.Datom{
+		{E: datalog.NewIdentity("bar:1"), A: datalog.NewKeyword(":price/value"), V: 101.5, Tx: 1},
+		{E: datalog.NewIdentity("bar:2"), A: datalog.NewKeyword(":price/value"), V: 0.0, Tx: 1},
+		{E: datalog.NewIdentity("bar:3"), A: datalog.NewKeyword(":price/value"), V: 99.0, Tx: 1},
+	}
+	q, err := parser.ParseQuery(`{:query [:find ?p :where [_ :price/value ?p] :order-by [?p]] :limit 2}`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		var mu sync.Mutex
+		var results []annotations.QueryResultEvent
+		handler := &annotations.TypedHandler{
+			OnQueryResult: func(_ annotations.Event, e annotations.QueryResultEvent) {
+				mu.Lock()
+				results = append(results, e)
+				mu.Unlock()
+			},
+		}
+
+		exec := NewExecutor(NewMemoryPatternMatcher(datoms))
+		exec.SetUseQueryExecutor(useQueryExecutor)
+		exec.SetColumnStatistics(true)
+		result, err := exec.ExecuteWithContext(NewContext(handler.Handle), q)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if result.Size() != 2 {
+			t.Errorf("Expected the result to be usable after the statistics, got %d tuples", result.Size())
+		}
+
+		// One summary of the limited result, with the suspicious zero price
+		if len(results) != 1 {
+			t.Fatalf("QueryExecutor=%v: expected one result summary, got %d", useQueryExecutor, len(results))
+		}
+		got := results[0]
+		if got.Tuples != 2 || len(got.Columns) != 1 {
+			t.Fatalf("QueryExecutor=%v: expected 2 tuples in one column, got %+v", useQueryExecutor, got)
+		}
+		if c := got.Columns[0]; c.Column != "?p" || c.Min != 0.0 || c.Max != 99.0 || c.Nulls != 0 {
+			t.Errorf("QueryExecutor=%v: expected ?p from 0 to 99, got %v", useQueryExecutor, c)
+		}
+	}
+
+	// Off by default
+	var events int
+	exec := NewExecutor(NewMemoryPatternMatcher(datoms))
+	handler := &annotations.TypedHandler{
+		OnQueryResult: func(annotations.Event, annotations.QueryResultEvent) { events++ },
+	}
+	if _, err := exec.ExecuteWithContext(NewContext(handler.Handle), q); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if events != 0 {
+		t.Errorf("Expected no result summary by default, got %d", events)
+	}
+}
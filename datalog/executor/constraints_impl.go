@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
 // Simple constraint implementations that don't depend on storage package
@@ -120,6 +121,7 @@ type timeExtractionConstraint struct {
 	position  int
 	extractFn string
 	expected  interface{}
+	location  *time.Location // Zone extractFn is evaluated in (nil = the time's own)
 }
 
 func (c *timeExtractionConstraint) Evaluate(datom *datalog.Datom) bool {
@@ -133,21 +135,8 @@ func (c *timeExtractionConstraint) Evaluate(datom *datalog.Datom) bool {
 		return false
 	}
 
-	var extracted interface{}
-	switch c.extractFn {
-	case "year":
-		extracted = int64(t.Year())
-	case "month":
-		extracted = int64(t.Month())
-	case "day":
-		extracted = int64(t.Day())
-	case "hour":
-		extracted = int64(t.Hour())
-	case "minute":
-		extracted = int64(t.Minute())
-	case "second":
-		extracted = int64(t.Second())
-	default:
+	extracted, ok := query.ExtractTimeField(c.extractFn, t, c.location)
+	if !ok {
 		return false
 	}
 
@@ -155,6 +144,9 @@ func (c *timeExtractionConstraint) Evaluate(datom *datalog.Datom) bool {
 }
 
 func (c *timeExtractionConstraint) String() string {
+	if c.location != nil {
+		return fmt.Sprintf("%s(V, %s) = %v", c.extractFn, c.location, c.expected)
+	}
 	return fmt.Sprintf("%s(V) = %v", c.extractFn, c.expected)
 }
 
@@ -195,7 +195,7 @@ func (c *AnnotatedContext) ExecutePhase(name string, phase interface{}, fn func(
 	}
 
 	if result != nil {
-		completeData["tuple.count"] = result.Size()
+		completeData["tuple.count"] = annotationSize(result)
 	}
 
 	if err != nil {
@@ -219,12 +219,7 @@ func (c *AnnotatedContext) MatchPatterns(patterns []query.Pattern, fn func() ([]
 
 	matches, err := fn()
 
-	totalTuples := 0
-	for _, rel := range matches {
-		if rel != nil {
-			totalTuples += rel.Size()
-		}
-	}
+	totalTuples := annotationSizes(matches)
 
 	c.collector.AddTiming(annotations.PatternsToRelationsRealized, start, map[string]interface{}{
 		"pattern.count": len(patterns),
@@ -313,18 +308,8 @@ func (c *AnnotatedContext) CombineRelations(oldRels, newRels []Relation, fn func
 	}
 
 	// Add sizes if available
-	oldTuples := 0
-	for _, rel := range oldRels {
-		if rel != nil {
-			oldTuples += rel.Size()
-		}
-	}
-	newTuples := 0
-	for _, rel := range newRels {
-		if rel != nil {
-			newTuples += rel.Size()
-		}
-	}
+	oldTuples := annotationSizes(oldRels)
+	newTuples := annotationSizes(newRels)
 
 	beginData["tuples/count-old"] = oldTuples
 	beginData["tuples/count-new"] = newTuples
@@ -341,18 +326,16 @@ func (c *AnnotatedContext) CombineRelations(oldRels, newRels []Relation, fn func
 	// Track collapse if reduction occurred
 	totalInput := len(oldRels) + len(newRels)
 	if totalInput > 0 && len(result) < totalInput {
-		resultTuples := 0
-		for _, rel := range result {
-			if rel != nil {
-				resultTuples += rel.Size()
-			}
+		tuplesBefore := oldTuples + newTuples
+		if oldTuples < 0 || newTuples < 0 {
+			tuplesBefore = -1
 		}
 
 		collapseData := map[string]interface{}{
 			"relations/before": totalInput,
 			"relations/after":  len(result),
-			"tuples/before":    oldTuples + newTuples,
-			"tuples/after":     resultTuples,
+			"tuples/before":    tuplesBefore,
+			"tuples/after":     annotationSizes(result),
 			"reduction.pct":    float64(totalInput-len(result)) / float64(totalInput) * 100,
 		}
 
@@ -364,29 +347,15 @@ func (c *AnnotatedContext) CombineRelations(oldRels, newRels []Relation, fn func
 
 func (c *AnnotatedContext) JoinRelations(left, right Relation, fn func() Relation) Relation {
 	start := time.Now()
-	leftSize := -1  // Use -1 to indicate unknown size
-	rightSize := -1
 
-	// CRITICAL FIX: Don't call Size() on StreamingRelations before the join
-	// Size() can trigger materialization which may lose tuples if the iterator was partially consumed
-	// Only call Size() on materialized relations where it's safe
-	if left != nil {
-		if _, isStreaming := left.(*StreamingRelation); !isStreaming {
-			leftSize = left.Size()
-		}
-	}
-	if right != nil {
-		if _, isStreaming := right.(*StreamingRelation); !isStreaming {
-			rightSize = right.Size()
-		}
-	}
+	// Don't call Size() on the inputs: it can materialize a streaming
+	// relation, or consume one that the join is about to read
+	leftSize := annotationSize(left)
+	rightSize := annotationSize(right)
 
 	result := fn()
 
-	resultSize := 0
-	if result != nil {
-		resultSize = result.Size()
-	}
+	resultSize := annotationSize(result)
 
 	// Group join metrics
 	data := map[string]interface{}{
@@ -396,34 +365,34 @@ func (c *AnnotatedContext) JoinRelations(left, right Relation, fn func() Relatio
 	}
 
 	// Calculate amplification factor
-	if leftSize+rightSize > 0 {
+	if leftSize >= 0 && rightSize >= 0 && resultSize >= 0 && leftSize+rightSize > 0 {
 		data["amplification"] = float64(resultSize) / float64(leftSize+rightSize)
 	}
 
 	// Add columns being joined
 	if left != nil && right != nil {
-		data["left.columns"] = left.Columns()
-		data["right.columns"] = right.Columns()
+		data["left.columns"] = left.Symbols()
+		data["right.columns"] = right.Symbols()
 	}
 
 	// Add relation attributes for rendering
 	if left != nil {
-		leftAttrs := make([]string, len(left.Columns()))
-		for i, col := range left.Columns() {
+		leftAttrs := make([]string, len(left.Symbols()))
+		for i, col := range left.Symbols() {
 			leftAttrs[i] = string(col)
 		}
 		data["left.attrs"] = leftAttrs
 	}
 	if right != nil {
-		rightAttrs := make([]string, len(right.Columns()))
-		for i, col := range right.Columns() {
+		rightAttrs := make([]string, len(right.Symbols()))
+		for i, col := range right.Symbols() {
 			rightAttrs[i] = string(col)
 		}
 		data["right.attrs"] = rightAttrs
 	}
 	if result != nil {
-		resultAttrs := make([]string, len(result.Columns()))
-		for i, col := range result.Columns() {
+		resultAttrs := make([]string, len(result.Symbols()))
+		for i, col := range result.Symbols() {
 			resultAttrs[i] = string(col)
 		}
 		data["result.attrs"] = resultAttrs
@@ -435,25 +404,22 @@ func (c *AnnotatedContext) JoinRelations(left, right Relation, fn func() Relatio
 
 func (c *AnnotatedContext) FilterRelation(rel Relation, predicate string, fn func() Relation) Relation {
 	start := time.Now()
-	inputSize := 0
-	if rel != nil {
-		inputSize = rel.Size()
-	}
+	inputSize := annotationSize(rel)
 
 	result := fn()
 
-	outputSize := 0
-	if result != nil {
-		outputSize = result.Size()
-	}
+	outputSize := annotationSize(result)
 
-	c.collector.AddTiming("filter/predicate", start, map[string]interface{}{
+	data := map[string]interface{}{
 		"predicate":   predicate,
 		"input.size":  inputSize,
 		"output.size": outputSize,
-		"filtered":    inputSize - outputSize,
-		"selectivity": float64(outputSize) / float64(inputSize),
-	})
+	}
+	if inputSize >= 0 && outputSize >= 0 {
+		data["filtered"] = inputSize - outputSize
+		data["selectivity"] = float64(outputSize) / float64(inputSize)
+	}
+	c.collector.AddTiming("filter/predicate", start, data)
 
 	return result
 }
@@ -462,31 +428,25 @@ func (c *AnnotatedContext) CollapseRelations(rels []Relation, fn func() []Relati
 	start := time.Now()
 
 	inputCount := len(rels)
-	inputTuples := 0
-	for _, rel := range rels {
-		if rel != nil {
-			inputTuples += rel.Size()
-		}
-	}
+	inputTuples := annotationSizes(rels)
 
 	result := fn()
 
 	outputCount := len(result)
-	outputTuples := 0
-	for _, rel := range result {
-		if rel != nil {
-			outputTuples += rel.Size()
-		}
-	}
+	outputTuples := annotationSizes(result)
+	tuplesKnown := inputTuples >= 0 && outputTuples >= 0
 
-	if outputCount < inputCount || outputTuples < inputTuples {
-		c.collector.AddTiming("collapse/success", start, map[string]interface{}{
+	if outputCount < inputCount || (tuplesKnown && outputTuples < inputTuples) {
+		data := map[string]interface{}{
 			"relations.before": inputCount,
 			"relations.after":  outputCount,
 			"tuples.before":    inputTuples,
 			"tuples.after":     outputTuples,
-			"reduction.pct":    (1.0 - float64(outputTuples)/float64(inputTuples)) * 100,
-		})
+		}
+		if tuplesKnown {
+			data["reduction.pct"] = (1.0 - float64(outputTuples)/float64(inputTuples)) * 100
+		}
+		c.collector.AddTiming("collapse/success", start, data)
 	}
 
 	return result
@@ -508,27 +468,43 @@ func (c *AnnotatedContext) EvaluateExpression(expr string, tupleCount int, fn fu
 func (c *AnnotatedContext) EvaluateExpressionRelation(rel Relation, expr string, fn func() Relation) Relation {
 	start := time.Now()
 
-	inputSize := 0
-	if rel != nil {
-		inputSize = rel.Size()
-	}
+	inputSize := annotationSize(rel)
 
 	result := fn()
 
-	resultSize := 0
-	if result != nil {
-		resultSize = result.Size()
-	}
-
 	c.collector.AddTiming("expression/evaluate", start, map[string]interface{}{
 		"expression":  expr,
 		"input.size":  inputSize,
-		"result.size": resultSize,
+		"result.size": annotationSize(result),
 	})
 
 	return result
 }
 
+// annotationSize returns a relation's size for an annotation, or -1 if it
+// isn't known. Annotations must never call Size(): it can materialize a
+// huge intermediate, or consume a streaming relation before its reader.
+func annotationSize(rel Relation) int {
+	if size, ok := KnownSize(rel); ok {
+		return size
+	}
+	return -1
+}
+
+// annotationSizes returns the total size of relations for an annotation,
+// or -1 if any of their sizes isn't known
+func annotationSizes(rels []Relation) int {
+	total := 0
+	for _, rel := range rels {
+		size := annotationSize(rel)
+		if size < 0 {
+			return -1
+		}
+		total += size
+	}
+	return total
+}
+
 func (c *AnnotatedContext) Collector() *annotations.Collector {
 	return c.collector
 }
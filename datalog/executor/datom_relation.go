@@ -73,6 +73,10 @@ func (it *DatomIterator) Close() error {
 	return nil
 }
 
+func (it *DatomIterator) Err() error {
+	return nil
+}
+
 // NewDatomRelation creates a relation from datoms
 func NewDatomRelation(datoms []datalog.Datom, binding PatternBinding) Relation {
 	// Build columns from binding
@@ -229,6 +229,9 @@ func (e *DefaultQueryExecutor) executeWithDecorrelation(ctx Context, q *query.Qu
 	groups := Relations(inputs)
 
 	for i, clause := range otherClauses {
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
 		switch c := clause.(type) {
 		case *query.DataPattern:
 			newRel, err := e.executePattern(ctx, c, groups)
@@ -344,7 +347,10 @@ func (e *DefaultQueryExecutor) executeWithDecorrelation(ctx Context, q *query.Qu
 			combined = Relations(collapsed).Product()
 		}
 
-		aggregated := ExecuteAggregationsWithContext(ctx, combined, q.Find)
+		if err := checkAggregateColumns(ctx, combined, q.Find, e.options); err != nil {
+			return nil, err
+		}
+		aggregated := executeAggregations(ctx, combined, q.Find, e.options.Summation)
 		return []Relation{aggregated}, nil
 	}
 
@@ -375,7 +381,7 @@ func (e *DefaultQueryExecutor) executeWithDecorrelation(ctx Context, q *query.Qu
 	groupsHaveSymbols := make([][]bool, len(groups))
 	for i, group := range groups {
 		groupsHaveSymbols[i] = make([]bool, len(findVars))
-		cols := group.Columns()
+		cols := group.Symbols()
 		for j, sym := range findVars {
 			for _, col := range cols {
 				if col == sym {
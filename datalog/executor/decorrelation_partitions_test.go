@@ -0,0 +1,172 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+// partitionTestDatoms returns categories, each with products carrying a price and stock
+func partitionTestDatoms(categories, products int) []datalog.Datom {
+	nameAttr := datalog.NewKeyword(":category/name")
+	categoryAttr := datalog.NewKeyword(":product/category")
+	priceAttr := datalog.NewKeyword(":product/price")
+	stockAttr := datalog.NewKeyword(":product/stock")
+
+	var datoms []datalog.Datom
+	for c := 0; c < categories; c++ {
+		catID := datalog.NewIdentity(fmt.Sprintf("cat-%d", c))
+		datoms = append(datoms, datalog.Datom{E: catID, A: nameAttr, V: fmt.Sprintf("C%03d", c), Tx: 1})
+		for p := 0; p < products; p++ {
+			prodID := datalog.NewIdentity(fmt.Sprintf("prod-%d-%d", c, p))
+			datoms = append(datoms,
+				datalog.Datom{E: prodID, A: categoryAttr, V: catID, Tx: 1},
+				datalog.Datom{E: prodID, A: priceAttr, V: int64(100 + c*10 + p), Tx: 1},
+				datalog.Datom{E: prodID, A: stockAttr, V: int64(c + p), Tx: 1},
+			)
+		}
+	}
+	return datoms
+}
+
+// sortedRows renders a relation's tuples as sorted strings for comparison
+func sortedRows(rel Relation) []string {
+	var rows []string
+	it := rel.Iterator()
+	defer it.Close()
+	for it.Next() {
+		rows = append(rows, fmt.Sprintf("%v", it.Tuple()))
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func TestDecorrelationPartitions(t *testing.T) {
+	datoms := partitionTestDatoms(60, 8)
+
+	// Two grouped aggregates correlated on ?c; the first also returns its key
+	q, err := parser.ParseQuery(`[:find ?name ?c2 ?max-price ?total-stock
+	                              :where
+	                                [?c :category/name ?name]
+	                                [(q [:find ?cat (max ?p)
+	                                     :in $ ?cat
+	                                     :where [?prod :product/category ?cat]
+	                                            [?prod :product/price ?p]]
+	                                   $ ?c) [[?c2 ?max-price]]]
+	                                [(q [:find ?cat (sum ?s)
+	                                     :in $ ?cat
+	                                     :where [?prod :product/category ?cat]
+	                                            [?prod :product/stock ?s]]
+	                                   $ ?c) [[?c3 ?total-stock]]]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	run := func(opts planner.PlannerOptions) ([]string, []annotations.Event) {
+		var mu sync.Mutex
+		var events []annotations.Event
+		handler := func(event annotations.Event) {
+			if strings.HasPrefix(event.Name, "decorrelated_subqueries/") {
+				mu.Lock()
+				events = append(events, event)
+				mu.Unlock()
+			}
+		}
+
+		exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), opts)
+		result, err := exec.ExecuteWithContext(NewContext(handler), q)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		rows := sortedRows(result)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return rows, events
+	}
+
+	baseline, _ := run(planner.PlannerOptions{})
+	if len(baseline) != 60 {
+		t.Fatalf("Expected 60 rows without decorrelation, got %d", len(baseline))
+	}
+
+	// The returned grouping key must be the category, not an aggregate
+	if !strings.Contains(baseline[0], "cat-0 107") {
+		t.Errorf("Unexpected first row %s", baseline[0])
+	}
+
+	decorrelated, _ := run(planner.PlannerOptions{EnableSubqueryDecorrelation: true})
+	partitioned, events := run(planner.PlannerOptions{
+		EnableSubqueryDecorrelation: true,
+		DecorrelationPartitions:     4,
+	})
+
+	for name, rows := range map[string][]string{"decorrelated": decorrelated, "partitioned": partitioned} {
+		if len(rows) != len(baseline) {
+			t.Fatalf("%s: expected %d rows, got %d", name, len(baseline), len(rows))
+		}
+		for i := range rows {
+			if rows[i] != baseline[i] {
+				t.Fatalf("%s: row %d is %s, expected %s", name, i, rows[i], baseline[i])
+			}
+		}
+	}
+
+	partitionKeys := 0
+	partitionEvents := 0
+	for _, event := range events {
+		if strings.HasPrefix(event.Name, "decorrelated_subqueries/partition_") {
+			partitionEvents++
+			partitionKeys += event.Data["keys"].(int)
+		}
+	}
+	if partitionEvents != 4 {
+		t.Errorf("Expected 4 partition events, got %d", partitionEvents)
+	}
+	if partitionKeys != 60 {
+		t.Errorf("Expected partitions to cover 60 keys, got %d", partitionKeys)
+	}
+}
+
+func TestDecorrelationPartitionsMoreThanKeys(t *testing.T) {
+	datoms := partitionTestDatoms(3, 4)
+
+	q, err := parser.ParseQuery(`[:find ?name ?max-price
+	                              :where
+	                                [?c :category/name ?name]
+	                                [(q [:find ?cat (max ?p)
+	                                     :in $ ?cat
+	                                     :where [?prod :product/category ?cat]
+	                                            [?prod :product/price ?p]]
+	                                   $ ?c) [[?c2 ?max-price]]]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+		EnableSubqueryDecorrelation: true,
+		DecorrelationPartitions:     16,
+	})
+	result, err := exec.Execute(q)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	rows := sortedRows(result)
+	expected := []string{"[C000 103]", "[C001 113]", "[C002 123]"}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, rows)
+	}
+	for i := range rows {
+		if rows[i] != expected[i] {
+			t.Errorf("Row %d: expected %s, got %s", i, expected[i], rows[i])
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/wbrown/janus-datalog/datalog/annotations"
@@ -9,6 +11,9 @@ import (
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
+// ErrQueryTimeout is returned when a query exceeds its :timeout option
+var ErrQueryTimeout = errors.New("query timed out")
+
 // Executor is the main query execution engine
 type Executor struct {
 	matcher                  PatternMatcher
@@ -16,6 +21,8 @@ type Executor struct {
 	options                  ExecutorOptions
 	enableParallelSubqueries bool
 	maxSubqueryWorkers       int
+	metadata                 *ResultMetadata // Set by ExecuteWithMetadata to record the plan
+	queries                  *QueryTracker   // Executing queries, for ActiveQueries and CancelQuery
 }
 
 // NewExecutor creates a new query executor with default options
@@ -62,9 +69,16 @@ func NewExecutorWithOptions(matcher PatternMatcher, opts planner.PlannerOptions)
 		options:                  execOpts,
 		enableParallelSubqueries: opts.EnableParallelSubqueries,
 		maxSubqueryWorkers:       opts.MaxSubqueryWorkers,
+		queries:                  NewQueryTracker(),
 	}
 }
 
+// ExecutorOptionsFor returns the executor options NewExecutorWithOptions
+// derives from opts
+func ExecutorOptionsFor(opts planner.PlannerOptions) ExecutorOptions {
+	return convertToExecutorOptions(opts)
+}
+
 // convertToExecutorOptions extracts executor-specific options from PlannerOptions
 func convertToExecutorOptions(opts planner.PlannerOptions) ExecutorOptions {
 	return ExecutorOptions{
@@ -81,6 +95,11 @@ func convertToExecutorOptions(opts planner.PlannerOptions) ExecutorOptions {
 		EnableStreamingAggregation:      opts.EnableStreamingAggregation,
 		EnableStreamingAggregationDebug: opts.EnableStreamingAggregationDebug,
 		EnableDebugLogging:              opts.EnableDebugLogging,
+		EnableOrderedScan:               opts.EnableOrderedScan,
+		EnableLatestPerEntity:           opts.EnableLatestPerEntity,
+		EnableSharedPatterns:            opts.EnableSharedPatterns,
+		SpoolThreshold:                  opts.SpoolThreshold,
+		SpoolDir:                        opts.SpoolDir,
 	}
 }
 
@@ -100,6 +119,29 @@ func (e *Executor) SetUseQueryExecutor(use bool) {
 	e.options.UseQueryExecutor = use
 }
 
+// SetDuplicateColumns sets how the executor's hash joins handle non-join
+// columns present on both sides
+func (e *Executor) SetDuplicateColumns(policy DuplicateColumnPolicy) {
+	e.options.DuplicateColumns = policy
+}
+
+// SetLenientAggregation sets whether aggregates over unknown columns are
+// annotated instead of failing the query
+func (e *Executor) SetLenientAggregation(lenient bool) {
+	e.options.LenientAggregation = lenient
+}
+
+// SetSummation sets how the executor's sum and avg aggregates add float values
+func (e *Executor) SetSummation(summation Summation) {
+	e.options.Summation = summation
+}
+
+// SetColumnStatistics sets whether the final result of each query is
+// summarized per column in a QueryComplete annotation
+func (e *Executor) SetColumnStatistics(enable bool) {
+	e.options.EnableColumnStatistics = enable
+}
+
 // Execute runs a parsed query and returns the results
 func (e *Executor) Execute(q *query.Query) (Relation, error) {
 	// Use a no-op context for backward compatibility
@@ -116,9 +158,160 @@ func (e *Executor) ExecuteWithContext(ctx Context, q *query.Query) (Relation, er
 // This is the unified query execution method that treats regular queries and subqueries the same way.
 // For regular queries, pass an empty slice for inputRelations.
 // For subqueries, pass the relations corresponding to the :in clause variables.
+//
+// Query options (:timeout, :offset, :limit) are honored here, after ordering.
+//
+// The query is listed by ActiveQueries until it returns, or, if it timed out,
+// until its abandoned goroutine stops. It fails with ErrQueriesClosed once the
+// executor's QueryTracker has been closed.
 func (e *Executor) ExecuteWithRelations(ctx Context, q *query.Query, inputRelations []Relation) (Relation, error) {
-	// Apply decorator pattern: wrap matcher with annotations if context has a handler
-	matcher := e.matcher
+	if e.queries != nil {
+		run, err := e.queries.begin(q.String())
+		if err != nil {
+			return nil, err
+		}
+		ctx.SetMetadata(activeQueryKey, run)
+		// With a timeout, the query's goroutine ends the run (see executeWithTimeout)
+		if q.Timeout <= 0 {
+			defer e.queries.end(run)
+		}
+	}
+
+	start := time.Now()
+	var result Relation
+	var err error
+	if q.Timeout > 0 {
+		result, err = e.executeWithTimeout(ctx, q, inputRelations)
+	} else {
+		result, err = e.executeQuery(ctx, q, inputRelations)
+		if err == nil && result != nil {
+			result, err = e.finishResult(result, q)
+		}
+	}
+	if err == nil && result != nil && e.options.EnableColumnStatistics {
+		result, err = annotateColumnStatistics(ctx, start, result)
+	}
+	return result, err
+}
+
+// executeQuery executes q without applying query options, as an ordered scan
+// if enabled and the query and matcher support it
+func (e *Executor) executeQuery(ctx Context, q *query.Query, inputRelations []Relation) (Relation, error) {
+	if e.options.EnableLatestPerEntity && len(inputRelations) == 0 {
+		if result, ok, err := e.executeLatestPerEntity(ctx, q); ok || err != nil {
+			return result, err
+		}
+	}
+	if e.options.EnableOrderedScan && len(inputRelations) == 0 {
+		if result, ok, err := e.executeOrderedScan(ctx, q); ok || err != nil {
+			return result, err
+		}
+	}
+	return e.executeWithRelations(ctx, q, inputRelations)
+}
+
+// finishResult applies :offset/:limit and, if configured, spools large results to disk.
+// An iteration error already seen while producing the result (a failed scan
+// under a sort or aggregate) is returned here; streaming results report
+// theirs from Iterator().Err().
+func (e *Executor) finishResult(result Relation, q *query.Query) (Relation, error) {
+	result = SliceRelation(result, q.Offset, q.Limit)
+	if err := RelationErr(result); err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	if e.options.SpoolThreshold > 0 {
+		return SpoolRelation(result, e.options.SpoolThreshold, e.options.SpoolDir)
+	}
+	return result, nil
+}
+
+// executeWithTimeout runs the query in its own goroutine and gives up once q.Timeout
+// has elapsed. The result is materialized inside the goroutine so that lazily
+// evaluated work is covered by the deadline as well. A timed-out query is canceled
+// and abandoned: its goroutine stops at its next clause or phase, or once a long
+// scan finishes, and its result is discarded. A tracked query stays registered
+// with the QueryTracker until the goroutine stops, so that the tracker's Wait,
+// and closing a store that waits on it, cover the abandoned work.
+//
+// The goroutine executes its own copy of q, which the caller may change once
+// the query has timed out, and reports completion through a timeoutContext, so
+// that the timeout is the last completion ctx sees.
+func (e *Executor) executeWithTimeout(ctx Context, q *query.Query, inputRelations []Relation) (Relation, error) {
+	// Untracked queries get a run of their own, so they can be canceled
+	run := activeQuery(ctx)
+	tracked := run != nil && e.queries != nil
+	if run == nil {
+		run = &queryRun{query: q.String(), start: time.Now()}
+		ctx.SetMetadata(activeQueryKey, run)
+	}
+
+	type outcome struct {
+		rel Relation
+		err error
+	}
+	done := make(chan outcome, 1)
+	worker := &timeoutContext{Context: ctx}
+	wq := q.Clone()
+
+	go func() {
+		result, err := e.executeQuery(worker, wq, inputRelations)
+		if err == nil && result != nil {
+			result, err = e.finishResult(result, wq)
+		}
+		if err == nil && result != nil {
+			result = result.Materialize()
+		}
+		if tracked {
+			e.queries.end(run)
+		}
+		done <- outcome{rel: result, err: err}
+	}()
+
+	timer := time.NewTimer(q.Timeout)
+	defer timer.Stop()
+
+	select {
+	case out := <-done:
+		return out.rel, out.err
+	case <-timer.C:
+		run.canceled.Store(true)
+		err := fmt.Errorf("%w after %v", ErrQueryTimeout, q.Timeout)
+		worker.abandon(err)
+		return nil, err
+	}
+}
+
+// timeoutContext is the context a query runs with under a timeout. Once the
+// query is abandoned, the QueryComplete calls its goroutine still makes are
+// dropped, so that only the timeout is reported.
+type timeoutContext struct {
+	Context
+	mu        sync.Mutex
+	abandoned bool
+}
+
+// QueryComplete forwards the completion unless the query has been abandoned
+func (c *timeoutContext) QueryComplete(relationCount, tupleCount int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.abandoned {
+		c.Context.QueryComplete(relationCount, tupleCount, err)
+	}
+}
+
+// abandon reports err as the query's completion and drops later ones
+func (c *timeoutContext) abandon(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.abandoned = true
+	c.Context.QueryComplete(0, 0, err)
+}
+
+// executeWithRelations plans and executes a query without applying query options
+func (e *Executor) executeWithRelations(ctx Context, q *query.Query, inputRelations []Relation) (Relation, error) {
+	// Share scans between the query and its subqueries, then apply decorator
+	// pattern: wrap matcher with annotations if context has a handler
+	matcher := e.shareScans(ctx, q, e.matcher)
 	if collector := ctx.Collector(); collector != nil {
 		matcher = WrapMatcher(matcher, collector.Handler())
 	}
@@ -167,8 +360,9 @@ func (e *Executor) ExecuteWithRelations(ctx Context, q *query.Query, inputRelati
 			}
 			relationIndex++
 		case query.CollectionInput:
-			// For collection inputs, mark the variable as bound
-			if relationIndex < len(inputRelations) && inputRelations[relationIndex].Size() > 0 {
+			// For collection inputs, mark the variable as bound (an empty
+			// collection binds it to no values)
+			if relationIndex < len(inputRelations) {
 				initialBindings[inp.Symbol] = true
 			}
 			relationIndex++
@@ -190,6 +384,10 @@ func (e *Executor) ExecuteWithRelations(ctx Context, q *query.Query, inputRelati
 			return nil, fmt.Errorf("query planning failed: %w", err)
 		}
 		ctx.QueryPlanCreated(realizedPlan.String())
+		e.recordPlan(len(realizedPlan.Phases), realizedPlan.Cached)
+		if run := activeQuery(ctx); run != nil {
+			run.phases.Store(int64(len(realizedPlan.Phases)))
+		}
 		return executor.ExecuteRealized(ctx, realizedPlan, inputRelations)
 	} else {
 		// Old path: Use legacy phase executor (only works with PlannerAdapter)
@@ -200,9 +398,10 @@ func (e *Executor) ExecuteWithRelations(ctx Context, q *query.Query, inputRelati
 
 		oldPlanner := adapter.GetUnderlyingPlanner()
 		var oldPlan *planner.QueryPlan
+		var cached bool
 		var err error
 		if len(initialBindings) == 0 {
-			oldPlan, err = oldPlanner.Plan(q)
+			oldPlan, cached, err = oldPlanner.PlanWithCacheStatus(q)
 		} else {
 			oldPlan, err = oldPlanner.PlanWithBindings(q, initialBindings)
 		}
@@ -211,10 +410,19 @@ func (e *Executor) ExecuteWithRelations(ctx Context, q *query.Query, inputRelati
 			return nil, fmt.Errorf("query planning failed: %w", err)
 		}
 		ctx.QueryPlanCreated(oldPlan.String())
+		e.recordPlan(len(oldPlan.Phases), cached)
 		return executor.executePhasesWithInputs(ctx, oldPlan, inputRelations)
 	}
 }
 
+// PlanQuery returns the realized plan for q without executing it. The plan can be
+// inspected or rewritten with the planner's plan manipulation API and then run
+// with ExecuteRealized. Query options (:timeout, :offset, :limit) are not part
+// of the plan and are only applied by ExecuteWithRelations.
+func (e *Executor) PlanQuery(q *query.Query) (*planner.RealizedPlan, error) {
+	return e.planner.PlanQuery(q)
+}
+
 // ExecuteRealized executes a RealizedPlan (Stage B: Query-based execution)
 // This is the simplified executor that consumes Query fragments from the planner.
 //
@@ -235,11 +443,23 @@ func (e *Executor) ExecuteRealized(ctx Context, plan *planner.RealizedPlan, inpu
 		currentGroups = []Relation{boundRelation}
 	}
 
+	// Index pins are only published once a phase uses them, and are reset per phase
+	pinsPublished := false
+
 	// Execute each phase as an independent query
 	for i, phase := range plan.Phases {
 		phaseIndex := i
 		isLastPhase := (i == len(plan.Phases)-1)
 
+		if err := enterPhase(ctx, phaseIndex+1, len(plan.Phases)); err != nil {
+			return nil, err
+		}
+
+		if len(phase.IndexPins) > 0 || pinsPublished {
+			ctx.SetMetadata("index_pins", phase.IndexPins)
+			pinsPublished = true
+		}
+
 		// DEBUG: Log phase execution
 		if collector := ctx.Collector(); collector != nil {
 			collector.Add(annotations.Event{
@@ -281,10 +501,14 @@ func (e *Executor) ExecuteRealized(ctx Context, plan *planner.RealizedPlan, inpu
 				for it.Next() {
 					tuples = append(tuples, it.Tuple())
 				}
+				err := it.Err()
 				it.Close()
+				if err != nil {
+					return nil, fmt.Errorf("phase %d group %d failed: %w", phaseIndex+1, i, err)
+				}
 
 				opts := group.Options()
-				materialized := NewMaterializedRelationWithOptions(group.Columns(), tuples, opts)
+				materialized := NewMaterializedRelationWithOptions(group.Symbols(), tuples, opts)
 
 				projected, err := materialized.Project(phase.Keep)
 				if err != nil {
@@ -324,6 +548,11 @@ func (e *Executor) ExecuteRealized(ctx Context, plan *planner.RealizedPlan, inpu
 		return nil, nil
 	}
 
+	// Apply ordering if specified
+	if len(plan.Query.OrderBy) > 0 {
+		return currentGroups[0].Sort(plan.Query.OrderBy), nil
+	}
+
 	return currentGroups[0], nil
 }
 
@@ -353,7 +582,7 @@ func (e *Executor) executePhasesWithInputs(ctx Context, plan *planner.QueryPlan,
 		inputParameterRelation = currentResult // Save for later
 
 		// Add input bindings to the bindings map
-		for _, col := range currentResult.Columns() {
+		for _, col := range currentResult.Symbols() {
 			bindings[col] = currentResult
 		}
 	}
@@ -390,10 +619,14 @@ func (e *Executor) executePhasesWithInputs(ctx Context, plan *planner.QueryPlan,
 		for it.Next() {
 			tuples = append(tuples, it.Tuple())
 		}
+		err = it.Err()
 		it.Close()
+		if err != nil {
+			return nil, fmt.Errorf("phase %d failed: %w", i+1, err)
+		}
 
 		opts := phaseResult.Options()
-		currentResult = NewMaterializedRelationWithOptions(phaseResult.Columns(), tuples, opts)
+		currentResult = NewMaterializedRelationWithOptions(phaseResult.Symbols(), tuples, opts)
 
 		// Update bindings with new symbols from this phase
 		for _, sym := range phase.Provides {
@@ -457,7 +690,11 @@ func (e *Executor) executePhasesWithInputs(ctx Context, plan *planner.QueryPlan,
 
 	var finalResult Relation
 	if hasAggregates {
-		finalResult = ExecuteAggregationsWithContext(ctx, currentResult, findClause)
+		aggregated, err := aggregateQueryResult(ctx, currentResult, plan.Query, findClause, e.options)
+		if err != nil {
+			return nil, err
+		}
+		finalResult = aggregated
 	} else {
 		var findVars []query.Symbol
 		for _, elem := range plan.Query.Find {
@@ -470,7 +707,7 @@ func (e *Executor) executePhasesWithInputs(ctx Context, plan *planner.QueryPlan,
 		// This handles the case where input parameters appear in :find but aren't used in patterns
 		var missingFromCurrent []query.Symbol
 		currentCols := make(map[query.Symbol]bool)
-		for _, col := range currentResult.Columns() {
+		for _, col := range currentResult.Symbols() {
 			currentCols[col] = true
 		}
 		for _, findVar := range findVars {
@@ -482,7 +719,7 @@ func (e *Executor) executePhasesWithInputs(ctx Context, plan *planner.QueryPlan,
 		// If we have missing symbols and they're in input parameters, join them back
 		if len(missingFromCurrent) > 0 && inputParameterRelation != nil {
 			inputCols := make(map[query.Symbol]bool)
-			for _, col := range inputParameterRelation.Columns() {
+			for _, col := range inputParameterRelation.Symbols() {
 				inputCols[col] = true
 			}
 
@@ -509,7 +746,17 @@ func (e *Executor) executePhasesWithInputs(ctx Context, plan *planner.QueryPlan,
 		if err != nil {
 			return nil, fmt.Errorf("projection failed: %w", err)
 		}
-		finalResult = projected.Materialize()
+		if e.options.SpoolThreshold > 0 && len(plan.Query.OrderBy) == 0 && plan.Query.Limit <= 0 {
+			// Spool straight from the projection, so that a large result is
+			// written out as it is produced instead of collected in memory first
+			spooled, err := SpoolRelation(projected, e.options.SpoolThreshold, e.options.SpoolDir)
+			if err != nil {
+				return nil, err
+			}
+			finalResult = spooled
+		} else {
+			finalResult = projected.Materialize()
+		}
 	}
 
 	// Apply ordering if specified
@@ -528,6 +775,14 @@ func (e *Executor) SetPlanCache(cache *planner.PlanCache) {
 	}
 }
 
+// SetStatisticsProvider sets the source of the statistics the executor's
+// planner costs plans with
+func (e *Executor) SetStatisticsProvider(provider planner.StatisticsProvider) {
+	if e.planner != nil {
+		e.planner.SetStatisticsProvider(provider)
+	}
+}
+
 // GetPlanner returns the executor's planner for direct access
 func (e *Executor) GetPlanner() planner.QueryPlanner {
 	return e.planner
@@ -538,6 +793,14 @@ func (e *Executor) Options() ExecutorOptions {
 	return e.options
 }
 
+// SetOptions replaces the executor's configuration options, including the
+// parallel subquery settings
+func (e *Executor) SetOptions(opts ExecutorOptions) {
+	e.options = opts
+	e.enableParallelSubqueries = opts.EnableParallelSubqueries
+	e.maxSubqueryWorkers = opts.MaxSubqueryWorkers
+}
+
 // HashJoin performs a hash join using the executor's options
 func (e *Executor) HashJoin(left, right Relation, joinCols []query.Symbol) Relation {
 	return HashJoinWithOptions(left, right, joinCols, e.options)
@@ -119,7 +119,7 @@ func (e *Executor) executeWithRelationInputIterationSequential(ctx Context, plan
 
 	// Union all results
 	var allTuples []Tuple
-	columns := allResults[0].Columns()
+	columns := allResults[0].Symbols()
 
 	for _, rel := range allResults {
 		it := rel.Iterator()
@@ -240,7 +240,7 @@ func (e *Executor) executeWithRelationInputIterationParallel(ctx Context, plan *
 
 	// Union all results
 	var allTuples []Tuple
-	columns := allResults[0].Columns()
+	columns := allResults[0].Symbols()
 
 	for _, rel := range allResults {
 		it := rel.Iterator()
@@ -265,7 +265,7 @@ func (e *Executor) executePhasesWithInputsNonIterating(ctx Context, plan *planne
 		currentResult = BindQueryInputs(plan.Query, inputRelations)
 
 		// Add input bindings to the bindings map
-		for _, col := range currentResult.Columns() {
+		for _, col := range currentResult.Symbols() {
 			bindings[col] = currentResult
 		}
 	}
@@ -292,7 +292,7 @@ func (e *Executor) executePhasesWithInputsNonIterating(ctx Context, plan *planne
 		currentResult = phaseResult
 
 		// Track bindings from this phase
-		for _, col := range phaseResult.Columns() {
+		for _, col := range phaseResult.Symbols() {
 			bindings[col] = phaseResult
 		}
 	}
@@ -309,7 +309,11 @@ func (e *Executor) executePhasesWithInputsNonIterating(ctx Context, plan *planne
 
 	var finalResult Relation
 	if hasAggregates {
-		finalResult = ExecuteAggregationsWithContext(ctx, currentResult, plan.Query.Find)
+		aggregated, err := aggregateQueryResult(ctx, currentResult, plan.Query, plan.Query.Find, e.options)
+		if err != nil {
+			return nil, err
+		}
+		finalResult = aggregated
 	} else {
 		var findVars []query.Symbol
 		for _, elem := range plan.Query.Find {
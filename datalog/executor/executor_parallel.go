@@ -45,7 +45,7 @@ func (pe *ParallelExecutor) ExecuteWithRelations(ctx Context, q *query.Query, in
 			continue
 		case query.ScalarInput, query.CollectionInput, query.TupleInput, query.RelationInput:
 			if relationIndex < len(inputRelations) {
-				for _, col := range inputRelations[relationIndex].Columns() {
+				for _, col := range inputRelations[relationIndex].Symbols() {
 					initialBindings[col] = true
 				}
 				relationIndex++
@@ -110,6 +110,10 @@ func (pe *ParallelExecutor) executePhasesWithInputs(ctx Context, plan *planner.Q
 		var phaseResult Relation
 		var err error
 
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
+
 		// Check if we can parallelize this phase
 		if len(phase.Patterns) >= 2 {
 			// Try parallel execution
@@ -153,7 +157,11 @@ func (pe *ParallelExecutor) executePhasesWithInputs(ctx Context, plan *planner.Q
 
 	var finalResult Relation
 	if hasAggregates {
-		finalResult = ExecuteAggregationsWithContext(ctx, currentResult, plan.Query.Find)
+		aggregated, err := aggregateQueryResult(ctx, currentResult, plan.Query, plan.Query.Find, pe.options)
+		if err != nil {
+			return nil, err
+		}
+		finalResult = aggregated
 	} else {
 		var findVars []query.Symbol
 		for _, elem := range plan.Query.Find {
@@ -39,18 +39,14 @@ func (e *Executor) executePhaseSequential(ctx Context, phase *planner.Phase, pha
 				continue
 			}
 
-			// Check if pattern has pre-computed constraints from planner
-			var constraints []StorageConstraint
-			if patternPlan.Metadata != nil {
-				// Try new constraints.StorageConstraint interface type first
-				if newConstraints, ok := patternPlan.Metadata["storage_constraints"].([]StorageConstraint); ok && len(newConstraints) > 0 {
-					constraints = newConstraints
-				} else if plannerConstraints, ok := patternPlan.Metadata["storage_constraints"].([]planner.StorageConstraint); ok && len(plannerConstraints) > 0 {
-					// Fallback to old planner.StorageConstraint struct type
-					constraints = convertPlannerConstraints(pattern, plannerConstraints)
-				}
+			if err := checkCanceled(ctx); err != nil {
+				return nil, err
 			}
 
+			// Constraints the planner pushed down to this pattern
+			constraints := convertPlannerConstraints(pattern, patternPlan.Constraints)
+			constraints = append(constraints, patternPlan.StorageFilters...)
+
 			// Materialize relations that share symbols with the pattern
 			// These relations will be: (1) used for binding-based filtering, (2) joined with the result
 			// Materializing allows them to be iterated multiple times without consuming the iterator
@@ -87,8 +83,8 @@ func (e *Executor) executePhaseSequential(ctx Context, phase *planner.Phase, pha
 				// Add result info if we have a result
 				if rel != nil {
 					// Convert columns to string array for output formatter
-					symbols := make([]string, len(rel.Columns()))
-					for i, col := range rel.Columns() {
+					symbols := make([]string, len(rel.Symbols()))
+					for i, col := range rel.Symbols() {
 						symbols[i] = string(col)
 					}
 					annotData["symbol.order"] = symbols
@@ -114,6 +110,7 @@ func (e *Executor) executePhaseSequential(ctx Context, phase *planner.Phase, pha
 			if err != nil {
 				return nil, fmt.Errorf("pattern %d failed: %w", i, err)
 			}
+			rel = limitPatternRelation(ctx, pattern, rel, e.options)
 
 			// Don't call IsEmpty() - it consumes streaming iterators
 			// Collapse() will handle empty relations naturally
@@ -240,6 +237,7 @@ func convertPlannerConstraints(pattern *query.DataPattern, plannerConstraints []
 				position:  2, // Value position for time
 				extractFn: pc.TimeField,
 				expected:  pc.Value,
+				location:  pc.TimeZone,
 			})
 		}
 	}
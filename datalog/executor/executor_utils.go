@@ -2,6 +2,7 @@ package executor
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 
 	"github.com/wbrown/janus-datalog/datalog"
@@ -75,6 +76,9 @@ func MaterializeResult(rel Relation, columns []query.Symbol) Relation {
 	for it.Next() {
 		tuples = append(tuples, it.Tuple())
 	}
+	if err := it.Err(); err != nil {
+		return newFailedRelation(columns, err, rel.Options())
+	}
 
 	// DEBUG: Check for tuple copying bug
 	if len(tuples) > 1 {
@@ -114,7 +118,10 @@ func SortRelation(rel Relation, orderBy []query.OrderByClause) Relation {
 	}
 
 	// Get column indices for sort variables
-	columns := rel.Columns()
+	columns := rel.Symbols()
+	if err := it.Err(); err != nil {
+		return newFailedRelation(columns, err, rel.Options())
+	}
 	sortIndices := make([]int, len(orderBy))
 	for i, clause := range orderBy {
 		idx := -1
@@ -154,6 +161,36 @@ func SortRelation(rel Relation, orderBy []query.OrderByClause) Relation {
 	return NewMaterializedRelationWithOptions(columns, tuples, opts)
 }
 
+// SliceRelation returns the tuples of a relation after skipping offset tuples,
+// keeping at most limit tuples (limit <= 0 means no limit).
+// Returns the relation unchanged when neither offset nor limit is set.
+func SliceRelation(rel Relation, offset, limit int) Relation {
+	if offset <= 0 && limit <= 0 {
+		return rel
+	}
+
+	tuples := []Tuple{}
+	it := rel.Iterator()
+	defer it.Close()
+
+	skipped := 0
+	for it.Next() {
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		if limit > 0 && len(tuples) >= limit {
+			break
+		}
+		tuples = append(tuples, it.Tuple())
+	}
+	if err := it.Err(); err != nil {
+		return newFailedRelation(rel.Symbols(), err, rel.Options())
+	}
+
+	return NewMaterializedRelationWithOptions(rel.Symbols(), tuples, rel.Options())
+}
+
 // computeAggregate computes an aggregate over all values in a column
 func computeAggregate(rel Relation, colIdx int, function string) interface{} {
 	var values []interface{}
@@ -168,12 +205,13 @@ func computeAggregate(rel Relation, colIdx int, function string) interface{} {
 		}
 	}
 
-	return computeAggregateValues(values, function)
+	return computeAggregateValues(values, query.FindAggregate{Function: function}, rel.Options().Summation)
 }
 
-// computeAggregateValues computes an aggregate over a slice of values
-func computeAggregateValues(values []interface{}, function string) interface{} {
-	switch function {
+// computeAggregateValues computes an aggregate over a slice of values, adding
+// float values with summation
+func computeAggregateValues(values []interface{}, agg query.FindAggregate, summation Summation) interface{} {
+	switch agg.Function {
 	case "count":
 		return int64(len(values))
 
@@ -181,30 +219,30 @@ func computeAggregateValues(values []interface{}, function string) interface{} {
 		if len(values) == 0 {
 			return nil
 		}
-		var sum float64
+		sum := floatSum{mode: summation}
 		for _, v := range values {
 			if num, ok := toFloat64(v); ok {
-				sum += num
+				sum.Add(num)
 			}
 		}
-		return sum
+		return sum.Value()
 
 	case "avg":
 		if len(values) == 0 {
 			return nil
 		}
-		var sum float64
+		sum := floatSum{mode: summation}
 		count := 0
 		for _, v := range values {
 			if num, ok := toFloat64(v); ok {
-				sum += num
+				sum.Add(num)
 				count++
 			}
 		}
 		if count == 0 {
 			return nil
 		}
-		return sum / float64(count)
+		return sum.Value() / float64(count)
 
 	case "min":
 		if len(values) == 0 {
@@ -236,6 +274,15 @@ func computeAggregateValues(values []interface{}, function string) interface{} {
 		}
 		return max
 
+	case "percentile":
+		return computePercentile(values, aggregateParam(agg))
+
+	case "histogram":
+		return computeHistogram(values, aggregateParam(agg))
+
+	case "string-agg":
+		return computeStringAgg(values, agg)
+
 	default:
 		return nil
 	}
@@ -259,6 +306,75 @@ func toFloat64(v interface{}) (float64, bool) {
 	}
 }
 
+// expandCollection converts a collection input value into single-column tuples,
+// one per element. Slices and arrays are expanded ([]byte is a scalar value and
+// is not); any other value is treated as a collection of one.
+func expandCollection(value interface{}) []Tuple {
+	if _, ok := value.([]byte); ok {
+		return []Tuple{{value}}
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []Tuple{{value}}
+	}
+
+	tuples := make([]Tuple, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		tuples[i] = Tuple{v.Index(i).Interface()}
+	}
+	return tuples
+}
+
+// relationInputRows returns the rows of a relation-valued input: a Relation, or
+// a slice of rows such as [][]interface{} or []Tuple. ok is false for any other
+// value. Rows of the wrong width are an error.
+func relationInputRows(value interface{}, width int) (rows []Tuple, ok bool, err error) {
+	if rel, isRel := value.(Relation); isRel {
+		if len(rel.Symbols()) != width {
+			return nil, true, fmt.Errorf("relation input expects %d columns, got %d", width, len(rel.Symbols()))
+		}
+		it := rel.Iterator()
+		defer it.Close()
+		for it.Next() {
+			rows = append(rows, it.Tuple())
+		}
+		if err := it.Err(); err != nil {
+			return nil, true, err
+		}
+		return rows, true, nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false, nil
+	}
+	if elem := v.Type().Elem(); elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array && elem.Kind() != reflect.Interface {
+		return nil, false, nil
+	}
+
+	rows = make([]Tuple, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		row := v.Index(i)
+		if row.Kind() == reflect.Interface {
+			row = row.Elem()
+		}
+		if _, isBytes := row.Interface().([]byte); isBytes || (row.Kind() != reflect.Slice && row.Kind() != reflect.Array) {
+			// A flat collection, not a slice of rows
+			return nil, false, nil
+		}
+		if row.Len() != width {
+			return nil, true, fmt.Errorf("relation input row %d has %d values, expected %d", i, row.Len(), width)
+		}
+		tuple := make(Tuple, width)
+		for j := 0; j < width; j++ {
+			tuple[j] = row.Index(j).Interface()
+		}
+		rows = append(rows, tuple)
+	}
+	return rows, true, nil
+}
+
 // BindQueryInputs binds input relations to a query's :in clause specifications.
 // This processes the query's input specifications (ScalarInput, TupleInput, RelationInput, etc.)
 // and creates a unified relation containing all bound input variables.
@@ -308,7 +424,7 @@ func BindQueryInputs(q *query.Query, inputRelations []Relation) Relation {
 			// Multiple tuples input - use the relation directly with renamed columns
 			if relationIndex < len(inputRelations) {
 				rel := inputRelations[relationIndex]
-				if rel.Size() > 0 && len(inp.Symbols) == len(rel.Columns()) {
+				if rel.Size() > 0 && len(inp.Symbols) == len(rel.Symbols()) {
 					// Create a new relation with the input variables as column names
 					tuples := make([]Tuple, 0, rel.Size())
 
@@ -328,7 +444,7 @@ func BindQueryInputs(q *query.Query, inputRelations []Relation) Relation {
 			// Single tuple input - expect a relation with one row
 			if relationIndex < len(inputRelations) {
 				rel := inputRelations[relationIndex]
-				if rel.Size() > 0 && len(inp.Symbols) == len(rel.Columns()) {
+				if rel.Size() > 0 && len(inp.Symbols) == len(rel.Symbols()) {
 					// Take the first tuple and bind to variables
 					it := rel.Iterator()
 					if it.Next() {
@@ -343,27 +459,27 @@ func BindQueryInputs(q *query.Query, inputRelations []Relation) Relation {
 			}
 
 		case query.CollectionInput:
-			// Collection input - all values in one column
+			// Collection input - all values in one column. Each row may carry
+			// either a single element or a whole collection (e.g. a []interface{}
+			// passed as one value), which is expanded into one row per element.
 			if relationIndex < len(inputRelations) {
 				rel := inputRelations[relationIndex]
-				if rel.Size() > 0 {
-					// Create a relation with the collection variable
-					columns := []query.Symbol{inp.Symbol}
-					tuples := make([]Tuple, 0, rel.Size())
-
-					it := rel.Iterator()
-					for it.Next() {
-						tuple := it.Tuple()
-						if len(tuple) > 0 {
-							// Take first value from each tuple
-							tuples = append(tuples, Tuple{tuple[0]})
-						}
+				columns := []query.Symbol{inp.Symbol}
+				tuples := make([]Tuple, 0, rel.Size())
+
+				it := rel.Iterator()
+				for it.Next() {
+					tuple := it.Tuple()
+					if len(tuple) > 0 {
+						tuples = append(tuples, expandCollection(tuple[0])...)
 					}
-					it.Close()
-
-					opts := rel.Options()
-					boundRelations = append(boundRelations, NewMaterializedRelationWithOptions(columns, tuples, opts))
 				}
+				it.Close()
+
+				// An empty collection still binds the variable, so the query
+				// matches nothing instead of running unconstrained
+				opts := rel.Options()
+				boundRelations = append(boundRelations, NewMaterializedRelationWithOptions(columns, tuples, opts))
 				relationIndex++
 			}
 		}
@@ -37,7 +37,7 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 		for _, group := range groups {
 			// Check if this group has all required symbols for the expression
 			hasAllInputs := true
-			groupCols := group.Columns()
+			groupCols := group.Symbols()
 			for _, input := range exprPlan.Inputs {
 				found := false
 				for _, col := range groupCols {
@@ -61,8 +61,8 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 							"expression":     exprPlan.Expression.String(),
 							"required":       exprPlan.Inputs,
 							"available":      groupCols,
-							"group_size":     group.Size(),
-							"group_columns":  group.Columns(),
+							"group_size":     annotationSize(group),
+							"group_columns":  group.Symbols(),
 						},
 					})
 				}
@@ -79,8 +79,8 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 						"is_equality":   exprPlan.IsEquality,
 						"output":        exprPlan.Output,
 						"inputs":        exprPlan.Inputs,
-						"input_size":    group.Size(),
-						"input_columns": group.Columns(),
+						"input_size":    annotationSize(group),
+						"input_columns": group.Symbols(),
 					},
 				})
 			}
@@ -101,14 +101,18 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 
 			// Annotate after evaluation
 			if collector := ctx.Collector(); collector != nil {
+				outputSize, inputSize := annotationSize(result), annotationSize(group)
+				data := map[string]interface{}{
+					"expression":     exprPlan.Expression.String(),
+					"output_size":    outputSize,
+					"output_columns": result.Symbols(),
+				}
+				if outputSize >= 0 && inputSize > 0 {
+					data["reduction"] = float64(outputSize) / float64(inputSize)
+				}
 				collector.Add(annotations.Event{
 					Name: "expression/complete",
-					Data: map[string]interface{}{
-						"expression":     exprPlan.Expression.String(),
-						"output_size":    result.Size(),
-						"output_columns": result.Columns(),
-						"reduction":      float64(result.Size()) / float64(group.Size()),
-					},
+					Data: data,
 				})
 			}
 
@@ -137,7 +141,7 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 		for _, group := range groups {
 			// Check if this group has all required symbols for the predicate
 			hasAllSymbols := true
-			groupCols := group.Columns()
+			groupCols := group.Symbols()
 			for _, sym := range predPlan.Predicate.RequiredSymbols() {
 				found := false
 				for _, col := range groupCols {
@@ -193,6 +197,12 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 		// Execute decorrelated subqueries first (if any)
 		if len(phase.DecorrelatedSubqueries) > 0 {
 			for _, decorPlan := range phase.DecorrelatedSubqueries {
+				// The input is iterated to join back against the merged results,
+				// and joined again below, so it must not be a one-shot stream
+				if sr, ok := result.(*StreamingRelation); ok {
+					result = sr.Materialize()
+				}
+
 				decorResult, err := executeDecorrelatedSubqueries(ctx, e, &decorPlan, result)
 				if err != nil {
 					return nil, fmt.Errorf("decorrelated subquery execution failed: %w", err)
@@ -276,7 +286,7 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 		// evaluated on a per-group basis and may be skipped if inputs aren't available
 		// in that specific group. Filter Keep to only include symbols in the relation.
 		if len(keepCols) > 0 && result != nil {
-			resultCols := result.Columns()
+			resultCols := result.Symbols()
 			colSet := make(map[query.Symbol]bool)
 			for _, col := range resultCols {
 				colSet[col] = true
@@ -316,7 +326,7 @@ func (e *Executor) applyExpressionsAndPredicates(ctx Context, phase *planner.Pha
 
 // filterWithPredicate filters a relation using a Predicate's Eval method
 func filterWithPredicate(rel Relation, pred query.Predicate) Relation {
-	columns := rel.Columns()
+	columns := rel.Symbols()
 
 	// Pre-allocate filtered only for materialized relations to avoid forcing materialization
 	var filtered []Tuple
@@ -329,9 +339,7 @@ func filterWithPredicate(rel Relation, pred query.Predicate) Relation {
 	// Reuse single bindings map to avoid repeated allocations
 	bindings := make(map[query.Symbol]interface{}, len(columns))
 
-	iter := rel.Iterator()
-	for iter.Next() {
-		tuple := iter.Tuple()
+	rel.ForEach(func(tuple Tuple) (bool, error) {
 
 		// Clear and populate bindings map
 		for k := range bindings {
@@ -346,13 +354,14 @@ func filterWithPredicate(rel Relation, pred query.Predicate) Relation {
 		if err != nil {
 			// Log error but continue processing
 			// TODO: Consider better error handling strategy
-			continue
+			return false, nil
 		}
 
 		if passes {
 			filtered = append(filtered, tuple)
 		}
-	}
+		return false, nil
+	})
 
 	// Extract options from source relation to preserve configuration
 	opts := rel.Options()
@@ -361,7 +370,7 @@ func filterWithPredicate(rel Relation, pred query.Predicate) Relation {
 
 // filterWithExpression filters a relation using an Expression that acts as a predicate (IsEquality = true)
 func filterWithExpression(rel Relation, expr *query.Expression) Relation {
-	columns := rel.Columns()
+	columns := rel.Symbols()
 
 	// Pre-allocate filtered only for materialized relations to avoid forcing materialization
 	var filtered []Tuple
@@ -374,9 +383,7 @@ func filterWithExpression(rel Relation, expr *query.Expression) Relation {
 	// Reuse single bindings map to avoid repeated allocations
 	bindings := make(map[query.Symbol]interface{}, len(columns))
 
-	iter := rel.Iterator()
-	for iter.Next() {
-		tuple := iter.Tuple()
+	rel.ForEach(func(tuple Tuple) (bool, error) {
 
 		// Clear and populate bindings map
 		for k := range bindings {
@@ -389,14 +396,15 @@ func filterWithExpression(rel Relation, expr *query.Expression) Relation {
 		// Evaluate the expression (should return a boolean)
 		result, err := expr.Function.Eval(bindings)
 		if err != nil {
-			continue
+			return false, nil
 		}
 
 		// Check if the result is true
 		if passes, ok := result.(bool); ok && passes {
 			filtered = append(filtered, tuple)
 		}
-	}
+		return false, nil
+	})
 
 	// Extract options from source relation to preserve configuration
 	opts := rel.Options()
@@ -405,7 +413,7 @@ func filterWithExpression(rel Relation, expr *query.Expression) Relation {
 
 // evaluateExpressionNew evaluates an expression and adds the result as a new column
 func evaluateExpressionNew(rel Relation, expr *query.Expression) Relation {
-	columns := rel.Columns()
+	columns := rel.Symbols()
 
 	// Add the binding column if it doesn't exist
 	hasBinding := false
@@ -433,9 +441,7 @@ func evaluateExpressionNew(rel Relation, expr *query.Expression) Relation {
 		}
 	}
 
-	iter := rel.Iterator()
-	for iter.Next() {
-		tuple := iter.Tuple()
+	rel.ForEach(func(tuple Tuple) (bool, error) {
 
 		// Clear and populate bindings map
 		for k := range bindings {
@@ -449,7 +455,7 @@ func evaluateExpressionNew(rel Relation, expr *query.Expression) Relation {
 		result, err := expr.Function.Eval(bindings)
 		if err != nil {
 			// Skip tuples where expression fails
-			continue
+			return false, nil
 		}
 
 		// Create new tuple with result
@@ -474,7 +480,8 @@ func evaluateExpressionNew(rel Relation, expr *query.Expression) Relation {
 			// No binding, just keep original tuple (shouldn't happen)
 			newTuples = append(newTuples, tuple)
 		}
-	}
+		return false, nil
+	})
 
 	// Extract options from source relation to preserve configuration
 	opts := rel.Options()
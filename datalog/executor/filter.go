@@ -149,7 +149,7 @@ func (f VariadicFilter) String() string {
 // FilterRelation applies a filter to a relation
 func FilterRelation(rel Relation, filter Filter) Relation {
 	// Check if all required symbols are present
-	cols := rel.Columns()
+	cols := rel.Symbols()
 	for _, sym := range filter.RequiredSymbols() {
 		found := false
 		for _, col := range cols {
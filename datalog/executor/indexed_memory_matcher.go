@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/wbrown/janus-datalog/datalog"
@@ -76,6 +77,10 @@ func (it *boundDatomIterator) Close() error {
 	return nil
 }
 
+func (it *boundDatomIterator) Err() error {
+	return nil
+}
+
 // NewIndexedMemoryMatcher creates a new indexed pattern matcher for in-memory datoms
 func NewIndexedMemoryMatcher(datoms []datalog.Datom) *IndexedMemoryMatcher {
 	return &IndexedMemoryMatcher{
@@ -203,6 +208,23 @@ func (m *IndexedMemoryMatcher) MatchWithConstraints(
 	return NewStreamingRelationWithOptions(columns, iterator, relOpts), nil
 }
 
+// MatchOrdered implements OrderedMatcher. The attribute's datoms are matched
+// and sorted by value, standing in for an AVET index scan.
+func (m *IndexedMemoryMatcher) MatchOrdered(pattern *query.DataPattern, descending bool) (Relation, error) {
+	m.buildIndices()
+
+	datoms := m.matchWithIndex(pattern, nil)
+	sort.SliceStable(datoms, func(i, j int) bool {
+		cmp := datalog.CompareValues(datoms[i].V, datoms[j].V)
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	return datomsToRelationWithOptions(datoms, pattern, pattern.ExtractColumns(), m.options), nil
+}
+
 // matchWithIndex performs indexed pattern matching
 func (m *IndexedMemoryMatcher) matchWithIndex(pattern *query.DataPattern, constraints []StorageConstraint) []datalog.Datom {
 	// Choose the best index based on which pattern elements are bound
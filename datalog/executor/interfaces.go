@@ -1,7 +1,10 @@
 package executor
 
 import (
+	"errors"
+
 	"github.com/wbrown/janus-datalog/datalog/constraints"
+	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -23,3 +26,27 @@ type PredicateAwareMatcher interface {
 		constraints []StorageConstraint,
 	) (Relation, error)
 }
+
+// IndexPinnedMatcher extends PatternMatcher with explicit index selection.
+// It backs planner.RealizedPlan.PinIndex: the pattern is scanned on the given
+// index without bindings, and the result is joined with the other relations
+// by the executor as usual.
+type IndexPinnedMatcher interface {
+	PatternMatcher
+	MatchWithIndex(pattern *query.DataPattern, index planner.IndexType) (Relation, error)
+}
+
+// ErrOrderedScanUnsupported is returned by OrderedMatcher.MatchOrdered when the
+// matcher cannot produce the pattern's tuples in value order
+var ErrOrderedScanUnsupported = errors.New("ordered scan not supported")
+
+// OrderedMatcher extends PatternMatcher with value-ordered scans.
+// It backs ordered streaming for :order-by (see planner.FindOrderedScan): the
+// pattern [?e :attr ?v] is scanned without bindings and its tuples are produced
+// in ascending (or descending) order of ?v as compared by datalog.CompareValues.
+// Matchers that cannot guarantee that order return ErrOrderedScanUnsupported
+// and the query is executed normally.
+type OrderedMatcher interface {
+	PatternMatcher
+	MatchOrdered(pattern *query.DataPattern, descending bool) (Relation, error)
+}
@@ -71,6 +71,11 @@ func (it *FilterIterator) Close() error {
 	return it.source.Close()
 }
 
+// Err returns the source iterator's error
+func (it *FilterIterator) Err() error {
+	return it.source.Err()
+}
+
 // ProjectIterator projects specific columns from the source relation
 type ProjectIterator struct {
 	relation   Relation // Source relation (may be cached/materialized)
@@ -135,6 +140,14 @@ func (it *ProjectIterator) Close() error {
 	return nil
 }
 
+// Err returns the source iterator's error
+func (it *ProjectIterator) Err() error {
+	if it.source != nil {
+		return it.source.Err()
+	}
+	return nil
+}
+
 // TransformIterator applies a transformation function to each tuple
 type TransformIterator struct {
 	source    Iterator
@@ -169,11 +182,17 @@ func (it *TransformIterator) Close() error {
 	return it.source.Close()
 }
 
+// Err returns the source iterator's error
+func (it *TransformIterator) Err() error {
+	return it.source.Err()
+}
+
 // ConcatIterator concatenates multiple iterators sequentially
 type ConcatIterator struct {
 	iterators []Iterator
 	current   int
 	tuple     Tuple
+	err       error
 }
 
 // NewConcatIterator creates a new concatenating iterator
@@ -191,7 +210,11 @@ func (it *ConcatIterator) Next() bool {
 			it.tuple = it.iterators[it.current].Tuple()
 			return true
 		}
-		// Current iterator exhausted, move to next
+		// Current iterator exhausted, move to next unless it failed
+		if err := it.iterators[it.current].Err(); err != nil {
+			it.err = err
+			return false
+		}
 		it.iterators[it.current].Close()
 		it.current++
 	}
@@ -215,6 +238,11 @@ func (it *ConcatIterator) Close() error {
 	return lastErr
 }
 
+// Err returns the error of the iterator that ended the concatenation, if any
+func (it *ConcatIterator) Err() error {
+	return it.err
+}
+
 // PredicateFilterIterator wraps another iterator and filters based on a query.Predicate
 type PredicateFilterIterator struct {
 	source    Iterator
@@ -264,6 +292,11 @@ func (it *PredicateFilterIterator) Close() error {
 	return it.source.Close()
 }
 
+// Err returns the source iterator's error
+func (it *PredicateFilterIterator) Err() error {
+	return it.source.Err()
+}
+
 // FunctionEvaluatorIterator adds a new column by evaluating a function
 type FunctionEvaluatorIterator struct {
 	source       Iterator
@@ -327,6 +360,11 @@ func (it *FunctionEvaluatorIterator) Close() error {
 	return it.source.Close()
 }
 
+// Err returns the source iterator's error
+func (it *FunctionEvaluatorIterator) Err() error {
+	return it.source.Err()
+}
+
 // DedupIterator removes duplicate tuples based on full tuple equality
 type DedupIterator struct {
 	source  Iterator
@@ -364,3 +402,8 @@ func (it *DedupIterator) Tuple() Tuple {
 func (it *DedupIterator) Close() error {
 	return it.source.Close()
 }
+
+// Err returns the source iterator's error
+func (it *DedupIterator) Err() error {
+	return it.source.Err()
+}
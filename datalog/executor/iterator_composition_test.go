@@ -36,6 +36,10 @@ func (it *mockIterator) Close() error {
 	return nil
 }
 
+func (it *mockIterator) Err() error {
+	return nil
+}
+
 func TestFilterIterator(t *testing.T) {
 	// Create test data
 	tuples := []Tuple{
@@ -0,0 +1,233 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+var errScanFailed = errors.New("scan failed")
+
+// failingIterator yields the tuples of its source until limit have been
+// returned, then stops with errScanFailed, like a storage scan hitting a
+// read error
+type failingIterator struct {
+	source Iterator
+	limit  int
+	err    error
+}
+
+func (it *failingIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.limit == 0 {
+		it.err = errScanFailed
+		return false
+	}
+	if !it.source.Next() {
+		return false
+	}
+	it.limit--
+	return true
+}
+
+func (it *failingIterator) Tuple() Tuple { return it.source.Tuple() }
+func (it *failingIterator) Close() error { return it.source.Close() }
+func (it *failingIterator) Err() error   { return it.err }
+
+func newFailingRelation(tuples []Tuple, limit int) *StreamingRelation {
+	source := &sliceIterator{tuples: tuples, pos: -1}
+	return NewStreamingRelation([]query.Symbol{"?x", "?y"}, &failingIterator{source: source, limit: limit})
+}
+
+// failingMatcher fails the scans of one attribute part way
+type failingMatcher struct {
+	PatternMatcher
+	attr string
+}
+
+func (m *failingMatcher) Match(pattern *query.DataPattern, bindings Relations) (Relation, error) {
+	rel, err := m.PatternMatcher.Match(pattern, bindings)
+	if err != nil {
+		return nil, err
+	}
+	return m.fail(pattern, rel), nil
+}
+
+// MatchOrdered fails the ordered scans of the attribute part way too
+func (m *failingMatcher) MatchOrdered(pattern *query.DataPattern, descending bool) (Relation, error) {
+	om, ok := m.PatternMatcher.(OrderedMatcher)
+	if !ok {
+		return nil, ErrOrderedScanUnsupported
+	}
+	rel, err := om.MatchOrdered(pattern, descending)
+	if err != nil {
+		return nil, err
+	}
+	return m.fail(pattern, rel), nil
+}
+
+func (m *failingMatcher) fail(pattern *query.DataPattern, rel Relation) Relation {
+	if c, ok := pattern.GetA().(query.Constant); ok && c.Value == datalog.NewKeyword(m.attr) {
+		return NewStreamingRelation(rel.Symbols(), &failingIterator{source: rel.Iterator(), limit: 5})
+	}
+	return rel
+}
+
+func TestIteratorErr(t *testing.T) {
+	tuples := []Tuple{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}}
+
+	t.Run("ForEach", func(t *testing.T) {
+		visited := 0
+		err := newFailingRelation(tuples, 2).ForEach(func(Tuple) (bool, error) {
+			visited++
+			return false, nil
+		})
+		if !errors.Is(err, errScanFailed) {
+			t.Errorf("Expected scan error, got %v", err)
+		}
+		if visited != 2 {
+			t.Errorf("Expected 2 tuples before the error, got %d", visited)
+		}
+	})
+
+	t.Run("Composition", func(t *testing.T) {
+		filtered := NewFilterIterator(&failingIterator{source: &sliceIterator{tuples: tuples, pos: -1}, limit: 1},
+			[]query.Symbol{"?x", "?y"}, NewSimpleFilter(func(Tuple) bool { return true }))
+		concat := NewConcatIterator(filtered, &sliceIterator{tuples: tuples, pos: -1})
+		count := 0
+		for concat.Next() {
+			count++
+		}
+		if !errors.Is(concat.Err(), errScanFailed) {
+			t.Errorf("Expected scan error through filter and concat, got %v", concat.Err())
+		}
+		if count != 1 {
+			t.Errorf("Expected concatenation to stop at the error after 1 tuple, got %d", count)
+		}
+	})
+
+	t.Run("CachedReplay", func(t *testing.T) {
+		rel := newFailingRelation(tuples, 2)
+		rel.Materialize()
+		for i := 0; i < 2; i++ {
+			it := rel.Iterator()
+			for it.Next() {
+			}
+			if !errors.Is(it.Err(), errScanFailed) {
+				t.Errorf("Iteration %d: expected scan error, got %v", i, it.Err())
+			}
+			it.Close()
+		}
+		if !errors.Is(rel.Err(), errScanFailed) {
+			t.Errorf("Expected relation to report scan error, got %v", rel.Err())
+		}
+	})
+
+	t.Run("HashJoin", func(t *testing.T) {
+		other := NewMaterializedRelation([]query.Symbol{"?x", "?z"}, []Tuple{{int64(1), true}, {int64(3), false}})
+		for _, streaming := range []bool{false, true} {
+			joined := HashJoinWithOptions(newFailingRelation(tuples, 1), other, []query.Symbol{"?x"},
+				ExecutorOptions{EnableStreamingJoins: streaming})
+			err := joined.ForEach(func(Tuple) (bool, error) { return false, nil })
+			if !errors.Is(err, errScanFailed) {
+				t.Errorf("Streaming=%v: expected join to fail with scan error, got %v", streaming, err)
+			}
+		}
+	})
+
+	t.Run("Aggregation", func(t *testing.T) {
+		find := []query.FindElement{
+			query.FindVariable{Symbol: "?y"},
+			query.FindAggregate{Function: "count", Arg: "?x"},
+		}
+		if err := RelationErr(ExecuteAggregations(newFailingRelation(tuples, 2), find)); !errors.Is(err, errScanFailed) {
+			t.Errorf("Expected grouped aggregation to fail with scan error, got %v", err)
+		}
+		single := []query.FindElement{query.FindAggregate{Function: "sum", Arg: "?x"}}
+		if err := RelationErr(ExecuteAggregations(newFailingRelation(tuples, 2), single)); !errors.Is(err, errScanFailed) {
+			t.Errorf("Expected aggregation to fail with scan error, got %v", err)
+		}
+	})
+}
+
+func TestQueryScanError(t *testing.T) {
+	queries := []string{
+		`[:find ?s (sum ?c) :where [?b :price/symbol ?s] [?b :price/close ?c]]`,
+		`[:find ?b ?c :where [?b :price/close ?c] :order-by [[?c :desc]]]`,
+		`[:find ?b ?c :where [?b :price/close ?c]]`,
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for i, queryStr := range queries {
+			t.Run(fmt.Sprintf("QueryExecutor=%v/%d", useQueryExecutor, i), func(t *testing.T) {
+				q, err := parser.ParseQuery(queryStr)
+				if err != nil {
+					t.Fatalf("failed to parse query: %v", err)
+				}
+
+				matcher := &failingMatcher{PatternMatcher: NewMemoryPatternMatcher(latestPerEntityDatoms()), attr: ":price/close"}
+				result, err := NewExecutorWithOptions(matcher, planner.PlannerOptions{
+					UseQueryExecutor: useQueryExecutor,
+				}).Execute(q)
+				if err == nil {
+					// Streaming results report the error when iterated
+					err = result.ForEach(func(Tuple) (bool, error) { return false, nil })
+				}
+				if !errors.Is(err, errScanFailed) {
+					t.Errorf("Expected scan error, got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestOptimizedQueryScanError(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		attr    string
+		options planner.PlannerOptions
+	}{
+		{
+			name:    "OrderedScan",
+			query:   `{:query [:find ?b ?c :where [?b :price/close ?c] [?b :price/time ?t] :order-by [?c]] :limit 5}`,
+			attr:    ":price/close",
+			options: planner.PlannerOptions{EnableOrderedScan: true},
+		},
+		{
+			name: "SharedPatterns",
+			query: `[:find ?s ?c ?n
+			         :where [?b :price/symbol ?s]
+			                [?b :price/close ?c]
+			                [(q [:find (count ?bar) :in $ ?sym
+			                     :where [?bar :price/symbol ?sym]] $ ?s) [[?n]]]]`,
+			attr:    ":price/symbol",
+			options: planner.PlannerOptions{EnableSharedPatterns: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			matcher := &failingMatcher{PatternMatcher: NewMemoryPatternMatcher(latestPerEntityDatoms()), attr: tt.attr}
+			result, err := NewExecutorWithOptions(matcher, tt.options).Execute(q)
+			if err == nil {
+				err = result.ForEach(func(Tuple) (bool, error) { return false, nil })
+			}
+			if !errors.Is(err, errScanFailed) {
+				t.Errorf("Expected scan error, got %v", err)
+			}
+		})
+	}
+}
@@ -19,9 +19,7 @@ type hashJoinIterator struct {
 	probeIt      Iterator
 	seen         *TupleKeyMap
 	buildIsLeft  bool
-	joinCols     []query.Symbol
-	leftCols     []query.Symbol
-	rightCols    []query.Symbol
+	layout       *joinLayout
 	probeIndices []int
 	options      ExecutorOptions
 
@@ -52,9 +50,9 @@ func (it *hashJoinIterator) Next() bool {
 			// Combine tuples
 			var joined Tuple
 			if it.buildIsLeft {
-				joined = combineTuples(buildTuple, it.currentProbeTuple, it.joinCols, it.leftCols, it.rightCols)
+				joined = it.layout.combine(buildTuple, it.currentProbeTuple)
 			} else {
-				joined = combineTuples(it.currentProbeTuple, buildTuple, it.joinCols, it.leftCols, it.rightCols)
+				joined = it.layout.combine(it.currentProbeTuple, buildTuple)
 			}
 
 			// Check for duplicates using seen map
@@ -111,6 +109,12 @@ func (it *hashJoinIterator) Tuple() Tuple {
 	return it.currentJoined
 }
 
+// Err returns the probe side's error; build side errors fail the join before
+// the iterator is created
+func (it *hashJoinIterator) Err() error {
+	return it.probeIt.Err()
+}
+
 func (it *hashJoinIterator) Close() error {
 	if !it.closed {
 		it.closed = true
@@ -161,8 +165,8 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 		if opts.EnableDebugLogging {
 			fmt.Printf("[HashJoin] Called with left (type=%T, size=%d), right (type=%T, size=%d), joinCols=%v, EnableStreamingJoins=%v\n",
 				left, leftSize, right, rightSize, joinCols, opts.EnableStreamingJoins)
-			fmt.Printf("[HashJoin] Left columns: %v\n", left.Columns())
-			fmt.Printf("[HashJoin] Right columns: %v\n", right.Columns())
+			fmt.Printf("[HashJoin] Left columns: %v\n", left.Symbols())
+			fmt.Printf("[HashJoin] Right columns: %v\n", right.Symbols())
 
 			// Debug: check left relation's shouldCache flag if it's a StreamingRelation
 			if sr, ok := left.(*StreamingRelation); ok {
@@ -189,18 +193,8 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 	}
 
 	// Determine output columns (union without duplicates)
-	outputCols := append([]query.Symbol{}, left.Columns()...)
-	rightColSet := make(map[query.Symbol]bool)
-	for _, col := range joinCols {
-		rightColSet[col] = true
-	}
-
-	// Add right columns that aren't in join columns
-	for _, col := range right.Columns() {
-		if !rightColSet[col] {
-			outputCols = append(outputCols, col)
-		}
-	}
+	layout := mustJoinLayout(left.Symbols(), right.Symbols(), joinCols, opts.DuplicateColumns)
+	outputCols := layout.columns
 
 	// Choose smaller relation to build hash table
 	var buildRel, probeRel Relation
@@ -279,7 +273,7 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 	// Check if any column name matches transaction ID patterns
 	// We'll verify the actual type on the first tuple during iteration
 	txIndex := -1
-	for i, col := range buildRel.Columns() {
+	for i, col := range buildRel.Symbols() {
 		if col == query.Symbol("?tx") || col == query.Symbol("?t") ||
 			col == query.Symbol("?txid") || col == query.Symbol("?transaction") {
 			txIndex = i
@@ -476,6 +470,11 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 		}
 	}
 
+	// A build side that failed part way would silently drop matches
+	if err := buildIt.Err(); err != nil {
+		return newFailedRelation(outputCols, fmt.Errorf("hash join build failed: %w", err), opts)
+	}
+
 	// Probe phase - find matches
 	// Check if streaming mode is enabled
 	if opts.EnableStreamingJoins {
@@ -508,9 +507,7 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 			probeIt:      probeRel.Iterator(),
 			seen:         NewTupleKeyMapWithCapacity(expectedResults),
 			buildIsLeft:  buildIsLeft,
-			joinCols:     joinCols,
-			leftCols:     left.Columns(),
-			rightCols:    right.Columns(),
+			layout:       layout,
 			probeIndices: probeIndices,
 			options:      opts,
 			matchIdx:     0,
@@ -581,9 +578,9 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 				// Combine tuples
 				var joined Tuple
 				if buildIsLeft {
-					joined = combineTuples(buildTuple, probeTuple, joinCols, left.Columns(), right.Columns())
+					joined = layout.combine(buildTuple, probeTuple)
 				} else {
-					joined = combineTuples(probeTuple, buildTuple, joinCols, left.Columns(), right.Columns())
+					joined = layout.combine(probeTuple, buildTuple)
 				}
 
 				// Create a key for deduplication based on all tuple values
@@ -600,6 +597,9 @@ func HashJoinWithOptions(left, right Relation, joinCols []query.Symbol, opts Exe
 		fmt.Printf("[HashJoin] Probe phase complete: probed %d tuples, found %d matches, produced %d results\n",
 			probeCount, matchCount, len(results))
 	}
+	if err := probeIt.Err(); err != nil {
+		return newFailedRelation(outputCols, fmt.Errorf("hash join probe failed: %w", err), opts)
+	}
 
 	// We already deduplicated with 'seen', no need to do it again
 	return NewMaterializedRelationNoDedupeWithOptions(outputCols, results, opts)
@@ -632,6 +632,9 @@ func SemiJoin(left, right Relation, joinCols []query.Symbol) Relation {
 		key := NewTupleKey(tuple, rightIndices)
 		rightKeys.Put(key, true)
 	}
+	if err := rightIt.Err(); err != nil {
+		return newFailedRelation(left.Symbols(), err, opts)
+	}
 
 	// Filter left relation
 	var results []Tuple
@@ -645,8 +648,11 @@ func SemiJoin(left, right Relation, joinCols []query.Symbol) Relation {
 			results = append(results, tuple)
 		}
 	}
+	if err := leftIt.Err(); err != nil {
+		return newFailedRelation(left.Symbols(), err, opts)
+	}
 
-	return NewMaterializedRelationWithOptions(left.Columns(), results, opts)
+	return NewMaterializedRelationWithOptions(left.Symbols(), results, opts)
 }
 
 // AntiJoin returns tuples from left that have no matches in right
@@ -676,6 +682,9 @@ func AntiJoin(left, right Relation, joinCols []query.Symbol) Relation {
 		key := NewTupleKey(tuple, rightIndices)
 		rightKeys.Put(key, true)
 	}
+	if err := rightIt.Err(); err != nil {
+		return newFailedRelation(left.Symbols(), err, opts)
+	}
 
 	// Filter left relation
 	var results []Tuple
@@ -689,8 +698,11 @@ func AntiJoin(left, right Relation, joinCols []query.Symbol) Relation {
 			results = append(results, tuple)
 		}
 	}
+	if err := leftIt.Err(); err != nil {
+		return newFailedRelation(left.Symbols(), err, opts)
+	}
 
-	return NewMaterializedRelationWithOptions(left.Columns(), results, opts)
+	return NewMaterializedRelationWithOptions(left.Symbols(), results, opts)
 }
 
 // Helper functions
@@ -700,39 +712,6 @@ func isStreaming(rel Relation) bool {
 	return ok
 }
 
-func combineTuples(left, right Tuple, joinCols []query.Symbol, leftCols, rightCols []query.Symbol) Tuple {
-	// Create set of join columns for quick lookup
-	joinSet := make(map[query.Symbol]bool, len(joinCols))
-	for _, col := range joinCols {
-		joinSet[col] = true
-	}
-
-	// Calculate exact result size to avoid repeated allocations
-	rightNonJoinCount := 0
-	for _, col := range rightCols {
-		if !joinSet[col] {
-			rightNonJoinCount++
-		}
-	}
-
-	// Pre-allocate result with exact size
-	result := make(Tuple, len(left)+rightNonJoinCount)
-
-	// Copy left tuple
-	copy(result, left)
-
-	// Add values from right that aren't join columns
-	offset := len(left)
-	for i, col := range rightCols {
-		if !joinSet[col] {
-			result[offset] = right[i]
-			offset++
-		}
-	}
-
-	return result
-}
-
 func crossProduct(left, right Relation) Relation {
 	// Warning: This can be very expensive!
 	// Extract options from left relation
@@ -741,7 +720,7 @@ func crossProduct(left, right Relation) Relation {
 		opts = right.Options()
 	}
 
-	outputCols := append(left.Columns(), right.Columns()...)
+	outputCols := append(left.Symbols(), right.Symbols()...)
 	var results []Tuple
 
 	leftIt := left.Iterator()
@@ -758,7 +737,14 @@ func crossProduct(left, right Relation) Relation {
 			combined := append(append(Tuple{}, leftTuple...), rightTuple...)
 			results = append(results, combined)
 		}
+		err := rightIt.Err()
 		rightIt.Close()
+		if err != nil {
+			return newFailedRelation(outputCols, err, opts)
+		}
+	}
+	if err := leftIt.Err(); err != nil {
+		return newFailedRelation(outputCols, err, opts)
 	}
 
 	return NewMaterializedRelationWithOptions(outputCols, results, opts)
@@ -0,0 +1,161 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// DuplicateColumnPolicy decides what an equi-join does with a column that
+// both inputs carry but that is not one of the join columns. A natural join
+// never sees one, since it joins on every shared column; they arise when a
+// caller joins on a subset, e.g. renamed subquery outputs that reuse a name.
+type DuplicateColumnPolicy int
+
+const (
+	// DuplicateColumnsCoalesce keeps a single column holding the left value,
+	// or the right value where the left one is nil
+	DuplicateColumnsCoalesce DuplicateColumnPolicy = iota
+	// DuplicateColumnsPrefix keeps both columns, renaming the right one
+	// with DuplicateColumnPrefix (?x becomes ?right.x)
+	DuplicateColumnsPrefix
+	// DuplicateColumnsError rejects the join with ErrDuplicateColumn
+	DuplicateColumnsError
+)
+
+// DuplicateColumnPrefix is prepended to right-hand duplicate columns under
+// DuplicateColumnsPrefix
+const DuplicateColumnPrefix = "right."
+
+// ErrDuplicateColumn reports a non-join column present on both sides of a
+// join under DuplicateColumnsError
+var ErrDuplicateColumn = errors.New("duplicate non-join column")
+
+func (p DuplicateColumnPolicy) String() string {
+	switch p {
+	case DuplicateColumnsCoalesce:
+		return "coalesce"
+	case DuplicateColumnsPrefix:
+		return "prefix"
+	case DuplicateColumnsError:
+		return "error"
+	default:
+		return fmt.Sprintf("DuplicateColumnPolicy(%d)", int(p))
+	}
+}
+
+// JoinColumns returns the output columns of an equi-join of relations with
+// the given columns on joinCols: the left columns followed by the right
+// columns that are not join columns, with duplicates resolved by policy.
+func JoinColumns(leftCols, rightCols, joinCols []query.Symbol, policy DuplicateColumnPolicy) ([]query.Symbol, error) {
+	layout, err := newJoinLayout(leftCols, rightCols, joinCols, policy)
+	if err != nil {
+		return nil, err
+	}
+	return layout.columns, nil
+}
+
+// joinLayout describes how a joined tuple is assembled from its inputs
+type joinLayout struct {
+	columns  []query.Symbol
+	leftLen  int
+	appended []int    // Right indices appended after the left tuple
+	coalesce [][2]int // Output index filled from right index where it is nil
+}
+
+func newJoinLayout(leftCols, rightCols, joinCols []query.Symbol, policy DuplicateColumnPolicy) (*joinLayout, error) {
+	isJoinCol := make(map[query.Symbol]bool, len(joinCols))
+	for _, col := range joinCols {
+		isJoinCol[col] = true
+	}
+	// Output position of each column name taken so far
+	outIndex := make(map[query.Symbol]int, len(leftCols)+len(rightCols))
+	for i, col := range leftCols {
+		if _, seen := outIndex[col]; !seen {
+			outIndex[col] = i
+		}
+	}
+
+	layout := &joinLayout{
+		columns: append([]query.Symbol{}, leftCols...),
+		leftLen: len(leftCols),
+	}
+	appendColumn := func(col query.Symbol, rightIdx int) {
+		outIndex[col] = len(layout.columns)
+		layout.columns = append(layout.columns, col)
+		layout.appended = append(layout.appended, rightIdx)
+	}
+
+	var duplicates []query.Symbol
+	for i, col := range rightCols {
+		if isJoinCol[col] {
+			continue
+		}
+		pos, dup := outIndex[col]
+		if !dup {
+			appendColumn(col, i)
+			continue
+		}
+		switch policy {
+		case DuplicateColumnsCoalesce:
+			layout.coalesce = append(layout.coalesce, [2]int{pos, i})
+		case DuplicateColumnsPrefix:
+			appendColumn(prefixedColumn(col, outIndex), i)
+		default:
+			duplicates = append(duplicates, col)
+		}
+	}
+
+	if len(duplicates) > 0 {
+		return nil, fmt.Errorf("%w: %v joining %v with %v on %v", ErrDuplicateColumn, duplicates, leftCols, rightCols, joinCols)
+	}
+	return layout, nil
+}
+
+// mustJoinLayout is newJoinLayout for joins that return a Relation and so
+// cannot report an error. Under DuplicateColumnsError a collision panics
+// with an error wrapping ErrDuplicateColumn; check with JoinColumns first.
+func mustJoinLayout(leftCols, rightCols, joinCols []query.Symbol, policy DuplicateColumnPolicy) *joinLayout {
+	layout, err := newJoinLayout(leftCols, rightCols, joinCols, policy)
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+// prefixedColumn renames a duplicate right column, keeping the ? of a
+// variable in front and numbering the name if it is still taken
+func prefixedColumn(col query.Symbol, taken map[query.Symbol]int) query.Symbol {
+	name := string(col)
+	var renamed string
+	if strings.HasPrefix(name, "?") {
+		renamed = "?" + DuplicateColumnPrefix + name[1:]
+	} else {
+		renamed = DuplicateColumnPrefix + name
+	}
+	candidate := query.Symbol(renamed)
+	for n := 2; ; n++ {
+		if _, ok := taken[candidate]; !ok {
+			break
+		}
+		candidate = query.Symbol(fmt.Sprintf("%s%d", renamed, n))
+	}
+	return candidate
+}
+
+// combine builds the joined tuple for a matching left and right tuple
+func (l *joinLayout) combine(left, right Tuple) Tuple {
+	result := make(Tuple, len(l.columns))
+	copy(result, left)
+	for j, ri := range l.appended {
+		result[l.leftLen+j] = right[ri]
+	}
+	for _, c := range l.coalesce {
+		if result[c[0]] == nil {
+			result[c[0]] = right[c[1]]
+		}
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// Both inputs carry ?total, e.g. from two subqueries whose outputs were
+// renamed to the same variable, but the join is only on ?e
+func duplicateColumnInputs() (Relation, Relation) {
+	left := NewMaterializedRelation(
+		[]query.Symbol{"?e", "?total"},
+		[]Tuple{{"a", int64(1)}, {"b", nil}},
+	)
+	right := NewMaterializedRelation(
+		[]query.Symbol{"?e", "?total", "?name"},
+		[]Tuple{{"a", int64(10), "Alice"}, {"b", int64(20), "Bob"}},
+	)
+	return left, right
+}
+
+func TestJoinDuplicateColumns(t *testing.T) {
+	joinCols := []query.Symbol{"?e"}
+
+	tests := []struct {
+		policy  DuplicateColumnPolicy
+		columns []query.Symbol
+		tuples  []Tuple
+	}{
+		{
+			policy:  DuplicateColumnsCoalesce,
+			columns: []query.Symbol{"?e", "?total", "?name"},
+			tuples:  []Tuple{{"a", int64(1), "Alice"}, {"b", int64(20), "Bob"}},
+		},
+		{
+			policy:  DuplicateColumnsPrefix,
+			columns: []query.Symbol{"?e", "?total", "?right.total", "?name"},
+			tuples:  []Tuple{{"a", int64(1), int64(10), "Alice"}, {"b", nil, int64(20), "Bob"}},
+		},
+	}
+
+	joins := map[string]func(left, right Relation, opts ExecutorOptions) Relation{
+		"hash": func(left, right Relation, opts ExecutorOptions) Relation {
+			return HashJoinWithOptions(left, right, joinCols, opts)
+		},
+		"streaming": func(left, right Relation, opts ExecutorOptions) Relation {
+			opts.EnableStreamingJoins = true
+			return HashJoinWithOptions(left, right, joinCols, opts)
+		},
+		"symmetric": func(left, right Relation, opts ExecutorOptions) Relation {
+			return SymmetricHashJoinWithOptions(left, right, joinCols, opts)
+		},
+	}
+
+	for _, tt := range tests {
+		for name, join := range joins {
+			t.Run(tt.policy.String()+"/"+name, func(t *testing.T) {
+				left, right := duplicateColumnInputs()
+				result := join(left, right, ExecutorOptions{DuplicateColumns: tt.policy})
+
+				if !reflect.DeepEqual(result.Columns(), tt.columns) {
+					t.Errorf("columns = %v, want %v", result.Columns(), tt.columns)
+				}
+				got := result.Sorted()
+				if !reflect.DeepEqual(got, tt.tuples) {
+					t.Errorf("tuples = %v, want %v", got, tt.tuples)
+				}
+			})
+		}
+	}
+}
+
+func TestJoinDuplicateColumnsError(t *testing.T) {
+	left, right := duplicateColumnInputs()
+	joinCols := []query.Symbol{"?e"}
+
+	_, err := JoinColumns(left.Columns(), right.Columns(), joinCols, DuplicateColumnsError)
+	if !errors.Is(err, ErrDuplicateColumn) {
+		t.Fatalf("JoinColumns: expected ErrDuplicateColumn, got %v", err)
+	}
+
+	// Joining on every shared column has no duplicates to reject
+	cols, err := JoinColumns(left.Columns(), right.Columns(), []query.Symbol{"?e", "?total"}, DuplicateColumnsError)
+	if err != nil {
+		t.Fatalf("JoinColumns on all shared columns: %v", err)
+	}
+	if want := []query.Symbol{"?e", "?total", "?name"}; !reflect.DeepEqual(cols, want) {
+		t.Errorf("columns = %v, want %v", cols, want)
+	}
+
+	defer func() {
+		r := recover()
+		if err, ok := r.(error); !ok || !errors.Is(err, ErrDuplicateColumn) {
+			t.Errorf("HashJoin: expected panic with ErrDuplicateColumn, got %v", r)
+		}
+	}()
+	HashJoinWithOptions(left, right, joinCols, ExecutorOptions{DuplicateColumns: DuplicateColumnsError})
+}
+
+func TestPrefixedColumnCollision(t *testing.T) {
+	// The renamed column is itself taken, e.g. after chained joins
+	cols, err := JoinColumns(
+		[]query.Symbol{"?e", "?x", "?right.x"},
+		[]query.Symbol{"?e", "?x", "(max ?v)", "(max ?v)"},
+		[]query.Symbol{"?e"},
+		DuplicateColumnsPrefix,
+	)
+	if err != nil {
+		t.Fatalf("JoinColumns: %v", err)
+	}
+	want := []query.Symbol{"?e", "?x", "?right.x", "?right.x2", "(max ?v)", "right.(max ?v)"}
+	if !reflect.DeepEqual(cols, want) {
+		t.Errorf("columns = %v, want %v", cols, want)
+	}
+}
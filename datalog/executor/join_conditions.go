@@ -104,7 +104,7 @@ func MultiColumnHashJoin(left, right Relation, leftCols, rightCols []query.Symbo
 	}
 
 	// Determine output columns
-	outputCols := append([]query.Symbol{}, left.Columns()...)
+	outputCols := append([]query.Symbol{}, left.Symbols()...)
 
 	// Track which right columns are part of join conditions or already exist
 	skipRightCols := make(map[query.Symbol]bool)
@@ -120,8 +120,8 @@ func MultiColumnHashJoin(left, right Relation, leftCols, rightCols []query.Symbo
 	}
 
 	// Also skip columns that naturally exist in both relations
-	for _, leftCol := range left.Columns() {
-		for _, rightCol := range right.Columns() {
+	for _, leftCol := range left.Symbols() {
+		for _, rightCol := range right.Symbols() {
 			if leftCol == rightCol {
 				skipRightCols[rightCol] = true
 			}
@@ -129,7 +129,7 @@ func MultiColumnHashJoin(left, right Relation, leftCols, rightCols []query.Symbo
 	}
 
 	// Add right columns that we're not skipping
-	for _, col := range right.Columns() {
+	for _, col := range right.Symbols() {
 		if !skipRightCols[col] {
 			outputCols = append(outputCols, col)
 		}
@@ -186,7 +186,7 @@ func MultiColumnHashJoin(left, right Relation, leftCols, rightCols []query.Symbo
 
 				// Add right columns that aren't being skipped
 				rightIdx := 0
-				for _, col := range right.Columns() {
+				for _, col := range right.Symbols() {
 					if !skipRightCols[col] && rightIdx < len(rightTuple) {
 						outputTuple = append(outputTuple, rightTuple[rightIdx])
 					}
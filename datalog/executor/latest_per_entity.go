@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// executeLatestPerEntity executes q's latest-per-entity subquery (see
+// planner.FindLatestPerEntity) as one ordered scan instead of an
+// aggregation per group.
+//
+// The link pattern is matched once to map each entity to its groups. The
+// order pattern is then scanned in value order, and the first rows seen for
+// each group (all rows sharing that group's first value, so ties are kept)
+// are emitted as (?b ?s ?t) bindings. The scan stops once every group has
+// moved past its first value, and the rest of the query is executed on the
+// bindings.
+//
+// Returns ok=false if the query has no such subquery or the matcher cannot
+// scan in order; the query should then be executed normally.
+func (e *Executor) executeLatestPerEntity(ctx Context, q *query.Query) (Relation, bool, error) {
+	scan, ok := planner.FindLatestPerEntity(q)
+	if !ok {
+		return nil, false, nil
+	}
+	om, ok := e.matcher.(OrderedMatcher)
+	if !ok {
+		return nil, false, nil
+	}
+
+	start := time.Now()
+	driving, err := om.MatchOrdered(scan.Order, scan.Descending)
+	if errors.Is(err, ErrOrderedScanUnsupported) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("ordered scan of %s failed: %w", scan.Order, err)
+	}
+
+	groups, err := e.matchLinks(scan)
+	if err != nil {
+		return nil, true, err
+	}
+
+	entityIdx := ColumnIndex(driving, scan.Entity)
+	valueIdx := ColumnIndex(driving, scan.Value)
+	if entityIdx < 0 || valueIdx < 0 {
+		return nil, true, fmt.Errorf("ordered scan of %s did not bind %s and %s", scan.Order, scan.Entity, scan.Value)
+	}
+
+	// first holds each group's first value; a group is done once a later
+	// value is seen for it
+	first := NewTupleKeyMap()
+	done := NewTupleKeyMap()
+	remaining := groups.distinct
+	var bindings []Tuple
+	scanned := 0
+
+	it := driving.Iterator()
+	for remaining > 0 && it.Next() {
+		tuple := it.Tuple()
+		scanned++
+		entityGroups, ok := groups.byEntity.Get(NewTupleKeyFull(Tuple{tuple[entityIdx]}))
+		if !ok {
+			continue
+		}
+		value := tuple[valueIdx]
+		for _, group := range entityGroups.([]interface{}) {
+			key := NewTupleKeyFull(Tuple{group})
+			if done.Exists(key) {
+				continue
+			}
+			if v, seen := first.Get(key); seen && !datalog.ValuesEqual(v, value) {
+				done.Put(key, true)
+				remaining--
+				continue
+			}
+			first.Put(key, value)
+			bindings = append(bindings, Tuple{tuple[entityIdx], group, value})
+		}
+	}
+	if err := it.Err(); err != nil {
+		it.Close()
+		return nil, true, fmt.Errorf("ordered scan of %s failed: %w", scan.Order, err)
+	}
+	it.Close()
+
+	if collector := ctx.Collector(); collector != nil {
+		collector.AddTiming("latest_per_entity/scan", start, map[string]interface{}{
+			"pattern":  scan.Order.String(),
+			"group_by": scan.Group,
+			"groups":   groups.distinct,
+			"scanned":  scanned,
+			"bindings": len(bindings),
+			"stopped":  remaining == 0,
+		})
+	}
+
+	remainder := scan.Remainder(q)
+	rel := NewMaterializedRelationWithOptions([]query.Symbol{scan.Entity, scan.Group, scan.Value}, bindings, e.options)
+	result, err := e.executeWithRelations(ctx, remainder, []Relation{rel})
+	if err != nil {
+		return nil, true, err
+	}
+	return result, true, nil
+}
+
+// latestGroups maps entities to the groups they link to
+type latestGroups struct {
+	byEntity *TupleKeyMap // entity -> []interface{} groups
+	distinct int
+}
+
+// matchLinks matches the link pattern [?b :link ?s] of a latest-per-entity
+// scan without bindings
+func (e *Executor) matchLinks(scan *planner.LatestPerEntity) (latestGroups, error) {
+	links, err := e.matcher.Match(scan.Link, nil)
+	if err != nil {
+		return latestGroups{}, fmt.Errorf("matching %s failed: %w", scan.Link, err)
+	}
+	entityIdx := ColumnIndex(links, scan.Entity)
+	groupIdx := ColumnIndex(links, scan.Group)
+	if entityIdx < 0 || groupIdx < 0 {
+		return latestGroups{}, fmt.Errorf("%s did not bind %s and %s", scan.Link, scan.Entity, scan.Group)
+	}
+
+	groups := latestGroups{byEntity: NewTupleKeyMap()}
+	seen := NewTupleKeyMap()
+	it := links.Iterator()
+	defer it.Close()
+	for it.Next() {
+		tuple := it.Tuple()
+		entity, group := tuple[entityIdx], tuple[groupIdx]
+		key := NewTupleKeyFull(Tuple{entity})
+		existing, _ := groups.byEntity.Get(key)
+		entityGroups, _ := existing.([]interface{})
+		groups.byEntity.Put(key, append(entityGroups, group))
+
+		groupKey := NewTupleKeyFull(Tuple{group})
+		if !seen.Exists(groupKey) {
+			seen.Put(groupKey, true)
+			groups.distinct++
+		}
+	}
+	if err := it.Err(); err != nil {
+		return latestGroups{}, fmt.Errorf("matching %s failed: %w", scan.Link, err)
+	}
+	return groups, nil
+}
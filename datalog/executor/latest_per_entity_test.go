@@ -0,0 +1,163 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+// latestPerEntityDatoms returns bars for several symbols, where the last two
+// bars of sym:0 share a time and bar:both belongs to two symbols
+func latestPerEntityDatoms() []datalog.Datom {
+	base := time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)
+	minute := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+
+	symbolAttr := datalog.NewKeyword(":price/symbol")
+	timeAttr := datalog.NewKeyword(":price/time")
+	closeAttr := datalog.NewKeyword(":price/close")
+
+	var datoms []datalog.Datom
+	for s := 0; s < 4; s++ {
+		sym := datalog.NewIdentity(fmt.Sprintf("sym:%d", s))
+		datoms = append(datoms, datalog.Datom{E: sym, A: datalog.NewKeyword(":symbol/ticker"), V: fmt.Sprintf("T%d", s), Tx: 1})
+		for i := 0; i < 20; i++ {
+			bar := datalog.NewIdentity(fmt.Sprintf("bar:%d:%d", s, i))
+			t := minute(i*10 + s)
+			if s == 0 && i == 19 {
+				t = minute(18*10 + s) // Tie with the previous bar
+			}
+			datoms = append(datoms,
+				datalog.Datom{E: bar, A: symbolAttr, V: sym, Tx: 1},
+				datalog.Datom{E: bar, A: timeAttr, V: t, Tx: 1},
+				datalog.Datom{E: bar, A: closeAttr, V: float64(100*s + i), Tx: 1},
+			)
+		}
+	}
+
+	both := datalog.NewIdentity("bar:both")
+	datoms = append(datoms,
+		datalog.Datom{E: both, A: symbolAttr, V: datalog.NewIdentity("sym:2"), Tx: 1},
+		datalog.Datom{E: both, A: symbolAttr, V: datalog.NewIdentity("sym:3"), Tx: 1},
+		datalog.Datom{E: both, A: timeAttr, V: minute(5), Tx: 1},
+		datalog.Datom{E: both, A: closeAttr, V: float64(-1), Tx: 1},
+	)
+	return datoms
+}
+
+func TestLatestPerEntity(t *testing.T) {
+	datoms := latestPerEntityDatoms()
+
+	queries := []string{
+		// Latest close per symbol
+		`[:find ?ticker ?t ?c
+		  :where [?s :symbol/ticker ?ticker]
+		         [?b :price/symbol ?s]
+		         [?b :price/time ?t]
+		         [(q [:find (max ?time) :in $ ?sym
+		              :where [?bar :price/symbol ?sym] [?bar :price/time ?time]] $ ?s) [[?t]]]
+		         [?b :price/close ?c]]`,
+		// Earliest bar, filtered and ordered
+		`[:find ?s ?first ?c
+		  :where [?b :price/time ?first]
+		         [?b :price/symbol ?s]
+		         [(q [:find (min ?time) :in $ ?sym
+		              :where [?bar :price/symbol ?sym] [?bar :price/time ?time]] $ ?s) [[?first]]]
+		         [?b :price/close ?c]
+		         [(>= ?c 0.0)]
+		  :order-by [?first]]`,
+		// Only the subquery and its patterns
+		`[:find ?s ?t
+		  :where [?b :price/symbol ?s]
+		         [?b :price/time ?t]
+		         [(q [:find (max ?time) :in $ ?sym
+		              :where [?bar :price/symbol ?sym] [?bar :price/time ?time]] $ ?s) [[?t]]]]`,
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for i, queryStr := range queries {
+			t.Run(fmt.Sprintf("QueryExecutor=%v/%d", useQueryExecutor, i), func(t *testing.T) {
+				q, err := parser.ParseQuery(queryStr)
+				if err != nil {
+					t.Fatalf("failed to parse query: %v", err)
+				}
+
+				subqueries := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+					UseQueryExecutor: useQueryExecutor,
+				})
+				expected, err := subqueries.Execute(q)
+				if err != nil {
+					t.Fatalf("query failed: %v", err)
+				}
+
+				var scanEvent *annotations.Event
+				handler := func(event annotations.Event) {
+					if event.Name == "latest_per_entity/scan" {
+						scanEvent = &event
+					}
+				}
+				latest := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+					UseQueryExecutor:      useQueryExecutor,
+					EnableLatestPerEntity: true,
+				})
+				result, err := latest.ExecuteWithContext(NewContext(handler), q)
+				if err != nil {
+					t.Fatalf("latest per entity query failed: %v", err)
+				}
+
+				if scanEvent == nil {
+					t.Fatal("Expected query to run as a latest per entity scan")
+				}
+				if scanEvent.Data["stopped"] != true {
+					t.Errorf("Expected the scan to stop once every symbol was seen, got %v", scanEvent.Data)
+				}
+
+				want, got := expected.Sorted(), result.Sorted()
+				if len(want) == 0 {
+					t.Fatal("Expected results from the subquery form")
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("Results differ:\nwant %v\ngot  %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestLatestPerEntityScanError(t *testing.T) {
+	q, err := parser.ParseQuery(`[:find ?s ?t
+	                              :where [?b :price/symbol ?s]
+	                                     [?b :price/time ?t]
+	                                     [(q [:find (max ?time) :in $ ?sym
+	                                          :where [?bar :price/symbol ?sym] [?bar :price/time ?time]] $ ?s) [[?t]]]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	// The ordered scan of the times, then the match of the links, fail part way
+	for _, attr := range []string{":price/time", ":price/symbol"} {
+		t.Run(attr, func(t *testing.T) {
+			var scanned bool
+			handler := func(event annotations.Event) {
+				if event.Name == "latest_per_entity/scan" {
+					scanned = true
+				}
+			}
+			matcher := &failingMatcher{PatternMatcher: NewMemoryPatternMatcher(latestPerEntityDatoms()), attr: attr}
+			_, err := NewExecutorWithOptions(matcher, planner.PlannerOptions{
+				EnableLatestPerEntity: true,
+			}).ExecuteWithContext(NewContext(handler), q)
+			if !errors.Is(err, errScanFailed) {
+				t.Fatalf("Expected scan error rather than partial rows, got %v", err)
+			}
+			if scanned {
+				t.Error("Expected no bindings to be emitted from a failed scan")
+			}
+		})
+	}
+}
@@ -18,13 +18,34 @@ type ExecutorOptions struct {
 	// Subquery optimization options
 	EnableSubqueryDecorrelation bool // If true, batch identical subqueries for efficiency
 	UseStreamingSubqueryUnion   bool // If true, use streaming union for subquery results (default: true)
-	UseComponentizedSubquery    bool // If true, use component-based subquery execution (strategy selector, batcher, worker pool)
+	UseComponentizedSubquery    bool // Deprecated: no effect; subqueries always run through the strategy selector
 
 	// Join options
 	EnableStreamingJoins bool
 	EnableDebugLogging   bool
 	DefaultHashTableSize int // Default hash table size for streaming relations (Size() = -1). If 0, uses 256.
 
+	// Non-join columns present on both sides of a hash join (see
+	// DuplicateColumnPolicy). Default: DuplicateColumnsCoalesce
+	DuplicateColumns DuplicateColumnPolicy
+
+	// Aggregates over a symbol that is not a column of the aggregated relation
+	// fail the query with an *AggregateColumnError. When lenient, the error is
+	// reported as an "aggregation/unknown_column" annotation instead and the
+	// aggregate sees no values.
+	LenientAggregation bool
+
+	// How sum and avg aggregates add float values: SummationFast for plain
+	// addition, SummationExact for the same result whatever order rows
+	// arrive in. Default: SummationCompensated
+	Summation Summation
+
+	// Compute min, max and nil count per column of each query's final result
+	// in one pass and report them with a QueryComplete annotation (see
+	// annotations.QueryResultEvent). Streaming results are materialized first.
+	// Only done when the query context collects annotations.
+	EnableColumnStatistics bool
+
 	// Storage join strategy: IndexNestedLoop threshold
 	// For bindingSize <= threshold: use IndexNestedLoop (iterator reuse with seeks)
 	// For bindingSize > threshold: continue to HashJoinScan/MergeJoin selection
@@ -35,4 +56,22 @@ type ExecutorOptions struct {
 	// Aggregation options
 	EnableStreamingAggregation      bool
 	EnableStreamingAggregationDebug bool
+
+	// Ordered scans: satisfy :order-by with :limit by scanning the ordering
+	// attribute in index order when the matcher supports it (see OrderedMatcher)
+	EnableOrderedScan bool
+
+	// Latest value per entity: execute max/min-per-group subqueries as one
+	// ordered scan emitting each group's first rows (see OrderedMatcher)
+	EnableLatestPerEntity bool
+
+	// Shared patterns: answer subquery patterns identical to an outer pattern
+	// from one cached scan instead of one storage match per invocation
+	EnableSharedPatterns bool
+
+	// Result spooling: final results larger than SpoolThreshold tuples are written
+	// to a temporary file in SpoolDir (default: os.TempDir()) and returned as a
+	// SpooledRelation. 0 disables spooling.
+	SpoolThreshold int
+	SpoolDir       string
 }
@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+const (
+	orderedScanMinBatch = 16
+	orderedScanMaxBatch = 64 * 1024
+)
+
+// executeOrderedScan executes q by scanning its driving pattern in value order
+// (see planner.FindOrderedScan) instead of sorting the final result.
+//
+// The ordered (?e ?v) bindings are cut into runs that never split a value, and
+// the rest of the query is executed for each run with the bindings as a
+// relation input. Runs arrive in :order-by order, so only each run's results
+// need sorting (for ties and secondary :order-by clauses), and the scan stops
+// once offset+limit results have been produced. Runs start at offset+limit
+// bindings and double, since the rest of the query may filter some bindings
+// out.
+//
+// Only queries with a :limit are executed this way. Each run executes the
+// rest of the query again, rescanning the patterns the bindings don't
+// constrain, which only pays off when the scan can stop early.
+//
+// Returns ok=false if the query has no :limit or no ordered scan, or the
+// matcher cannot provide one; the query should then be executed normally.
+func (e *Executor) executeOrderedScan(ctx Context, q *query.Query) (Relation, bool, error) {
+	if q.Limit <= 0 {
+		return nil, false, nil
+	}
+	scan, ok := planner.FindOrderedScan(q)
+	if !ok {
+		return nil, false, nil
+	}
+	om, ok := e.matcher.(OrderedMatcher)
+	if !ok {
+		return nil, false, nil
+	}
+
+	start := time.Now()
+	driving, err := om.MatchOrdered(scan.Pattern, scan.Descending)
+	if errors.Is(err, ErrOrderedScanUnsupported) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("ordered scan of %s failed: %w", scan.Pattern, err)
+	}
+
+	entityIdx := ColumnIndex(driving, scan.Entity)
+	valueIdx := ColumnIndex(driving, scan.Value)
+	if entityIdx < 0 || valueIdx < 0 {
+		return nil, true, fmt.Errorf("ordered scan of %s did not bind %s and %s", scan.Pattern, scan.Entity, scan.Value)
+	}
+
+	remainder := scan.Remainder(q)
+	bindingColumns := []query.Symbol{scan.Entity, scan.Value}
+
+	wanted := q.Offset + q.Limit
+	batchSize := wanted
+	if batchSize < orderedScanMinBatch {
+		batchSize = orderedScanMinBatch
+	}
+
+	var columns []query.Symbol
+	for _, elem := range q.Find {
+		if v, ok := elem.(query.FindVariable); ok {
+			columns = append(columns, v.Symbol)
+		}
+	}
+
+	var results []Tuple
+	var batch []Tuple
+	runs, scanned := 0, 0
+
+	executeRun := func() error {
+		runs++
+		bindings := NewMaterializedRelationWithOptions(bindingColumns, batch, e.options)
+		result, err := e.executeWithRelations(ctx, remainder, []Relation{bindings})
+		if err != nil {
+			return err
+		}
+		sorted := SortRelation(result, q.OrderBy)
+		columns = sorted.Symbols()
+		it := sorted.Iterator()
+		for it.Next() {
+			results = append(results, it.Tuple())
+		}
+		if err := it.Err(); err != nil {
+			it.Close()
+			return err
+		}
+		it.Close()
+		batch = nil
+		return nil
+	}
+
+	it := driving.Iterator()
+	done := false
+	for it.Next() {
+		tuple := it.Tuple()
+		binding := Tuple{tuple[entityIdx], tuple[valueIdx]}
+		scanned++
+
+		// Only cut a run between two different values, so that ties are
+		// sorted together with their secondary :order-by clauses
+		if len(batch) >= batchSize && !datalog.ValuesEqual(batch[len(batch)-1][1], binding[1]) {
+			if err := executeRun(); err != nil {
+				it.Close()
+				return nil, true, err
+			}
+			if len(results) >= wanted {
+				done = true
+				break
+			}
+			if batchSize < orderedScanMaxBatch {
+				batchSize *= 2
+			}
+		}
+		batch = append(batch, binding)
+	}
+	if err := it.Err(); err != nil {
+		it.Close()
+		return nil, true, fmt.Errorf("ordered scan of %s failed: %w", scan.Pattern, err)
+	}
+	it.Close()
+
+	if !done && len(batch) > 0 {
+		if err := executeRun(); err != nil {
+			return nil, true, err
+		}
+	}
+
+	if collector := ctx.Collector(); collector != nil {
+		collector.AddTiming("ordered_scan/complete", start, map[string]interface{}{
+			"pattern":  scan.Pattern.String(),
+			"order_by": scan.Value,
+			"runs":     runs,
+			"scanned":  scanned,
+			"results":  len(results),
+			"stopped":  done,
+		})
+	}
+
+	return NewMaterializedRelationWithOptions(columns, results, e.options), true, nil
+}
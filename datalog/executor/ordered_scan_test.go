@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+// orderedScanDatoms returns bars whose times repeat, so ordering needs a tie-breaker
+func orderedScanDatoms(n int) []datalog.Datom {
+	timeAttr := datalog.NewKeyword(":bar/time")
+	closeAttr := datalog.NewKeyword(":bar/close")
+
+	var datoms []datalog.Datom
+	for i := 0; i < n; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("bar:%d", i))
+		datoms = append(datoms,
+			datalog.Datom{E: e, A: timeAttr, V: int64(i * 7 % (n / 4)), Tx: 1},
+			datalog.Datom{E: e, A: closeAttr, V: int64(i % 11), Tx: 1},
+		)
+	}
+	return datoms
+}
+
+func TestOrderedScan(t *testing.T) {
+	datoms := orderedScanDatoms(400)
+
+	queries := []string{
+		`{:query [:find ?t ?c :where [?b :bar/time ?t] [?b :bar/close ?c] [(> ?c 3)] :order-by [?t [?c :desc]]] :limit 25}`,
+		`{:query [:find ?t ?c :where [?b :bar/time ?t] [?b :bar/close ?c] :order-by [[?t :desc] ?c]] :offset 40 :limit 7}`,
+		`[:find ?t ?c :where [?b :bar/time ?t] [?b :bar/close ?c] [(< ?c 2)] :order-by [?t ?c]]`,
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for i, queryStr := range queries {
+			t.Run(fmt.Sprintf("QueryExecutor=%v/%d", useQueryExecutor, i), func(t *testing.T) {
+				q, err := parser.ParseQuery(queryStr)
+				if err != nil {
+					t.Fatalf("failed to parse query: %v", err)
+				}
+
+				sorted := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+					UseQueryExecutor: useQueryExecutor,
+				})
+				expected, err := sorted.Execute(q)
+				if err != nil {
+					t.Fatalf("query failed: %v", err)
+				}
+
+				var scanEvent *annotations.Event
+				handler := func(event annotations.Event) {
+					if event.Name == "ordered_scan/complete" {
+						scanEvent = &event
+					}
+				}
+				ordered := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+					UseQueryExecutor:  useQueryExecutor,
+					EnableOrderedScan: true,
+				})
+				result, err := ordered.ExecuteWithContext(NewContext(handler), q)
+				if err != nil {
+					t.Fatalf("ordered scan query failed: %v", err)
+				}
+
+				// Without :limit the query is sorted as usual
+				if q.Limit == 0 {
+					if scanEvent != nil {
+						t.Errorf("Expected a query without :limit not to run as an ordered scan, got %v", scanEvent.Data)
+					}
+				} else if scanEvent == nil {
+					t.Fatal("Expected query to run as an ordered scan")
+				} else if scanEvent.Data["stopped"] != true {
+					t.Errorf("Expected ordered scan to stop early with :limit, got %v", scanEvent.Data)
+				}
+
+				if result.Size() != expected.Size() {
+					t.Fatalf("Expected %d results, got %d", expected.Size(), result.Size())
+				}
+				for j := 0; j < expected.Size(); j++ {
+					if fmt.Sprint(result.Get(j)) != fmt.Sprint(expected.Get(j)) {
+						t.Fatalf("Row %d is %v, expected %v", j, result.Get(j), expected.Get(j))
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// limitPatternRelation enforces a pattern's :max-datoms hint on its match
+// result. The result streams until the cap is reached; if more datoms match,
+// matching stops there (ending the underlying storage scan) and a
+// PatternLimitReached warning is emitted, as the query result is partial.
+func limitPatternRelation(ctx Context, pattern *query.DataPattern, rel Relation, opts ExecutorOptions) Relation {
+	if pattern.MaxDatoms <= 0 || rel == nil {
+		return rel
+	}
+	return NewStreamingRelationWithOptions(rel.Symbols(), &patternLimitIterator{
+		ctx:       ctx,
+		pattern:   pattern,
+		rel:       rel,
+		remaining: pattern.MaxDatoms,
+		start:     time.Now(),
+	}, opts)
+}
+
+// patternLimitIterator yields at most pattern.MaxDatoms tuples of rel
+type patternLimitIterator struct {
+	ctx       Context
+	pattern   *query.DataPattern
+	rel       Relation
+	it        Iterator
+	remaining int
+	start     time.Time
+	done      bool
+}
+
+func (it *patternLimitIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.it == nil {
+		it.it = it.rel.Iterator()
+	}
+
+	if it.remaining == 0 {
+		// Peek once to tell a truncated match from one that fit the cap
+		it.done = true
+		if it.it.Next() {
+			if collector := it.ctx.Collector(); collector != nil {
+				collector.AddPayloadTiming(annotations.PatternLimitReached, it.start, annotations.PatternLimitEvent{
+					Pattern:   it.pattern.String(),
+					MaxDatoms: it.pattern.MaxDatoms,
+				})
+			}
+		}
+		return false
+	}
+
+	if !it.it.Next() {
+		it.done = true
+		return false
+	}
+	it.remaining--
+	return true
+}
+
+func (it *patternLimitIterator) Tuple() Tuple {
+	return it.it.Tuple()
+}
+
+func (it *patternLimitIterator) Close() error {
+	if it.it == nil {
+		return nil
+	}
+	return it.it.Close()
+}
+
+func (it *patternLimitIterator) Err() error {
+	if it.it == nil {
+		return nil
+	}
+	return it.it.Err()
+}
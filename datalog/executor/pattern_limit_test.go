@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+func TestPatternMaxDatoms(t *testing.T) {
+	var datoms []datalog.Datom
+	for i := 0; i < 50; i++ {
+		datoms = append(datoms, datalog.Datom{
+			E:  datalog.NewIdentity(fmt.Sprintf("item:%d", i)),
+			A:  datalog.NewKeyword(":item/size"),
+			V:  int64(i),
+			Tx: 1,
+		})
+	}
+
+	tests := []struct {
+		maxDatoms int
+		expected  int
+		partial   bool
+	}{
+		{maxDatoms: 10, expected: 10, partial: true},
+		{maxDatoms: 50, expected: 50, partial: false},
+		{maxDatoms: 100, expected: 50, partial: false},
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("QueryExecutor=%v/%d", useQueryExecutor, tt.maxDatoms), func(t *testing.T) {
+				q, err := parser.ParseQuery(fmt.Sprintf(`[:find ?e ?size :where [?e :item/size ?size {:max-datoms %d}]]`, tt.maxDatoms))
+				if err != nil {
+					t.Fatalf("failed to parse query: %v", err)
+				}
+
+				var warnings []annotations.PatternLimitEvent
+				handler := &annotations.TypedHandler{
+					OnPatternLimit: func(_ annotations.Event, ev annotations.PatternLimitEvent) {
+						warnings = append(warnings, ev)
+					},
+				}
+
+				exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+					UseQueryExecutor: useQueryExecutor,
+				})
+				result, err := exec.ExecuteWithContext(NewContext(handler.Handle), q)
+				if err != nil {
+					t.Fatalf("query failed: %v", err)
+				}
+
+				// The warning is emitted as the result streams
+				count := 0
+				it := result.Iterator()
+				for it.Next() {
+					count++
+				}
+				it.Close()
+				if count != tt.expected {
+					t.Errorf("expected %d results, got %d", tt.expected, count)
+				}
+				if tt.partial != (len(warnings) == 1) {
+					t.Fatalf("expected partial=%v, got warnings %v", tt.partial, warnings)
+				}
+				if tt.partial && warnings[0].MaxDatoms != tt.maxDatoms {
+					t.Errorf("expected warning for %d datoms, got %d", tt.maxDatoms, warnings[0].MaxDatoms)
+				}
+			})
+		}
+	}
+}
@@ -149,7 +149,7 @@ func evaluateConstraints(datom *datalog.Datom, constraints []StorageConstraint)
 // bindPatternFromTuple creates a new pattern with variables replaced by tuple values
 func bindPatternFromTuple(pattern *query.DataPattern, tuple Tuple, rel Relation) *query.DataPattern {
 	// Get symbol positions in the relation
-	symbols := rel.Columns()
+	symbols := rel.Symbols()
 	symbolIndex := make(map[query.Symbol]int)
 	for i, sym := range symbols {
 		symbolIndex[sym] = i
@@ -309,6 +309,10 @@ func (it *datomIterator) Close() error {
 	return nil
 }
 
+func (it *datomIterator) Err() error {
+	return nil
+}
+
 // datomsToRelation converts datoms to a streaming relation (zero-copy lazy evaluation)
 func datomsToRelation(datoms []datalog.Datom, pattern *query.DataPattern, columns []query.Symbol) Relation {
 	return datomsToRelationWithOptions(datoms, pattern, columns, ExecutorOptions{})
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/constraints"
 	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 	"testing"
@@ -379,3 +380,51 @@ func (m *mockMatcher) Match(pattern *query.DataPattern, bindings Relations) (Rel
 	// Return empty relation for testing
 	return NewMaterializedRelation([]query.Symbol{}, []Tuple{}), nil
 }
+
+// constraintRecordingMatcher records the constraints it is given
+type constraintRecordingMatcher struct {
+	mockMatcher
+	constraints []StorageConstraint
+}
+
+func (m *constraintRecordingMatcher) MatchWithConstraints(pattern *query.DataPattern, bindings Relations, constraints []StorageConstraint) (Relation, error) {
+	m.constraints = append(m.constraints, constraints...)
+	return m.Match(pattern, bindings)
+}
+
+// TestPatternPlanConstraintsReachMatcher verifies that both the pushed-down
+// predicates and the storage filters of a pattern are passed to the matcher
+func TestPatternPlanConstraintsReachMatcher(t *testing.T) {
+	year := 2025
+	phase := planner.Phase{
+		Patterns: []planner.PatternPlan{{
+			Pattern: &query.DataPattern{Elements: []query.PatternElement{
+				query.Variable{Name: "?b"},
+				query.Constant{Value: datalog.NewKeyword(":price/time")},
+				query.Variable{Name: "?t"},
+			}},
+			Constraints: []planner.StorageConstraint{
+				{Type: planner.ConstraintRange, Value: int64(5), Operator: query.OpGT},
+			},
+			StorageFilters: []StorageConstraint{
+				constraints.ComposeTimeConstraint(&year, nil, nil, nil, nil, nil, 2),
+			},
+		}},
+	}
+
+	matcher := &constraintRecordingMatcher{}
+	exec := NewExecutor(matcher)
+	if _, err := exec.executePhaseSequential(NewContext(nil), &phase, 0, nil); err != nil {
+		t.Fatalf("phase execution failed: %v", err)
+	}
+
+	if len(matcher.constraints) != 2 {
+		t.Fatalf("Expected 2 constraints, got %v", matcher.constraints)
+	}
+	if _, ok := matcher.constraints[0].(*rangeConstraint); !ok {
+		t.Errorf("Expected the pushed-down range constraint first, got %T", matcher.constraints[0])
+	}
+	if _, ok := matcher.constraints[1].(*constraints.TimeRangeConstraint); !ok {
+		t.Errorf("Expected the time range filter second, got %T", matcher.constraints[1])
+	}
+}
@@ -3,7 +3,7 @@ package executor
 import (
 	"fmt"
 
-	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -59,6 +59,9 @@ func (e *DefaultQueryExecutor) Execute(ctx Context, q *query.Query, inputs []Rel
 	// Patterns/Subqueries produce NEW relations (append + collapse)
 	// Expressions/Predicates TRANSFORM relations (replace groups + collapse)
 	for i, clause := range q.Where {
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
 		switch c := clause.(type) {
 		case *query.DataPattern:
 			newRel, err := e.executePattern(ctx, c, groups)
@@ -138,7 +141,10 @@ func (e *DefaultQueryExecutor) Execute(ctx Context, q *query.Query, inputs []Rel
 		}
 
 		// Apply aggregations using existing function
-		result := ExecuteAggregationsWithContext(ctx, groups[0], q.Find)
+		result, err := aggregateQueryResult(ctx, groups[0], q, q.Find, e.options)
+		if err != nil {
+			return nil, err
+		}
 		return []Relation{result}, nil
 
 	} else {
@@ -152,7 +158,7 @@ func (e *DefaultQueryExecutor) Execute(ctx Context, q *query.Query, inputs []Rel
 			groupsHaveSymbols := make([][]bool, len(groups))
 			for i, group := range groups {
 				groupsHaveSymbols[i] = make([]bool, len(findSymbols))
-				cols := group.Columns()
+				cols := group.Symbols()
 				for j, sym := range findSymbols {
 					for _, col := range cols {
 						if col == sym {
@@ -248,6 +254,29 @@ func (e *DefaultQueryExecutor) executePattern(ctx Context, pattern *query.DataPa
 	// Materializing allows them to be iterated multiple times without consuming the iterator
 	bindings := materializeRelationsForPattern(pattern, Relations(groups))
 
+	// Honor index pins from a manipulated plan (see planner.RealizedPlan.PinIndex).
+	// The pinned scan ignores bindings; the result is joined with groups like any other.
+	if value, ok := ctx.GetMetadata("index_pins"); ok {
+		pins, _ := value.(map[*query.DataPattern]planner.IndexType)
+		if index, pinned := pins[pattern]; pinned {
+			if ipm, ok := e.matcher.(IndexPinnedMatcher); ok {
+				rel, err := ipm.MatchWithIndex(pattern, index)
+				if err != nil {
+					return nil, err
+				}
+				return limitPatternRelation(ctx, pattern, rel, e.options), nil
+			}
+		}
+	}
+
+	// A variable attribute restricted to an [(in ?a #{...})] set is matched per attribute
+	if rel, ok, err := matchAttributeSet(e.matcher, pattern, bindings, e.options); ok {
+		if err != nil {
+			return nil, err
+		}
+		return limitPatternRelation(ctx, pattern, rel, e.options), nil
+	}
+
 	// Use PatternMatcher with current groups as bindings
 	// NOTE: bindings are used for pattern selection heuristics (FindBestForPattern)
 	// and potentially for batch scanning - they will also be joined with the result later
@@ -255,7 +284,7 @@ func (e *DefaultQueryExecutor) executePattern(ctx Context, pattern *query.DataPa
 	if err != nil {
 		return nil, err
 	}
-	return rel, nil
+	return limitPatternRelation(ctx, pattern, rel, e.options), nil
 }
 
 // executeExpression evaluates an expression clause
@@ -268,7 +297,7 @@ func (e *DefaultQueryExecutor) executeExpression(ctx Context, expr *query.Expres
 	requiredSyms := expr.Function.RequiredSymbols()
 	for _, rel := range groups {
 		hasAny := false
-		relCols := rel.Columns()
+		relCols := rel.Symbols()
 		for _, sym := range requiredSyms {
 			for _, col := range relCols {
 				if col == sym {
@@ -313,7 +342,7 @@ func (e *DefaultQueryExecutor) executePredicate(ctx Context, pred query.Predicat
 	requiredSyms := pred.RequiredSymbols()
 	for _, rel := range groups {
 		hasAny := false
-		relCols := rel.Columns()
+		relCols := rel.Symbols()
 		for _, sym := range requiredSyms {
 			for _, col := range relCols {
 				if col == sym {
@@ -349,26 +378,11 @@ func (e *DefaultQueryExecutor) executePredicate(ctx Context, pred query.Predicat
 }
 
 // executeSubquery executes a nested subquery
-// Subqueries produce new relations from nested query execution
+// Subqueries produce new relations from nested query execution. A
+// SubqueryStrategySelector chooses whether to run the subquery once for all
+// input combinations, in parallel, or sequentially, and the choice is
+// annotated with its reasons.
 func (e *DefaultQueryExecutor) executeSubquery(ctx Context, subq *query.SubqueryPattern, groups []Relation) (Relation, error) {
-	// Check if componentized path is enabled
-	if e.options.UseComponentizedSubquery {
-		return e.executeSubqueryComponentized(ctx, subq, groups)
-	}
-
-	// Log that we're using the legacy path
-	if collector := ctx.Collector(); collector != nil {
-		collector.Add(annotations.Event{
-			Name: "subquery/executor-path",
-			Data: map[string]interface{}{
-				"path":          "Legacy QueryExecutor",
-				"query":         subq.Query.String(),
-				"input_count":   len(subq.Inputs),
-				"groups_count":  len(groups),
-			},
-		})
-	}
-
 	// CRITICAL: Materialize groups FIRST to prevent iterator consumption
 	// When we create Product() and materialize it, that will consume the underlying iterators
 	// We need to preserve groups for later use in the outer query
@@ -409,61 +423,18 @@ func (e *DefaultQueryExecutor) executeSubquery(ctx Context, subq *query.Subquery
 	// Get unique combinations of input values
 	inputCombinations := getUniqueInputCombinations(combinedRel, inputSymbols)
 
-	// Execute subquery for each combination
-	var allResults []Relation
-
-	for _, inputValues := range inputCombinations {
-		// Create input relations for this combination
-		inputRelations := createInputRelationsForSubqueryWithOptions(subq, inputValues, e.options)
+	choice := NewSubqueryStrategySelector(ParallelSubqueryThreshold).Choose(subq, len(inputCombinations), e.options)
+	choice.annotate(ctx, subq)
 
-		// DEBUG: Log input relations
-		if collector := ctx.Collector(); collector != nil {
-			for i, rel := range inputRelations {
-				collector.Add(annotations.Event{
-					Name: "subquery/input-relation",
-					Data: map[string]interface{}{
-						"index":   i,
-						"columns": rel.Columns(),
-						"size":    rel.Size(),
-					},
-				})
-			}
-		}
-
-		// Execute the nested query recursively using QueryExecutor
-		nestedGroups, err := e.Execute(ctx, subq.Query, inputRelations)
-		if err != nil {
-			return nil, fmt.Errorf("nested query execution failed: %w", err)
-		}
-
-		// For subqueries, we expect a single result group (aggregations should have collapsed)
-		if len(nestedGroups) == 0 {
-			// Empty result - skip this combination
-			continue
-		}
-		if len(nestedGroups) > 1 {
-			return nil, fmt.Errorf("subquery returned %d disjoint groups - expected 1", len(nestedGroups))
-		}
-
-		nestedResult := nestedGroups[0]
-
-		// Apply binding form to join results with outer query values
-		boundResult, err := applyBindingForm(nestedResult, subq.Binding, inputValues, inputSymbols)
-		if err != nil {
-			return nil, fmt.Errorf("binding form application failed: %w", err)
-		}
-
-		allResults = append(allResults, boundResult)
-	}
-
-	// Combine all results
-	if len(allResults) == 0 {
-		// No results - return empty relation with appropriate columns
-		return NewMaterializedRelation(extractBindingSymbols(subq.Binding), []Tuple{}), nil
+	unionBuilder := NewStreamingUnionBuilder(e.options)
+	switch choice.Strategy {
+	case StrategyBatched:
+		return e.executeSubqueryBatched(ctx, subq, inputCombinations, inputSymbols)
+	case StrategyParallel:
+		return e.executeSubqueryParallel(ctx, subq, inputCombinations, inputSymbols, NewWorkerPool(e.options.MaxSubqueryWorkers), unionBuilder)
+	default:
+		return e.executeSubquerySequential(ctx, subq, inputCombinations, inputSymbols, unionBuilder)
 	}
-
-	// Union all results (they should have the same schema)
-	return combineSubqueryResultsSimple(allResults), nil
 }
 
 // createInputRelationsForSubquery creates input relations from subquery inputs and outer values
@@ -475,30 +446,6 @@ func createInputRelationsForSubqueryWithOptions(subq *query.SubqueryPattern, out
 	return createInputRelationsFromPatternWithOptions(subq, outerValues, opts)
 }
 
-// combineSubqueryResultsSimple combines multiple subquery results into a single relation
-func combineSubqueryResultsSimple(results []Relation) Relation {
-	if len(results) == 0 {
-		return nil
-	}
-	if len(results) == 1 {
-		return results[0]
-	}
-
-	// Collect all tuples
-	columns := results[0].Columns()
-	var allTuples []Tuple
-
-	for _, result := range results {
-		it := result.Iterator()
-		defer it.Close()
-		for it.Next() {
-			allTuples = append(allTuples, it.Tuple())
-		}
-	}
-
-	return NewMaterializedRelation(columns, allTuples)
-}
-
 // extractBindingSymbols extracts symbols from a binding form
 func extractBindingSymbols(binding query.BindingForm) []query.Symbol {
 	switch b := binding.(type) {
@@ -513,76 +460,27 @@ func extractBindingSymbols(binding query.BindingForm) []query.Symbol {
 	}
 }
 
-// executeSubqueryComponentized executes subquery using component-based optimization
-// This uses: SubqueryStrategySelector, SubqueryBatcher, WorkerPool, StreamingUnionBuilder
-func (e *DefaultQueryExecutor) executeSubqueryComponentized(ctx Context, subq *query.SubqueryPattern, groups []Relation) (Relation, error) {
-	// Initialize components (could be cached on executor for reuse)
-	selector := NewSubqueryStrategySelector(100) // Default threshold
-	batcher := NewSubqueryBatcher()
-	workerPool := NewWorkerPool(e.options.MaxSubqueryWorkers)
-	unionBuilder := NewStreamingUnionBuilder(e.options)
-
-	// Combine all groups into a single relation
-	var combinedRel Relation
-	if len(groups) == 0 {
-		return nil, fmt.Errorf("no input groups for subquery")
-	} else if len(groups) == 1 {
-		combinedRel = groups[0]
-	} else {
-		combinedRel = Relations(groups).Product()
-	}
-
-	// Extract input symbols using batcher
-	inputSymbols := batcher.ExtractInputSymbols(subq.Query.In)
-
-	// Get unique input combinations
-	inputCombinations := getUniqueInputCombinations(combinedRel, inputSymbols)
-
-	// Select execution strategy
-	strategy := selector.SelectStrategy(subq.Query, len(inputCombinations), e.options)
-
-	// Log strategy selection
-	if collector := ctx.Collector(); collector != nil {
-		collector.Add(annotations.Event{
-			Name: "subquery/componentized-strategy",
-			Data: map[string]interface{}{
-				"strategy":           strategy.String(),
-				"input_combinations": len(inputCombinations),
-				"query":              subq.Query.String(),
-			},
-		})
-	}
-
-	// Execute based on strategy
-	switch strategy {
-	case StrategyBatched:
-		return e.executeSubqueryBatched(ctx, subq, inputCombinations, inputSymbols, batcher)
-
-	case StrategyParallel:
-		return e.executeSubqueryParallel(ctx, subq, inputCombinations, inputSymbols, workerPool, unionBuilder)
-
-	case StrategySequential:
-		return e.executeSubquerySequential(ctx, subq, inputCombinations, inputSymbols, unionBuilder)
-
-	default:
-		return nil, fmt.Errorf("unknown strategy: %v", strategy)
-	}
-}
-
 // executeSubqueryBatched executes subquery once with all inputs as RelationInput
 func (e *DefaultQueryExecutor) executeSubqueryBatched(
 	ctx Context,
 	subq *query.SubqueryPattern,
 	combinations []map[query.Symbol]interface{},
 	inputSymbols []query.Symbol,
-	batcher *SubqueryBatcher,
 ) (Relation, error) {
 	if len(combinations) == 0 {
 		return NewMaterializedRelation(extractBindingSymbols(subq.Binding), []Tuple{}), nil
 	}
 
-	// Build batched input relation
-	batchedInput := batcher.BuildBatchedInput(combinations, inputSymbols)
+	// Build batched input relation, with the outer values in the columns the
+	// subquery's RelationInput names
+	batcher := NewSubqueryBatcher()
+	var tuples []Tuple
+	it := batcher.BuildBatchedInput(combinations, inputSymbols).Iterator()
+	for it.Next() {
+		tuples = append(tuples, it.Tuple())
+	}
+	it.Close()
+	batchedInput := NewMaterializedRelation(batcher.ExtractRelationSymbols(subq.Query.In), tuples)
 
 	// Create input relations for the subquery
 	inputRelations := []Relation{batchedInput}
@@ -600,8 +498,8 @@ func (e *DefaultQueryExecutor) executeSubqueryBatched(
 		return nil, fmt.Errorf("batched subquery returned %d groups, expected 1", len(nestedGroups))
 	}
 
-	// Apply binding form (no per-tuple input values for batched)
-	return applyBindingForm(nestedGroups[0], subq.Binding, nil, inputSymbols)
+	// Apply binding form; the result already binds the inputs, returned in :find
+	return applyBindingForm(nestedGroups[0], subq.Binding, nil, nil)
 }
 
 // executeSubqueryParallel executes subquery iterations in parallel using WorkerPool
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// slowMatcher delays every match to exercise query timeouts
+type slowMatcher struct {
+	inner PatternMatcher
+	delay time.Duration
+}
+
+func (m *slowMatcher) Match(pattern *query.DataPattern, bindings Relations) (Relation, error) {
+	time.Sleep(m.delay)
+	return m.inner.Match(pattern, bindings)
+}
+
+func queryOptionsTestDatoms() []datalog.Datom {
+	nameAttr := datalog.NewKeyword(":user/name")
+	var datoms []datalog.Datom
+	for _, name := range []string{"Eve", "Bob", "Dave", "Alice", "Carol"} {
+		e := datalog.NewIdentity("user:" + name)
+		datoms = append(datoms, datalog.Datom{E: e, A: nameAttr, V: name, Tx: 1})
+	}
+	return datoms
+}
+
+func TestQueryOffsetLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  string
+		expected []string
+	}{
+		{"limit only", ":limit 2", []string{"Alice", "Bob"}},
+		{"offset only", ":offset 3", []string{"Dave", "Eve"}},
+		{"offset and limit", ":offset 1 :limit 2", []string{"Bob", "Carol"}},
+		{"offset past end", ":offset 10", []string{}},
+		{"no limit", ":limit -1", []string{"Alice", "Bob", "Carol", "Dave", "Eve"}},
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/QueryExecutor=%v", tt.name, useQueryExecutor), func(t *testing.T) {
+				exec := NewExecutor(NewMemoryPatternMatcher(queryOptionsTestDatoms()))
+				exec.SetUseQueryExecutor(useQueryExecutor)
+
+				q, err := parser.ParseQuery(fmt.Sprintf(`{:query [:find ?name
+				                                                  :where [?e :user/name ?name]
+				                                                  :order-by [?name]]
+				                                          %s}`, tt.options))
+				if err != nil {
+					t.Fatalf("failed to parse query: %v", err)
+				}
+
+				result, err := exec.Execute(q)
+				if err != nil {
+					t.Fatalf("execution failed: %v", err)
+				}
+
+				if result.Size() != len(tt.expected) {
+					t.Fatalf("expected %d results, got %d", len(tt.expected), result.Size())
+				}
+				for i, want := range tt.expected {
+					if got := result.Get(i)[0]; got != want {
+						t.Errorf("row %d: expected %s, got %v", i, want, got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	matcher := &slowMatcher{
+		inner: NewMemoryPatternMatcher(queryOptionsTestDatoms()),
+		delay: 200 * time.Millisecond,
+	}
+	exec := NewExecutor(matcher)
+
+	q, err := parser.ParseQuery(`{:query [:find ?name :where [?e :user/name ?name]] :timeout 20}`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	_, err = exec.Execute(q)
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("expected ErrQueryTimeout, got %v", err)
+	}
+
+	// A generous timeout lets the query finish normally
+	q.Timeout = 5 * time.Second
+	q.Limit = 3
+	result, err := exec.Execute(q)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if result.Size() != 3 {
+		t.Errorf("expected 3 results, got %d", result.Size())
+	}
+}
@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -16,30 +17,43 @@ type Tuple = query.Tuple
 
 // Relation represents a set of tuples with named columns
 type Relation interface {
-	// Columns returns the column names (symbols) in order
-	// Deprecated: Use Symbols() instead for consistency with relational theory
+	// Columns returns the column names (symbols) in order.
+	// Deprecated: Use Symbols(), or Schema() for the column types as well.
 	Columns() []query.Symbol
 
 	// Symbols returns the symbols (attribute names) of this relation
 	// In relational theory, a tuple is a map from symbols to values
 	Symbols() []query.Symbol
 
+	// Schema returns the symbols of this relation in order, with the types of
+	// their values where known
+	Schema() RelationSchema
+
 	// Iterator returns an iterator over tuples
 	Iterator() Iterator
 
+	// ForEach calls fn for each tuple until fn returns stop or an error, and
+	// closes the iterator either way. It returns fn's error, or else Close's.
+	ForEach(fn func(Tuple) (stop bool, err error)) error
+
 	// Size returns the number of tuples (may be expensive for iterators)
 	Size() int
 
 	// IsEmpty returns true if the relation has no tuples
 	IsEmpty() bool
 
-	// Get returns a specific tuple by index (may be expensive for streaming relations)
+	// Get returns a specific tuple by index, or nil if out of range.
+	// On streaming relations this materializes the whole relation; iterate
+	// with Iterator() instead, or use At on a RandomAccessRelation.
 	Get(i int) Tuple
 
-	// String returns a compact string representation for annotations/logging
+	// String returns a compact string representation for annotations/logging.
+	// It must not iterate or materialize the relation; see KnownSize.
 	String() string
 
-	// Table returns a formatted markdown table representation
+	// Table returns a formatted markdown table representation of every
+	// tuple, reading (and for streaming relations, consuming) all of them.
+	// Use PreviewTable to show a bounded number of rows.
 	Table() string
 
 	// Project creates a new Relation with only the symbols from the pattern
@@ -96,6 +110,20 @@ type Relation interface {
 	// All operations return NEW Relations
 }
 
+// ErrTupleIndexOutOfRange is returned by At for a position outside a relation
+var ErrTupleIndexOutOfRange = errors.New("tuple index out of range")
+
+// RandomAccessRelation is a relation whose tuples are stored and can be read
+// by position, such as a MaterializedRelation. Unlike Get, At never
+// materializes anything, so code that needs positional access should require
+// this interface rather than call Get on an arbitrary Relation.
+type RandomAccessRelation interface {
+	Relation
+
+	// At returns the tuple at position i, or ErrTupleIndexOutOfRange
+	At(i int) (Tuple, error)
+}
+
 // Iterator provides streaming access to tuples
 type Iterator interface {
 	// Next advances to the next tuple
@@ -106,6 +134,65 @@ type Iterator interface {
 
 	// Close releases any resources
 	Close() error
+
+	// Err returns the error, if any, that ended iteration early. Next
+	// returning false means either the tuples ran out or an error occurred;
+	// check Err after the loop to tell the two apart, as with sql.Rows.
+	Err() error
+}
+
+// ErrorReporter is implemented by relations that can report the error that
+// ended their iteration early, such as a StreamingRelation over a storage scan
+type ErrorReporter interface {
+	Err() error
+}
+
+// RelationErr returns the iteration error recorded by rel, if it records one.
+// Relations that hold their tuples in memory never fail and return nil.
+func RelationErr(rel Relation) error {
+	if er, ok := rel.(ErrorReporter); ok {
+		return er.Err()
+	}
+	return nil
+}
+
+// sizeReporter is implemented by relations that can tell whether their size
+// is known without iterating or materializing anything
+type sizeReporter interface {
+	knownSize() (int, bool)
+}
+
+// KnownSize returns the number of tuples in rel if that is known without
+// consuming or materializing it. Annotations and String() use it so that
+// describing a streaming relation never changes how it is evaluated.
+func KnownSize(rel Relation) (int, bool) {
+	if rel == nil {
+		return 0, true
+	}
+	if sr, ok := rel.(sizeReporter); ok {
+		return sr.knownSize()
+	}
+	return -1, false
+}
+
+// forEachTuple implements Relation.ForEach on top of rel's iterator
+func forEachTuple(rel Relation, fn func(Tuple) (bool, error)) error {
+	it := rel.Iterator()
+	for it.Next() {
+		stop, err := fn(it.Tuple())
+		if err != nil {
+			it.Close()
+			return err
+		}
+		if stop {
+			return it.Close()
+		}
+	}
+	if err := it.Err(); err != nil {
+		it.Close()
+		return err
+	}
+	return it.Close()
 }
 
 // CountingIterator wraps an iterator and tracks tuple count without buffering
@@ -142,6 +229,10 @@ func (i *CountingIterator) Close() error {
 	return i.inner.Close()
 }
 
+func (i *CountingIterator) Err() error {
+	return i.inner.Err()
+}
+
 // Count returns the number of tuples seen so far
 func (i *CountingIterator) Count() int {
 	return i.count
@@ -164,6 +255,7 @@ type CachingIterator struct {
 	mu                *sync.Mutex    // Protects state transitions
 	done              bool
 	signaled          bool           // Ensure we only signal once
+	cacheErr          *error         // Where to record the inner iterator's error, if set
 }
 
 // NewCachingIterator creates a caching iterator that builds a cache as it iterates
@@ -203,8 +295,15 @@ func (ci *CachingIterator) Next() bool {
 		return true
 	}
 
-	// Iteration complete - signal waiting goroutines
+	// Iteration complete - record any error, then signal waiting goroutines
 	ci.done = true
+	if ci.cacheErr != nil {
+		if err := ci.inner.Err(); err != nil {
+			ci.mu.Lock()
+			*ci.cacheErr = err
+			ci.mu.Unlock()
+		}
+	}
 	ci.signalComplete()
 	return false
 }
@@ -223,6 +322,10 @@ func (ci *CachingIterator) Close() error {
 	return ci.inner.Close()
 }
 
+func (ci *CachingIterator) Err() error {
+	return ci.inner.Err()
+}
+
 func (ci *CachingIterator) signalComplete() {
 	ci.mu.Lock()
 	// Check if already signaled (must be inside lock to avoid race)
@@ -316,12 +419,24 @@ func deduplicateTuples(tuples []Tuple) []Tuple {
 	return result
 }
 
-func (r *MaterializedRelation) Columns() []query.Symbol {
+func (r *MaterializedRelation) Symbols() []query.Symbol {
 	return r.columns
 }
 
-func (r *MaterializedRelation) Symbols() []query.Symbol {
-	return r.columns
+// Schema returns the relation's symbols with unknown types
+func (r *MaterializedRelation) Schema() RelationSchema {
+	return NewRelationSchema(r.columns)
+}
+
+// Columns returns the relation's symbols.
+// Deprecated: Use Symbols or Schema.
+func (r *MaterializedRelation) Columns() []query.Symbol {
+	return r.Symbols()
+}
+
+// ForEach calls fn for each tuple (see Relation.ForEach)
+func (r *MaterializedRelation) ForEach(fn func(Tuple) (bool, error)) error {
+	return forEachTuple(r, fn)
 }
 
 func (r *MaterializedRelation) Iterator() Iterator {
@@ -352,6 +467,14 @@ func (r *MaterializedRelation) Get(i int) Tuple {
 	return r.tuples[i]
 }
 
+// At implements RandomAccessRelation
+func (r *MaterializedRelation) At(i int) (Tuple, error) {
+	if i < 0 || i >= len(r.tuples) {
+		return nil, fmt.Errorf("%w: %d not in [0, %d)", ErrTupleIndexOutOfRange, i, len(r.tuples))
+	}
+	return r.tuples[i], nil
+}
+
 // ColumnIndex returns the index of a column by symbol
 func (r *MaterializedRelation) ColumnIndex(sym query.Symbol) int {
 	for i, col := range r.columns {
@@ -382,16 +505,33 @@ func (r *MaterializedRelation) Tuples() []Tuple {
 	return r.tuples
 }
 
+func (r *MaterializedRelation) knownSize() (int, bool) {
+	return len(r.tuples), true
+}
+
 // String returns a compact string representation for annotations
 func (r *MaterializedRelation) String() string {
-	// Format as: Relation([?x ?y], N Tuples) with colors
+	return relationString(r.columns, r.Size())
+}
+
+// relationString formats a relation for annotations as
+// Relation([?x ?y], N Tuples) with colors, or Relation([?x ?y], streaming)
+// if its size is unknown (negative)
+func relationString(columns []query.Symbol, count int) string {
 	var symbols []string
-	for _, col := range r.columns {
+	for _, col := range columns {
 		symbols = append(symbols, string(col))
 	}
 
+	if count < 0 {
+		return fmt.Sprintf("%s%s%s%s",
+			color.BlueString("Relation(["),
+			color.CyanString(strings.Join(symbols, " ")),
+			color.BlueString("]"),
+			color.BlueString(", streaming)"))
+	}
+
 	// Color the tuple count based on size
-	count := r.Size()
 	var countStr string
 	switch {
 	case count == 0:
@@ -491,21 +631,16 @@ func (r *MaterializedRelation) Project(columns []query.Symbol) (Relation, error)
 		return nil, fmt.Errorf("cannot project empty column list - invalid query")
 	}
 
+	// Column not found is a query error in Datalog
+	schema := r.Schema()
+	if _, err := schema.Project(columns); err != nil {
+		return nil, err
+	}
+
 	// Find column indices
 	indices := make([]int, len(columns))
 	for i, col := range columns {
-		idx := -1
-		for j, existing := range r.columns {
-			if existing == col {
-				idx = j
-				break
-			}
-		}
-		if idx < 0 {
-			// Column not found - this is a query error in Datalog
-			return nil, fmt.Errorf("cannot project: column %s not found in relation (has columns: %v)", col, r.columns)
-		}
-		indices[i] = idx
+		indices[i] = schema.Index(col)
 	}
 
 	// Project tuples - directly access our tuples field
@@ -654,10 +789,12 @@ func contains(symbols []query.Symbol, sym query.Symbol) bool {
 	return false
 }
 
-// sliceIterator iterates over a slice of tuples
+// sliceIterator iterates over a slice of tuples. A non-nil err is reported by
+// Err, for tuples cached from a stream that failed part way.
 type sliceIterator struct {
 	tuples []Tuple
 	pos    int
+	err    error
 }
 
 func (it *sliceIterator) Next() bool {
@@ -676,6 +813,10 @@ func (it *sliceIterator) Close() error {
 	return nil
 }
 
+func (it *sliceIterator) Err() error {
+	return it.err
+}
+
 // StreamingRelation wraps an iterator as a relation
 type StreamingRelation struct {
 	columns  []query.Symbol
@@ -697,8 +838,12 @@ type StreamingRelation struct {
 	cachingInProgress bool          // True while first iterator is building cache
 	cacheReady        bool          // True when caching has completed (prevents double-iterator creation)
 	cacheComplete     chan struct{} // Closed when cache is ready (signals waiting goroutines)
+	cacheErr          error         // Error that ended the caching iteration early
 	mu                sync.Mutex    // Protects cache state transitions
 
+	// Error the relation was created with (see newFailedRelation)
+	err error
+
 	// Lightweight size tracking: count tuples without buffering data
 	counter         *CountingIterator // For tracking tuple count during iteration
 	iteratorCalled  bool              // Track if Iterator() was already called (for single-use enforcement)
@@ -723,23 +868,44 @@ func NewStreamingRelationWithOptions(columns []query.Symbol, iterator Iterator,
 	}
 }
 
-func (r *StreamingRelation) Columns() []query.Symbol {
+func (r *StreamingRelation) Symbols() []query.Symbol {
 	return r.columns
 }
 
-func (r *StreamingRelation) Symbols() []query.Symbol {
-	return r.columns
+// Schema returns the relation's symbols with unknown types
+func (r *StreamingRelation) Schema() RelationSchema {
+	return NewRelationSchema(r.columns)
+}
+
+// Columns returns the relation's symbols.
+// Deprecated: Use Symbols or Schema.
+func (r *StreamingRelation) Columns() []query.Symbol {
+	return r.Symbols()
+}
+
+// ForEach calls fn for each tuple (see Relation.ForEach). Like Iterator, it
+// consumes the stream unless the relation is cached.
+func (r *StreamingRelation) ForEach(fn func(Tuple) (bool, error)) error {
+	return forEachTuple(r, fn)
 }
 
 func (r *StreamingRelation) Iterator() Iterator {
 	r.mu.Lock()
 
+	// Drained into memory by Get: iterate the materialized tuples
+	if r.materialized != nil {
+		r.mu.Unlock()
+		return r.materialized.Iterator()
+	}
+
 	// Fast path: If we have a complete cache, return reusable iterator
 	if r.cacheReady {
+		cacheErr := r.cacheErr
 		r.mu.Unlock()
 		return &sliceIterator{
 			tuples: r.cache,
 			pos:    -1,
+			err:    cacheErr,
 		}
 	}
 
@@ -752,9 +918,13 @@ func (r *StreamingRelation) Iterator() Iterator {
 		<-completeChan
 
 		// Cache is now ready, return iterator over cached data
+		r.mu.Lock()
+		cacheErr := r.cacheErr
+		r.mu.Unlock()
 		return &sliceIterator{
 			tuples: r.cache,
 			pos:    -1,
+			err:    cacheErr,
 		}
 	}
 
@@ -791,13 +961,46 @@ func (r *StreamingRelation) Iterator() Iterator {
 
 	// If caching enabled, wrap with CachingIterator
 	if r.shouldCache {
-		return NewCachingIterator(baseIter, &r.cache, r.cacheComplete, &r.cachingInProgress, &r.cacheReady, &r.mu)
+		ci := NewCachingIterator(baseIter, &r.cache, r.cacheComplete, &r.cachingInProgress, &r.cacheReady, &r.mu)
+		ci.cacheErr = &r.cacheErr
+		return ci
 	}
 
 	// Pure streaming - single use
 	return baseIter
 }
 
+// Err returns the error that ended iteration of the relation early, if any.
+// Like sql.Rows.Err it is only meaningful once the stream has been consumed;
+// before that it reports nil.
+func (r *StreamingRelation) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if r.cacheErr != nil {
+		return r.cacheErr
+	}
+	if r.counter != nil && r.counter.IsDone() {
+		return r.counter.Err()
+	}
+	return nil
+}
+
+// newFailedRelation returns an empty relation that reports err from Err and
+// from its iterator. Operators that cannot return an error use it to pass a
+// failure on to whoever consumes their result.
+func newFailedRelation(columns []query.Symbol, err error, opts ExecutorOptions) *StreamingRelation {
+	return &StreamingRelation{
+		columns:  columns,
+		iterator: &sliceIterator{pos: -1, err: err},
+		size:     -1,
+		options:  opts,
+		err:      err,
+	}
+}
+
 func (r *StreamingRelation) Size() int {
 	r.mu.Lock()
 
@@ -884,55 +1087,62 @@ func (r *StreamingRelation) IsEmpty() bool {
 	return false
 }
 
-// Get returns a specific tuple by index
+// Get returns a specific tuple by index.
+//
+// Deprecated: Get drains the stream into memory on first use, hiding an O(n)
+// materialization behind what looks like an O(1) call. Iterate with
+// Iterator(), or Materialize() and use At on the result.
 func (r *StreamingRelation) Get(i int) Tuple {
-	// Trigger materialization, then delegate to materialized version
-	_ = r.Iterator() // Triggers materializeOnce
-	if r.materialized != nil {
-		return r.materialized.Get(i)
-	}
-	return nil
-}
-
-// String returns a compact string representation for annotations
-func (r *StreamingRelation) String() string {
-	// Format as: Relation([?x ?y], N Tuples) with colors
-	var symbols []string
-	for _, col := range r.columns {
-		symbols = append(symbols, string(col))
+	r.mu.Lock()
+	if r.cacheReady {
+		r.mu.Unlock()
+		if i < 0 || i >= len(r.cache) {
+			return nil
+		}
+		return r.cache[i]
 	}
+	r.mu.Unlock()
 
-	// For streaming relations, we might not know the size
-	if r.size >= 0 {
-		// Color the tuple count based on size
-		var countStr string
-		switch {
-		case r.size == 0:
-			countStr = color.RedString("%d", r.size)
-		case r.size < 100:
-			countStr = color.GreenString("%d", r.size)
-		case r.size < 10000:
-			countStr = color.YellowString("%d", r.size)
-		default:
-			countStr = color.RedString("%d", r.size)
+	r.materializeOnce.Do(func() {
+		it := r.Iterator()
+		defer it.Close()
+		var tuples []Tuple
+		for it.Next() {
+			tuples = append(tuples, it.Tuple())
+		}
+		r.mu.Lock()
+		r.materialized = NewMaterializedRelationNoDedupeWithOptions(r.columns, tuples, r.options)
+		if err := it.Err(); err != nil && r.err == nil {
+			r.err = err
 		}
+		r.mu.Unlock()
+	})
+	return r.materialized.Get(i)
+}
 
-		return fmt.Sprintf("%s%s%s%s%s %s%s",
-			color.BlueString("Relation(["),
-			color.CyanString(strings.Join(symbols, " ")),
-			color.BlueString("]"),
-			color.BlueString(", "),
-			countStr,
-			"Tuples",
-			color.BlueString(")"))
+// knownSize reports the size once the relation has been cached,
+// materialized or fully iterated, without triggering any of those
+func (r *StreamingRelation) knownSize() (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.cacheReady:
+		return len(r.cache), true
+	case r.size >= 0:
+		return r.size, true
+	case r.materialized != nil:
+		return r.materialized.Size(), true
+	case r.counter != nil && r.counter.IsDone():
+		return r.counter.Count(), true
 	}
+	return -1, false
+}
 
-	// Size unknown
-	return fmt.Sprintf("%s%s%s%s",
-		color.BlueString("Relation(["),
-		color.CyanString(strings.Join(symbols, " ")),
-		color.BlueString("]"),
-		color.BlueString(", streaming)"))
+// String returns a compact string representation for annotations. It never
+// consumes the relation, so the size is only shown once it is known.
+func (r *StreamingRelation) String() string {
+	size, _ := r.knownSize()
+	return relationString(r.columns, size)
 }
 
 // Table returns a formatted markdown table representation
@@ -1023,17 +1233,8 @@ func (r *StreamingRelation) Project(columns []query.Symbol) (Relation, error) {
 
 	// Streaming is now the default behavior
 	// Validate columns exist
-	for _, col := range columns {
-		found := false
-		for _, existing := range r.columns {
-			if existing == col {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil, fmt.Errorf("cannot project: column %s not found in relation", col)
-		}
+	if _, err := r.Schema().Project(columns); err != nil {
+		return nil, err
 	}
 	// CRITICAL FIX: Pass the relation itself to ProjectIterator, not the raw iterator
 	// This allows ProjectIterator to call r.Iterator(), which respects caching/materialization
@@ -1074,9 +1275,9 @@ func (r *StreamingRelation) Materialize() Relation {
 // Sort returns a new relation sorted by the specified order-by clauses
 // Warning: This materializes the streaming relation
 func (r *StreamingRelation) Sort(orderBy []query.OrderByClause) Relation {
-	// First materialize, then sort
-	materialized := r.Materialize()
-	return materialized.Sort(orderBy)
+	// Materialize() returns the streaming relation itself (with caching enabled),
+	// so sort the collected tuples directly instead of delegating back to Sort()
+	return SortRelation(r, orderBy)
 }
 
 // Filter returns a new relation with only tuples that satisfy the filter
@@ -1164,20 +1365,14 @@ type PatternBinding struct {
 
 // ColumnIndex returns the index of a column, or -1 if not found
 func ColumnIndex(rel Relation, sym query.Symbol) int {
-	cols := rel.Columns()
-	for i, col := range cols {
-		if col == sym {
-			return i
-		}
-	}
-	return -1
+	return rel.Schema().Index(sym)
 }
 
 // CommonColumns returns columns that appear in both relations
 func CommonColumns(r1, r2 Relation) []query.Symbol {
-	cols1 := r1.Columns()
+	cols1 := r1.Symbols()
 	cols2Set := make(map[query.Symbol]bool)
-	for _, col := range r2.Columns() {
+	for _, col := range r2.Symbols() {
 		cols2Set[col] = true
 	}
 
@@ -1202,8 +1397,11 @@ func Select(rel Relation, pred func(Tuple) bool) Relation {
 			selected = append(selected, tuple)
 		}
 	}
+	if err := it.Err(); err != nil {
+		return newFailedRelation(rel.Symbols(), err, rel.Options())
+	}
 
-	return NewMaterializedRelation(rel.Columns(), selected)
+	return NewMaterializedRelation(rel.Symbols(), selected)
 }
 
 // ProductRelation represents a streaming Cartesian product of multiple relations
@@ -1224,10 +1422,16 @@ func NewProductRelation(relations []Relation) *ProductRelation {
 		}
 	}
 
-	// Combine columns from all relations
+	// Combine columns from all relations. The product iterates every
+	// relation but the first once per tuple of those before it, so they
+	// must support repeated iteration.
 	var allColumns []query.Symbol
-	for _, rel := range relations {
-		allColumns = append(allColumns, rel.Columns()...)
+	relations = append([]Relation(nil), relations...)
+	for i, rel := range relations {
+		allColumns = append(allColumns, rel.Symbols()...)
+		if i > 0 {
+			relations[i] = rel.Materialize()
+		}
 	}
 
 	// Extract options from first relation
@@ -1240,12 +1444,24 @@ func NewProductRelation(relations []Relation) *ProductRelation {
 	}
 }
 
-func (p *ProductRelation) Columns() []query.Symbol {
+func (p *ProductRelation) Symbols() []query.Symbol {
 	return p.columns
 }
 
-func (p *ProductRelation) Symbols() []query.Symbol {
-	return p.columns
+// Schema returns the relation's symbols with unknown types
+func (p *ProductRelation) Schema() RelationSchema {
+	return NewRelationSchema(p.columns)
+}
+
+// Columns returns the relation's symbols.
+// Deprecated: Use Symbols or Schema.
+func (p *ProductRelation) Columns() []query.Symbol {
+	return p.Symbols()
+}
+
+// ForEach calls fn for each tuple of the product (see Relation.ForEach)
+func (p *ProductRelation) ForEach(fn func(Tuple) (bool, error)) error {
+	return forEachTuple(p, fn)
 }
 
 func (p *ProductRelation) Iterator() Iterator {
@@ -1276,6 +1492,21 @@ func (p *ProductRelation) Size() int {
 	return size
 }
 
+func (p *ProductRelation) knownSize() (int, bool) {
+	size := 1
+	for _, rel := range p.relations {
+		relSize, ok := KnownSize(rel)
+		if !ok {
+			return -1, false
+		}
+		size *= relSize
+	}
+	if len(p.relations) == 0 {
+		return 0, true
+	}
+	return size, true
+}
+
 func (p *ProductRelation) IsEmpty() bool {
 	for _, rel := range p.relations {
 		if rel.IsEmpty() {
@@ -1285,6 +1516,10 @@ func (p *ProductRelation) IsEmpty() bool {
 	return len(p.relations) == 0
 }
 
+// Get returns the i-th tuple of the product.
+//
+// Deprecated: Get materializes the full product on every call. Iterate with
+// Iterator() instead.
 func (p *ProductRelation) Get(i int) Tuple {
 	// Materialize for random access
 	return p.Materialize().Get(i)
@@ -1314,21 +1549,12 @@ func (p *ProductRelation) Project(columns []query.Symbol) (Relation, error) {
 	}
 
 	// Validate columns exist
-	for _, col := range columns {
-		found := false
-		for _, existing := range p.Columns() {
-			if existing == col {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil, fmt.Errorf("cannot project: column %s not found in relation", col)
-		}
+	if _, err := p.Schema().Project(columns); err != nil {
+		return nil, err
 	}
 	// Product relations are streaming - use iterator composition
 	// Pass the relation itself so ProjectIterator can call Iterator() when needed
-	projIter := NewProjectIterator(p, p.Columns(), columns)
+	projIter := NewProjectIterator(p, p.Symbols(), columns)
 	// Use default options since ProductRelation is a wrapper
 	return NewStreamingRelation(columns, projIter), nil
 }
@@ -1341,6 +1567,9 @@ func (p *ProductRelation) Materialize() Relation {
 	for it.Next() {
 		tuples = append(tuples, it.Tuple())
 	}
+	if err := it.Err(); err != nil {
+		return newFailedRelation(p.columns, err, p.options)
+	}
 
 	return NewMaterializedRelationWithOptions(p.columns, tuples, p.options)
 }
@@ -1403,6 +1632,7 @@ type ProductIterator struct {
 	current   []Tuple
 	first     bool
 	done      bool
+	err       error
 }
 
 func (pi *ProductIterator) Next() bool {
@@ -1424,6 +1654,7 @@ func (pi *ProductIterator) Next() bool {
 			if !pi.iterators[i].Next() {
 				// Empty relation - product is empty
 				pi.done = true
+				pi.err = pi.iterators[i].Err()
 				return false
 			}
 			pi.current[i] = pi.iterators[i].Tuple()
@@ -1439,6 +1670,11 @@ func (pi *ProductIterator) Next() bool {
 		}
 
 		// This iterator exhausted - reset it and advance previous
+		if err := pi.iterators[i].Err(); err != nil {
+			pi.done = true
+			pi.err = err
+			return false
+		}
 		if i == 0 {
 			// Leftmost iterator exhausted - we're done
 			pi.done = true
@@ -1451,6 +1687,7 @@ func (pi *ProductIterator) Next() bool {
 		if !pi.iterators[i].Next() {
 			// Should not happen - relation became empty
 			pi.done = true
+			pi.err = pi.iterators[i].Err()
 			return false
 		}
 		pi.current[i] = pi.iterators[i].Tuple()
@@ -1476,3 +1713,8 @@ func (pi *ProductIterator) Close() error {
 	}
 	return nil
 }
+
+// Err returns the error of the relation iterator that ended the product early
+func (pi *ProductIterator) Err() error {
+	return pi.err
+}
@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// closeTrackingIterator records whether Close was called and can fail it
+type closeTrackingIterator struct {
+	Iterator
+	closed   bool
+	closeErr error
+}
+
+func (it *closeTrackingIterator) Close() error {
+	it.closed = true
+	it.Iterator.Close()
+	return it.closeErr
+}
+
+func newCloseTrackingRelation(n int) (*StreamingRelation, *closeTrackingIterator) {
+	tuples := make([]Tuple, n)
+	for i := range tuples {
+		tuples[i] = Tuple{int64(i)}
+	}
+	it := &closeTrackingIterator{Iterator: &sliceIterator{tuples: tuples, pos: -1}}
+	return NewStreamingRelation([]query.Symbol{"?x"}, it), it
+}
+
+func TestRelationForEach(t *testing.T) {
+	t.Run("VisitsAllTuples", func(t *testing.T) {
+		rel := NewMaterializedRelation([]query.Symbol{"?x"}, []Tuple{{int64(1)}, {int64(2)}, {int64(3)}})
+		var sum int64
+		err := rel.ForEach(func(tuple Tuple) (bool, error) {
+			sum += tuple[0].(int64)
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("ForEach failed: %v", err)
+		}
+		if sum != 6 {
+			t.Errorf("Expected sum 6, got %d", sum)
+		}
+	})
+
+	t.Run("StopsEarlyAndCloses", func(t *testing.T) {
+		rel, it := newCloseTrackingRelation(10)
+		visited := 0
+		err := rel.ForEach(func(tuple Tuple) (bool, error) {
+			visited++
+			return visited == 3, nil
+		})
+		if err != nil {
+			t.Fatalf("ForEach failed: %v", err)
+		}
+		if visited != 3 {
+			t.Errorf("Expected 3 tuples visited, got %d", visited)
+		}
+		if !it.closed {
+			t.Error("Expected iterator to be closed after early stop")
+		}
+	})
+
+	t.Run("PropagatesCallbackError", func(t *testing.T) {
+		rel, it := newCloseTrackingRelation(10)
+		it.closeErr = errors.New("close failed")
+		boom := errors.New("boom")
+		visited := 0
+		err := rel.ForEach(func(tuple Tuple) (bool, error) {
+			visited++
+			if visited == 2 {
+				return false, boom
+			}
+			return false, nil
+		})
+		if !errors.Is(err, boom) {
+			t.Errorf("Expected callback error, got %v", err)
+		}
+		if visited != 2 {
+			t.Errorf("Expected iteration to stop at the error, visited %d", visited)
+		}
+		if !it.closed {
+			t.Error("Expected iterator to be closed after callback error")
+		}
+	})
+
+	t.Run("PropagatesCloseError", func(t *testing.T) {
+		rel, it := newCloseTrackingRelation(2)
+		it.closeErr = errors.New("close failed")
+		err := rel.ForEach(func(tuple Tuple) (bool, error) {
+			return false, nil
+		})
+		if err == nil || err.Error() != "close failed" {
+			t.Errorf("Expected close error, got %v", err)
+		}
+	})
+
+	t.Run("ProductRelation", func(t *testing.T) {
+		a := NewMaterializedRelation([]query.Symbol{"?a"}, []Tuple{{int64(1)}, {int64(2)}})
+		b := NewMaterializedRelation([]query.Symbol{"?b"}, []Tuple{{"x"}, {"y"}, {"z"}})
+		count := 0
+		err := NewProductRelation([]Relation{a, b}).ForEach(func(tuple Tuple) (bool, error) {
+			count++
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("ForEach failed: %v", err)
+		}
+		if count != 6 {
+			t.Errorf("Expected 6 tuples, got %d", count)
+		}
+	})
+}
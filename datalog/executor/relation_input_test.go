@@ -6,6 +6,7 @@ import (
 
 	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -628,3 +629,113 @@ func TestRelationInputParallelStress(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateInputRelationsFromValuesRelationInput(t *testing.T) {
+	q := &query.Query{In: []query.InputSpec{
+		query.DatabaseInput{},
+		query.ScalarInput{Symbol: "?limit"},
+		query.RelationInput{Symbols: []query.Symbol{"?sym", "?min"}},
+	}}
+
+	rows := make([][]interface{}, 300)
+	for i := range rows {
+		rows[i] = []interface{}{fmt.Sprintf("SYM%04d", i), int64(i)}
+	}
+	tuples := make([]Tuple, len(rows))
+	for i, row := range rows {
+		tuples[i] = Tuple(row)
+	}
+
+	tests := []struct {
+		name     string
+		values   []interface{}
+		wantRows int // -1 means the values should be rejected
+	}{
+		{"slice of rows", []interface{}{query.Symbol("$"), 10, rows}, 300},
+		{"relation", []interface{}{query.Symbol("$"), 10, NewMaterializedRelation([]query.Symbol{"?a", "?b"}, tuples)}, 300},
+		{"tuples", []interface{}{query.Symbol("$"), 10, tuples}, 300},
+		{"empty", []interface{}{query.Symbol("$"), 10, [][]interface{}{}}, 0},
+		{"positional", []interface{}{query.Symbol("$"), 10, "SYM0001", int64(1)}, 1},
+		{"wrong width", []interface{}{query.Symbol("$"), 10, [][]interface{}{{"SYM0001"}}}, -1},
+		{"relation wrong width", []interface{}{query.Symbol("$"), 10, NewMaterializedRelation([]query.Symbol{"?a"}, nil)}, -1},
+		{"missing values", []interface{}{query.Symbol("$"), 10}, -1},
+		{"extra values", []interface{}{query.Symbol("$"), 10, rows, 5}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs := createInputRelationsFromValues(q, tt.values)
+			if tt.wantRows < 0 {
+				if inputs != nil {
+					t.Fatalf("Expected values to be rejected, got %d relations", len(inputs))
+				}
+				return
+			}
+			if len(inputs) != 2 {
+				t.Fatalf("Expected 2 input relations, got %d", len(inputs))
+			}
+			rel := inputs[1]
+			if cols := rel.Columns(); len(cols) != 2 || cols[0] != "?sym" || cols[1] != "?min" {
+				t.Errorf("Expected relation input bound to [?sym ?min], got %v", cols)
+			}
+			if rel.Size() != tt.wantRows {
+				t.Errorf("Expected %d rows, got %d", tt.wantRows, rel.Size())
+			}
+		})
+	}
+}
+
+func TestRelationInputSubquery(t *testing.T) {
+	datoms := collectionInputDatoms(1000)
+
+	// The outer query passes a whole relation through ?pairs; the subquery
+	// binds every row with [[?sym ?min] ...] and aggregates over all of them
+	q, err := parser.ParseQuery(`[:find ?n ?total
+	                              :in $ ?pairs
+	                              :where [(q [:find (count ?e) (sum ?price)
+	                                          :in $ [[?sym ?min] ...]
+	                                          :where [?e :stock/symbol ?sym]
+	                                                 [?e :stock/price ?price]
+	                                                 [(>= ?price ?min)]]
+	                                        $ ?pairs) [[?n ?total]]]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	// Every even symbol in [0, 600), with a minimum price that excludes
+	// the first hundred
+	var pairs [][]interface{}
+	var expectedCount, expectedSum int64
+	for i := 0; i < 600; i += 2 {
+		pairs = append(pairs, []interface{}{fmt.Sprintf("SYM%04d", i), int64(100)})
+		if i >= 100 {
+			expectedCount++
+			expectedSum += int64(i)
+		}
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		t.Run(fmt.Sprintf("QueryExecutor=%v", useQueryExecutor), func(t *testing.T) {
+			exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+				UseQueryExecutor: useQueryExecutor,
+			})
+
+			input := NewMaterializedRelation([]query.Symbol{"?pairs"}, []Tuple{{pairs}})
+			result, err := exec.ExecuteWithRelations(NewContext(nil), q, []Relation{input})
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if result.Size() != 1 {
+				t.Fatalf("Expected 1 result, got %d", result.Size())
+			}
+
+			tuple := result.Get(0)
+			if n := tuple[0]; n != expectedCount && n != int(expectedCount) {
+				t.Errorf("Expected count %d, got %v (%T)", expectedCount, n, n)
+			}
+			if total := tuple[1]; total != expectedSum && total != float64(expectedSum) {
+				t.Errorf("Expected sum %d, got %v (%T)", expectedSum, total, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func streamingInts(n int) *StreamingRelation {
+	tuples := make([]Tuple, n)
+	for i := range tuples {
+		tuples[i] = Tuple{int64(i)}
+	}
+	return NewStreamingRelation([]query.Symbol{"?x"}, &sliceIterator{tuples: tuples, pos: -1})
+}
+
+func TestMaterializedRelationAt(t *testing.T) {
+	var rel RandomAccessRelation = NewMaterializedRelation(
+		[]query.Symbol{"?x"},
+		[]Tuple{{int64(1)}, {int64(2)}},
+	)
+
+	tuple, err := rel.At(1)
+	if err != nil {
+		t.Fatalf("At(1): %v", err)
+	}
+	if tuple[0] != int64(2) {
+		t.Errorf("At(1) = %v, want [2]", tuple)
+	}
+
+	for _, i := range []int{-1, 2} {
+		if _, err := rel.At(i); !errors.Is(err, ErrTupleIndexOutOfRange) {
+			t.Errorf("At(%d): expected ErrTupleIndexOutOfRange, got %v", i, err)
+		}
+	}
+}
+
+func TestStreamingRelationGetThenIterate(t *testing.T) {
+	rel := streamingInts(5)
+
+	if tuple := rel.Get(3); tuple == nil || tuple[0] != int64(3) {
+		t.Fatalf("Get(3) = %v, want [3]", tuple)
+	}
+	if tuple := rel.Get(10); tuple != nil {
+		t.Errorf("Get(10) = %v, want nil", tuple)
+	}
+	if size := rel.Size(); size != 5 {
+		t.Errorf("Size() after Get = %d, want 5", size)
+	}
+
+	// Get drained the stream; iteration reads the materialized tuples
+	count := 0
+	it := rel.Iterator()
+	for it.Next() {
+		count++
+	}
+	it.Close()
+	if count != 5 {
+		t.Errorf("iterated %d tuples after Get, want 5", count)
+	}
+}
+
+func TestApplyBindingFormStreaming(t *testing.T) {
+	inputs := map[query.Symbol]interface{}{"?in": "a"}
+	inputSymbols := []query.Symbol{"$", "?in"}
+
+	t.Run("tuple binding", func(t *testing.T) {
+		got, err := applyBindingForm(streamingInts(1), query.TupleBinding{Variables: []query.Symbol{"?v"}}, inputs, inputSymbols)
+		if err != nil {
+			t.Fatalf("applyBindingForm: %v", err)
+		}
+		tuples := got.Sorted()
+		if len(tuples) != 1 || tuples[0][0] != "a" || tuples[0][1] != int64(0) {
+			t.Errorf("expected [a 0], got %v", tuples)
+		}
+	})
+
+	t.Run("tuple binding empty", func(t *testing.T) {
+		got, err := applyBindingForm(streamingInts(0), query.TupleBinding{Variables: []query.Symbol{"?v"}}, inputs, inputSymbols)
+		if err != nil {
+			t.Fatalf("applyBindingForm: %v", err)
+		}
+		if !got.IsEmpty() {
+			t.Errorf("expected empty relation, got %v", got.Sorted())
+		}
+	})
+
+	t.Run("tuple binding too many", func(t *testing.T) {
+		_, err := applyBindingForm(streamingInts(3), query.TupleBinding{Variables: []query.Symbol{"?v"}}, inputs, inputSymbols)
+		if err == nil || !strings.Contains(err.Error(), "got 3") {
+			t.Errorf("expected error for 3 results, got %v", err)
+		}
+	})
+
+	t.Run("relation binding", func(t *testing.T) {
+		got, err := applyBindingForm(streamingInts(4), query.RelationBinding{Variables: []query.Symbol{"?v"}}, inputs, inputSymbols)
+		if err != nil {
+			t.Fatalf("applyBindingForm: %v", err)
+		}
+		if size := got.Size(); size != 4 {
+			t.Errorf("expected 4 tuples, got %d", size)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func sizeTestTuples(n int) []Tuple {
+	tuples := make([]Tuple, n)
+	for i := range tuples {
+		tuples[i] = Tuple{int64(i)}
+	}
+	return tuples
+}
+
+func TestKnownSize(t *testing.T) {
+	cols := []query.Symbol{"?x"}
+	source := NewCountingIterator(NewMaterializedRelation(cols, sizeTestTuples(3)).Iterator())
+	rel := NewStreamingRelation(cols, source)
+
+	if _, ok := KnownSize(rel); ok {
+		t.Error("Expected an unread streaming relation's size to be unknown")
+	}
+	if s := rel.String(); !strings.Contains(s, "streaming") {
+		t.Errorf("Expected String() to report a streaming relation, got %s", s)
+	}
+	if source.Count() != 0 {
+		t.Fatalf("KnownSize and String read %d tuples", source.Count())
+	}
+
+	count := 0
+	if err := rel.ForEach(func(Tuple) (bool, error) {
+		count++
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("Expected 3 tuples, got %d", count)
+	}
+	if size, ok := KnownSize(rel); !ok || size != 3 {
+		t.Errorf("Expected size 3 once iterated, got %d (known %v)", size, ok)
+	}
+
+	product := NewProductRelation([]Relation{
+		NewMaterializedRelation(cols, sizeTestTuples(2)),
+		NewMaterializedRelation([]query.Symbol{"?y"}, []Tuple{{"a"}, {"b"}, {"c"}}),
+	})
+	if size, ok := KnownSize(product); !ok || size != 6 {
+		t.Errorf("Expected product size 6, got %d (known %v)", size, ok)
+	}
+}
+
+func TestAnnotationsDontConsumeStreaming(t *testing.T) {
+	var events []annotations.Event
+	ctx := NewContext(func(e annotations.Event) { events = append(events, e) })
+
+	cols := []query.Symbol{"?x"}
+	source := NewCountingIterator(NewMaterializedRelation(cols, sizeTestTuples(5)).Iterator())
+	input := NewStreamingRelationWithOptions(cols, source, ExecutorOptions{EnableTrueStreaming: true})
+
+	// A filter whose output streams from its input, like a composed
+	// filter iterator
+	output := ctx.FilterRelation(input, "(< ?x 5)", func() Relation {
+		return input
+	})
+	if source.Count() != 0 {
+		t.Fatalf("Annotating the filter read %d tuples", source.Count())
+	}
+
+	// The single-use input is still intact for the filter's reader
+	count := 0
+	if err := output.ForEach(func(Tuple) (bool, error) {
+		count++
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("Expected 5 filtered tuples, got %d", count)
+	}
+
+	var filter *annotations.Event
+	for i := range events {
+		if events[i].Name == "filter/predicate" {
+			filter = &events[i]
+		}
+	}
+	if filter == nil {
+		t.Fatal("Expected a filter/predicate event")
+	}
+	if filter.Data["input.size"] != -1 {
+		t.Errorf("Expected unknown input size, got %v", filter.Data["input.size"])
+	}
+	if _, ok := filter.Data["selectivity"]; ok {
+		t.Error("Expected no selectivity without known sizes")
+	}
+
+	var buf bytes.Buffer
+	line := annotations.NewOutputFormatter(&buf).Format(*filter)
+	if !strings.Contains(line, "on ? Tuples") {
+		t.Errorf("Expected unknown sizes rendered as ?, got %s", line)
+	}
+}
@@ -0,0 +1,163 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// FactWriter accepts datoms to be written, e.g. a storage.Transaction
+type FactWriter interface {
+	Add(e datalog.EntityRef, a datalog.Keyword, v interface{}) error
+}
+
+// RelationStorePlan maps the rows of a relation to entities and attributes.
+//
+// Each row becomes one entity, identified either by EntityColumn (a column
+// holding an Identity or LookupRef) or by the values of the EntityKey columns,
+// named EntityPrefix:value1:value2... Key-derived entities are deterministic,
+// so storing the same rows again updates the same entities.
+type RelationStorePlan struct {
+	EntityColumn query.Symbol
+	EntityPrefix string
+	EntityKey    []query.Symbol
+
+	// Attributes maps columns to the attribute their values are stored as.
+	// Columns not listed are not stored; nil values are skipped.
+	Attributes map[query.Symbol]datalog.Keyword
+}
+
+// StoreRelation writes the rows of rel to w as entities according to plan,
+// materializing a derived dataset (e.g. daily aggregates from a query) through
+// the same transactional path as any other data. It returns the number of
+// entities written; nothing is durable until the caller commits w.
+func StoreRelation(w FactWriter, rel Relation, plan RelationStorePlan) (int, error) {
+	columns := rel.Symbols()
+	indexOf := func(sym query.Symbol) (int, error) {
+		for i, col := range columns {
+			if col == sym {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("relation has no column %s", sym)
+	}
+
+	entityIdx := -1
+	var keyIndices []int
+	switch {
+	case plan.EntityColumn != "" && len(plan.EntityKey) > 0:
+		return 0, fmt.Errorf("store plan must set EntityColumn or EntityKey, not both")
+	case plan.EntityColumn != "":
+		idx, err := indexOf(plan.EntityColumn)
+		if err != nil {
+			return 0, err
+		}
+		entityIdx = idx
+	case len(plan.EntityKey) > 0:
+		for _, sym := range plan.EntityKey {
+			idx, err := indexOf(sym)
+			if err != nil {
+				return 0, err
+			}
+			keyIndices = append(keyIndices, idx)
+		}
+	default:
+		return 0, fmt.Errorf("store plan must set EntityColumn or EntityKey")
+	}
+
+	// Attribute columns in relation order, so datoms are written deterministically
+	type attrColumn struct {
+		index int
+		attr  datalog.Keyword
+	}
+	var attrs []attrColumn
+	for i, col := range columns {
+		if attr, ok := plan.Attributes[col]; ok {
+			attrs = append(attrs, attrColumn{index: i, attr: attr})
+		}
+	}
+	if len(attrs) != len(plan.Attributes) {
+		for sym := range plan.Attributes {
+			if _, err := indexOf(sym); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	it := rel.Iterator()
+	defer it.Close()
+
+	entities := 0
+	for it.Next() {
+		tuple := it.Tuple()
+
+		var entity datalog.EntityRef
+		if entityIdx >= 0 {
+			switch e := tuple[entityIdx].(type) {
+			case datalog.Identity:
+				entity = e
+			case *datalog.Identity:
+				entity = *e
+			case datalog.LookupRef:
+				entity = e
+			default:
+				return entities, fmt.Errorf("entity column %s holds %T, not an identity", plan.EntityColumn, tuple[entityIdx])
+			}
+		} else {
+			parts := make([]string, 0, len(keyIndices)+1)
+			if plan.EntityPrefix != "" {
+				parts = append(parts, plan.EntityPrefix)
+			}
+			for _, idx := range keyIndices {
+				parts = append(parts, fmt.Sprint(storableValue(tuple[idx])))
+			}
+			entity = datalog.NewIdentity(strings.Join(parts, ":"))
+		}
+
+		for _, ac := range attrs {
+			v := storableValue(tuple[ac.index])
+			if v == nil {
+				continue
+			}
+			if !isStorableValue(v) {
+				return entities, fmt.Errorf("column %s holds %T, which cannot be stored", columns[ac.index], v)
+			}
+			if err := w.Add(entity, ac.attr, v); err != nil {
+				return entities, fmt.Errorf("failed to store %s: %w", columns[ac.index], err)
+			}
+		}
+		entities++
+	}
+	return entities, nil
+}
+
+// storableValue dereferences interned values and widens ints to int64
+func storableValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *datalog.Identity:
+		return *val
+	case *datalog.Keyword:
+		return *val
+	case *uint64:
+		return int64(*val)
+	case uint64:
+		return int64(val)
+	case int:
+		return int64(val)
+	default:
+		return v
+	}
+}
+
+// isStorableValue reports whether v is a datom value type
+func isStorableValue(v interface{}) bool {
+	switch v.(type) {
+	case string, int64, float64, bool, time.Time, []byte, datalog.Identity, datalog.Keyword:
+		return true
+	default:
+		return false
+	}
+}
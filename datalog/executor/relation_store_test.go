@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// recordingWriter collects the datoms written to it
+type recordingWriter struct {
+	datoms []datalog.Datom
+}
+
+func (w *recordingWriter) Add(e datalog.EntityRef, a datalog.Keyword, v interface{}) error {
+	w.datoms = append(w.datoms, datalog.Datom{E: e.(datalog.Identity), A: a, V: v})
+	return nil
+}
+
+func TestStoreRelation(t *testing.T) {
+	rel := NewMaterializedRelation(
+		[]query.Symbol{"?symbol", "?day", "(sum ?volume)", "(avg ?close)"},
+		[]Tuple{
+			{"AAPL", int64(1), int64(1500), 101.5},
+			{"AAPL", int64(2), int64(900), nil},
+			{"MSFT", int64(1), int64(700), 55.25},
+		},
+	)
+
+	t.Run("entity key", func(t *testing.T) {
+		w := &recordingWriter{}
+		n, err := StoreRelation(w, rel, RelationStorePlan{
+			EntityPrefix: "daily",
+			EntityKey:    []query.Symbol{"?symbol", "?day"},
+			Attributes: map[query.Symbol]datalog.Keyword{
+				"?symbol":       datalog.NewKeyword(":daily/symbol"),
+				"(sum ?volume)": datalog.NewKeyword(":daily/volume"),
+				"(avg ?close)":  datalog.NewKeyword(":daily/avg-close"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("StoreRelation failed: %v", err)
+		}
+		if n != 3 {
+			t.Errorf("Expected 3 entities, got %d", n)
+		}
+		// The nil average of the second row is skipped
+		if len(w.datoms) != 8 {
+			t.Fatalf("Expected 8 datoms, got %d: %v", len(w.datoms), w.datoms)
+		}
+		if !w.datoms[0].E.Equal(datalog.NewIdentity("daily:AAPL:1")) {
+			t.Errorf("Expected entity daily:AAPL:1, got %v", w.datoms[0].E)
+		}
+		if w.datoms[0].A.String() != ":daily/symbol" || w.datoms[1].A.String() != ":daily/volume" {
+			t.Errorf("Expected datoms in column order, got %v", w.datoms[:3])
+		}
+	})
+
+	t.Run("entity column", func(t *testing.T) {
+		entities := NewMaterializedRelation(
+			[]query.Symbol{"?e", "?total"},
+			[]Tuple{{datalog.NewIdentity("order:1"), 42}},
+		)
+		w := &recordingWriter{}
+		if _, err := StoreRelation(w, entities, RelationStorePlan{
+			EntityColumn: "?e",
+			Attributes:   map[query.Symbol]datalog.Keyword{"?total": datalog.NewKeyword(":order/total")},
+		}); err != nil {
+			t.Fatalf("StoreRelation failed: %v", err)
+		}
+		if len(w.datoms) != 1 || !w.datoms[0].E.Equal(datalog.NewIdentity("order:1")) || w.datoms[0].V != int64(42) {
+			t.Errorf("Unexpected datoms %v", w.datoms)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		plan RelationStorePlan
+		want string
+	}{
+		{"no entity", RelationStorePlan{}, "must set EntityColumn or EntityKey"},
+		{"both entities", RelationStorePlan{EntityColumn: "?symbol", EntityKey: []query.Symbol{"?day"}}, "not both"},
+		{"unknown key", RelationStorePlan{EntityKey: []query.Symbol{"?missing"}}, "no column ?missing"},
+		{"unknown attribute", RelationStorePlan{
+			EntityKey:  []query.Symbol{"?symbol"},
+			Attributes: map[query.Symbol]datalog.Keyword{"?missing": datalog.NewKeyword(":x/y")},
+		}, "no column ?missing"},
+		{"non-identity entity", RelationStorePlan{EntityColumn: "?symbol"}, "not an identity"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := StoreRelation(&recordingWriter{}, rel, tc.plan)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
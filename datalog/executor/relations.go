@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -19,8 +20,8 @@ func (rs Relations) Project(symbols ...query.Symbol) Relation {
 	minExtraColumns := int(^uint(0) >> 1) // Max int
 
 	for _, rel := range rs {
-		if containsAll(rel.Columns(), symbols) {
-			extraColumns := len(rel.Columns()) - len(symbols)
+		if containsAll(rel.Symbols(), symbols) {
+			extraColumns := len(rel.Symbols()) - len(symbols)
 			if extraColumns < minExtraColumns {
 				minExtraColumns = extraColumns
 				bestRel = rel
@@ -47,7 +48,7 @@ func (rs Relations) FindBestForPattern(pattern *query.DataPattern) Relation {
 
 	for _, rel := range rs {
 		score := 0
-		cols := rel.Columns()
+		cols := rel.Symbols()
 		colSet := make(map[query.Symbol]bool)
 		for _, col := range cols {
 			colSet[col] = true
@@ -104,7 +105,7 @@ func (rs Relations) FindRelationsForSymbols(symbols ...query.Symbol) Relations {
 
 	var result Relations
 	for _, rel := range rs {
-		for _, col := range rel.Columns() {
+		for _, col := range rel.Symbols() {
 			if symbolSet[col] {
 				result = append(result, rel)
 				break
@@ -129,6 +130,33 @@ func (rs Relations) Product() Relation {
 	return NewProductRelation(rs)
 }
 
+// JoinGraph returns the graph of which relations share columns. Node i of
+// the graph is rs[i].
+func (rs Relations) JoinGraph() *planner.JoinGraph {
+	columns := make([][]query.Symbol, len(rs))
+	for i, rel := range rs {
+		columns[i] = rel.Symbols()
+	}
+	return planner.NewJoinGraph(columns)
+}
+
+// Partition splits the relations into groups that share columns, directly or
+// through other relations in the group. Relations keep their order within a
+// group, and groups are ordered by their first relation. Nothing is joined;
+// each group collapses to one relation, and more than one group means the
+// relations can only be combined by a Cartesian product.
+func (rs Relations) Partition() []Relations {
+	components := rs.JoinGraph().Components()
+	groups := make([]Relations, len(components))
+	for i, nodes := range components {
+		groups[i] = make(Relations, len(nodes))
+		for j, node := range nodes {
+			groups[i][j] = rs[node]
+		}
+	}
+	return groups
+}
+
 // Collapse joins relations that share columns and returns all relation groups.
 // Relations that can be joined are combined into single relations.
 // Relations that share no columns remain separate.
@@ -146,13 +174,10 @@ func (rs Relations) Collapse(ctx Context) Relations {
 	// Keep track of independent relation groups
 	var groups Relations
 
-	// Process relations one by one
-	remaining := Relations(rs)
-
-	for len(remaining) > 0 {
-		// Start a new group with the first remaining relation
-		currentGroup := remaining[0]
-		remaining = remaining[1:]
+	for _, partition := range rs.Partition() {
+		// Start the group with the partition's first relation
+		currentGroup := partition[0]
+		remaining := partition[1:]
 
 		// Keep joining relations into this group until no more can join
 		changed := true
@@ -188,8 +213,8 @@ func (rs Relations) Collapse(ctx Context) Relations {
 
 // hasSharedColumns checks if two relations share any columns
 func hasSharedColumns(r1, r2 Relation) bool {
-	cols1 := r1.Columns()
-	cols2 := r2.Columns()
+	cols1 := r1.Symbols()
+	cols2 := r2.Symbols()
 
 	for _, c1 := range cols1 {
 		for _, c2 := range cols2 {
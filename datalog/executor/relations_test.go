@@ -304,3 +304,29 @@ func TestRelationsCollapse(t *testing.T) {
 		assert.Equal(t, r1, groups[0])
 	})
 }
+
+func TestRelationsPartition(t *testing.T) {
+	r1 := NewMaterializedRelation([]query.Symbol{"?x", "?y"}, []Tuple{{1, 2}})
+	r2 := NewMaterializedRelation([]query.Symbol{"?a", "?b"}, []Tuple{{"foo", "bar"}})
+	r3 := NewMaterializedRelation([]query.Symbol{"?z"}, []Tuple{{3}})
+	r4 := NewMaterializedRelation([]query.Symbol{"?y", "?z"}, []Tuple{{2, 3}})
+
+	relations := Relations{r1, r2, r3, r4}
+
+	graph := relations.JoinGraph()
+	assert.Equal(t, [][]int{{3}, nil, {3}, {0, 2}}, graph.Edges)
+	assert.Equal(t, []query.Symbol{"?y"}, graph.Shared(0, 3))
+	assert.False(t, graph.Connected())
+
+	// r1 and r3 are connected through r4
+	groups := relations.Partition()
+	assert.Equal(t, []Relations{{r1, r3, r4}, {r2}}, groups)
+
+	// Collapse joins each partition into one relation
+	collapsed := relations.Collapse(NewContext(nil))
+	assert.Equal(t, len(groups), len(collapsed))
+	assert.ElementsMatch(t, []query.Symbol{"?x", "?y", "?z"}, collapsed[0].Columns())
+	assert.Equal(t, 1, collapsed[0].Size())
+
+	assert.Empty(t, Relations{}.Partition())
+}
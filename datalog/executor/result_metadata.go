@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// ResultMetadata describes how a query result was produced, so that
+// applications can log its provenance and cost with the result itself
+type ResultMetadata struct {
+	BasisTx       uint64        // Latest transaction visible to the query (0 if the matcher can't tell)
+	WallTime      time.Duration // Planning and execution, including materializing the result
+	DatomsScanned int64         // Datoms read by storage scans (0 if the matcher doesn't count them)
+	PlanCacheHit  bool          // The plan came from the plan cache
+	Phases        int           // Phases in the executed plan (0 for ordered and latest-per-entity scans)
+}
+
+// String returns the metadata as a single log-friendly line
+func (m ResultMetadata) String() string {
+	return fmt.Sprintf("basis-tx=%d wall-time=%v datoms-scanned=%d plan-cache-hit=%v phases=%d",
+		m.BasisTx, m.WallTime, m.DatomsScanned, m.PlanCacheHit, m.Phases)
+}
+
+// ScanCountingMatcher is implemented by matchers that can count the datoms
+// their scans read
+type ScanCountingMatcher interface {
+	PatternMatcher
+	// WithScanCounter returns a matcher that adds the datoms each of its
+	// scans reads to counter once the scan is closed
+	WithScanCounter(counter *atomic.Int64) PatternMatcher
+}
+
+// BasisReporter is implemented by matchers that can report the latest
+// transaction their scans see
+type BasisReporter interface {
+	BasisTx() (uint64, error)
+}
+
+// ExecuteWithMetadata runs a query like ExecuteWithRelations and returns the
+// materialized result with metadata describing its execution
+func (e *Executor) ExecuteWithMetadata(ctx Context, q *query.Query, inputRelations []Relation) (Relation, *ResultMetadata, error) {
+	start := time.Now()
+	metadata := &ResultMetadata{}
+
+	if br, ok := e.matcher.(BasisReporter); ok {
+		basis, err := br.BasisTx()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read basis transaction: %w", err)
+		}
+		metadata.BasisTx = basis
+	}
+
+	run := &Executor{
+		matcher:                  e.matcher,
+		planner:                  e.planner,
+		options:                  e.options,
+		enableParallelSubqueries: e.enableParallelSubqueries,
+		maxSubqueryWorkers:       e.maxSubqueryWorkers,
+		metadata:                 metadata,
+		queries:                  e.queries,
+	}
+	var scanned atomic.Int64
+	if sc, ok := e.matcher.(ScanCountingMatcher); ok {
+		run.matcher = sc.WithScanCounter(&scanned)
+	}
+
+	result, err := run.ExecuteWithRelations(ctx, q, inputRelations)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result != nil {
+		result = result.Materialize()
+		if err := RelationErr(result); err != nil {
+			return nil, nil, fmt.Errorf("query execution failed: %w", err)
+		}
+	}
+
+	metadata.WallTime = time.Since(start)
+	metadata.DatomsScanned = scanned.Load()
+	return result, metadata, nil
+}
+
+// recordPlan notes the plan of the query being executed in the metadata
+// requested by ExecuteWithMetadata, if any
+func (e *Executor) recordPlan(phases int, cached bool) {
+	if e.metadata != nil {
+		e.metadata.Phases = phases
+		e.metadata.PlanCacheHit = cached
+	}
+}
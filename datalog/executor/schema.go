@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// RelationSchema describes the columns of a relation: its symbols in order
+// and, where known, the type of each column's values. The relations in this
+// package don't track types yet, so their schemas have none; Types is for
+// operators that know them, such as typed projections and columnar storage.
+type RelationSchema struct {
+	Symbols []query.Symbol
+	Types   map[query.Symbol]datalog.ValueType // Known column types; nil if none are
+}
+
+// NewRelationSchema creates a schema of symbols with unknown types
+func NewRelationSchema(symbols []query.Symbol) RelationSchema {
+	return RelationSchema{Symbols: symbols}
+}
+
+// Len returns the number of columns
+func (s RelationSchema) Len() int {
+	return len(s.Symbols)
+}
+
+// Index returns the position of sym, or -1 if the schema has no such column
+func (s RelationSchema) Index(sym query.Symbol) int {
+	for i, col := range s.Symbols {
+		if col == sym {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains reports whether the schema has a column for sym
+func (s RelationSchema) Contains(sym query.Symbol) bool {
+	return s.Index(sym) >= 0
+}
+
+// Type returns the type of sym's values, if known
+func (s RelationSchema) Type(sym query.Symbol) (datalog.ValueType, bool) {
+	t, ok := s.Types[sym]
+	return t, ok
+}
+
+// WithType returns a copy of the schema recording that sym's values are of
+// type t. The schema is returned unchanged if it has no column for sym.
+func (s RelationSchema) WithType(sym query.Symbol, t datalog.ValueType) RelationSchema {
+	if !s.Contains(sym) {
+		return s
+	}
+	types := make(map[query.Symbol]datalog.ValueType, len(s.Types)+1)
+	for k, v := range s.Types {
+		types[k] = v
+	}
+	types[sym] = t
+	return RelationSchema{Symbols: s.Symbols, Types: types}
+}
+
+// Project returns the schema of the given columns, in their order, keeping
+// their types. It returns an error if the schema has no column for one of
+// them.
+func (s RelationSchema) Project(symbols []query.Symbol) (RelationSchema, error) {
+	var types map[query.Symbol]datalog.ValueType
+	for _, sym := range symbols {
+		if !s.Contains(sym) {
+			return RelationSchema{}, fmt.Errorf("cannot project: column %s not found in relation (has columns: %v)", sym, s.Symbols)
+		}
+		if t, ok := s.Types[sym]; ok {
+			if types == nil {
+				types = make(map[query.Symbol]datalog.ValueType)
+			}
+			types[sym] = t
+		}
+	}
+	return RelationSchema{Symbols: symbols, Types: types}, nil
+}
+
+// String returns the schema as e.g. "[?e ?price:float]"
+func (s RelationSchema) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, sym := range s.Symbols {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(string(sym))
+		if t, ok := s.Types[sym]; ok {
+			b.WriteByte(':')
+			b.WriteString(valueTypeName(t))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// valueTypeName returns the name of a value type for display
+func valueTypeName(t datalog.ValueType) string {
+	switch t {
+	case datalog.TypeString:
+		return "string"
+	case datalog.TypeInt:
+		return "int"
+	case datalog.TypeFloat:
+		return "float"
+	case datalog.TypeBool:
+		return "bool"
+	case datalog.TypeTime:
+		return "time"
+	case datalog.TypeBytes:
+		return "bytes"
+	case datalog.TypeReference:
+		return "ref"
+	case datalog.TypeKeyword:
+		return "keyword"
+	default:
+		return fmt.Sprintf("type(%d)", t)
+	}
+}
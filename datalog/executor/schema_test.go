@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestRelationSchema(t *testing.T) {
+	schema := NewRelationSchema([]query.Symbol{"?e", "?name", "?price"}).
+		WithType("?price", datalog.TypeFloat).
+		WithType("?missing", datalog.TypeInt)
+
+	if schema.Len() != 3 || schema.Index("?name") != 1 || schema.Contains("?missing") {
+		t.Errorf("Unexpected columns in %v", schema)
+	}
+	if typ, ok := schema.Type("?price"); !ok || typ != datalog.TypeFloat {
+		t.Errorf("Expected ?price to be a float, got %v, %v", typ, ok)
+	}
+	if _, ok := schema.Type("?name"); ok {
+		t.Error("Expected ?name to have no known type")
+	}
+	if got := schema.String(); got != "[?e ?name ?price:float]" {
+		t.Errorf("Unexpected schema string %q", got)
+	}
+
+	projected, err := schema.Project([]query.Symbol{"?price", "?e"})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	if got := projected.String(); got != "[?price:float ?e]" {
+		t.Errorf("Expected the projection to keep ?price's type, got %q", got)
+	}
+	if _, err := schema.Project([]query.Symbol{"?missing"}); err == nil {
+		t.Error("Expected projecting a missing column to fail")
+	}
+}
+
+func TestRelationSchemaMatchesSymbols(t *testing.T) {
+	symbols := []query.Symbol{"?a", "?b"}
+	tuples := []Tuple{{int64(1), "x"}, {int64(2), "y"}}
+	relations := map[string]Relation{
+		"materialized": NewMaterializedRelation(symbols, tuples),
+		"streaming":    NewStreamingRelation(symbols, &sliceIterator{tuples: tuples, pos: -1}),
+	}
+	for name, rel := range relations {
+		if got := rel.Schema().Symbols; !reflect.DeepEqual(got, symbols) {
+			t.Errorf("%s: expected schema symbols %v, got %v", name, symbols, got)
+		}
+		if !reflect.DeepEqual(rel.Columns(), rel.Symbols()) {
+			t.Errorf("%s: expected Columns to match Symbols", name)
+		}
+	}
+}
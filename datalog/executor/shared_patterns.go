@@ -0,0 +1,270 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// sharedPatternMatcher wraps a PatternMatcher for the duration of one query
+// and answers patterns found by planner.FindSharedPatterns from a single scan.
+//
+// The first match of a shared pattern scans it without bindings and keeps the
+// tuples. Later matches, typically one per subquery invocation, are answered
+// from that scan through a hash index on the positions their bindings cover.
+// The result may contain tuples the underlying matcher would have filtered
+// out (the bindings are only used to narrow it down), which is safe because
+// the executor joins every pattern result with its bindings.
+type sharedPatternMatcher struct {
+	underlying PatternMatcher
+	keys       map[string]bool
+
+	mu    sync.Mutex
+	scans map[string]*sharedScan
+}
+
+// sharedScan is the unbound scan of a shared pattern, with tuples holding the
+// pattern's variables in order of first occurrence
+type sharedScan struct {
+	once   sync.Once
+	tuples []Tuple
+	err    error
+
+	mu      sync.Mutex
+	indexes map[string]*TupleKeyMap // Positions "0,2" -> key -> []Tuple
+}
+
+// newSharedPatternMatcher wraps m to share the scans of the given patterns
+func newSharedPatternMatcher(m PatternMatcher, shared []planner.SharedPattern) *sharedPatternMatcher {
+	keys := make(map[string]bool, len(shared))
+	for _, sp := range shared {
+		keys[sp.Key] = true
+	}
+	return &sharedPatternMatcher{
+		underlying: m,
+		keys:       keys,
+		scans:      make(map[string]*sharedScan),
+	}
+}
+
+// shareScans wraps the matcher to share scans between q and its subqueries if
+// enabled and q has shared patterns
+func (e *Executor) shareScans(ctx Context, q *query.Query, matcher PatternMatcher) PatternMatcher {
+	if !e.options.EnableSharedPatterns {
+		return matcher
+	}
+	if _, ok := matcher.(*sharedPatternMatcher); ok {
+		return matcher
+	}
+
+	start := time.Now()
+	shared := planner.FindSharedPatterns(q)
+	if len(shared) == 0 {
+		return matcher
+	}
+
+	if collector := ctx.Collector(); collector != nil {
+		patterns := make([]string, len(shared))
+		for i, sp := range shared {
+			patterns[i] = sp.Inner.String() + " = " + sp.Outer.String()
+		}
+		collector.AddTiming("shared_patterns/detected", start, map[string]interface{}{
+			"patterns": patterns,
+		})
+	}
+	return newSharedPatternMatcher(matcher, shared)
+}
+
+// Match implements PatternMatcher, answering shared patterns from their scan
+func (m *sharedPatternMatcher) Match(pattern *query.DataPattern, bindings Relations) (Relation, error) {
+	key, ok := planner.SharedPatternKey(pattern)
+	if !ok || !m.keys[key] {
+		return m.underlying.Match(pattern, bindings)
+	}
+
+	vars := patternVariables(pattern)
+	scan := m.scan(key, pattern, len(vars))
+	if scan.err != nil {
+		return nil, scan.err
+	}
+
+	// Narrow the scan down to the best binding relation's values
+	binding := bindings.FindBestForPattern(pattern)
+	if binding == nil {
+		return NewMaterializedRelationNoDedupe(vars, scan.tuples), nil
+	}
+	var positions, bindingCols []int
+	for i, v := range vars {
+		if col := columnIndex(binding.Symbols(), v); col >= 0 {
+			positions = append(positions, i)
+			bindingCols = append(bindingCols, col)
+		}
+	}
+	if len(positions) == 0 {
+		return NewMaterializedRelationNoDedupe(vars, scan.tuples), nil
+	}
+
+	index := scan.index(positions)
+	seen := NewTupleKeyMap()
+	var tuples []Tuple
+	it := binding.Iterator()
+	defer it.Close()
+	for it.Next() {
+		k := NewTupleKey(it.Tuple(), bindingCols)
+		if seen.Exists(k) {
+			continue
+		}
+		seen.Put(k, true)
+		if matches, ok := index.Get(k); ok {
+			tuples = append(tuples, matches.([]Tuple)...)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return NewMaterializedRelationNoDedupe(vars, tuples), nil
+}
+
+// scan returns the unbound scan of pattern, running it on first use
+func (m *sharedPatternMatcher) scan(key string, pattern *query.DataPattern, width int) *sharedScan {
+	m.mu.Lock()
+	scan, ok := m.scans[key]
+	if !ok {
+		scan = &sharedScan{indexes: make(map[string]*TupleKeyMap)}
+		m.scans[key] = scan
+	}
+	m.mu.Unlock()
+
+	scan.once.Do(func() {
+		scan.tuples, scan.err = m.scanPattern(pattern, width)
+	})
+	return scan
+}
+
+// scanPattern matches pattern without bindings and reorders the result into
+// the pattern's variable order
+func (m *sharedPatternMatcher) scanPattern(pattern *query.DataPattern, width int) ([]Tuple, error) {
+	rel, err := m.underlying.Match(pattern, nil)
+	if err != nil {
+		return nil, fmt.Errorf("shared pattern scan failed: %w", err)
+	}
+
+	vars := patternVariables(pattern)
+	cols := make([]int, width)
+	for i, v := range vars {
+		if cols[i] = columnIndex(rel.Symbols(), v); cols[i] < 0 {
+			return nil, fmt.Errorf("shared pattern scan of %s has no column %s", pattern, v)
+		}
+	}
+
+	var tuples []Tuple
+	it := rel.Iterator()
+	defer it.Close()
+	for it.Next() {
+		t := it.Tuple()
+		tuple := make(Tuple, width)
+		for i, col := range cols {
+			tuple[i] = t[col]
+		}
+		tuples = append(tuples, tuple)
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("shared pattern scan failed: %w", err)
+	}
+	return tuples, nil
+}
+
+// index returns the scan's tuples hashed on the given positions, building the
+// index on first use
+func (s *sharedScan) index(positions []int) *TupleKeyMap {
+	name := fmt.Sprint(positions)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index, ok := s.indexes[name]; ok {
+		return index
+	}
+
+	index := NewTupleKeyMapWithCapacity(len(s.tuples))
+	for _, t := range s.tuples {
+		k := NewTupleKey(t, positions)
+		var matches []Tuple
+		if existing, ok := index.Get(k); ok {
+			matches = existing.([]Tuple)
+		}
+		index.Put(k, append(matches, t))
+	}
+	s.indexes[name] = index
+	return index
+}
+
+// patternVariables returns the variables of pattern in order of first occurrence
+func patternVariables(pattern *query.DataPattern) []query.Symbol {
+	var vars []query.Symbol
+	seen := make(map[query.Symbol]bool)
+	for _, elem := range pattern.Elements {
+		if v, ok := elem.(query.Variable); ok && !seen[v.Name] {
+			seen[v.Name] = true
+			vars = append(vars, v.Name)
+		}
+	}
+	return vars
+}
+
+func columnIndex(columns []query.Symbol, sym query.Symbol) int {
+	for i, col := range columns {
+		if col == sym {
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchWithConstraints implements PredicateAwareMatcher. Constrained matches
+// are not shared; they go to the underlying matcher.
+func (m *sharedPatternMatcher) MatchWithConstraints(
+	pattern *query.DataPattern,
+	bindings Relations,
+	constraints []StorageConstraint,
+) (Relation, error) {
+	if pm, ok := m.underlying.(PredicateAwareMatcher); ok {
+		return pm.MatchWithConstraints(pattern, bindings, constraints)
+	}
+	return m.Match(pattern, bindings)
+}
+
+// MatchWithIndex implements IndexPinnedMatcher if the underlying matcher supports it.
+func (m *sharedPatternMatcher) MatchWithIndex(pattern *query.DataPattern, index planner.IndexType) (Relation, error) {
+	if ipm, ok := m.underlying.(IndexPinnedMatcher); ok {
+		return ipm.MatchWithIndex(pattern, index)
+	}
+	return m.Match(pattern, nil)
+}
+
+// MatchOrdered implements OrderedMatcher if the underlying matcher supports it.
+func (m *sharedPatternMatcher) MatchOrdered(pattern *query.DataPattern, descending bool) (Relation, error) {
+	if om, ok := m.underlying.(OrderedMatcher); ok {
+		return om.MatchOrdered(pattern, descending)
+	}
+	return nil, ErrOrderedScanUnsupported
+}
+
+// WithTimeRanges implements TimeRangeAware if the underlying matcher supports
+// it, so that time ranges still reach the patterns that aren't shared.
+func (m *sharedPatternMatcher) WithTimeRanges(ranges []TimeRange) TimeRangeAware {
+	if tra, ok := m.underlying.(TimeRangeAware); ok {
+		tra.WithTimeRanges(ranges)
+	}
+	return m
+}
+
+// SetHandler passes the annotation handler on to the underlying matcher
+func (m *sharedPatternMatcher) SetHandler(handler annotations.Handler) {
+	if sh, ok := m.underlying.(interface{ SetHandler(annotations.Handler) }); ok {
+		sh.SetHandler(handler)
+	}
+}
@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// countingMatcher counts the matches that reach the underlying matcher
+type countingMatcher struct {
+	PatternMatcher
+	matches int64
+}
+
+func (m *countingMatcher) Match(pattern *query.DataPattern, bindings Relations) (Relation, error) {
+	atomic.AddInt64(&m.matches, 1)
+	return m.PatternMatcher.Match(pattern, bindings)
+}
+
+func TestSharedPatterns(t *testing.T) {
+	datoms := latestPerEntityDatoms()
+
+	queries := []string{
+		// Both subquery patterns appear in the outer query
+		`[:find ?ticker ?max
+		  :where [?s :symbol/ticker ?ticker]
+		         [?b :price/symbol ?s]
+		         [?b :price/time ?time]
+		         [(q [:find (max ?t) :in $ ?sym
+		              :where [?bar :price/symbol ?sym] [?bar :price/time ?t]] $ ?s) [[?max]]]]`,
+		// Bar count per symbol next to each bar's close
+		`[:find ?s ?c ?n
+		  :where [?b :price/symbol ?s]
+		         [?b :price/close ?c]
+		         [(q [:find (count ?bar) :in $ ?sym
+		              :where [?bar :price/symbol ?sym]] $ ?s) [[?n]]]]`,
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		for i, queryStr := range queries {
+			t.Run(fmt.Sprintf("QueryExecutor=%v/%d", useQueryExecutor, i), func(t *testing.T) {
+				q, err := parser.ParseQuery(queryStr)
+				if err != nil {
+					t.Fatalf("failed to parse query: %v", err)
+				}
+
+				unshared := &countingMatcher{PatternMatcher: NewMemoryPatternMatcher(datoms)}
+				expected, err := NewExecutorWithOptions(unshared, planner.PlannerOptions{
+					UseQueryExecutor: useQueryExecutor,
+				}).Execute(q)
+				if err != nil {
+					t.Fatalf("query failed: %v", err)
+				}
+
+				var detected bool
+				handler := func(event annotations.Event) {
+					if event.Name == "shared_patterns/detected" {
+						detected = true
+					}
+				}
+				shared := &countingMatcher{PatternMatcher: NewMemoryPatternMatcher(datoms)}
+				result, err := NewExecutorWithOptions(shared, planner.PlannerOptions{
+					UseQueryExecutor:     useQueryExecutor,
+					EnableSharedPatterns: true,
+				}).ExecuteWithContext(NewContext(handler), q)
+				if err != nil {
+					t.Fatalf("shared pattern query failed: %v", err)
+				}
+
+				if !detected {
+					t.Error("Expected shared patterns to be detected")
+				}
+				if shared.matches >= unshared.matches {
+					t.Errorf("Expected fewer storage matches with shared patterns, got %d (unshared %d)",
+						shared.matches, unshared.matches)
+				}
+
+				want, got := expected.Sorted(), result.Sorted()
+				if len(want) == 0 {
+					t.Fatal("Expected results")
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("Results differ:\nwant %v\ngot  %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+// timeRangeMatcher records the time ranges it is given
+type timeRangeMatcher struct {
+	PatternMatcher
+	ranges []TimeRange
+}
+
+func (m *timeRangeMatcher) WithTimeRanges(ranges []TimeRange) TimeRangeAware {
+	m.ranges = ranges
+	return m
+}
+
+func TestSharedPatternsTimeRanges(t *testing.T) {
+	underlying := &timeRangeMatcher{PatternMatcher: NewMemoryPatternMatcher(latestPerEntityDatoms())}
+	exec := NewExecutor(newSharedPatternMatcher(underlying, nil))
+
+	start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	ranges := []TimeRange{{Start: start, End: start.Add(time.Hour)}}
+	ctx := NewContext(nil)
+	ctx.SetMetadata("time_ranges", ranges)
+
+	q, err := parser.ParseQuery(`[:find ?b :where [?b :price/time ?t]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	if _, err := exec.matchPatternWithRelations(ctx, q.Where[0].(*query.DataPattern), nil); err != nil {
+		t.Fatalf("match failed: %v", err)
+	}
+	if len(underlying.ranges) != 1 || !underlying.ranges[0].Start.Equal(start) {
+		t.Errorf("Expected the time ranges to reach the underlying matcher, got %v", underlying.ranges)
+	}
+}
@@ -0,0 +1,760 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// spoolIndexStride is the number of tuples between recorded file offsets.
+// Get() and Page() seek to the nearest recorded offset and decode forward.
+const spoolIndexStride = 1024
+
+// SpooledRelation is a relation whose tuples live in a temporary file instead
+// of memory. It is produced by SpoolRelation for final results that exceed
+// ExecutorOptions.SpoolThreshold, so very large extractions can be returned
+// without holding every tuple in memory.
+//
+// A SpooledRelation is immutable and can be iterated any number of times;
+// each Iterator() reads the file independently. Operations other than
+// iteration and paging stream the file through a StreamingRelation.
+//
+// Call Close() to remove the spool file once the result is no longer needed.
+// Unclosed spool files are removed when the relation is garbage collected.
+type SpooledRelation struct {
+	columns []query.Symbol
+	path    string
+	size    int
+	offsets []int64 // File offset of every spoolIndexStride-th tuple
+	options ExecutorOptions
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// SpoolRelation returns rel unchanged if it holds at most threshold tuples.
+// Otherwise it writes all tuples to a temporary file in dir (os.TempDir() if
+// empty) and returns a SpooledRelation reading from that file. Memory use is
+// bounded by threshold tuples regardless of the size of rel.
+func SpoolRelation(rel Relation, threshold int, dir string) (Relation, error) {
+	if rel == nil || threshold <= 0 {
+		return rel, nil
+	}
+	if m, ok := rel.(*MaterializedRelation); ok && m.Size() <= threshold {
+		return rel, nil
+	}
+	if _, ok := rel.(*SpooledRelation); ok {
+		return rel, nil
+	}
+
+	it := rel.Iterator()
+	defer it.Close()
+
+	// Buffer up to threshold tuples - small results stay in memory
+	var buffered []Tuple
+	for len(buffered) <= threshold && it.Next() {
+		buffered = append(buffered, it.Tuple())
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read relation to spool: %w", err)
+	}
+	if len(buffered) <= threshold {
+		return NewMaterializedRelationNoDedupeWithOptions(rel.Symbols(), buffered, rel.Options()), nil
+	}
+
+	f, err := os.CreateTemp(dir, "janus-spool-*.bin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	spooled := &SpooledRelation{
+		columns: rel.Symbols(),
+		path:    f.Name(),
+		options: rel.Options(),
+	}
+
+	w := &spoolWriter{w: bufio.NewWriterSize(f, 64*1024)}
+	write := func(tuple Tuple) error {
+		if spooled.size%spoolIndexStride == 0 {
+			spooled.offsets = append(spooled.offsets, w.n)
+		}
+		spooled.size++
+		return w.writeTuple(tuple)
+	}
+
+	for _, tuple := range buffered {
+		if err = write(tuple); err != nil {
+			break
+		}
+	}
+	buffered = nil
+	for err == nil && it.Next() {
+		err = write(it.Tuple())
+	}
+	if err == nil {
+		err = it.Err()
+	}
+	if err == nil {
+		err = w.w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(spooled.path)
+		return nil, fmt.Errorf("failed to spool relation: %w", err)
+	}
+
+	runtime.SetFinalizer(spooled, func(r *SpooledRelation) { r.Close() })
+	return spooled, nil
+}
+
+// Close removes the spool file. Iterators opened before Close keep working
+// until they are closed; new iterators are empty.
+func (r *SpooledRelation) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Path returns the location of the spool file
+func (r *SpooledRelation) Path() string {
+	return r.path
+}
+
+func (r *SpooledRelation) Symbols() []query.Symbol {
+	return r.columns
+}
+
+// Schema returns the relation's symbols with unknown types
+func (r *SpooledRelation) Schema() RelationSchema {
+	return NewRelationSchema(r.columns)
+}
+
+// Columns returns the relation's symbols.
+// Deprecated: Use Symbols or Schema.
+func (r *SpooledRelation) Columns() []query.Symbol {
+	return r.Symbols()
+}
+
+// ForEach calls fn for each spooled tuple (see Relation.ForEach)
+func (r *SpooledRelation) ForEach(fn func(Tuple) (bool, error)) error {
+	return forEachTuple(r, fn)
+}
+
+// Iterator returns an iterator reading tuples from the spool file
+func (r *SpooledRelation) Iterator() Iterator {
+	return r.iteratorAt(0)
+}
+
+// iteratorAt returns an iterator positioned at tuple index start
+func (r *SpooledRelation) iteratorAt(start int) *spoolIterator {
+	if start >= r.size {
+		return &spoolIterator{}
+	}
+
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+	if closed {
+		return &spoolIterator{err: fmt.Errorf("spooled relation is closed")}
+	}
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return &spoolIterator{err: fmt.Errorf("failed to open spool file: %w", err)}
+	}
+
+	block := start / spoolIndexStride
+	if _, err := f.Seek(r.offsets[block], io.SeekStart); err != nil {
+		f.Close()
+		return &spoolIterator{err: fmt.Errorf("failed to seek spool file: %w", err)}
+	}
+
+	it := &spoolIterator{
+		file:      f,
+		reader:    &spoolReader{r: bufio.NewReaderSize(f, 64*1024)},
+		arity:     len(r.columns),
+		remaining: r.size - block*spoolIndexStride,
+	}
+	for skip := start - block*spoolIndexStride; skip > 0; skip-- {
+		if !it.Next() {
+			break
+		}
+	}
+	return it
+}
+
+func (r *SpooledRelation) Size() int {
+	return r.size
+}
+
+func (r *SpooledRelation) knownSize() (int, bool) {
+	return r.size, true
+}
+
+func (r *SpooledRelation) IsEmpty() bool {
+	return r.size == 0
+}
+
+func (r *SpooledRelation) Options() ExecutorOptions {
+	return r.options
+}
+
+// Get returns the tuple at index i, seeking to the nearest indexed block.
+// Prefer Iterator() or Page() for sequential access.
+func (r *SpooledRelation) Get(i int) Tuple {
+	if i < 0 || i >= r.size {
+		return nil
+	}
+	it := r.iteratorAt(i)
+	defer it.Close()
+	if !it.Next() {
+		return nil
+	}
+	return it.Tuple()
+}
+
+// At returns the tuple at index i, or ErrTupleIndexOutOfRange
+func (r *SpooledRelation) At(i int) (Tuple, error) {
+	if i < 0 || i >= r.size {
+		return nil, fmt.Errorf("%w: %d not in [0, %d)", ErrTupleIndexOutOfRange, i, r.size)
+	}
+	return r.Get(i), nil
+}
+
+// Page returns tuples [offset, offset+limit) as a materialized relation.
+// Use Page(...).Table() to render part of a large result.
+func (r *SpooledRelation) Page(offset, limit int) Relation {
+	if offset < 0 {
+		offset = 0
+	}
+	var tuples []Tuple
+	it := r.iteratorAt(offset)
+	defer it.Close()
+	for (limit <= 0 || len(tuples) < limit) && it.Next() {
+		tuples = append(tuples, it.Tuple())
+	}
+	return NewMaterializedRelationNoDedupeWithOptions(r.columns, tuples, r.options)
+}
+
+// String returns a compact string representation for annotations
+func (r *SpooledRelation) String() string {
+	var symbols []string
+	for _, col := range r.columns {
+		symbols = append(symbols, string(col))
+	}
+	return fmt.Sprintf("SpooledRelation([%s], %d Tuples)", strings.Join(symbols, " "), r.size)
+}
+
+// Table returns a formatted markdown table of all tuples.
+// For large results render a window instead: r.Page(offset, limit).Table()
+func (r *SpooledRelation) Table() string {
+	return NewTableFormatter().FormatRelation(r)
+}
+
+// Materialize returns self - a spooled relation is already reusable
+func (r *SpooledRelation) Materialize() Relation {
+	return r
+}
+
+// stream wraps a fresh spool iterator for operations that consume the relation
+func (r *SpooledRelation) stream() Relation {
+	return NewStreamingRelationWithOptions(r.columns, r.Iterator(), r.options)
+}
+
+func (r *SpooledRelation) ProjectFromPattern(pattern *query.DataPattern) Relation {
+	return r.stream().ProjectFromPattern(pattern)
+}
+
+func (r *SpooledRelation) Sorted() []Tuple {
+	return r.stream().Sorted()
+}
+
+func (r *SpooledRelation) Project(columns []query.Symbol) (Relation, error) {
+	return r.stream().Project(columns)
+}
+
+func (r *SpooledRelation) Sort(orderBy []query.OrderByClause) Relation {
+	return SortRelation(r, orderBy)
+}
+
+func (r *SpooledRelation) Filter(filter Filter) Relation {
+	return r.stream().Filter(filter)
+}
+
+func (r *SpooledRelation) FilterWithPredicate(pred query.Predicate) Relation {
+	return r.stream().FilterWithPredicate(pred)
+}
+
+func (r *SpooledRelation) EvaluateFunction(fn query.Function, outputColumn query.Symbol) Relation {
+	return r.stream().EvaluateFunction(fn, outputColumn)
+}
+
+func (r *SpooledRelation) Select(pred func(Tuple) bool) Relation {
+	return r.stream().Select(pred)
+}
+
+func (r *SpooledRelation) Join(other Relation) Relation {
+	return r.stream().Join(other)
+}
+
+func (r *SpooledRelation) HashJoin(other Relation, joinCols []query.Symbol) Relation {
+	return r.stream().HashJoin(other, joinCols)
+}
+
+func (r *SpooledRelation) SemiJoin(other Relation, joinCols []query.Symbol) Relation {
+	return r.stream().SemiJoin(other, joinCols)
+}
+
+func (r *SpooledRelation) AntiJoin(other Relation, joinCols []query.Symbol) Relation {
+	return r.stream().AntiJoin(other, joinCols)
+}
+
+func (r *SpooledRelation) Aggregate(findElements []query.FindElement) Relation {
+	return r.stream().Aggregate(findElements)
+}
+
+// spoolIterator decodes tuples sequentially from a spool file
+type spoolIterator struct {
+	file      *os.File
+	reader    *spoolReader
+	arity     int
+	remaining int
+	current   Tuple
+	err       error
+}
+
+func (it *spoolIterator) Next() bool {
+	if it.err != nil || it.remaining <= 0 || it.reader == nil {
+		return false
+	}
+	tuple := make(Tuple, it.arity)
+	for i := range tuple {
+		v, err := it.reader.readValue()
+		if err != nil {
+			it.err = fmt.Errorf("failed to read spool file: %w", err)
+			return false
+		}
+		tuple[i] = v
+	}
+	it.remaining--
+	it.current = tuple
+	return true
+}
+
+func (it *spoolIterator) Tuple() Tuple {
+	return it.current
+}
+
+// Err returns the error that stopped iteration, if any
+func (it *spoolIterator) Err() error {
+	return it.err
+}
+
+func (it *spoolIterator) Close() error {
+	if it.file == nil {
+		return nil
+	}
+	err := it.file.Close()
+	it.file = nil
+	return err
+}
+
+// Spool value encoding: a tag byte followed by the value payload.
+// Lengths and Go ints use varints; fixed-width numbers are big-endian.
+const (
+	spoolNil byte = iota
+	spoolString
+	spoolInt64
+	spoolInt
+	spoolUint64
+	spoolFloat64
+	spoolBool
+	spoolTime
+	spoolBytes
+	spoolIdentity
+	spoolIdentityPtr
+	spoolKeyword
+	spoolKeywordPtr
+	spoolList
+	spoolInt8
+	spoolInt16
+	spoolInt32
+	spoolUint
+	spoolUint8
+	spoolUint16
+	spoolUint32
+	spoolUint64Ptr
+	spoolFloat32
+	spoolHistogram
+)
+
+// spoolWriter encodes values and tracks the number of bytes written
+type spoolWriter struct {
+	w   *bufio.Writer
+	n   int64
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *spoolWriter) writeTuple(tuple Tuple) error {
+	for _, v := range tuple {
+		if err := w.writeValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *spoolWriter) writeByte(b byte) error {
+	w.n++
+	return w.w.WriteByte(b)
+}
+
+func (w *spoolWriter) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return err
+}
+
+func (w *spoolWriter) writeUvarint(x uint64) error {
+	return w.write(w.buf[:binary.PutUvarint(w.buf[:], x)])
+}
+
+func (w *spoolWriter) writeVarint(x int64) error {
+	return w.write(w.buf[:binary.PutVarint(w.buf[:], x)])
+}
+
+func (w *spoolWriter) writeTaggedVarint(tag byte, x int64) error {
+	if err := w.writeByte(tag); err != nil {
+		return err
+	}
+	return w.writeVarint(x)
+}
+
+func (w *spoolWriter) writeTaggedUvarint(tag byte, x uint64) error {
+	if err := w.writeByte(tag); err != nil {
+		return err
+	}
+	return w.writeUvarint(x)
+}
+
+func (w *spoolWriter) writeUint64(x uint64) error {
+	binary.BigEndian.PutUint64(w.buf[:8], x)
+	return w.write(w.buf[:8])
+}
+
+func (w *spoolWriter) writeBytes(p []byte) error {
+	if err := w.writeUvarint(uint64(len(p))); err != nil {
+		return err
+	}
+	return w.write(p)
+}
+
+func (w *spoolWriter) writeTagged(tag byte, p []byte) error {
+	if err := w.writeByte(tag); err != nil {
+		return err
+	}
+	return w.writeBytes(p)
+}
+
+func (w *spoolWriter) writeIdentity(tag byte, id datalog.Identity) error {
+	if err := w.writeByte(tag); err != nil {
+		return err
+	}
+	hash := id.Hash()
+	if err := w.write(hash[:]); err != nil {
+		return err
+	}
+	// Preserve the original string when known; otherwise String() is the L85 form
+	str := id.String()
+	if str == id.L85() {
+		str = ""
+	}
+	return w.writeBytes([]byte(str))
+}
+
+func (w *spoolWriter) writeValue(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		return w.writeByte(spoolNil)
+	case string:
+		return w.writeTagged(spoolString, []byte(val))
+	case int64:
+		if err := w.writeByte(spoolInt64); err != nil {
+			return err
+		}
+		return w.writeUint64(uint64(val))
+	case int:
+		return w.writeTaggedVarint(spoolInt, int64(val))
+	case int8:
+		return w.writeTaggedVarint(spoolInt8, int64(val))
+	case int16:
+		return w.writeTaggedVarint(spoolInt16, int64(val))
+	case int32:
+		return w.writeTaggedVarint(spoolInt32, int64(val))
+	case uint64:
+		if err := w.writeByte(spoolUint64); err != nil {
+			return err
+		}
+		return w.writeUint64(val)
+	case *uint64:
+		if err := w.writeByte(spoolUint64Ptr); err != nil {
+			return err
+		}
+		return w.writeUint64(*val)
+	case uint:
+		return w.writeTaggedUvarint(spoolUint, uint64(val))
+	case uint8:
+		return w.writeTaggedUvarint(spoolUint8, uint64(val))
+	case uint16:
+		return w.writeTaggedUvarint(spoolUint16, uint64(val))
+	case uint32:
+		return w.writeTaggedUvarint(spoolUint32, uint64(val))
+	case float64:
+		if err := w.writeByte(spoolFloat64); err != nil {
+			return err
+		}
+		return w.writeUint64(math.Float64bits(val))
+	case float32:
+		return w.writeTaggedUvarint(spoolFloat32, uint64(math.Float32bits(val)))
+	case bool:
+		if err := w.writeByte(spoolBool); err != nil {
+			return err
+		}
+		if val {
+			return w.writeByte(1)
+		}
+		return w.writeByte(0)
+	case time.Time:
+		data, err := val.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return w.writeTagged(spoolTime, data)
+	case []byte:
+		return w.writeTagged(spoolBytes, val)
+	case datalog.Identity:
+		return w.writeIdentity(spoolIdentity, val)
+	case *datalog.Identity:
+		return w.writeIdentity(spoolIdentityPtr, *val)
+	case datalog.Keyword:
+		return w.writeTagged(spoolKeyword, []byte(val.String()))
+	case *datalog.Keyword:
+		return w.writeTagged(spoolKeywordPtr, []byte(val.String()))
+	case []interface{}:
+		if err := w.writeByte(spoolList); err != nil {
+			return err
+		}
+		if err := w.writeUvarint(uint64(len(val))); err != nil {
+			return err
+		}
+		for _, elem := range val {
+			if err := w.writeValue(elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Histogram:
+		if err := w.writeByte(spoolHistogram); err != nil {
+			return err
+		}
+		if err := w.writeUint64(math.Float64bits(val.BucketWidth)); err != nil {
+			return err
+		}
+		if err := w.writeUvarint(uint64(len(val.Buckets))); err != nil {
+			return err
+		}
+		for _, bucket := range val.Buckets {
+			if err := w.writeUint64(math.Float64bits(bucket.Start)); err != nil {
+				return err
+			}
+			if err := w.writeVarint(bucket.Count); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("cannot spool value of type %T", v)
+	}
+}
+
+// spoolReader decodes values written by spoolWriter
+type spoolReader struct {
+	r   *bufio.Reader
+	buf [8]byte
+}
+
+func (r *spoolReader) readUint64() (uint64, error) {
+	if _, err := io.ReadFull(r.r, r.buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(r.buf[:]), nil
+}
+
+func (r *spoolReader) readFloat64() (float64, error) {
+	x, err := r.readUint64()
+	return math.Float64frombits(x), err
+}
+
+func (r *spoolReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(r.r, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *spoolReader) readIdentity() (datalog.Identity, error) {
+	var hash [20]byte
+	if _, err := io.ReadFull(r.r, hash[:]); err != nil {
+		return datalog.Identity{}, err
+	}
+	str, err := r.readBytes()
+	if err != nil {
+		return datalog.Identity{}, err
+	}
+	if len(str) > 0 {
+		return datalog.NewIdentity(string(str)), nil
+	}
+	return datalog.NewIdentityFromHash(hash), nil
+}
+
+func (r *spoolReader) readValue() (interface{}, error) {
+	tag, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case spoolNil:
+		return nil, nil
+	case spoolString:
+		p, err := r.readBytes()
+		return string(p), err
+	case spoolInt64:
+		x, err := r.readUint64()
+		return int64(x), err
+	case spoolInt:
+		x, err := binary.ReadVarint(r.r)
+		return int(x), err
+	case spoolInt8:
+		x, err := binary.ReadVarint(r.r)
+		return int8(x), err
+	case spoolInt16:
+		x, err := binary.ReadVarint(r.r)
+		return int16(x), err
+	case spoolInt32:
+		x, err := binary.ReadVarint(r.r)
+		return int32(x), err
+	case spoolUint64:
+		return r.readUint64()
+	case spoolUint64Ptr:
+		x, err := r.readUint64()
+		if err != nil {
+			return nil, err
+		}
+		return &x, nil
+	case spoolUint:
+		x, err := binary.ReadUvarint(r.r)
+		return uint(x), err
+	case spoolUint8:
+		x, err := binary.ReadUvarint(r.r)
+		return uint8(x), err
+	case spoolUint16:
+		x, err := binary.ReadUvarint(r.r)
+		return uint16(x), err
+	case spoolUint32:
+		x, err := binary.ReadUvarint(r.r)
+		return uint32(x), err
+	case spoolFloat64:
+		return r.readFloat64()
+	case spoolFloat32:
+		x, err := binary.ReadUvarint(r.r)
+		return math.Float32frombits(uint32(x)), err
+	case spoolBool:
+		b, err := r.r.ReadByte()
+		return b != 0, err
+	case spoolTime:
+		p, err := r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		var t time.Time
+		err = t.UnmarshalBinary(p)
+		return t, err
+	case spoolBytes:
+		return r.readBytes()
+	case spoolIdentity:
+		return r.readIdentity()
+	case spoolIdentityPtr:
+		id, err := r.readIdentity()
+		if err != nil {
+			return nil, err
+		}
+		return datalog.InternIdentity(id), nil
+	case spoolKeyword:
+		p, err := r.readBytes()
+		return datalog.NewKeyword(string(p)), err
+	case spoolKeywordPtr:
+		p, err := r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return datalog.InternKeyword(string(p)), nil
+	case spoolList:
+		n, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.readValue(); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case spoolHistogram:
+		var h Histogram
+		if h.BucketWidth, err = r.readFloat64(); err != nil {
+			return nil, err
+		}
+		n, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, err
+		}
+		h.Buckets = make([]HistogramBucket, n)
+		for i := range h.Buckets {
+			if h.Buckets[i].Start, err = r.readFloat64(); err != nil {
+				return nil, err
+			}
+			if h.Buckets[i].Count, err = binary.ReadVarint(r.r); err != nil {
+				return nil, err
+			}
+		}
+		return h, nil
+	default:
+		return nil, fmt.Errorf("unknown spool value tag %d", tag)
+	}
+}
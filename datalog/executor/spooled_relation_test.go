@@ -0,0 +1,259 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestSpoolRelationBelowThreshold(t *testing.T) {
+	rel := NewMaterializedRelation([]query.Symbol{"?x"}, []Tuple{{int64(1)}, {int64(2)}})
+
+	spooled, err := SpoolRelation(rel, 2, t.TempDir())
+	if err != nil {
+		t.Fatalf("SpoolRelation failed: %v", err)
+	}
+	if spooled != rel {
+		t.Errorf("Expected relation at threshold to be returned unchanged, got %T", spooled)
+	}
+
+	stream := NewStreamingRelation([]query.Symbol{"?x"}, &sliceIterator{tuples: []Tuple{{int64(1)}}, pos: -1})
+	spooled, err = SpoolRelation(stream, 2, t.TempDir())
+	if err != nil {
+		t.Fatalf("SpoolRelation failed: %v", err)
+	}
+	if _, ok := spooled.(*MaterializedRelation); !ok || spooled.Size() != 1 {
+		t.Errorf("Expected small streaming result to stay in memory, got %T with %d tuples", spooled, spooled.Size())
+	}
+}
+
+func TestSpoolRelationRoundTrip(t *testing.T) {
+	when := time.Date(2025, 3, 14, 15, 9, 26, 535, time.FixedZone("EST", -5*3600))
+	named := datalog.NewIdentity("user:alice")
+	stored := datalog.NewIdentityFromHash(datalog.NewIdentity("user:bob").Hash())
+	kw := datalog.NewKeyword(":user/name")
+	big := uint64(1 << 62)
+
+	values := Tuple{
+		nil, "text", int64(-42), 7, uint64(1 << 60), 3.25, true, when,
+		[]byte{1, 2, 3}, named, stored, &named, kw, &kw,
+		[]interface{}{int64(1), "two", []interface{}{3.0}},
+		int8(-8), int16(-16), int32(-32), uint(1), uint8(8), uint16(16), uint32(32), &big,
+		float32(1.5), Histogram{BucketWidth: 10, Buckets: []HistogramBucket{{Start: 0, Count: 3}, {Start: 20, Count: 1}}},
+	}
+
+	columns := make([]query.Symbol, len(values))
+	for i := range columns {
+		columns[i] = query.Symbol(fmt.Sprintf("?c%d", i))
+	}
+
+	const n = 3000 // spans several index blocks
+	tuples := make([]Tuple, n)
+	for i := range tuples {
+		tuple := make(Tuple, len(values))
+		copy(tuple, values)
+		tuple[2] = int64(i)
+		tuples[i] = tuple
+	}
+
+	dir := t.TempDir()
+	rel, err := SpoolRelation(NewMaterializedRelationNoDedupe(columns, tuples), 100, dir)
+	if err != nil {
+		t.Fatalf("SpoolRelation failed: %v", err)
+	}
+	spooled, ok := rel.(*SpooledRelation)
+	if !ok {
+		t.Fatalf("Expected *SpooledRelation, got %T", rel)
+	}
+	if spooled.Size() != n {
+		t.Fatalf("Expected %d tuples, got %d", n, spooled.Size())
+	}
+
+	// Full iteration, twice
+	for pass := 0; pass < 2; pass++ {
+		count := 0
+		it := spooled.Iterator()
+		for it.Next() {
+			if got := it.Tuple()[2]; got != int64(count) {
+				t.Fatalf("Pass %d: tuple %d has ?c2=%v", pass, count, got)
+			}
+			count++
+		}
+		it.Close()
+		if count != n {
+			t.Fatalf("Pass %d: iterated %d tuples, expected %d", pass, count, n)
+		}
+	}
+
+	got := spooled.Get(2049)
+	if got[2] != int64(2049) {
+		t.Errorf("Get(2049) returned ?c2=%v", got[2])
+	}
+	if got[0] != nil || got[1] != "text" || got[3] != 7 || got[4] != uint64(1<<60) || got[5] != 3.25 || got[6] != true {
+		t.Errorf("Scalar values did not round trip: %v", got[:7])
+	}
+	if tm, ok := got[7].(time.Time); !ok || !tm.Equal(when) || tm.Format(time.RFC3339) != when.Format(time.RFC3339) {
+		t.Errorf("Time did not round trip: %v", got[7])
+	}
+	if b, ok := got[8].([]byte); !ok || len(b) != 3 || b[2] != 3 {
+		t.Errorf("Bytes did not round trip: %v", got[8])
+	}
+	if id, ok := got[9].(datalog.Identity); !ok || id.String() != "user:alice" {
+		t.Errorf("Named identity did not round trip: %v", got[9])
+	}
+	if id, ok := got[10].(datalog.Identity); !ok || !id.Equal(stored) || id.String() != stored.String() {
+		t.Errorf("Hash-only identity did not round trip: %v", got[10])
+	}
+	if id, ok := got[11].(*datalog.Identity); !ok || !id.Equal(named) {
+		t.Errorf("Identity pointer did not round trip: %v", got[11])
+	}
+	if k, ok := got[12].(datalog.Keyword); !ok || k != kw {
+		t.Errorf("Keyword did not round trip: %v", got[12])
+	}
+	if k, ok := got[13].(*datalog.Keyword); !ok || *k != kw {
+		t.Errorf("Keyword pointer did not round trip: %v", got[13])
+	}
+	if list, ok := got[14].([]interface{}); !ok || len(list) != 3 || list[1] != "two" {
+		t.Errorf("List did not round trip: %v", got[14])
+	}
+	if got[15] != int8(-8) || got[16] != int16(-16) || got[17] != int32(-32) || got[18] != uint(1) ||
+		got[19] != uint8(8) || got[20] != uint16(16) || got[21] != uint32(32) || got[23] != float32(1.5) {
+		t.Errorf("Sized numbers did not round trip: %v", got[15:24])
+	}
+	if p, ok := got[22].(*uint64); !ok || *p != big {
+		t.Errorf("Uint64 pointer did not round trip: %v", got[22])
+	}
+	if h, ok := got[24].(Histogram); !ok || h.String() != values[24].(Histogram).String() {
+		t.Errorf("Histogram did not round trip: %v", got[24])
+	}
+
+	page := spooled.Page(1020, 10)
+	if page.Size() != 10 || page.Get(0)[2] != int64(1020) || page.Get(9)[2] != int64(1029) {
+		t.Errorf("Page(1020, 10) returned wrong window: %d tuples", page.Size())
+	}
+	if tail := spooled.Page(n-5, 100); tail.Size() != 5 {
+		t.Errorf("Expected 5 tuples at end of spool, got %d", tail.Size())
+	}
+
+	if err := spooled.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(spooled.Path()); !os.IsNotExist(err) {
+		t.Errorf("Expected spool file to be removed, stat returned %v", err)
+	}
+}
+
+func TestSpoolRelationUnsupportedValue(t *testing.T) {
+	rel := NewMaterializedRelation([]query.Symbol{"?x"}, []Tuple{{struct{}{}}, {int64(1)}})
+	if _, err := SpoolRelation(rel, 1, t.TempDir()); err == nil {
+		t.Error("Expected error spooling an unsupported value type")
+	}
+}
+
+func TestExecutorSpoolsLargeResults(t *testing.T) {
+	nameAttr := datalog.NewKeyword(":user/name")
+	var datoms []datalog.Datom
+	for i := 0; i < 500; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("user:%d", i))
+		datoms = append(datoms, datalog.Datom{E: e, A: nameAttr, V: fmt.Sprintf("user-%03d", i), Tx: 1})
+	}
+
+	q, err := parser.ParseQuery(`[:find ?e ?name :where [?e :user/name ?name] :order-by [?name]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		t.Run(fmt.Sprintf("QueryExecutor=%v", useQueryExecutor), func(t *testing.T) {
+			exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+				UseQueryExecutor: useQueryExecutor,
+				SpoolThreshold:   100,
+				SpoolDir:         t.TempDir(),
+			})
+
+			result, err := exec.Execute(q)
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			spooled, ok := result.(*SpooledRelation)
+			if !ok {
+				t.Fatalf("Expected spooled result, got %T", result)
+			}
+			defer spooled.Close()
+
+			if spooled.Size() != 500 {
+				t.Fatalf("Expected 500 tuples, got %d", spooled.Size())
+			}
+			if first := spooled.Get(0); first[1] != "user-000" {
+				t.Errorf("Expected ordered results, first tuple is %v", first)
+			}
+			if last := spooled.Get(499); last[1] != "user-499" {
+				t.Errorf("Expected ordered results, last tuple is %v", last)
+			}
+		})
+	}
+}
+
+func TestExecutorSpoolsUnorderedAndAggregateResults(t *testing.T) {
+	latencyAttr := datalog.NewKeyword(":request/latency")
+	serviceAttr := datalog.NewKeyword(":request/service")
+	var datoms []datalog.Datom
+	for i := 0; i < 500; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("request:%d", i))
+		datoms = append(datoms,
+			datalog.Datom{E: e, A: serviceAttr, V: fmt.Sprintf("svc-%03d", i/2), Tx: 1},
+			datalog.Datom{E: e, A: latencyAttr, V: int64(i), Tx: 1})
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		size  int
+	}{
+		{"Unordered", `[:find ?e ?latency :where [?e :request/latency ?latency]]`, 500},
+		{"Histogram", `[:find ?svc (histogram ?latency 100) :where [?e :request/service ?svc] [?e :request/latency ?latency]]`, 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+			exec := NewExecutorWithOptions(NewMemoryPatternMatcher(datoms), planner.PlannerOptions{
+				SpoolThreshold: 100,
+				SpoolDir:       t.TempDir(),
+			})
+
+			result, err := exec.Execute(q)
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			spooled, ok := result.(*SpooledRelation)
+			if !ok {
+				t.Fatalf("Expected spooled result, got %T", result)
+			}
+			defer spooled.Close()
+
+			if spooled.Size() != tt.size {
+				t.Fatalf("Expected %d tuples, got %d", tt.size, spooled.Size())
+			}
+			it := spooled.Iterator()
+			defer it.Close()
+			for it.Next() {
+				if _, ok := it.Tuple()[1].(Histogram); tt.name == "Histogram" && !ok {
+					t.Fatalf("Expected histogram values, got %T", it.Tuple()[1])
+				}
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("iterating spooled result failed: %v", err)
+			}
+		})
+	}
+}
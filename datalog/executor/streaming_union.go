@@ -38,7 +38,7 @@ func (s *StreamingUnionBuilder) Union(relations []Relation) Relation {
 // unionStreaming creates a streaming union via channel
 // Results are consumed lazily as they're iterated
 func (s *StreamingUnionBuilder) unionStreaming(relations []Relation) Relation {
-	columns := relations[0].Columns()
+	columns := relations[0].Symbols()
 
 	// Create channel for streaming
 	unionChan := make(chan relationItem, 1)
@@ -56,7 +56,7 @@ func (s *StreamingUnionBuilder) unionStreaming(relations []Relation) Relation {
 // unionMaterialized combines all relations by materializing
 // All results are collected before returning
 func (s *StreamingUnionBuilder) unionMaterialized(relations []Relation) Relation {
-	columns := relations[0].Columns()
+	columns := relations[0].Symbols()
 	var allTuples []Tuple
 
 	for _, rel := range relations {
@@ -85,7 +85,7 @@ func (s *StreamingUnionBuilder) UnionWithColumns(relations []Relation, columns [
 	if len(relations) == 1 {
 		// Project to ensure correct column order
 		rel := relations[0]
-		if !symbolsEqual(rel.Columns(), columns) {
+		if !symbolsEqual(rel.Symbols(), columns) {
 			projected, err := rel.Project(columns)
 			if err != nil {
 				return nil, err
@@ -98,7 +98,7 @@ func (s *StreamingUnionBuilder) UnionWithColumns(relations []Relation, columns [
 	// Check if all relations have same column schema
 	allMatch := true
 	for _, rel := range relations {
-		if !symbolsEqual(rel.Columns(), columns) {
+		if !symbolsEqual(rel.Symbols(), columns) {
 			allMatch = false
 			break
 		}
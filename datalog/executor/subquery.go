@@ -32,29 +32,22 @@ func (e *Executor) executeSubquery(ctx Context, subqPlan planner.SubqueryPlan, i
 // ExecuteSubquery executes a subquery using the parent executor to inherit optimizations.
 // This ensures subqueries benefit from parallel execution, predicate pushdown, plan cache, etc.
 func ExecuteSubquery(ctx Context, parentExec *Executor, subqPlan planner.SubqueryPlan, inputRelation Relation) (Relation, error) {
-	// CRITICAL: Extract input combinations ONCE before trying batched vs sequential paths
-	// Both paths need the combinations, and calling Iterator() twice on a StreamingRelation will panic
+	// CRITICAL: Extract input combinations ONCE, before choosing a strategy
+	// Calling Iterator() twice on a StreamingRelation will panic
 	inputCombinations := getUniqueInputCombinations(inputRelation, subqPlan.Inputs)
 
-	// Check if we can batch execute with RelationInput
-	if canBatchSubquery(subqPlan.Subquery.Query) {
-		// Try batched execution with pre-extracted combinations
-		batchedResult, err := executeBatchedSubqueryWithCombinations(ctx, parentExec, subqPlan, inputCombinations)
-		if err == nil {
-			return batchedResult, nil
-		}
-		// Fall back to sequential if batching fails
-	}
-
-	// Check if we should use parallel execution
-	useParallel := parentExec.enableParallelSubqueries && len(inputCombinations) >= ParallelSubqueryThreshold
+	choice := NewSubqueryStrategySelector(ParallelSubqueryThreshold).Choose(
+		subqPlan.Subquery, len(inputCombinations), ExecutorOptions{EnableParallelSubqueries: parentExec.enableParallelSubqueries})
+	choice.annotate(ctx, subqPlan.Subquery)
 
-	if useParallel {
+	switch choice.Strategy {
+	case StrategyBatched:
+		return executeBatchedSubqueryWithCombinations(ctx, parentExec, subqPlan, inputCombinations)
+	case StrategyParallel:
 		return executeSubqueryParallel(ctx, parentExec, subqPlan, inputCombinations)
+	default:
+		return executeSubquerySequential(ctx, parentExec, subqPlan, inputCombinations)
 	}
-
-	// Sequential execution (original code)
-	return executeSubquerySequential(ctx, parentExec, subqPlan, inputCombinations)
 }
 
 // executeSubquerySequential executes subquery iterations sequentially
@@ -81,7 +74,7 @@ func executeSubquerySequentialStreaming(ctx Context, parentExec *Executor, subqP
 			inputRelations := createInputRelationsFromPattern(subqPlan.Subquery, inputValues)
 
 			// Execute the nested query with input relations
-			result, err := executePhasesWithInputs(ctx, parentExec, subqPlan.NestedPlan, inputRelations)
+			result, err := executeNestedPlan(ctx, parentExec, subqPlan.NestedPlan, inputRelations)
 			if err != nil {
 				unionChan <- relationItem{err: fmt.Errorf("nested query execution failed: %w", err)}
 				continue
@@ -138,7 +131,7 @@ func executeSubquerySequentialMaterialized(ctx Context, parentExec *Executor, su
 
 		// Execute the nested query with input relations using the parent executor
 		// This ensures all optimizations are inherited
-		result, err := executePhasesWithInputs(ctx, parentExec, subqPlan.NestedPlan, inputRelations)
+		result, err := executeNestedPlan(ctx, parentExec, subqPlan.NestedPlan, inputRelations)
 		if err != nil {
 			return nil, fmt.Errorf("nested query execution failed: %w", err)
 		}
@@ -210,7 +203,7 @@ func executeSubqueryParallelStreaming(ctx Context, parentExec *Executor, subqPla
 				inputRelations := createInputRelationsFromPattern(subqPlan.Subquery, work.inputValues)
 
 				// Execute the nested query with input relations
-				result, err := executePhasesWithInputs(workerCtx, parentExec, subqPlan.NestedPlan, inputRelations)
+				result, err := executeNestedPlan(workerCtx, parentExec, subqPlan.NestedPlan, inputRelations)
 				if err != nil {
 					unionChan <- relationItem{err: fmt.Errorf("nested query execution failed: %w", err)}
 					continue
@@ -323,7 +316,7 @@ func executeSubqueryParallelMaterialized(ctx Context, parentExec *Executor, subq
 				inputRelations := createInputRelationsFromPattern(subqPlan.Subquery, work.inputValues)
 
 				// Execute the nested query with input relations using worker's own context
-				result, err := executePhasesWithInputs(workerCtx, parentExec, subqPlan.NestedPlan, inputRelations)
+				result, err := executeNestedPlan(workerCtx, parentExec, subqPlan.NestedPlan, inputRelations)
 				if err != nil {
 					resultChan <- resultItem{index: work.index, err: fmt.Errorf("nested query execution failed: %w", err)}
 					cancel() // Cancel other workers
@@ -393,7 +386,7 @@ func combineSubqueryResults(allResults []Relation, subqPlan planner.SubqueryPlan
 
 	// Union all results by collecting all tuples
 	var allTuples []Tuple
-	columns := validResults[0].Columns()
+	columns := validResults[0].Symbols()
 
 	for _, rel := range validResults {
 		it := rel.Iterator()
@@ -408,6 +401,18 @@ func combineSubqueryResults(allResults []Relation, subqPlan planner.SubqueryPlan
 	return result, nil
 }
 
+// executeNestedPlan executes a subquery's nested plan for a single input combination.
+// A RelationInput is bound as a whole relation (one row for positional values,
+// every row for a relation-valued input) instead of being iterated per tuple.
+func executeNestedPlan(ctx Context, parentExec *Executor, plan *planner.QueryPlan, inputRelations []Relation) (Relation, error) {
+	if plan.Metadata != nil {
+		for key, value := range plan.Metadata {
+			ctx.SetMetadata(key, value)
+		}
+	}
+	return parentExec.executePhasesWithInputsNonIterating(ctx, plan, inputRelations)
+}
+
 // executePhasesWithInputs executes query phases with additional input relations.
 // This function needs the full parent executor to inherit its optimizations.
 func executePhasesWithInputs(ctx Context, parentExec *Executor, plan *planner.QueryPlan, inputRelations []Relation) (Relation, error) {
@@ -518,98 +523,87 @@ func createInputRelationsFromValues(q *query.Query, orderedValues []interface{})
 }
 
 // createInputRelationsFromValuesWithOptions creates relations from ordered input values with options.
+// A RelationInput takes either one relation-valued input (a Relation or a slice
+// of rows, see relationInputRows), bound as a multi-row relation, or one value
+// per symbol, bound as a single row. Returns nil if the values do not match
+// the :in clause.
 func createInputRelationsFromValuesWithOptions(q *query.Query, orderedValues []interface{}, opts ExecutorOptions) []Relation {
 	var relations []Relation
 
-	// Check if we have the correct number of inputs
-	expectedInputs := 0
-	for _, input := range q.In {
-		switch inp := input.(type) {
-		case query.DatabaseInput:
-			expectedInputs++ // Database REQUIRES explicit $
-		case query.ScalarInput:
-			expectedInputs++
-		case query.RelationInput:
-			expectedInputs += len(inp.Symbols)
-		case query.TupleInput:
-			expectedInputs += len(inp.Symbols)
-		case query.CollectionInput:
-			expectedInputs++
-		}
-	}
-
-	if len(orderedValues) != expectedInputs {
-		return nil // Wrong number of inputs - this is an error
-	}
-
 	// Process :in clause to create appropriate relations
 	valueIndex := 0
 	for _, input := range q.In {
+		// Every input, including $, consumes at least one value
+		if valueIndex >= len(orderedValues) {
+			return nil // Wrong number of inputs - this is an error
+		}
+
 		switch inp := input.(type) {
 		case query.DatabaseInput:
 			// Expect an explicit $ symbol at this position
-			if valueIndex < len(orderedValues) {
-				// Check if it's the database marker
-				if sym, ok := orderedValues[valueIndex].(query.Symbol); ok && sym == "$" {
-					// Database marker present - skip it
-					valueIndex++
-				} else {
-					// Not a database marker - this is an error
-					return nil
-				}
+			if sym, ok := orderedValues[valueIndex].(query.Symbol); !ok || sym != "$" {
+				// Not a database marker - this is an error
+				return nil
 			}
+			valueIndex++
 
 		case query.ScalarInput:
 			// Create a single-value relation
-			if valueIndex < len(orderedValues) {
-				rel := NewMaterializedRelationWithOptions(
-					[]query.Symbol{inp.Symbol},
-					[]Tuple{{orderedValues[valueIndex]}},
-					opts,
-				)
-				relations = append(relations, rel)
+			rel := NewMaterializedRelationWithOptions(
+				[]query.Symbol{inp.Symbol},
+				[]Tuple{{orderedValues[valueIndex]}},
+				opts,
+			)
+			relations = append(relations, rel)
+			valueIndex++
+
+		case query.RelationInput:
+			// A single relation-valued input binds every row
+			rows, ok, err := relationInputRows(orderedValues[valueIndex], len(inp.Symbols))
+			if err != nil {
+				return nil
+			}
+			if ok {
+				relations = append(relations, NewMaterializedRelationWithOptions(inp.Symbols, rows, opts))
 				valueIndex++
+				continue
 			}
 
-		case query.RelationInput:
-			// For now, treat as scalar inputs (will be enhanced later)
-			// This handles the common case where subqueries use scalar inputs
-			if valueIndex+len(inp.Symbols) <= len(orderedValues) {
-				tuple := make(Tuple, len(inp.Symbols))
-				for i := range inp.Symbols {
-					tuple[i] = orderedValues[valueIndex+i]
-				}
-				rel := NewMaterializedRelationWithOptions(inp.Symbols, []Tuple{tuple}, opts)
-				relations = append(relations, rel)
-				valueIndex += len(inp.Symbols)
+			// Otherwise one value per symbol, bound as a single row
+			if valueIndex+len(inp.Symbols) > len(orderedValues) {
+				return nil
 			}
+			tuple := make(Tuple, len(inp.Symbols))
+			copy(tuple, orderedValues[valueIndex:])
+			relations = append(relations, NewMaterializedRelationWithOptions(inp.Symbols, []Tuple{tuple}, opts))
+			valueIndex += len(inp.Symbols)
 
 		case query.TupleInput:
 			// Create a single-tuple relation
-			if valueIndex+len(inp.Symbols) <= len(orderedValues) {
-				tuple := make(Tuple, len(inp.Symbols))
-				for i := range inp.Symbols {
-					tuple[i] = orderedValues[valueIndex+i]
-				}
-				rel := NewMaterializedRelationWithOptions(inp.Symbols, []Tuple{tuple}, opts)
-				relations = append(relations, rel)
-				valueIndex += len(inp.Symbols)
+			if valueIndex+len(inp.Symbols) > len(orderedValues) {
+				return nil
 			}
+			tuple := make(Tuple, len(inp.Symbols))
+			copy(tuple, orderedValues[valueIndex:])
+			relations = append(relations, NewMaterializedRelationWithOptions(inp.Symbols, []Tuple{tuple}, opts))
+			valueIndex += len(inp.Symbols)
 
 		case query.CollectionInput:
-			// Create a single-column relation
-			if valueIndex < len(orderedValues) {
-				rel := NewMaterializedRelationWithOptions(
-					[]query.Symbol{inp.Symbol},
-					[]Tuple{{orderedValues[valueIndex]}},
-					opts,
-				)
-				relations = append(relations, rel)
-				valueIndex++
-			}
+			// Create a single-column relation with one row per element
+			rel := NewMaterializedRelationWithOptions(
+				[]query.Symbol{inp.Symbol},
+				expandCollection(orderedValues[valueIndex]),
+				opts,
+			)
+			relations = append(relations, rel)
+			valueIndex++
 		}
 	}
 
+	if valueIndex != len(orderedValues) {
+		return nil // Wrong number of inputs - this is an error
+	}
+
 	return relations
 }
 
@@ -655,7 +649,7 @@ func createSubqueryContext(parentCtx Context, inputs []query.InputSpec, inputVal
 // This is a pure function that performs relation transformation.
 func augmentWithInputValues(rel Relation, inputSymbols []query.Symbol, inputValues []interface{}) Relation {
 	// Create new columns list
-	newColumns := append(rel.Columns(), inputSymbols...)
+	newColumns := append(rel.Symbols(), inputSymbols...)
 
 	// Create augmented tuples
 	var augmentedTuples []Tuple
@@ -690,17 +684,31 @@ func applyBindingForm(result Relation, binding query.BindingForm, inputValues ma
 			}
 		}
 
+		// Read the single result by iteration; Size() and Get() would
+		// materialize a streaming result
+		it := result.Iterator()
+		defer it.Close()
+
 		// EMPTY RESULT = PATTERN FAILS TO MATCH
 		// Return empty relation instead of error (datalog semantics)
-		if result.Size() == 0 {
+		if !it.Next() {
 			columns := make([]query.Symbol, len(realInputSymbols)+len(b.Variables))
 			copy(columns, realInputSymbols)
 			copy(columns[len(realInputSymbols):], b.Variables)
 			return NewMaterializedRelation(columns, []Tuple{}), nil
 		}
 
-		if result.Size() != 1 {
-			return nil, fmt.Errorf("tuple binding expects exactly 1 result, got %d", result.Size())
+		// Copy: iterators may reuse the tuple buffer
+		first := it.Tuple()
+		resultTuple := make(Tuple, len(first))
+		copy(resultTuple, first)
+
+		if it.Next() {
+			count := 2
+			for it.Next() {
+				count++
+			}
+			return nil, fmt.Errorf("tuple binding expects exactly 1 result, got %d", count)
 		}
 
 		// fmt.Printf("DEBUG: TupleBinding variables: %v\n", b.Variables)
@@ -716,8 +724,6 @@ func applyBindingForm(result Relation, binding query.BindingForm, inputValues ma
 			tuple[i] = inputValues[sym]
 		}
 
-		// Add result values
-		resultTuple := result.Get(0)
 		// fmt.Printf("DEBUG: Result tuple: %v\n", resultTuple)
 
 		// Check for nil values in result (INVARIANT: should never happen)
@@ -748,7 +754,7 @@ func applyBindingForm(result Relation, binding query.BindingForm, inputValues ma
 
 	case query.RelationBinding:
 		// [[?a ?b] ...] - bind as relation with multiple columns
-		resultCols := result.Columns()
+		resultCols := result.Symbols()
 		if len(b.Variables) != len(resultCols) {
 			return nil, fmt.Errorf("relation binding expects %d columns, got %d", len(b.Variables), len(resultCols))
 		}
@@ -768,7 +774,9 @@ func applyBindingForm(result Relation, binding query.BindingForm, inputValues ma
 
 		// Create tuples with input values + each result row (excluding $)
 		var tuples []Tuple
-		for i := 0; i < result.Size(); i++ {
+		it := result.Iterator()
+		defer it.Close()
+		for it.Next() {
 			tuple := make(Tuple, len(columns))
 
 			// Add input values (excluding $)
@@ -777,7 +785,7 @@ func applyBindingForm(result Relation, binding query.BindingForm, inputValues ma
 			}
 
 			// Add result values
-			resultTuple := result.Get(i)
+			resultTuple := it.Tuple()
 			for j := range b.Variables {
 				tuple[len(realInputSymbols)+j] = resultTuple[j]
 			}
@@ -849,7 +857,7 @@ func (sc *subqueryContext) matchesWithRelation(datom datalog.Datom, pattern *que
 		return true // No constraints
 	}
 
-	cols := rel.Columns()
+	cols := rel.Symbols()
 	it := rel.Iterator()
 	if !it.Next() {
 		it.Close()
@@ -943,27 +951,10 @@ func (sc *subqueryContext) GetMetadata(key string) (interface{}, bool) {
 	return sc.parent.GetMetadata(key)
 }
 
-// canBatchSubquery checks if a subquery can be executed with batched inputs.
-// This requires the subquery to have a RelationInput in its :in clause.
-func canBatchSubquery(q *query.Query) bool {
-	// Check if the query has a RelationInput after the database
-	hasDatabase := false
-	for _, input := range q.In {
-		switch input.(type) {
-		case query.DatabaseInput:
-			hasDatabase = true
-		case query.RelationInput:
-			// Found a RelationInput - we can batch!
-			return hasDatabase // Must have database before relation
-		}
-	}
-	return false
-}
-
 // executeBatchedSubquery executes a subquery with all input combinations at once.
-// This requires the subquery to accept RelationInput (e.g., :in $ [[?sym ?d]]).
-// IMPORTANT: This doesn't work correctly for aggregating subqueries because
-// the aggregation will compute over the entire dataset instead of per input tuple.
+// This requires the subquery to accept RelationInput (e.g., :in $ [[?sym ?d]])
+// and return its symbols in :find, so that its rows, and its aggregates'
+// groups, carry the inputs they belong to (see batchableSubquery).
 func executeBatchedSubqueryWithCombinations(ctx Context, parentExec *Executor, subqPlan planner.SubqueryPlan, inputCombinations []map[query.Symbol]interface{}) (Relation, error) {
 	if len(inputCombinations) == 0 {
 		columns := getBindingColumns(subqPlan.Subquery.Binding, subqPlan.Inputs)
@@ -988,6 +979,24 @@ func executeBatchedSubqueryWithCombinations(ctx Context, parentExec *Executor, s
 		}
 	}
 
+	// Batching only works when the outer values are the relation's columns;
+	// a relation-valued input or extra scalar inputs must run per combination
+	var relationSymbols []query.Symbol
+	width := -1
+	for _, input := range subqPlan.Subquery.Query.In {
+		switch inp := input.(type) {
+		case query.DatabaseInput:
+		case query.RelationInput:
+			relationSymbols = inp.Symbols
+			width = len(inp.Symbols)
+		default:
+			return nil, fmt.Errorf("cannot batch subquery with %s input", inp)
+		}
+	}
+	if width != len(columns) {
+		return nil, fmt.Errorf("cannot batch subquery: relation input expects %d columns, subquery passes %d values", width, len(columns))
+	}
+
 	// Build tuples from all combinations
 	for _, values := range inputCombinations {
 		var tuple Tuple
@@ -1001,8 +1010,8 @@ func executeBatchedSubqueryWithCombinations(ctx Context, parentExec *Executor, s
 		}
 	}
 
-	// Create the batched input relation
-	batchedInputRel := NewMaterializedRelation(columns, allTuples)
+	// Create the batched input relation, named as the subquery's :in expects
+	batchedInputRel := NewMaterializedRelation(relationSymbols, allTuples)
 
 	// Create input relations for the subquery
 	// We need to pass $ and the batched relation
@@ -1028,9 +1037,8 @@ func executeBatchedSubqueryWithCombinations(ctx Context, parentExec *Executor, s
 	}
 
 	// For batched execution, we can't apply the binding form per-input
-	// The result should already have all rows
-	// Just apply the binding with empty input values (no scalar substitution needed)
-	boundResult, err := applyBindingForm(result, subqPlan.Subquery.Binding, nil, subqPlan.Inputs)
+	// The result already binds the inputs, returned in :find
+	boundResult, err := applyBindingForm(result, subqPlan.Subquery.Binding, nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("binding form application failed: %w", err)
 	}
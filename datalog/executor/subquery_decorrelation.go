@@ -3,10 +3,12 @@ package executor
 import (
 	"fmt"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/annotations"
 	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
@@ -44,6 +46,17 @@ func executeDecorrelatedSubqueries(ctx Context,
 		})
 	}
 
+	// Partition-wise execution: each worker computes only the groups for a
+	// range of correlation keys and streams its joined slice to the caller
+	if exec.planner != nil {
+		partitions := exec.planner.Options().DecorrelationPartitions
+		if partitions > 1 && len(decorPlan.PartitionedPlans) == len(decorPlan.MergedPlans) {
+			if result, ok, err := executeDecorrelatedPartitions(ctx, exec, decorPlan, inputRelation, partitions); ok || err != nil {
+				return result, err
+			}
+		}
+	}
+
 	// Execute each merged query ONCE (computes ALL groups via GROUP BY)
 	var groupResults []Relation
 
@@ -126,8 +139,8 @@ func executeDecorrelatedSubqueries(ctx Context,
 
 				if collector != nil {
 					collector.AddTiming(fmt.Sprintf("decorrelated_subqueries/merged_query_%d", idx), timings[idx], map[string]interface{}{
-						"result_size":    result.Size(),
-						"result_columns": result.Columns(),
+						"result_size":    annotationSize(result),
+						"result_columns": result.Symbols(),
 					})
 				}
 			}(i, mergedPlan)
@@ -153,8 +166,8 @@ func executeDecorrelatedSubqueries(ctx Context,
 
 			if collector != nil {
 				collector.AddTiming(fmt.Sprintf("decorrelated_subqueries/merged_query_%d", i), mergedStart, map[string]interface{}{
-					"result_size":    result.Size(),
-					"result_columns": result.Columns(),
+					"result_size":    annotationSize(result),
+					"result_columns": result.Symbols(),
 				})
 			}
 		}
@@ -178,8 +191,8 @@ func executeDecorrelatedSubqueries(ctx Context,
 			Name:  "decorrelated_subqueries/combined",
 			Start: start,
 			Data: map[string]interface{}{
-				"combined_size":    combinedResult.Size(),
-				"combined_columns": combinedResult.Columns(),
+				"combined_size":    annotationSize(combinedResult),
+				"combined_columns": combinedResult.Symbols(),
 			},
 		})
 	}
@@ -196,27 +209,275 @@ func executeDecorrelatedSubqueries(ctx Context,
 			Name:  "decorrelated_subqueries/joined_with_input",
 			Start: start,
 			Data: map[string]interface{}{
-				"joined_size":    joined.Size(),
-				"joined_columns": joined.Columns(),
+				"joined_size":    annotationSize(joined),
+				"joined_columns": joined.Symbols(),
 			},
 		})
 	}
 
 	// Rename and reorder columns in one step to match original subquery order
 	// This fixes the parallel decorrelation column ordering bug
-	finalResult := applyBindingRenamesAndReorder(joined, groupResults, decorPlan, inputRelation.Columns())
+	finalResult := applyBindingRenamesAndReorder(joined, groupResults, decorPlan, inputRelation.Symbols())
 
 	// Add completion event
 	if collector != nil {
 		collector.AddTiming("decorrelated_subqueries/complete", start, map[string]interface{}{
 			"filter_groups": len(decorPlan.MergedPlans),
-			"result_size":   finalResult.Size(),
+			"result_size":   annotationSize(finalResult),
 		})
 	}
 
 	return finalResult, nil
 }
 
+// executeDecorrelatedPartitions executes the partitioned merged queries over
+// contiguous ranges of the input's distinct correlation keys.
+//
+// The distinct keys are sorted and split into at most `partitions` ranges. Each
+// range is executed by a worker: the partitioned plans receive the range's keys
+// as a relation input, so they aggregate only those groups, and the partial
+// result is joined back to the input tuples carrying those keys. Partitions are
+// streamed through a UnionRelation as they complete, so the full merged result
+// is never materialized.
+//
+// Returns ok=false if the input does not carry the correlation keys, in which
+// case the caller should fall back to unpartitioned execution.
+func executeDecorrelatedPartitions(ctx Context,
+	exec *Executor,
+	decorPlan *planner.DecorrelatedSubqueryPlan,
+	inputRelation Relation,
+	partitions int) (Relation, bool, error) {
+
+	start := time.Now()
+	collector := ctx.Collector()
+
+	// Locate the correlation keys in the input
+	var keyIndices []int
+	for _, key := range decorPlan.CorrelationKeys {
+		if key == "$" {
+			continue
+		}
+		idx := ColumnIndex(inputRelation, key)
+		if idx < 0 {
+			return nil, false, nil
+		}
+		keyIndices = append(keyIndices, idx)
+	}
+
+	// Group input tuples by distinct correlation key
+	var keys []Tuple
+	var tuplesByKey [][]Tuple
+	keyPos := make(map[string]int)
+	it := inputRelation.Iterator()
+	for it.Next() {
+		tuple := it.Tuple()
+		hashKey := makeJoinKey(tuple, keyIndices)
+		pos, seen := keyPos[hashKey]
+		if !seen {
+			pos = len(keys)
+			keyPos[hashKey] = pos
+			keys = append(keys, filterKeyValues(tuple, keyIndices))
+			tuplesByKey = append(tuplesByKey, nil)
+		}
+		tuplesByKey[pos] = append(tuplesByKey[pos], tuple)
+	}
+	it.Close()
+
+	finalColumns := decorrelatedColumns(decorPlan, inputRelation.Symbols())
+	if len(keys) == 0 {
+		return NewMaterializedRelation(finalColumns, []Tuple{}), true, nil
+	}
+
+	// Sort keys so each partition covers a contiguous key range
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return compareKeyTuples(keys[order[a]], keys[order[b]]) < 0
+	})
+
+	if partitions > len(keys) {
+		partitions = len(keys)
+	}
+
+	var joinKeys []query.Symbol
+	if len(decorPlan.GroupingVars) > 0 {
+		joinKeys = decorPlan.GroupingVars[0]
+	}
+
+	if collector != nil {
+		collector.Add(annotations.Event{
+			Name:  "decorrelated_subqueries/partitioned",
+			Start: start,
+			Data: map[string]interface{}{
+				"partitions":    partitions,
+				"distinct_keys": len(keys),
+			},
+		})
+	}
+
+	numWorkers := exec.maxSubqueryWorkers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	// Every partition sends exactly one item, so workers never block
+	unionChan := make(chan relationItem, partitions)
+	sem := make(chan struct{}, numWorkers)
+	var wg sync.WaitGroup
+
+	for p := 0; p < partitions; p++ {
+		lo := p * len(keys) / partitions
+		hi := (p + 1) * len(keys) / partitions
+
+		wg.Add(1)
+		go func(idx int, keyOrder []int) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			partitionStart := time.Now()
+
+			// Each worker needs its own context to avoid concurrent map writes
+			var workerCtx Context
+			if collector != nil {
+				workerCtx = NewContext(collector.Handler())
+			} else {
+				workerCtx = NewContext(nil)
+			}
+
+			var partitionKeys, partitionTuples []Tuple
+			for _, pos := range keyOrder {
+				partitionKeys = append(partitionKeys, keys[pos])
+				partitionTuples = append(partitionTuples, tuplesByKey[pos]...)
+			}
+			partitionInput := NewMaterializedRelation(inputRelation.Symbols(), partitionTuples)
+
+			var timeRanges []TimeRange
+			if len(partitionTuples) >= 50 {
+				var err error
+				timeRanges, err = extractTimeRanges(partitionInput, decorPlan.CorrelationKeys)
+				if err != nil {
+					unionChan <- relationItem{err: fmt.Errorf("failed to extract time ranges: %w", err)}
+					return
+				}
+			}
+
+			groupResults := make([]Relation, len(decorPlan.PartitionedPlans))
+			for i, plan := range decorPlan.PartitionedPlans {
+				keyRelation, err := partitionKeyRelation(plan, partitionKeys)
+				if err != nil {
+					unionChan <- relationItem{err: fmt.Errorf("partitioned query %d: %w", i, err)}
+					return
+				}
+
+				// Copy the plan so partitions can carry their own metadata
+				partitionPlan := *plan
+				partitionPlan.Metadata = make(map[string]interface{}, len(plan.Metadata)+1)
+				for k, v := range plan.Metadata {
+					partitionPlan.Metadata[k] = v
+				}
+				if len(timeRanges) > 0 {
+					partitionPlan.Metadata["time_ranges"] = timeRanges
+				}
+
+				result, err := executeNestedPlan(workerCtx, exec, &partitionPlan, []Relation{keyRelation})
+				if err != nil {
+					unionChan <- relationItem{err: fmt.Errorf("merged query %d failed on partition %d: %w", i, idx, err)}
+					return
+				}
+				groupResults[i] = result
+			}
+
+			combined, err := joinDecorrelatedResults(groupResults, joinKeys)
+			if err != nil {
+				unionChan <- relationItem{err: fmt.Errorf("joining decorrelated results failed: %w", err)}
+				return
+			}
+
+			joined := hashJoinWithMapping(partitionInput, combined, decorPlan.CorrelationKeys, joinKeys)
+			partitionResult := applyBindingRenamesAndReorder(joined, groupResults, decorPlan, inputRelation.Symbols())
+
+			if collector != nil {
+				collector.AddTiming(fmt.Sprintf("decorrelated_subqueries/partition_%d", idx), partitionStart, map[string]interface{}{
+					"keys":        len(partitionKeys),
+					"input_size":  len(partitionTuples),
+					"result_size": annotationSize(partitionResult),
+				})
+			}
+
+			unionChan <- relationItem{relation: partitionResult}
+		}(p, order[lo:hi])
+	}
+
+	go func() {
+		wg.Wait()
+		close(unionChan)
+		if collector != nil {
+			collector.AddTiming("decorrelated_subqueries/complete", start, map[string]interface{}{
+				"filter_groups": len(decorPlan.PartitionedPlans),
+				"partitions":    partitions,
+			})
+		}
+	}()
+
+	// Peek at first result to detect early errors
+	firstItem, ok := <-unionChan
+	if !ok {
+		return NewMaterializedRelation(finalColumns, []Tuple{}), true, nil
+	}
+	if firstItem.err != nil {
+		return nil, true, firstItem.err
+	}
+
+	newChan := make(chan relationItem, partitions)
+	newChan <- firstItem
+	go func() {
+		for item := range unionChan {
+			newChan <- item
+		}
+		close(newChan)
+	}()
+
+	return NewUnionRelation(newChan, finalColumns, exec.options), true, nil
+}
+
+// partitionKeyRelation builds the relation input for a partitioned plan from
+// a partition's correlation keys
+func partitionKeyRelation(plan *planner.QueryPlan, keys []Tuple) (Relation, error) {
+	for _, inputSpec := range plan.Query.In {
+		if inp, ok := inputSpec.(query.RelationInput); ok {
+			if len(keys) > 0 && len(keys[0]) != len(inp.Symbols) {
+				return nil, fmt.Errorf("correlation key has %d values but relation input expects %d",
+					len(keys[0]), len(inp.Symbols))
+			}
+			return NewMaterializedRelation(inp.Symbols, keys), nil
+		}
+	}
+	return nil, fmt.Errorf("partitioned plan has no relation input")
+}
+
+// filterKeyValues extracts the values at the given indices from a tuple
+func filterKeyValues(tuple Tuple, indices []int) Tuple {
+	key := make(Tuple, len(indices))
+	for i, idx := range indices {
+		key[i] = tuple[idx]
+	}
+	return key
+}
+
+// compareKeyTuples compares two key tuples lexicographically
+func compareKeyTuples(a, b Tuple) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := datalog.CompareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
 // applyBindingRenamesAndReorder renames and reorders columns in one operation.
 //
 // This function fixes the parallel decorrelation column ordering bug by:
@@ -228,26 +489,16 @@ func executeDecorrelatedSubqueries(ctx Context,
 func applyBindingRenamesAndReorder(joined Relation, groupResults []Relation, decorPlan *planner.DecorrelatedSubqueryPlan,
 	inputColumns []query.Symbol) Relation {
 
-	// Find the maximum original subquery index to determine how many subqueries there were
-	maxSubqIdx := -1
-	for subqIdx := range decorPlan.ColumnMapping {
-		if subqIdx > maxSubqIdx {
-			maxSubqIdx = subqIdx
-		}
-	}
-
-	// Build final columns in correct order: [input columns] + [binding vars in original subquery order]
-	finalColumns := make([]query.Symbol, len(inputColumns))
-	copy(finalColumns, inputColumns)
+	maxSubqIdx := maxDecorrelatedSubqueryIndex(decorPlan)
+	finalColumns := decorrelatedColumns(decorPlan, inputColumns)
 
-	// Collect binding variables in original subquery order
-	var orderedBindingVars []query.Symbol
-	for subqIdx := 0; subqIdx <= maxSubqIdx; subqIdx++ {
-		if resultMap, exists := decorPlan.ColumnMapping[subqIdx]; exists {
-			orderedBindingVars = append(orderedBindingVars, resultMap.BindingVars...)
+	// Correlation keys of the input, positionally matching the grouping columns
+	var inputKeys []query.Symbol
+	for _, key := range decorPlan.CorrelationKeys {
+		if key != "$" {
+			inputKeys = append(inputKeys, key)
 		}
 	}
-	finalColumns = append(finalColumns, orderedBindingVars...)
 
 	// Build mapping from binding variables to aggregate column symbols
 	// We need to look at each filter group's output to know which aggregate corresponds to which binding var
@@ -257,13 +508,22 @@ func applyBindingRenamesAndReorder(joined Relation, groupResults []Relation, dec
 		if resultMap, exists := decorPlan.ColumnMapping[subqIdx]; exists {
 			// Get this filter group's result columns
 			filterGroupResult := groupResults[resultMap.FilterGroupIdx]
-			filterGroupCols := filterGroupResult.Columns()
+			filterGroupCols := filterGroupResult.Symbols()
+
+			var groupKeyCount int
+			if resultMap.FilterGroupIdx < len(decorPlan.GroupingVars) {
+				groupKeyCount = len(decorPlan.GroupingVars[resultMap.FilterGroupIdx])
+			}
 
 			// Map each binding variable to its actual column symbol in the filter group
 			for i, bindingVar := range resultMap.BindingVars {
 				if i < len(resultMap.ColumnIndices) {
 					colIdx := resultMap.ColumnIndices[i]
-					if colIdx < len(filterGroupCols) {
+					if colIdx < groupKeyCount && colIdx < len(inputKeys) {
+						// Grouping columns are dropped by the join with the input,
+						// which carries the same values under its correlation key
+						bindingToSymbol[bindingVar] = inputKeys[colIdx]
+					} else if colIdx < len(filterGroupCols) {
 						// The actual column symbol (like "(max ?h)") in the filter group
 						bindingToSymbol[bindingVar] = filterGroupCols[colIdx]
 					}
@@ -273,7 +533,7 @@ func applyBindingRenamesAndReorder(joined Relation, groupResults []Relation, dec
 	}
 
 	// Build index mapping: for each final column position, where to find it in joined relation
-	oldColumns := joined.Columns()
+	oldColumns := joined.Symbols()
 	indexMapping := make([]int, len(finalColumns))
 
 	for finalIdx, col := range finalColumns {
@@ -317,6 +577,33 @@ func applyBindingRenamesAndReorder(joined Relation, groupResults []Relation, dec
 	return NewMaterializedRelation(finalColumns, tuples)
 }
 
+// maxDecorrelatedSubqueryIndex returns the highest original subquery index in
+// the column mapping, or -1 if there are none
+func maxDecorrelatedSubqueryIndex(decorPlan *planner.DecorrelatedSubqueryPlan) int {
+	maxSubqIdx := -1
+	for subqIdx := range decorPlan.ColumnMapping {
+		if subqIdx > maxSubqIdx {
+			maxSubqIdx = subqIdx
+		}
+	}
+	return maxSubqIdx
+}
+
+// decorrelatedColumns returns the columns of a decorrelated result:
+// [input columns] + [binding vars in original subquery order]
+func decorrelatedColumns(decorPlan *planner.DecorrelatedSubqueryPlan, inputColumns []query.Symbol) []query.Symbol {
+	finalColumns := make([]query.Symbol, len(inputColumns))
+	copy(finalColumns, inputColumns)
+
+	maxSubqIdx := maxDecorrelatedSubqueryIndex(decorPlan)
+	for subqIdx := 0; subqIdx <= maxSubqIdx; subqIdx++ {
+		if resultMap, exists := decorPlan.ColumnMapping[subqIdx]; exists {
+			finalColumns = append(finalColumns, resultMap.BindingVars...)
+		}
+	}
+	return finalColumns
+}
+
 // joinDecorrelatedResults joins multiple decorrelated query results
 func joinDecorrelatedResults(results []Relation, keys []query.Symbol) (Relation, error) {
 	if len(results) == 0 {
@@ -367,7 +654,7 @@ func hashJoinWithMapping(left, right Relation, leftKeys, rightKeys []query.Symbo
 
 		// If key not found in either relation, return empty
 		if leftIndices[i] < 0 || rightIndices[i] < 0 {
-			resultColumns := append(left.Columns(), filterColumns(right.Columns(), filteredRightKeys)...)
+			resultColumns := append(left.Symbols(), filterColumns(right.Symbols(), filteredRightKeys)...)
 			return NewMaterializedRelation(resultColumns, []Tuple{})
 		}
 	}
@@ -404,7 +691,7 @@ func hashJoinWithMapping(left, right Relation, leftKeys, rightKeys []query.Symbo
 
 	// Probe and build result
 	var resultTuples []Tuple
-	resultColumns := append(left.Columns(), filterColumns(right.Columns(), filteredRightKeys)...)
+	resultColumns := append(left.Symbols(), filterColumns(right.Symbols(), filteredRightKeys)...)
 
 	it = probeRel.Iterator()
 	for it.Next() {
@@ -452,7 +739,7 @@ func hashJoinOnKeys(left, right Relation, keys []query.Symbol) Relation {
 		// But we'll handle it gracefully by treating it as no match
 		if leftIndices[i] < 0 || rightIndices[i] < 0 {
 			// Return empty relation
-			resultColumns := append(left.Columns(), filterColumns(right.Columns(), keys)...)
+			resultColumns := append(left.Symbols(), filterColumns(right.Symbols(), keys)...)
 			return NewMaterializedRelation(resultColumns, []Tuple{})
 		}
 	}
@@ -489,7 +776,7 @@ func hashJoinOnKeys(left, right Relation, keys []query.Symbol) Relation {
 
 	// Probe and build result
 	var resultTuples []Tuple
-	resultColumns := append(left.Columns(), filterColumns(right.Columns(), keys)...)
+	resultColumns := append(left.Symbols(), filterColumns(right.Symbols(), keys)...)
 
 	it = probeRel.Iterator()
 	for it.Next() {
@@ -578,7 +865,7 @@ type timeKey struct {
 // This enables semi-join pushdown by constraining merged queries to scan only relevant time periods
 func extractTimeRanges(inputRelation Relation, correlationKeys []query.Symbol) ([]TimeRange, error) {
 	// Get columns from input relation
-	cols := inputRelation.Columns()
+	cols := inputRelation.Symbols()
 	if len(cols) == 0 {
 		return nil, nil
 	}
@@ -1,21 +1,26 @@
 package executor
 
-import "github.com/wbrown/janus-datalog/datalog/query"
+import (
+	"fmt"
+
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
 
 // SubqueryExecutionStrategy represents different ways to execute subqueries
 type SubqueryExecutionStrategy int
 
 const (
 	// StrategyBatched executes subquery once with all inputs as RelationInput
-	// Used when subquery has :in $ [[?sym ?hr] ...] format
+	// Used when subquery has :in $ [[?sym ?hr] ...] format and returns ?sym ?hr
 	StrategyBatched SubqueryExecutionStrategy = iota
 
 	// StrategyParallel executes subquery iterations in parallel using worker pool
-	// Used when input count >= threshold and parallel execution is enabled
+	// Used when estimated work >= threshold and parallel execution is enabled
 	StrategyParallel
 
 	// StrategySequential executes subquery iterations sequentially in a loop
-	// Used when input count < threshold or parallel execution is disabled
+	// Used when estimated work < threshold or parallel execution is disabled
 	StrategySequential
 )
 
@@ -33,13 +38,16 @@ func (s SubqueryExecutionStrategy) String() string {
 	}
 }
 
-// SubqueryStrategySelector chooses optimal execution strategy based on query structure and configuration
+// SubqueryStrategySelector chooses the execution strategy for a subquery from
+// the number of input combinations it runs for, an estimate of the cost of
+// each run, and whether batching would change its aggregates
 type SubqueryStrategySelector struct {
 	parallelThreshold int
 }
 
 // NewSubqueryStrategySelector creates a new strategy selector
-// parallelThreshold: minimum number of inputs to trigger parallel execution (0 = use default of 100)
+// parallelThreshold: minimum estimated work (input combinations × cost per run)
+// to trigger parallel execution (0 = use default of 100)
 func NewSubqueryStrategySelector(parallelThreshold int) *SubqueryStrategySelector {
 	if parallelThreshold <= 0 {
 		parallelThreshold = 100 // Default threshold
@@ -49,27 +57,180 @@ func NewSubqueryStrategySelector(parallelThreshold int) *SubqueryStrategySelecto
 	}
 }
 
-// SelectStrategy chooses the optimal execution strategy for a subquery
-// Returns:
-// - StrategyBatched if subquery accepts RelationInput
-// - StrategyParallel if parallel enabled and inputCount >= threshold
-// - StrategySequential otherwise
+// SubqueryStrategyChoice is the strategy chosen for a subquery, with the
+// figures and reasons behind it
+type SubqueryStrategyChoice struct {
+	Strategy          SubqueryExecutionStrategy
+	InputCombinations int      // Distinct input combinations the subquery runs for
+	EstimatedCost     int      // Estimated cost of one run of the subquery
+	Reasons           []string // Why each strategy was or wasn't chosen
+}
+
+// Choose selects the execution strategy for a subquery pattern:
+//   - StrategyBatched if the subquery takes its inputs as one RelationInput and
+//     returns them in :find, so that one run gives every combination's results,
+//     and its aggregates are grouped by input
+//   - StrategyParallel if parallel execution is enabled and the input
+//     combinations times the estimated cost of a run meets the threshold
+//   - StrategySequential otherwise
+func (s *SubqueryStrategySelector) Choose(
+	subq *query.SubqueryPattern,
+	inputCount int,
+	opts ExecutorOptions,
+) SubqueryStrategyChoice {
+	choice := SubqueryStrategyChoice{
+		Strategy:          StrategySequential,
+		InputCombinations: inputCount,
+		EstimatedCost:     estimateSubqueryCost(subq.Query),
+	}
+
+	reason, ok := batchableSubquery(subq)
+	choice.Reasons = append(choice.Reasons, reason)
+	if ok {
+		choice.Strategy = StrategyBatched
+		return choice
+	}
+
+	work := inputCount * choice.EstimatedCost
+	switch {
+	case !opts.EnableParallelSubqueries:
+		choice.Reasons = append(choice.Reasons, "parallel subqueries are disabled")
+	case inputCount < 2:
+		choice.Reasons = append(choice.Reasons, fmt.Sprintf("%d input combination(s) cannot run in parallel", inputCount))
+	case work < s.parallelThreshold:
+		choice.Reasons = append(choice.Reasons, fmt.Sprintf(
+			"%d input combinations at cost %d is below the parallel threshold of %d",
+			inputCount, choice.EstimatedCost, s.parallelThreshold))
+	default:
+		choice.Strategy = StrategyParallel
+		choice.Reasons = append(choice.Reasons, fmt.Sprintf(
+			"%d input combinations at cost %d meets the parallel threshold of %d",
+			inputCount, choice.EstimatedCost, s.parallelThreshold))
+	}
+	return choice
+}
+
+// SelectStrategy chooses the execution strategy for a subquery pattern
+// without the reasons; see Choose
 func (s *SubqueryStrategySelector) SelectStrategy(
-	subq *query.Query,
+	subq *query.SubqueryPattern,
 	inputCount int,
 	opts ExecutorOptions,
 ) SubqueryExecutionStrategy {
-	// Check if subquery can be batched (has RelationInput in :in clause)
-	if CanBatchSubquery(subq) {
-		return StrategyBatched
+	return s.Choose(subq, inputCount, opts).Strategy
+}
+
+// annotate records the choice for the subquery in ctx's annotations
+func (c SubqueryStrategyChoice) annotate(ctx Context, subq *query.SubqueryPattern) {
+	if collector := ctx.Collector(); collector != nil {
+		collector.Add(annotations.Event{
+			Name: "subquery/strategy",
+			Data: map[string]interface{}{
+				"query":              subq.Query.String(),
+				"strategy":           c.Strategy.String(),
+				"input_combinations": c.InputCombinations,
+				"estimated_cost":     c.EstimatedCost,
+				"reasons":            c.Reasons,
+			},
+		})
 	}
+}
 
-	// Check if parallel execution is enabled and threshold is met
-	if opts.EnableParallelSubqueries && inputCount >= s.parallelThreshold {
-		return StrategyParallel
+// subqueryRunsPerInput is the number of runs assumed for a nested subquery
+// when estimating the cost of its enclosing subquery
+const subqueryRunsPerInput = 10
+
+// estimateSubqueryCost estimates the cost of one run of a subquery from its
+// clauses: one for each data pattern, plus the cost of each nested subquery's
+// runs. Predicates and expressions are taken as free.
+func estimateSubqueryCost(q *query.Query) int {
+	cost := 0
+	for _, clause := range q.Where {
+		switch c := clause.(type) {
+		case *query.DataPattern:
+			cost++
+		case *query.SubqueryPattern:
+			cost += subqueryRunsPerInput * estimateSubqueryCost(c.Query)
+		}
+	}
+	if cost < 1 {
+		cost = 1
 	}
+	return cost
+}
 
-	return StrategySequential
+// batchableSubquery reports whether a subquery can run once for all its input
+// combinations, and why. Its :in must be $ and one RelationInput receiving
+// the variables passed in, and each relation symbol must be a :find variable
+// bound back to the variable passed for it, so that each result row carries
+// the inputs it belongs to and aggregates are grouped by input.
+func batchableSubquery(subq *query.SubqueryPattern) (string, bool) {
+	q := subq.Query
+	if !CanBatchSubquery(q) {
+		return "no relation input to batch", false
+	}
+
+	var relation query.RelationInput
+	for _, input := range q.In {
+		switch inp := input.(type) {
+		case query.DatabaseInput:
+		case query.RelationInput:
+			relation = inp
+		default:
+			return fmt.Sprintf("cannot batch with %s input", inp), false
+		}
+	}
+
+	var passed []query.Symbol
+	for _, input := range subq.Inputs {
+		switch inp := input.(type) {
+		case query.Variable:
+			passed = append(passed, inp.Name)
+		case query.Constant:
+			if sym, ok := inp.Value.(query.Symbol); ok && sym == "$" {
+				continue
+			}
+			return fmt.Sprintf("cannot batch with constant input %v", inp.Value), false
+		}
+	}
+	if len(passed) != len(relation.Symbols) {
+		return fmt.Sprintf("relation input has %d columns but %d values are passed",
+			len(relation.Symbols), len(passed)), false
+	}
+
+	aggregates := false
+	for _, elem := range q.Find {
+		if _, ok := elem.(query.FindAggregate); ok {
+			aggregates = true
+		}
+	}
+	positions := make([]int, len(relation.Symbols))
+	for i, sym := range relation.Symbols {
+		positions[i] = -1
+		for j, elem := range q.Find {
+			if v, ok := elem.(query.FindVariable); ok && v.Symbol == sym {
+				positions[i] = j
+				break
+			}
+		}
+		if positions[i] < 0 && aggregates {
+			return fmt.Sprintf("%s is not in :find, so batching would aggregate across all inputs", sym), false
+		}
+		if positions[i] < 0 {
+			return fmt.Sprintf("%s is not in :find, so results cannot be matched to inputs", sym), false
+		}
+	}
+
+	binding, ok := subq.Binding.(query.RelationBinding)
+	if !ok {
+		return "only a relation binding can take every input's results", false
+	}
+	for i, sym := range relation.Symbols {
+		if positions[i] >= len(binding.Variables) || binding.Variables[positions[i]] != passed[i] {
+			return fmt.Sprintf("%s is not bound back to %s", sym, passed[i]), false
+		}
+	}
+	return "relation input returns its inputs in :find", true
 }
 
 // GetParallelThreshold returns the configured parallel threshold
@@ -1,29 +1,28 @@
 package executor
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/parser"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
 func TestStrategySelector_Batched(t *testing.T) {
 	selector := NewSubqueryStrategySelector(100)
 
-	// Query with RelationInput should use batched strategy
-	q := &query.Query{
-		In: []query.InputSpec{
-			query.DatabaseInput{},
-			query.RelationInput{
-				Symbols: []query.Symbol{"?sym"},
-			},
-		},
-	}
+	// Subquery returning its RelationInput symbols should use batched strategy
+	subq := batchableSubqueryPattern()
 
 	opts := ExecutorOptions{
 		EnableParallelSubqueries: true,
 	}
 
-	strategy := selector.SelectStrategy(q, 200, opts)
+	strategy := selector.SelectStrategy(subq, 200, opts)
 	if strategy != StrategyBatched {
 		t.Errorf("Expected StrategyBatched, got %v", strategy)
 	}
@@ -44,7 +43,7 @@ func TestStrategySelector_Parallel(t *testing.T) {
 		EnableParallelSubqueries: true,
 	}
 
-	strategy := selector.SelectStrategy(q, 150, opts)
+	strategy := selector.SelectStrategy(&query.SubqueryPattern{Query: q}, 150, opts)
 	if strategy != StrategyParallel {
 		t.Errorf("Expected StrategyParallel, got %v", strategy)
 	}
@@ -65,7 +64,7 @@ func TestStrategySelector_Sequential(t *testing.T) {
 		EnableParallelSubqueries: true,
 	}
 
-	strategy := selector.SelectStrategy(q, 50, opts)
+	strategy := selector.SelectStrategy(&query.SubqueryPattern{Query: q}, 50, opts)
 	if strategy != StrategySequential {
 		t.Errorf("Expected StrategySequential, got %v", strategy)
 	}
@@ -86,7 +85,7 @@ func TestStrategySelector_ParallelDisabled(t *testing.T) {
 		EnableParallelSubqueries: false,
 	}
 
-	strategy := selector.SelectStrategy(q, 200, opts)
+	strategy := selector.SelectStrategy(&query.SubqueryPattern{Query: q}, 200, opts)
 	if strategy != StrategySequential {
 		t.Errorf("Expected StrategySequential (parallel disabled), got %v", strategy)
 	}
@@ -107,13 +106,13 @@ func TestStrategySelector_CustomThreshold(t *testing.T) {
 	}
 
 	// 60 inputs with threshold of 50 should trigger parallel
-	strategy := selector.SelectStrategy(q, 60, opts)
+	strategy := selector.SelectStrategy(&query.SubqueryPattern{Query: q}, 60, opts)
 	if strategy != StrategyParallel {
 		t.Errorf("Expected StrategyParallel (60 >= 50), got %v", strategy)
 	}
 
 	// 40 inputs with threshold of 50 should use sequential
-	strategy = selector.SelectStrategy(q, 40, opts)
+	strategy = selector.SelectStrategy(&query.SubqueryPattern{Query: q}, 40, opts)
 	if strategy != StrategySequential {
 		t.Errorf("Expected StrategySequential (40 < 50), got %v", strategy)
 	}
@@ -217,23 +216,165 @@ func TestStrategyString(t *testing.T) {
 func TestStrategySelector_BatchedTakesPrecedence(t *testing.T) {
 	selector := NewSubqueryStrategySelector(100)
 
-	// Query with RelationInput should use batched even if parallel conditions met
-	q := &query.Query{
-		In: []query.InputSpec{
-			query.DatabaseInput{},
-			query.RelationInput{
-				Symbols: []query.Symbol{"?sym"},
-			},
-		},
-	}
+	// Batchable subquery should use batched even if parallel conditions met
+	subq := batchableSubqueryPattern()
 
 	opts := ExecutorOptions{
 		EnableParallelSubqueries: true,
 	}
 
 	// Even with 1000 inputs, batched should take precedence
-	strategy := selector.SelectStrategy(q, 1000, opts)
+	strategy := selector.SelectStrategy(subq, 1000, opts)
 	if strategy != StrategyBatched {
 		t.Errorf("Expected StrategyBatched to take precedence over parallel, got %v", strategy)
 	}
 }
+
+// batchableSubqueryPattern is [(q [:find ?sym (max ?p) :in $ [[?sym] ...] ...] $ ?s) [[?s ?max] ...]]
+func batchableSubqueryPattern() *query.SubqueryPattern {
+	return &query.SubqueryPattern{
+		Query: &query.Query{
+			Find: []query.FindElement{
+				query.FindVariable{Symbol: "?sym"},
+				query.FindAggregate{Function: "max", Arg: "?p"},
+			},
+			In: []query.InputSpec{
+				query.DatabaseInput{},
+				query.RelationInput{Symbols: []query.Symbol{"?sym"}},
+			},
+		},
+		Inputs: []query.PatternElement{
+			query.Constant{Value: query.Symbol("$")},
+			query.Variable{Name: "?s"},
+		},
+		Binding: query.RelationBinding{Variables: []query.Symbol{"?s", "?max"}},
+	}
+}
+
+func TestStrategySelector_BatchingNeedsInputsInFind(t *testing.T) {
+	selector := NewSubqueryStrategySelector(100)
+	opts := ExecutorOptions{EnableParallelSubqueries: true}
+
+	// Without ?sym in :find, one run would take the max across all inputs
+	subq := batchableSubqueryPattern()
+	subq.Query.Find = subq.Query.Find[1:]
+	subq.Binding = query.TupleBinding{Variables: []query.Symbol{"?max"}}
+	choice := selector.Choose(subq, 200, opts)
+	if choice.Strategy != StrategyParallel {
+		t.Errorf("Expected StrategyParallel, got %v", choice.Strategy)
+	}
+	if len(choice.Reasons) != 2 || !strings.Contains(choice.Reasons[0], "aggregate across all inputs") {
+		t.Errorf("Expected the aggregate to be given as the reason not to batch, got %q", choice.Reasons)
+	}
+
+	// ?sym must be bound back to the variable passed for it
+	subq = batchableSubqueryPattern()
+	subq.Binding = query.RelationBinding{Variables: []query.Symbol{"?other", "?max"}}
+	if choice := selector.Choose(subq, 200, opts); choice.Strategy == StrategyBatched {
+		t.Errorf("Expected no batching when ?sym is bound to ?other, got %q", choice.Reasons)
+	}
+}
+
+func TestStrategySelector_CostEstimate(t *testing.T) {
+	selector := NewSubqueryStrategySelector(100)
+	opts := ExecutorOptions{EnableParallelSubqueries: true}
+
+	q, err := parser.ParseQuery(`[:find (max ?p)
+	                              :in $ ?sym
+	                              :where [?b :price/symbol ?sym] [?b :price/time ?t] [?b :price/value ?p]
+	                                     [(> ?t 10)]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	subq := &query.SubqueryPattern{Query: q}
+
+	// Three patterns per run: 40 inputs is enough work to run in parallel
+	choice := selector.Choose(subq, 40, opts)
+	if choice.EstimatedCost != 3 || choice.Strategy != StrategyParallel {
+		t.Errorf("Expected parallel at cost 3, got %v at cost %d: %q", choice.Strategy, choice.EstimatedCost, choice.Reasons)
+	}
+	if choice := selector.Choose(subq, 30, opts); choice.Strategy != StrategySequential {
+		t.Errorf("Expected sequential for 30 inputs at cost 3, got %v", choice.Strategy)
+	}
+	if choice := selector.Choose(subq, 1, opts); choice.Strategy != StrategySequential {
+		t.Errorf("Expected sequential for a single input, got %v", choice.Strategy)
+	}
+}
+
+func TestSubqueryStrategyAnnotations(t *testing.T) {
+	var datoms []datalog.Datom
+	for i, ticker := range []string{"A", "B", "C", "D", "E", "F"} {
+		symbol := datalog.NewIdentity("symbol:" + ticker)
+		datoms = append(datoms, datalog.Datom{E: symbol, A: datalog.NewKeyword(":symbol/ticker"), V: ticker, Tx: 1})
+		for j := 1; j <= 3; j++ {
+			bar := datalog.NewIdentity(fmt.Sprintf("bar:%s:%d", ticker, j))
+			datoms = append(datoms,
+				datalog.Datom{E: bar, A: datalog.NewKeyword(":price/symbol"), V: symbol, Tx: 2},
+				datalog.Datom{E: bar, A: datalog.NewKeyword(":price/value"), V: float64(100*i + 10*j), Tx: 2})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		strategy string
+	}{
+		{"batched", `[:find ?ticker ?max
+		              :where [?s :symbol/ticker ?ticker]
+		                     [(q [:find ?sym (max ?p) :in $ [[?sym] ...]
+		                          :where [?b :price/symbol ?sym] [?b :price/value ?p]]
+		                         $ ?s) [[?s ?max] ...]]]`, "batched"},
+		{"aggregate not grouped by input", `[:find ?ticker ?max
+		              :where [?s :symbol/ticker ?ticker]
+		                     [(q [:find (max ?p) :in $ [[?sym] ...]
+		                          :where [?b :price/symbol ?sym] [?b :price/value ?p]]
+		                         $ ?s) [[?max]]]]`, "parallel"},
+		{"scalar input", `[:find ?ticker ?max
+		              :where [?s :symbol/ticker ?ticker]
+		                     [(q [:find (max ?p) :in $ ?sym
+		                          :where [?b :price/symbol ?sym] [?b :price/value ?p]]
+		                         $ ?s) [[?max]]]]`, "parallel"},
+	}
+
+	for _, tt := range tests {
+		for _, useQueryExecutor := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/QueryExecutor=%v", tt.name, useQueryExecutor), func(t *testing.T) {
+				q, err := parser.ParseQuery(tt.query)
+				if err != nil {
+					t.Fatalf("Failed to parse query: %v", err)
+				}
+
+				var mu sync.Mutex
+				var events []annotations.Event
+				handler := func(e annotations.Event) {
+					if e.Name == "subquery/strategy" {
+						mu.Lock()
+						events = append(events, e)
+						mu.Unlock()
+					}
+				}
+
+				exec := NewExecutor(NewMemoryPatternMatcher(datoms))
+				exec.SetUseQueryExecutor(useQueryExecutor)
+				result, err := exec.ExecuteWithContext(NewContext(handler), q)
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+
+				// Each ticker's own maximum, however the subquery ran
+				if got := fmt.Sprint(result.Sorted()); got != "[[A 30] [B 130] [C 230] [D 330] [E 430] [F 530]]" {
+					t.Errorf("Expected each ticker's maximum, got %s", got)
+				}
+
+				if len(events) != 1 {
+					t.Fatalf("Expected one strategy annotation, got %d", len(events))
+				}
+				data := events[0].Data
+				if data["strategy"] != tt.strategy || data["input_combinations"] != 6 {
+					t.Errorf("Expected %s for 6 input combinations, got %v for %v: %q",
+						tt.strategy, data["strategy"], data["input_combinations"], data["reasons"])
+				}
+			})
+		}
+	}
+}
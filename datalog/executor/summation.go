@@ -0,0 +1,140 @@
+package executor
+
+import "math"
+
+// Summation selects how sum and avg aggregates add float values
+type Summation int
+
+const (
+	// SummationCompensated adds values in arrival order, carrying the
+	// rounding error of each addition in a second term (Neumaier's variant
+	// of Kahan summation). Long sums of small values don't drift as they do
+	// with plain addition, at the cost of a few more operations per value.
+	// The result can still depend on the order in its last bit.
+	SummationCompensated Summation = iota
+	// SummationFast adds values in arrival order with plain float64
+	// addition. Rounding error grows with the number of values, and
+	// depends on the order, so a sum over rows from parallel subqueries can
+	// differ in its last bits from run to run.
+	SummationFast
+	// SummationExact tracks the exact sum and rounds it once, so the result
+	// is the correctly rounded sum whatever order the values arrive in. It
+	// keeps a few partial sums per aggregate and is slower than
+	// SummationFast. Exact sums also combine exactly, so partial sums from
+	// parallel workers can be merged without changing the result.
+	SummationExact
+)
+
+// String returns the summation's name
+func (s Summation) String() string {
+	switch s {
+	case SummationCompensated:
+		return "compensated"
+	case SummationFast:
+		return "fast"
+	case SummationExact:
+		return "exact"
+	default:
+		return "unknown"
+	}
+}
+
+// floatSum accumulates float64 values under a Summation
+type floatSum struct {
+	mode Summation
+	sum  float64 // SummationFast and SummationCompensated
+	comp float64 // SummationCompensated: the rounding error lost from sum
+
+	// SummationExact: non-overlapping partial sums in increasing magnitude
+	// whose exact total is the sum (Shewchuk's algorithm), plus infinities
+	// and NaNs, which are summed apart
+	partials []float64
+	special  float64
+}
+
+// Add adds x to the sum
+func (s *floatSum) Add(x float64) {
+	switch s.mode {
+	case SummationFast:
+		s.sum += x
+		return
+	case SummationCompensated:
+		t := s.sum + x
+		if math.Abs(s.sum) >= math.Abs(x) {
+			s.comp += (s.sum - t) + x
+		} else {
+			s.comp += (x - t) + s.sum
+		}
+		s.sum = t
+		return
+	}
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		s.special += x
+		return
+	}
+
+	i := 0
+	for _, y := range s.partials {
+		if math.Abs(x) < math.Abs(y) {
+			x, y = y, x
+		}
+		hi := x + y
+		lo := y - (hi - x)
+		if lo != 0 {
+			s.partials[i] = lo
+			i++
+		}
+		x = hi
+	}
+	if math.IsInf(x, 0) {
+		// A partial sum overflowed; the sum is taken as infinite
+		s.special += x
+		s.partials = s.partials[:0]
+		return
+	}
+	s.partials = append(s.partials[:i], x)
+}
+
+// Value returns the sum, rounded once for SummationExact
+func (s *floatSum) Value() float64 {
+	switch s.mode {
+	case SummationFast:
+		return s.sum
+	case SummationCompensated:
+		// An infinite or NaN sum has no meaningful compensation
+		if math.IsInf(s.sum, 0) || math.IsNaN(s.sum) {
+			return s.sum
+		}
+		return s.sum + s.comp
+	}
+	if s.special != 0 || math.IsNaN(s.special) {
+		return s.special
+	}
+
+	n := len(s.partials)
+	if n == 0 {
+		return 0
+	}
+	n--
+	hi := s.partials[n]
+	lo := 0.0
+	for n > 0 {
+		x := hi
+		n--
+		y := s.partials[n]
+		hi = x + y
+		lo = y - (hi - x)
+		if lo != 0 {
+			break
+		}
+	}
+	// Round half to even when the remaining partials push lo past a tie
+	if n > 0 && ((lo < 0 && s.partials[n-1] < 0) || (lo > 0 && s.partials[n-1] > 0)) {
+		y := lo * 2
+		x := hi + y
+		if y == x-hi {
+			hi = x
+		}
+	}
+	return hi
+}
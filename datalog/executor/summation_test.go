@@ -0,0 +1,138 @@
+package executor
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func sumValues(mode Summation, values []float64) float64 {
+	sum := floatSum{mode: mode}
+	for _, v := range values {
+		sum.Add(v)
+	}
+	return sum.Value()
+}
+
+func TestExactSummation(t *testing.T) {
+	// Plain addition loses the 1 to rounding in this order, but not in others
+	values := []float64{1e16, 1, -1e16}
+	if got := sumValues(SummationFast, values); got != 0 {
+		t.Fatalf("Expected fast summation to lose the 1, got %v", got)
+	}
+	if got := sumValues(SummationExact, values); got != 1 {
+		t.Errorf("Expected an exact sum of 1, got %v", got)
+	}
+
+	// Any order gives the correctly rounded sum
+	rng := rand.New(rand.NewSource(42))
+	values = make([]float64, 10000)
+	exact := new(big.Float).SetPrec(2048)
+	for i := range values {
+		values[i] = (rng.Float64() - 0.5) * math.Pow(10, float64(rng.Intn(30)-15))
+		exact.Add(exact, big.NewFloat(values[i]))
+	}
+	want, _ := exact.Float64()
+
+	for i := 0; i < 5; i++ {
+		rng.Shuffle(len(values), func(a, b int) { values[a], values[b] = values[b], values[a] })
+		if got := sumValues(SummationExact, values); got != want {
+			t.Errorf("Shuffle %d: expected exact sum %v, got %v", i, want, got)
+		}
+	}
+
+	special := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{math.Inf(1), 1}, math.Inf(1)},
+		{[]float64{math.MaxFloat64, math.MaxFloat64}, math.Inf(1)},
+	}
+	for _, tc := range special {
+		if got := sumValues(SummationExact, tc.values); got != tc.want {
+			t.Errorf("Sum of %v: expected %v, got %v", tc.values, tc.want, got)
+		}
+	}
+	if got := sumValues(SummationExact, []float64{math.Inf(1), math.Inf(-1)}); !math.IsNaN(got) {
+		t.Errorf("Expected NaN for opposite infinities, got %v", got)
+	}
+}
+
+func TestCompensatedSummation(t *testing.T) {
+	// A million small values: plain addition drifts, compensated doesn't
+	values := make([]float64, 1000000)
+	exact := new(big.Float).SetPrec(2048)
+	for i := range values {
+		values[i] = 0.1
+		exact.Add(exact, big.NewFloat(0.1))
+	}
+	want, _ := exact.Float64()
+
+	fast := sumValues(SummationFast, values)
+	if fast == want {
+		t.Fatalf("Expected plain addition to drift from %v", want)
+	}
+	if got := sumValues(SummationCompensated, values); got != want {
+		t.Errorf("Expected compensated sum %v, got %v (plain addition gives %v)", want, got, fast)
+	}
+
+	// The compensation survives cancellation that plain addition loses
+	if got := sumValues(SummationCompensated, []float64{1e16, 1, -1e16}); got != 1 {
+		t.Errorf("Expected a compensated sum of 1, got %v", got)
+	}
+	if got := sumValues(SummationCompensated, []float64{math.Inf(1), 1}); !math.IsInf(got, 1) {
+		t.Errorf("Expected an infinite sum, got %v", got)
+	}
+
+	// The zero options value adds with compensation
+	var opts ExecutorOptions
+	if opts.Summation != SummationCompensated {
+		t.Errorf("Expected compensated summation by default, got %v", opts.Summation)
+	}
+}
+
+func TestSummationOption(t *testing.T) {
+	columns := []query.Symbol{"?g", "?v"}
+	tuples := []Tuple{{"a", 1e16}, {"a", 1.0}, {"a", -1e16}}
+	find := []query.FindElement{
+		query.FindAggregate{Function: "sum", Arg: "?v"},
+		query.FindAggregate{Function: "avg", Arg: "?v"},
+	}
+	grouped := append([]query.FindElement{query.FindVariable{Symbol: "?g"}}, find...)
+
+	inputs := map[string]func() Relation{
+		"Batch": func() Relation {
+			return NewMaterializedRelation(columns, tuples)
+		},
+		"Streaming": func() Relation {
+			source := NewMaterializedRelation(columns, tuples).Iterator()
+			return NewStreamingRelationWithOptions(columns, source, ExecutorOptions{EnableStreamingAggregation: true})
+		},
+	}
+	for name, input := range inputs {
+		for _, elements := range [][]query.FindElement{find, grouped} {
+			result, err := aggregateQueryResult(NewContext(nil), input(), &query.Query{}, elements, ExecutorOptions{Summation: SummationExact})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			it := result.Iterator()
+			if !it.Next() {
+				t.Fatalf("%s: expected a result row", name)
+			}
+			tuple := it.Tuple()
+			it.Close()
+
+			offset := len(elements) - len(find)
+			if sum := tuple[offset]; sum != 1.0 {
+				t.Errorf("%s %v: expected exact sum 1, got %v", name, elements, sum)
+			}
+			if avg := tuple[offset+1]; avg != 1.0/3 {
+				t.Errorf("%s %v: expected exact avg 1/3, got %v", name, elements, avg)
+			}
+		}
+	}
+}
@@ -49,18 +49,8 @@ func SymmetricHashJoinWithOptions(left, right Relation, joinCols []query.Symbol,
 	}
 
 	// Determine output columns (union without duplicates)
-	outputCols := append([]query.Symbol{}, left.Columns()...)
-	rightColSet := make(map[query.Symbol]bool)
-	for _, col := range joinCols {
-		rightColSet[col] = true
-	}
-
-	// Add right columns that aren't in join columns
-	for _, col := range right.Columns() {
-		if !rightColSet[col] {
-			outputCols = append(outputCols, col)
-		}
-	}
+	layout := mustJoinLayout(left.Symbols(), right.Symbols(), joinCols, opts.DuplicateColumns)
+	outputCols := layout.columns
 
 	// Determine initial hash table size
 	// Use configurable DefaultHashTableSize for better cache locality
@@ -79,10 +69,7 @@ func SymmetricHashJoinWithOptions(left, right Relation, joinCols []query.Symbol,
 		rightTable:   NewTupleKeyMapWithCapacity(tableSize),
 		leftIndices:  leftIndices,
 		rightIndices: rightIndices,
-		joinCols:     joinCols,
-		leftCols:     left.Columns(),
-		rightCols:    right.Columns(),
-		outputCols:   outputCols,
+		layout:       layout,
 		resultQueue:  make([]Tuple, 0),
 		seen:         NewTupleKeyMapWithCapacity(tableSize),
 		batchSize:    100, // Process tuples in batches for efficiency
@@ -97,14 +84,13 @@ type symmetricHashJoinIterator struct {
 	leftIt, rightIt           Iterator
 	leftTable, rightTable     *TupleKeyMap
 	leftIndices, rightIndices []int
-	joinCols                  []query.Symbol
-	leftCols, rightCols       []query.Symbol
-	outputCols                []query.Symbol
+	layout                    *joinLayout
 	resultQueue               []Tuple
 	seen                      *TupleKeyMap // For deduplication
 	leftDone, rightDone       bool
 	batchSize                 int
 	resultPos                 int
+	err                       error // Error that ended either side early
 }
 
 // Next advances to the next result tuple
@@ -129,6 +115,12 @@ func (it *symmetricHashJoinIterator) Next() bool {
 		if !it.rightDone {
 			it.processRightBatch()
 		}
+
+		// A failed side makes every later result suspect, so stop here
+		if it.err != nil {
+			it.resultQueue = it.resultQueue[:0]
+			return false
+		}
 	}
 
 	return len(it.resultQueue) > 0
@@ -154,7 +146,7 @@ func (it *symmetricHashJoinIterator) processLeftBatch() {
 			rightMatches := rightMatchesVal.([]Tuple)
 			for _, rightTuple := range rightMatches {
 				// Combine tuples
-				joined := it.combineTuples(leftTuple, rightTuple, true)
+				joined := it.layout.combine(leftTuple, rightTuple)
 
 				// Deduplicate
 				dedupKey := NewTupleKeyFull(joined)
@@ -179,6 +171,9 @@ func (it *symmetricHashJoinIterator) processLeftBatch() {
 	// Check if left is exhausted
 	if processed < it.batchSize {
 		it.leftDone = true
+		if err := it.leftIt.Err(); err != nil && it.err == nil {
+			it.err = err
+		}
 	}
 }
 
@@ -202,7 +197,7 @@ func (it *symmetricHashJoinIterator) processRightBatch() {
 			leftMatches := leftMatchesVal.([]Tuple)
 			for _, leftTuple := range leftMatches {
 				// Combine tuples
-				joined := it.combineTuples(leftTuple, rightTuple, true)
+				joined := it.layout.combine(leftTuple, rightTuple)
 
 				// Deduplicate
 				dedupKey := NewTupleKeyFull(joined)
@@ -227,37 +222,10 @@ func (it *symmetricHashJoinIterator) processRightBatch() {
 	// Check if right is exhausted
 	if processed < it.batchSize {
 		it.rightDone = true
-	}
-}
-
-// combineTuples combines left and right tuples, avoiding duplication of join columns
-func (it *symmetricHashJoinIterator) combineTuples(leftTuple, rightTuple Tuple, leftFirst bool) Tuple {
-	// Start with all columns from left
-	result := make(Tuple, len(it.outputCols))
-	copy(result, leftTuple)
-
-	// Add non-join columns from right
-	rightOffset := len(leftTuple)
-	rightColIndex := 0
-	for i, col := range it.rightCols {
-		// Skip join columns
-		isJoinCol := false
-		for _, joinCol := range it.joinCols {
-			if col == joinCol {
-				isJoinCol = true
-				break
-			}
-		}
-
-		if !isJoinCol {
-			if rightOffset+rightColIndex < len(result) {
-				result[rightOffset+rightColIndex] = rightTuple[i]
-				rightColIndex++
-			}
+		if err := it.rightIt.Err(); err != nil && it.err == nil {
+			it.err = err
 		}
 	}
-
-	return result
 }
 
 // Tuple returns the current result tuple
@@ -270,6 +238,11 @@ func (it *symmetricHashJoinIterator) Tuple() Tuple {
 	return nil
 }
 
+// Err returns the error that ended either input early
+func (it *symmetricHashJoinIterator) Err() error {
+	return it.err
+}
+
 // Close releases resources
 func (it *symmetricHashJoinIterator) Close() error {
 	var err1, err2 error
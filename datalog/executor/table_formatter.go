@@ -18,6 +18,10 @@ type TableFormatter struct {
 	MaxWidth int
 	// TruncateString is the string to append when truncating
 	TruncateString string
+	// MaxRows is the maximum number of tuples to read and show (0 for all).
+	// Reading stops there, so a capped table of a huge streaming relation
+	// never materializes it.
+	MaxRows int
 }
 
 // NewTableFormatter creates a new table formatter with default settings
@@ -28,26 +32,40 @@ func NewTableFormatter() *TableFormatter {
 	}
 }
 
-// FormatRelation formats a Relation as a markdown table
+// FormatRelation formats a Relation as a markdown table. It reads the
+// relation's tuples, so a streaming relation is consumed; set MaxRows to
+// bound how many are read.
 func (tf *TableFormatter) FormatRelation(rel Relation) string {
-	if rel == nil || rel.IsEmpty() {
+	if rel == nil {
 		return "_Empty relation_"
 	}
 
-	// Collect all tuples
+	// Collect the tuples, up to MaxRows
 	var tuples []Tuple
+	truncated := false
 	it := rel.Iterator()
 	defer it.Close()
 
 	for it.Next() {
+		if tf.MaxRows > 0 && len(tuples) == tf.MaxRows {
+			truncated = true
+			break
+		}
 		tuple := it.Tuple()
 		tupleCopy := make(Tuple, len(tuple))
 		copy(tupleCopy, tuple)
 		tuples = append(tuples, tupleCopy)
 	}
+	if len(tuples) == 0 {
+		return "_Empty relation_"
+	}
 
-	columns := rel.Columns()
-	return tf.formatTable(columns, tuples)
+	columns := rel.Symbols()
+	table := tf.formatTable(columns, tuples)
+	if truncated {
+		table += fmt.Sprintf("_Showing the first %d rows; the rest were not read_\n", len(tuples))
+	}
+	return table
 }
 
 // formatTable formats columns and tuples as a markdown table
@@ -137,6 +155,16 @@ func PrintResult(result Relation) {
 	PrintRelation(result)
 }
 
+// PreviewTable formats at most maxRows tuples of a relation as a markdown
+// table. Unlike Table(), it never reads more than maxRows+1 tuples, so it is
+// safe for relations of any size; a streaming relation that has not been
+// materialized is still consumed.
+func PreviewTable(rel Relation, maxRows int) string {
+	formatter := NewTableFormatter()
+	formatter.MaxRows = maxRows
+	return formatter.FormatRelation(rel)
+}
+
 // RelationString returns a string representation of a relation
 func RelationString(rel Relation) string {
 	formatter := NewTableFormatter()
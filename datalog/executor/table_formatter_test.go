@@ -20,6 +20,24 @@ func TestTableFormatter(t *testing.T) {
 		}
 	})
 
+	t.Run("PreviewTableCapsRows", func(t *testing.T) {
+		columns := []query.Symbol{"?n"}
+		tuples := make([]Tuple, 1000)
+		for i := range tuples {
+			tuples[i] = Tuple{int64(i)}
+		}
+		source := NewCountingIterator(NewMaterializedRelation(columns, tuples).Iterator())
+		rel := NewStreamingRelation(columns, source)
+
+		result := PreviewTable(rel, 3)
+		if !strings.Contains(result, "3 rows") || !strings.Contains(result, "first 3 rows") {
+			t.Errorf("Expected a 3 row preview, got %s", result)
+		}
+		if source.Count() > 4 {
+			t.Errorf("Expected at most 4 tuples read, read %d", source.Count())
+		}
+	})
+
 	t.Run("FormatSimpleRelation", func(t *testing.T) {
 		columns := []query.Symbol{"?name", "?age", "?active"}
 		tuples := []Tuple{
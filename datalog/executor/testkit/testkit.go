@@ -0,0 +1,335 @@
+// Package testkit provides assertions for testing code that produces
+// executor relations, such as query results.
+//
+//	want := executor.NewMaterializedRelation(
+//	    []query.Symbol{"?name", "?age"},
+//	    []executor.Tuple{{"Alice", int64(30)}, {"Bob", int64(25)}},
+//	)
+//	testkit.AssertRelationsEqual(t, want, result)
+//
+// Rows are compared as multisets unless Options.Ordered is set, and columns
+// are matched by symbol, so a result with the same columns in another order
+// is equal. Integers compare by value regardless of their Go type (a literal
+// 30 equals int64(30)), and floats may be compared with a tolerance.
+package testkit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// Options controls how relations are compared
+type Options struct {
+	Ordered        bool    // Rows must appear in the same order (e.g. queries with :order-by)
+	FloatTolerance float64 // Maximum absolute difference between floats considered equal
+	MaxDiffRows    int     // Rows listed per section of a diff (default 10)
+}
+
+// AssertRelationsEqual reports a test error with a diff unless got has the
+// same columns and rows as want, in any order. got is consumed, so a
+// streaming relation cannot be iterated again afterwards.
+func AssertRelationsEqual(t testing.TB, want, got executor.Relation) {
+	t.Helper()
+	AssertRelationsEqualWithOptions(t, want, got, Options{})
+}
+
+// AssertRelationsEqualWithOptions is AssertRelationsEqual with explicit
+// comparison options
+func AssertRelationsEqualWithOptions(t testing.TB, want, got executor.Relation, opts Options) {
+	t.Helper()
+	if diff := DiffRelations(want, got, opts); diff != "" {
+		t.Errorf("relations differ:\n%s", diff)
+	}
+}
+
+// DiffRelations compares two relations and describes how got differs from
+// want, or returns "" if they are equal
+func DiffRelations(want, got executor.Relation, opts Options) string {
+	if opts.MaxDiffRows <= 0 {
+		opts.MaxDiffRows = 10
+	}
+	if want == nil || got == nil {
+		if want == nil && got == nil {
+			return ""
+		}
+		return fmt.Sprintf("want %s, got %s", describe(want), describe(got))
+	}
+
+	wantCols, gotCols := want.Symbols(), got.Symbols()
+	perm, ok := columnPermutation(wantCols, gotCols)
+	if !ok {
+		return fmt.Sprintf("columns differ: want %v, got %v", wantCols, gotCols)
+	}
+
+	wantRows := collectRows(want, nil)
+	gotRows := collectRows(got, perm)
+
+	if opts.Ordered {
+		return diffOrdered(wantCols, wantRows, gotRows, opts)
+	}
+	return diffUnordered(wantCols, wantRows, gotRows, opts)
+}
+
+// columnPermutation maps each want column to its index in got
+func columnPermutation(want, got []query.Symbol) ([]int, bool) {
+	if len(want) != len(got) {
+		return nil, false
+	}
+	index := make(map[query.Symbol]int, len(got))
+	for i, col := range got {
+		index[col] = i
+	}
+	perm := make([]int, len(want))
+	for i, col := range want {
+		j, ok := index[col]
+		if !ok {
+			return nil, false
+		}
+		perm[i] = j
+	}
+	return perm, true
+}
+
+// collectRows reads all tuples of rel, reordering columns by perm if given
+func collectRows(rel executor.Relation, perm []int) []executor.Tuple {
+	var rows []executor.Tuple
+	it := rel.Iterator()
+	defer it.Close()
+	for it.Next() {
+		tuple := it.Tuple()
+		row := make(executor.Tuple, len(tuple))
+		for i := range row {
+			if perm != nil {
+				row[i] = tuple[perm[i]]
+			} else {
+				row[i] = tuple[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func diffOrdered(cols []query.Symbol, want, got []executor.Tuple, opts Options) string {
+	var lines []string
+	for i := 0; i < len(want) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			lines = append(lines, fmt.Sprintf("  row %d: missing %s", i, formatRow(want[i])))
+		case i >= len(want):
+			lines = append(lines, fmt.Sprintf("  row %d: unexpected %s", i, formatRow(got[i])))
+		case !rowsEqual(want[i], got[i], opts.FloatTolerance):
+			lines = append(lines, fmt.Sprintf("  row %d: want %s, got %s", i, formatRow(want[i]), formatRow(got[i])))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return header(cols, len(want), len(got)) + strings.Join(truncate(lines, opts.MaxDiffRows), "\n")
+}
+
+func diffUnordered(cols []query.Symbol, want, got []executor.Tuple, opts Options) string {
+	// Pair exactly equal rows by key first, then the rest within tolerance
+	byKey := make(map[string][]int)
+	for i, row := range got {
+		key := rowKey(row)
+		byKey[key] = append(byKey[key], i)
+	}
+	matched := make([]bool, len(got))
+	var missing []executor.Tuple
+	for _, row := range want {
+		key := rowKey(row)
+		if idx := byKey[key]; len(idx) > 0 {
+			matched[idx[0]] = true
+			byKey[key] = idx[1:]
+			continue
+		}
+		missing = append(missing, row)
+	}
+
+	var stillMissing []executor.Tuple
+	for _, row := range missing {
+		found := false
+		for i, candidate := range got {
+			if !matched[i] && rowsEqual(row, candidate, opts.FloatTolerance) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			stillMissing = append(stillMissing, row)
+		}
+	}
+
+	var unexpected []executor.Tuple
+	for i, row := range got {
+		if !matched[i] {
+			unexpected = append(unexpected, row)
+		}
+	}
+	if len(stillMissing) == 0 && len(unexpected) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header(cols, len(want), len(got)))
+	if len(stillMissing) > 0 {
+		fmt.Fprintf(&sb, "  missing %d rows:\n", len(stillMissing))
+		for _, line := range truncate(formatRows(stillMissing), opts.MaxDiffRows) {
+			sb.WriteString("    - " + line + "\n")
+		}
+	}
+	if len(unexpected) > 0 {
+		fmt.Fprintf(&sb, "  unexpected %d rows:\n", len(unexpected))
+		for _, line := range truncate(formatRows(unexpected), opts.MaxDiffRows) {
+			sb.WriteString("    + " + line + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func header(cols []query.Symbol, want, got int) string {
+	return fmt.Sprintf("  columns %v: want %d rows, got %d\n", cols, want, got)
+}
+
+// truncate keeps the first max lines, noting how many were left out
+func truncate(lines []string, max int) []string {
+	if len(lines) <= max {
+		return lines
+	}
+	return append(lines[:max:max], fmt.Sprintf("... and %d more", len(lines)-max))
+}
+
+func rowsEqual(a, b executor.Tuple, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !valuesEqual(a[i], b[i], tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares numbers by value, floats within tolerance, and other
+// values with datalog.ValuesEqual
+func valuesEqual(a, b interface{}, tolerance float64) bool {
+	an, aFloat, aOK := number(a)
+	bn, bFloat, bOK := number(b)
+	if aOK && bOK {
+		if aFloat || bFloat {
+			return an == bn || math.Abs(an-bn) <= tolerance
+		}
+		return integer(a) == integer(b)
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return datalog.ValuesEqual(a, b)
+}
+
+// number returns a numeric value as a float64 and whether it is a float
+func number(v interface{}) (float64, bool, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), false, true
+	case int32:
+		return float64(n), false, true
+	case int64:
+		return float64(n), false, true
+	case uint64:
+		return float64(n), false, true
+	case *uint64:
+		return float64(*n), false, true
+	case float32:
+		return float64(n), true, true
+	case float64:
+		return n, true, true
+	default:
+		return 0, false, false
+	}
+}
+
+// integer returns an integer value exactly, for values number reports as
+// non-float
+func integer(v interface{}) string {
+	switch n := v.(type) {
+	case *uint64:
+		return fmt.Sprint(*n)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+// rowKey returns a key shared by exactly equal rows
+func rowKey(row executor.Tuple) string {
+	var sb strings.Builder
+	for _, v := range row {
+		if _, isFloat, ok := number(v); ok && !isFloat {
+			sb.WriteString("n:" + integer(v))
+		} else {
+			fmt.Fprintf(&sb, "%T:%s", deref(v), formatValue(v))
+		}
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+func formatRows(rows []executor.Tuple) []string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = formatRow(row)
+	}
+	return lines
+}
+
+func formatRow(row executor.Tuple) string {
+	parts := make([]string, len(row))
+	for i, v := range row {
+		parts[i] = formatValue(v)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// formatValue formats a value as it would be written in a query
+func formatValue(v interface{}) string {
+	switch val := deref(v).(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("%q", val)
+	case datalog.Identity:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// deref dereferences interned values
+func deref(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *datalog.Identity:
+		return *val
+	case *datalog.Keyword:
+		return *val
+	case *uint64:
+		return *val
+	default:
+		return v
+	}
+}
+
+func describe(rel executor.Relation) string {
+	if rel == nil {
+		return "nil relation"
+	}
+	return fmt.Sprintf("relation with columns %v", rel.Symbols())
+}
@@ -0,0 +1,138 @@
+package testkit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// recorder captures assertion failures instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func rel(cols string, rows ...executor.Tuple) executor.Relation {
+	var symbols []query.Symbol
+	for _, c := range strings.Fields(cols) {
+		symbols = append(symbols, query.Symbol(c))
+	}
+	return executor.NewMaterializedRelation(symbols, rows)
+}
+
+func TestDiffRelations(t *testing.T) {
+	alice := datalog.NewIdentity("person:alice")
+	a, b := 0.1, 0.2 // Variables, so the sum is not computed exactly at compile time
+
+	tests := []struct {
+		name     string
+		want     executor.Relation
+		got      executor.Relation
+		opts     Options
+		expected []string // Substrings of the diff; nil means equal
+	}{
+		{
+			name: "row order ignored",
+			want: rel("?name ?age", executor.Tuple{"Alice", int64(30)}, executor.Tuple{"Bob", int64(25)}),
+			got:  rel("?name ?age", executor.Tuple{"Bob", int64(25)}, executor.Tuple{"Alice", int64(30)}),
+		},
+		{
+			name: "columns reordered",
+			want: rel("?name ?age", executor.Tuple{"Alice", int64(30)}),
+			got:  rel("?age ?name", executor.Tuple{int64(30), "Alice"}),
+		},
+		{
+			name: "integer types",
+			want: rel("?n", executor.Tuple{30}),
+			got:  rel("?n", executor.Tuple{int64(30)}),
+		},
+		{
+			name: "interned identity",
+			want: rel("?e", executor.Tuple{alice}),
+			got:  rel("?e", executor.Tuple{&alice}),
+		},
+		{
+			name: "float within tolerance",
+			want: rel("?avg", executor.Tuple{0.3}),
+			got:  rel("?avg", executor.Tuple{a + b}),
+			opts: Options{FloatTolerance: 1e-9},
+		},
+		{
+			name:     "float without tolerance",
+			want:     rel("?avg", executor.Tuple{0.3}),
+			got:      rel("?avg", executor.Tuple{a + b}),
+			expected: []string{"missing 1 rows", "- [0.3]", "+ [0.30000000000000004]"},
+		},
+		{
+			name:     "missing row",
+			want:     rel("?x", executor.Tuple{"a"}, executor.Tuple{"b"}),
+			got:      rel("?x", executor.Tuple{"a"}),
+			expected: []string{"want 2 rows, got 1", `- ["b"]`},
+		},
+		{
+			name:     "columns differ",
+			want:     rel("?name ?age", executor.Tuple{"Alice", int64(30)}),
+			got:      rel("?name ?city", executor.Tuple{"Alice", "Paris"}),
+			expected: []string{"columns differ: want [?name ?age], got [?name ?city]"},
+		},
+		{
+			name:     "ordered",
+			want:     rel("?x", executor.Tuple{int64(1)}, executor.Tuple{int64(2)}),
+			got:      rel("?x", executor.Tuple{int64(2)}, executor.Tuple{int64(1)}),
+			opts:     Options{Ordered: true},
+			expected: []string{"row 0: want [1], got [2]", "row 1: want [2], got [1]"},
+		},
+		{
+			name:     "diff truncated",
+			want:     rel("?x"),
+			got:      rel("?x", executor.Tuple{"a"}, executor.Tuple{"b"}, executor.Tuple{"c"}),
+			opts:     Options{MaxDiffRows: 2},
+			expected: []string{"unexpected 3 rows", `+ ["b"]`, "... and 1 more"},
+		},
+		{
+			name:     "nil relation",
+			want:     rel("?x"),
+			expected: []string{"got nil relation"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffRelations(tt.want, tt.got, tt.opts)
+			if tt.expected == nil {
+				if diff != "" {
+					t.Errorf("expected equal relations, got diff:\n%s", diff)
+				}
+				return
+			}
+			for _, s := range tt.expected {
+				if !strings.Contains(diff, s) {
+					t.Errorf("diff missing %q:\n%s", s, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertRelationsEqual(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertRelationsEqual(r, rel("?x", executor.Tuple{"a"}), rel("?x", executor.Tuple{"a"}))
+	if len(r.errors) != 0 {
+		t.Errorf("expected no errors, got %v", r.errors)
+	}
+
+	AssertRelationsEqual(r, rel("?x", executor.Tuple{"a"}), rel("?x", executor.Tuple{"b"}))
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "relations differ:") {
+		t.Errorf("expected one relations differ error, got %v", r.errors)
+	}
+}
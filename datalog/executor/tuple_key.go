@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"reflect"
 	"unsafe"
 
 	"github.com/wbrown/janus-datalog/datalog"
@@ -114,6 +115,23 @@ func hashValue(v interface{}) uint64 {
 	case nil:
 		return 0
 
+	case []interface{}:
+		return hashValues(val)
+
+	case [][]interface{}:
+		rows := make([]interface{}, len(val))
+		for i, row := range val {
+			rows[i] = hashValues(row)
+		}
+		return hashValues(rows)
+
+	case Relation:
+		// Relation-valued inputs are compared by identity
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr {
+			return uint64(rv.Pointer())
+		}
+		return 0
+
 	default:
 		// Fallback: use pointer as hash
 		return uint64(uintptr(unsafe.Pointer(&v)))
@@ -21,6 +21,7 @@ type UnionRelation struct {
 	opts         ExecutorOptions
 	cached       []Tuple // Cache for reuse after first iteration
 	cacheBuilt   bool    // Has cache been built?
+	cacheErr     error   // First error seen while building the cache
 	cacheMutex   sync.Mutex // Protect cache building
 }
 
@@ -39,14 +40,25 @@ func NewUnionRelation(source <-chan relationItem, columns []query.Symbol, opts E
 	}
 }
 
-// Columns returns the column names
-func (ur *UnionRelation) Columns() []query.Symbol {
+// Symbols returns the symbols of the union
+func (ur *UnionRelation) Symbols() []query.Symbol {
 	return ur.columns
 }
 
-// Symbols returns the symbols (same as Columns)
-func (ur *UnionRelation) Symbols() []query.Symbol {
-	return ur.columns
+// Schema returns the relation's symbols with unknown types
+func (ur *UnionRelation) Schema() RelationSchema {
+	return NewRelationSchema(ur.columns)
+}
+
+// Columns returns the relation's symbols.
+// Deprecated: Use Symbols or Schema.
+func (ur *UnionRelation) Columns() []query.Symbol {
+	return ur.Symbols()
+}
+
+// ForEach calls fn for each tuple of the union (see Relation.ForEach)
+func (ur *UnionRelation) ForEach(fn func(Tuple) (bool, error)) error {
+	return forEachTuple(ur, fn)
 }
 
 // Iterator returns an iterator that consumes from the channel (first call) or cache (subsequent calls)
@@ -59,13 +71,16 @@ func (ur *UnionRelation) Iterator() Iterator {
 		return &sliceIterator{
 			tuples: ur.cached,
 			pos:    -1,
+			err:    ur.cacheErr,
 		}
 	}
 
 	// First call - need to consume channel and build cache
 
 	// Create iterator that will build cache as a side effect
-	return NewUnionIteratorWithCache(ur.source, &ur.cached, &ur.cacheBuilt)
+	it := NewUnionIteratorWithCache(ur.source, &ur.cached, &ur.cacheBuilt)
+	it.cacheErr = &ur.cacheErr
+	return it
 }
 
 // Size forces materialization to count tuples (expensive!)
@@ -82,13 +97,27 @@ func (ur *UnionRelation) IsEmpty() bool {
 }
 
 // Get forces materialization (expensive!)
+//
+// Deprecated: Get materializes every branch of the union. Iterate with
+// Iterator() instead.
 func (ur *UnionRelation) Get(i int) Tuple {
 	return ur.Materialize().Get(i)
 }
 
-// String returns a string representation
+func (ur *UnionRelation) knownSize() (int, bool) {
+	ur.cacheMutex.Lock()
+	defer ur.cacheMutex.Unlock()
+	if ur.cacheBuilt {
+		return len(ur.cached), true
+	}
+	return -1, false
+}
+
+// String returns a string representation. The branches are not evaluated,
+// so the size is only shown once the union has been iterated.
 func (ur *UnionRelation) String() string {
-	return ur.Materialize().String()
+	size, _ := ur.knownSize()
+	return relationString(ur.columns, size)
 }
 
 // Table returns a formatted table
@@ -112,20 +141,31 @@ func (ur *UnionRelation) Project(columns []query.Symbol) (Relation, error) {
 }
 
 // Materialize forces consumption of all relations and returns a materialized result
-// Note: This doesn't return errors because Relation.Materialize() doesn't have error return
-// Errors from Close() are silently dropped (limitation of Relation interface)
+// Note: Relation.Materialize() has no error return, so a failed branch yields
+// an empty relation that reports the error from Err and its iterator
 func (ur *UnionRelation) Materialize() Relation {
 	var allTuples []Tuple
 	it := ur.Iterator()
-	defer it.Close() // Errors silently dropped
+	defer it.Close()
 
 	for it.Next() {
 		allTuples = append(allTuples, it.Tuple())
 	}
+	if err := it.Err(); err != nil {
+		return newFailedRelation(ur.columns, err, ur.opts)
+	}
 
 	return NewMaterializedRelation(ur.columns, allTuples)
 }
 
+// Err returns the first error of the union's branches, once they have been
+// consumed by an iterator
+func (ur *UnionRelation) Err() error {
+	ur.cacheMutex.Lock()
+	defer ur.cacheMutex.Unlock()
+	return ur.cacheErr
+}
+
 // Sort returns a sorted relation (forces materialization)
 func (ur *UnionRelation) Sort(orderBy []query.OrderByClause) Relation {
 	return ur.Materialize().Sort(orderBy)
@@ -193,6 +233,7 @@ type UnionIterator struct {
 	firstError   error // Track first error encountered
 	cache        *[]Tuple // Pointer to cache to build
 	cacheBuilt   *bool    // Pointer to flag
+	cacheErr     *error   // Where to record firstError once exhausted, if set
 }
 
 // NewUnionIteratorWithCache creates a new union iterator that builds cache as it iterates
@@ -237,6 +278,9 @@ func (it *UnionIterator) Next() bool {
 
 		// Current iterator exhausted - close it and get next relation
 		if it.currentIter != nil {
+			if err := it.currentIter.Err(); err != nil && it.firstError == nil {
+				it.firstError = err
+			}
 			it.currentIter.Close()
 			it.currentIter = nil
 		}
@@ -251,6 +295,9 @@ func (it *UnionIterator) Next() bool {
 			if it.cacheBuilt != nil {
 				*it.cacheBuilt = true
 			}
+			if it.cacheErr != nil {
+				*it.cacheErr = it.firstError
+			}
 
 			return false
 		}
@@ -282,6 +329,11 @@ func (it *UnionIterator) Tuple() Tuple {
 	return it.currentTuple
 }
 
+// Err returns the first error of the consumed relations
+func (it *UnionIterator) Err() error {
+	return it.firstError
+}
+
 // Close releases resources
 func (it *UnionIterator) Close() error {
 	if it.currentIter != nil {
@@ -0,0 +1,34 @@
+package datalog
+
+import "fmt"
+
+// EntityRef identifies an entity wherever one is accepted: either an Identity
+// or a LookupRef that is resolved to one.
+type EntityRef interface {
+	isEntityRef()
+}
+
+func (Identity) isEntityRef()  {}
+func (LookupRef) isEntityRef() {}
+
+// LookupRef identifies an entity by an attribute value that is unique to it,
+// written [:person/email "a@b.com"] in queries. It is resolved through the
+// AVET index to the single entity holding that value; a value held by no
+// entity or by several does not resolve.
+type LookupRef struct {
+	Attr  Keyword
+	Value interface{}
+}
+
+// NewLookupRef creates a lookup ref for an attribute value
+func NewLookupRef(attr string, value interface{}) LookupRef {
+	return LookupRef{Attr: NewKeyword(attr), Value: value}
+}
+
+// String returns the lookup ref in EDN form, e.g. [:person/email "a@b.com"]
+func (r LookupRef) String() string {
+	if s, ok := r.Value.(string); ok {
+		return fmt.Sprintf("[%s %q]", r.Attr, s)
+	}
+	return fmt.Sprintf("[%s %v]", r.Attr, r.Value)
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestParseParameterizedAggregates(t *testing.T) {
+	q, err := ParseQuery(`[:find ?svc (percentile 0.99 ?latency) (histogram ?latency 50)
+	                       :where [?r :request/service ?svc] [?r :request/latency ?latency]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	percentile := q.Find[1].(query.FindAggregate)
+	if percentile.Function != "percentile" || percentile.Arg != "?latency" || percentile.Param != 0.99 {
+		t.Errorf("unexpected percentile aggregate %#v", percentile)
+	}
+	histogram := q.Find[2].(query.FindAggregate)
+	if histogram.Function != "histogram" || histogram.Arg != "?latency" || histogram.Param != int64(50) {
+		t.Errorf("unexpected histogram aggregate %#v", histogram)
+	}
+
+	if percentile.String() != "(percentile 0.99 ?latency)" || histogram.String() != "(histogram ?latency 50)" {
+		t.Errorf("unexpected aggregate strings %s, %s", percentile, histogram)
+	}
+
+	// Integer quantiles are accepted as floats
+	q, err = ParseQuery(`[:find (percentile 1 ?x) :where [_ :a ?x]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := q.Find[0].(query.FindAggregate).Param; p != 1.0 {
+		t.Errorf("expected quantile 1.0, got %#v", p)
+	}
+}
+
+func TestParseStringAgg(t *testing.T) {
+	q, err := ParseQuery(`[:find ?team (string-agg ?name ", ") (string-agg ?name "/" :desc)
+	                       :where [?p :person/team ?team] [?p :person/name ?name]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	asc := q.Find[1].(query.FindAggregate)
+	if asc.Function != "string-agg" || asc.Arg != "?name" || asc.Param != ", " || asc.Descending {
+		t.Errorf("unexpected string-agg aggregate %#v", asc)
+	}
+	desc := q.Find[2].(query.FindAggregate)
+	if desc.Param != "/" || !desc.Descending {
+		t.Errorf("unexpected descending string-agg aggregate %#v", desc)
+	}
+
+	if asc.String() != `(string-agg ?name ", ")` || desc.String() != `(string-agg ?name "/" :desc)` {
+		t.Errorf("unexpected aggregate strings %s, %s", asc, desc)
+	}
+
+	reparsed, err := ParseQuery(FormatQuery(q))
+	if err != nil {
+		t.Fatalf("failed to reparse formatted query: %v", err)
+	}
+	if reparsed.Find[2].String() != desc.String() {
+		t.Errorf("round trip changed aggregate to %s", reparsed.Find[2])
+	}
+}
+
+func TestParseParameterizedAggregateErrors(t *testing.T) {
+	tests := map[string]string{
+		`[:find (percentile 1.5 ?x) :where [_ :a ?x]]`:     "percentile must be between 0 and 1",
+		`[:find (percentile ?x 0.5) :where [_ :a ?x]]`:     "percentile argument must be a variable",
+		`[:find (percentile "p" ?x) :where [_ :a ?x]]`:     "percentile must be a number",
+		`[:find (histogram ?x 0) :where [_ :a ?x]]`:        "bucket width must be positive",
+		`[:find (histogram ?x ?w) :where [_ :a ?x ?w]]`:    "histogram parameter must be a number",
+		`[:find (sum ?x 10) :where [_ :a ?x]]`:             "takes exactly one argument",
+		`[:find (string-agg ?x 1) :where [_ :a ?x]]`:       "separator must be a string",
+		`[:find (string-agg ?x "," :up) :where [_ :a ?x]]`: "order must be :asc or :desc",
+		`[:find (histogram ?x 10 :desc) :where [_ :a ?x]]`: "takes exactly two arguments",
+	}
+	for input, expected := range tests {
+		_, err := ParseQuery(input)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", input, expected, err)
+		}
+	}
+}
@@ -42,6 +42,13 @@ func TestParseExpressionPatterns(t *testing.T) {
 			wantArgs: 1,
 			wantBind: "?y",
 		},
+		{
+			name:     "boolean negation",
+			input:    `[:find ?x ?disabled :where [?x :flag/enabled ?enabled] [(not ?enabled) ?disabled]]`,
+			wantFunc: "not",
+			wantArgs: 1,
+			wantBind: "?disabled",
+		},
 		{
 			name:     "complex arithmetic",
 			input:    `[:find ?result :where [?x :value ?v] [(* ?v 2) ?temp] [(+ ?temp 10) ?result]]`,
@@ -102,6 +109,13 @@ func TestParseExpressionPatterns(t *testing.T) {
 				if tt.wantArgs != 1 {
 					t.Errorf("Identity function should have 1 arg")
 				}
+			case *query.NotFunction:
+				if tt.wantFunc != "not" {
+					t.Errorf("Expected not function")
+				}
+				if tt.wantArgs != 1 {
+					t.Errorf("Not function should have 1 arg")
+				}
 			default:
 				t.Errorf("Unexpected function type: %T", fn)
 			}
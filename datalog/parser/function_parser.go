@@ -2,6 +2,8 @@ package parser
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -18,6 +20,8 @@ func parseFunction(fn string, args []query.PatternElement) (query.Function, erro
 		return parseGroundFunction(args)
 	case "identity":
 		return parseIdentity(args)
+	case "not":
+		return parseNot(args)
 	default:
 		return nil, fmt.Errorf("unsupported function: %s", fn)
 	}
@@ -64,16 +68,42 @@ func parseStringConcat(args []query.PatternElement) (query.Function, error) {
 	}, nil
 }
 
-// parseTimeExtraction handles time extraction functions
+// parseTimeExtraction handles time extraction functions, with an optional
+// time zone name: [(day ?t "America/New_York") ?d]
 func parseTimeExtraction(field string, args []query.PatternElement) (query.Function, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("%s requires exactly 1 argument, got %d", field, len(args))
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("%s requires 1 or 2 arguments, got %d", field, len(args))
 	}
 
-	return &query.TimeExtractionFunction{
+	fn := &query.TimeExtractionFunction{
 		Field:    field,
 		TimeTerm: elementToTerm(args[0]),
-	}, nil
+	}
+	if len(args) == 2 {
+		c, ok := args[1].(query.Constant)
+		if !ok {
+			return nil, fmt.Errorf("%s time zone must be a string, got %s", field, args[1])
+		}
+		name, ok := c.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s time zone must be a string, got %v", field, c.Value)
+		}
+		loc, err := parseTimeZone(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		fn.Location = loc
+	}
+	return fn, nil
+}
+
+// parseTimeZone loads a time zone by its IANA name, such as "America/New_York"
+func parseTimeZone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	return loc, nil
 }
 
 // parseGroundFunction handles ground function - binds a constant value
@@ -103,6 +133,17 @@ func parseIdentity(args []query.PatternElement) (query.Function, error) {
 	}, nil
 }
 
+// parseNot handles the not function - negates a boolean value
+func parseNot(args []query.PatternElement) (query.Function, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("not requires exactly 1 argument, got %d", len(args))
+	}
+
+	return &query.NotFunction{
+		Arg: elementToTerm(args[0]),
+	}, nil
+}
+
 // parseAggregate creates an AggregateFunction from a function name and variable
 func parseAggregate(fn string, varName query.Symbol) (query.AggregateFunction, error) {
 	switch fn {
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestParseLookupRef(t *testing.T) {
+	q, err := ParseQuery(`[:find ?name :where [[:person/email "a@b.com"] :person/name ?name]]`)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+
+	pattern := q.Where[0].(*query.DataPattern)
+	c, ok := pattern.GetE().(query.Constant)
+	if !ok {
+		t.Fatalf("Expected constant entity, got %T", pattern.GetE())
+	}
+	ref, ok := c.Value.(datalog.LookupRef)
+	if !ok {
+		t.Fatalf("Expected lookup ref, got %T", c.Value)
+	}
+	if ref.Attr != datalog.NewKeyword(":person/email") || ref.Value != "a@b.com" {
+		t.Errorf("Unexpected lookup ref %v", ref)
+	}
+	if pattern.String() != `[[:person/email "a@b.com"] :person/name ?name]` {
+		t.Errorf("Unexpected pattern string %s", pattern)
+	}
+
+	reparsed, err := ParseQuery(FormatQuery(q))
+	if err != nil {
+		t.Fatalf("Failed to reparse formatted query: %v", err)
+	}
+	if reparsed.Where[0].String() != pattern.String() {
+		t.Errorf("Round trip changed pattern: %s", reparsed.Where[0])
+	}
+
+	for _, input := range []string{
+		`[:find ?name :where [[:person/email] :person/name ?name]]`,
+		`[:find ?name :where [["a@b.com" :person/email] :person/name ?name]]`,
+		`[:find ?name :where [[:person/email ?email] :person/name ?name]]`,
+	} {
+		if _, err := ParseQuery(input); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/edn"
@@ -11,6 +12,16 @@ import (
 )
 
 // ParseQuery parses a Datalog query from EDN format
+//
+// Two forms are accepted:
+//   - the query vector: [:find ... :where ...]
+//   - the options map: {:query [:find ...] :timeout 5000 :offset 10 :limit 100}
+//
+// In the map form, :timeout is in milliseconds, :offset skips result tuples and
+// :limit caps the number of result tuples (-1 means no limit). Aggregate
+// queries may also set :grouping-sets [[?a ?b] [?a] []] to aggregate at
+// several grouping levels, or :rollup true for every prefix of the :find
+// variables.
 func ParseQuery(input string) (*query.Query, error) {
 	// Parse as EDN first
 	node, err := edn.Parse(input)
@@ -18,12 +29,192 @@ func ParseQuery(input string) (*query.Query, error) {
 		return nil, fmt.Errorf("EDN parse error: %w", err)
 	}
 
-	// Must be a vector
-	if node.Type != edn.NodeVector {
-		return nil, fmt.Errorf("query must be a vector, got %v", node.Type)
+	return parseQueryNode(node)
+}
+
+// parseQueryNode parses a top-level query in either vector or map form
+func parseQueryNode(node *edn.Node) (*query.Query, error) {
+	switch node.Type {
+	case edn.NodeVector:
+		return parseQueryVector(node)
+	case edn.NodeMap:
+		return parseQueryMap(node)
+	default:
+		return nil, fmt.Errorf("query must be a vector or map, got %v", node.Type)
+	}
+}
+
+// parseQueryMap parses the options map form {:query [...] :timeout ms :offset n :limit n}
+func parseQueryMap(node *edn.Node) (*query.Query, error) {
+	var queryNode *edn.Node
+	var timeZone *time.Location
+	var timeout, offset, limit int64
+	hasLimit := false
+	var groupingSets [][]query.Symbol
+	rollup := false
+
+	for i := 0; i+1 < len(node.Nodes); i += 2 {
+		key := &node.Nodes[i]
+		value := &node.Nodes[i+1]
+		if key.Type != edn.NodeKeyword {
+			return nil, fmt.Errorf("query map keys must be keywords, got %v", key.Type)
+		}
+
+		var err error
+		switch key.Value {
+		case ":query":
+			if value.Type != edn.NodeVector {
+				return nil, fmt.Errorf(":query must be a vector, got %v", value.Type)
+			}
+			queryNode = value
+		case ":timeout":
+			timeout, err = parseQueryOption(key.Value, value, 0)
+		case ":offset":
+			offset, err = parseQueryOption(key.Value, value, 0)
+		case ":limit":
+			limit, err = parseQueryOption(key.Value, value, -1)
+			hasLimit = true
+		case ":grouping-sets":
+			groupingSets, err = parseGroupingSets(value)
+		case ":rollup":
+			if value.Type != edn.NodeBool {
+				return nil, fmt.Errorf(":rollup must be a boolean, got %v", value.Type)
+			}
+			rollup = value.Value == "true"
+		case ":time-zone":
+			if value.Type != edn.NodeString {
+				return nil, fmt.Errorf(":time-zone must be a string, got %v", value.Type)
+			}
+			var name string
+			if name, err = value.AsString(); err == nil {
+				timeZone, err = parseTimeZone(name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown query option: %s", key.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if queryNode == nil {
+		return nil, fmt.Errorf("query map must contain :query")
+	}
+	if hasLimit && limit == 0 {
+		return nil, fmt.Errorf(":limit must be positive or -1 for no limit")
+	}
+
+	q, err := parseQueryVector(queryNode)
+	if err != nil {
+		return nil, err
+	}
+
+	q.Timeout = time.Duration(timeout) * time.Millisecond
+	q.Offset = int(offset)
+	if timeZone != nil {
+		// Time extractions without a zone of their own use the query's
+		query.SetDefaultTimeZone(q, timeZone)
+	}
+	if limit > 0 {
+		q.Limit = int(limit)
+	}
+
+	if rollup {
+		if groupingSets != nil {
+			return nil, fmt.Errorf(":rollup and :grouping-sets cannot be combined")
+		}
+		groupingSets = rollupGroupingSets(q.Find)
+	}
+	if groupingSets != nil {
+		if err := validateGroupingSets(q.Find, groupingSets); err != nil {
+			return nil, err
+		}
+		q.GroupingSets = groupingSets
+	}
+	return q, nil
+}
+
+// parseGroupingSets parses :grouping-sets, a vector of variable vectors
+func parseGroupingSets(node *edn.Node) ([][]query.Symbol, error) {
+	if node.Type != edn.NodeVector || len(node.Nodes) == 0 {
+		return nil, fmt.Errorf(":grouping-sets must be a non-empty vector of variable vectors")
+	}
+
+	sets := make([][]query.Symbol, len(node.Nodes))
+	for i := range node.Nodes {
+		setNode := &node.Nodes[i]
+		if setNode.Type != edn.NodeVector {
+			return nil, fmt.Errorf("grouping set %d must be a vector, got %v", i, setNode.Type)
+		}
+		sets[i] = []query.Symbol{}
+		for _, varNode := range setNode.Nodes {
+			sym := query.Symbol(varNode.Value)
+			if varNode.Type != edn.NodeSymbol || !sym.IsVariable() {
+				return nil, fmt.Errorf("grouping set %d must contain variables, got %s", i, varNode.Value)
+			}
+			sets[i] = append(sets[i], sym)
+		}
+	}
+	return sets, nil
+}
+
+// rollupGroupingSets returns the grouping sets of :rollup: every prefix of
+// the :find variables, from all of them down to the grand total
+func rollupGroupingSets(find []query.FindElement) [][]query.Symbol {
+	var vars []query.Symbol
+	for _, elem := range find {
+		if v, ok := elem.(query.FindVariable); ok {
+			vars = append(vars, v.Symbol)
+		}
 	}
 
-	return parseQueryVector(node)
+	sets := make([][]query.Symbol, 0, len(vars)+1)
+	for n := len(vars); n >= 0; n-- {
+		sets = append(sets, vars[:n:n])
+	}
+	return sets
+}
+
+// validateGroupingSets checks that grouping sets only group on :find
+// variables of an aggregate query
+func validateGroupingSets(find []query.FindElement, sets [][]query.Symbol) error {
+	findVars := make(map[query.Symbol]bool)
+	hasAggregate := false
+	for _, elem := range find {
+		switch e := elem.(type) {
+		case query.FindVariable:
+			findVars[e.Symbol] = true
+		case query.FindAggregate:
+			hasAggregate = true
+		}
+	}
+	if !hasAggregate {
+		return fmt.Errorf("grouping sets require an aggregate in :find")
+	}
+
+	for i, set := range sets {
+		for _, sym := range set {
+			if !findVars[sym] {
+				return fmt.Errorf("grouping set %d variable %s is not a :find variable", i, sym)
+			}
+		}
+	}
+	return nil
+}
+
+// parseQueryOption parses an integer query option value, enforcing a minimum
+func parseQueryOption(name string, node *edn.Node, min int64) (int64, error) {
+	if node.Type != edn.NodeInt {
+		return 0, fmt.Errorf("%s must be an integer, got %v", name, node.Type)
+	}
+	val, err := strconv.ParseInt(node.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if val < min {
+		return 0, fmt.Errorf("%s must be >= %d, got %d", name, min, val)
+	}
+	return val, nil
 }
 
 // parseQueryVector parses a query from an EDN vector node
@@ -178,6 +369,12 @@ func parseFindElement(node *edn.Node) (query.FindElement, error) {
 		return query.FindVariable{Symbol: sym}, nil
 
 	case edn.NodeList:
+		// Parameterized aggregates (percentile 0.99 ?x), (histogram ?x 10),
+		// (string-agg ?x ", ") and (string-agg ?x ", " :desc)
+		if len(node.Nodes) == 3 || len(node.Nodes) == 4 {
+			return parseParameterizedAggregate(node)
+		}
+
 		// Aggregate function (sum ?x), (count ?x), etc.
 		if len(node.Nodes) != 2 {
 			return nil, fmt.Errorf("aggregate function must have exactly 2 elements: function and argument")
@@ -216,6 +413,95 @@ func parseFindElement(node *edn.Node) (query.FindElement, error) {
 	}
 }
 
+// parseParameterizedAggregate parses an aggregate taking a constant argument:
+// (percentile q ?x) with 0 <= q <= 1, (histogram ?x bucket-width), or
+// (string-agg ?x separator) with an optional :asc or :desc element order
+func parseParameterizedAggregate(node *edn.Node) (query.FindElement, error) {
+	if node.Nodes[0].Type != edn.NodeSymbol {
+		return nil, fmt.Errorf("aggregate function name must be a symbol")
+	}
+	fn := node.Nodes[0].Value
+
+	var argNode, paramNode *edn.Node
+	switch fn {
+	case "percentile":
+		paramNode, argNode = &node.Nodes[1], &node.Nodes[2]
+	case "histogram", "string-agg":
+		argNode, paramNode = &node.Nodes[1], &node.Nodes[2]
+	default:
+		return nil, fmt.Errorf("aggregate function %s takes exactly one argument", fn)
+	}
+	if len(node.Nodes) == 4 && fn != "string-agg" {
+		return nil, fmt.Errorf("aggregate function %s takes exactly two arguments", fn)
+	}
+
+	if argNode.Type != edn.NodeSymbol || !query.Symbol(argNode.Value).IsVariable() {
+		return nil, fmt.Errorf("%s argument must be a variable", fn)
+	}
+
+	param, err := parsePatternElement(paramNode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter: %w", fn, err)
+	}
+	constant, ok := param.(query.Constant)
+	if !ok && fn == "string-agg" {
+		return nil, fmt.Errorf("string-agg separator must be a string")
+	} else if !ok {
+		return nil, fmt.Errorf("%s parameter must be a number", fn)
+	}
+
+	descending := false
+	switch fn {
+	case "percentile":
+		var q float64
+		switch v := constant.Value.(type) {
+		case float64:
+			q = v
+		case int64:
+			q = float64(v)
+		default:
+			return nil, fmt.Errorf("percentile must be a number, got %v", constant.Value)
+		}
+		if q < 0 || q > 1 {
+			return nil, fmt.Errorf("percentile must be between 0 and 1, got %v", q)
+		}
+		constant.Value = q
+	case "histogram":
+		var width float64
+		switch v := constant.Value.(type) {
+		case float64:
+			width = v
+		case int64:
+			width = float64(v)
+		default:
+			return nil, fmt.Errorf("histogram bucket width must be a number, got %v", constant.Value)
+		}
+		if width <= 0 {
+			return nil, fmt.Errorf("histogram bucket width must be positive, got %v", constant.Value)
+		}
+	case "string-agg":
+		if _, ok := constant.Value.(string); !ok {
+			return nil, fmt.Errorf("string-agg separator must be a string, got %v", constant.Value)
+		}
+		if len(node.Nodes) == 4 {
+			switch order := &node.Nodes[3]; {
+			case order.Type == edn.NodeKeyword && order.Value == ":asc":
+			case order.Type == edn.NodeKeyword && order.Value == ":desc":
+				descending = true
+			default:
+				return nil, fmt.Errorf("string-agg order must be :asc or :desc, got %s", order.Value)
+			}
+		}
+	}
+
+	return query.FindAggregate{
+		Function:   fn,
+		Arg:        query.Symbol(argNode.Value),
+		Param:      constant.Value,
+		Descending: descending,
+	}, nil
+}
+
 // parsePattern parses a pattern from an EDN vector
 func parsePattern(node *edn.Node) (query.Clause, error) {
 	if node.Type != edn.NodeVector {
@@ -252,17 +538,35 @@ func parsePattern(node *edn.Node) (query.Clause, error) {
 		}
 	}
 
-	// Otherwise it's a data pattern
-	if len(node.Nodes) < 3 || len(node.Nodes) > 4 {
-		return nil, fmt.Errorf("data pattern must have 3 or 4 elements, got %d", len(node.Nodes))
+	// Otherwise it's a data pattern, optionally ending with a hint map
+	elements := node.Nodes
+	var hints *edn.Node
+	if len(elements) > 0 && elements[len(elements)-1].Type == edn.NodeMap {
+		hints = &elements[len(elements)-1]
+		elements = elements[:len(elements)-1]
+	}
+	if len(elements) < 3 || len(elements) > 4 {
+		return nil, fmt.Errorf("data pattern must have 3 or 4 elements, got %d", len(elements))
 	}
 
 	pattern := &query.DataPattern{
-		Elements: make([]query.PatternElement, len(node.Nodes)),
+		Elements: make([]query.PatternElement, len(elements)),
+	}
+	if hints != nil {
+		if err := parsePatternHints(hints, pattern); err != nil {
+			return nil, err
+		}
 	}
 
-	for i, elem := range node.Nodes {
-		patternElem, err := parsePatternElement(&elem)
+	for i, elem := range elements {
+		var patternElem query.PatternElement
+		var err error
+		if i == 0 && elem.Type == edn.NodeVector {
+			// Entity given by lookup ref: [[:person/email "a@b.com"] ...]
+			patternElem, err = parseLookupRef(&elem)
+		} else {
+			patternElem, err = parsePatternElement(&elem)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("error parsing pattern element %d: %w", i, err)
 		}
@@ -272,6 +576,51 @@ func parsePattern(node *edn.Node) (query.Clause, error) {
 	return pattern, nil
 }
 
+// parseLookupRef parses a lookup ref [:attr value] as a constant entity
+func parseLookupRef(node *edn.Node) (query.PatternElement, error) {
+	if len(node.Nodes) != 2 || node.Nodes[0].Type != edn.NodeKeyword {
+		return nil, fmt.Errorf("lookup ref must be [attribute value], got %d elements", len(node.Nodes))
+	}
+	value, err := parsePatternElement(&node.Nodes[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid lookup ref value: %w", err)
+	}
+	c, ok := value.(query.Constant)
+	if !ok {
+		return nil, fmt.Errorf("lookup ref value must be a constant, got %s", value)
+	}
+	return query.Constant{Value: datalog.LookupRef{
+		Attr:  datalog.NewKeyword(node.Nodes[0].Value),
+		Value: c.Value,
+	}}, nil
+}
+
+// parsePatternHints parses a data pattern hint map such as {:max-datoms 1000}
+func parsePatternHints(node *edn.Node, pattern *query.DataPattern) error {
+	if len(node.Nodes)%2 != 0 {
+		return fmt.Errorf("pattern hints must be key/value pairs")
+	}
+	for i := 0; i+1 < len(node.Nodes); i += 2 {
+		key := &node.Nodes[i]
+		value := &node.Nodes[i+1]
+		if key.Type != edn.NodeKeyword {
+			return fmt.Errorf("pattern hint keys must be keywords, got %v", key.Type)
+		}
+
+		switch key.Value {
+		case ":max-datoms":
+			maxDatoms, err := parseQueryOption(key.Value, value, 1)
+			if err != nil {
+				return err
+			}
+			pattern.MaxDatoms = int(maxDatoms)
+		default:
+			return fmt.Errorf("unknown pattern hint: %s", key.Value)
+		}
+	}
+	return nil
+}
+
 // tryParsePredicate attempts to parse a node as a concrete Predicate type
 func tryParsePredicate(node *edn.Node) (query.Predicate, error) {
 	if node.Type != edn.NodeList {
@@ -289,6 +638,11 @@ func tryParsePredicate(node *edn.Node) (query.Predicate, error) {
 
 	fn := node.Nodes[0].Value
 
+	// in takes a set literal, which isn't a pattern element
+	if fn == "in" {
+		return parseIn(node.Nodes[1:])
+	}
+
 	// Parse arguments as PatternElements first
 	args := make([]query.PatternElement, len(node.Nodes)-1)
 	for i := 1; i < len(node.Nodes); i++ {
@@ -584,11 +938,54 @@ func parsePatternElement(node *edn.Node) (query.PatternElement, error) {
 		val := node.Value == "true"
 		return query.Constant{Value: val}, nil
 
+	case edn.NodeTagged:
+		// Timestamps: #inst "2025-01-02T09:30:00Z"
+		if node.Tag != "inst" {
+			return nil, fmt.Errorf("unsupported tagged literal: #%s", node.Tag)
+		}
+		val, err := node.AsInst()
+		if err != nil {
+			return nil, err
+		}
+		return query.Constant{Value: val}, nil
+
 	default:
 		return nil, fmt.Errorf("unsupported pattern element type: %v", node.Type)
 	}
 }
 
+// ParseValue parses a single literal as a datom value, with the syntax of
+// constants in queries: keywords, strings, integers, floats, booleans and
+// #inst timestamps. Symbols such as bare words are rejected, so "123abc" is
+// an error rather than 123.
+func ParseValue(input string) (interface{}, error) {
+	lexer := edn.NewLexer(input)
+	if err := lexer.Lex(); err != nil {
+		return nil, fmt.Errorf("EDN lex error: %w", err)
+	}
+	nodes, err := edn.NewParser(lexer).ParseAll()
+	if err != nil {
+		return nil, fmt.Errorf("EDN parse error: %w", err)
+	}
+	if len(nodes) != 1 {
+		return nil, fmt.Errorf("expected a single value, got %d", len(nodes))
+	}
+
+	return ParseValueNode(&nodes[0])
+}
+
+// ParseValueNode converts a parsed EDN literal to a datom value (see ParseValue)
+func ParseValueNode(node *edn.Node) (interface{}, error) {
+	if node.Type == edn.NodeSymbol {
+		return nil, fmt.Errorf("%s is not a value; quote strings, e.g. %q", node.Value, node.Value)
+	}
+	elem, err := parsePatternElement(node)
+	if err != nil {
+		return nil, err
+	}
+	return elem.(query.Constant).Value, nil
+}
+
 // ParseMultipleQueries parses multiple queries from a single input
 func ParseMultipleQueries(input string) ([]*query.Query, error) {
 	lexer := edn.NewLexer(input)
@@ -604,10 +1001,7 @@ func ParseMultipleQueries(input string) ([]*query.Query, error) {
 
 	var queries []*query.Query
 	for i, node := range nodes {
-		if node.Type != edn.NodeVector {
-			return nil, fmt.Errorf("query %d must be a vector, got %v", i, node.Type)
-		}
-		q, err := parseQueryVector(&node)
+		q, err := parseQueryNode(&node)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing query %d: %w", i, err)
 		}
@@ -694,8 +1088,46 @@ func ValidateQuery(q *query.Query) error {
 }
 
 // FormatQuery formats a query as a readable string in EDN format
+// Queries with execution options are formatted in the map form.
 func FormatQuery(q *query.Query) string {
-	return formatQueryWithIndent(q, "")
+	if !q.HasOptions() {
+		return formatQueryWithIndent(q, "")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{:query ")
+	sb.WriteString(formatQueryWithIndent(q, "        "))
+	if q.Timeout > 0 {
+		sb.WriteString("\n :timeout ")
+		sb.WriteString(strconv.FormatInt(q.Timeout.Milliseconds(), 10))
+	}
+	if q.Offset > 0 {
+		sb.WriteString("\n :offset ")
+		sb.WriteString(strconv.Itoa(q.Offset))
+	}
+	if q.Limit > 0 {
+		sb.WriteString("\n :limit ")
+		sb.WriteString(strconv.Itoa(q.Limit))
+	}
+	if len(q.GroupingSets) > 0 {
+		sb.WriteString("\n :grouping-sets [")
+		for i, set := range q.GroupingSets {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			sb.WriteString("[")
+			for j, sym := range set {
+				if j > 0 {
+					sb.WriteString(" ")
+				}
+				sb.WriteString(string(sym))
+			}
+			sb.WriteString("]")
+		}
+		sb.WriteString("]")
+	}
+	sb.WriteString("}")
+	return sb.String()
 }
 
 // formatQueryWithIndent formats a query with a given indentation prefix
@@ -846,6 +1278,13 @@ func formatValue(sb *strings.Builder, v interface{}) {
 	case datalog.Keyword:
 		sb.WriteString(val.String())
 
+	case datalog.LookupRef:
+		sb.WriteString("[")
+		sb.WriteString(val.Attr.String())
+		sb.WriteString(" ")
+		formatValue(sb, val.Value)
+		sb.WriteString("]")
+
 	case datalog.Identity:
 		// For entity references in queries, use the original string representation
 		// wrapped in a custom reader tag for clarity
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestParsePatternMaxDatoms(t *testing.T) {
+	q, err := ParseQuery(`[:find ?e ?v :where [?e :unknown/attr ?v {:max-datoms 1000}] [?e :person/name _]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pattern := q.Where[0].(*query.DataPattern)
+	if len(pattern.Elements) != 3 {
+		t.Fatalf("expected 3 pattern elements, got %d", len(pattern.Elements))
+	}
+	if pattern.MaxDatoms != 1000 {
+		t.Errorf("expected MaxDatoms 1000, got %d", pattern.MaxDatoms)
+	}
+	if other := q.Where[1].(*query.DataPattern); other.MaxDatoms != 0 {
+		t.Errorf("expected no limit on unhinted pattern, got %d", other.MaxDatoms)
+	}
+
+	// The hint round-trips through String()
+	if s := pattern.String(); s != "[?e :unknown/attr ?v {:max-datoms 1000}]" {
+		t.Errorf("unexpected pattern string %s", s)
+	}
+	reparsed, err := ParseQuery(q.String())
+	if err != nil {
+		t.Fatalf("failed to reparse %s: %v", q.String(), err)
+	}
+	if reparsed.Where[0].(*query.DataPattern).MaxDatoms != 1000 {
+		t.Errorf("hint lost when reparsing %s", q.String())
+	}
+
+	// Hints also follow a tx element
+	q, err = ParseQuery(`[:find ?e :where [?e :person/name _ ?tx {:max-datoms 5}]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pattern := q.Where[0].(*query.DataPattern); len(pattern.Elements) != 4 || pattern.MaxDatoms != 5 {
+		t.Errorf("expected 4 elements with MaxDatoms 5, got %v", pattern)
+	}
+}
+
+func TestParsePatternHintErrors(t *testing.T) {
+	tests := map[string]string{
+		`[:find ?e :where [?e :a ?v {:max-datoms 0}]]`:   ":max-datoms must be >= 1",
+		`[:find ?e :where [?e :a ?v {:max-datoms "x"}]]`: ":max-datoms must be an integer",
+		`[:find ?e :where [?e :a ?v {:sample 10}]]`:      "unknown pattern hint: :sample",
+		`[:find ?e :where [?e :a {:max-datoms 10}]]`:     "data pattern must have 3 or 4 elements",
+	}
+	for input, expected := range tests {
+		_, err := ParseQuery(input)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", input, expected, err)
+		}
+	}
+}
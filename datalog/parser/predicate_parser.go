@@ -2,6 +2,8 @@ package parser
 
 import (
 	"fmt"
+
+	"github.com/wbrown/janus-datalog/datalog/edn"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -164,6 +166,42 @@ func parseMissing(args []query.PatternElement) (query.Predicate, error) {
 	}, nil
 }
 
+// parseIn handles [(in ?x #{a b c})] membership predicates
+func parseIn(args []edn.Node) (query.Predicate, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("in requires a variable and a set, got %d arguments", len(args))
+	}
+	elem, err := parsePatternElement(&args[0])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing in variable: %w", err)
+	}
+	v, ok := elem.(query.Variable)
+	if !ok {
+		return nil, fmt.Errorf("in requires a variable, got %s", elem)
+	}
+	if args[1].Type != edn.NodeSet {
+		return nil, fmt.Errorf("in requires a set literal #{...}, got %v", args[1].Type)
+	}
+
+	values := make([]interface{}, 0, len(args[1].Nodes))
+	for i := range args[1].Nodes {
+		elem, err := parsePatternElement(&args[1].Nodes[i])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing in value %d: %w", i, err)
+		}
+		c, ok := elem.(query.Constant)
+		if !ok {
+			return nil, fmt.Errorf("in values must be constants, got %s", elem)
+		}
+		values = append(values, c.Value)
+	}
+
+	return &query.InPredicate{
+		Variable: v.Name,
+		Values:   values,
+	}, nil
+}
+
 // elementToTerm converts a query.PatternElement to a Term
 func elementToTerm(elem query.PatternElement) query.Term {
 	switch e := elem.(type) {
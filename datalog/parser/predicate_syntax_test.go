@@ -98,6 +98,23 @@ func TestPredicateSyntaxCoverage(t *testing.T) {
 			queryStr:     `[:find ?x :where [?e :attr ?x] [(str/starts-with? ?x "foo")]]`,
 			expectedType: "*query.FunctionPredicate",
 		},
+
+		// Set membership
+		{
+			name:         "in with a set",
+			queryStr:     `[:find ?v :where [?e ?a ?v] [(in ?a #{:person/name :person/email})]]`,
+			expectedType: "*query.InPredicate",
+		},
+		{
+			name:        "in without a set",
+			queryStr:    `[:find ?v :where [?e ?a ?v] [(in ?a [:person/name])]]`,
+			shouldError: true,
+		},
+		{
+			name:        "in with a constant",
+			queryStr:    `[:find ?v :where [?e ?a ?v] [(in :person/name #{:person/name})]]`,
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -199,6 +216,8 @@ func typeStr(v interface{}) string {
 		return "*query.MissingPredicate"
 	case *query.FunctionPredicate:
 		return "*query.FunctionPredicate"
+	case *query.InPredicate:
+		return "*query.InPredicate"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,237 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestParseQueryMapForm(t *testing.T) {
+	input := `{:query [:find ?e ?name
+	                   :where [?e :person/name ?name]]
+	           :timeout 5000
+	           :offset 10
+	           :limit 100}`
+
+	q, err := ParseQuery(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(q.Find) != 2 || len(q.Where) != 1 {
+		t.Fatalf("expected 2 find elements and 1 pattern, got %d and %d", len(q.Find), len(q.Where))
+	}
+	if q.Timeout != 5*time.Second {
+		t.Errorf("expected timeout 5s, got %v", q.Timeout)
+	}
+	if q.Offset != 10 {
+		t.Errorf("expected offset 10, got %d", q.Offset)
+	}
+	if q.Limit != 100 {
+		t.Errorf("expected limit 100, got %d", q.Limit)
+	}
+}
+
+func TestParseQueryMapFormDefaults(t *testing.T) {
+	q, err := ParseQuery(`{:query [:find ?e :where [?e :person/name _]]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.HasOptions() {
+		t.Errorf("expected no options, got timeout=%v offset=%d limit=%d", q.Timeout, q.Offset, q.Limit)
+	}
+
+	// -1 is the Datomic convention for "no limit"
+	q, err = ParseQuery(`{:query [:find ?e :where [?e :person/name _]] :limit -1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Limit != 0 {
+		t.Errorf("expected :limit -1 to mean no limit, got %d", q.Limit)
+	}
+
+	// Plain vector queries carry no options
+	q, err = ParseQuery(`[:find ?e :where [?e :person/name _]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.HasOptions() {
+		t.Errorf("vector query should have no options")
+	}
+}
+
+func TestParseQueryMapFormErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		error string
+	}{
+		{
+			name:  "missing query",
+			input: `{:limit 10}`,
+			error: "query map must contain :query",
+		},
+		{
+			name:  "query not a vector",
+			input: `{:query (:find ?e)}`,
+			error: ":query must be a vector",
+		},
+		{
+			name:  "unknown option",
+			input: `{:query [:find ?e :where [?e :foo _]] :args []}`,
+			error: "unknown query option: :args",
+		},
+		{
+			name:  "non-keyword key",
+			input: `{"query" [:find ?e :where [?e :foo _]]}`,
+			error: "query map keys must be keywords",
+		},
+		{
+			name:  "non-integer timeout",
+			input: `{:query [:find ?e :where [?e :foo _]] :timeout "5s"}`,
+			error: ":timeout must be an integer",
+		},
+		{
+			name:  "negative offset",
+			input: `{:query [:find ?e :where [?e :foo _]] :offset -1}`,
+			error: ":offset must be >= 0",
+		},
+		{
+			name:  "zero limit",
+			input: `{:query [:find ?e :where [?e :foo _]] :limit 0}`,
+			error: ":limit must be positive or -1",
+		},
+		{
+			name:  "invalid inner query",
+			input: `{:query [:find ?e]}`,
+			error: "query must have at least one where pattern",
+		},
+		{
+			name:  "grouping set of non-find variable",
+			input: `{:query [:find ?s (sum ?v) :where [?e :a ?s] [?e :b ?v]] :grouping-sets [[?e] []]}`,
+			error: "grouping set 0 variable ?e is not a :find variable",
+		},
+		{
+			name:  "grouping sets without aggregates",
+			input: `{:query [:find ?s :where [?e :a ?s]] :rollup true}`,
+			error: "grouping sets require an aggregate",
+		},
+		{
+			name:  "rollup with grouping sets",
+			input: `{:query [:find ?s (sum ?v) :where [?e :a ?s] [?e :b ?v]] :rollup true :grouping-sets [[?s]]}`,
+			error: ":rollup and :grouping-sets cannot be combined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.input)
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.error)
+			}
+			if !contains(err.Error(), tt.error) {
+				t.Errorf("expected error containing %q, got %q", tt.error, err.Error())
+			}
+		})
+	}
+}
+
+func TestFormatQueryMapFormRoundTrip(t *testing.T) {
+	input := `{:query [:find ?name :where [?e :person/name ?name]] :timeout 250 :offset 2 :limit 5}`
+
+	q, err := ParseQuery(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	formatted := FormatQuery(q)
+	q2, err := ParseQuery(formatted)
+	if err != nil {
+		t.Fatalf("formatted query failed to parse: %v\nformatted: %s", err, formatted)
+	}
+
+	if q2.Timeout != q.Timeout || q2.Offset != q.Offset || q2.Limit != q.Limit {
+		t.Errorf("options not preserved: got timeout=%v offset=%d limit=%d", q2.Timeout, q2.Offset, q2.Limit)
+	}
+}
+
+func TestParseGroupingSets(t *testing.T) {
+	q, err := ParseQuery(`{:query [:find ?sym ?day (sum ?vol)
+	                               :where [?b :bar/symbol ?sym] [?b :bar/day ?day] [?b :bar/volume ?vol]]
+	                       :rollup true}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(q.GroupingSets) != "[[?sym ?day] [?sym] []]" {
+		t.Errorf("expected rollup grouping sets, got %v", q.GroupingSets)
+	}
+
+	q, err = ParseQuery(`{:query [:find ?sym ?day (sum ?vol)
+	                               :where [?b :bar/symbol ?sym] [?b :bar/day ?day] [?b :bar/volume ?vol]]
+	                       :grouping-sets [[?sym ?day] [?day]]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(q.GroupingSets) != "[[?sym ?day] [?day]]" {
+		t.Errorf("unexpected grouping sets %v", q.GroupingSets)
+	}
+
+	// Grouping sets survive formatting
+	q2, err := ParseQuery(FormatQuery(q))
+	if err != nil {
+		t.Fatalf("formatted query failed to parse: %v\nformatted: %s", err, FormatQuery(q))
+	}
+	if fmt.Sprint(q2.GroupingSets) != fmt.Sprint(q.GroupingSets) {
+		t.Errorf("grouping sets not preserved: got %v", q2.GroupingSets)
+	}
+}
+
+func TestParseTimeZones(t *testing.T) {
+	q, err := ParseQuery(`{:query [:find ?d ?h ?m
+	                              :where [?b :bar/time ?t]
+	                                     [(day ?t "America/New_York") ?d]
+	                                     [(hour ?t) ?h]
+	                                     [(q [:find (max ?x) :in $ ?t :where [?c :bar/time ?x] [(month ?x) ?m]] $ ?t) [[?m]]]]
+	                      :time-zone "Asia/Tokyo"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zones := map[string]string{}
+	var collect func(q *query.Query)
+	collect = func(q *query.Query) {
+		for _, clause := range q.Where {
+			switch c := clause.(type) {
+			case *query.Expression:
+				if fn, ok := c.Function.(*query.TimeExtractionFunction); ok {
+					zones[fn.Field] = fn.Location.String()
+				}
+			case *query.SubqueryPattern:
+				collect(c.Query)
+			}
+		}
+	}
+	collect(q)
+
+	// An explicit zone wins over the query's, which applies to the others,
+	// nested queries included
+	expected := map[string]string{"day": "America/New_York", "hour": "Asia/Tokyo", "month": "Asia/Tokyo"}
+	for field, zone := range expected {
+		if zones[field] != zone {
+			t.Errorf("expected %s in %s, got %q", field, zone, zones[field])
+		}
+	}
+
+	for _, input := range []string{
+		`[:find ?d :where [?b :bar/time ?t] [(day ?t "Mars/Olympus_Mons") ?d]]`,
+		`[:find ?d :where [?b :bar/time ?t] [(day ?t ?zone) ?d]]`,
+		`{:query [:find ?e :where [?e :person/name _]] :time-zone "Nowhere/Special"}`,
+		`{:query [:find ?e :where [?e :person/name _]] :time-zone :utc}`,
+	} {
+		if _, err := ParseQuery(input); err == nil {
+			t.Errorf("expected an invalid time zone error for %s", input)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"123", int64(123)},
+		{"-7", int64(-7)},
+		{"1.5", 1.5},
+		{`"hello world"`, "hello world"},
+		{`"123"`, "123"},
+		{":person/name", datalog.NewKeyword(":person/name")},
+		{"true", true},
+		{"false", false},
+		{`#inst "2025-01-02T09:30:00Z"`, time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)},
+		{`#inst "2025-01-02T09:30:00.5-05:00"`, time.Date(2025, 1, 2, 14, 30, 0, 500000000, time.UTC)},
+		{`#inst "2025-01-02"`, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"  42  ", int64(42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseValue(tt.input)
+			if err != nil {
+				t.Fatalf("ParseValue(%s) failed: %v", tt.input, err)
+			}
+			if want, ok := tt.expected.(time.Time); ok {
+				if got, ok := got.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("ParseValue(%s) = %v, want %v", tt.input, got, want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseValue(%s) = %#v (%T), want %#v", tt.input, got, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseValueErrors(t *testing.T) {
+	inputs := []string{
+		"123abc",
+		"hello",
+		"?x",
+		"",
+		"1 2",
+		`"unterminated`,
+		`#inst "yesterday"`,
+		`#uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"`,
+		"[1 2]",
+		"nil",
+	}
+
+	for _, input := range inputs {
+		if v, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) = %v, expected an error", input, v)
+		}
+	}
+}
+
+func TestInstConstant(t *testing.T) {
+	q, err := ParseQuery(`[:find ?e :where [?e :event/time #inst "2025-01-02T09:30:00Z"]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	c, ok := q.Where[0].(*query.DataPattern).GetV().(query.Constant)
+	if !ok {
+		t.Fatalf("Expected a constant value, got %v", q.Where[0])
+	}
+	if got, ok := c.Value.(time.Time); !ok || !got.Equal(time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected #inst to parse as a time, got %#v", c.Value)
+	}
+}
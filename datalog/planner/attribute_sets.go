@@ -0,0 +1,99 @@
+package planner
+
+import (
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// expandAttributeSets returns the clauses with each data pattern whose
+// attribute is a variable constrained by an [(in ?a #{...})] predicate of
+// keywords restricted to that attribute set:
+//
+//	[?e ?a ?v] [(in ?a #{:person/name :person/email})]
+//
+// The executor matches such a pattern once per attribute, through AEVT,
+// instead of scanning every datom and filtering on ?a. The predicate is kept,
+// as it still filters ?a wherever the pattern isn't expanded (when ?a is
+// already bound, say). Several in predicates on one variable intersect.
+//
+// The second result is false, and the clauses are returned unchanged, if no
+// pattern is restricted.
+func expandAttributeSets(clauses []query.Clause) ([]query.Clause, bool) {
+	sets := make(map[query.Symbol][]datalog.Keyword)
+	for _, clause := range clauses {
+		in, ok := clause.(*query.InPredicate)
+		if !ok {
+			continue
+		}
+		attrs, ok := keywordSet(in.Values)
+		if !ok {
+			continue
+		}
+		if prev, seen := sets[in.Variable]; seen {
+			attrs = intersectKeywords(prev, attrs)
+		}
+		sets[in.Variable] = attrs
+	}
+	if len(sets) == 0 {
+		return clauses, false
+	}
+
+	var where []query.Clause
+	for i, clause := range clauses {
+		dp, ok := clause.(*query.DataPattern)
+		if !ok || len(dp.Elements) < 2 {
+			continue
+		}
+		v, ok := dp.Elements[1].(query.Variable)
+		if !ok {
+			continue
+		}
+		attrs, ok := sets[v.Name]
+		if !ok {
+			continue
+		}
+		if where == nil {
+			where = append([]query.Clause(nil), clauses...)
+		}
+		restricted := *dp
+		restricted.Attributes = attrs
+		where[i] = &restricted
+	}
+	if where == nil {
+		return clauses, false
+	}
+	return where, true
+}
+
+// keywordSet returns the distinct keywords of values, or false if any value
+// isn't a keyword
+func keywordSet(values []interface{}) ([]datalog.Keyword, bool) {
+	var attrs []datalog.Keyword
+	seen := make(map[datalog.Keyword]bool)
+	for _, v := range values {
+		kw, ok := v.(datalog.Keyword)
+		if !ok {
+			return nil, false
+		}
+		if !seen[kw] {
+			seen[kw] = true
+			attrs = append(attrs, kw)
+		}
+	}
+	return attrs, true
+}
+
+// intersectKeywords returns the keywords of a that are also in b
+func intersectKeywords(a, b []datalog.Keyword) []datalog.Keyword {
+	inB := make(map[datalog.Keyword]bool, len(b))
+	for _, kw := range b {
+		inB[kw] = true
+	}
+	var both []datalog.Keyword
+	for _, kw := range a {
+		if inB[kw] {
+			both = append(both, kw)
+		}
+	}
+	return both
+}
@@ -0,0 +1,94 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestExpandAttributeSets(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string // Each pattern's attribute set, "" if unrestricted
+	}{
+		{
+			name:  "Attribute variable",
+			query: `[:find ?e ?v :where [?e ?a ?v] [(in ?a #{:person/name :person/email})]]`,
+			want:  []string{":person/name :person/email"},
+		},
+		{
+			name:  "Intersected sets",
+			query: `[:find ?e ?v :where [?e ?a ?v] [(in ?a #{:person/name :person/email})] [(in ?a #{:person/email :person/age})]]`,
+			want:  []string{":person/email"},
+		},
+		{
+			name:  "Only patterns with the variable as attribute",
+			query: `[:find ?e ?v :where [?e ?a ?v] [?a :db/doc ?doc] [(in ?a #{:person/name})]]`,
+			want:  []string{":person/name", ""},
+		},
+		{
+			name:  "Values that are not keywords",
+			query: `[:find ?e ?v :where [?e ?a ?v] [(in ?a #{:person/name "name"})]]`,
+		},
+		{
+			name:  "Set on the value",
+			query: `[:find ?e :where [?e :person/name ?v] [(in ?v #{"Alice" "Bob"})]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			where, ok := expandAttributeSets(q.Where)
+			if tt.want == nil {
+				if ok || &where[0] != &q.Where[0] {
+					t.Errorf("Expected clauses unchanged, got %v", where)
+				}
+				return
+			}
+			if !ok || len(where) != len(q.Where) {
+				t.Fatalf("Expected restricted patterns, got %v", where)
+			}
+
+			var got []string
+			for i, clause := range where {
+				dp, ok := clause.(*query.DataPattern)
+				if !ok {
+					if clause != q.Where[i] {
+						t.Errorf("Expected %v kept, got %v", q.Where[i], clause)
+					}
+					continue
+				}
+				set := ""
+				for j, attr := range dp.Attributes {
+					if j > 0 {
+						set += " "
+					}
+					set += attr.String()
+				}
+				got = append(got, set)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected attribute sets %q, got %q", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Pattern %d: expected attribute set %q, got %q", i, tt.want[i], got[i])
+				}
+			}
+
+			// The query itself is left as parsed
+			for _, clause := range q.Where {
+				if dp, ok := clause.(*query.DataPattern); ok && len(dp.Attributes) > 0 {
+					t.Errorf("Expected the parsed pattern unchanged, got %v", dp)
+				}
+			}
+		})
+	}
+}
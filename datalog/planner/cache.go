@@ -17,8 +17,9 @@ type PlanCache struct {
 	mu    sync.RWMutex
 
 	// Statistics
-	hits   int64
-	misses int64
+	hits          int64
+	misses        int64
+	invalidations int64
 
 	// Configuration
 	maxSize int
@@ -46,13 +47,20 @@ func NewPlanCache(maxSize int, ttl time.Duration) *PlanCache {
 	}
 }
 
-// Get retrieves a cached plan if it exists and is not expired
+// GetWithOptions retrieves a cached plan if it exists and is not expired
 func (c *PlanCache) GetWithOptions(q *query.Query, opts PlannerOptions) (*QueryPlan, bool) {
+	return c.GetWithEpoch(q, opts, 0)
+}
+
+// GetWithEpoch retrieves a plan cached for the same options and statistics
+// epoch. A cached plan that uses a feature the options disable is treated as
+// a miss, so the caller falls back to planning the query afresh.
+func (c *PlanCache) GetWithEpoch(q *query.Query, opts PlannerOptions, epoch uint64) (*QueryPlan, bool) {
 	if c == nil {
 		return nil, false
 	}
 
-	key := c.computeKeyWithOptions(q, opts)
+	key := c.computeKeyWithOptions(q, opts, epoch)
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -70,6 +78,14 @@ func (c *PlanCache) GetWithOptions(q *query.Query, opts PlannerOptions) (*QueryP
 		return nil, false
 	}
 
+	// Defensive check: the key covers the options, so this only fires if the
+	// key misses an option that shapes plans
+	if feature := disabledPlanFeature(cached.plan, opts); feature != "" {
+		atomic.AddInt64(&c.invalidations, 1)
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
 	atomic.AddInt64(&c.hits, 1)
 	return cached.plan, true
 }
@@ -80,13 +96,18 @@ func (c *PlanCache) Get(q *query.Query) (*QueryPlan, bool) {
 	return c.GetWithOptions(q, PlannerOptions{})
 }
 
-// Set stores a plan in the cache
+// SetWithOptions stores a plan in the cache
 func (c *PlanCache) SetWithOptions(q *query.Query, plan *QueryPlan, opts PlannerOptions) {
+	c.SetWithEpoch(q, plan, opts, 0)
+}
+
+// SetWithEpoch stores a plan made with the given options and statistics epoch
+func (c *PlanCache) SetWithEpoch(q *query.Query, plan *QueryPlan, opts PlannerOptions, epoch uint64) {
 	if c == nil || plan == nil {
 		return
 	}
 
-	key := c.computeKeyWithOptions(q, opts)
+	key := c.computeKeyWithOptions(q, opts, epoch)
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -125,6 +146,7 @@ func (c *PlanCache) Clear() {
 	c.cache = make(map[string]*cachedPlan)
 	atomic.StoreInt64(&c.hits, 0)
 	atomic.StoreInt64(&c.misses, 0)
+	atomic.StoreInt64(&c.invalidations, 0)
 }
 
 // Stats returns cache statistics
@@ -139,8 +161,18 @@ func (c *PlanCache) Stats() (hits, misses int64, size int) {
 	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses), len(c.cache)
 }
 
-// computeKeyWithOptions generates a deterministic key for a query with planner options
-func (c *PlanCache) computeKeyWithOptions(q *query.Query, opts PlannerOptions) string {
+// Invalidations returns how many cached plans were rejected because they use
+// a feature the requesting options disable. These are also counted as misses.
+func (c *PlanCache) Invalidations() int64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.invalidations)
+}
+
+// computeKeyWithOptions generates a deterministic key for a query with planner
+// options and statistics epoch
+func (c *PlanCache) computeKeyWithOptions(q *query.Query, opts PlannerOptions, epoch uint64) string {
 	// Create a string representation that captures the query structure AND options
 	// This needs to be deterministic and capture all relevant aspects
 
@@ -183,12 +215,15 @@ func (c *PlanCache) computeKeyWithOptions(q *query.Query, opts PlannerOptions) s
 		}
 	}
 
-	// Hash planner options that affect the plan
-	fmt.Fprintf(h, "OPTIONS:")
-	fmt.Fprintf(h, "DynamicReorder:%v;", opts.EnableDynamicReordering)
-	fmt.Fprintf(h, "PredicatePush:%v;", opts.EnablePredicatePushdown)
-	fmt.Fprintf(h, "CondAggRewrite:%v;", opts.EnableConditionalAggregateRewriting)
-	fmt.Fprintf(h, "SubqueryDecorr:%v;", opts.EnableSubqueryDecorrelation)
+	// Hash grouping sets (carried by the plan's last phase)
+	if len(q.GroupingSets) > 0 {
+		fmt.Fprintf(h, "GROUPINGSETS:%v;", q.GroupingSets)
+	}
+
+	// Hash planner options that affect the plan, and the statistics they were
+	// costed against
+	fmt.Fprintf(h, "OPTIONS:%s;", opts.planFingerprint())
+	fmt.Fprintf(h, "STATS:%d;", epoch)
 
 	return hex.EncodeToString(h.Sum(nil))
 }
@@ -196,7 +231,59 @@ func (c *PlanCache) computeKeyWithOptions(q *query.Query, opts PlannerOptions) s
 // computeKey generates a deterministic key for a query - deprecated
 func (c *PlanCache) computeKey(q *query.Query) string {
 	// For backward compatibility
-	return c.computeKeyWithOptions(q, PlannerOptions{})
+	return c.computeKeyWithOptions(q, PlannerOptions{}, 0)
+}
+
+// planFingerprint canonically encodes every option the planners read, so
+// plans made under different options never share a cache key. Executor-only
+// options are left out: they do not change the plan.
+func (o PlannerOptions) planFingerprint() string {
+	return fmt.Sprintf("ClauseBased:%v;DynamicReorder:%v;FineGrained:%v;MaxPhases:%d;"+
+		"PredicatePush:%v;SemanticRewrite:%v;EqualityConstRewrite:%v;CondAggRewrite:%v;"+
+		"AttrSetExpand:%v;SubqueryDecorr:%v;CSE:%v;DecorrPartitions:%d",
+		o.UseClauseBasedPlanner, o.EnableDynamicReordering, o.EnableFineGrainedPhases, o.MaxPhases,
+		o.EnablePredicatePushdown, o.EnableSemanticRewriting, o.EnableEqualityConstantRewriting, o.EnableConditionalAggregateRewriting,
+		o.EnableAttributeSetExpansion, o.EnableSubqueryDecorrelation, o.EnableCSE, o.DecorrelationPartitions)
+}
+
+// disabledPlanFeature returns the name of a planning feature the plan relies
+// on that opts disable, or "" if the plan is compatible with opts
+func disabledPlanFeature(plan *QueryPlan, opts PlannerOptions) string {
+	for _, phase := range plan.Phases {
+		if len(phase.DecorrelatedSubqueries) > 0 {
+			if !opts.EnableSubqueryDecorrelation {
+				return "subquery decorrelation"
+			}
+			for _, dsp := range phase.DecorrelatedSubqueries {
+				if len(dsp.PartitionedPlans) > 0 && opts.DecorrelationPartitions <= 1 {
+					return "partitioned decorrelation"
+				}
+			}
+		}
+		if _, ok := phase.Metadata["conditional_aggregates"]; ok && !opts.EnableConditionalAggregateRewriting {
+			return "conditional aggregate rewriting"
+		}
+		if !opts.EnableSemanticRewriting {
+			for _, pred := range phase.Predicates {
+				if pred.Metadata["optimized_by_constraint"] == true {
+					return "semantic rewriting"
+				}
+			}
+			for _, expr := range phase.Expressions {
+				if expr.Metadata["optimized_by_constraint"] == true {
+					return "semantic rewriting"
+				}
+			}
+		}
+		if !opts.EnablePredicatePushdown && !opts.EnableSemanticRewriting {
+			for _, pat := range phase.Patterns {
+				if len(pat.Constraints) > 0 || len(pat.StorageFilters) > 0 || len(pat.PushablePredicates) > 0 {
+					return "predicate pushdown"
+				}
+			}
+		}
+	}
+	return ""
 }
 
 // evictExpired removes expired entries from the cache
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -282,3 +283,290 @@ func TestPlannerWithCache(t *testing.T) {
 		t.Error("Expected cache to be disabled")
 	}
 }
+
+func TestPlanCacheGroupingSets(t *testing.T) {
+	cache := NewPlanCache(10, 1*time.Minute)
+
+	q := &query.Query{
+		Find: []query.FindElement{
+			query.FindVariable{Symbol: "?name"},
+			query.FindAggregate{Function: "count", Arg: "?e"},
+		},
+		Where: []query.Clause{
+			&query.DataPattern{
+				Elements: []query.PatternElement{
+					query.Variable{Name: "?e"},
+					query.Constant{Value: datalog.NewKeyword(":person/name")},
+					query.Variable{Name: "?name"},
+				},
+			},
+		},
+	}
+	cache.Set(q, &QueryPlan{Query: q})
+
+	// The plan's last phase carries the grouping sets, so the same query
+	// with grouping sets must not share the cached plan
+	rollup := *q
+	rollup.GroupingSets = [][]query.Symbol{{"?name"}, {}}
+	if _, ok := cache.Get(&rollup); ok {
+		t.Error("Expected cache miss for query with grouping sets")
+	}
+}
+
+func TestPlanCacheKeyCoversOptionsAndEpoch(t *testing.T) {
+	cache := NewPlanCache(10, 1*time.Minute)
+
+	q := &query.Query{
+		Find: []query.FindElement{
+			query.FindVariable{Symbol: "?e"},
+		},
+		Where: []query.Clause{
+			&query.DataPattern{
+				Elements: []query.PatternElement{
+					query.Variable{Name: "?e"},
+					query.Constant{Value: datalog.NewKeyword(":person/name")},
+					query.Constant{Value: "Dana"},
+				},
+			},
+		},
+	}
+	plan := &QueryPlan{Query: q}
+
+	base := PlannerOptions{EnableSubqueryDecorrelation: true}
+	cache.SetWithEpoch(q, plan, base, 1)
+
+	if _, ok := cache.GetWithEpoch(q, base, 1); !ok {
+		t.Fatal("Expected hit for identical options and epoch")
+	}
+
+	// Options the key previously ignored must not share plans
+	variants := map[string]PlannerOptions{
+		"cse":          {EnableSubqueryDecorrelation: true, EnableCSE: true},
+		"partitions":   {EnableSubqueryDecorrelation: true, DecorrelationPartitions: 4},
+		"semantic":     {EnableSubqueryDecorrelation: true, EnableSemanticRewriting: true},
+		"fine-grained": {EnableSubqueryDecorrelation: true, EnableFineGrainedPhases: true},
+		"clause-based": {EnableSubqueryDecorrelation: true, UseClauseBasedPlanner: true},
+	}
+	for name, opts := range variants {
+		if _, ok := cache.GetWithEpoch(q, opts, 1); ok {
+			t.Errorf("%s: expected miss for different options", name)
+		}
+	}
+
+	// Executor-only options do not change the plan
+	execOnly := base
+	execOnly.EnableParallelSubqueries = true
+	execOnly.MaxSubqueryWorkers = 8
+	if _, ok := cache.GetWithEpoch(q, execOnly, 1); !ok {
+		t.Error("Expected hit when only executor options differ")
+	}
+
+	if _, ok := cache.GetWithEpoch(q, base, 2); ok {
+		t.Error("Expected miss for a newer statistics epoch")
+	}
+}
+
+func TestPlanCacheRejectsPlanUsingDisabledFeature(t *testing.T) {
+	cache := NewPlanCache(10, 1*time.Minute)
+
+	q := &query.Query{
+		Find: []query.FindElement{
+			query.FindVariable{Symbol: "?e"},
+		},
+	}
+	decorrelated := &QueryPlan{
+		Query: q,
+		Phases: []Phase{
+			{DecorrelatedSubqueries: []DecorrelatedSubqueryPlan{{TotalSubqueries: 2}}},
+		},
+	}
+
+	// Plant the plan under the key for options that disable decorrelation, as
+	// a key that misses an option would
+	opts := PlannerOptions{}
+	cache.cache[cache.computeKeyWithOptions(q, opts, 0)] = &cachedPlan{plan: decorrelated, timestamp: time.Now()}
+
+	if _, ok := cache.GetWithOptions(q, opts); ok {
+		t.Fatal("Expected cached plan using decorrelation to be rejected")
+	}
+	if n := cache.Invalidations(); n != 1 {
+		t.Errorf("Expected 1 invalidation, got %d", n)
+	}
+	if _, misses, _ := cache.Stats(); misses != 1 {
+		t.Errorf("Expected rejection to count as a miss, got %d misses", misses)
+	}
+}
+
+func TestDisabledPlanFeature(t *testing.T) {
+	pushed := &QueryPlan{Phases: []Phase{{
+		Patterns: []PatternPlan{{Constraints: []StorageConstraint{{}}}},
+	}}}
+	rewritten := &QueryPlan{Phases: []Phase{{
+		Predicates: []PredicatePlan{{Metadata: map[string]interface{}{"optimized_by_constraint": true}}},
+	}}}
+	condAgg := &QueryPlan{Phases: []Phase{{
+		Metadata: map[string]interface{}{"conditional_aggregates": nil},
+	}}}
+	partitioned := &QueryPlan{Phases: []Phase{{
+		DecorrelatedSubqueries: []DecorrelatedSubqueryPlan{{PartitionedPlans: []*QueryPlan{{}}}},
+	}}}
+
+	tests := []struct {
+		name     string
+		plan     *QueryPlan
+		opts     PlannerOptions
+		expected string
+	}{
+		{"pushdown disabled", pushed, PlannerOptions{}, "predicate pushdown"},
+		{"pushdown enabled", pushed, PlannerOptions{EnablePredicatePushdown: true}, ""},
+		{"semantic disabled", rewritten, PlannerOptions{EnablePredicatePushdown: true}, "semantic rewriting"},
+		{"semantic enabled", rewritten, PlannerOptions{EnableSemanticRewriting: true}, ""},
+		{"conditional aggregates disabled", condAgg, PlannerOptions{}, "conditional aggregate rewriting"},
+		{"partitions disabled", partitioned, PlannerOptions{EnableSubqueryDecorrelation: true}, "partitioned decorrelation"},
+		{"partitions enabled", partitioned, PlannerOptions{EnableSubqueryDecorrelation: true, DecorrelationPartitions: 4}, ""},
+	}
+	for _, tt := range tests {
+		if got := disabledPlanFeature(tt.plan, tt.opts); got != tt.expected {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestPlannerReplansOnStatisticsEpoch(t *testing.T) {
+	cache := NewPlanCache(100, 0)
+	stats := &Statistics{AttributeCardinality: map[string]int{}, EntityCount: 1000}
+	planner := NewPlanner(stats, PlannerOptions{Cache: cache})
+
+	q := &query.Query{
+		Find: []query.FindElement{
+			query.FindVariable{Symbol: "?e"},
+		},
+		Where: []query.Clause{
+			&query.DataPattern{
+				Elements: []query.PatternElement{
+					query.Variable{Name: "?e"},
+					query.Constant{Value: datalog.NewKeyword(":person/name")},
+					query.Variable{Name: "?name"},
+				},
+			},
+		},
+	}
+
+	first, err := planner.Plan(q)
+	if err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+	if cached, _ := planner.Plan(q); cached != first {
+		t.Error("Expected cached plan for unchanged statistics")
+	}
+
+	stats.AttributeCardinality[":person/name"] = 50000
+	stats.Epoch++
+
+	replanned, err := planner.Plan(q)
+	if err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+	if replanned == first {
+		t.Error("Expected a fresh plan after the statistics epoch changed")
+	}
+}
+
+// staticStatistics is a StatisticsProvider returning fixed statistics
+type staticStatistics struct {
+	stats *Statistics
+}
+
+func (s *staticStatistics) Statistics() *Statistics {
+	return s.stats
+}
+
+func TestPlannerUsesStatisticsProvider(t *testing.T) {
+	cache := NewPlanCache(100, 0)
+	planner := NewPlanner(nil, PlannerOptions{Cache: cache})
+	provider := &staticStatistics{stats: &Statistics{Epoch: 7}}
+	planner.SetStatisticsProvider(provider)
+
+	q, err := parser.ParseQuery(`[:find ?e :where [?e :person/name ?name]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	first, err := planner.Plan(q)
+	if err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+	if _, ok := cache.GetWithEpoch(q, planner.Options(), 7); !ok {
+		t.Error("Expected the plan cached under the provider's epoch")
+	}
+	if cached, _ := planner.Plan(q); cached != first {
+		t.Error("Expected cached plan while the provider's statistics are unchanged")
+	}
+
+	provider.stats = &Statistics{Epoch: 8}
+	if replanned, _ := planner.Plan(q); replanned == first {
+		t.Error("Expected a fresh plan once the provider returns new statistics")
+	}
+}
+
+func TestPlannerCachesNestedPlans(t *testing.T) {
+	cache := NewPlanCache(100, 0)
+	planner := NewPlanner(nil, PlannerOptions{Cache: cache})
+
+	// A report with the same nested query for two different symbols
+	report, err := parser.ParseQuery(`[:find ?a ?b ?max-a ?max-b
+	  :where [?a :symbol/ticker "A"]
+	         [?b :symbol/ticker "B"]
+	         [(q [:find (max ?p) :in $ ?s :where [?bar :price/symbol ?s] [?bar :price/value ?p]] $ ?a) [[?max-a]]]
+	         [(q [:find (max ?p) :in $ ?s :where [?bar :price/symbol ?s] [?bar :price/value ?p]] $ ?b) [[?max-b]]]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	plan, err := planner.Plan(report)
+	if err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+
+	var nested []*QueryPlan
+	for _, phase := range plan.Phases {
+		for _, subq := range phase.Subqueries {
+			nested = append(nested, subq.NestedPlan)
+		}
+	}
+	if len(nested) != 2 || nested[0] == nil || nested[0] != nested[1] {
+		t.Fatalf("Expected both subqueries to share one nested plan, got %v", nested)
+	}
+
+	// The report and its nested query missed; the second nested query hit
+	if hits, misses, size := cache.Stats(); hits != 1 || misses != 2 || size != 2 {
+		t.Errorf("Expected 1 hit, 2 misses and 2 cached plans, got %d, %d and %d", hits, misses, size)
+	}
+
+	// Another query with the same nested query reuses its plan
+	other, err := parser.ParseQuery(`[:find ?c ?max
+	  :where [?c :symbol/ticker "C"]
+	         [(q [:find (max ?p) :in $ ?s :where [?bar :price/symbol ?s] [?bar :price/value ?p]] $ ?c) [[?max]]]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	otherPlan, err := planner.Plan(other)
+	if err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+	for _, phase := range otherPlan.Phases {
+		for _, subq := range phase.Subqueries {
+			if subq.NestedPlan != nested[0] {
+				t.Error("Expected the nested plan to come from the cache")
+			}
+		}
+	}
+
+	// Plans made under other options are not shared
+	reordering := NewPlanner(nil, PlannerOptions{Cache: cache, EnableDynamicReordering: true})
+	if _, err := reordering.Plan(other); err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+	if _, misses, _ := cache.Stats(); misses != 5 {
+		t.Errorf("Expected the other options to miss for the query and its nested query, got %d misses", misses)
+	}
+}
@@ -27,6 +27,8 @@ func extractClauseSymbols(clause query.Clause) ClauseSymbols {
 		return extractNotEqualSymbols(c)
 	case *query.MissingPredicate:
 		return extractMissingPredicateSymbols(c)
+	case *query.InPredicate:
+		return ClauseSymbols{Requires: []query.Symbol{c.Variable}}
 	case *query.Subquery:
 		return extractSubquerySymbols(c)
 	default:
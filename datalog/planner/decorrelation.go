@@ -350,13 +350,13 @@ func (p *Planner) createDecorrelatedPlan(phase *Phase, group DecorrelationGroup)
 	}
 
 	// Create merged query plan for each filter group
-	var mergedPlans []*QueryPlan
+	var mergedPlans, partitionedPlans []*QueryPlan
 	var allGroupingVars [][]query.Symbol
 	columnMapping := make(map[int]ResultMap)
 
 	for groupIdx, fg := range filterGroups {
 		// Merge subqueries in this filter group
-		mergedQuery, colMap, groupingVars, err := mergeSubqueriesInGroup(subqueries, fg, group.Signature)
+		mergedQuery, partitionedQuery, colMap, groupingVars, err := mergeSubqueriesInGroup(subqueries, fg, group.Signature)
 		if err != nil {
 			return nil, fmt.Errorf("failed to merge subqueries in filter group %d: %w", groupIdx, err)
 		}
@@ -367,6 +367,15 @@ func (p *Planner) createDecorrelatedPlan(phase *Phase, group DecorrelationGroup)
 			return nil, fmt.Errorf("failed to plan merged query for filter group %d: %w", groupIdx, err)
 		}
 
+		// Plan the partitioned variant only if partition-wise execution is enabled
+		if p.options.DecorrelationPartitions > 1 {
+			partitionedPlan, err := p.Plan(partitionedQuery)
+			if err != nil {
+				return nil, fmt.Errorf("failed to plan partitioned query for filter group %d: %w", groupIdx, err)
+			}
+			partitionedPlans = append(partitionedPlans, partitionedPlan)
+		}
+
 		mergedPlans = append(mergedPlans, mergedPlan)
 		allGroupingVars = append(allGroupingVars, groupingVars)
 
@@ -400,6 +409,7 @@ func (p *Planner) createDecorrelatedPlan(phase *Phase, group DecorrelationGroup)
 		OriginalSubqueries: group.Subqueries,
 		FilterGroups:       filterGroups,
 		MergedPlans:        mergedPlans,
+		PartitionedPlans:   partitionedPlans,
 		CorrelationKeys:    group.Signature.CorrelationVars,
 		GroupingVars:       allGroupingVars,
 		ColumnMapping:      columnMapping,
@@ -411,13 +421,18 @@ func (p *Planner) createDecorrelatedPlan(phase *Phase, group DecorrelationGroup)
 }
 
 // mergeSubqueriesInGroup merges subqueries in a filter group into single query
-// Returns: merged query, column mapping, grouping variables, error
+// Returns: merged query, partitioned query, column mapping, grouping variables, error
+//
+// The partitioned query is the merged query restricted to a set of correlation
+// keys: it takes the formal parameters as a relation input ([[?sym ?y ...] ...])
+// and keeps the correlation predicates, so it computes only the groups whose
+// keys are supplied.
 func mergeSubqueriesInGroup(subqueries []*SubqueryPlan, fg FilterGroup,
-	sig CorrelationSignature) (*query.Query, map[int][]int, []query.Symbol, error) {
+	sig CorrelationSignature) (*query.Query, *query.Query, map[int][]int, []query.Symbol, error) {
 
 	// Start with first subquery as base
 	if len(fg.Subqueries) == 0 {
-		return nil, nil, nil, fmt.Errorf("empty filter group")
+		return nil, nil, nil, nil, fmt.Errorf("empty filter group")
 	}
 
 	baseSubq := subqueries[fg.Subqueries[0]]
@@ -495,12 +510,26 @@ func mergeSubqueriesInGroup(subqueries []*SubqueryPlan, fg FilterGroup,
 	for _, subqIdx := range fg.Subqueries {
 		subq := subqueries[subqIdx]
 
+		// Formal parameters of this subquery, by grouping key position
+		paramPos := make(map[query.Symbol]int)
+		for _, inputSpec := range subq.Subquery.Query.In {
+			if inp, ok := inputSpec.(query.ScalarInput); ok {
+				paramPos[inp.Symbol] = len(paramPos)
+			}
+		}
+
 		var colIndices []int
 		for _, findElem := range subq.NestedPlan.Query.Find {
 			if findElem.IsAggregate() {
 				allFindElements = append(allFindElements, findElem)
 				colIndices = append(colIndices, nextColIdx)
 				nextColIdx++
+			} else if v, ok := findElem.(query.FindVariable); ok {
+				// A grouping key returned by the subquery is already in the
+				// merged result as a grouping column
+				if pos, isParam := paramPos[v.Symbol]; isParam && pos < groupKeyCount {
+					colIndices = append(colIndices, pos)
+				}
 			}
 		}
 
@@ -533,6 +562,7 @@ func mergeSubqueriesInGroup(subqueries []*SubqueryPlan, fg FilterGroup,
 	}
 
 	// Add non-correlation predicates from base query
+	var correlationClauses []query.Clause
 	for _, clause := range baseQuery.Where {
 		// Skip patterns (already added above) - check for concrete pattern types
 		if _, ok := clause.(*query.DataPattern); ok {
@@ -565,6 +595,7 @@ func mergeSubqueriesInGroup(subqueries []*SubqueryPlan, fg FilterGroup,
 
 					if hasFormalParam {
 						// This is a correlation predicate - skip it
+						correlationClauses = append(correlationClauses, clause)
 						continue
 					}
 				}
@@ -582,7 +613,23 @@ func mergeSubqueriesInGroup(subqueries []*SubqueryPlan, fg FilterGroup,
 		Where: whereClauses,                             // Filtered WHERE clauses
 	}
 
-	return mergedQuery, columnMapping, groupingVars, nil
+	// Create partitioned query: correlation keys come back in as a relation
+	var keyParams []query.Symbol
+	for _, param := range formalParams {
+		if param != "$" {
+			keyParams = append(keyParams, param)
+		}
+	}
+	partitionedWhere := make([]query.Clause, 0, len(whereClauses)+len(correlationClauses))
+	partitionedWhere = append(partitionedWhere, whereClauses...)
+	partitionedWhere = append(partitionedWhere, correlationClauses...)
+	partitionedQuery := &query.Query{
+		Find:  allFindElements,
+		In:    []query.InputSpec{query.DatabaseInput{}, query.RelationInput{Symbols: keyParams}},
+		Where: partitionedWhere,
+	}
+
+	return mergedQuery, partitionedQuery, columnMapping, groupingVars, nil
 }
 
 // detectAndPlanDecorrelation detects and plans decorrelated subqueries in a phase
@@ -287,3 +287,76 @@ func TestDetectDecorrelationOpportunities_SingleSubquery(t *testing.T) {
 		t.Errorf("Expected 0 opportunities (single subquery), got %d", len(opportunities))
 	}
 }
+
+func TestCreateDecorrelatedPlan_PartitionedPlans(t *testing.T) {
+	outer := `[:find ?name ?max-price ?total-stock
+	           :where
+	             [?c :category/name ?name]
+	             [(q [:find ?cat (max ?p)
+	                  :in $ ?cat
+	                  :where [?prod :product/category ?cat]
+	                         [?prod :product/price ?p]]
+	                $ ?c) [[?c2 ?max-price]]]
+	             [(q [:find ?cat (sum ?s)
+	                  :in $ ?cat
+	                  :where [?prod :product/category ?cat]
+	                         [?prod :product/stock ?s]]
+	                $ ?c) [[?c3 ?total-stock]]]]`
+
+	q, err := parser.ParseQuery(outer)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	findDecorrelated := func(plan *QueryPlan) *DecorrelatedSubqueryPlan {
+		for i := range plan.Phases {
+			if len(plan.Phases[i].DecorrelatedSubqueries) > 0 {
+				return &plan.Phases[i].DecorrelatedSubqueries[0]
+			}
+		}
+		return nil
+	}
+
+	plain, err := NewPlanner(nil, PlannerOptions{EnableSubqueryDecorrelation: true}).Plan(q)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	decor := findDecorrelated(plain)
+	if decor == nil {
+		t.Fatal("Expected subqueries to be decorrelated")
+	}
+	if len(decor.PartitionedPlans) != 0 {
+		t.Errorf("Expected no partitioned plans without DecorrelationPartitions, got %d", len(decor.PartitionedPlans))
+	}
+
+	partitioned, err := NewPlanner(nil, PlannerOptions{
+		EnableSubqueryDecorrelation: true,
+		DecorrelationPartitions:     4,
+	}).Plan(q)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	decor = findDecorrelated(partitioned)
+	if decor == nil {
+		t.Fatal("Expected subqueries to be decorrelated")
+	}
+	if len(decor.PartitionedPlans) != len(decor.MergedPlans) {
+		t.Fatalf("Expected %d partitioned plans, got %d", len(decor.MergedPlans), len(decor.PartitionedPlans))
+	}
+
+	for i, plan := range decor.PartitionedPlans {
+		in := plan.Query.In
+		if len(in) != 2 {
+			t.Fatalf("Partitioned plan %d: expected :in $ plus a relation input, got %v", i, in)
+		}
+		rel, ok := in[1].(query.RelationInput)
+		if !ok || len(rel.Symbols) != 1 || rel.Symbols[0] != "?cat" {
+			t.Errorf("Partitioned plan %d: expected relation input [[?cat] ...], got %v", i, in[1])
+		}
+	}
+
+	// The grouping key returned by the first subquery maps to the grouping column
+	if mapping := decor.ColumnMapping[0]; len(mapping.ColumnIndices) != 2 || mapping.ColumnIndices[0] != 0 {
+		t.Errorf("Expected ?c2 to map to grouping column 0, got %v", mapping.ColumnIndices)
+	}
+}
@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// rewriteEqualityConstants returns q's :where clauses with each equality
+// predicate that fixes a variable to a constant folded into the patterns
+// binding that variable:
+//
+//	[?p :person/name ?name] [(= ?name "Alice")]  →  [?p :person/name "Alice"]
+//
+// The pattern then has a bound value and is scanned through AVET, instead of
+// scanning the attribute and filtering afterwards.
+//
+// A variable is only rewritten when it appears nowhere but in the value
+// position of data patterns and in its one equality predicate: not in :find,
+// :order-by, another clause or the inputs. Only strings, keywords and booleans
+// are folded; = compares numbers across types (25 = 25.0) while a pattern
+// constant only matches values of its own type.
+//
+// The clauses are returned unchanged (the same slice) if nothing is rewritten.
+func rewriteEqualityConstants(q *query.Query, inputSymbols map[query.Symbol]bool) []query.Clause {
+	// Count every use of each symbol outside data pattern value positions
+	uses := make(map[query.Symbol]int)
+	valueUses := make(map[query.Symbol]int)
+	for sym := range inputSymbols {
+		uses[sym]++
+	}
+	for _, elem := range q.Find {
+		for _, sym := range findElementSymbols(elem) {
+			uses[sym]++
+		}
+	}
+	for _, ob := range q.OrderBy {
+		uses[ob.Variable]++
+	}
+	for _, set := range q.GroupingSets {
+		for _, sym := range set {
+			uses[sym]++
+		}
+	}
+	for _, clause := range q.Where {
+		if dp, ok := clause.(*query.DataPattern); ok {
+			for i, elem := range dp.Elements {
+				if v, ok := elem.(query.Variable); ok {
+					if i == 2 {
+						valueUses[v.Name]++
+					} else {
+						uses[v.Name]++
+					}
+				}
+			}
+			continue
+		}
+		syms := validationSymbols(clause)
+		for _, sym := range syms.Requires {
+			uses[sym]++
+		}
+		for _, sym := range syms.Provides {
+			uses[sym]++
+		}
+	}
+
+	// The predicate itself is the variable's one permitted other use
+	constants := make(map[query.Symbol]interface{})
+	folded := make(map[query.Clause]bool)
+	for _, clause := range q.Where {
+		sym, value, ok := equalityConstant(clause)
+		if !ok || uses[sym] != 1 || valueUses[sym] == 0 {
+			continue
+		}
+		constants[sym] = value
+		folded[clause] = true
+	}
+	if len(constants) == 0 {
+		return q.Where
+	}
+
+	where := make([]query.Clause, 0, len(q.Where)-len(folded))
+	for _, clause := range q.Where {
+		if folded[clause] {
+			continue
+		}
+		if dp, ok := clause.(*query.DataPattern); ok && len(dp.Elements) > 2 {
+			if v, ok := dp.Elements[2].(query.Variable); ok {
+				if value, ok := constants[v.Name]; ok {
+					// Copy the pattern so its hints (MaxDatoms, Attributes) are kept
+					np := *dp
+					np.Elements = append([]query.PatternElement(nil), dp.Elements...)
+					np.Elements[2] = query.Constant{Value: value}
+					clause = &np
+				}
+			}
+		}
+		where = append(where, clause)
+	}
+	return where
+}
+
+// equalityConstant returns the variable and constant of an [(= ?v c)] or
+// [(= c ?v)] predicate whose constant can be folded into a pattern
+func equalityConstant(clause query.Clause) (query.Symbol, interface{}, bool) {
+	comp, ok := clause.(*query.Comparison)
+	if !ok || comp.Op != query.OpEQ {
+		return "", nil, false
+	}
+
+	v, isVar := comp.Left.(query.VariableTerm)
+	c, isConst := comp.Right.(query.ConstantTerm)
+	if !isVar || !isConst {
+		v, isVar = comp.Right.(query.VariableTerm)
+		c, isConst = comp.Left.(query.ConstantTerm)
+	}
+	if !isVar || !isConst {
+		return "", nil, false
+	}
+
+	switch c.Value.(type) {
+	case string, datalog.Keyword, bool:
+		return v.Symbol, c.Value, true
+	}
+	return "", nil, false
+}
@@ -0,0 +1,175 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestRewriteEqualityConstants(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		inputs  map[query.Symbol]bool
+		rewrite bool
+	}{
+		{
+			name:    "String constant",
+			query:   `[:find ?p :where [?p :person/name ?name] [(= ?name "Alice")]]`,
+			rewrite: true,
+		},
+		{
+			name:    "Constant on the left",
+			query:   `[:find ?p :where [?p :person/role ?role] [(= :admin ?role)]]`,
+			rewrite: true,
+		},
+		{
+			name:    "Several value positions",
+			query:   `[:find ?p ?q :where [?p :person/name ?name] [?q :pet/name ?name] [(= ?name "Rex")]]`,
+			rewrite: true,
+		},
+		{
+			name:  "Numeric constant",
+			query: `[:find ?p :where [?p :person/age ?age] [(= ?age 25)]]`,
+		},
+		{
+			name:  "Variable in find",
+			query: `[:find ?p ?name :where [?p :person/name ?name] [(= ?name "Alice")]]`,
+		},
+		{
+			name:  "Variable in entity position",
+			query: `[:find ?p :where [?p :person/friend ?f] [?f :person/active ?f] [(= ?f true)]]`,
+		},
+		{
+			name:  "Variable in another predicate",
+			query: `[:find ?p :where [?p :person/name ?name] [(= ?name "Alice")] [(!= ?name "Bob")]]`,
+		},
+		{
+			name:  "Variable in order-by",
+			query: `[:find ?p :where [?p :person/name ?name] [(= ?name "Alice")] :order-by [?name]]`,
+		},
+		{
+			name:   "Input variable",
+			query:  `[:find ?p :in $ ?name :where [?p :person/name ?name] [(= ?name "Alice")]]`,
+			inputs: map[query.Symbol]bool{"?name": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			where := rewriteEqualityConstants(q, tt.inputs)
+			if !tt.rewrite {
+				if len(where) != len(q.Where) || &where[0] != &q.Where[0] {
+					t.Errorf("Expected clauses unchanged, got %v", where)
+				}
+				return
+			}
+
+			if len(where) != len(q.Where)-1 {
+				t.Fatalf("Expected the predicate to be removed, got %v", where)
+			}
+			for _, clause := range where {
+				dp, ok := clause.(*query.DataPattern)
+				if !ok {
+					t.Fatalf("Expected only data patterns, got %v", clause)
+				}
+				if _, ok := dp.Elements[2].(query.Constant); !ok {
+					t.Errorf("Expected constant value in %s", dp)
+				}
+			}
+			for _, clause := range q.Where {
+				if dp, ok := clause.(*query.DataPattern); ok {
+					if _, ok := dp.Elements[2].(query.Variable); !ok {
+						t.Errorf("Original pattern %s was modified", dp)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteEqualityConstantsKeepsHints(t *testing.T) {
+	q, err := parser.ParseQuery(`[:find ?p :where [?p :person/city ?c {:max-datoms 5}] [(= ?c "Paris")]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	q.Where[0].(*query.DataPattern).Attributes = []datalog.Keyword{datalog.NewKeyword(":person/city")}
+
+	where := rewriteEqualityConstants(q, nil)
+	if len(where) != 1 {
+		t.Fatalf("Expected the predicate to be folded, got %v", where)
+	}
+	dp := where[0].(*query.DataPattern)
+	if _, ok := dp.Elements[2].(query.Constant); !ok {
+		t.Errorf("Expected constant value in %s", dp)
+	}
+	if dp.MaxDatoms != 5 {
+		t.Errorf("Expected MaxDatoms 5 to be kept, got %d", dp.MaxDatoms)
+	}
+	if len(dp.Attributes) != 1 {
+		t.Errorf("Expected Attributes to be kept, got %v", dp.Attributes)
+	}
+}
+
+func TestEqualityConstantPlanning(t *testing.T) {
+	q, err := parser.ParseQuery(`[:find ?p ?age
+	                              :where [?p :person/name ?name]
+	                                     [?p :person/age ?age]
+	                                     [(= ?name "Alice")]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	for _, clauseBased := range []bool{false, true} {
+		opts := PlannerOptions{EnableEqualityConstantRewriting: true, UseClauseBasedPlanner: clauseBased}
+		plan, err := CreatePlanner(nil, opts).PlanQuery(q)
+		if err != nil {
+			t.Fatalf("ClauseBased=%v: plan failed: %v", clauseBased, err)
+		}
+
+		var nameScan *query.DataPattern
+		for _, phase := range plan.Phases {
+			for _, clause := range phase.Query.Where {
+				if _, ok := clause.(query.Predicate); ok {
+					t.Errorf("ClauseBased=%v: expected the predicate to be folded, found %v", clauseBased, clause)
+				}
+				if dp, ok := clause.(*query.DataPattern); ok && dp.GetA().String() == ":person/name" {
+					nameScan = dp
+				}
+			}
+		}
+		if nameScan == nil {
+			t.Fatalf("ClauseBased=%v: :person/name pattern missing from plan", clauseBased)
+		}
+		if c, ok := nameScan.GetV().(query.Constant); !ok || c.Value != "Alice" {
+			t.Errorf("ClauseBased=%v: expected [?p :person/name \"Alice\"], got %s", clauseBased, nameScan)
+		}
+	}
+
+	// The phase-based plan scans the folded pattern through AVET
+	plan, err := NewPlanner(nil, PlannerOptions{EnableEqualityConstantRewriting: true}).Plan(q)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	found := false
+	for _, phase := range plan.Phases {
+		for _, pp := range phase.Patterns {
+			if dp := pp.Pattern.(*query.DataPattern); dp.GetA().String() == ":person/name" {
+				found = true
+				if pp.Index != AVET {
+					t.Errorf("Expected AVET for %s, got %v", dp, pp.Index)
+				}
+			}
+		}
+	}
+	if !found {
+		t.Error(":person/name pattern missing from plan")
+	}
+}
@@ -89,20 +89,18 @@ func TestTimeExtractionExpressionWithEquality(t *testing.T) {
 							if attr, ok := dp.Elements[1].(query.Constant); ok {
 								if kw, ok := attr.Value.(datalog.Keyword); ok && kw.String() == ":price/time" {
 									// This is the time pattern - check for constraints
-									if pattern.Metadata != nil {
-										if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-											for _, c := range constraints {
-												if c.Type == ConstraintTimeExtraction {
-													foundConstraint = true
-													if c.TimeField != tt.constraintField {
-														t.Errorf("Expected time field %s, got %s", tt.constraintField, c.TimeField)
-													}
-													if c.Value != tt.constraintValue {
-														t.Errorf("Expected value %v, got %v", tt.constraintValue, c.Value)
-													}
-													if c.Operator != query.OpEQ {
-														t.Errorf("Expected = operator, got %s", c.Operator)
-													}
+									if constraints := pattern.Constraints; len(constraints) > 0 {
+										for _, c := range constraints {
+											if c.Type == ConstraintTimeExtraction {
+												foundConstraint = true
+												if c.TimeField != tt.constraintField {
+													t.Errorf("Expected time field %s, got %s", tt.constraintField, c.TimeField)
+												}
+												if c.Value != tt.constraintValue {
+													t.Errorf("Expected value %v, got %v", tt.constraintValue, c.Value)
+												}
+												if c.Operator != query.OpEQ {
+													t.Errorf("Expected = operator, got %s", c.Operator)
 												}
 											}
 										}
@@ -161,13 +159,11 @@ func TestOHLCQueryWithExpressionHandling(t *testing.T) {
 					if attr, ok := dp.Elements[1].(query.Constant); ok {
 						if kw, ok := attr.Value.(datalog.Keyword); ok && kw.String() == ":price/time" {
 							// Found the time pattern
-							if pattern.Metadata != nil {
-								if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-									for _, c := range constraints {
-										if c.Type == ConstraintTimeExtraction && c.TimeField == "day" && c.Value == int64(20) {
-											foundDayConstraint = true
-											t.Logf("Found day=20 constraint on :price/time pattern")
-										}
+							if constraints := pattern.Constraints; len(constraints) > 0 {
+								for _, c := range constraints {
+									if c.Type == ConstraintTimeExtraction && c.TimeField == "day" && c.Value == int64(20) {
+										foundDayConstraint = true
+										t.Logf("Found day=20 constraint on :price/time pattern")
 									}
 								}
 							}
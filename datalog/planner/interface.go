@@ -15,6 +15,10 @@ type QueryPlanner interface {
 
 	// SetCache sets the query plan cache
 	SetCache(cache *PlanCache)
+
+	// SetStatisticsProvider sets the source of the statistics the planner
+	// uses in place of those it was created with
+	SetStatisticsProvider(provider StatisticsProvider)
 }
 
 // Ensure both planners implement the interface
@@ -35,11 +39,13 @@ func NewPlannerAdapter(stats *Statistics, options PlannerOptions) *PlannerAdapte
 
 // PlanQuery implements QueryPlanner
 func (pa *PlannerAdapter) PlanQuery(q *query.Query) (*RealizedPlan, error) {
-	plan, err := pa.planner.Plan(q)
+	plan, cached, err := pa.planner.PlanWithCacheStatus(q)
 	if err != nil {
 		return nil, err
 	}
-	return plan.Realize(), nil
+	realized := plan.Realize()
+	realized.Cached = cached
+	return realized, nil
 }
 
 // PlanQueryWithBindings implements QueryPlanner
@@ -61,6 +67,11 @@ func (pa *PlannerAdapter) SetCache(cache *PlanCache) {
 	pa.planner.SetCache(cache)
 }
 
+// SetStatisticsProvider implements QueryPlanner
+func (pa *PlannerAdapter) SetStatisticsProvider(provider StatisticsProvider) {
+	pa.planner.SetStatisticsProvider(provider)
+}
+
 // GetUnderlyingPlanner returns the wrapped old planner (for testing/migration)
 func (pa *PlannerAdapter) GetUnderlyingPlanner() *Planner {
 	return pa.planner
@@ -86,6 +97,11 @@ func (p *ClauseBasedPlanner) SetCache(cache *PlanCache) {
 	p.cache = cache
 }
 
+// SetStatisticsProvider implements QueryPlanner for ClauseBasedPlanner
+func (p *ClauseBasedPlanner) SetStatisticsProvider(provider StatisticsProvider) {
+	p.statsProvider = provider
+}
+
 // CreatePlanner creates the appropriate planner based on options
 func CreatePlanner(stats *Statistics, options PlannerOptions) QueryPlanner {
 	if options.UseClauseBasedPlanner {
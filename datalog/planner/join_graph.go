@@ -0,0 +1,121 @@
+package planner
+
+import (
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// JoinGraph records which of a list of nodes (relations, patterns, phases)
+// share symbols. Two nodes are joined by an edge when they have at least one
+// symbol in common; nodes in different connected components can only be
+// combined by a Cartesian product.
+//
+// Build one with NewJoinGraph from the symbols of each node; executor.Relations
+// builds one from relation columns with JoinGraph.
+type JoinGraph struct {
+	Symbols [][]query.Symbol // The symbols of each node
+	Edges   [][]int          // Edges[i] lists the nodes sharing a symbol with node i, ascending
+}
+
+// NewJoinGraph builds the join graph of nodes with the given symbols
+func NewJoinGraph(symbols [][]query.Symbol) *JoinGraph {
+	g := &JoinGraph{
+		Symbols: symbols,
+		Edges:   make([][]int, len(symbols)),
+	}
+
+	// Index the nodes by symbol so shared symbols are found in one pass
+	bySymbol := make(map[query.Symbol][]int)
+	for i, syms := range symbols {
+		for _, sym := range syms {
+			nodes := bySymbol[sym]
+			if len(nodes) == 0 || nodes[len(nodes)-1] != i {
+				bySymbol[sym] = append(nodes, i)
+			}
+		}
+	}
+
+	for i, syms := range symbols {
+		linked := make(map[int]bool)
+		for _, sym := range syms {
+			for _, j := range bySymbol[sym] {
+				linked[j] = j != i
+			}
+		}
+		for j := range symbols {
+			if linked[j] {
+				g.Edges[i] = append(g.Edges[i], j)
+			}
+		}
+	}
+	return g
+}
+
+// Shared returns the symbols nodes i and j have in common, in node i's order
+func (g *JoinGraph) Shared(i, j int) []query.Symbol {
+	other := make(map[query.Symbol]bool, len(g.Symbols[j]))
+	for _, sym := range g.Symbols[j] {
+		other[sym] = true
+	}
+	var shared []query.Symbol
+	for _, sym := range g.Symbols[i] {
+		if other[sym] {
+			shared = append(shared, sym)
+			other[sym] = false // Report repeated symbols once
+		}
+	}
+	return shared
+}
+
+// Components returns the connected components of the graph. Each component
+// lists its nodes in ascending order, and components are ordered by their
+// first node.
+func (g *JoinGraph) Components() [][]int {
+	component := make([]int, len(g.Symbols))
+	for i := range component {
+		component[i] = -1
+	}
+
+	var components [][]int
+	for start := range g.Symbols {
+		if component[start] >= 0 {
+			continue
+		}
+		id := len(components)
+		component[start] = id
+		stack := []int{start}
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, next := range g.Edges[node] {
+				if component[next] < 0 {
+					component[next] = id
+					stack = append(stack, next)
+				}
+			}
+		}
+		components = append(components, nil)
+	}
+
+	for node, id := range component {
+		components[id] = append(components[id], node)
+	}
+	return components
+}
+
+// Connected reports whether all nodes are in one component, i.e. combining
+// them needs no Cartesian product. A graph with no nodes is connected.
+func (g *JoinGraph) Connected() bool {
+	return len(g.Components()) <= 1
+}
+
+// ClauseJoinGraph builds the join graph of clauses from the symbols each
+// clause requires or provides. Clauses in separate components of the graph
+// would be combined by a Cartesian product.
+func ClauseJoinGraph(clauses []query.Clause) *JoinGraph {
+	symbols := make([][]query.Symbol, len(clauses))
+	for i, clause := range clauses {
+		syms := extractClauseSymbols(clause)
+		symbols[i] = append(append([]query.Symbol{}, syms.Requires...), syms.Provides...)
+	}
+	return NewJoinGraph(symbols)
+}
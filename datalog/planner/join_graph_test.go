@@ -0,0 +1,99 @@
+package planner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestJoinGraph(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbols    [][]query.Symbol
+		edges      string
+		components string
+	}{
+		{
+			name:       "empty",
+			edges:      "[]",
+			components: "[]",
+		},
+		{
+			name:       "chain",
+			symbols:    [][]query.Symbol{{"?a", "?b"}, {"?b", "?c"}, {"?c", "?d"}},
+			edges:      "[[1] [0 2] [1]]",
+			components: "[[0 1 2]]",
+		},
+		{
+			name:       "connected through a later node",
+			symbols:    [][]query.Symbol{{"?a"}, {"?b"}, {"?a", "?b"}},
+			edges:      "[[2] [2] [0 1]]",
+			components: "[[0 1 2]]",
+		},
+		{
+			name:       "disjoint groups",
+			symbols:    [][]query.Symbol{{"?a", "?b"}, {"?x"}, {"?b"}, {"?x", "?y"}, {}},
+			edges:      "[[2] [3] [0] [1] []]",
+			components: "[[0 2] [1 3] [4]]",
+		},
+		{
+			name:       "repeated symbols",
+			symbols:    [][]query.Symbol{{"?a", "?a"}, {"?a"}},
+			edges:      "[[1] [0]]",
+			components: "[[0 1]]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewJoinGraph(tt.symbols)
+			if got := fmt.Sprint(g.Edges); got != tt.edges {
+				t.Errorf("Expected edges %s, got %s", tt.edges, got)
+			}
+			components := g.Components()
+			if got := fmt.Sprint(components); got != tt.components {
+				t.Errorf("Expected components %s, got %s", tt.components, got)
+			}
+			if g.Connected() != (len(components) <= 1) {
+				t.Errorf("Connected() = %v with %d components", g.Connected(), len(components))
+			}
+		})
+	}
+}
+
+func TestJoinGraphShared(t *testing.T) {
+	g := NewJoinGraph([][]query.Symbol{{"?a", "?b", "?c", "?b"}, {"?c", "?b", "?d"}})
+	if got := fmt.Sprint(g.Shared(0, 1)); got != "[?b ?c]" {
+		t.Errorf("Expected [?b ?c], got %s", got)
+	}
+	if got := fmt.Sprint(g.Shared(1, 0)); got != "[?c ?b]" {
+		t.Errorf("Expected [?c ?b], got %s", got)
+	}
+}
+
+func TestClauseJoinGraph(t *testing.T) {
+	q, err := parser.ParseQuery(`[:find ?n1 ?n2
+	  :where [?p1 :person/name ?n1]
+	         [?p2 :person/name ?n2]
+	         [?p1 :person/age ?a1]
+	         [(> ?a1 21)]
+	         [?p2 :person/type "employee"]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	g := ClauseJoinGraph(q.Where)
+	if g.Connected() {
+		t.Fatal("Expected the two people to be disjoint")
+	}
+	if got := fmt.Sprint(g.Components()); got != "[[0 2 3] [1 4]]" {
+		t.Errorf("Expected components [[0 2 3] [1 4]], got %s", got)
+	}
+
+	q.Where = append(q.Where, &query.Comparison{Op: query.OpLT, Left: query.VariableTerm{Symbol: "?a1"}, Right: query.VariableTerm{Symbol: "?n2"}})
+	if !ClauseJoinGraph(q.Where).Connected() {
+		t.Error("Expected the comparison to connect both people")
+	}
+}
@@ -0,0 +1,217 @@
+package planner
+
+import (
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// LatestPerEntity describes a "latest value per entity" query: for each
+// group, the entities whose ordering value is the group's maximum (or
+// minimum), e.g. the most recent price bar of every symbol.
+//
+// It is recognized in its max-subquery form:
+//
+//	[?b :price/symbol ?s]
+//	[?b :price/time ?t]
+//	[(q [:find (max ?t2) :in $ ?sym
+//	     :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2]]
+//	    $ ?s) [[?t]]]
+//
+// which runs one aggregation per group. Scanning [?b :price/time ?t] in
+// descending value order instead, the first row seen for each group is its
+// maximum, so the subquery is replaced by a single ordered scan that emits
+// each group's first rows (ties included) and stops once every group has
+// been seen.
+type LatestPerEntity struct {
+	Link       *query.DataPattern     // [?b :link/attr ?s]
+	Order      *query.DataPattern     // [?b :order/attr ?t]
+	Subquery   *query.SubqueryPattern // The max (or min) subquery replaced by the scan
+	Entity     query.Symbol           // ?b
+	Group      query.Symbol           // ?s
+	Value      query.Symbol           // ?t, bound to the aggregate
+	Descending bool                   // max scans in descending order, min in ascending
+}
+
+// FindLatestPerEntity reports whether q contains a latest-per-entity
+// subquery that can be executed as an ordered scan.
+//
+// This requires:
+//   - no inputs other than the database
+//   - a subquery [:find (max ?t2) :in $ ?sym :where [?b2 :link ?sym] [?b2 :order ?t2]]
+//     (or min) with exactly those two patterns, called with $ and an outer
+//     variable ?s and bound to [[?t]]
+//   - outer patterns [?b :link ?s] and [?b :order ?t] with the same
+//     constant attributes and nothing in tx position
+func FindLatestPerEntity(q *query.Query) (*LatestPerEntity, bool) {
+	if q == nil {
+		return nil, false
+	}
+	for _, in := range q.In {
+		if _, ok := in.(query.DatabaseInput); !ok {
+			return nil, false
+		}
+	}
+
+	for _, clause := range q.Where {
+		sq, ok := clause.(*query.SubqueryPattern)
+		if !ok {
+			continue
+		}
+		linkAttr, orderAttr, descending, ok := latestSubquery(sq)
+		if !ok {
+			continue
+		}
+		group, ok := sq.Inputs[1].(query.Variable)
+		if !ok {
+			continue
+		}
+		value := sq.Binding.(query.TupleBinding).Variables[0]
+
+		// Outer patterns [?b :link ?s] and [?b :order ?t] on the same ?b
+		for _, c := range q.Where {
+			link, ok := c.(*query.DataPattern)
+			if !ok {
+				continue
+			}
+			entity, ok := simplePattern(link, linkAttr, group.Name)
+			if !ok {
+				continue
+			}
+			for _, c := range q.Where {
+				order, ok := c.(*query.DataPattern)
+				if !ok || order == link {
+					continue
+				}
+				if e, ok := simplePattern(order, orderAttr, value); ok && e == entity {
+					return &LatestPerEntity{
+						Link:       link,
+						Order:      order,
+						Subquery:   sq,
+						Entity:     entity,
+						Group:      group.Name,
+						Value:      value,
+						Descending: descending,
+					}, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// latestSubquery matches the subquery of a latest-per-entity query, returning
+// its link and order attributes and whether it takes the maximum
+func latestSubquery(sq *query.SubqueryPattern) (datalog.Keyword, datalog.Keyword, bool, bool) {
+	var none datalog.Keyword
+	sub := sq.Query
+	binding, ok := sq.Binding.(query.TupleBinding)
+	if sub == nil || !ok || len(binding.Variables) != 1 || len(sq.Inputs) != 2 {
+		return none, none, false, false
+	}
+	if db, ok := sq.Inputs[0].(query.Constant); !ok || db.Value != query.Symbol("$") {
+		return none, none, false, false
+	}
+
+	if len(sub.Find) != 1 || len(sub.In) != 2 || len(sub.Where) != 2 {
+		return none, none, false, false
+	}
+	agg, ok := sub.Find[0].(query.FindAggregate)
+	if !ok || agg.IsConditional() || (agg.Function != "max" && agg.Function != "min") {
+		return none, none, false, false
+	}
+	if _, ok := sub.In[0].(query.DatabaseInput); !ok {
+		return none, none, false, false
+	}
+	groupIn, ok := sub.In[1].(query.ScalarInput)
+	if !ok {
+		return none, none, false, false
+	}
+
+	first, ok1 := sub.Where[0].(*query.DataPattern)
+	second, ok2 := sub.Where[1].(*query.DataPattern)
+	if !ok1 || !ok2 {
+		return none, none, false, false
+	}
+	for _, pair := range [][2]*query.DataPattern{{first, second}, {second, first}} {
+		link, order := pair[0], pair[1]
+		linkAttr, ok := constantAttribute(link)
+		if !ok {
+			continue
+		}
+		orderAttr, ok := constantAttribute(order)
+		if !ok {
+			continue
+		}
+		entity, ok := simplePattern(link, linkAttr, groupIn.Symbol)
+		if !ok {
+			continue
+		}
+		if e, ok := simplePattern(order, orderAttr, agg.Arg); ok && e == entity {
+			return linkAttr, orderAttr, agg.Function == "max", true
+		}
+	}
+	return none, none, false, false
+}
+
+// simplePattern matches [?e attr ?v] with the given attribute and value
+// variable and nothing in tx position, returning ?e
+func simplePattern(p *query.DataPattern, attr datalog.Keyword, value query.Symbol) (query.Symbol, bool) {
+	a, ok := constantAttribute(p)
+	if !ok || a.String() != attr.String() {
+		return "", false
+	}
+	e, eOK := p.GetE().(query.Variable)
+	v, vOK := p.GetV().(query.Variable)
+	if !eOK || !vOK || v.Name != value || e.Name == v.Name {
+		return "", false
+	}
+	if t := p.GetT(); t != nil && !t.IsBlank() {
+		return "", false
+	}
+	return e.Name, true
+}
+
+func constantAttribute(p *query.DataPattern) (datalog.Keyword, bool) {
+	c, ok := p.GetA().(query.Constant)
+	if !ok {
+		return datalog.Keyword{}, false
+	}
+	kw, ok := c.Value.(datalog.Keyword)
+	return kw, ok
+}
+
+// Remainder returns the query evaluated on the scan's (?b ?s ?t) rows: the
+// same :find, :where and :order-by, taking the rows as a relation input, with
+// the subquery and the two patterns it covers removed (the order pattern is
+// kept if nothing else remains)
+func (l *LatestPerEntity) Remainder(q *query.Query) *query.Query {
+	var where []query.Clause
+	for _, clause := range q.Where {
+		switch c := clause.(type) {
+		case *query.DataPattern:
+			if c == l.Link || c == l.Order {
+				continue
+			}
+		case *query.SubqueryPattern:
+			if c == l.Subquery {
+				continue
+			}
+		}
+		where = append(where, clause)
+	}
+	if len(where) == 0 {
+		// Plans need at least one clause; the order pattern only re-checks
+		// the bound rows
+		where = []query.Clause{l.Order}
+	}
+	return &query.Query{
+		Find: q.Find,
+		In: []query.InputSpec{
+			query.DatabaseInput{},
+			query.RelationInput{Symbols: []query.Symbol{l.Entity, l.Group, l.Value}},
+		},
+		Where:        where,
+		OrderBy:      q.OrderBy,
+		GroupingSets: q.GroupingSets,
+	}
+}
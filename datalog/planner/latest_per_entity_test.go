@@ -0,0 +1,86 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestFindLatestPerEntity(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		expected   bool
+		descending bool
+	}{
+		{
+			name: "max subquery",
+			query: `[:find ?s ?c :where [?b :price/symbol ?s] [?b :price/time ?t]
+			         [(q [:find (max ?t2) :in $ ?sym :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2]] $ ?s) [[?t]]]
+			         [?b :price/close ?c]]`,
+			expected:   true,
+			descending: true,
+		},
+		{
+			name: "min subquery with patterns reversed",
+			query: `[:find ?s ?c :where [?b :price/time ?first] [?b :price/close ?c] [?b :price/symbol ?s]
+			         [(q [:find (min ?t) :in $ ?sym :where [?x :price/time ?t] [?x :price/symbol ?sym]] $ ?s) [[?first]]]]`,
+			expected: true,
+		},
+		{
+			name: "extra filter in subquery",
+			query: `[:find ?s ?t :where [?b :price/symbol ?s] [?b :price/time ?t]
+			         [(q [:find (max ?t2) :in $ ?sym :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2] [(> ?t2 0)]] $ ?s) [[?t]]]]`,
+		},
+		{
+			name: "different attribute in outer query",
+			query: `[:find ?s ?t :where [?b :price/symbol ?s] [?b :price/open-time ?t]
+			         [(q [:find (max ?t2) :in $ ?sym :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2]] $ ?s) [[?t]]]]`,
+		},
+		{
+			name: "outer patterns on different entities",
+			query: `[:find ?s ?t :where [?b :price/symbol ?s] [?o :price/time ?t]
+			         [(q [:find (max ?t2) :in $ ?sym :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2]] $ ?s) [[?t]]]]`,
+		},
+		{
+			name: "sum aggregate",
+			query: `[:find ?s ?t :where [?b :price/symbol ?s] [?b :price/time ?t]
+			         [(q [:find (sum ?t2) :in $ ?sym :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2]] $ ?s) [[?t]]]]`,
+		},
+		{
+			name: "input parameter",
+			query: `[:find ?t :in $ ?s :where [?b :price/symbol ?s] [?b :price/time ?t]
+			         [(q [:find (max ?t2) :in $ ?sym :where [?b2 :price/symbol ?sym] [?b2 :price/time ?t2]] $ ?s) [[?t]]]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			scan, ok := FindLatestPerEntity(q)
+			if ok != tt.expected {
+				t.Fatalf("Expected latest per entity %v, got %v", tt.expected, ok)
+			}
+			if !ok {
+				return
+			}
+			if scan.Descending != tt.descending {
+				t.Errorf("Expected descending=%v, got %v", tt.descending, scan.Descending)
+			}
+
+			remainder := scan.Remainder(q)
+			if len(q.Where) > 3 && len(remainder.Where) != len(q.Where)-3 {
+				t.Errorf("Expected the subquery and its two patterns removed, got %v", remainder.Where)
+			}
+			input, ok := remainder.In[1].(query.RelationInput)
+			if !ok || len(input.Symbols) != 3 || input.Symbols[0] != scan.Entity || input.Symbols[1] != scan.Group || input.Symbols[2] != scan.Value {
+				t.Errorf("Expected relation input [%s %s %s], got %v", scan.Entity, scan.Group, scan.Value, remainder.In)
+			}
+		})
+	}
+}
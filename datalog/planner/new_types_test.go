@@ -88,16 +88,11 @@ func TestPlannerWithNewPredicateTypes(t *testing.T) {
 				// and pushed to storage constraints
 				hasTimeConstraints := false
 				for _, pattern := range phase.Patterns {
-					if pattern.Metadata != nil {
-						if constraints, ok := pattern.Metadata["storage_constraints"]; ok {
-							hasTimeConstraints = true
-							// Verify constraints are properly typed
-							if constraintList, ok := constraints.([]StorageConstraint); ok {
-								for _, c := range constraintList {
-									if c.Type == ConstraintTimeExtraction {
-										t.Logf("Found time extraction constraint: field=%s, value=%v", c.TimeField, c.Value)
-									}
-								}
+					if len(pattern.Constraints) > 0 {
+						hasTimeConstraints = true
+						for _, c := range pattern.Constraints {
+							if c.Type == ConstraintTimeExtraction {
+								t.Logf("Found time extraction constraint: field=%s, value=%v", c.TimeField, c.Value)
 							}
 						}
 					}
@@ -0,0 +1,102 @@
+package planner
+
+import (
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// OrderedScan describes how a query's :order-by can be produced by scanning
+// one of its patterns in value order instead of sorting the final result.
+//
+// The driving pattern has the form [?e :attr ?v] where ?v is the first
+// :order-by variable. Scanning it in value order (AVET for a constant
+// attribute) yields (?e ?v) bindings already ordered by ?v; the rest of the
+// query is evaluated for consecutive runs of those bindings, and only each
+// run's results need sorting. With :limit, the scan stops as soon as enough
+// results have been produced.
+type OrderedScan struct {
+	Pattern    *query.DataPattern // Driving pattern [?e :attr ?v]
+	Entity     query.Symbol       // ?e
+	Value      query.Symbol       // ?v, the first :order-by variable
+	Descending bool               // Scan in descending value order
+}
+
+// FindOrderedScan reports whether q can be executed as an ordered scan.
+//
+// This requires:
+//   - an :order-by whose first variable is returned by :find
+//   - no aggregates in :find (ordering applies to groups, not pattern bindings)
+//   - no inputs other than the database
+//   - a data pattern [?e :attr ?v] with a constant attribute, binding the
+//     first :order-by variable in value position and nothing in tx position
+func FindOrderedScan(q *query.Query) (*OrderedScan, bool) {
+	if q == nil || len(q.OrderBy) == 0 {
+		return nil, false
+	}
+
+	for _, in := range q.In {
+		if _, ok := in.(query.DatabaseInput); !ok {
+			return nil, false
+		}
+	}
+
+	orderVar := q.OrderBy[0].Variable
+	inFind := false
+	for _, elem := range q.Find {
+		if elem.IsAggregate() {
+			return nil, false
+		}
+		if v, ok := elem.(query.FindVariable); ok && v.Symbol == orderVar {
+			inFind = true
+		}
+	}
+	if !inFind {
+		return nil, false
+	}
+
+	for _, clause := range q.Where {
+		pattern, ok := clause.(*query.DataPattern)
+		if !ok {
+			continue
+		}
+
+		e, eOK := pattern.GetE().(query.Variable)
+		v, vOK := pattern.GetV().(query.Variable)
+		if !eOK || !vOK || v.Name != orderVar || e.Name == v.Name {
+			continue
+		}
+		a, aOK := pattern.GetA().(query.Constant)
+		if !aOK {
+			continue
+		}
+		if _, isKeyword := a.Value.(datalog.Keyword); !isKeyword {
+			continue
+		}
+		if t := pattern.GetT(); t != nil && !t.IsBlank() {
+			continue
+		}
+
+		return &OrderedScan{
+			Pattern:    pattern,
+			Entity:     e.Name,
+			Value:      v.Name,
+			Descending: q.OrderBy[0].Direction == query.OrderDesc,
+		}, true
+	}
+
+	return nil, false
+}
+
+// Remainder returns the query evaluated for each run of ordered bindings:
+// the same :find and :where, taking the driving pattern's (?e ?v) bindings
+// as a relation input, without ordering or execution options.
+func (s *OrderedScan) Remainder(q *query.Query) *query.Query {
+	return &query.Query{
+		Find: q.Find,
+		In: []query.InputSpec{
+			query.DatabaseInput{},
+			query.RelationInput{Symbols: []query.Symbol{s.Entity, s.Value}},
+		},
+		Where: q.Where,
+	}
+}
@@ -0,0 +1,78 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestFindOrderedScan(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		expected   bool
+		descending bool
+	}{
+		{
+			name:     "ascending on value position",
+			query:    `[:find ?t ?c :where [?b :bar/time ?t] [?b :bar/close ?c] :order-by [?t]]`,
+			expected: true,
+		},
+		{
+			name:       "descending",
+			query:      `[:find ?t ?c :where [?b :bar/close ?c] [?b :bar/time ?t] :order-by [[?t :desc] ?c]]`,
+			expected:   true,
+			descending: true,
+		},
+		{
+			name:  "no order-by",
+			query: `[:find ?t :where [?b :bar/time ?t]]`,
+		},
+		{
+			name:  "aggregate",
+			query: `[:find ?b (max ?t) :where [?b :bar/time ?t] :order-by [?b]]`,
+		},
+		{
+			name:  "order variable not in value position",
+			query: `[:find ?b ?t :where [?b :bar/time ?t] :order-by [?b]]`,
+		},
+		{
+			name:  "order variable not returned",
+			query: `[:find ?c :where [?b :bar/time ?t] [?b :bar/close ?c] :order-by [?t]]`,
+		},
+		{
+			name:  "input parameter",
+			query: `[:find ?t :in $ ?b :where [?b :bar/time ?t] :order-by [?t]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			scan, ok := FindOrderedScan(q)
+			if ok != tt.expected {
+				t.Fatalf("Expected ordered scan %v, got %v", tt.expected, ok)
+			}
+			if !ok {
+				return
+			}
+			if scan.Entity != "?b" || scan.Value != "?t" || scan.Descending != tt.descending {
+				t.Errorf("Unexpected ordered scan %+v", scan)
+			}
+
+			remainder := scan.Remainder(q)
+			if len(remainder.OrderBy) != 0 || remainder.Limit != 0 {
+				t.Errorf("Remainder should not order or limit: %s", remainder)
+			}
+			rel, ok := remainder.In[1].(query.RelationInput)
+			if !ok || len(rel.Symbols) != 2 || rel.Symbols[0] != "?b" || rel.Symbols[1] != "?t" {
+				t.Errorf("Expected remainder to take [[?b ?t] ...], got %v", remainder.In)
+			}
+		})
+	}
+}
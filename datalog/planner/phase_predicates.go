@@ -105,11 +105,7 @@ func removePushedPredicates(predicates []PredicatePlan, patterns []PatternPlan)
 	// Collect all constraints that were pushed
 	var pushedConstraints []StorageConstraint
 	for _, pattern := range patterns {
-		if pattern.Metadata != nil {
-			if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-				pushedConstraints = append(pushedConstraints, constraints...)
-			}
-		}
+		pushedConstraints = append(pushedConstraints, pattern.Constraints...)
 	}
 
 	// Keep predicates that weren't converted to storage constraints
@@ -134,6 +130,7 @@ func removePushedPredicates(predicates []PredicatePlan, patterns []PatternPlan)
 func constraintMatchesPredicate(constraint StorageConstraint, pred PredicatePlan) bool {
 	if constraint.Type == ConstraintTimeExtraction && pred.Type == PredicateTimeExtraction {
 		return constraint.TimeField == pred.TimeField &&
+			constraint.TimeZone == pred.TimeZone &&
 			constraint.Value == pred.Value &&
 			constraint.Operator == pred.Operator
 	}
@@ -154,16 +151,7 @@ func constraintMatchesPredicate(constraint StorageConstraint, pred PredicatePlan
 func analyzeSelectivity(pattern PatternPlan) float64 {
 	selectivity := 1.0
 
-	if pattern.Metadata == nil {
-		return selectivity
-	}
-
-	constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint)
-	if !ok {
-		return selectivity
-	}
-
-	for _, constraint := range constraints {
+	for _, constraint := range pattern.Constraints {
 		switch constraint.Type {
 		case ConstraintEquality:
 			selectivity *= 0.01 // Exact match is very selective
@@ -0,0 +1,347 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// Plan manipulation API
+//
+// RealizedPlan is the stable interchange format between planner and executor,
+// so custom optimization passes can operate on it directly. The workflow is:
+//
+//	plan, err := exec.PlanQuery(q)          // or any QueryPlanner
+//	plan = plan.Clone()                     // never mutate a cached plan
+//	err = plan.RemovePhase(2)               // restructure phases
+//	err = plan.PinIndex(0, pattern, planner.AVET)
+//	err = plan.Validate()                   // check symbol flow
+//	rel, err := exec.ExecuteRealized(ctx, plan, nil)
+//
+// Phase queries may also be edited in place (e.g. reordering Where clauses or
+// changing Keep); call Relink afterwards to rebuild the :in and :find clauses
+// that connect the phases.
+
+// String returns the index name (EAVT, AEVT, ...)
+func (idx IndexType) String() string {
+	return indexName(idx)
+}
+
+// ParseIndexType parses an index name such as "AVET" (case-insensitive)
+func ParseIndexType(name string) (IndexType, error) {
+	for _, idx := range []IndexType{EAVT, AEVT, AVET, VAET, TAEV} {
+		if strings.EqualFold(name, indexName(idx)) {
+			return idx, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown index type: %s", name)
+}
+
+// Clone returns a copy of the plan that can be mutated without affecting the
+// original. Phase queries, symbol lists, metadata maps and index pins are copied;
+// the clauses themselves are shared, so pins made on the clone refer to the same
+// *query.DataPattern values as the original.
+func (rpl *RealizedPlan) Clone() *RealizedPlan {
+	clone := &RealizedPlan{
+		Query:  rpl.Query,
+		Phases: make([]RealizedPhase, len(rpl.Phases)),
+		Cached: rpl.Cached,
+	}
+	for i, phase := range rpl.Phases {
+		clone.Phases[i] = phase.clone()
+	}
+	return clone
+}
+
+func (rp RealizedPhase) clone() RealizedPhase {
+	c := RealizedPhase{
+		Available: append([]query.Symbol(nil), rp.Available...),
+		Provides:  append([]query.Symbol(nil), rp.Provides...),
+		Keep:      append([]query.Symbol(nil), rp.Keep...),
+	}
+	if rp.Query != nil {
+		q := *rp.Query
+		q.Find = append([]query.FindElement(nil), rp.Query.Find...)
+		q.In = append([]query.InputSpec(nil), rp.Query.In...)
+		q.Where = append([]query.Clause(nil), rp.Query.Where...)
+		c.Query = &q
+	}
+	if rp.Metadata != nil {
+		c.Metadata = make(map[string]interface{}, len(rp.Metadata))
+		for k, v := range rp.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	if rp.IndexPins != nil {
+		c.IndexPins = make(map[*query.DataPattern]IndexType, len(rp.IndexPins))
+		for k, v := range rp.IndexPins {
+			c.IndexPins[k] = v
+		}
+	}
+	return c
+}
+
+// Patterns returns the data patterns of this phase in execution order
+func (rp *RealizedPhase) Patterns() []*query.DataPattern {
+	var patterns []*query.DataPattern
+	if rp.Query == nil {
+		return nil
+	}
+	for _, clause := range rp.Query.Where {
+		if dp, ok := clause.(*query.DataPattern); ok {
+			patterns = append(patterns, dp)
+		}
+	}
+	return patterns
+}
+
+// PinnedIndex returns the index pinned for pattern, if any
+func (rp *RealizedPhase) PinnedIndex(pattern *query.DataPattern) (IndexType, bool) {
+	idx, ok := rp.IndexPins[pattern]
+	return idx, ok
+}
+
+// PinIndex forces the executor to scan pattern (which must be one of the
+// data patterns in the given phase) using index instead of the index the
+// storage layer would choose. Pins are hints: matchers that do not support
+// them fall back to regular matching.
+func (rpl *RealizedPlan) PinIndex(phase int, pattern *query.DataPattern, index IndexType) error {
+	if phase < 0 || phase >= len(rpl.Phases) {
+		return fmt.Errorf("phase %d out of range (plan has %d phases)", phase, len(rpl.Phases))
+	}
+	if index > TAEV {
+		return fmt.Errorf("invalid index type: %s", index)
+	}
+	p := &rpl.Phases[phase]
+	if !containsPattern(p.Patterns(), pattern) {
+		return fmt.Errorf("pattern %s is not part of phase %d", pattern, phase)
+	}
+	if p.IndexPins == nil {
+		p.IndexPins = make(map[*query.DataPattern]IndexType)
+	}
+	p.IndexPins[pattern] = index
+	return nil
+}
+
+// UnpinIndex removes an index pin, restoring automatic index selection
+func (rpl *RealizedPlan) UnpinIndex(phase int, pattern *query.DataPattern) {
+	if phase < 0 || phase >= len(rpl.Phases) {
+		return
+	}
+	delete(rpl.Phases[phase].IndexPins, pattern)
+}
+
+// RemovePhase deletes phase i and relinks the remaining phases. If the last
+// phase is removed, the new last phase takes over the final :find clause.
+// The only phase of a plan cannot be removed.
+func (rpl *RealizedPlan) RemovePhase(i int) error {
+	if i < 0 || i >= len(rpl.Phases) {
+		return fmt.Errorf("phase %d out of range (plan has %d phases)", i, len(rpl.Phases))
+	}
+	if len(rpl.Phases) == 1 {
+		return fmt.Errorf("cannot remove the only phase of a plan")
+	}
+
+	removed := rpl.Phases[i]
+	rpl.Phases = append(rpl.Phases[:i:i], rpl.Phases[i+1:]...)
+
+	if i == len(rpl.Phases) && removed.Query != nil {
+		last := &rpl.Phases[len(rpl.Phases)-1]
+		last.Query.Find = removed.Query.Find
+	}
+
+	rpl.Relink()
+	return nil
+}
+
+// Relink rebuilds the :in clause of every phase and the :find clause of every
+// intermediate phase from the Keep lists, mirroring what Realize produces.
+// The last phase keeps its :find clause, which carries the query's aggregates.
+func (rpl *RealizedPlan) Relink() {
+	for i := range rpl.Phases {
+		phase := &rpl.Phases[i]
+		if phase.Query == nil {
+			continue
+		}
+
+		var in []query.InputSpec
+		if i > 0 && len(rpl.Phases[i-1].Keep) > 0 {
+			in = append(in, query.DatabaseInput{})
+			in = append(in, query.RelationInput{Symbols: rpl.Phases[i-1].Keep})
+		}
+		phase.Query.In = in
+
+		if i < len(rpl.Phases)-1 {
+			var find []query.FindElement
+			for _, sym := range phase.Keep {
+				find = append(find, query.FindVariable{Symbol: sym})
+			}
+			phase.Query.Find = find
+		}
+	}
+}
+
+// Validate checks that symbols flow correctly through the plan: every clause's
+// required symbols must be bound by an input, an earlier phase's Keep, or an
+// earlier clause in the same phase; each phase must bind what it keeps; and
+// the last phase must bind every :find variable. A :find variable that a
+// Keep drops is reported as a *DroppedSymbolError. Validate should be called
+// after manipulating a plan and before executing it.
+func (rpl *RealizedPlan) Validate() error {
+	if len(rpl.Phases) == 0 {
+		return fmt.Errorf("plan has no phases")
+	}
+
+	bound := make(map[query.Symbol]bool)
+	if rpl.Query != nil {
+		for _, sym := range inputSymbols(rpl.Query.In) {
+			bound[sym] = true
+		}
+	}
+
+	// A :find symbol dropped by a Keep is reported as such, rather than as
+	// unbound in the last phase
+	if last := rpl.Phases[len(rpl.Phases)-1]; last.Query != nil {
+		inputs := make(map[query.Symbol]bool, len(bound))
+		for sym := range bound {
+			inputs[sym] = true
+		}
+		if err := validateKeepProjections(realizedKeepSteps(rpl.Phases), last.Query.Find, inputs); err != nil {
+			return err
+		}
+	}
+
+	for i, phase := range rpl.Phases {
+		if phase.Query == nil {
+			return fmt.Errorf("phase %d has no query", i)
+		}
+
+		for _, sym := range inputSymbols(phase.Query.In) {
+			if !bound[sym] {
+				return fmt.Errorf("phase %d: input %s is not provided by an earlier phase", i, sym)
+			}
+		}
+
+		for _, clause := range phase.Query.Where {
+			syms := validationSymbols(clause)
+			for _, sym := range syms.Requires {
+				if !bound[sym] {
+					return fmt.Errorf("phase %d: clause %s requires unbound symbol %s", i, clauseString(clause), sym)
+				}
+			}
+			for _, sym := range syms.Provides {
+				bound[sym] = true
+			}
+		}
+
+		for pattern := range phase.IndexPins {
+			if !containsPattern(phase.Patterns(), pattern) {
+				return fmt.Errorf("phase %d: index pin for pattern %s that is not part of the phase", i, pattern)
+			}
+		}
+
+		if i == len(rpl.Phases)-1 {
+			for _, elem := range phase.Query.Find {
+				for _, sym := range findElementSymbols(elem) {
+					if sym != "" && !bound[sym] {
+						return fmt.Errorf("phase %d: find element %s uses unbound symbol %s", i, elem, sym)
+					}
+				}
+			}
+			break
+		}
+
+		// An empty Keep passes every column through to the next phase
+		if len(phase.Keep) == 0 {
+			continue
+		}
+		next := make(map[query.Symbol]bool, len(phase.Keep))
+		for _, sym := range phase.Keep {
+			if !bound[sym] {
+				return fmt.Errorf("phase %d: keeps symbol %s that it does not bind", i, sym)
+			}
+			next[sym] = true
+		}
+		bound = next
+	}
+
+	return nil
+}
+
+// validationSymbols extends extractClauseSymbols with the clause types that
+// the executor evaluates but the clause-based planner does not schedule.
+func validationSymbols(clause query.Clause) ClauseSymbols {
+	switch c := clause.(type) {
+	case *query.DataPattern, *query.Expression, *query.Subquery:
+		return extractClauseSymbols(c)
+	case *query.SubqueryPattern:
+		var syms ClauseSymbols
+		for _, input := range c.Inputs {
+			if v, ok := input.(query.Variable); ok {
+				syms.Requires = append(syms.Requires, v.Name)
+			}
+		}
+		switch b := c.Binding.(type) {
+		case query.TupleBinding:
+			syms.Provides = b.Variables
+		case query.RelationBinding:
+			syms.Provides = b.Variables
+		case query.CollectionBinding:
+			syms.Provides = []query.Symbol{b.Variable}
+		}
+		return syms
+	case query.Predicate:
+		return ClauseSymbols{Requires: c.RequiredSymbols()}
+	default:
+		return extractClauseSymbols(c)
+	}
+}
+
+// inputSymbols returns the symbols bound by an :in clause
+func inputSymbols(in []query.InputSpec) []query.Symbol {
+	var syms []query.Symbol
+	for _, spec := range in {
+		switch s := spec.(type) {
+		case query.ScalarInput:
+			syms = append(syms, s.Symbol)
+		case query.CollectionInput:
+			syms = append(syms, s.Symbol)
+		case query.TupleInput:
+			syms = append(syms, s.Symbols...)
+		case query.RelationInput:
+			syms = append(syms, s.Symbols...)
+		}
+	}
+	return syms
+}
+
+// findElementSymbols returns the symbols a :find element reads
+func findElementSymbols(elem query.FindElement) []query.Symbol {
+	switch e := elem.(type) {
+	case query.FindVariable:
+		return []query.Symbol{e.Symbol}
+	case query.FindAggregate:
+		if e.IsConditional() {
+			return []query.Symbol{e.Arg, e.Predicate}
+		}
+		return []query.Symbol{e.Arg}
+	}
+	return nil
+}
+
+func containsPattern(patterns []*query.DataPattern, pattern *query.DataPattern) bool {
+	for _, dp := range patterns {
+		if dp == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func clauseString(clause query.Clause) string {
+	if s, ok := clause.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", clause)
+}
@@ -0,0 +1,166 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func planForEdit(t *testing.T, queryStr string) *RealizedPlan {
+	t.Helper()
+	q, err := parser.ParseQuery(queryStr)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	plan, err := NewPlannerAdapter(nil, PlannerOptions{}).PlanQuery(q)
+	if err != nil {
+		t.Fatalf("Failed to plan query: %v", err)
+	}
+	return plan
+}
+
+const twoPhaseQuery = `[:find ?name (max ?age)
+                        :in $ ?min
+                        :where
+                        [?e :person/name ?name]
+                        [?e :person/age ?age]
+                        [(> ?age ?min)]
+                        [?e :person/friend ?f]
+                        [?f :person/name ?fn]]`
+
+func TestRealizedPlanValidate(t *testing.T) {
+	queries := []string{
+		`[:find ?name :where [?e :person/name ?name]]`,
+		`[:find ?name ?cname :where [?e :person/name ?name] [?e :person/company ?c] [?c :company/name ?cname]]`,
+		`[:find ?e ?m :where [?e :event/time ?t] [(month ?t) ?m] [(= ?m 3)]]`,
+		twoPhaseQuery,
+	}
+	for _, qs := range queries {
+		if err := planForEdit(t, qs).Validate(); err != nil {
+			t.Errorf("Planner output failed validation for %s: %v", qs, err)
+		}
+	}
+
+	// Breaking the symbol flow must be detected
+	plan := planForEdit(t, `[:find ?e ?m :where [?e :event/time ?t] [(month ?t) ?m]]`)
+	phase := &plan.Phases[0]
+	phase.Query.Where = phase.Query.Where[1:] // drop the pattern that binds ?t
+	err := plan.Validate()
+	if err == nil || !strings.Contains(err.Error(), "requires unbound symbol ?t") {
+		t.Errorf("Expected unbound symbol error, got %v", err)
+	}
+}
+
+func TestRealizedPlanCloneIsIndependent(t *testing.T) {
+	plan := planForEdit(t, twoPhaseQuery)
+	if len(plan.Phases) < 2 {
+		t.Fatalf("Expected a multi-phase plan, got %d phases", len(plan.Phases))
+	}
+	originalWhere := len(plan.Phases[0].Query.Where)
+	originalKeep := len(plan.Phases[0].Keep)
+
+	clone := plan.Clone()
+	clone.Phases[0].Query.Where = clone.Phases[0].Query.Where[:0]
+	clone.Phases[0].Keep = append(clone.Phases[0].Keep, "?extra")
+	if err := clone.RemovePhase(1); err != nil {
+		t.Fatalf("RemovePhase failed: %v", err)
+	}
+
+	if len(plan.Phases[0].Query.Where) != originalWhere {
+		t.Error("Mutating clone's Where changed the original plan")
+	}
+	if len(plan.Phases[0].Keep) != originalKeep {
+		t.Error("Mutating clone's Keep changed the original plan")
+	}
+	if len(plan.Phases) < 2 {
+		t.Error("Removing a phase from the clone changed the original plan")
+	}
+}
+
+func TestRealizedPlanRemovePhase(t *testing.T) {
+	plan := planForEdit(t, twoPhaseQuery)
+	phases := len(plan.Phases)
+	finalFind := plan.Phases[phases-1].Query.Find
+
+	if err := plan.RemovePhase(phases); err == nil {
+		t.Error("Expected out of range error")
+	}
+
+	if err := plan.RemovePhase(phases - 1); err != nil {
+		t.Fatalf("RemovePhase failed: %v", err)
+	}
+	if len(plan.Phases) != phases-1 {
+		t.Fatalf("Expected %d phases, got %d", phases-1, len(plan.Phases))
+	}
+
+	// The new last phase takes over the final :find (with the aggregate)
+	last := plan.Phases[len(plan.Phases)-1]
+	if len(last.Query.Find) != len(finalFind) || !last.Query.Find[1].IsAggregate() {
+		t.Errorf("Expected final :find %v on new last phase, got %v", finalFind, last.Query.Find)
+	}
+	if len(plan.Phases[0].Query.In) != 0 {
+		t.Errorf("First phase should have no :in after relinking, got %v", plan.Phases[0].Query.In)
+	}
+
+	for len(plan.Phases) > 1 {
+		if err := plan.RemovePhase(0); err != nil {
+			t.Fatalf("RemovePhase failed: %v", err)
+		}
+	}
+	if err := plan.RemovePhase(0); err == nil {
+		t.Error("Expected error removing the only phase")
+	}
+}
+
+func TestRealizedPlanPinIndex(t *testing.T) {
+	plan := planForEdit(t, `[:find ?name :where [?e :person/name ?name]]`)
+	patterns := plan.Phases[0].Patterns()
+	if len(patterns) != 1 {
+		t.Fatalf("Expected 1 pattern, got %d", len(patterns))
+	}
+
+	if err := plan.PinIndex(0, patterns[0], AVET); err != nil {
+		t.Fatalf("PinIndex failed: %v", err)
+	}
+	if idx, ok := plan.Phases[0].PinnedIndex(patterns[0]); !ok || idx != AVET {
+		t.Errorf("Expected AVET pin, got %v (pinned=%v)", idx, ok)
+	}
+	if !strings.Contains(plan.Phases[0].String(), "AVET") {
+		t.Errorf("Expected pin in phase string:\n%s", plan.Phases[0].String())
+	}
+	if err := plan.Validate(); err != nil {
+		t.Errorf("Pinned plan failed validation: %v", err)
+	}
+
+	// Pins survive cloning
+	clone := plan.Clone()
+	if _, ok := clone.Phases[0].PinnedIndex(patterns[0]); !ok {
+		t.Error("Expected pin to survive Clone")
+	}
+	clone.UnpinIndex(0, patterns[0])
+	if _, ok := plan.Phases[0].PinnedIndex(patterns[0]); !ok {
+		t.Error("Unpinning the clone changed the original plan")
+	}
+
+	foreign := &query.DataPattern{Elements: patterns[0].Elements}
+	if err := plan.PinIndex(0, foreign, EAVT); err == nil {
+		t.Error("Expected error pinning a pattern that is not part of the phase")
+	}
+	if err := plan.PinIndex(1, patterns[0], EAVT); err == nil {
+		t.Error("Expected error pinning in a missing phase")
+	}
+}
+
+func TestParseIndexType(t *testing.T) {
+	for _, idx := range []IndexType{EAVT, AEVT, AVET, VAET, TAEV} {
+		parsed, err := ParseIndexType(strings.ToLower(idx.String()))
+		if err != nil || parsed != idx {
+			t.Errorf("ParseIndexType(%s) = %v, %v", idx, parsed, err)
+		}
+	}
+	if _, err := ParseIndexType("XYZ"); err == nil {
+		t.Error("Expected error for unknown index")
+	}
+}
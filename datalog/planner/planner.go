@@ -21,6 +21,7 @@ import (
 // Planner creates optimized query plans
 type Planner struct {
 	stats             *Statistics
+	statsProvider     StatisticsProvider // Overrides stats when set
 	options           PlannerOptions
 	expressionOutputs map[query.Symbol]bool // Track which variables are provided by expressions
 	cache             *PlanCache            // Query plan cache
@@ -48,33 +49,47 @@ func (p *Planner) Options() PlannerOptions {
 
 // Plan creates an optimized query plan
 func (p *Planner) Plan(q *query.Query) (*QueryPlan, error) {
-	// Check cache first (with planner options)
+	plan, _, err := p.PlanWithCacheStatus(q)
+	return plan, err
+}
+
+// PlanWithCacheStatus creates an optimized query plan like Plan, also
+// reporting whether the plan came from the plan cache
+func (p *Planner) PlanWithCacheStatus(q *query.Query) (*QueryPlan, bool, error) {
+	// Check cache first (with planner options and statistics epoch)
+	epoch := p.statistics().Epoch
 	if p.cache != nil {
-		if cached, ok := p.cache.GetWithOptions(q, p.options); ok {
-			return cached, nil
+		if cached, ok := p.cache.GetWithEpoch(q, p.options, epoch); ok {
+			return cached, true, nil
 		}
 	}
 
 	// Plan the query
 	plan, err := p.PlanWithBindings(q, nil)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	// Cache the plan (with planner options)
+	// Cache the plan (with planner options and statistics epoch)
 	if p.cache != nil {
-		p.cache.SetWithOptions(q, plan, p.options)
+		p.cache.SetWithEpoch(q, plan, p.options, epoch)
 	}
 
-	return plan, nil
+	return plan, false, nil
 }
 
 // PlanWithBindings creates an optimized query plan with initial bindings
-// This is used for subqueries where input parameters are already bound
+// This is used for subqueries where input parameters are already bound.
+// It is safe for concurrent use.
 func (p *Planner) PlanWithBindings(q *query.Query, initialBindings map[query.Symbol]bool) (*QueryPlan, error) {
-	// Separate patterns by type
-	dataPatterns, predicates, expressions, subqueries := p.separatePatterns(q.Where)
+	// Plan with a copy of the planner, so that concurrent and nested calls
+	// don't share the state kept while planning (expressionOutputs)
+	call := *p
+	return call.planWithBindings(q, initialBindings)
+}
 
+// planWithBindings implements PlanWithBindings on a planner of its own
+func (p *Planner) planWithBindings(q *query.Query, initialBindings map[query.Symbol]bool) (*QueryPlan, error) {
 	// Extract find symbols from FindElements
 	var findSymbols []query.Symbol
 	findSymbolSet := make(map[query.Symbol]bool)
@@ -120,6 +135,27 @@ func (p *Planner) PlanWithBindings(q *query.Query, initialBindings map[query.Sym
 		}
 	}
 
+	// Fold constant equality predicates into the patterns they constrain
+	if p.options.EnableEqualityConstantRewriting {
+		if where := rewriteEqualityConstants(q, inputSymbols); len(where) != len(q.Where) {
+			rewritten := *q
+			rewritten.Where = where
+			q = &rewritten
+		}
+	}
+
+	// Restrict patterns with a variable attribute to their [(in ?a #{...})] set
+	if p.options.EnableAttributeSetExpansion {
+		if where, ok := expandAttributeSets(q.Where); ok {
+			rewritten := *q
+			rewritten.Where = where
+			q = &rewritten
+		}
+	}
+
+	// Separate patterns by type
+	dataPatterns, predicates, expressions, subqueries := p.separatePatterns(q.Where)
+
 	// Collect expression output variables to avoid treating them as ground predicates
 	expressionOutputs := make(map[query.Symbol]bool)
 	for _, expr := range expressions {
@@ -194,6 +230,11 @@ func (p *Planner) PlanWithBindings(q *query.Query, initialBindings map[query.Sym
 		return nil, err
 	}
 
+	// Validate that the find variables survive every phase's Keep projection
+	if err := validateKeepProjections(phaseKeepSteps(phases), q.Find, inputSymbols); err != nil {
+		return nil, err
+	}
+
 	return &QueryPlan{
 		Query:  q,
 		Phases: phases,
@@ -9,9 +9,10 @@ import (
 // ClauseBasedPlanner is the new planner that operates on clauses directly
 // This implements the "optimize-first, phase-once" architecture (Stage C)
 type ClauseBasedPlanner struct {
-	stats   *Statistics
-	options PlannerOptions
-	cache   *PlanCache
+	stats         *Statistics
+	statsProvider StatisticsProvider // Overrides stats when set
+	options       PlannerOptions
+	cache         *PlanCache
 }
 
 // NewClauseBasedPlanner creates a new clause-based planner
@@ -29,12 +30,24 @@ func NewClauseBasedPlanner(stats *Statistics, options PlannerOptions) *ClauseBas
 	}
 }
 
+// statistics returns the statistics to plan with
+func (p *ClauseBasedPlanner) statistics() *Statistics {
+	if p.statsProvider != nil {
+		if stats := p.statsProvider.Statistics(); stats != nil {
+			return stats
+		}
+	}
+	return p.stats
+}
+
 // Plan creates an optimized query plan using the clause-based approach
 func (p *ClauseBasedPlanner) Plan(q *query.Query) (*RealizedPlan, error) {
 	// Check cache first
 	if p.cache != nil {
-		if cached, ok := p.cache.GetWithOptions(q, p.options); ok {
-			return cached.Realize(), nil
+		if cached, ok := p.cache.GetWithEpoch(q, p.options, p.statistics().Epoch); ok {
+			realized := cached.Realize()
+			realized.Cached = true
+			return realized, nil
 		}
 	}
 
@@ -99,6 +112,14 @@ func (p *ClauseBasedPlanner) PlanWithBindings(q *query.Query, initialBindings ma
 	clauses := q.Where
 
 	// Step 2: Apply optimizations as pure clause transformations
+	if p.options.EnableEqualityConstantRewriting {
+		clauses = rewriteEqualityConstants(q, inputSymbols)
+	}
+	if p.options.EnableAttributeSetExpansion {
+		if where, ok := expandAttributeSets(clauses); ok {
+			clauses = where
+		}
+	}
 	// TODO: Implement semantic rewriting as pure clause transformation
 	// TODO: Implement decorrelation as pure clause transformation
 	// For now, these complex optimizations are disabled in the clause-based planner
@@ -133,6 +154,9 @@ func (p *ClauseBasedPlanner) PlanWithBindings(q *query.Query, initialBindings ma
 			In:    buildInClause(cp.Available),
 			Where: cp.Clauses,
 		}
+		if isLastPhase {
+			phaseQuery.GroupingSets = q.GroupingSets
+		}
 
 		realizedPhases[i] = RealizedPhase{
 			Query:     phaseQuery,
@@ -143,6 +167,11 @@ func (p *ClauseBasedPlanner) PlanWithBindings(q *query.Query, initialBindings ma
 		}
 	}
 
+	// Validate that the find variables survive every phase's Keep projection
+	if err := validateKeepProjections(realizedKeepSteps(realizedPhases), q.Find, inputSymbols); err != nil {
+		return nil, err
+	}
+
 	return &RealizedPlan{
 		Query:  q,
 		Phases: realizedPhases,
@@ -327,6 +327,11 @@ func (p *Planner) extractExpressionInputs(expr *query.Expression) []query.Symbol
 				inputs = append(inputs, v.Symbol)
 				seen[v.Symbol] = true
 			}
+		case *query.NotFunction:
+			if v, ok := fn.Arg.(query.VariableTerm); ok && !seen[v.Symbol] {
+				inputs = append(inputs, v.Symbol)
+				seen[v.Symbol] = true
+			}
 		case *query.TimeExtractionFunction:
 			if v, ok := fn.TimeTerm.(query.VariableTerm); ok && !seen[v.Symbol] {
 				inputs = append(inputs, v.Symbol)
@@ -319,7 +319,7 @@ func (p *Planner) scorePattern(pattern *query.DataPattern, resolved map[query.Sy
 			// Use cardinality statistics if available
 			if constant, ok := elem.(query.Constant); ok {
 				if attr, ok := constant.Value.(datalog.Keyword); ok {
-					if card, exists := p.stats.AttributeCardinality[attr.String()]; exists {
+					if card, exists := p.statistics().AttributeCardinality[attr.String()]; exists {
 						score += card / 100 // Higher cardinality = less selective
 					}
 				}
@@ -110,15 +110,17 @@ func (p *Planner) createPredicatePlan(pred query.Predicate) PredicatePlan {
 			plan.Operator = actualOp
 		}
 
-		// Extract variable and value info
+		// Extract variable and value info. Only a comparison against a
+		// constant has them: comparing two variables (one may be an input)
+		// can't be pushed to storage as a constraint on either.
 		if v, ok := p.Left.(query.VariableTerm); ok {
-			plan.Variable = v.Symbol
 			if c, ok := p.Right.(query.ConstantTerm); ok {
+				plan.Variable = v.Symbol
 				plan.Value = c.Value
 			}
 		} else if v, ok := p.Right.(query.VariableTerm); ok {
-			plan.Variable = v.Symbol
 			if c, ok := p.Left.(query.ConstantTerm); ok {
+				plan.Variable = v.Symbol
 				plan.Value = c.Value
 			}
 		}
@@ -140,6 +142,9 @@ func (p *Planner) createPredicatePlan(pred query.Predicate) PredicatePlan {
 	case *query.FunctionPredicate:
 		plan.Type = PredicateFunction
 
+	case *query.InPredicate:
+		plan.Type = PredicateMembership
+
 	default:
 		// Unknown predicate type
 		plan.Type = PredicateUnknown
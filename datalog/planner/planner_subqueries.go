@@ -50,16 +50,8 @@ func (p *Planner) assignSubqueriesToPhases(phases []Phase, subqueries []*query.S
 			}
 
 			if allAvailable {
-				// Create initial bindings for the subquery based on its DECLARED PARAMETERS
-				// NOT the outer query's arguments
-				params := p.extractSubqueryParameters(subq)
-				subqueryBindings := make(map[query.Symbol]bool)
-				for _, param := range params {
-					subqueryBindings[param] = true
-				}
-
-				// Recursively plan the nested query with input bindings
-				nestedPlan, err := p.PlanWithBindings(subq.Query, subqueryBindings)
+				// Recursively plan the nested query with its parameters bound
+				nestedPlan, err := p.planNestedQuery(subq)
 				if err != nil {
 					// For now, we'll skip subqueries that fail to plan
 					// In production, we should propagate this error
@@ -96,15 +88,8 @@ func (p *Planner) assignSubqueriesToPhases(phases []Phase, subqueries []*query.S
 		if !assigned[subq] {
 			lastIdx := len(phases) - 1
 
-			// Create initial bindings for the subquery based on its DECLARED PARAMETERS
-			// NOT the outer query's arguments
-			subqueryBindings := make(map[query.Symbol]bool)
-			for _, param := range p.extractSubqueryParameters(subq) {
-				subqueryBindings[param] = true
-			}
-
-			// Recursively plan the nested query with input bindings
-			nestedPlan, err := p.PlanWithBindings(subq.Query, subqueryBindings)
+			// Recursively plan the nested query with its parameters bound
+			nestedPlan, err := p.planNestedQuery(subq)
 			if err != nil {
 				// For now, we'll skip subqueries that fail to plan
 				// In production, we should propagate this error
@@ -166,6 +151,30 @@ func (p *Planner) assignSubqueriesToPhases(phases []Phase, subqueries []*query.S
 	}
 }
 
+// planNestedQuery plans a subquery's nested query with its DECLARED
+// PARAMETERS bound, NOT the outer query's arguments. The parameters are the
+// nested query's :in symbols, which planning binds anyway, so the plan is the
+// one Plan makes for the nested query by itself. It is shared through the
+// plan cache: identical nested queries, within a query or across queries,
+// are planned once per options and statistics epoch.
+func (p *Planner) planNestedQuery(subq *query.SubqueryPattern) (*QueryPlan, error) {
+	subqueryBindings := make(map[query.Symbol]bool)
+	for _, param := range p.extractSubqueryParameters(subq) {
+		subqueryBindings[param] = true
+	}
+
+	epoch := p.statistics().Epoch
+	if cached, ok := p.cache.GetWithEpoch(subq.Query, p.options, epoch); ok {
+		return cached, nil
+	}
+	plan, err := p.PlanWithBindings(subq.Query, subqueryBindings)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.SetWithEpoch(subq.Query, plan, p.options, epoch)
+	return plan, nil
+}
+
 // extractSubqueryParameters extracts the parameter names declared by the subquery
 // These are the symbols from the subquery's :in clause (e.g., :in $ ?symbol ?d)
 func (p *Planner) extractSubqueryParameters(subq *query.SubqueryPattern) []query.Symbol {
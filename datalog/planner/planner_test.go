@@ -400,14 +400,12 @@ func TestPredicatePlacement(t *testing.T) {
 
 		// Also check storage constraints (predicates may be pushed to storage)
 		for _, pattern := range phase.Patterns {
-			if pattern.Metadata != nil {
-				if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-					for _, c := range constraints {
-						if c.Type == ConstraintRange && c.Operator == query.OpGT {
-							foundAgeCheck = true
-						}
-						// Note: str/starts-with? is not currently pushed to storage
+			if constraints := pattern.Constraints; len(constraints) > 0 {
+				for _, c := range constraints {
+					if c.Type == ConstraintRange && c.Operator == query.OpGT {
+						foundAgeCheck = true
 					}
+					// Note: str/starts-with? is not currently pushed to storage
 				}
 			}
 		}
@@ -1,6 +1,7 @@
 package planner
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 
@@ -197,6 +198,124 @@ func (p *Planner) validatePlan(phases []Phase, expressions []*query.Expression,
 	return nil
 }
 
+// ErrFindSymbolDropped is wrapped by DroppedSymbolError
+var ErrFindSymbolDropped = errors.New("find symbol dropped by phase projection")
+
+// DroppedSymbolError reports a :find variable or aggregate argument that the
+// plan binds but an intermediate phase's Keep projection discards, so it would
+// be missing from the final phase's columns
+type DroppedSymbolError struct {
+	Element string         // The :find element, e.g. "(max ?v)" or "?s"
+	Symbol  query.Symbol   // The dropped symbol
+	Phase   int            // The phase (1-based) whose Keep dropped it
+	Keep    []query.Symbol // That phase's Keep
+}
+
+func (e *DroppedSymbolError) Error() string {
+	return fmt.Sprintf("%v: %s in %s is not kept by phase %d, which keeps %v",
+		ErrFindSymbolDropped, e.Symbol, e.Element, e.Phase, e.Keep)
+}
+
+func (e *DroppedSymbolError) Unwrap() error {
+	return ErrFindSymbolDropped
+}
+
+// keepStep is a phase as the executor projects it: the symbols the phase
+// binds and the symbols it keeps for the next phase. Both planners' phases
+// reduce to keep steps, so that they share validateKeepProjections.
+type keepStep struct {
+	binds    []query.Symbol
+	keep     []query.Symbol
+	metadata map[string]interface{}
+}
+
+// phaseKeepSteps returns the keep steps of the planner's phases
+func phaseKeepSteps(phases []Phase) []keepStep {
+	steps := make([]keepStep, len(phases))
+	for i, phase := range phases {
+		binds := append([]query.Symbol(nil), phase.Provides...)
+		for _, expr := range phase.Expressions {
+			if expr.Output != "" {
+				binds = append(binds, expr.Output)
+			}
+		}
+		steps[i] = keepStep{binds: binds, keep: phase.Keep, metadata: phase.Metadata}
+	}
+	return steps
+}
+
+// realizedKeepSteps returns the keep steps of realized phases
+func realizedKeepSteps(phases []RealizedPhase) []keepStep {
+	steps := make([]keepStep, len(phases))
+	for i, phase := range phases {
+		binds := append([]query.Symbol(nil), phase.Provides...)
+		if phase.Query != nil {
+			for _, clause := range phase.Query.Where {
+				binds = append(binds, validationSymbols(clause).Provides...)
+			}
+		}
+		steps[i] = keepStep{binds: binds, keep: phase.Keep, metadata: phase.Metadata}
+	}
+	return steps
+}
+
+// validateKeepProjections walks the phases the way the executor projects
+// them and returns a *DroppedSymbolError for the first :find element whose
+// symbol is bound by some phase but no longer present after the last phase.
+// Symbols that no phase binds are left to the planners' own validation.
+func validateKeepProjections(steps []keepStep, find []query.FindElement, inputSymbols map[query.Symbol]bool) error {
+	columns := make(map[query.Symbol]bool)
+	for sym := range inputSymbols {
+		columns[sym] = true
+	}
+	droppedBy := make(map[query.Symbol]int)
+
+	for i, step := range steps {
+		for _, sym := range step.binds {
+			columns[sym] = true
+			delete(droppedBy, sym)
+		}
+
+		// The last phase is projected to :find, and an empty Keep passes
+		// every column through
+		if i == len(steps)-1 || len(step.keep) == 0 {
+			continue
+		}
+
+		kept := make(map[query.Symbol]bool, len(step.keep))
+		for _, sym := range step.keep {
+			kept[sym] = true
+		}
+		// The executor also keeps columns required by conditional aggregates
+		if cols, ok := step.metadata["aggregate_required_columns"].([]query.Symbol); ok {
+			for _, sym := range cols {
+				kept[sym] = true
+			}
+		}
+		for sym := range columns {
+			if !kept[sym] {
+				delete(columns, sym)
+				droppedBy[sym] = i
+			}
+		}
+	}
+
+	for _, elem := range find {
+		for _, sym := range findElementSymbols(elem) {
+			if i, dropped := droppedBy[sym]; dropped && !columns[sym] {
+				return &DroppedSymbolError{
+					Element: elem.String(),
+					Symbol:  sym,
+					Phase:   i + 1,
+					Keep:    steps[i].keep,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 // CacheStats returns cache statistics if caching is enabled
 func (p *Planner) CacheStats() (hits, misses int64, size int, enabled bool) {
 	if p.cache == nil {
@@ -217,3 +336,19 @@ func (p *Planner) ClearCache() {
 func (p *Planner) SetCache(cache *PlanCache) {
 	p.cache = cache
 }
+
+// SetStatisticsProvider sets the source of the statistics the planner uses
+// in place of those it was created with
+func (p *Planner) SetStatisticsProvider(provider StatisticsProvider) {
+	p.statsProvider = provider
+}
+
+// statistics returns the statistics to plan with
+func (p *Planner) statistics() *Statistics {
+	if p.statsProvider != nil {
+		if stats := p.statsProvider.Statistics(); stats != nil {
+			return stats
+		}
+	}
+	return p.stats
+}
@@ -194,13 +194,11 @@ func TestTimeExtractionPredicates(t *testing.T) {
 	var foundTimeConstraint bool
 	for _, phase := range plan.Phases {
 		for _, pattern := range phase.Patterns {
-			if pattern.Metadata != nil {
-				if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-					for _, c := range constraints {
-						if c.Type == ConstraintTimeExtraction && c.TimeField == "day" && c.Value == int64(20) {
-							foundTimeConstraint = true
-							t.Log("Found time extraction constraint: day = 20")
-						}
+			if constraints := pattern.Constraints; len(constraints) > 0 {
+				for _, c := range constraints {
+					if c.Type == ConstraintTimeExtraction && c.TimeField == "day" && c.Value == int64(20) {
+						foundTimeConstraint = true
+						t.Log("Found time extraction constraint: day = 20")
 					}
 				}
 			}
@@ -237,10 +235,8 @@ func TestPredicatePropagationWithConstraints(t *testing.T) {
 		// Without pushdown, no storage constraints
 		for _, phase := range plan.Phases {
 			for _, pattern := range phase.Patterns {
-				if pattern.Metadata != nil {
-					if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok && len(constraints) > 0 {
-						t.Errorf("Expected no storage constraints without pushdown, got %d", len(constraints))
-					}
+				if constraints := pattern.Constraints; len(constraints) > 0 {
+					t.Errorf("Expected no storage constraints without pushdown, got %d", len(constraints))
 				}
 			}
 		}
@@ -276,20 +272,18 @@ func TestPredicatePropagationWithConstraints(t *testing.T) {
 						if attr, ok := dp.Elements[1].(query.Constant); ok {
 							if kw, ok := attr.Value.(datalog.Keyword); ok && kw.String() == ":price/volume" {
 								// This pattern should have a constraint
-								if pattern.Metadata != nil {
-									if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok && len(constraints) > 0 {
-										foundConstraint = true
-										// Verify the constraint
-										c := constraints[0]
-										if c.Type != ConstraintRange {
-											t.Errorf("Expected range constraint, got %s", c.Type)
-										}
-										if c.Operator != query.OpGT {
-											t.Errorf("Expected > operator, got %s", c.Operator)
-										}
-										if c.Value != int64(1000000) {
-											t.Errorf("Expected value 1000000, got %v", c.Value)
-										}
+								if constraints := pattern.Constraints; len(constraints) > 0 {
+									foundConstraint = true
+									// Verify the constraint
+									c := constraints[0]
+									if c.Type != ConstraintRange {
+										t.Errorf("Expected range constraint, got %s", c.Type)
+									}
+									if c.Operator != query.OpGT {
+										t.Errorf("Expected > operator, got %s", c.Operator)
+									}
+									if c.Value != int64(1000000) {
+										t.Errorf("Expected value 1000000, got %v", c.Value)
 									}
 								}
 							}
@@ -337,10 +331,8 @@ func TestCrossPatternPropagation(t *testing.T) {
 					if attr, ok := dp.Elements[1].(query.Constant); ok {
 						if kw, ok := attr.Value.(datalog.Keyword); ok {
 							attrStr := kw.String()
-							if pattern.Metadata != nil {
-								if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-									constraintsByAttribute[attrStr] = constraints
-								}
+							if constraints := pattern.Constraints; len(constraints) > 0 {
+								constraintsByAttribute[attrStr] = constraints
 							}
 						}
 					}
@@ -411,12 +403,10 @@ func TestPredicateRemovalAfterPushdown(t *testing.T) {
 	foundInMetadata := false
 	for _, phase := range plan.Phases {
 		for _, pattern := range phase.Patterns {
-			if pattern.Metadata != nil {
-				if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-					for _, c := range constraints {
-						if c.Type == ConstraintRange && c.Value == int64(1000000) {
-							foundInMetadata = true
-						}
+			if constraints := pattern.Constraints; len(constraints) > 0 {
+				for _, c := range constraints {
+					if c.Type == ConstraintRange && c.Value == int64(1000000) {
+						foundInMetadata = true
 					}
 				}
 			}
@@ -253,10 +253,8 @@ func TestOHLCQueryPhaseStructure(t *testing.T) {
 		// Check for storage constraints
 		constraintCount := 0
 		for _, pattern := range phase.Patterns {
-			if pattern.Metadata != nil {
-				if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-					constraintCount += len(constraints)
-				}
+			if constraints := pattern.Constraints; len(constraints) > 0 {
+				constraintCount += len(constraints)
 			}
 		}
 		t.Logf("  Storage constraints pushed: %d", constraintCount)
@@ -51,12 +51,8 @@ func TestPredicatePropagation(t *testing.T) {
 
 		// Check that the time predicate was propagated to the time pattern
 		timePattern := phase.Patterns[1]
-		if timePattern.Metadata == nil {
-			t.Fatal("Expected metadata on time pattern")
-		}
-
-		constraints, ok := timePattern.Metadata["storage_constraints"].([]StorageConstraint)
-		if !ok || len(constraints) == 0 {
+		constraints := timePattern.Constraints
+		if len(constraints) == 0 {
 			t.Fatal("Expected storage constraints on time pattern")
 		}
 
@@ -115,8 +111,8 @@ func TestPredicatePropagation(t *testing.T) {
 
 		// The high > 150 predicate should be pushed to the high pattern
 		highPattern := phase.Patterns[1]
-		constraints, ok := highPattern.Metadata["storage_constraints"].([]StorageConstraint)
-		if !ok || len(constraints) == 0 {
+		constraints := highPattern.Constraints
+		if len(constraints) == 0 {
 			t.Fatal("Expected storage constraints on high pattern")
 		}
 
@@ -194,14 +190,14 @@ func TestPredicatePropagation(t *testing.T) {
 
 		// Check time pattern has time constraint
 		timePattern := phase.Patterns[1]
-		timeConstraints, _ := timePattern.Metadata["storage_constraints"].([]StorageConstraint)
+		timeConstraints := timePattern.Constraints
 		if len(timeConstraints) != 1 {
 			t.Fatalf("Expected 1 constraint on time pattern, got %d", len(timeConstraints))
 		}
 
 		// Check volume pattern has volume constraint
 		volumePattern := phase.Patterns[2]
-		volumeConstraints, _ := volumePattern.Metadata["storage_constraints"].([]StorageConstraint)
+		volumeConstraints := volumePattern.Constraints
 		if len(volumeConstraints) != 1 {
 			t.Fatalf("Expected 1 constraint on volume pattern, got %d", len(volumeConstraints))
 		}
@@ -253,14 +249,12 @@ func TestPredicatePropagation(t *testing.T) {
 
 		// Only the price pattern should have constraints
 		userPattern := phase.Patterns[0]
-		if userPattern.Metadata != nil {
-			if constraints, ok := userPattern.Metadata["storage_constraints"].([]StorageConstraint); ok && len(constraints) > 0 {
-				t.Error("User pattern shouldn't have constraints from price predicate")
-			}
+		if constraints := userPattern.Constraints; len(constraints) > 0 {
+			t.Error("User pattern shouldn't have constraints from price predicate")
 		}
 
 		pricePattern := phase.Patterns[1]
-		constraints, _ := pricePattern.Metadata["storage_constraints"].([]StorageConstraint)
+		constraints := pricePattern.Constraints
 		if len(constraints) != 1 {
 			t.Fatalf("Expected 1 constraint on price pattern, got %d", len(constraints))
 		}
@@ -346,9 +340,7 @@ func TestSelectivityEstimation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			pattern := PatternPlan{
-				Metadata: map[string]interface{}{
-					"storage_constraints": tt.constraints,
-				},
+				Constraints: tt.constraints,
 			}
 
 			selectivity := analyzeSelectivity(pattern)
@@ -62,15 +62,13 @@ func TestPredicatePushdownIntegration(t *testing.T) {
 		foundStorageConstraints := false
 		for _, phase := range planWithPush.Phases {
 			for _, pattern := range phase.Patterns {
-				if pattern.Metadata != nil {
-					if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok && len(constraints) > 0 {
-						foundStorageConstraints = true
-
-						// Verify we have the day constraint
-						for _, c := range constraints {
-							if c.Type == ConstraintTimeExtraction && c.TimeField == "day" {
-								t.Logf("Found pushed day constraint: %+v", c)
-							}
+				if constraints := pattern.Constraints; len(constraints) > 0 {
+					foundStorageConstraints = true
+
+					// Verify we have the day constraint
+					for _, c := range constraints {
+						if c.Type == ConstraintTimeExtraction && c.TimeField == "day" {
+							t.Logf("Found pushed day constraint: %+v", c)
 						}
 					}
 				}
@@ -112,17 +110,15 @@ func TestPredicatePushdownIntegration(t *testing.T) {
 
 		for _, phase := range plan.Phases {
 			for _, pattern := range phase.Patterns {
-				if pattern.Metadata != nil {
-					if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok && len(constraints) > 0 {
-						patternsWithConstraints++
-						totalConstraints += len(constraints)
-
-						// Log what constraints were pushed where
-						if dp, ok := pattern.Pattern.(*query.DataPattern); ok {
-							if len(dp.Elements) > 1 {
-								if attr, ok := dp.Elements[1].(query.Constant); ok {
-									t.Logf("Pattern %v has %d constraints", attr.Value, len(constraints))
-								}
+				if constraints := pattern.Constraints; len(constraints) > 0 {
+					patternsWithConstraints++
+					totalConstraints += len(constraints)
+
+					// Log what constraints were pushed where
+					if dp, ok := pattern.Pattern.(*query.DataPattern); ok {
+						if len(dp.Elements) > 1 {
+							if attr, ok := dp.Elements[1].(query.Constant); ok {
+								t.Logf("Pattern %v has %d constraints", attr.Value, len(constraints))
 							}
 						}
 					}
@@ -145,10 +141,8 @@ func TestPredicatePushdownIntegration(t *testing.T) {
 
 		// Pattern with day constraint (1/30 selectivity)
 		patternWithDay := PatternPlan{
-			Metadata: map[string]interface{}{
-				"storage_constraints": []StorageConstraint{
-					{Type: ConstraintTimeExtraction, TimeField: "day"},
-				},
+			Constraints: []StorageConstraint{
+				{Type: ConstraintTimeExtraction, TimeField: "day"},
 			},
 		}
 
@@ -160,10 +154,8 @@ func TestPredicatePushdownIntegration(t *testing.T) {
 
 		// Pattern with equality constraint (0.01 selectivity)
 		patternWithEquality := PatternPlan{
-			Metadata: map[string]interface{}{
-				"storage_constraints": []StorageConstraint{
-					{Type: ConstraintEquality, Operator: query.OpEQ},
-				},
+			Constraints: []StorageConstraint{
+				{Type: ConstraintEquality, Operator: query.OpEQ},
 			},
 		}
 
@@ -174,11 +166,9 @@ func TestPredicatePushdownIntegration(t *testing.T) {
 
 		// Combined constraints multiply selectivity
 		patternWithBoth := PatternPlan{
-			Metadata: map[string]interface{}{
-				"storage_constraints": []StorageConstraint{
-					{Type: ConstraintTimeExtraction, TimeField: "day"},
-					{Type: ConstraintEquality, Operator: query.OpEQ},
-				},
+			Constraints: []StorageConstraint{
+				{Type: ConstraintTimeExtraction, TimeField: "day"},
+				{Type: ConstraintEquality, Operator: query.OpEQ},
 			},
 		}
 
@@ -220,10 +210,8 @@ func TestPredicatePushdownIntegration(t *testing.T) {
 
 			// But they should be in pattern metadata
 			for _, pattern := range phase.Patterns {
-				if pattern.Metadata != nil {
-					if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok && len(constraints) > 0 {
-						t.Logf("Pattern has %d storage constraints", len(constraints))
-					}
+				if constraints := pattern.Constraints; len(constraints) > 0 {
+					t.Logf("Pattern has %d storage constraints", len(constraints))
 				}
 			}
 		}
@@ -278,13 +266,11 @@ func TestRealWorldQueries(t *testing.T) {
 							}
 							if attrStr == ":bar/date" {
 								// This pattern should have date range constraints
-								if pattern.Metadata != nil {
-									if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-										for _, c := range constraints {
-											if c.Type == ConstraintRange {
-												foundDateConstraints++
-												t.Logf("Found date range constraint: %+v", c)
-											}
+								if constraints := pattern.Constraints; len(constraints) > 0 {
+									for _, c := range constraints {
+										if c.Type == ConstraintRange {
+											foundDateConstraints++
+											t.Logf("Found date range constraint: %+v", c)
 										}
 									}
 								}
@@ -328,13 +314,11 @@ func TestRealWorldQueries(t *testing.T) {
 		foundHourConstraints := false
 		for _, phase := range plan.Phases {
 			for _, pattern := range phase.Patterns {
-				if pattern.Metadata != nil {
-					if constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint); ok {
-						for _, c := range constraints {
-							if c.Type == ConstraintTimeExtraction && c.TimeField == "hour" {
-								foundHourConstraints = true
-								t.Logf("Found hour constraint: %+v", c)
-							}
+				if constraints := pattern.Constraints; len(constraints) > 0 {
+					for _, c := range constraints {
+						if c.Type == ConstraintTimeExtraction && c.TimeField == "hour" {
+							foundHourConstraints = true
+							t.Logf("Found hour constraint: %+v", c)
 						}
 					}
 				}
@@ -1,6 +1,8 @@
 package planner
 
 import (
+	"time"
+
 	"github.com/wbrown/janus-datalog/datalog/constraints"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
@@ -8,8 +10,9 @@ import (
 // TimeExtractionPattern represents a detected time extraction + equality pattern
 // Example: [(year ?time) ?py] followed by [(= ?py 2025)]
 type TimeExtractionPattern struct {
-	Function      string       // "year", "month", "day", "hour", "minute", "second"
-	SourceVar     query.Symbol // ?time (the time value being extracted from)
+	Function      string         // "year", "month", "day", "hour", "minute", "second"
+	Location      *time.Location // Zone the function extracts in (nil = the time's own)
+	SourceVar     query.Symbol   // ?time (the time value being extracted from)
 	ResultVar     query.Symbol // ?py (the variable holding the extracted value)
 	ComparedValue interface{}  // The constant it's compared to (e.g., 2025)
 	PatternIndex  int          // Which pattern binds the source variable
@@ -55,6 +58,7 @@ func detectTimeExtractionPatterns(phase *Phase) []TimeExtractionPattern {
 				if _, bound := varToPattern[sourceVar]; bound {
 					exprResults[exprPlan.Expression.Binding] = &TimeExtractionInfo{
 						Function:  timeFunc.Field,
+						Location:  timeFunc.Location,
 						SourceVar: sourceVar,
 						ExprIndex: exprIdx,
 					}
@@ -83,6 +87,7 @@ func detectTimeExtractionPatterns(phase *Phase) []TimeExtractionPattern {
 						if found {
 							patterns = append(patterns, TimeExtractionPattern{
 								Function:      info.Function,
+								Location:      info.Location,
 								SourceVar:     info.SourceVar,
 								ResultVar:     resultVar,
 								ComparedValue: constValue,
@@ -102,6 +107,7 @@ func detectTimeExtractionPatterns(phase *Phase) []TimeExtractionPattern {
 						if found {
 							patterns = append(patterns, TimeExtractionPattern{
 								Function:      info.Function,
+								Location:      info.Location,
 								SourceVar:     info.SourceVar,
 								ResultVar:     resultVar,
 								ComparedValue: constValue,
@@ -122,6 +128,7 @@ func detectTimeExtractionPatterns(phase *Phase) []TimeExtractionPattern {
 
 type TimeExtractionInfo struct {
 	Function  string
+	Location  *time.Location
 	SourceVar query.Symbol
 	ExprIndex int
 }
@@ -135,41 +142,55 @@ func groupTimePatternsBySource(patterns []TimeExtractionPattern) map[query.Symbo
 	return grouped
 }
 
-// composeTimeConstraint combines multiple time predicates into a single constraint
+// composeTimeConstraint combines multiple time predicates into a single
+// constraint. It returns nil unless the predicates describe one contiguous
+// range: integer components from the year down, with none skipped, all
+// extracted in the same zone, and no finer than days for a named zone.
 func composeTimeConstraint(patterns []TimeExtractionPattern) constraints.StorageConstraint {
 	if len(patterns) == 0 {
 		return nil
 	}
 
-	var year, month, day, hour, minute, second *int
-
+	fields := map[string]*int{}
+	loc := patterns[0].Location
 	for _, pat := range patterns {
 		val, ok := pat.ComparedValue.(int64)
-		if !ok {
-			continue
+		if !ok || pat.Location != loc {
+			return nil
 		}
 		intVal := int(val)
+		if prev, seen := fields[pat.Function]; seen && *prev != intVal {
+			return nil
+		}
+		fields[pat.Function] = &intVal
+	}
 
-		switch pat.Function {
-		case "year":
-			year = &intVal
-		case "month":
-			month = &intVal
-		case "day":
-			day = &intVal
-		case "hour":
-			hour = &intVal
-		case "minute":
-			minute = &intVal
-		case "second":
-			second = &intVal
+	// Without the larger components, e.g. day 15 of every month, the
+	// matching times are not one range
+	n := 0
+	for _, field := range []string{"year", "month", "day", "hour", "minute", "second"} {
+		if fields[field] == nil {
+			break
 		}
+		n++
+	}
+	if n != len(fields) {
+		return nil
+	}
+
+	// Local hours repeat when clocks go back, so in a zone only whole days
+	// and longer are single ranges
+	if loc == nil {
+		loc = time.UTC
+	} else if loc != time.UTC && n > 3 {
+		return nil
 	}
 
 	// Use the position from the first pattern (they should all be the same source)
 	position := patterns[0].Position
 
-	return constraints.ComposeTimeConstraint(year, month, day, hour, minute, second, position)
+	return constraints.ComposeTimeConstraintIn(fields["year"], fields["month"], fields["day"],
+		fields["hour"], fields["minute"], fields["second"], position, loc)
 }
 
 // rewriteTimePredicates applies semantic rewriting to time extraction predicates
@@ -201,19 +222,7 @@ func rewriteTimePredicates(plan *QueryPlan, opts PlannerOptions) {
 			patternIdx := group[0].PatternIndex
 			pattern := &phase.Patterns[patternIdx]
 
-			// Store constraints in metadata
-			if pattern.Metadata == nil {
-				pattern.Metadata = make(map[string]interface{})
-			}
-
-			// Get existing constraints or create new slice
-			var existingConstraints []constraints.StorageConstraint
-			if existing, ok := pattern.Metadata["storage_constraints"]; ok {
-				existingConstraints = existing.([]constraints.StorageConstraint)
-			}
-
-			// Append new constraint
-			pattern.Metadata["storage_constraints"] = append(existingConstraints, constraint)
+			pattern.StorageFilters = append(pattern.StorageFilters, constraint)
 
 			// Mark expressions and predicates as handled by constraint
 			for _, pat := range group {
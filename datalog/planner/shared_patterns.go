@@ -0,0 +1,148 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// SharedPattern is a data pattern of a subquery that reads the same datoms as
+// a pattern of the outer query, e.g.
+//
+//	[?b :price/symbol ?s]                        ; outer
+//	[(q [:find (max ?t) :in $ ?sym
+//	     :where [?bar :price/symbol ?sym] ...] $ ?s) [[?max]]]
+//
+// Once ?sym is unified with ?s, [?bar :price/symbol ?sym] is the outer pattern
+// restricted to one value of ?s, so every invocation of the subquery can be
+// answered from the outer pattern's scan instead of going back to storage.
+type SharedPattern struct {
+	Outer    *query.DataPattern     // The pattern in the outer query
+	Subquery *query.SubqueryPattern // The subquery containing Inner
+	Inner    *query.DataPattern     // The matching pattern in the subquery
+	Key      string                 // SharedPatternKey of both patterns
+}
+
+// FindSharedPatterns returns the subquery patterns of q that are identical to
+// an outer pattern after unifying the subquery's :in parameters with the
+// outer variables it is called with.
+//
+// Positions are compared one by one. They match when both hold the same
+// constant, both are blank, the subquery holds a parameter and the outer
+// pattern holds the variable passed for it, or the subquery holds one of its
+// own variables and the outer pattern holds any variable. Only patterns of
+// subqueries called directly from q are considered.
+func FindSharedPatterns(q *query.Query) []SharedPattern {
+	if q == nil {
+		return nil
+	}
+
+	var outer []*query.DataPattern
+	for _, clause := range q.Where {
+		if p, ok := clause.(*query.DataPattern); ok {
+			outer = append(outer, p)
+		}
+	}
+	if len(outer) == 0 {
+		return nil
+	}
+
+	var shared []SharedPattern
+	for _, clause := range q.Where {
+		sq, ok := clause.(*query.SubqueryPattern)
+		if !ok || sq.Query == nil {
+			continue
+		}
+		varMap := subqueryInputMap(sq)
+		for _, c := range sq.Query.Where {
+			inner, ok := c.(*query.DataPattern)
+			if !ok {
+				continue
+			}
+			key, ok := SharedPatternKey(inner)
+			if !ok {
+				continue
+			}
+			for _, o := range outer {
+				if k, _ := SharedPatternKey(o); k == key && unifies(inner, o, varMap) {
+					shared = append(shared, SharedPattern{Outer: o, Subquery: sq, Inner: inner, Key: key})
+					break
+				}
+			}
+		}
+	}
+	return shared
+}
+
+// subqueryInputMap maps the subquery's scalar :in parameters to the outer
+// variables passed for them
+func subqueryInputMap(sq *query.SubqueryPattern) map[query.Symbol]query.Symbol {
+	varMap := make(map[query.Symbol]query.Symbol)
+	for i, in := range sq.Query.In {
+		if i >= len(sq.Inputs) {
+			break
+		}
+		scalar, ok := in.(query.ScalarInput)
+		if !ok {
+			continue
+		}
+		if v, ok := sq.Inputs[i].(query.Variable); ok {
+			varMap[scalar.Symbol] = v.Name
+		}
+	}
+	return varMap
+}
+
+// unifies reports whether inner reads the same datoms as outer once its
+// parameters are replaced by the outer variables in varMap
+func unifies(inner, outer *query.DataPattern, varMap map[query.Symbol]query.Symbol) bool {
+	if len(inner.Elements) != len(outer.Elements) {
+		return false
+	}
+	for i, elem := range inner.Elements {
+		v, ok := elem.(query.Variable)
+		if !ok {
+			continue // Constants and blanks were compared by the key
+		}
+		o, ok := outer.Elements[i].(query.Variable)
+		if !ok {
+			return false
+		}
+		if mapped, isParam := varMap[v.Name]; isParam && mapped != o.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// SharedPatternKey returns a canonical form of p in which variables are
+// numbered by first occurrence and constants keep their type and value, so
+// [?b :price/symbol ?s] and [?bar :price/symbol ?sym] have the same key.
+// Patterns with other element types, or restricted to an attribute set, have
+// no key.
+func SharedPatternKey(p *query.DataPattern) (string, bool) {
+	if p == nil || len(p.Attributes) > 0 {
+		return "", false
+	}
+	vars := make(map[query.Symbol]int)
+	parts := make([]string, len(p.Elements))
+	for i, elem := range p.Elements {
+		switch e := elem.(type) {
+		case query.Variable:
+			n, ok := vars[e.Name]
+			if !ok {
+				n = len(vars)
+				vars[e.Name] = n
+			}
+			parts[i] = fmt.Sprintf("?%d", n)
+		case query.Blank:
+			parts[i] = "_"
+		case query.Constant:
+			parts[i] = fmt.Sprintf("%T:%v", e.Value, e.Value)
+		default:
+			return "", false
+		}
+	}
+	return "[" + strings.Join(parts, " ") + "]", true
+}
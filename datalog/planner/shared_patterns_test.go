@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestFindSharedPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string // Inner patterns found shared
+	}{
+		{
+			name: "parameter unified with outer variable",
+			query: `[:find ?s ?max :where [?b :price/symbol ?s]
+			         [(q [:find (max ?t) :in $ ?sym :where [?bar :price/symbol ?sym] [?bar :price/time ?t]] $ ?s) [[?max]]]]`,
+			expected: []string{"[?bar :price/symbol ?sym]"},
+		},
+		{
+			name: "local variables match any outer variables",
+			query: `[:find ?s ?max :where [?b :price/symbol ?s] [?b :price/time ?time]
+			         [(q [:find (max ?t) :in $ ?sym :where [?bar :price/symbol ?sym] [?bar :price/time ?t]] $ ?s) [[?max]]]]`,
+			expected: []string{"[?bar :price/symbol ?sym]", "[?bar :price/time ?t]"},
+		},
+		{
+			name: "parameter passed a different outer variable",
+			query: `[:find ?s ?max :where [?b :price/symbol ?s] [?x :symbol/peer ?p]
+			         [(q [:find (max ?t) :in $ ?sym :where [?bar :price/symbol ?sym] [?bar :price/time ?t]] $ ?p) [[?max]]]]`,
+		},
+		{
+			name: "different constant",
+			query: `[:find ?s ?max :where [?b :price/symbol ?s] [?b :price/open ?o]
+			         [(q [:find (max ?t) :in $ ?sym :where [?bar :price/ticker ?sym] [?bar :price/close ?t]] $ ?s) [[?max]]]]`,
+		},
+		{
+			name: "constant against variable",
+			query: `[:find ?b ?max :where [?b :price/symbol "AAPL"]
+			         [(q [:find (max ?t) :in $ ?sym :where [?bar :price/symbol ?sym] [?bar :price/time ?t]] $ ?b) [[?max]]]]`,
+		},
+		{
+			name: "repeated variable",
+			query: `[:find ?e ?n :where [?e :node/parent ?e]
+			         [(q [:find (count ?c) :in $ ?x :where [?c :node/parent ?p]] $ ?e) [[?n]]]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parser.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			shared := FindSharedPatterns(q)
+			if len(shared) != len(tt.expected) {
+				t.Fatalf("Expected %d shared patterns, got %d: %v", len(tt.expected), len(shared), shared)
+			}
+			for i, sp := range shared {
+				if sp.Inner.String() != tt.expected[i] {
+					t.Errorf("Expected shared pattern %s, got %s", tt.expected[i], sp.Inner)
+				}
+				if outerKey, _ := SharedPatternKey(sp.Outer); outerKey != sp.Key {
+					t.Errorf("Outer pattern %s has key %s, expected %s", sp.Outer, outerKey, sp.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestSharedPatternKey(t *testing.T) {
+	q, err := parser.ParseQuery(`[:find ?a :where [?a :x/y ?b] [?c :x/y ?d] [?e :x/y ?e] [?f :x/y 1] [?g :x/y "1"] [?h :x/y ?i ?tx]]`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	keys := make([]string, len(q.Where))
+	for i, clause := range q.Where {
+		key, ok := SharedPatternKey(clause.(*query.DataPattern))
+		if !ok {
+			t.Fatalf("Expected a key for %v", clause)
+		}
+		keys[i] = key
+	}
+	if keys[0] != keys[1] {
+		t.Errorf("Expected renamed patterns to share a key, got %s and %s", keys[0], keys[1])
+	}
+	for i := 2; i < len(keys); i++ {
+		for j := 0; j < i; j++ {
+			if j != 1 && keys[i] == keys[j] {
+				t.Errorf("Expected distinct keys for %v and %v, both %s", q.Where[i], q.Where[j], keys[i])
+			}
+		}
+	}
+}
@@ -35,7 +35,9 @@ import (
 // renamePatternVariables renames variables in a pattern according to a mapping
 func renamePatternVariables(pat *query.DataPattern, varMap map[query.Symbol]query.Symbol) *query.DataPattern {
 	renamed := &query.DataPattern{
-		Elements: make([]query.PatternElement, len(pat.Elements)),
+		Elements:   make([]query.PatternElement, len(pat.Elements)),
+		MaxDatoms:  pat.MaxDatoms,
+		Attributes: pat.Attributes,
 	}
 
 	for i, elem := range pat.Elements {
@@ -78,12 +80,14 @@ func renameFunctionVariables(fn query.Function, varMap map[query.Symbol]query.Sy
 		return &query.TimeExtractionFunction{
 			Field:    f.Field,
 			TimeTerm: renameTermVariables(f.TimeTerm, varMap),
+			Location: f.Location,
 		}
 	case query.TimeExtractionFunction:
 		// Handle value type as well
 		return query.TimeExtractionFunction{
 			Field:    f.Field,
 			TimeTerm: renameTermVariables(f.TimeTerm, varMap),
+			Location: f.Location,
 		}
 	case *query.ComparisonFunction:
 		return &query.ComparisonFunction{
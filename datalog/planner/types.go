@@ -2,8 +2,10 @@ package planner
 
 import (
 	"fmt"
+	"github.com/wbrown/janus-datalog/datalog/constraints"
 	"github.com/wbrown/janus-datalog/datalog/query"
 	"strings"
+	"time"
 )
 
 // IndexType represents different index orderings (copied to avoid circular import)
@@ -43,8 +45,9 @@ type Phase struct {
 // Example: [(day ?t) ?d] + [(= ?d 20)] -> time extraction constraint
 func (p *Phase) combineTimeExtractions() {
 	// Map output variables to time extraction expressions
-	timeExtractionOutputs := make(map[query.Symbol]string)      // variable -> time field (day, month, etc.)
-	timeExtractionInputs := make(map[query.Symbol]query.Symbol) // output var -> input var
+	timeExtractionOutputs := make(map[query.Symbol]string)       // variable -> time field (day, month, etc.)
+	timeExtractionInputs := make(map[query.Symbol]query.Symbol)  // output var -> input var
+	timeExtractionZones := make(map[query.Symbol]*time.Location) // output var -> zone (nil = the time's own)
 
 	// Check expressions for time extraction functions
 	for _, exprPlan := range p.Expressions {
@@ -54,6 +57,7 @@ func (p *Phase) combineTimeExtractions() {
 				// This is a time extraction expression
 				if exprPlan.Output != "" {
 					timeExtractionOutputs[exprPlan.Output] = tef.Field
+					timeExtractionZones[exprPlan.Output] = tef.Location
 					// The input is typically the first argument
 					if len(exprPlan.Inputs) > 0 {
 						timeExtractionInputs[exprPlan.Output] = exprPlan.Inputs[0]
@@ -87,6 +91,7 @@ func (p *Phase) combineTimeExtractions() {
 					Type:         PredicateTimeExtraction,
 					Variable:     inputVar,
 					TimeField:    timeField,
+					TimeZone:     timeExtractionZones[pred.Variable],
 					Value:        pred.Value,
 					Operator:     operator,
 					RequiredVars: []query.Symbol{inputVar},
@@ -133,6 +138,7 @@ type StorageConstraint struct {
 	Value     interface{}     // The value or range
 	Operator  query.CompareOp // For comparisons: OpEQ, OpLT, OpGT, OpLTE, OpGTE
 	TimeField string          // For time extraction: "year", "month", "day", etc.
+	TimeZone  *time.Location  // For time extraction: zone of TimeField (nil = the time's own)
 }
 
 // PatternPlan represents a planned pattern with index selection
@@ -143,7 +149,17 @@ type PatternPlan struct {
 	Selectivity        int                    // Estimated selectivity (lower = more selective)
 	Bindings           map[query.Symbol]bool  // Variables that will be bound after execution
 	PushablePredicates []PredicatePlan        // Predicates that can be pushed to storage for this pattern
-	Metadata           map[string]interface{} // Additional metadata (storage constraints, etc.)
+	Metadata           map[string]interface{} // Additional metadata
+
+	// Constraints are the phase's predicates pushed down to this pattern by
+	// PushPredicates. They describe the predicate; the executor converts them
+	// into storage filters for the pattern's positions.
+	Constraints []StorageConstraint
+
+	// StorageFilters are constraints evaluated by storage as they are, such
+	// as the time ranges produced by semantic rewriting. They are passed to
+	// the matcher together with the converted Constraints.
+	StorageFilters []constraints.StorageConstraint
 }
 
 // ApplyConstraints analyzes predicates and applies relevant constraints to this pattern
@@ -156,12 +172,8 @@ func (pp *PatternPlan) ApplyConstraints(predicates []PredicatePlan, phase Phase)
 		}
 	}
 
-	// Add constraints to metadata
 	if len(constraints) > 0 {
-		if pp.Metadata == nil {
-			pp.Metadata = make(map[string]interface{})
-		}
-		pp.Metadata["storage_constraints"] = constraints
+		pp.Constraints = constraints
 	}
 }
 
@@ -189,6 +201,7 @@ func (pp *PatternPlan) toConstraint(pred PredicatePlan, phase Phase) *StorageCon
 								Attribute: attrStr,
 								Value:     pred.Value,
 								TimeField: pred.TimeField,
+								TimeZone:  pred.TimeZone,
 								Operator:  pred.Operator,
 							}
 						}
@@ -251,6 +264,7 @@ const (
 	PredicateGround
 	PredicateMissing
 	PredicateFunction
+	PredicateMembership
 	PredicateUnknown
 )
 
@@ -273,6 +287,8 @@ func (t PredicatePlanType) String() string {
 		return "missing"
 	case PredicateFunction:
 		return "function"
+	case PredicateMembership:
+		return "membership"
 	default:
 		return "unknown"
 	}
@@ -290,6 +306,7 @@ type PredicatePlan struct {
 	Value     interface{}            // Constant value (if applicable)
 	Operator  query.CompareOp        // Operator (OpEQ, OpLT, OpGT, etc.)
 	TimeField string                 // For time extraction predicates
+	TimeZone  *time.Location         // Zone of TimeField (nil = the time's own)
 	Metadata  map[string]interface{} // Additional metadata (e.g., optimized_by_constraint)
 }
 
@@ -333,6 +350,7 @@ type DecorrelatedSubqueryPlan struct {
 	OriginalSubqueries []int             // Indices in Phase.Subqueries
 	FilterGroups       []FilterGroup     // Groups of subqueries by filter
 	MergedPlans        []*QueryPlan      // One plan per filter group
+	PartitionedPlans   []*QueryPlan      // Merged plans taking correlation keys as a relation input (only with DecorrelationPartitions > 1)
 	CorrelationKeys    []query.Symbol    // Keys to join on from outer query (e.g., ?year, ?month, ?day, ?hour)
 	GroupingVars       [][]query.Symbol  // Actual grouping variables in merged queries (per filter group)
 	ColumnMapping      map[int]ResultMap // Original subquery -> result columns
@@ -383,7 +401,16 @@ type BoundMask struct {
 // Statistics tracks query statistics for optimization
 type Statistics struct {
 	AttributeCardinality map[string]int // Estimated distinct values per attribute
+	AttributeDatoms      map[string]int // Datoms per attribute
 	EntityCount          int            // Total number of entities
+	Epoch                uint64         // Bump when the statistics change so cached plans costed against older ones are not reused
+}
+
+// StatisticsProvider supplies a planner with current statistics, such as a
+// database maintaining them as transactions commit. A returned Statistics is
+// not modified afterwards: the provider returns a new one as they change.
+type StatisticsProvider interface {
+	Statistics() *Statistics
 }
 
 // PlannerOptions configures both the query planner and executor
@@ -401,9 +428,12 @@ type PlannerOptions struct {
 	EnableSubqueryDecorrelation         bool       // Enable Selinger-style subquery decorrelation optimization
 	EnableParallelDecorrelation         bool       // Execute decorrelated merged queries in parallel (requires EnableSubqueryDecorrelation)
 	EnableCSE                           bool       // Enable Common Subexpression Elimination for decorrelated subqueries
+	DecorrelationPartitions             int        // If > 1, execute decorrelated merged queries partition-wise over this many correlation key ranges
 	EnableSemanticRewriting             bool       // Rewrite predicates for efficiency (e.g., year(t)=2025 → time range constraint)
+	EnableEqualityConstantRewriting     bool       // Fold [(= ?v "c")] into the patterns binding ?v so they scan AVET (string, keyword and boolean constants)
+	EnableAttributeSetExpansion         bool       // Match [?e ?a ?v] once per attribute of an [(in ?a #{...})] set instead of scanning every attribute
 	UseStreamingSubqueryUnion           bool       // Use streaming union for subquery results instead of materializing all (default: true)
-	UseComponentizedSubquery            bool       // Use component-based subquery execution (strategy selector, batcher, worker pool)
+	UseComponentizedSubquery            bool       // Deprecated: no effect; subqueries always run through the strategy selector
 	MaxPhases                           int        // Maximum phases to generate (0 = unlimited)
 	EnableFineGrainedPhases             bool       // Use fine-grained phase creation to avoid cross-products
 	Cache                               *PlanCache // Shared query plan cache (optional)
@@ -425,6 +455,19 @@ type PlannerOptions struct {
 
 	// Storage join strategy options
 	IndexNestedLoopThreshold int // Threshold for choosing IndexNestedLoop vs HashJoinScan (default: 0)
+
+	// Ordered scans
+	EnableOrderedScan bool // Satisfy :order-by with :limit by scanning the ordering attribute in index order, stopping early
+
+	// Latest value per entity
+	EnableLatestPerEntity bool // Replace max/min-per-group subqueries with one ordered scan (see FindLatestPerEntity)
+
+	// Shared patterns
+	EnableSharedPatterns bool // Share scans of subquery patterns identical to outer patterns (see FindSharedPatterns)
+
+	// Result spooling
+	SpoolThreshold int    // Spool final results with more tuples than this to disk (0 = never)
+	SpoolDir       string // Directory for spool files (default: os.TempDir())
 }
 
 // String returns a human-readable representation of the query plan
@@ -544,6 +587,10 @@ type RealizedPhase struct {
 	Provides  []query.Symbol         // Symbols this phase provides
 	Keep      []query.Symbol         // Symbols to keep for next phase
 	Metadata  map[string]interface{} // Phase metadata (decorrelation hints, etc.)
+
+	// IndexPins overrides storage index selection for individual patterns of
+	// this phase. Set via RealizedPlan.PinIndex.
+	IndexPins map[*query.DataPattern]IndexType
 }
 
 // RealizedPlan is the output of the planner in the realized format.
@@ -551,6 +598,7 @@ type RealizedPhase struct {
 type RealizedPlan struct {
 	Query  *query.Query     // Original user query
 	Phases []RealizedPhase  // Phases as Datalog query fragments
+	Cached bool             // Realized from a plan in the plan cache
 }
 
 // Realize converts a QueryPlan (with Phase structures) into a RealizedPlan
@@ -574,6 +622,10 @@ func (qp *QueryPlan) Realize() *RealizedPlan {
 		}
 		realizedPhases[i] = realizePhase(phase, isLastPhase, prevKeep)
 	}
+	// The last phase aggregates, so it carries the grouping sets
+	if n := len(realizedPhases); n > 0 && qp.Query != nil {
+		realizedPhases[n-1].Query.GroupingSets = qp.Query.GroupingSets
+	}
 	return &RealizedPlan{
 		Query:  qp.Query,
 		Phases: realizedPhases,
@@ -608,16 +660,7 @@ func reconstructPredicatesFromConstraints(phase Phase) []query.Clause {
 
 	// Collect all constraints from patterns
 	for _, pattern := range phase.Patterns {
-		if pattern.Metadata == nil {
-			continue
-		}
-
-		constraints, ok := pattern.Metadata["storage_constraints"].([]StorageConstraint)
-		if !ok {
-			continue
-		}
-
-		for _, constraint := range constraints {
+		for _, constraint := range pattern.Constraints {
 			switch constraint.Type {
 			case ConstraintEquality:
 				// Reconstruct equality predicate: [(= ?var value)]
@@ -786,6 +829,11 @@ func (rp *RealizedPhase) String() string {
 	if len(rp.Keep) > 0 {
 		sb.WriteString(fmt.Sprintf("Keep: %v\n", rp.Keep))
 	}
+	for _, pattern := range rp.Patterns() {
+		if idx, ok := rp.IndexPins[pattern]; ok {
+			sb.WriteString(fmt.Sprintf("Pinned: %s -> %s\n", pattern, indexName(idx)))
+		}
+	}
 
 	return sb.String()
 }
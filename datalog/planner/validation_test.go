@@ -1,8 +1,10 @@
 package planner
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/wbrown/janus-datalog/datalog/parser"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -146,3 +148,131 @@ func TestValidatePlan_InputSymbols(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateKeepProjections(t *testing.T) {
+	// Phase 1 binds ?e and ?name but keeps only ?e; phase 2 joins on ?e
+	phases := []Phase{
+		{Provides: []query.Symbol{"?e", "?name"}, Keep: []query.Symbol{"?e"}},
+		{Provides: []query.Symbol{"?e", "?age"}, Keep: []query.Symbol{"?e", "?age"}},
+	}
+
+	tests := []struct {
+		name    string
+		find    []query.FindElement
+		inputs  map[query.Symbol]bool
+		phases  []Phase
+		element string
+		symbol  query.Symbol
+	}{
+		{
+			name: "Kept variables",
+			find: []query.FindElement{query.FindVariable{Symbol: "?e"}, query.FindVariable{Symbol: "?age"}},
+		},
+		{
+			name:    "Dropped variable",
+			find:    []query.FindElement{query.FindVariable{Symbol: "?e"}, query.FindVariable{Symbol: "?name"}},
+			element: "?name",
+			symbol:  "?name",
+		},
+		{
+			name:    "Dropped aggregate argument",
+			find:    []query.FindElement{query.FindVariable{Symbol: "?age"}, query.FindAggregate{Function: "max", Arg: "?name"}},
+			element: "(max ?name)",
+			symbol:  "?name",
+		},
+		{
+			name: "Rebound by a later phase",
+			find: []query.FindElement{query.FindVariable{Symbol: "?name"}},
+			phases: []Phase{
+				phases[0],
+				{Provides: []query.Symbol{"?e", "?name"}, Keep: []query.Symbol{"?e", "?name"}},
+			},
+		},
+		{
+			name: "Kept for a conditional aggregate",
+			find: []query.FindElement{query.FindAggregate{Function: "max", Arg: "?name"}},
+			phases: []Phase{
+				{Provides: []query.Symbol{"?e", "?name"}, Keep: []query.Symbol{"?e"},
+					Metadata: map[string]interface{}{"aggregate_required_columns": []query.Symbol{"?name"}}},
+				phases[1],
+			},
+		},
+		{
+			name: "Never bound is left to validatePlan",
+			find: []query.FindElement{query.FindVariable{Symbol: "?missing"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.phases
+			if plan == nil {
+				plan = phases
+			}
+			err := validateKeepProjections(phaseKeepSteps(plan), tt.find, tt.inputs)
+			if tt.symbol == "" {
+				if err != nil {
+					t.Errorf("Expected no error but got: %v", err)
+				}
+				return
+			}
+
+			var dropped *DroppedSymbolError
+			if !errors.As(err, &dropped) {
+				t.Fatalf("Expected DroppedSymbolError, got %v", err)
+			}
+			if !errors.Is(err, ErrFindSymbolDropped) {
+				t.Errorf("Expected error to wrap ErrFindSymbolDropped")
+			}
+			if dropped.Symbol != tt.symbol || dropped.Element != tt.element || dropped.Phase != 1 {
+				t.Errorf("Expected %s in %s dropped by phase 1, got %v", tt.symbol, tt.element, err)
+			}
+		})
+	}
+}
+
+func TestKeepProjectionsRealizedPlans(t *testing.T) {
+	q, err := parser.ParseQuery(twoPhaseQuery)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	inputs := map[query.Symbol]bool{"?min": true}
+
+	planners := map[string]QueryPlanner{
+		"Planner":            NewPlannerAdapter(nil, PlannerOptions{}),
+		"ClauseBasedPlanner": NewClauseBasedPlanner(nil, PlannerOptions{}),
+	}
+	for name, p := range planners {
+		t.Run(name, func(t *testing.T) {
+			plan, err := p.PlanQuery(q)
+			if err != nil {
+				t.Fatalf("Failed to plan query: %v", err)
+			}
+			if err := validateKeepProjections(realizedKeepSteps(plan.Phases), q.Find, inputs); err != nil {
+				t.Errorf("Expected the planned Keeps to pass, got %v", err)
+			}
+		})
+	}
+
+	// Validate reports a find symbol that an edited Keep drops
+	plan := planForEdit(t, twoPhaseQuery)
+	if len(plan.Phases) < 2 {
+		t.Fatalf("Expected a multi-phase plan, got %d phases", len(plan.Phases))
+	}
+	phase := &plan.Phases[0]
+	var keep []query.Symbol
+	for _, sym := range phase.Keep {
+		if sym != "?name" {
+			keep = append(keep, sym)
+		}
+	}
+	if len(keep) == len(phase.Keep) {
+		t.Fatalf("Expected the first phase to keep ?name, keeps %v", phase.Keep)
+	}
+	phase.Keep = keep
+
+	var dropped *DroppedSymbolError
+	if err := plan.Validate(); !errors.As(err, &dropped) || dropped.Symbol != "?name" || dropped.Phase != 1 {
+		t.Errorf("Expected Validate to report ?name dropped by phase 1, got %v", err)
+	}
+}
@@ -13,6 +13,7 @@ func (*ChainedComparison) clause() {}
 func (*NotEqualPredicate) clause() {}
 func (*GroundPredicate) clause()   {}
 func (*MissingPredicate) clause()  {}
+func (*InPredicate) clause()       {}
 func (*Expression) clause()        {}
 func (*Subquery) clause()          {}
 
@@ -0,0 +1,94 @@
+package query
+
+import "github.com/wbrown/janus-datalog/datalog"
+
+// Clone returns a deep copy of the query: its options, clauses and nested
+// subqueries can be changed without affecting q. Functions and constant
+// values are shared, as they are never modified.
+func (q *Query) Clone() *Query {
+	if q == nil {
+		return nil
+	}
+	c := *q
+	c.Find = append([]FindElement(nil), q.Find...)
+	c.OrderBy = append([]OrderByClause(nil), q.OrderBy...)
+
+	if q.In != nil {
+		c.In = make([]InputSpec, len(q.In))
+		for i, input := range q.In {
+			switch inp := input.(type) {
+			case TupleInput:
+				c.In[i] = TupleInput{Symbols: append([]Symbol(nil), inp.Symbols...)}
+			case RelationInput:
+				c.In[i] = RelationInput{Symbols: append([]Symbol(nil), inp.Symbols...)}
+			default:
+				c.In[i] = input
+			}
+		}
+	}
+
+	if q.Where != nil {
+		c.Where = make([]Clause, len(q.Where))
+		for i, clause := range q.Where {
+			c.Where[i] = cloneClause(clause)
+		}
+	}
+
+	if q.GroupingSets != nil {
+		c.GroupingSets = make([][]Symbol, len(q.GroupingSets))
+		for i, set := range q.GroupingSets {
+			c.GroupingSets[i] = append([]Symbol(nil), set...)
+		}
+	}
+	return &c
+}
+
+// Clone returns a copy of the pattern, hints included, whose elements and
+// attribute set can be changed without affecting p
+func (p *DataPattern) Clone() *DataPattern {
+	c := *p
+	c.Elements = append([]PatternElement(nil), p.Elements...)
+	c.Attributes = append([]datalog.Keyword(nil), p.Attributes...)
+	return &c
+}
+
+// cloneClause returns a copy of a :where clause, with nested queries cloned
+func cloneClause(clause Clause) Clause {
+	switch c := clause.(type) {
+	case *DataPattern:
+		return c.Clone()
+	case *Comparison:
+		cmp := *c
+		return &cmp
+	case *ChainedComparison:
+		cmp := *c
+		cmp.Terms = append([]Term(nil), c.Terms...)
+		return &cmp
+	case *NotEqualPredicate:
+		ne := *c
+		return &ne
+	case *GroundPredicate:
+		return &GroundPredicate{Variables: append([]Symbol(nil), c.Variables...)}
+	case *MissingPredicate:
+		return &MissingPredicate{Variables: append([]Symbol(nil), c.Variables...)}
+	case *InPredicate:
+		return &InPredicate{Variable: c.Variable, Values: append([]interface{}(nil), c.Values...)}
+	case FunctionPredicate:
+		c.Args = append([]PatternElement(nil), c.Args...)
+		return c
+	case *Expression:
+		expr := *c
+		return &expr
+	case *Subquery:
+		sq := *c
+		sq.Query = c.Query.Clone()
+		sq.Inputs = append([]Symbol(nil), c.Inputs...)
+		return &sq
+	case *SubqueryPattern:
+		sp := *c
+		sp.Query = c.Query.Clone()
+		sp.Inputs = append([]PatternElement(nil), c.Inputs...)
+		return &sp
+	}
+	return clause
+}
@@ -0,0 +1,53 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryClone(t *testing.T) {
+	inner := &Query{
+		Find:  []FindElement{FindVariable{Symbol: "?n"}},
+		Where: []Clause{&DataPattern{Elements: []PatternElement{Variable{Name: "?e"}, Constant{Value: "a"}, Variable{Name: "?n"}}}},
+	}
+	q := &Query{
+		Find: []FindElement{FindVariable{Symbol: "?e"}},
+		In:   []InputSpec{DatabaseInput{}, TupleInput{Symbols: []Symbol{"?a", "?b"}}},
+		Where: []Clause{
+			&DataPattern{Elements: []PatternElement{Variable{Name: "?e"}, Variable{Name: "?a"}, Variable{Name: "?v"}}, MaxDatoms: 5},
+			&Comparison{Op: OpEQ, Left: VariableTerm{Symbol: "?v"}, Right: ConstantTerm{Value: "x"}},
+			&SubqueryPattern{Query: inner, Inputs: []PatternElement{Variable{Name: "?e"}}, Binding: CollectionBinding{Variable: "?ns"}},
+		},
+		Timeout: time.Second,
+		Limit:   10,
+	}
+
+	c := q.Clone()
+	if c.String() != q.String() || c.Timeout != q.Timeout || c.Limit != q.Limit {
+		t.Fatalf("Expected an identical copy, got %s", c)
+	}
+
+	// Changing the copy leaves the original alone
+	c.Limit = 1
+	c.In[1].(TupleInput).Symbols[0] = "?z"
+	dp := c.Where[0].(*DataPattern)
+	dp.Elements[2] = Constant{Value: "y"}
+	if dp.MaxDatoms != 5 {
+		t.Errorf("Expected MaxDatoms to be copied, got %d", dp.MaxDatoms)
+	}
+	c.Where[1].(*Comparison).Op = OpLT
+	c.Where[2].(*SubqueryPattern).Query.Where[0].(*DataPattern).Elements[1] = Constant{Value: "b"}
+
+	if q.Limit != 10 || q.In[1].(TupleInput).Symbols[0] != "?a" {
+		t.Error("Expected the original options and inputs unchanged")
+	}
+	if _, ok := q.Where[0].(*DataPattern).Elements[2].(Variable); !ok {
+		t.Error("Expected the original pattern unchanged")
+	}
+	if q.Where[1].(*Comparison).Op != OpEQ {
+		t.Error("Expected the original comparison unchanged")
+	}
+	if inner.Where[0].(*DataPattern).Elements[1].(Constant).Value != "a" {
+		t.Error("Expected the original nested query unchanged")
+	}
+}
@@ -152,9 +152,11 @@ func (s StringConcatFunction) ReturnType() string {
 }
 
 // TimeExtractionFunction extracts components from time values
+// Example: [(day ?t)] or [(day ?t "America/New_York")] for the day in a zone
 type TimeExtractionFunction struct {
 	Field    string // "year", "month", "day", "hour", "minute", "second"
 	TimeTerm Term
+	Location *time.Location // Zone the component is extracted in (nil = the time's own)
 }
 
 func (t TimeExtractionFunction) RequiredSymbols() []Symbol {
@@ -172,26 +174,72 @@ func (t TimeExtractionFunction) Eval(bindings map[Symbol]interface{}) (interface
 		return nil, fmt.Errorf("expected time.Time, got %T", timeVal)
 	}
 
-	switch t.Field {
+	v, ok := ExtractTimeField(t.Field, tm, t.Location)
+	if !ok {
+		return nil, fmt.Errorf("unknown time field: %s", t.Field)
+	}
+	return v, nil
+}
+
+func (t TimeExtractionFunction) String() string {
+	if t.Location != nil {
+		return fmt.Sprintf("(%s %s %q)", t.Field, t.TimeTerm, t.Location.String())
+	}
+	return fmt.Sprintf("(%s %s)", t.Field, t.TimeTerm)
+}
+
+// ExtractTimeField returns a component of a time as seen in loc, or in its
+// own location if loc is nil. It returns false for an unknown field.
+func ExtractTimeField(field string, tm time.Time, loc *time.Location) (int64, bool) {
+	if loc != nil {
+		tm = tm.In(loc)
+	}
+	switch field {
 	case "year":
-		return int64(tm.Year()), nil
+		return int64(tm.Year()), true
 	case "month":
-		return int64(tm.Month()), nil
+		return int64(tm.Month()), true
 	case "day":
-		return int64(tm.Day()), nil
+		return int64(tm.Day()), true
 	case "hour":
-		return int64(tm.Hour()), nil
+		return int64(tm.Hour()), true
 	case "minute":
-		return int64(tm.Minute()), nil
+		return int64(tm.Minute()), true
 	case "second":
-		return int64(tm.Second()), nil
+		return int64(tm.Second()), true
 	default:
-		return nil, fmt.Errorf("unknown time field: %s", t.Field)
+		return 0, false
 	}
 }
 
-func (t TimeExtractionFunction) String() string {
-	return fmt.Sprintf("(%s %s)", t.Field, t.TimeTerm)
+// SetDefaultTimeZone sets the zone of the query's time extractions that
+// don't name one, including those in nested queries. The parser applies a
+// query map's :time-zone option with it.
+func SetDefaultTimeZone(q *Query, loc *time.Location) {
+	for _, clause := range q.Where {
+		switch c := clause.(type) {
+		case *Expression:
+			switch fn := c.Function.(type) {
+			case *TimeExtractionFunction:
+				if fn.Location == nil {
+					fn.Location = loc
+				}
+			case TimeExtractionFunction:
+				if fn.Location == nil {
+					fn.Location = loc
+					c.Function = fn
+				}
+			}
+		case *SubqueryPattern:
+			if c.Query != nil {
+				SetDefaultTimeZone(c.Query, loc)
+			}
+		case *Subquery:
+			if c.Query != nil {
+				SetDefaultTimeZone(c.Query, loc)
+			}
+		}
+	}
 }
 
 func (t TimeExtractionFunction) ReturnType() string {
@@ -246,6 +294,36 @@ func (i IdentityFunction) ReturnType() string {
 	return "any"
 }
 
+// NotFunction negates a boolean value
+// Example: [(not ?enabled) ?disabled]
+type NotFunction struct {
+	Arg Term
+}
+
+func (n NotFunction) RequiredSymbols() []Symbol {
+	return n.Arg.RequiredSymbols()
+}
+
+func (n NotFunction) Eval(bindings map[Symbol]interface{}) (interface{}, error) {
+	val, ok := n.Arg.Resolve(bindings)
+	if !ok {
+		return nil, fmt.Errorf("cannot resolve argument %s", n.Arg)
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return nil, fmt.Errorf("not requires a boolean, got %T", val)
+	}
+	return !b, nil
+}
+
+func (n NotFunction) String() string {
+	return fmt.Sprintf("(not %s)", n.Arg)
+}
+
+func (n NotFunction) ReturnType() string {
+	return "boolean"
+}
+
 // Helper functions for type conversion
 func toNumber(val interface{}) interface{} {
 	switch v := val.(type) {
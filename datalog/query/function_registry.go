@@ -53,43 +53,43 @@ func NewFunctionRegistry() *FunctionRegistry {
 	r.Register(FunctionMetadata{
 		Name:        "year",
 		MinArgs:     1,
-		MaxArgs:     1,
-		Description: "Extract year from time value",
+		MaxArgs:     2,
+		Description: "Extract year from time value, optionally in a named time zone",
 	})
 
 	r.Register(FunctionMetadata{
 		Name:        "month",
 		MinArgs:     1,
-		MaxArgs:     1,
-		Description: "Extract month from time value",
+		MaxArgs:     2,
+		Description: "Extract month from time value, optionally in a named time zone",
 	})
 
 	r.Register(FunctionMetadata{
 		Name:        "day",
 		MinArgs:     1,
-		MaxArgs:     1,
-		Description: "Extract day from time value",
+		MaxArgs:     2,
+		Description: "Extract day from time value, optionally in a named time zone",
 	})
 
 	r.Register(FunctionMetadata{
 		Name:        "hour",
 		MinArgs:     1,
-		MaxArgs:     1,
-		Description: "Extract hour from time value",
+		MaxArgs:     2,
+		Description: "Extract hour from time value, optionally in a named time zone",
 	})
 
 	r.Register(FunctionMetadata{
 		Name:        "minute",
 		MinArgs:     1,
-		MaxArgs:     1,
-		Description: "Extract minute from time value",
+		MaxArgs:     2,
+		Description: "Extract minute from time value, optionally in a named time zone",
 	})
 
 	r.Register(FunctionMetadata{
 		Name:        "second",
 		MinArgs:     1,
-		MaxArgs:     1,
-		Description: "Extract second from time value",
+		MaxArgs:     2,
+		Description: "Extract second from time value, optionally in a named time zone",
 	})
 
 	// Date comparison functions
@@ -100,6 +100,14 @@ func NewFunctionRegistry() *FunctionRegistry {
 		Description: "Check if two time values are on the same date",
 	})
 
+	// Boolean functions
+	r.Register(FunctionMetadata{
+		Name:        "not",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Description: "Check if boolean value is false",
+	})
+
 	return r
 }
 
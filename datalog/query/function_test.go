@@ -92,6 +92,31 @@ func TestStringConcatFunction(t *testing.T) {
 	}
 }
 
+func TestNotFunction(t *testing.T) {
+	fn := NotFunction{Arg: VariableTerm{Symbol: "?b"}}
+
+	for _, b := range []bool{true, false} {
+		result, err := fn.Eval(map[Symbol]interface{}{"?b": b})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result != !b {
+			t.Errorf("(not %v): expected %v, got %v", b, !b, result)
+		}
+	}
+
+	if _, err := fn.Eval(map[Symbol]interface{}{"?b": "true"}); err == nil {
+		t.Error("Expected error for non-boolean argument")
+	}
+	if _, err := fn.Eval(map[Symbol]interface{}{}); err == nil {
+		t.Error("Expected error for unbound argument")
+	}
+
+	if fn.String() != "(not ?b)" {
+		t.Errorf("Expected (not ?b), got %s", fn.String())
+	}
+}
+
 func TestTimeExtractionFunction(t *testing.T) {
 	testTime := time.Date(2024, 6, 15, 14, 30, 45, 0, time.UTC)
 
@@ -444,6 +444,27 @@ func (f FunctionPredicate) Eval(bindings map[Symbol]interface{}) (bool, error) {
 
 		return len(str) >= len(prefix) && str[:len(prefix)] == prefix, nil
 
+	case "not":
+		// [(not ?b)] passes when ?b is false
+		if len(f.Args) != 1 {
+			return false, fmt.Errorf("not requires 1 argument, got %d", len(f.Args))
+		}
+		var val interface{}
+		if v, ok := f.Args[0].(Variable); ok {
+			bound, exists := bindings[Symbol(v.Name)]
+			if !exists {
+				return false, fmt.Errorf("variable %s not bound", v.Name)
+			}
+			val = bound
+		} else if c, ok := f.Args[0].(Constant); ok {
+			val = c.Value
+		}
+		b, ok := val.(bool)
+		if !ok {
+			return false, nil // Not a boolean, can't be false
+		}
+		return !b, nil
+
 	default:
 		// Unknown function - for now just return false
 		// In a real implementation, we'd have a registry of functions
@@ -471,3 +492,50 @@ func (f FunctionPredicate) CanPushToStorage() bool {
 }
 
 func (f FunctionPredicate) clause() {}
+
+// InPredicate tests a variable's value against a set of constants:
+// [(in ?a #{:person/name :person/email})]. When the variable is a data
+// pattern's attribute, the planner also uses the set to scan only those
+// attributes (see DataPattern.Attributes).
+type InPredicate struct {
+	Variable Symbol
+	Values   []interface{}
+}
+
+func (p InPredicate) RequiredSymbols() []Symbol {
+	return []Symbol{p.Variable}
+}
+
+func (p InPredicate) Eval(bindings map[Symbol]interface{}) (bool, error) {
+	val, ok := bindings[p.Variable]
+	if !ok {
+		return false, fmt.Errorf("variable %s not bound", p.Variable)
+	}
+	for _, v := range p.Values {
+		if datalog.ValuesEqual(val, v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p InPredicate) String() string {
+	s := fmt.Sprintf("[(in %s #{", p.Variable)
+	for i, v := range p.Values {
+		if i > 0 {
+			s += " "
+		}
+		s += Constant{Value: v}.String()
+	}
+	s += "})]"
+	return s
+}
+
+func (p InPredicate) Selectivity() float64 {
+	// A small set is usually selective
+	return 0.1
+}
+
+func (p InPredicate) CanPushToStorage() bool {
+	return false
+}
@@ -65,6 +65,16 @@ type Pattern interface {
 // DataPattern represents a data pattern [e a v] or [e a v t]
 type DataPattern struct {
 	Elements []PatternElement
+
+	// MaxDatoms caps the datoms the pattern may match (0 = no limit), set by
+	// a trailing {:max-datoms n} hint. Matching stops at the cap and the
+	// query returns a partial result, reported as PatternLimitReached.
+	MaxDatoms int
+
+	// Attributes lists the attributes a variable attribute position may take,
+	// set by the planner from an [(in ?a #{...})] predicate. The pattern is
+	// then matched once per attribute instead of scanning every attribute.
+	Attributes []datalog.Keyword
 }
 
 // SubqueryPattern represents a nested query pattern [(q <query> <inputs...>) <binding>]
@@ -137,6 +147,19 @@ func (p DataPattern) String() string {
 		}
 		result += elem.String()
 	}
+	if p.MaxDatoms > 0 {
+		result += fmt.Sprintf(" {:max-datoms %d}", p.MaxDatoms)
+	}
+	if len(p.Attributes) > 0 {
+		result += " {:attributes #{"
+		for i, attr := range p.Attributes {
+			if i > 0 {
+				result += " "
+			}
+			result += attr.String()
+		}
+		result += "}}"
+	}
 	result += "]"
 	return result
 }
@@ -273,6 +296,23 @@ type Query struct {
 	In      []InputSpec     // Input specifications (database and parameters)
 	Where   []Clause        // Clauses in WHERE (DataPattern, Predicate, Expression, Subquery)
 	OrderBy []OrderByClause // Optional ordering of results
+
+	// Execution options from the map query form {:query [...] :timeout 5000 :limit 100}
+	Timeout time.Duration // Maximum execution time (0 = no timeout)
+	Offset  int           // Number of result tuples to skip (applied after :order-by)
+	Limit   int           // Maximum number of result tuples to return (0 = no limit)
+
+	// GroupingSets aggregates at several grouping levels in one pass, from
+	// :grouping-sets or :rollup. Each set lists the :find variables grouped
+	// on; the others are nil in that level's rows. Nil groups by all :find
+	// variables as usual.
+	GroupingSets [][]Symbol
+}
+
+// HasOptions returns true if any execution option (timeout, offset, limit,
+// grouping sets) is set
+func (q Query) HasOptions() bool {
+	return q.Timeout > 0 || q.Offset > 0 || q.Limit > 0 || len(q.GroupingSets) > 0
 }
 
 // InputSpec represents an input specification in the :in clause
@@ -360,9 +400,11 @@ func (f FindVariable) IsAggregate() bool {
 
 // FindAggregate represents an aggregate function in the find clause
 type FindAggregate struct {
-	Function  string // "sum", "avg", "count", "min", "max"
-	Arg       Symbol // Variable to aggregate
-	Predicate Symbol // Optional: predicate variable for conditional aggregates (e.g., min-if, max-if)
+	Function   string      // "sum", "avg", "count", "min", "max", "percentile", "histogram", "string-agg"
+	Arg        Symbol      // Variable to aggregate
+	Predicate  Symbol      // Optional: predicate variable for conditional aggregates (e.g., min-if, max-if)
+	Param      interface{} // Optional: constant argument (percentile quantile, histogram bucket width, string-agg separator)
+	Descending bool        // string-agg: join values in descending rather than ascending order
 }
 
 // IsConditional returns true if this is a conditional aggregate (has a predicate)
@@ -373,7 +415,18 @@ func (f FindAggregate) IsConditional() bool {
 func (f FindAggregate) String() string {
 	// Note: Predicate field is for internal query rewriting only
 	// Users never write conditional aggregate syntax explicitly
-	return fmt.Sprintf("(%s %s)", f.Function, f.Arg)
+	switch {
+	case f.Param == nil:
+		return fmt.Sprintf("(%s %s)", f.Function, f.Arg)
+	case f.Function == "percentile":
+		return fmt.Sprintf("(%s %v %s)", f.Function, f.Param, f.Arg)
+	case f.Function == "string-agg" && f.Descending:
+		return fmt.Sprintf("(%s %s %q :desc)", f.Function, f.Arg, f.Param)
+	case f.Function == "string-agg":
+		return fmt.Sprintf("(%s %s %q)", f.Function, f.Arg, f.Param)
+	default:
+		return fmt.Sprintf("(%s %s %v)", f.Function, f.Arg, f.Param)
+	}
 }
 
 func (f FindAggregate) IsAggregate() bool {
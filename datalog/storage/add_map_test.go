@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestAddMapNested(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	created, err := tx.AddMapAll(map[string]interface{}{
+		":order/id": "o-1",
+		":order/customer": map[string]interface{}{
+			":customer/name": "Alice",
+			":customer/address": map[string]interface{}{
+				":address/city": "Lisbon",
+			},
+		},
+		":order/lines": []map[string]interface{}{
+			{":line/sku": "A", ":line/qty": int64(2)},
+			{":line/sku": "B", ":line/qty": int64(1)},
+		},
+		":order/tags": []interface{}{"rush", map[string]interface{}{":tag/name": "gift"}},
+	})
+	if err != nil {
+		t.Fatalf("AddMapAll failed: %v", err)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	// Order, customer, address, two lines and a tag
+	if len(created) != 6 {
+		t.Fatalf("Expected 6 created entities, got %d", len(created))
+	}
+	seen := make(map[string]bool)
+	for _, id := range created {
+		if seen[id.String()] {
+			t.Errorf("Entity %v created twice", id)
+		}
+		seen[id.String()] = true
+	}
+
+	result, err := db.ExecuteQuery(`[:find ?id ?city
+	  :where [?o :order/id ?id]
+	         [?o :order/customer ?c]
+	         [?c :customer/address ?a]
+	         [?a :address/city ?city]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected the order's customer city, got %d rows", len(result))
+	}
+	if city := result[0][1]; city != "Lisbon" {
+		t.Errorf("Expected Lisbon, got %v", city)
+	}
+
+	result, err = db.ExecuteQuery(`[:find ?o ?sku ?qty
+	  :where [?o :order/lines ?l]
+	         [?l :line/sku ?sku]
+	         [?l :line/qty ?qty]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected 2 order lines, got %d", len(result))
+	}
+	for i := 0; i < len(result); i++ {
+		if o := result[i][0].(*datalog.Identity); !o.Equal(created[0]) {
+			t.Errorf("Expected lines of the root order %v, got %v", created[0], o)
+		}
+	}
+
+	result, err = db.ExecuteQuery(`[:find ?tag :where [?o :order/tags ?tag]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("Expected a value and a ref tag, got %d", len(result))
+	}
+}
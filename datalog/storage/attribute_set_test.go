@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestAttributeSetExpansion(t *testing.T) {
+	dir, err := os.MkdirTemp("", "attribute-set-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	// 50 people with 10 attributes each
+	tx := db.NewTransaction()
+	for i := 0; i < 50; i++ {
+		person := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		for j := 0; j < 10; j++ {
+			tx.Add(person, datalog.NewKeyword(fmt.Sprintf(":person/field%d", j)), fmt.Sprintf("value %d-%d", i, j))
+		}
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	t.Run("ScansOnlyTheSet", func(t *testing.T) {
+		q := `[:find ?e ?a ?v :where [?e ?a ?v] [(in ?a #{:person/field1 :person/field2})]]`
+		rows, md, err := db.ExecuteQueryWithMetadata(q)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 100 {
+			t.Fatalf("Expected 100 rows, got %d", len(rows))
+		}
+		for _, row := range rows {
+			attr := row[1].(datalog.Keyword).String()
+			if attr != ":person/field1" && attr != ":person/field2" {
+				t.Fatalf("Expected only the set's attributes, got %s", attr)
+			}
+		}
+		if md.DatomsScanned != 100 {
+			t.Errorf("Expected the two attributes' 100 datoms scanned, got %d", md.DatomsScanned)
+		}
+	})
+
+	t.Run("BoundEntity", func(t *testing.T) {
+		q := `[:find ?a ?v :where [?e :person/field0 "value 7-0"] [?e ?a ?v] [(in ?a #{:person/field3 :person/field9})]]`
+		rows, err := db.ExecuteQuery(q)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		got := map[string]string{}
+		for _, row := range rows {
+			got[row[0].(datalog.Keyword).String()] = row[1].(string)
+		}
+		if len(got) != 2 || got[":person/field3"] != "value 7-3" || got[":person/field9"] != "value 7-9" {
+			t.Errorf("Expected fields 3 and 9 of person 7, got %v", got)
+		}
+	})
+
+	t.Run("BoundAttribute", func(t *testing.T) {
+		// An input binds ?a; the set still filters it
+		q := `[:find ?v :in $ [?a ...] :where [?e ?a ?v] [(in ?a #{:person/field4})]]`
+		rows, err := db.ExecuteQueryWithInputs(q, []interface{}{
+			datalog.NewKeyword(":person/field4"),
+			datalog.NewKeyword(":person/field5"),
+		})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 50 {
+			t.Errorf("Expected 50 rows of field 4, got %d", len(rows))
+		}
+	})
+}
@@ -10,8 +10,10 @@ import (
 
 // BadgerStore implements Store using BadgerDB
 type BadgerStore struct {
-	db      *badger.DB
-	encoder KeyEncoder
+	db          *badger.DB
+	encoder     KeyEncoder
+	offloadSize int      // Strings and bytes above this size go to the blob keyspace (0 = none)
+	scans       activity // Open iterators; Shutdown waits for them to be closed
 }
 
 // NewBadgerStore creates a new BadgerDB-backed store with the specified encoder
@@ -55,8 +57,25 @@ func (s *BadgerStore) Assert(datoms []datalog.Datom) error {
 	})
 }
 
+// keyWriter is satisfied by both badger transactions and write batches
+type keyWriter interface {
+	Set(key, value []byte) error
+}
+
 // assertDatom adds a single datom to all indices
-func (s *BadgerStore) assertDatom(txn *badger.Txn, d *datalog.Datom) error {
+func (s *BadgerStore) assertDatom(txn keyWriter, d *datalog.Datom) error {
+	// Write an offloaded value once, under its hash, and index its reference
+	if ref, data, ok := s.offload(d.V); ok {
+		if err := txn.Set(blobKey(ref.hash), data); err != nil {
+			return fmt.Errorf("failed to write offloaded value: %w", err)
+		}
+		indexed := *d
+		indexed.V = ref
+		d = &indexed
+	} else if size := valueSize(d.V); size > maxInlineValueSize {
+		return fmt.Errorf("%w: %s value is %d bytes, limit is %d", ErrValueTooLarge, d.A, size, maxInlineValueSize)
+	}
+
 	// Serialize the datom
 	sd := ToStorageDatom(*d)
 	value := sd.Bytes()
@@ -87,6 +106,12 @@ func (s *BadgerStore) Retract(datoms []datalog.Datom) error {
 
 // retractDatom removes a single datom from all indices
 func (s *BadgerStore) retractDatom(txn *badger.Txn, d *datalog.Datom) error {
+	// Offloaded values are left in the blob keyspace, as other datoms may
+	// share them
+	indexed := *d
+	indexed.V = s.indexedValue(d.V)
+	d = &indexed
+
 	// Remove from all indices
 	indices := []IndexType{EAVT, AEVT, AVET, VAET, TAEV}
 	for _, idx := range indices {
@@ -99,22 +124,65 @@ func (s *BadgerStore) retractDatom(txn *badger.Txn, d *datalog.Datom) error {
 	return nil
 }
 
+// storedCopies returns every stored datom with d's entity, attribute and
+// value, whichever transaction asserted it
+func (s *BadgerStore) storedCopies(txn *badger.Txn, d *datalog.Datom) ([]datalog.Datom, error) {
+	sd := ToStorageDatom(datalog.Datom{E: d.E, A: d.A})
+	prefix := s.encoder.EncodePrefix(EAVT, sd.E[:], sd.A[:])
+	valueType, value := datalog.Type(d.V), datalog.ValueBytes(d.V)
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var copies []datalog.Datom
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		stored, err := DatomFromKey(EAVT, it.Item().KeyCopy(nil), s.encoder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode datom: %w", err)
+		}
+		// Compare the value itself, but keep the datom as its keys hold it
+		resolved := *stored
+		if err := resolveBlob(txn, &resolved); err != nil {
+			return nil, err
+		}
+		if datalog.Type(resolved.V) == valueType && bytes.Equal(datalog.ValueBytes(resolved.V), value) {
+			copies = append(copies, *stored)
+		}
+	}
+	return copies, nil
+}
+
 // Scan returns an iterator for a range of keys
 func (s *BadgerStore) Scan(index IndexType, start, end []byte) (Iterator, error) {
-	txn := s.db.NewTransaction(false)
-
 	opts := badger.DefaultIteratorOptions
 	opts.PrefetchSize = 1000   // Increased from 10 for better bulk scan performance
 	opts.PrefetchValues = true // We need values for datom construction
 
-	it := txn.NewIterator(opts)
+	iter, err := s.openIterator(opts)
+	if err != nil {
+		return nil, err
+	}
+	iter.start = start
+	iter.end = end
+	iter.index = index
+	return iter, nil
+}
 
+// openIterator starts a read transaction and an iterator over it, counted as
+// open until the BadgerIterator is closed. It returns ErrShutdown once the
+// database is shutting down.
+func (s *BadgerStore) openIterator(opts badger.IteratorOptions) (*BadgerIterator, error) {
+	if err := s.scans.begin(); err != nil {
+		return nil, err
+	}
+	txn := s.db.NewTransaction(false)
 	return &BadgerIterator{
-		txn:   txn,
-		it:    it,
-		start: start,
-		end:   end,
-		index: index,
+		txn:     txn,
+		it:      txn.NewIterator(opts),
+		scans:   &s.scans,
+		reverse: opts.Reverse,
 	}, nil
 }
 
@@ -141,7 +209,7 @@ func (s *BadgerStore) Get(index IndexType, key []byte) (*datalog.Datom, error) {
 				V:  sd.V,
 				Tx: sd.Tx.Uint64(),
 			}
-			return nil
+			return resolveBlob(txn, result)
 		})
 	})
 
@@ -154,11 +222,14 @@ func (s *BadgerStore) Get(index IndexType, key []byte) (*datalog.Datom, error) {
 
 // BeginTx starts a new transaction
 func (s *BadgerStore) BeginTx() (StoreTx, error) {
-	txn := s.db.NewTransaction(true)
+	return s.beginTx(), nil
+}
+
+func (s *BadgerStore) beginTx() *BadgerTx {
 	return &BadgerTx{
 		store: s,
-		txn:   txn,
-	}, nil
+		txn:   s.db.NewTransaction(true),
+	}
 }
 
 // Close closes the store
@@ -172,6 +243,11 @@ func (s *BadgerStore) ScanKeysOnly(index IndexType, start, end []byte) (Iterator
 	return NewKeyOnlyIterator(s, index, start, end)
 }
 
+// ScanKeysOnlyReverse is ScanKeysOnly in descending key order
+func (s *BadgerStore) ScanKeysOnlyReverse(index IndexType, start, end []byte) (Iterator, error) {
+	return newKeyOnlyIterator(s, index, start, end, true)
+}
+
 // ScanKeysOnlyWithMask - DEPRECATED: Key mask filtering was benchmarked slower
 // Just use regular key-only scanning with filtering in the matcher
 func (s *BadgerStore) ScanKeysOnlyWithMask(index IndexType, start, end []byte, mask *KeyMaskConstraint) (Iterator, error) {
@@ -213,10 +289,21 @@ type BadgerIterator struct {
 	end   []byte
 	index IndexType
 	valid bool
+
+	// reverse iterates from end (exclusive) down to start; the underlying
+	// Badger iterator must have been created with Reverse set
+	reverse bool
+
+	scans  *activity // The store's open iterators, ended on Close
+	closed bool
 }
 
 // Next advances the iterator
 func (i *BadgerIterator) Next() bool {
+	if i.reverse {
+		return i.nextReverse()
+	}
+
 	if !i.valid {
 		// First call - seek to start
 		i.it.Seek(i.start)
@@ -241,6 +328,37 @@ func (i *BadgerIterator) Next() bool {
 	return true
 }
 
+// nextReverse advances a reverse iterator
+func (i *BadgerIterator) nextReverse() bool {
+	if !i.valid {
+		// First call - seek to the last key at or before end, which is exclusive
+		i.valid = true
+		if i.end != nil {
+			i.it.Seek(i.end)
+			if i.it.Valid() && bytes.Equal(i.it.Item().Key(), i.end) {
+				i.it.Next()
+			}
+		} else {
+			i.it.Rewind()
+		}
+	} else {
+		i.it.Next()
+	}
+
+	if !i.it.Valid() {
+		return false
+	}
+
+	if i.start != nil {
+		key := i.it.Item().Key()
+		if bytes.Compare(key, i.start) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Datom returns the current datom
 func (i *BadgerIterator) Datom() (*datalog.Datom, error) {
 	item := i.it.Item()
@@ -261,7 +379,7 @@ func (i *BadgerIterator) Datom() (*datalog.Datom, error) {
 			V:  sd.V,
 			Tx: sd.Tx.Uint64(),
 		}
-		return nil
+		return resolveBlob(i.txn, result)
 	})
 
 	return result, err
@@ -269,8 +387,15 @@ func (i *BadgerIterator) Datom() (*datalog.Datom, error) {
 
 // Close closes the iterator
 func (i *BadgerIterator) Close() error {
+	if i.closed {
+		return nil
+	}
+	i.closed = true
 	i.it.Close()
 	i.txn.Discard()
+	if i.scans != nil {
+		i.scans.end()
+	}
 	return nil
 }
 
@@ -309,6 +434,32 @@ func (t *BadgerTx) Retract(datoms []datalog.Datom) error {
 	return nil
 }
 
+// RetractFacts removes every stored copy of each datom's entity, attribute
+// and value, ignoring the datom's transaction. A retraction staged in a
+// transaction names a fact, not the transaction that asserted it.
+func (t *BadgerTx) RetractFacts(datoms []datalog.Datom) error {
+	_, err := t.retractFacts(datoms)
+	return err
+}
+
+// retractFacts is RetractFacts, returning the stored datoms it removed
+func (t *BadgerTx) retractFacts(datoms []datalog.Datom) ([]datalog.Datom, error) {
+	var removed []datalog.Datom
+	for _, d := range datoms {
+		copies, err := t.store.storedCopies(t.txn, &d)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range copies {
+			if err := t.store.retractDatom(t.txn, &c); err != nil {
+				return nil, err
+			}
+		}
+		removed = append(removed, copies...)
+	}
+	return removed, nil
+}
+
 // Commit commits the transaction
 func (t *BadgerTx) Commit() error {
 	return t.txn.Commit()
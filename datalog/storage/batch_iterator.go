@@ -250,7 +250,7 @@ func (it *batchScanIterator) scanRange(rg RangeGroup) {
 
 	// Open scan for this range using key-only scanning
 	var err error
-	it.storageIter, err = it.matcher.store.ScanKeysOnly(it.index, rg.startKey, rg.endKey)
+	it.storageIter, err = it.matcher.scanKeysOnly(it.index, rg.startKey, rg.endKey)
 	if err != nil {
 		return
 	}
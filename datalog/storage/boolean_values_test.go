@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+// TestBooleanAttributeValues verifies that constant booleans in patterns are
+// looked up in AVET, scanning only the matching datoms, and that boolean
+// values can be negated with (not ?b) as a predicate and as a function
+func TestBooleanAttributeValues(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i := 0; i < 100; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("feature:%d", i))
+		tx.Add(e, datalog.NewKeyword(":flag/enabled"), i%10 == 0)
+		tx.Add(e, datalog.NewKeyword(":flag/name"), fmt.Sprintf("feature%d", i))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	count := func(t *testing.T, queryStr string, handler annotations.Handler) int {
+		t.Helper()
+		q, err := parser.ParseQuery(queryStr)
+		if err != nil {
+			t.Fatalf("Failed to parse query: %v", err)
+		}
+		result, err := db.NewExecutor().ExecuteWithContext(executor.NewContext(handler), q)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		n := 0
+		it := result.Iterator()
+		defer it.Close()
+		for it.Next() {
+			n++
+		}
+		return n
+	}
+
+	for _, tc := range []struct {
+		value    bool
+		expected int
+	}{{true, 10}, {false, 90}} {
+		t.Run(fmt.Sprintf("constant %v", tc.value), func(t *testing.T) {
+			var index string
+			var scanned int
+			handler := func(event annotations.Event) {
+				if event.Name == "pattern/storage-scan" && event.Data["pattern"] == fmt.Sprintf("[?e :flag/enabled %v]", tc.value) {
+					index, _ = event.Data["index"].(string)
+					scanned, _ = event.Data["datoms.scanned"].(int)
+				}
+			}
+			n := count(t, fmt.Sprintf(`[:find ?name :where [?e :flag/enabled %v] [?e :flag/name ?name]]`, tc.value), handler)
+			if n != tc.expected {
+				t.Errorf("Expected %d results, got %d", tc.expected, n)
+			}
+			if index != "AVET" {
+				t.Errorf("Expected AVET lookup, got %q", index)
+			}
+			if scanned != tc.expected {
+				t.Errorf("Expected %d datoms scanned, got %d", tc.expected, scanned)
+			}
+		})
+	}
+
+	t.Run("not predicate", func(t *testing.T) {
+		n := count(t, `[:find ?name :where [?e :flag/enabled ?b] [(not ?b)] [?e :flag/name ?name]]`, nil)
+		if n != 90 {
+			t.Errorf("Expected 90 disabled features, got %d", n)
+		}
+	})
+
+	t.Run("not function", func(t *testing.T) {
+		n := count(t, `[:find ?name :where [?e :flag/enabled ?b] [(not ?b) ?disabled] [(= ?disabled true)] [?e :flag/name ?name]]`, nil)
+		if n != 90 {
+			t.Errorf("Expected 90 disabled features, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// ErrCommitInDoubt is returned by Commit when the datoms were committed but
+// the commit hook's Commit failed. The transaction is recorded as in doubt
+// and RecoverCommits calls the hook's Commit again.
+var ErrCommitInDoubt = errors.New("commit in doubt")
+
+// CommitHook coordinates a transaction's commit with an external system,
+// such as writes to object storage or published messages, in two phases:
+// Prepare persists the side effect's intent before the datoms are written,
+// and Commit finalizes it once they are committed. Any callback may be nil.
+//
+// The database records each hooked transaction until its hook completes, so
+// that after a crash RecoverCommits can finalize or abort the transactions
+// left in doubt. Callbacks may therefore be called again for a transaction
+// they already handled and must be idempotent.
+type CommitHook struct {
+	// Name identifies the hook in the database's records, so RecoverCommits
+	// can match in-doubt transactions with their hook after a restart
+	Name string
+
+	// Prepare is called with the transaction's ID and datoms before they are
+	// written. An error aborts the commit.
+	Prepare func(txID uint64, assertions, retractions []datalog.Datom) error
+
+	// Commit is called after the datoms are committed. An error leaves the
+	// transaction in doubt (see ErrCommitInDoubt).
+	Commit func(txID uint64) error
+
+	// Abort discards a prepared side effect when writing the datoms fails,
+	// and for transactions that were prepared but never committed
+	Abort func(txID uint64) error
+}
+
+// InDoubtCommit is a hooked transaction whose hook has not completed
+type InDoubtCommit struct {
+	Hook      string // The hook's Name
+	TxID      uint64
+	Committed bool // The datoms were committed and the hook's Commit is pending; otherwise its Abort is
+}
+
+// Commit records are kept under their own key prefix, outside the indices
+const commitRecordPrefix = 0xF0
+
+const (
+	commitPrepared  byte = 'p'
+	commitCommitted byte = 'c'
+)
+
+func commitRecordKey(hook string, txID uint64) []byte {
+	key := make([]byte, 0, 1+len(hook)+8)
+	key = append(key, commitRecordPrefix)
+	key = append(key, hook...)
+	return binary.BigEndian.AppendUint64(key, txID)
+}
+
+// SetCommitHook sets the hook that coordinates this transaction's commit
+// with an external system
+func (t *Transaction) SetCommitHook(hook CommitHook) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hook = &hook
+}
+
+// prepareCommit records the transaction as prepared, then calls the hook's
+// Prepare, aborting if it fails
+func (d *Database) prepareCommit(hook *CommitHook, txID uint64, assertions, retractions []datalog.Datom) error {
+	if hook.Name == "" {
+		return fmt.Errorf("commit hook has no name")
+	}
+
+	key := commitRecordKey(hook.Name, txID)
+	err := d.store.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(key); err == nil {
+			return fmt.Errorf("transaction %d already has an unresolved commit for hook %s, see RecoverCommits", txID, hook.Name)
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		return txn.Set(key, []byte{commitPrepared})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record commit for hook %s: %w", hook.Name, err)
+	}
+
+	if hook.Prepare != nil {
+		if err := hook.Prepare(txID, assertions, retractions); err != nil {
+			d.abortCommit(hook, txID)
+			return fmt.Errorf("commit hook %s failed to prepare transaction %d: %w", hook.Name, txID, err)
+		}
+	}
+	return nil
+}
+
+// abortCommit calls the hook's Abort and forgets the transaction. If Abort
+// fails the transaction stays in doubt for RecoverCommits.
+func (d *Database) abortCommit(hook *CommitHook, txID uint64) error {
+	if hook.Abort != nil {
+		if err := hook.Abort(txID); err != nil {
+			return fmt.Errorf("commit hook %s failed to abort transaction %d: %w", hook.Name, txID, err)
+		}
+	}
+	return d.forgetCommit(hook.Name, txID)
+}
+
+// finishCommit calls the hook's Commit and forgets the transaction. If
+// Commit fails the transaction stays in doubt for RecoverCommits.
+func (d *Database) finishCommit(hook *CommitHook, txID uint64) error {
+	if hook.Commit != nil {
+		if err := hook.Commit(txID); err != nil {
+			return fmt.Errorf("%w: transaction %d committed but commit hook %s failed: %w", ErrCommitInDoubt, txID, hook.Name, err)
+		}
+	}
+	return d.forgetCommit(hook.Name, txID)
+}
+
+func (d *Database) forgetCommit(hook string, txID uint64) error {
+	err := d.store.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(commitRecordKey(hook, txID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear commit record of transaction %d: %w", txID, err)
+	}
+	return nil
+}
+
+// markCommitted records, as part of the storage transaction writing the
+// datoms, that the hooked transaction committed
+func (t *BadgerTx) markCommitted(hook string, txID uint64) error {
+	return t.txn.Set(commitRecordKey(hook, txID), []byte{commitCommitted})
+}
+
+// InDoubtCommits returns the hooked transactions whose hook has not completed,
+// in hook and transaction ID order
+func (d *Database) InDoubtCommits() ([]InDoubtCommit, error) {
+	var commits []InDoubtCommit
+	err := d.store.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte{commitRecordPrefix}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			if len(key) < 1+8 {
+				return fmt.Errorf("malformed commit record key %x", key)
+			}
+			state, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			commits = append(commits, InDoubtCommit{
+				Hook:      string(key[1 : len(key)-8]),
+				TxID:      binary.BigEndian.Uint64(key[len(key)-8:]),
+				Committed: len(state) == 1 && state[0] == commitCommitted,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan commit records: %w", err)
+	}
+	return commits, nil
+}
+
+// RecoverCommits resolves the transactions left in doubt by a crash or a
+// failed hook: the hook's Commit is called for those whose datoms were
+// committed and its Abort for the rest. Call it after opening the database
+// and before committing, with every hook that transactions may have used.
+// It returns how many transactions were resolved; those whose hook is not
+// given or fails again stay in doubt and are reported in the error.
+func (d *Database) RecoverCommits(hooks ...CommitHook) (int, error) {
+	commits, err := d.InDoubtCommits()
+	if err != nil {
+		return 0, err
+	}
+
+	byName := make(map[string]*CommitHook, len(hooks))
+	for i := range hooks {
+		byName[hooks[i].Name] = &hooks[i]
+	}
+
+	resolved := 0
+	var errs []error
+	for _, c := range commits {
+		hook, ok := byName[c.Hook]
+		if !ok {
+			errs = append(errs, fmt.Errorf("transaction %d is in doubt for unknown commit hook %s", c.TxID, c.Hook))
+			continue
+		}
+		if c.Committed {
+			err = d.finishCommit(hook, c.TxID)
+		} else {
+			err = d.abortCommit(hook, c.TxID)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resolved++
+	}
+	return resolved, errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// recordingHook returns a commit hook that logs its calls, failing those
+// named in fail
+func recordingHook(calls *[]string, fail map[string]bool) CommitHook {
+	call := func(name string) error {
+		*calls = append(*calls, name)
+		if fail[name] {
+			return errors.New(name + " failed")
+		}
+		return nil
+	}
+	return CommitHook{
+		Name:    "outbox",
+		Prepare: func(txID uint64, assertions, retractions []datalog.Datom) error { return call("prepare") },
+		Commit:  func(txID uint64) error { return call("commit") },
+		Abort:   func(txID uint64) error { return call("abort") },
+	}
+}
+
+func TestCommitHook(t *testing.T) {
+	dir, err := os.MkdirTemp("", "commit-hook-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabaseWithTimeTx(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	name := datalog.NewKeyword(":person/name")
+	commit := func(value string, hook CommitHook) (uint64, error) {
+		tx := db.NewTransaction()
+		tx.SetCommitHook(hook)
+		if err := tx.Add(datalog.NewIdentity("person:"+value), name, value); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		return tx.Commit()
+	}
+	names := func() int {
+		rows, err := db.ExecuteQuery(`[:find ?n :where [_ :person/name ?n]]`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		return len(rows)
+	}
+	inDoubt := func() []InDoubtCommit {
+		commits, err := db.InDoubtCommits()
+		if err != nil {
+			t.Fatalf("InDoubtCommits failed: %v", err)
+		}
+		return commits
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		var calls []string
+		if _, err := commit("alice", recordingHook(&calls, nil)); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if want := []string{"prepare", "commit"}; !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+		if names() != 1 || len(inDoubt()) != 0 {
+			t.Errorf("Expected 1 name and nothing in doubt, got %d and %v", names(), inDoubt())
+		}
+	})
+
+	t.Run("PrepareFails", func(t *testing.T) {
+		var calls []string
+		if _, err := commit("bob", recordingHook(&calls, map[string]bool{"prepare": true})); err == nil {
+			t.Fatal("Expected commit to fail")
+		}
+		if want := []string{"prepare", "abort"}; !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+		if names() != 1 || len(inDoubt()) != 0 {
+			t.Errorf("Expected bob not committed and nothing in doubt, got %d names and %v", names(), inDoubt())
+		}
+	})
+
+	var inDoubtTx uint64
+	t.Run("CommitFails", func(t *testing.T) {
+		var calls []string
+		txID, err := commit("carol", recordingHook(&calls, map[string]bool{"commit": true}))
+		if !errors.Is(err, ErrCommitInDoubt) {
+			t.Fatalf("Expected ErrCommitInDoubt, got %v", err)
+		}
+		if txID == 0 || names() != 2 {
+			t.Errorf("Expected carol committed as a transaction, got ID %d and %d names", txID, names())
+		}
+		if want := []InDoubtCommit{{Hook: "outbox", TxID: txID, Committed: true}}; !reflect.DeepEqual(inDoubt(), want) {
+			t.Errorf("Expected %v in doubt, got %v", want, inDoubt())
+		}
+		inDoubtTx = txID
+	})
+
+	// A crash between Prepare and writing the datoms leaves a prepared record
+	var calls []string
+	hook := recordingHook(&calls, nil)
+	if err := db.prepareCommit(&hook, inDoubtTx+1, nil, nil); err != nil {
+		t.Fatalf("prepareCommit failed: %v", err)
+	}
+	db.Close()
+
+	t.Run("Recover", func(t *testing.T) {
+		db, err = NewDatabaseWithTimeTx(dir)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		if len(inDoubt()) != 2 {
+			t.Fatalf("Expected 2 transactions in doubt after reopening, got %v", inDoubt())
+		}
+
+		other := hook
+		other.Name = "mailer"
+		if n, err := db.RecoverCommits(other); n != 0 || err == nil {
+			t.Errorf("Expected recovery with an unknown hook to fail, got %d resolved and %v", n, err)
+		}
+
+		calls = nil
+		n, err := db.RecoverCommits(hook)
+		if err != nil {
+			t.Fatalf("RecoverCommits failed: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("Expected 2 transactions resolved, got %d", n)
+		}
+		if want := []string{"commit", "abort"}; !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+		if len(inDoubt()) != 0 {
+			t.Errorf("Expected nothing in doubt after recovery, got %v", inDoubt())
+		}
+	})
+	db.Close()
+}
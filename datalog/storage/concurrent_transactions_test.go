@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// TestConcurrentTransactions commits independent transactions from many
+// goroutines while a reader checks that commits become visible whole and in
+// transaction ID order
+func TestConcurrentTransactions(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	const (
+		workers      = 8
+		txPerWorker  = 25
+		itemsPerTx   = 20
+		totalCommits = workers * txPerWorker
+	)
+
+	seq := datalog.NewKeyword(":item/seq")
+	worker := datalog.NewKeyword(":item/worker")
+
+	var (
+		mu    sync.Mutex
+		txIDs []uint64
+		wg    sync.WaitGroup
+		errs  = make(chan error, workers+1)
+		done  = make(chan struct{})
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < txPerWorker; i++ {
+				tx := db.NewTransaction()
+				for j := 0; j < itemsPerTx; j++ {
+					e := datalog.NewIdentity(fmt.Sprintf("item:%d:%d:%d", w, i, j))
+					tx.Add(e, seq, int64(j))
+					tx.Add(e, worker, int64(w))
+				}
+				txID, err := tx.Commit()
+				if err != nil {
+					errs <- fmt.Errorf("worker %d: commit failed: %w", w, err)
+					return
+				}
+				mu.Lock()
+				txIDs = append(txIDs, txID)
+				mu.Unlock()
+			}
+		}(w)
+	}
+
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			rows, err := db.ExecuteQuery(`[:find ?t ?tx :where [?t :db/txInstant _ ?tx]]`)
+			if err != nil {
+				errs <- fmt.Errorf("reader: %w", err)
+				return
+			}
+			visible := make([]uint64, 0, len(rows))
+			for _, row := range rows {
+				switch tx := row[1].(type) {
+				case uint64:
+					visible = append(visible, tx)
+				case *uint64:
+					visible = append(visible, *tx)
+				}
+			}
+			sort.Slice(visible, func(i, j int) bool { return visible[i] < visible[j] })
+			for i, tx := range visible {
+				if tx != uint64(i+1) {
+					errs <- fmt.Errorf("reader: transaction %d visible before %d", tx, i+1)
+					return
+				}
+			}
+
+			rows, err = db.ExecuteQuery(`[:find ?e :where [?e :item/seq _]]`)
+			if err != nil {
+				errs <- fmt.Errorf("reader: %w", err)
+				return
+			}
+			if len(rows)%itemsPerTx != 0 {
+				errs <- fmt.Errorf("reader: saw %d items, a partial commit", len(rows))
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	<-readerDone
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if len(txIDs) != totalCommits {
+		t.Fatalf("Expected %d commits, got %d", totalCommits, len(txIDs))
+	}
+	sort.Slice(txIDs, func(i, j int) bool { return txIDs[i] < txIDs[j] })
+	for i, txID := range txIDs {
+		if txID != uint64(i+1) {
+			t.Fatalf("Expected transaction IDs 1..%d, got %d at position %d", totalCommits, txID, i)
+		}
+	}
+
+	rows, err := db.ExecuteQuery(`[:find ?w (count ?e) :where [?e :item/worker ?w]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != workers {
+		t.Fatalf("Expected %d workers, got %d", workers, len(rows))
+	}
+	for _, row := range rows {
+		if row[1] != int64(txPerWorker*itemsPerTx) {
+			t.Errorf("Worker %v: expected %d items, got %v", row[0], txPerWorker*itemsPerTx, row[1])
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/wbrown/janus-datalog/datalog/edn"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+// ConfigEnv is the environment variable naming a config file for
+// NewDatabase to load
+const ConfigEnv = "JANUS_DATALOG_CONFIG"
+
+// ErrNoConfigFile is returned by ReloadConfig when no config file was loaded
+var ErrNoConfigFile = errors.New("no config file loaded")
+
+// Config holds the default options of the executors a database creates with
+// NewExecutor, which also runs ExecuteQuery and its variants. Executors
+// created with NewExecutorWithOptions are not affected.
+type Config struct {
+	Planner  planner.PlannerOptions
+	Executor executor.ExecutorOptions // Executor-only options such as Summation, and overrides of those derived from Planner
+}
+
+// DefaultConfig returns DefaultPlannerOptions and the executor options
+// derived from them
+func DefaultConfig() Config {
+	opts := DefaultPlannerOptions()
+	return Config{
+		Planner:  opts,
+		Executor: executor.ExecutorOptionsFor(opts),
+	}
+}
+
+// ReadConfig reads a config file, EDN unless its extension is .json. The
+// file holds a map with optional planner and executor sections naming the
+// fields of PlannerOptions and ExecutorOptions to change from DefaultConfig:
+//
+//	{:planner  {:enable-subquery-decorrelation false
+//	            :max-subquery-workers 4}
+//	 :executor {:summation :exact}}
+//
+// or in JSON
+//
+//	{"planner": {"EnableSubqueryDecorrelation": false, "MaxSubqueryWorkers": 4},
+//	 "executor": {"Summation": "exact"}}
+//
+// Names match fields ignoring case, dashes and underscores. Options such as
+// Summation are set by name. The executor options are derived from the
+// planner section before the executor section is applied.
+func ReadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var sections map[string]map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &sections); err != nil {
+			return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	} else {
+		if sections, err = ednConfigSections(string(data)); err != nil {
+			return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	config := DefaultConfig()
+	for name := range sections {
+		if name != "planner" && name != "executor" {
+			return Config{}, fmt.Errorf("invalid config %s: unknown section %q", path, name)
+		}
+	}
+	if err := setConfigFields(&config.Planner, sections["planner"]); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: planner: %w", path, err)
+	}
+	config.Executor = executor.ExecutorOptionsFor(config.Planner)
+	if err := setConfigFields(&config.Executor, sections["executor"]); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: executor: %w", path, err)
+	}
+	return config, nil
+}
+
+// ednConfigSections converts an EDN config to the form of a JSON one
+func ednConfigSections(data string) (map[string]map[string]interface{}, error) {
+	node, err := edn.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := ednMapFields(*node)
+	if err != nil {
+		return nil, err
+	}
+	sections := make(map[string]map[string]interface{}, len(fields))
+	for name, section := range fields {
+		name := strings.TrimPrefix(name, ":")
+		options, err := ednMapFields(section)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		values := make(map[string]interface{}, len(options))
+		for key, value := range options {
+			key := strings.TrimPrefix(key, ":")
+			switch value.Type {
+			case edn.NodeBool:
+				values[key], err = value.AsBool()
+			case edn.NodeInt:
+				values[key], err = value.AsInt()
+			case edn.NodeFloat:
+				values[key], err = value.AsFloat()
+			case edn.NodeString:
+				values[key], err = value.AsString()
+			case edn.NodeKeyword:
+				values[key] = strings.TrimPrefix(value.Value, ":")
+			default:
+				err = fmt.Errorf("unsupported value %s", value.String())
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", name, key, err)
+			}
+		}
+		sections[name] = values
+	}
+	return sections, nil
+}
+
+// setConfigFields sets the fields of the struct target points to from
+// values, matching names ignoring case, dashes and underscores
+func setConfigFields(target interface{}, values map[string]interface{}) error {
+	v := reflect.ValueOf(target).Elem()
+	fields := make(map[string]int, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		fields[configName(v.Type().Field(i).Name)] = i
+	}
+
+	for name, value := range values {
+		i, ok := fields[configName(name)]
+		if !ok {
+			return fmt.Errorf("unknown option %q", name)
+		}
+		if err := setConfigField(v.Field(i), value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setConfigField sets a bool, int or string option, or an int option by
+// the name its String method returns
+func setConfigField(field reflect.Value, value interface{}) error {
+	switch field.Kind() {
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected a boolean, got %v", value)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		switch n := value.(type) {
+		case int64:
+			field.SetInt(n)
+		case float64:
+			if n != float64(int64(n)) {
+				return fmt.Errorf("expected an integer, got %v", n)
+			}
+			field.SetInt(int64(n))
+		case string:
+			return setConfigEnum(field, n)
+		default:
+			return fmt.Errorf("expected an integer, got %v", value)
+		}
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %v", value)
+		}
+		field.SetString(s)
+	default:
+		return fmt.Errorf("cannot be configured")
+	}
+	return nil
+}
+
+// setConfigEnum sets an int option whose type has a String method, such as
+// executor.Summation, to the value with the given name
+func setConfigEnum(field reflect.Value, name string) error {
+	if _, ok := field.Interface().(fmt.Stringer); !ok {
+		if n, err := strconv.ParseInt(name, 10, 64); err == nil {
+			field.SetInt(n)
+			return nil
+		}
+		return fmt.Errorf("expected an integer, got %q", name)
+	}
+	var names []string
+	value := reflect.New(field.Type()).Elem()
+	for i := int64(0); i < 16; i++ {
+		value.SetInt(i)
+		s := value.Interface().(fmt.Stringer).String()
+		if s == name {
+			field.SetInt(i)
+			return nil
+		}
+		if s != "unknown" && !strings.Contains(s, "(") {
+			names = append(names, s)
+		}
+	}
+	return fmt.Errorf("unknown value %q (expected one of %s)", name, strings.Join(names, ", "))
+}
+
+// configName normalizes an option name for matching
+func configName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "-", "")
+	return strings.ReplaceAll(name, "_", "")
+}
+
+// Config returns the database's current default executor options
+func (d *Database) Config() Config {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	if d.config == nil {
+		return DefaultConfig()
+	}
+	return *d.config
+}
+
+// SetConfig replaces the database's default executor options. Executors
+// created afterwards, including those running each ExecuteQuery, use them;
+// executors already created keep theirs. Plans cached under the previous
+// options are not reused, as options are part of the plan cache key.
+func (d *Database) SetConfig(config Config) {
+	config.Planner.Cache = nil
+	d.configMu.Lock()
+	defer d.configMu.Unlock()
+	d.config = &config
+}
+
+// LoadConfig sets the database's default executor options from a config
+// file (see ReadConfig) and remembers the file for ReloadConfig
+func (d *Database) LoadConfig(path string) error {
+	config, err := ReadConfig(path)
+	if err != nil {
+		return err
+	}
+	config.Planner.Cache = nil
+	d.configMu.Lock()
+	defer d.configMu.Unlock()
+	d.config = &config
+	d.configPath = path
+	return nil
+}
+
+// ReloadConfig re-reads the config file last loaded with LoadConfig. If the
+// file can't be read or is invalid, the current options are kept and the
+// error returned.
+func (d *Database) ReloadConfig() error {
+	d.configMu.RLock()
+	path := d.configPath
+	d.configMu.RUnlock()
+	if path == "" {
+		return ErrNoConfigFile
+	}
+	return d.LoadConfig(path)
+}
+
+// ReloadConfigOnSignal calls ReloadConfig whenever the process receives one
+// of signals, SIGHUP if none are given, until stop is called. Reload errors
+// are passed to onError, if not nil.
+func (d *Database) ReloadConfigOnSignal(onError func(error), signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := d.ReloadConfig(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog/executor"
+)
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[string]string{
+		"config.edn": `{:planner  {:enable-subquery-decorrelation false
+		                           :max-subquery-workers 4}
+		                :executor {:summation :exact
+		                           :enable-column-statistics true}}`,
+		"config.json": `{"planner": {"EnableSubqueryDecorrelation": false, "MaxSubqueryWorkers": 4},
+		                 "executor": {"Summation": "exact", "enable_column_statistics": true}}`,
+	}
+	for name, data := range configs {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := ReadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: ReadConfig failed: %v", name, err)
+		}
+		if config.Planner.EnableSubqueryDecorrelation || config.Planner.MaxSubqueryWorkers != 4 {
+			t.Errorf("%s: expected the planner section applied, got %+v", name, config.Planner)
+		}
+		if !config.Planner.EnableParallelSubqueries {
+			t.Errorf("%s: expected unset options to keep their defaults", name)
+		}
+		// Executor options derive from the planner section, then the executor section
+		if config.Executor.EnableSubqueryDecorrelation || config.Executor.MaxSubqueryWorkers != 4 {
+			t.Errorf("%s: expected executor options derived from the planner section, got %+v", name, config.Executor)
+		}
+		if config.Executor.Summation != executor.SummationExact || !config.Executor.EnableColumnStatistics {
+			t.Errorf("%s: expected the executor section applied, got %+v", name, config.Executor)
+		}
+	}
+
+	invalid := map[string]string{
+		"unknown option":  `{:planner {:enable-warp-drive true}}`,
+		"unknown section": `{:optimizer {}}`,
+		"wrong type":      `{:planner {:max-subquery-workers "four"}}`,
+		"unknown value":   `{:executor {:summation :approximate}}`,
+		"not settable":    `{:planner {:cache 1}}`,
+	}
+	for name, data := range invalid {
+		path := filepath.Join(dir, "invalid.edn")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadConfig(path); err == nil {
+			t.Errorf("%s: expected ReadConfig to fail", name)
+		}
+	}
+}
+
+func TestDatabaseConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "janus.edn")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{:planner {:enable-parallel-subqueries false}}`)
+
+	t.Setenv(ConfigEnv, path)
+	db, err := NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if db.NewExecutor().Options().EnableParallelSubqueries {
+		t.Error("Expected NewDatabase to load the config named by the environment")
+	}
+
+	// A reload applies to executors created afterwards
+	write(`{:planner {:enable-parallel-subqueries true} :executor {:summation :fast}}`)
+	if err := db.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	opts := db.NewExecutor().Options()
+	if !opts.EnableParallelSubqueries || opts.Summation != executor.SummationFast {
+		t.Errorf("Expected the reloaded options, got %+v", opts)
+	}
+
+	// An invalid file keeps the current options
+	write(`{:planner {:enable-parallel-subqueries`)
+	if err := db.ReloadConfig(); err == nil {
+		t.Error("Expected reloading an invalid config to fail")
+	}
+	if db.Config().Executor.Summation != executor.SummationFast {
+		t.Error("Expected a failed reload to keep the current options")
+	}
+
+	// Queries keep working under the new options
+	if _, err := db.ExecuteQuery(`[:find ?e :where [?e :person/name _]]`); err != nil {
+		t.Errorf("Query failed: %v", err)
+	}
+
+	// SIGHUP triggers a reload
+	write(`{:executor {:summation :exact}}`)
+	errs := make(chan error, 1)
+	stop := db.ReloadConfigOnSignal(func(err error) { errs <- err })
+	defer stop()
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("Cannot send SIGHUP: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for db.Config().Executor.Summation != executor.SummationExact {
+		select {
+		case err := <-errs:
+			t.Fatalf("Reload failed: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected SIGHUP to reload the config")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadConfigWithoutFile(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.ReloadConfig(); !errors.Is(err, ErrNoConfigFile) {
+		t.Errorf("Expected ErrNoConfigFile, got %v", err)
+	}
+
+	config := db.Config()
+	config.Planner.EnableOrderedScan = false
+	db.SetConfig(config)
+	if db.Config().Planner.EnableOrderedScan {
+		t.Error("Expected SetConfig to change the options")
+	}
+	if !strings.Contains(DefaultConfig().Executor.Summation.String(), "compensated") {
+		t.Error("Expected SetConfig not to change the defaults")
+	}
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -19,12 +21,34 @@ type Database struct {
 	store     *BadgerStore
 	txCounter atomic.Uint64
 	mu        sync.RWMutex
+	commitMu  sync.Mutex // Serializes commits so they apply in transaction ID order
 	activeTx  map[*Transaction]bool
-	useTimeTx bool               // Use time-based transaction IDs
-	planCache *planner.PlanCache // Shared query plan cache
+	useTimeTx bool                   // Use time-based transaction IDs
+	clock     hybridClock            // Issues time-based transaction IDs; guarded by commitMu
+	planCache *planner.PlanCache     // Shared query plan cache
+	queries   *executor.QueryTracker // Queries executing on the database's executors
+	keywords  KeywordNormalizer      // Applied to attributes on write and query (nil = as written); guarded by keywordsMu
+	limits    ValueLimits            // Value size limits for asserted datoms
+	stats     *dbStatistics          // Planner statistics, maintained on commit
+	ops       activity               // Commits, imports and exports in progress, for Shutdown
+
+	configMu   sync.RWMutex
+	config     *Config // Default executor options (nil = DefaultConfig)
+	configPath string  // File the config was loaded from, for ReloadConfig
+
+	capture atomic.Pointer[QueryCapture] // Records executed queries for Replay (nil = off)
+
+	entitySeq atomic.Uint64 // Distinguishes entity IDs created in the same nanosecond
+
+	keywordsMu sync.RWMutex
+
+	hooksMu    sync.RWMutex
+	writeHooks map[datalog.Keyword][]WriteHook // Replaced, never modified, by AddWriteHook
 }
 
-// NewDatabase creates a new database with BadgerDB storage
+// NewDatabase creates a new database with BadgerDB storage. If the
+// JANUS_DATALOG_CONFIG environment variable names a config file, its
+// executor defaults are loaded (see LoadConfig).
 func NewDatabase(path string) (*Database, error) {
 	// Use Binary encoding explicitly (matches BadgerStore default)
 	store, err := NewBadgerStore(path, NewKeyEncoder(BinaryStrategy))
@@ -32,23 +56,54 @@ func NewDatabase(path string) (*Database, error) {
 		return nil, fmt.Errorf("failed to create store: %w", err)
 	}
 
-	return &Database{
+	db := &Database{
 		store:     store,
 		activeTx:  make(map[*Transaction]bool),
 		planCache: planner.NewPlanCache(1000, 0), // 1000 plans, default TTL
-	}, nil
+		queries:   executor.NewQueryTracker(),
+		limits:    DefaultValueLimits(),
+		stats:     newDBStatistics(),
+	}
+	if err := db.stats.load(store); err != nil {
+		store.Close()
+		return nil, err
+	}
+	if path := os.Getenv(ConfigEnv); path != "" {
+		if err := db.LoadConfig(path); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	return db, nil
 }
 
-// NewDatabaseWithTimeTx creates a database that uses time-based transaction IDs
+// NewDatabaseWithTimeTx creates a database that uses time-based transaction
+// IDs. IDs are hybrid logical clock timestamps (see TxTime): they track the
+// commit time but keep increasing even if the wall clock steps back, including
+// across restarts, as the clock resumes from the latest stored transaction.
 func NewDatabaseWithTimeTx(path string) (*Database, error) {
 	db, err := NewDatabase(path)
 	if err != nil {
 		return nil, err
 	}
 	db.useTimeTx = true
+
+	latest, ok, err := db.store.latestTxID(0)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if ok {
+		db.clock.observe(latest)
+	}
 	return db, nil
 }
 
+// newEntityID returns a new entity ID for AddMap
+func (d *Database) newEntityID() datalog.Identity {
+	return datalog.NewIdentity(fmt.Sprintf("e%d-%d", time.Now().UnixNano(), d.entitySeq.Add(1)))
+}
+
 // NewTransaction starts a new write transaction
 func (d *Database) NewTransaction() *Transaction {
 	d.mu.Lock()
@@ -74,40 +129,18 @@ func (d *Database) NewTransactionAt(t time.Time) *Transaction {
 
 // Matcher returns a PatternMatcher for the current database state
 func (d *Database) Matcher() executor.PatternMatcher {
-	// Convert default planner options to executor options
-	opts := DefaultPlannerOptions()
-	execOpts := executor.ExecutorOptions{
-		EnableIteratorComposition:       opts.EnableIteratorComposition,
-		EnableTrueStreaming:             opts.EnableTrueStreaming,
-		EnableSymmetricHashJoin:         opts.EnableSymmetricHashJoin,
-		EnableParallelSubqueries:        opts.EnableParallelSubqueries,
-		MaxSubqueryWorkers:              opts.MaxSubqueryWorkers,
-		EnableStreamingJoins:            opts.EnableStreamingJoins,
-		EnableStreamingAggregation:      opts.EnableStreamingAggregation,
-		EnableStreamingAggregationDebug: opts.EnableStreamingAggregationDebug,
-		EnableDebugLogging:              opts.EnableDebugLogging,
-		IndexNestedLoopThreshold:        opts.IndexNestedLoopThreshold,
-	}
-	return NewBadgerMatcherWithOptions(d.store, execOpts)
+	// Convert the default planner options to executor options
+	matcher := NewBadgerMatcherWithOptions(d.store, matcherOptions(d.Config().Planner))
+	matcher.keywords = d.keywordNormalizer()
+	return matcher
 }
 
 // AsOf returns a PatternMatcher for a specific transaction
 func (d *Database) AsOf(txID uint64) executor.PatternMatcher {
-	// Convert default planner options to executor options
-	opts := DefaultPlannerOptions()
-	execOpts := executor.ExecutorOptions{
-		EnableIteratorComposition:       opts.EnableIteratorComposition,
-		EnableTrueStreaming:             opts.EnableTrueStreaming,
-		EnableSymmetricHashJoin:         opts.EnableSymmetricHashJoin,
-		EnableParallelSubqueries:        opts.EnableParallelSubqueries,
-		MaxSubqueryWorkers:              opts.MaxSubqueryWorkers,
-		EnableStreamingJoins:            opts.EnableStreamingJoins,
-		EnableStreamingAggregation:      opts.EnableStreamingAggregation,
-		EnableStreamingAggregationDebug: opts.EnableStreamingAggregationDebug,
-		EnableDebugLogging:              opts.EnableDebugLogging,
-		IndexNestedLoopThreshold:        opts.IndexNestedLoopThreshold,
-	}
-	return NewBadgerMatcherWithOptions(d.store, execOpts).AsOf(txID)
+	// Convert the default planner options to executor options
+	matcher := NewBadgerMatcherWithOptions(d.store, matcherOptions(d.Config().Planner))
+	matcher.keywords = d.keywordNormalizer()
+	return matcher.AsOf(txID)
 }
 
 // DefaultPlannerOptions returns the default planner and executor options for the database
@@ -138,24 +171,26 @@ func DefaultPlannerOptions() planner.PlannerOptions {
 		// Storage join strategy
 		IndexNestedLoopThreshold: 0, // Default to HashJoinScan for all binding sizes
 
+		// Ordered scans
+		EnableOrderedScan: true, // :order-by with :limit via AVET scans, stopping early
+
+		// Latest value per entity via one ordered scan instead of a subquery per group
+		EnableLatestPerEntity: true,
+
+		// [(= ?v "c")] folded into the pattern binding ?v, so it scans AVET
+		EnableEqualityConstantRewriting: true,
+
+		// [?e ?a ?v] [(in ?a #{...})] matched per attribute instead of scanning everything
+		EnableAttributeSetExpansion: true,
+
 		// Executor architecture (Stage B)
 		UseQueryExecutor: true, // Use new QueryExecutor by default (production-ready as of October 2025)
 	}
 }
 
-// NewExecutor creates a new query executor that uses the database's plan cache
-func (d *Database) NewExecutor() *executor.Executor {
-	opts := DefaultPlannerOptions()
-	opts.Cache = d.planCache // Use database's cache
-	return executor.NewExecutorWithOptions(d.Matcher(), opts)
-}
-
-// NewExecutorWithOptions creates a new query executor with custom options and the database's plan cache
-func (d *Database) NewExecutorWithOptions(opts planner.PlannerOptions) *executor.Executor {
-	// Override cache with database's cache
-	opts.Cache = d.planCache
-	// Create matcher with custom options
-	execOpts := executor.ExecutorOptions{
+// matcherOptions returns the executor options storage matchers use for opts
+func matcherOptions(opts planner.PlannerOptions) executor.ExecutorOptions {
+	return executor.ExecutorOptions{
 		EnableIteratorComposition:       opts.EnableIteratorComposition,
 		EnableTrueStreaming:             opts.EnableTrueStreaming,
 		EnableSymmetricHashJoin:         opts.EnableSymmetricHashJoin,
@@ -167,8 +202,39 @@ func (d *Database) NewExecutorWithOptions(opts planner.PlannerOptions) *executor
 		EnableDebugLogging:              opts.EnableDebugLogging,
 		IndexNestedLoopThreshold:        opts.IndexNestedLoopThreshold,
 	}
-	matcher := NewBadgerMatcherWithOptions(d.store, execOpts)
-	return executor.NewExecutorWithOptions(matcher, opts)
+}
+
+// NewExecutor creates a new query executor that uses the database's plan
+// cache and its configured default options (see Config)
+func (d *Database) NewExecutor() *executor.Executor {
+	return d.newExecutor(d.Config())
+}
+
+// newExecutor creates a query executor with config's options that uses the
+// database's plan cache
+func (d *Database) newExecutor(config Config) *executor.Executor {
+	opts := config.Planner
+	opts.Cache = d.planCache // Use database's cache
+	matcher := NewBadgerMatcherWithOptions(d.store, matcherOptions(opts))
+	matcher.keywords = d.keywordNormalizer()
+	exec := executor.NewExecutorWithOptions(matcher, opts)
+	exec.SetOptions(config.Executor)
+	exec.SetQueryTracker(d.queries)
+	exec.SetStatisticsProvider(d)
+	return exec
+}
+
+// NewExecutorWithOptions creates a new query executor with custom options and the database's plan cache
+func (d *Database) NewExecutorWithOptions(opts planner.PlannerOptions) *executor.Executor {
+	// Override cache with database's cache
+	opts.Cache = d.planCache
+	// Create matcher with custom options
+	matcher := NewBadgerMatcherWithOptions(d.store, matcherOptions(opts))
+	matcher.keywords = d.keywordNormalizer()
+	exec := executor.NewExecutorWithOptions(matcher, opts)
+	exec.SetQueryTracker(d.queries)
+	exec.SetStatisticsProvider(d)
+	return exec
 }
 
 // Store returns the underlying store for direct access (debugging/testing)
@@ -176,13 +242,22 @@ func (d *Database) Store() *BadgerStore {
 	return d.store
 }
 
-// Close closes the database
+// Close closes the database. It stops accepting queries and waits for those
+// still executing, including ones abandoned after their :timeout, which stop
+// at their next clause or phase, as closing the store would pull it from
+// under their scans.
 func (d *Database) Close() error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	d.queries.Close()
+	d.queries.Wait(context.Background())
 
-	// Rollback any active transactions
+	// Rollback any active transactions; Rollback takes d.mu itself
+	d.mu.Lock()
+	active := make([]*Transaction, 0, len(d.activeTx))
 	for tx := range d.activeTx {
+		active = append(active, tx)
+	}
+	d.mu.Unlock()
+	for _, tx := range active {
 		tx.Rollback()
 	}
 
@@ -199,6 +274,18 @@ func (d *Database) SetPlanCache(cache *planner.PlanCache) {
 	d.planCache = cache
 }
 
+// ActiveQueries returns the queries executing on the database's executors,
+// oldest first
+func (d *Database) ActiveQueries() []executor.ActiveQuery {
+	return d.queries.Active()
+}
+
+// CancelQuery cancels a query executing on one of the database's executors
+// (see executor.Executor.CancelQuery)
+func (d *Database) CancelQuery(id uint64) error {
+	return d.queries.Cancel(id)
+}
+
 // ClearPlanCache clears the query plan cache
 func (d *Database) ClearPlanCache() {
 	if d.planCache != nil {
@@ -258,6 +345,7 @@ func (d *Database) ExecuteQueryWithInputs(queryStr string, inputs ...interface{}
 	}
 
 	// Execute the query
+	start := time.Now()
 	exec := d.NewExecutor()
 	result, err := exec.ExecuteWithRelations(executor.NewContext(nil), q, inputRelations)
 	if err != nil {
@@ -265,7 +353,47 @@ func (d *Database) ExecuteQueryWithInputs(queryStr string, inputs ...interface{}
 	}
 
 	// Convert result to [][]interface{}
-	return relationToSlice(result), nil
+	rows, err := relationToSlice(result)
+	if err != nil {
+		return nil, err
+	}
+	d.captureQuery(queryStr, inputRelations, len(rows), start)
+	return rows, nil
+}
+
+// ExecuteQueryWithMetadata executes a parameterized Datalog query like
+// ExecuteQueryWithInputs and also returns how the result was produced: the
+// basis transaction, wall time, datoms scanned, whether the plan was cached
+// and the number of phases.
+//
+// Example:
+//
+//	results, md, err := db.ExecuteQueryWithMetadata(`[:find ?e :in $ ?name :where [?e :person/name ?name]]`, "Alice")
+//	log.Printf("%d results (%s)", len(results), md)
+func (d *Database) ExecuteQueryWithMetadata(queryStr string, inputs ...interface{}) ([][]interface{}, *executor.ResultMetadata, error) {
+	q, err := parser.ParseQuery(queryStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	inputRelations, err := d.convertInputsToRelations(q, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	exec := d.NewExecutor()
+	result, metadata, err := exec.ExecuteWithMetadata(executor.NewContext(nil), q, inputRelations)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query execution failed: %w", err)
+	}
+
+	rows, err := relationToSlice(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	d.captureQuery(queryStr, inputRelations, len(rows), start)
+	return rows, metadata, nil
 }
 
 // GetExecutor returns a new query executor
@@ -274,16 +402,25 @@ func (d *Database) GetExecutor() *executor.Executor {
 	return d.NewExecutor()
 }
 
-// Transaction represents a write transaction
+// Transaction represents a write transaction.
+//
+// Any number of transactions may be open at once and used from different
+// goroutines; each collects its own datoms and commits independently, with
+// no locking needed by the caller. A single Transaction is also safe for
+// concurrent use, though it is usually owned by one goroutine.
 type Transaction struct {
 	db       *Database
 	datoms   []datalog.Datom
 	retracts []datalog.Datom
 	mu       sync.Mutex
 	closed   bool
-	txTime   *time.Time // Optional custom transaction time
+	txTime   *time.Time  // Optional custom transaction time
+	hook     *CommitHook // Optional two-phase commit hook
 }
 
+// Ensure Transaction can receive relations stored with executor.StoreRelation
+var _ executor.FactWriter = (*Transaction)(nil)
+
 // SetTime sets a custom transaction time for this transaction
 // This is useful for backdated data (e.g., historical prices)
 func (t *Transaction) SetTime(txTime time.Time) {
@@ -292,8 +429,21 @@ func (t *Transaction) SetTime(txTime time.Time) {
 	t.txTime = &txTime
 }
 
-// Add asserts a new datom
-func (t *Transaction) Add(e datalog.Identity, a datalog.Keyword, v interface{}) error {
+// Add asserts a new datom. The entity may be given as a lookup ref, which is
+// resolved against the committed database.
+func (t *Transaction) Add(e datalog.EntityRef, a datalog.Keyword, v interface{}) error {
+	id, err := t.db.ResolveEntity(e)
+	if err != nil {
+		return err
+	}
+	a, err = t.db.normalizeKeyword(a)
+	if err != nil {
+		return err
+	}
+	if err := t.db.checkValueSize(a, v); err != nil {
+		return err
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -302,7 +452,7 @@ func (t *Transaction) Add(e datalog.Identity, a datalog.Keyword, v interface{})
 	}
 
 	t.datoms = append(t.datoms, datalog.Datom{
-		E:  e,
+		E:  id,
 		A:  a,
 		V:  v,
 		Tx: 0, // Will be set on commit
@@ -311,8 +461,18 @@ func (t *Transaction) Add(e datalog.Identity, a datalog.Keyword, v interface{})
 	return nil
 }
 
-// Retract removes a datom
-func (t *Transaction) Retract(e datalog.Identity, a datalog.Keyword, v interface{}) error {
+// Retract removes a datom. The entity may be given as a lookup ref, which is
+// resolved against the committed database.
+func (t *Transaction) Retract(e datalog.EntityRef, a datalog.Keyword, v interface{}) error {
+	id, err := t.db.ResolveEntity(e)
+	if err != nil {
+		return err
+	}
+	a, err = t.db.normalizeKeyword(a)
+	if err != nil {
+		return err
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -321,7 +481,7 @@ func (t *Transaction) Retract(e datalog.Identity, a datalog.Keyword, v interface
 	}
 
 	t.retracts = append(t.retracts, datalog.Datom{
-		E:  e,
+		E:  id,
 		A:  a,
 		V:  v,
 		Tx: 0, // Will be set on commit
@@ -331,34 +491,106 @@ func (t *Transaction) Retract(e datalog.Identity, a datalog.Keyword, v interface
 }
 
 // AddEntity adds all datoms for an entity map
-func (t *Transaction) AddEntity(e datalog.Identity, attrs map[datalog.Keyword]interface{}) error {
+func (t *Transaction) AddEntity(e datalog.EntityRef, attrs map[datalog.Keyword]interface{}) error {
+	id, err := t.db.ResolveEntity(e)
+	if err != nil {
+		return err
+	}
 	for attr, value := range attrs {
-		if err := t.Add(e, attr, value); err != nil {
+		if err := t.Add(id, attr, value); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// AddMap is a convenience method that creates an entity ID and adds the attributes
+// AddMap is a convenience method that creates an entity ID and adds the
+// attributes. Nested maps and vectors of maps are added as component
+// entities, see AddMapAll; the root entity's ID is returned.
 func (t *Transaction) AddMap(attrs map[string]interface{}) (datalog.Identity, error) {
-	// Generate entity ID
-	e := datalog.NewIdentity(fmt.Sprintf("e%d", time.Now().UnixNano()))
-
-	// Convert string keys to keywords and add
-	kwAttrs := make(map[datalog.Keyword]interface{})
-	for k, v := range attrs {
-		kwAttrs[datalog.NewKeyword(k)] = v
+	created, err := t.AddMapAll(attrs)
+	if err != nil {
+		return datalog.Identity{}, err
 	}
+	return created[0], nil
+}
 
-	if err := t.AddEntity(e, kwAttrs); err != nil {
-		return datalog.Identity{}, err
+// AddMapAll is AddMap for object graphs. An attribute whose value is a map
+// creates a child entity from that map and refers to it; a vector of maps
+// ([]map[string]interface{}, or []interface{} holding maps) creates one
+// child per map and adds a ref to each, as a cardinality-many attribute.
+// Other elements of a []interface{} are added as values of the attribute.
+// Children are created before the datoms that refer to them, to any depth.
+//
+// The IDs of all created entities are returned, the root entity's first.
+func (t *Transaction) AddMapAll(attrs map[string]interface{}) ([]datalog.Identity, error) {
+	var created []datalog.Identity
+	if _, err := t.addMap(attrs, &created); err != nil {
+		return nil, err
 	}
+	return created, nil
+}
 
+// addMap creates an entity from attrs and its nested maps, appending the IDs
+// of the entity and its children to created
+func (t *Transaction) addMap(attrs map[string]interface{}, created *[]datalog.Identity) (datalog.Identity, error) {
+	e := t.db.newEntityID()
+	*created = append(*created, e)
+
+	for k, v := range attrs {
+		attr := datalog.NewKeyword(k)
+		switch v := v.(type) {
+		case map[string]interface{}:
+			child, err := t.addMap(v, created)
+			if err != nil {
+				return datalog.Identity{}, fmt.Errorf("%s: %w", k, err)
+			}
+			if err := t.Add(e, attr, child); err != nil {
+				return datalog.Identity{}, err
+			}
+		case []map[string]interface{}:
+			for _, m := range v {
+				child, err := t.addMap(m, created)
+				if err != nil {
+					return datalog.Identity{}, fmt.Errorf("%s: %w", k, err)
+				}
+				if err := t.Add(e, attr, child); err != nil {
+					return datalog.Identity{}, err
+				}
+			}
+		case []interface{}:
+			for _, elem := range v {
+				if m, ok := elem.(map[string]interface{}); ok {
+					child, err := t.addMap(m, created)
+					if err != nil {
+						return datalog.Identity{}, fmt.Errorf("%s: %w", k, err)
+					}
+					elem = child
+				}
+				if err := t.Add(e, attr, elem); err != nil {
+					return datalog.Identity{}, err
+				}
+			}
+		default:
+			if err := t.Add(e, attr, v); err != nil {
+				return datalog.Identity{}, err
+			}
+		}
+	}
 	return e, nil
 }
 
-// Commit commits the transaction
+// Commit commits the transaction.
+//
+// Commits from concurrent transactions are serialized: each is assigned its
+// transaction ID and written while holding the database's commit lock, so
+// transactions become visible in ID order. A commit's retractions, assertions
+// and transaction metadata are written in one storage transaction, so readers
+// never observe part of a commit.
+//
+// With a commit hook (see SetCommitHook), the hook's Prepare is called before
+// the datoms are written and its Commit after; if Commit fails, the
+// transaction ID is returned with an error wrapping ErrCommitInDoubt.
 func (t *Transaction) Commit() (uint64, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -366,6 +598,19 @@ func (t *Transaction) Commit() (uint64, error) {
 	if t.closed {
 		return 0, fmt.Errorf("transaction is closed")
 	}
+	if err := t.db.ops.begin(); err != nil {
+		return 0, err
+	}
+	defer t.db.ops.end()
+
+	t.db.commitMu.Lock()
+	defer t.db.commitMu.Unlock()
+
+	// Derive datoms before the transaction ID is taken, so a failing hook
+	// leaves no gap
+	if err := t.runWriteHooks(); err != nil {
+		return 0, err
+	}
 
 	// Get transaction ID (time-based or sequential)
 	var txID uint64
@@ -379,8 +624,12 @@ func (t *Transaction) Commit() (uint64, error) {
 	}
 
 	if t.db.useTimeTx {
-		// Use nanosecond timestamp as transaction ID
-		txID = uint64(txTime.UnixNano())
+		// Use a hybrid logical clock timestamp as transaction ID
+		var err error
+		txID, err = t.db.nextTimeTxID(txTime, t.txTime != nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to assign transaction ID: %w", err)
+		}
 	} else {
 		// Use sequential counter
 		txID = t.db.txCounter.Add(1)
@@ -394,21 +643,7 @@ func (t *Transaction) Commit() (uint64, error) {
 		t.retracts[i].Tx = txID
 	}
 
-	// Apply retractions first
-	if len(t.retracts) > 0 {
-		if err := t.db.store.Retract(t.retracts); err != nil {
-			return 0, fmt.Errorf("failed to retract datoms: %w", err)
-		}
-	}
-
-	// Then apply assertions
-	if len(t.datoms) > 0 {
-		if err := t.db.store.Assert(t.datoms); err != nil {
-			return 0, fmt.Errorf("failed to assert datoms: %w", err)
-		}
-	}
-
-	// Add transaction metadata
+	// Transaction metadata
 	txEntity := datalog.NewIdentity(fmt.Sprintf("tx:%d", txID))
 	txMetadata := []datalog.Datom{
 		{
@@ -418,9 +653,18 @@ func (t *Transaction) Commit() (uint64, error) {
 			Tx: txID,
 		},
 	}
-	if err := t.db.store.Assert(txMetadata); err != nil {
-		// Log but don't fail the transaction
-		fmt.Printf("Warning: failed to write transaction metadata: %v\n", err)
+
+	if t.hook != nil {
+		if err := t.db.prepareCommit(t.hook, txID, t.datoms, t.retracts); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := t.write(txID, txMetadata); err != nil {
+		if t.hook != nil {
+			t.db.abortCommit(t.hook, txID)
+		}
+		return 0, err
 	}
 
 	// Clean up
@@ -429,9 +673,61 @@ func (t *Transaction) Commit() (uint64, error) {
 	delete(t.db.activeTx, t)
 	t.db.mu.Unlock()
 
+	if t.hook != nil {
+		if err := t.db.finishCommit(t.hook, txID); err != nil {
+			return txID, err
+		}
+	}
+
 	return txID, nil
 }
 
+// write applies the transaction's datoms and metadata, and the resulting
+// statistics, in one storage transaction
+func (t *Transaction) write(txID uint64, txMetadata []datalog.Datom) error {
+	storeTx := t.db.store.beginTx()
+	delta := newStatsDelta()
+
+	// Apply retractions first, then assertions
+	removed, err := storeTx.retractFacts(t.retracts)
+	if err != nil {
+		storeTx.Rollback()
+		return fmt.Errorf("failed to retract datoms: %w", err)
+	}
+	for i := range removed {
+		delta.retract(&removed[i])
+	}
+	if err := storeTx.Assert(t.datoms); err != nil {
+		storeTx.Rollback()
+		return fmt.Errorf("failed to assert datoms: %w", err)
+	}
+	if err := storeTx.Assert(txMetadata); err != nil {
+		storeTx.Rollback()
+		return fmt.Errorf("failed to write transaction metadata: %w", err)
+	}
+	for _, datoms := range [][]datalog.Datom{t.datoms, txMetadata} {
+		for i := range datoms {
+			delta.assert(&datoms[i])
+		}
+	}
+	stats := t.db.stats.stage(delta)
+	if err := stats.write(storeTx.txn); err != nil {
+		storeTx.Rollback()
+		return err
+	}
+	if t.hook != nil {
+		if err := storeTx.markCommitted(t.hook.Name, txID); err != nil {
+			storeTx.Rollback()
+			return fmt.Errorf("failed to record commit for hook %s: %w", t.hook.Name, err)
+		}
+	}
+	if err := storeTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction %d: %w", txID, err)
+	}
+	t.db.stats.apply(stats)
+	return nil
+}
+
 // Rollback aborts the transaction
 func (t *Transaction) Rollback() error {
 	t.mu.Lock()
@@ -477,10 +773,15 @@ func (d *Database) convertInputsToRelations(q *query.Query, inputs []interface{}
 				return nil, fmt.Errorf("not enough inputs: expected input for %s (have %d inputs, need %d)", spec.Symbol, len(inputs), inputIdx+1)
 			}
 
+			value, err := d.resolveInput(inputs[inputIdx])
+			if err != nil {
+				return nil, fmt.Errorf("input %s: %w", spec.Symbol, err)
+			}
+
 			// Create single-value relation
 			rel := executor.NewMaterializedRelation(
 				[]query.Symbol{spec.Symbol},
-				[]executor.Tuple{{inputs[inputIdx]}},
+				[]executor.Tuple{{value}},
 			)
 			inputRelations = append(inputRelations, rel)
 			inputIdx++
@@ -498,7 +799,11 @@ func (d *Database) convertInputsToRelations(q *query.Query, inputs []interface{}
 
 			tuples := make([]executor.Tuple, slice.Len())
 			for i := 0; i < slice.Len(); i++ {
-				tuples[i] = executor.Tuple{slice.Index(i).Interface()}
+				value, err := d.resolveInput(slice.Index(i).Interface())
+				if err != nil {
+					return nil, fmt.Errorf("input %s: %w", spec.Symbol, err)
+				}
+				tuples[i] = executor.Tuple{value}
 			}
 
 			rel := executor.NewMaterializedRelation(
@@ -526,7 +831,11 @@ func (d *Database) convertInputsToRelations(q *query.Query, inputs []interface{}
 			// Create single tuple
 			tuple := make(executor.Tuple, slice.Len())
 			for i := 0; i < slice.Len(); i++ {
-				tuple[i] = slice.Index(i).Interface()
+				value, err := d.resolveInput(slice.Index(i).Interface())
+				if err != nil {
+					return nil, fmt.Errorf("input %s: %w", spec.Symbols[i], err)
+				}
+				tuple[i] = value
 			}
 
 			rel := executor.NewMaterializedRelation(spec.Symbols, []executor.Tuple{tuple})
@@ -557,7 +866,11 @@ func (d *Database) convertInputsToRelations(q *query.Query, inputs []interface{}
 
 				tuple := make(executor.Tuple, innerSlice.Len())
 				for j := 0; j < innerSlice.Len(); j++ {
-					tuple[j] = innerSlice.Index(j).Interface()
+					value, err := d.resolveInput(innerSlice.Index(j).Interface())
+					if err != nil {
+						return nil, fmt.Errorf("input %s: %w", spec.Symbols[j], err)
+					}
+					tuple[j] = value
 				}
 				tuples[i] = tuple
 			}
@@ -576,8 +889,9 @@ func (d *Database) convertInputsToRelations(q *query.Query, inputs []interface{}
 	return inputRelations, nil
 }
 
-// relationToSlice converts an executor.Relation to [][]interface{}
-func relationToSlice(rel executor.Relation) [][]interface{} {
+// relationToSlice converts an executor.Relation to [][]interface{}, failing if
+// iterating the relation fails (e.g. a storage read error)
+func relationToSlice(rel executor.Relation) ([][]interface{}, error) {
 	// Don't preallocate if size is unknown (-1)
 	size := rel.Size()
 	var rows [][]interface{}
@@ -598,6 +912,9 @@ func relationToSlice(rel executor.Relation) [][]interface{} {
 		}
 		rows = append(rows, row)
 	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("reading query result failed: %w", err)
+	}
 
-	return rows
+	return rows, nil
 }
@@ -55,7 +55,7 @@ func DatomFromKey(index IndexType, key []byte, encoder KeyEncoder) (*datalog.Dat
 		}
 	}
 
-	v, err := datalog.ValueFromBytes(vType, vData)
+	v, err := decodeStoredValue(byte(vType), vData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode value: %w", err)
 	}
@@ -102,23 +102,28 @@ type KeyOnlyIterator struct {
 
 // NewKeyOnlyIterator creates an iterator that decodes datoms from keys
 func NewKeyOnlyIterator(store *BadgerStore, index IndexType, start, end []byte) (Iterator, error) {
-	txn := store.db.NewTransaction(false)
+	return newKeyOnlyIterator(store, index, start, end, false)
+}
 
+// newKeyOnlyIterator creates a key-only iterator over [start, end), scanning
+// from end to start if reverse is set
+func newKeyOnlyIterator(store *BadgerStore, index IndexType, start, end []byte, reverse bool) (Iterator, error) {
 	opts := badger.DefaultIteratorOptions
 	opts.PrefetchSize = 10000   // Much higher for key-only
 	opts.PrefetchValues = false // Don't fetch values!
+	opts.Reverse = reverse
 
-	it := txn.NewIterator(opts)
+	iter, err := store.openIterator(opts)
+	if err != nil {
+		return nil, err
+	}
+	iter.start = start
+	iter.end = end
+	iter.index = index
 
 	return &KeyOnlyIterator{
-		BadgerIterator: &BadgerIterator{
-			txn:   txn,
-			it:    it,
-			start: start,
-			end:   end,
-			index: index,
-		},
-		encoder: store.encoder,
+		BadgerIterator: iter,
+		encoder:        store.encoder,
 	}, nil
 }
 
@@ -137,7 +142,7 @@ func (i *KeyOnlyIterator) Next() bool {
 	// Decode datom from key
 	key := i.it.Item().Key()
 
-	i.currentDatom, i.currentError = DatomFromKey(i.index, key, i.encoder)
+	i.currentDatom, i.currentError = datomFromKey(i.txn, i.index, key, i.encoder)
 
 	if i.currentError != nil {
 		return false
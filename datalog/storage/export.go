@@ -0,0 +1,391 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/edn"
+)
+
+// ManifestFile is the name of the manifest Export writes next to its shards
+const ManifestFile = "manifest.edn"
+
+// shardMagic starts every shard file
+const shardMagic = "JDS1"
+
+// ExportOptions configures Export
+type ExportOptions struct {
+	// Shards splits the datoms by entity hash into this many files, so that
+	// every datom of an entity is in the same shard and Import can load the
+	// shards concurrently (<= 1 writes a single shard)
+	Shards int
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	Workers int // Shards loaded concurrently (0 = runtime.NumCPU())
+}
+
+// ExportManifest describes an export: its shards and their datom counts
+type ExportManifest struct {
+	Datoms int64
+	Shards []ExportShard
+}
+
+// ExportShard is one file of an export
+type ExportShard struct {
+	File   string // Relative to the export directory
+	Datoms int64
+}
+
+// Export writes a consistent snapshot of the database's datoms, including
+// transaction metadata, to dir: one file per shard and a manifest. The
+// directory is created if needed.
+//
+// Shard files are a sequence of length-prefixed records, one per datom, in
+// entity order. Import loads them into another database.
+func (d *Database) Export(dir string, opts ExportOptions) (*ExportManifest, error) {
+	if err := d.ops.begin(); err != nil {
+		return nil, err
+	}
+	defer d.ops.end()
+
+	shards := opts.Shards
+	if shards < 1 {
+		shards = 1
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	manifest := &ExportManifest{Shards: make([]ExportShard, shards)}
+	files := make([]*os.File, shards)
+	writers := make([]*bufio.Writer, shards)
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+	for i := range files {
+		manifest.Shards[i].File = fmt.Sprintf("shard-%03d.datoms", i)
+		f, err := os.Create(filepath.Join(dir, manifest.Shards[i].File))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create shard: %w", err)
+		}
+		files[i] = f
+		writers[i] = bufio.NewWriter(f)
+		writers[i].WriteString(shardMagic)
+	}
+
+	s := d.store
+	start, end := s.encoder.EncodePrefixRange(EAVT)
+	var record []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(start); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			if string(key) >= string(end) {
+				break
+			}
+			datom, err := datomFromKey(txn, EAVT, key, s.encoder)
+			if err != nil {
+				return fmt.Errorf("failed to decode EAVT key: %w", err)
+			}
+
+			hash := datom.E.Hash()
+			shard := binary.BigEndian.Uint64(hash[:8]) % uint64(shards)
+			record = appendDatomRecord(record[:0], datom)
+			if _, err := writers[shard].Write(record); err != nil {
+				return fmt.Errorf("failed to write shard: %w", err)
+			}
+			manifest.Shards[shard].Datoms++
+			manifest.Datoms++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export failed: %w", err)
+	}
+
+	for i, w := range writers {
+		if err := w.Flush(); err != nil {
+			return nil, fmt.Errorf("failed to write shard: %w", err)
+		}
+		if err := files[i].Close(); err != nil {
+			return nil, fmt.Errorf("failed to write shard: %w", err)
+		}
+		files[i] = nil
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest.String()), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Import loads an export written by Export into the database, loading its
+// shards concurrently. Imported datoms keep their transaction IDs, and later
+// commits are assigned IDs after them.
+func (d *Database) Import(dir string, opts ImportOptions) (*ExportManifest, error) {
+	if err := d.ops.begin(); err != nil {
+		return nil, err
+	}
+	defer d.ops.end()
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	shards := make(chan ExportShard)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		delta = newStatsDelta()
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				shardDelta, err := d.importShard(filepath.Join(dir, shard.File), shard.Datoms)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("shard %s: %w", shard.File, err))
+				} else {
+					delta.merge(shardDelta)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, shard := range manifest.Shards {
+		shards <- shard
+	}
+	close(shards)
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("import failed: %w", err)
+	}
+
+	// Continue transaction IDs after the imported ones
+	d.commitMu.Lock()
+	defer d.commitMu.Unlock()
+	stats := d.stats.stage(delta)
+	if err := d.store.db.Update(func(txn *badger.Txn) error { return stats.write(txn) }); err != nil {
+		return nil, fmt.Errorf("import failed: %w", err)
+	}
+	d.stats.apply(stats)
+	latest, ok, err := d.store.latestTxID(0)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if d.useTimeTx {
+			d.clock.observe(latest)
+		} else if latest > d.txCounter.Load() {
+			d.txCounter.Store(latest)
+		}
+	}
+	return manifest, nil
+}
+
+// importShard writes the datoms of one shard file to every index, returning
+// the changes to the statistics
+func (d *Database) importShard(path string, datoms int64) (*statsDelta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(shardMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != shardMagic {
+		return nil, fmt.Errorf("not a shard file")
+	}
+
+	wb := d.store.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	delta := newStatsDelta()
+	var count int64
+	var record []byte
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("truncated record %d: %w", count, err)
+		}
+		if n := int(binary.BigEndian.Uint32(size[:])); cap(record) < n {
+			record = make([]byte, n)
+		} else {
+			record = record[:n]
+		}
+		if _, err := io.ReadFull(r, record); err != nil {
+			return nil, fmt.Errorf("truncated record %d: %w", count, err)
+		}
+
+		datom, err := decodeDatomRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", count, err)
+		}
+		if err := d.store.assertDatom(wb, &datom); err != nil {
+			return nil, err
+		}
+		delta.assert(&datom)
+		count++
+	}
+	if count != datoms {
+		return nil, fmt.Errorf("expected %d datoms, read %d", datoms, count)
+	}
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return delta, nil
+}
+
+// appendDatomRecord appends a datom's record:
+// Size(4) + E(20) + Tx(8) + ASize(2) + A + VType(1) + V
+func appendDatomRecord(buf []byte, d *datalog.Datom) []byte {
+	attr := d.A.String()
+	vBytes := datalog.ValueBytes(d.V)
+	hash := d.E.Hash()
+
+	buf = binary.BigEndian.AppendUint32(buf, uint32(20+8+2+len(attr)+1+len(vBytes)))
+	buf = append(buf, hash[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, d.Tx)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(attr)))
+	buf = append(buf, attr...)
+	buf = append(buf, byte(datalog.Type(d.V)))
+	return append(buf, vBytes...)
+}
+
+// decodeDatomRecord decodes a record written by appendDatomRecord, without
+// its size
+func decodeDatomRecord(record []byte) (datalog.Datom, error) {
+	if len(record) < 20+8+2 {
+		return datalog.Datom{}, fmt.Errorf("record too short: %d bytes", len(record))
+	}
+	var hash [20]byte
+	copy(hash[:], record[:20])
+	tx := binary.BigEndian.Uint64(record[20:28])
+	aSize := int(binary.BigEndian.Uint16(record[28:30]))
+	if len(record) < 30+aSize+1 {
+		return datalog.Datom{}, fmt.Errorf("record truncated: %d bytes", len(record))
+	}
+	attr := string(record[30 : 30+aSize])
+	vType := datalog.ValueType(record[30+aSize])
+
+	v, err := datalog.ValueFromBytes(vType, record[30+aSize+1:])
+	if err != nil {
+		return datalog.Datom{}, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return datalog.Datom{
+		E:  datalog.NewIdentityFromHash(hash),
+		A:  datalog.NewKeyword(attr),
+		V:  v,
+		Tx: tx,
+	}, nil
+}
+
+// String returns the manifest as EDN, as written to ManifestFile
+func (m *ExportManifest) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "{:format \"janus-datoms\"\n :version 1\n :datoms %d\n :shards [", m.Datoms)
+	for i, shard := range m.Shards {
+		if i > 0 {
+			sb.WriteString("\n          ")
+		}
+		fmt.Fprintf(&sb, "{:file %q :datoms %d}", shard.File, shard.Datoms)
+	}
+	sb.WriteString("]}\n")
+	return sb.String()
+}
+
+// ReadManifest reads the manifest of the export in dir
+func ReadManifest(dir string) (*ExportManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	node, err := edn.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	fields, err := ednMapFields(*node)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if format, _ := fields[":format"].AsString(); format != "janus-datoms" {
+		return nil, fmt.Errorf("invalid manifest: not a datom export")
+	}
+	if version, _ := fields[":version"].AsInt(); version != 1 {
+		return nil, fmt.Errorf("invalid manifest: unsupported version %s", fields[":version"].String())
+	}
+
+	manifest := &ExportManifest{}
+	if manifest.Datoms, err = fields[":datoms"].AsInt(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: :datoms: %w", err)
+	}
+	shards := fields[":shards"]
+	if shards.Type != edn.NodeVector {
+		return nil, fmt.Errorf("invalid manifest: :shards is not a vector")
+	}
+	for i, node := range shards.Nodes {
+		shardFields, err := ednMapFields(node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: shard %d: %w", i, err)
+		}
+		var shard ExportShard
+		if shard.File, err = shardFields[":file"].AsString(); err != nil {
+			return nil, fmt.Errorf("invalid manifest: shard %d :file: %w", i, err)
+		}
+		if shard.Datoms, err = shardFields[":datoms"].AsInt(); err != nil {
+			return nil, fmt.Errorf("invalid manifest: shard %d :datoms: %w", i, err)
+		}
+		manifest.Shards = append(manifest.Shards, shard)
+	}
+	return manifest, nil
+}
+
+// ednMapFields returns the entries of an EDN map keyed by keyword
+func ednMapFields(node edn.Node) (map[string]edn.Node, error) {
+	if node.Type != edn.NodeMap || len(node.Nodes)%2 != 0 {
+		return nil, fmt.Errorf("expected a map, got %s", node.String())
+	}
+	fields := make(map[string]edn.Node, len(node.Nodes)/2)
+	for i := 0; i < len(node.Nodes); i += 2 {
+		key, err := node.Nodes[i].AsKeyword()
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = node.Nodes[i+1]
+	}
+	return fields, nil
+}
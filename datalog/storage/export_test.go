@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestExportImport(t *testing.T) {
+	dir, err := os.MkdirTemp("", "export-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src, err := NewDatabase(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer src.Close()
+
+	name := datalog.NewKeyword(":person/name")
+	age := datalog.NewKeyword(":person/age")
+	friend := datalog.NewKeyword(":person/friend")
+	joined := datalog.NewKeyword(":person/joined")
+	for i := 0; i < 20; i++ {
+		tx := src.NewTransaction()
+		person := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		tx.Add(person, name, fmt.Sprintf("Person %d", i))
+		tx.Add(person, age, int64(20+i))
+		tx.Add(person, joined, time.Date(2020, 1, 1+i, 0, 0, 0, 0, time.UTC))
+		if i > 0 {
+			tx.Add(person, friend, datalog.NewIdentity(fmt.Sprintf("person:%d", i-1)))
+		}
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+
+	exportDir := filepath.Join(dir, "export")
+	manifest, err := src.Export(exportDir, ExportOptions{Shards: 4})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(manifest.Shards) != 4 {
+		t.Fatalf("Expected 4 shards, got %d", len(manifest.Shards))
+	}
+	var total int64
+	for _, shard := range manifest.Shards {
+		if shard.Datoms == 0 {
+			t.Errorf("Expected every shard to get datoms, %s is empty", shard.File)
+		}
+		total += shard.Datoms
+	}
+	if total != manifest.Datoms {
+		t.Errorf("Shard counts sum to %d, manifest says %d", total, manifest.Datoms)
+	}
+
+	read, err := ReadManifest(exportDir)
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+	if !reflect.DeepEqual(read, manifest) {
+		t.Errorf("Expected manifest %v, read %v", manifest, read)
+	}
+
+	dst, err := NewDatabase(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer dst.Close()
+	if _, err := dst.Import(exportDir, ImportOptions{Workers: 2}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	queries := []string{
+		`[:find ?name ?age ?joined :where [?p :person/name ?name] [?p :person/age ?age] [?p :person/joined ?joined]]`,
+		`[:find ?a ?b :where [?x :person/friend ?y] [?x :person/name ?a] [?y :person/name ?b]]`,
+		`[:find ?name ?tx :where [?p :person/name ?name ?tx]]`,
+	}
+	for _, q := range queries {
+		want, got := queryRows(t, src, q), queryRows(t, dst, q)
+		if len(want) == 0 || !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: expected %d rows %v, imported database returned %d rows %v", q, len(want), want, len(got), got)
+		}
+	}
+
+	// Later commits continue after the imported transactions
+	tx := dst.NewTransaction()
+	tx.Add(datalog.NewIdentity("person:new"), name, "New")
+	txID, err := tx.Commit()
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if txID <= 20 {
+		t.Errorf("Expected a transaction ID after the imported ones, got %d", txID)
+	}
+}
+
+func TestImportManifestMismatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "export-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src, err := NewDatabase(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer src.Close()
+	tx := src.NewTransaction()
+	tx.Add(datalog.NewIdentity("person:alice"), datalog.NewKeyword(":person/name"), "Alice")
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	exportDir := filepath.Join(dir, "export")
+	manifest, err := src.Export(exportDir, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	manifest.Shards[0].Datoms++
+	if err := os.WriteFile(filepath.Join(exportDir, ManifestFile), []byte(manifest.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := NewDatabase(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer dst.Close()
+	if _, err := dst.Import(exportDir, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "shard-000.datoms") {
+		t.Errorf("Expected import to fail on shard-000.datoms, got %v", err)
+	}
+}
+
+// queryRows returns a query's rows formatted and sorted for comparison
+func queryRows(t *testing.T, db *Database, q string) []string {
+	t.Helper()
+	rows, err := db.ExecuteQuery(q)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			if tx, ok := v.(*uint64); ok {
+				v = *tx // Transaction IDs are bound as pointers
+			}
+			values[j] = v
+		}
+		out[i] = fmt.Sprint(values...)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -151,7 +151,7 @@ func (m *BadgerMatcher) matchWithHashJoin(
 	}
 
 	// Find the column index of joinSymbol in bindingRel
-	bindingCols := bindingRel.Columns()
+	bindingCols := bindingRel.Symbols()
 	columnIndex := -1
 	for i, col := range bindingCols {
 		if col == joinSymbol {
@@ -166,7 +166,10 @@ func (m *BadgerMatcher) matchWithHashJoin(
 	}
 
 	// Build hash set using column index (not datom position)
-	hashSet := m.buildHashSet(bindingRel, columnIndex)
+	hashSet, err := m.buildHashSet(bindingRel, columnIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bindings: %w", err)
+	}
 
 	if len(hashSet) == 0 {
 		// No bindings - return empty result
@@ -188,7 +191,7 @@ func (m *BadgerMatcher) matchWithHashJoin(
 	scanRange := m.calculatePatternScanRangeWithBinding(pattern, index, position, boundValue)
 
 	// PHASE 3: Create storage iterator
-	storageIter, err := m.store.ScanKeysOnly(index, scanRange.start, scanRange.end)
+	storageIter, err := m.scanKeysOnly(index, scanRange.start, scanRange.end)
 	if err != nil {
 		return nil, fmt.Errorf("hash join scan failed: %w", err)
 	}
@@ -330,14 +333,12 @@ func (m *BadgerMatcher) chooseIndexForValues(index IndexType, e, a, v, tx interf
 }
 
 // buildHashSet creates a hash set from binding relation for O(1) lookup
-func (m *BadgerMatcher) buildHashSet(bindingRel executor.Relation, position int) map[string]executor.Tuple {
+func (m *BadgerMatcher) buildHashSet(bindingRel executor.Relation, position int) (map[string]executor.Tuple, error) {
 	hashSet := make(map[string]executor.Tuple)
 
-	iter := bindingRel.Iterator()
-	for iter.Next() {
-		tuple := iter.Tuple()
+	err := bindingRel.ForEach(func(tuple executor.Tuple) (bool, error) {
 		if position >= len(tuple) {
-			continue
+			return false, nil
 		}
 
 		// Extract value at position and convert to hash key
@@ -347,10 +348,10 @@ func (m *BadgerMatcher) buildHashSet(bindingRel executor.Relation, position int)
 		if key != "" {
 			hashSet[key] = tuple
 		}
-	}
-	iter.Close()
+		return false, nil
+	})
 
-	return hashSet
+	return hashSet, err
 }
 
 // extractProbeKey extracts the value from datom at the specified position
@@ -404,7 +405,7 @@ func (m *BadgerMatcher) matchesWithBindingTuple(
 	bindingTuple executor.Tuple,
 ) bool {
 	// Build column index for binding relation
-	columns := bindingRel.Columns()
+	columns := bindingRel.Symbols()
 	colIndex := make(map[query.Symbol]int)
 	for i, col := range columns {
 		colIndex[col] = i
@@ -479,7 +480,7 @@ func (m *BadgerMatcher) matchWithMergeJoin(
 	scanRange := m.calculatePatternScanRange(pattern, index)
 
 	// PHASE 3: Create storage iterator
-	storageIter, err := m.store.ScanKeysOnly(index, scanRange.start, scanRange.end)
+	storageIter, err := m.scanKeysOnly(index, scanRange.start, scanRange.end)
 	if err != nil {
 		return nil, fmt.Errorf("merge join scan failed: %w", err)
 	}
@@ -619,6 +620,7 @@ type hashJoinIterator struct {
 	iter         Iterator                  // Storage iterator
 	tupleBuilder *query.InternedTupleBuilder
 	current      executor.Tuple
+	err          error // Error that ended the scan early
 	datomsScanned int // Track number of datoms scanned for event reporting
 	matchesFound  int // Track number of matches for event reporting
 }
@@ -627,7 +629,8 @@ func (it *hashJoinIterator) Next() bool {
 	for it.iter.Next() {
 		datom, err := it.iter.Datom()
 		if err != nil {
-			continue
+			it.err = fmt.Errorf("scan of %s failed: %w", it.pattern, err)
+			return false
 		}
 
 		// Count every datom scanned for performance monitoring
@@ -673,20 +676,21 @@ func (it *hashJoinIterator) Tuple() executor.Tuple {
 	return it.current
 }
 
+func (it *hashJoinIterator) Err() error {
+	return it.err
+}
+
 func (it *hashJoinIterator) Close() error {
 	// Emit event with scan statistics for performance monitoring
 	// ONLY emit if we actually scanned datoms (avoid emitting on unused iterators)
 	if it.matcher.handler != nil && it.datomsScanned > 0 {
-		it.matcher.handler(annotations.Event{
-			Name: "pattern/hash-join-complete",
-			Data: map[string]interface{}{
-				"pattern":        it.pattern.String(),
-				"index":          indexName(it.index),
-				"binding.size":   len(it.hashSet),
-				"datoms.scanned": it.datomsScanned,
-				"matches.found":  it.matchesFound,
-			},
-		})
+		it.matcher.handler(annotations.NewEvent("pattern/hash-join-complete", annotations.HashJoinScanEvent{
+			Pattern:       it.pattern.String(),
+			Index:         indexName(it.index),
+			BindingSize:   len(it.hashSet),
+			DatomsScanned: it.datomsScanned,
+			MatchesFound:  it.matchesFound,
+		}))
 	}
 
 	if it.iter != nil {
@@ -709,13 +713,15 @@ type mergeJoinIterator struct {
 	iter          Iterator         // Storage iterator
 	tupleBuilder  *query.InternedTupleBuilder
 	current       executor.Tuple
+	err           error // Error that ended the scan early
 }
 
 func (it *mergeJoinIterator) Next() bool {
 	for it.iter.Next() {
 		datom, err := it.iter.Datom()
 		if err != nil {
-			continue
+			it.err = fmt.Errorf("scan of %s failed: %w", it.pattern, err)
+			return false
 		}
 
 		// Check transaction validity
@@ -779,6 +785,10 @@ func (it *mergeJoinIterator) Tuple() executor.Tuple {
 	return it.current
 }
 
+func (it *mergeJoinIterator) Err() error {
+	return it.err
+}
+
 func (it *mergeJoinIterator) Close() error {
 	if it.iter != nil {
 		return it.iter.Close()
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+)
+
+// Time-based transaction IDs are hybrid logical clock (HLC) timestamps: the
+// commit time in nanoseconds since the Unix epoch with the low hlcLogicalBits
+// replaced by a logical counter. IDs stay within ~65µs of the commit time, so
+// they order like timestamps, while the counter keeps them unique and
+// increasing when commits share a clock tick or the wall clock steps back.
+const (
+	hlcLogicalBits = 16
+	hlcLogicalMask = 1<<hlcLogicalBits - 1
+)
+
+// ErrNoTransactionAsOf is returned by TxAsOfTime and AsOfTime for a time
+// before the first transaction
+var ErrNoTransactionAsOf = errors.New("no transaction at or before time")
+
+// hybridClock issues monotonic transaction IDs from wall-clock time. It is
+// guarded by the database's commit lock.
+type hybridClock struct {
+	last uint64 // Largest ID issued or observed
+}
+
+// next returns an ID greater than any issued or observed: the physical time
+// of now if the clock has moved past the last ID, otherwise the last ID with
+// its logical counter incremented
+func (c *hybridClock) next(now time.Time) uint64 {
+	id := hlcPhysical(now)
+	if id <= c.last {
+		id = c.last + 1
+	}
+	c.last = id
+	return id
+}
+
+// observe advances the clock to an ID issued elsewhere, so later IDs follow it
+func (c *hybridClock) observe(id uint64) {
+	if id > c.last {
+		c.last = id
+	}
+}
+
+// hlcPhysical returns the physical part of the HLC timestamp for t
+func hlcPhysical(t time.Time) uint64 {
+	return uint64(t.UnixNano()) &^ hlcLogicalMask
+}
+
+// TxTime returns the physical time encoded in a time-based transaction ID.
+// Sequential transaction IDs do not encode a time; use the transaction's
+// :db/txInstant instead.
+func TxTime(txID uint64) time.Time {
+	return time.Unix(0, int64(txID&^hlcLogicalMask))
+}
+
+// nextTimeTxID assigns a time-based transaction ID to a commit at txTime; the
+// caller holds commitMu.
+//
+// Commits at the current time take the next clock ID. A commit at an explicit
+// time (NewTransactionAt) is placed at that time rather than after the latest
+// commit, so backfilled history stays in time order; its counter follows any
+// transaction already stored in the same tick. A time ahead of the clock
+// advances it, as an HLC does for a timestamp received from another node.
+func (d *Database) nextTimeTxID(txTime time.Time, explicit bool) (uint64, error) {
+	if !explicit {
+		return d.clock.next(txTime), nil
+	}
+
+	id := hlcPhysical(txTime)
+	latest, ok, err := d.store.latestTxID(id + hlcLogicalMask + 1)
+	if err != nil {
+		return 0, err
+	}
+	if ok && latest >= id {
+		if latest&hlcLogicalMask == hlcLogicalMask {
+			return 0, fmt.Errorf("too many transactions at %s", txTime.Format(time.RFC3339Nano))
+		}
+		id = latest + 1
+	}
+	d.clock.observe(id)
+	return id, nil
+}
+
+// latestTxID returns the largest transaction ID below the given bound, or
+// the largest overall if below is 0
+func (s *BadgerStore) latestTxID(below uint64) (uint64, bool, error) {
+	start, end := s.encoder.EncodePrefixRange(TAEV)
+	if below > 0 {
+		bound := NewTxFromUint(below)
+		end = s.encoder.EncodePrefix(TAEV, bound[:])
+	}
+
+	it, err := s.ScanKeysOnlyReverse(TAEV, start, end)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to scan transactions: %w", err)
+	}
+	defer it.Close()
+
+	if !it.Next() {
+		return 0, false, nil
+	}
+	datom, err := it.Datom()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return datom.Tx, true, nil
+}
+
+// TxAsOfTime returns the transaction current at wall-clock time t: the latest
+// one whose :db/txInstant is at or before t. The :db/txInstant datoms in AVET
+// are the time-to-transaction mapping, so this is one short reverse scan.
+func (d *Database) TxAsOfTime(t time.Time) (uint64, error) {
+	if t.Before(time.Unix(0, 0)) {
+		return 0, fmt.Errorf("%w: %s", ErrNoTransactionAsOf, t.Format(time.RFC3339Nano))
+	}
+
+	s := d.store
+	aStorage := ToStorageDatom(datalog.Datom{A: datalog.NewKeyword(":db/txInstant")}).A
+	bound, err := s.valuePart(t.Add(time.Nanosecond))
+	if err != nil {
+		return 0, err
+	}
+	start := s.encoder.EncodePrefix(AVET, aStorage[:], []byte{byte(datalog.TypeTime)})
+	end := s.encoder.EncodePrefix(AVET, aStorage[:], bound)
+
+	it, err := s.ScanKeysOnlyReverse(AVET, start, end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan transaction instants: %w", err)
+	}
+	defer it.Close()
+
+	// Several transactions can share an instant; take the latest of them
+	var txID uint64
+	var instant time.Time
+	for it.Next() {
+		datom, err := it.Datom()
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode transaction instant: %w", err)
+		}
+		v, _ := datom.V.(time.Time)
+		if txID != 0 && !v.Equal(instant) {
+			break
+		}
+		instant = v
+		if datom.Tx > txID {
+			txID = datom.Tx
+		}
+	}
+	if txID == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNoTransactionAsOf, t.Format(time.RFC3339Nano))
+	}
+	return txID, nil
+}
+
+// AsOfTime returns a PatternMatcher for the database as of wall-clock time t,
+// i.e. as of TxAsOfTime(t). It works with sequential and time-based
+// transaction IDs alike.
+func (d *Database) AsOfTime(t time.Time) (executor.PatternMatcher, error) {
+	txID, err := d.TxAsOfTime(t)
+	if err != nil {
+		return nil, err
+	}
+	return d.AsOf(txID), nil
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+func TestHybridClockMonotonic(t *testing.T) {
+	var clock hybridClock
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first := clock.next(now)
+	if first != hlcPhysical(now) {
+		t.Errorf("first ID should be the physical time, got %d want %d", first, hlcPhysical(now))
+	}
+
+	// Same tick: logical counter increments
+	second := clock.next(now)
+	if second != first+1 {
+		t.Errorf("expected counter increment, got %d after %d", second, first)
+	}
+
+	// Clock steps back: IDs still increase
+	third := clock.next(now.Add(-time.Hour))
+	if third <= second {
+		t.Errorf("expected ID after %d despite clock skew, got %d", second, third)
+	}
+
+	// Clock moves on: IDs follow it again
+	later := now.Add(time.Second)
+	if id := clock.next(later); id != hlcPhysical(later) {
+		t.Errorf("expected clock to resume physical time, got %d", id)
+	}
+
+	if got := TxTime(first); got.Sub(now) > 0 || now.Sub(got) >= time.Duration(hlcLogicalMask+1) {
+		t.Errorf("TxTime(%d) = %s, not within a tick of %s", first, got, now)
+	}
+}
+
+func TestTimeTxIDsUniqueAndMonotonic(t *testing.T) {
+	dir, err := os.MkdirTemp("", "hlc-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabaseWithTimeTx(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	price := datalog.NewKeyword(":stock/price")
+	stock := datalog.NewIdentity("stock:acme")
+	commit := func(tx *Transaction, v int64) uint64 {
+		t.Helper()
+		if err := tx.Add(stock, price, v); err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+		id, err := tx.Commit()
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		return id
+	}
+
+	// Transactions at the same explicit time get distinct IDs in that tick
+	at := time.Date(2024, 1, 2, 16, 0, 0, 0, time.UTC)
+	a := commit(db.NewTransactionAt(at), 100)
+	b := commit(db.NewTransactionAt(at), 101)
+	if a == b {
+		t.Fatalf("transactions at the same time share ID %d", a)
+	}
+	if hlcPhysical(TxTime(b)) != hlcPhysical(at) {
+		t.Errorf("ID %d is not in the tick of %s", b, at)
+	}
+
+	// A commit dated ahead of the wall clock, e.g. from a skewed clock,
+	// advances the clock so later commits still order after it
+	future := commit(db.NewTransactionAt(time.Now().Add(time.Hour)), 102)
+	now := commit(db.NewTransaction(), 103)
+	if now <= future {
+		t.Errorf("wall-clock ID %d should follow skewed ID %d", now, future)
+	}
+
+	// The clock resumes from storage after a restart
+	db.Close()
+	db, err = NewDatabaseWithTimeTx(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if again := commit(db.NewTransaction(), 104); again <= now {
+		t.Errorf("ID %d after restart should follow %d", again, now)
+	}
+}
+
+func TestAsOfTime(t *testing.T) {
+	for _, timeTx := range []bool{true, false} {
+		name := "sequential"
+		if timeTx {
+			name = "time-tx"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "asof-time-test-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			var db *Database
+			if timeTx {
+				db, err = NewDatabaseWithTimeTx(dir)
+			} else {
+				db, err = NewDatabase(dir)
+			}
+			if err != nil {
+				t.Fatalf("Failed to create database: %v", err)
+			}
+			defer db.Close()
+
+			stock := datalog.NewIdentity("stock:acme")
+			day := func(d int) time.Time { return time.Date(2024, 1, d, 16, 0, 0, 0, time.UTC) }
+
+			var txIDs []uint64
+			for d := 1; d <= 3; d++ {
+				tx := db.NewTransactionAt(day(d))
+				tx.Add(stock, datalog.NewKeyword(":stock/price"), int64(100+d))
+				id, err := tx.Commit()
+				if err != nil {
+					t.Fatalf("Failed to commit: %v", err)
+				}
+				txIDs = append(txIDs, id)
+			}
+
+			q, err := parser.ParseQuery(`[:find ?p :where [?s :stock/price ?p]]`)
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+
+			tests := []struct {
+				at     time.Time
+				txID   uint64
+				prices int
+			}{
+				{day(1), txIDs[0], 1},
+				{day(2).Add(time.Hour), txIDs[1], 2},
+				{day(3).Add(-time.Nanosecond), txIDs[1], 2},
+				{day(5), txIDs[2], 3},
+			}
+			for _, tt := range tests {
+				txID, err := db.TxAsOfTime(tt.at)
+				if err != nil {
+					t.Fatalf("TxAsOfTime(%s): %v", tt.at, err)
+				}
+				if txID != tt.txID {
+					t.Errorf("TxAsOfTime(%s) = %d, want %d", tt.at, txID, tt.txID)
+				}
+
+				matcher, err := db.AsOfTime(tt.at)
+				if err != nil {
+					t.Fatalf("AsOfTime(%s): %v", tt.at, err)
+				}
+				result, err := executor.NewExecutor(matcher).Execute(q)
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+				if n := len(result.Sorted()); n != tt.prices {
+					t.Errorf("as of %s: expected %d prices, got %d", tt.at, tt.prices, n)
+				}
+			}
+
+			if _, err := db.AsOfTime(day(1).Add(-time.Nanosecond)); !errors.Is(err, ErrNoTransactionAsOf) {
+				t.Errorf("expected ErrNoTransactionAsOf before the first transaction, got %v", err)
+			}
+		})
+	}
+}
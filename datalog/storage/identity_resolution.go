@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// ResolveIdentities maps external key values to the entities holding them as
+// attr, e.g. the :order/external-id of each order in an import batch. All
+// values are looked up in one pass over AVET rather than one query per value.
+//
+// The result is keyed by the values as given. Values held by no entity are
+// absent from it; a value held by several entities is an
+// ErrLookupRefAmbiguous error, as attr does not identify an entity.
+func (d *Database) ResolveIdentities(attr datalog.Keyword, values []interface{}) (map[interface{}]datalog.Identity, error) {
+	s := d.store
+	aStorage := ToStorageDatom(datalog.Datom{A: attr}).A
+
+	vParts := make([][]byte, len(values))
+	prefixes := make([][]byte, len(values))
+	for i, v := range values {
+		if n, ok := v.(int); ok {
+			v = int64(n)
+		}
+		if _, ok := v.([]byte); ok {
+			return nil, fmt.Errorf("value %d: byte slices cannot be external keys", i)
+		}
+		vPart, err := s.valuePart(v)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		vParts[i] = vPart
+		prefixes[i] = s.encoder.EncodePrefix(AVET, aStorage[:], vPart)
+	}
+
+	result := make(map[interface{}]datalog.Identity, len(values))
+	err := s.scanPrefixes(AVET, prefixes, func(i int, datom *datalog.Datom) error {
+		// A value's encoding may be a prefix of another's (e.g. strings),
+		// so only datoms with the exact value count
+		if vPart, err := s.valuePart(datom.V); err != nil || !bytes.Equal(vPart, vParts[i]) {
+			return nil
+		}
+		if existing, ok := result[values[i]]; ok && !existing.Equal(datom.E) {
+			return fmt.Errorf("%w: [%s %v] is held by several entities", ErrLookupRefAmbiguous, attr, values[i])
+		}
+		result[values[i]] = datom.E
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ResolveValues is the inverse of ResolveIdentities: it maps entities to their
+// value of attr in one pass over EAVT. The result is keyed by the identities
+// as given. Entities without the attribute are absent from it; an entity with
+// several values maps to the one from the latest transaction.
+func (d *Database) ResolveValues(attr datalog.Keyword, entities []datalog.Identity) (map[datalog.Identity]interface{}, error) {
+	s := d.store
+	aStorage := ToStorageDatom(datalog.Datom{A: attr}).A
+
+	prefixes := make([][]byte, len(entities))
+	for i, e := range entities {
+		eStorage := ToStorageDatom(datalog.Datom{E: e}).E
+		prefixes[i] = s.encoder.EncodePrefix(EAVT, eStorage[:], aStorage[:])
+	}
+
+	result := make(map[datalog.Identity]interface{}, len(entities))
+	latest := make(map[datalog.Identity]uint64, len(entities))
+	err := s.scanPrefixes(EAVT, prefixes, func(i int, datom *datalog.Datom) error {
+		e := entities[i]
+		if tx, ok := latest[e]; !ok || datom.Tx > tx {
+			result[e] = datom.V
+			latest[e] = datom.Tx
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scanPrefixes calls visit with the datoms under each key prefix of an index.
+// The prefixes are visited in key order by seeking a single iterator, so a
+// batch of lookups costs one pass over the index.
+func (s *BadgerStore) scanPrefixes(index IndexType, prefixes [][]byte, visit func(i int, datom *datalog.Datom) error) error {
+	order := make([]int, len(prefixes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return bytes.Compare(prefixes[order[a]], prefixes[order[b]]) < 0
+	})
+
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for _, i := range order {
+			for it.Seek(prefixes[i]); it.ValidForPrefix(prefixes[i]); it.Next() {
+				datom, err := datomFromKey(txn, index, it.Item().Key(), s.encoder)
+				if err != nil {
+					return fmt.Errorf("failed to decode %s key: %w", indexName(index), err)
+				}
+				if err := visit(i, datom); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
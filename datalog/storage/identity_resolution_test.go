@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestResolveIdentities(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	externalID := datalog.NewKeyword(":order/external-id")
+	seq := datalog.NewKeyword(":order/seq")
+	region := datalog.NewKeyword(":order/region")
+
+	tx := db.NewTransaction()
+	for i := 0; i < 50; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("order:%d", i))
+		tx.Add(e, externalID, fmt.Sprintf("ext-%d", i))
+		tx.Add(e, seq, int64(i))
+		tx.Add(e, region, fmt.Sprintf("region-%d", i%2))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	t.Run("values to identities", func(t *testing.T) {
+		// ext-1 is a string prefix of ext-10..ext-19, which must not match it
+		values := []interface{}{"ext-1", "ext-42", "ext-7", "ext-missing", "ext-42"}
+		ids, err := db.ResolveIdentities(externalID, values)
+		if err != nil {
+			t.Fatalf("ResolveIdentities failed: %v", err)
+		}
+		if len(ids) != 3 {
+			t.Errorf("Expected 3 resolved values, got %d: %v", len(ids), ids)
+		}
+		for v, n := range map[string]int{"ext-1": 1, "ext-42": 42, "ext-7": 7} {
+			if id, ok := ids[v]; !ok || !id.Equal(datalog.NewIdentity(fmt.Sprintf("order:%d", n))) {
+				t.Errorf("%s resolved to %v, expected order:%d", v, id, n)
+			}
+		}
+		if _, ok := ids["ext-missing"]; ok {
+			t.Error("Expected unknown value to be absent")
+		}
+	})
+
+	t.Run("integer keys", func(t *testing.T) {
+		ids, err := db.ResolveIdentities(seq, []interface{}{3, int64(4)})
+		if err != nil {
+			t.Fatalf("ResolveIdentities failed: %v", err)
+		}
+		if !ids[3].Equal(datalog.NewIdentity("order:3")) || !ids[int64(4)].Equal(datalog.NewIdentity("order:4")) {
+			t.Errorf("Unexpected resolution: %v", ids)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, err := db.ResolveIdentities(region, []interface{}{"region-0"})
+		if !errors.Is(err, ErrLookupRefAmbiguous) {
+			t.Errorf("Expected ErrLookupRefAmbiguous, got %v", err)
+		}
+	})
+
+	t.Run("identities to values", func(t *testing.T) {
+		entities := []datalog.Identity{
+			datalog.NewIdentity("order:5"),
+			datalog.NewIdentity("order:12"),
+			datalog.NewIdentity("order:missing"),
+		}
+
+		tx := db.NewTransaction()
+		tx.Add(entities[1], externalID, "ext-12-renamed")
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		values, err := db.ResolveValues(externalID, entities)
+		if err != nil {
+			t.Fatalf("ResolveValues failed: %v", err)
+		}
+		if len(values) != 2 {
+			t.Errorf("Expected 2 resolved entities, got %d: %v", len(values), values)
+		}
+		if values[entities[0]] != "ext-5" {
+			t.Errorf("order:5 resolved to %v, expected ext-5", values[entities[0]])
+		}
+		if values[entities[1]] != "ext-12-renamed" {
+			t.Errorf("order:12 resolved to %v, expected the latest value ext-12-renamed", values[entities[1]])
+		}
+	})
+}
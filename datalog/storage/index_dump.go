@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/codec"
+)
+
+// IndexEntry is a raw index key together with the datom decoded from it
+type IndexEntry struct {
+	Key         []byte
+	Datom       *datalog.Datom // nil if the key could not be decoded
+	DecodeError error
+}
+
+// ParseIndexType parses an index name such as "avet" (case-insensitive)
+func ParseIndexType(name string) (IndexType, error) {
+	for _, idx := range []IndexType{EAVT, AEVT, AVET, VAET, TAEV} {
+		if strings.EqualFold(name, indexName(idx)) {
+			return idx, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown index %q (expected EAVT, AEVT, AVET, VAET or TAEV)", name)
+}
+
+// DumpIndex returns up to limit keys of an index in key order, starting with
+// the given prefix (limit <= 0 means no limit).
+//
+// The prefix lists leading datom components in the index's order, e.g.
+// [:person/age 20] for AVET. Entities may be given as datalog.Identity or as
+// a string naming the entity, attributes as datalog.Keyword or string, and
+// transactions as integers. Keys that fail to decode are returned with their
+// DecodeError set rather than aborting the dump.
+func (s *BadgerStore) DumpIndex(index IndexType, prefix []interface{}, limit int) ([]IndexEntry, error) {
+	keyPrefix, err := s.encodeIndexPrefix(index, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []IndexEntry
+	err = s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(keyPrefix); it.ValidForPrefix(keyPrefix); it.Next() {
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+			key := it.Item().KeyCopy(nil)
+			datom, err := datomFromKey(txn, index, key, s.encoder)
+			entries = append(entries, IndexEntry{Key: key, Datom: datom, DecodeError: err})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("index dump failed: %w", err)
+	}
+	return entries, nil
+}
+
+// DumpIndex returns up to limit decoded keys of an index starting with prefix.
+// See BadgerStore.DumpIndex.
+func (d *Database) DumpIndex(index IndexType, prefix []interface{}, limit int) ([]IndexEntry, error) {
+	return d.store.DumpIndex(index, prefix, limit)
+}
+
+// encodeIndexPrefix encodes leading datom components as an index key prefix
+func (s *BadgerStore) encodeIndexPrefix(index IndexType, prefix []interface{}) ([]byte, error) {
+	order := indexName(index)
+	if order == "UNKNOWN" {
+		return nil, fmt.Errorf("unknown index type: %d", index)
+	}
+	if len(prefix) > len(order) {
+		return nil, fmt.Errorf("%s prefix has %d components, at most %d allowed", order, len(prefix), len(order))
+	}
+
+	parts := make([][]byte, len(prefix))
+	for i, component := range prefix {
+		var err error
+		switch order[i] {
+		case 'E':
+			parts[i], err = entityPart(component)
+		case 'A':
+			parts[i], err = attributePart(component)
+		case 'V':
+			parts[i], err = s.valuePart(component)
+		case 'T':
+			parts[i], err = txPart(component)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s prefix component %d: %w", order, i, err)
+		}
+	}
+	return s.encoder.EncodePrefix(index, parts...), nil
+}
+
+func entityPart(v interface{}) ([]byte, error) {
+	switch e := v.(type) {
+	case datalog.Identity:
+		sd := ToStorageDatom(datalog.Datom{E: e})
+		return sd.E[:], nil
+	case string:
+		sd := ToStorageDatom(datalog.Datom{E: datalog.NewIdentity(e)})
+		return sd.E[:], nil
+	default:
+		return nil, fmt.Errorf("entity must be an identity or string, got %T", v)
+	}
+}
+
+func attributePart(v interface{}) ([]byte, error) {
+	switch a := v.(type) {
+	case datalog.Keyword:
+		sd := ToStorageDatom(datalog.Datom{A: a})
+		return sd.A[:], nil
+	case string:
+		sd := ToStorageDatom(datalog.Datom{A: datalog.NewKeyword(a)})
+		return sd.A[:], nil
+	default:
+		return nil, fmt.Errorf("attribute must be a keyword or string, got %T", v)
+	}
+}
+
+func txPart(v interface{}) ([]byte, error) {
+	var tx uint64
+	switch t := v.(type) {
+	case uint64:
+		tx = t
+	case int64:
+		tx = uint64(t)
+	case int:
+		tx = uint64(t)
+	default:
+		return nil, fmt.Errorf("transaction must be an integer, got %T", v)
+	}
+	storageTx := NewTxFromUint(tx)
+	return storageTx[:], nil
+}
+
+// valuePart encodes a value as its type byte and data, as stored in keys
+func (s *BadgerStore) valuePart(v interface{}) ([]byte, error) {
+	switch v.(type) {
+	case string, int64, float64, bool, time.Time, []byte, datalog.Identity, datalog.Keyword:
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+
+	vType, vData := storedValue(s.indexedValue(v))
+	if _, isL85 := s.encoder.(*L85KeyEncoder); isL85 && vType == byte(datalog.TypeReference) {
+		// L85 encoder stores references as type + L85-encoded bytes
+		var vArr [20]byte
+		copy(vArr[:], vData)
+		return append([]byte{vType}, []byte(codec.EncodeFixed20(vArr))...), nil
+	}
+	return append([]byte{vType}, vData...), nil
+}
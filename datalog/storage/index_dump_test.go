@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestDumpIndex(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	age := datalog.NewKeyword(":person/age")
+	tx := db.NewTransaction()
+	for i := 0; i < 10; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		tx.Add(e, age, int64(20+i%3))
+		tx.Add(e, datalog.NewKeyword(":person/name"), fmt.Sprintf("person%d", i))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	t.Run("attribute and value prefix", func(t *testing.T) {
+		entries, err := db.DumpIndex(AVET, []interface{}{":person/age", int64(20)}, 0)
+		if err != nil {
+			t.Fatalf("DumpIndex failed: %v", err)
+		}
+		// Ages 20 are persons 0, 3, 6 and 9
+		if len(entries) != 4 {
+			t.Fatalf("Expected 4 entries, got %d", len(entries))
+		}
+		for _, entry := range entries {
+			if entry.DecodeError != nil {
+				t.Fatalf("Failed to decode key %x: %v", entry.Key, entry.DecodeError)
+			}
+			if len(entry.Key) == 0 || entry.Key[0] != byte(AVET) {
+				t.Errorf("Expected an AVET key, got %x", entry.Key)
+			}
+			if entry.Datom.A != age || entry.Datom.V != int64(20) {
+				t.Errorf("Unexpected datom %v", entry.Datom)
+			}
+		}
+	})
+
+	t.Run("index order and limit", func(t *testing.T) {
+		entries, err := db.DumpIndex(AVET, []interface{}{age}, 5)
+		if err != nil {
+			t.Fatalf("DumpIndex failed: %v", err)
+		}
+		if len(entries) != 5 {
+			t.Fatalf("Expected 5 entries, got %d", len(entries))
+		}
+		for i := 1; i < len(entries); i++ {
+			if string(entries[i-1].Key) >= string(entries[i].Key) {
+				t.Errorf("Keys out of order at %d", i)
+			}
+			if datalog.CompareValues(entries[i-1].Datom.V, entries[i].Datom.V) > 0 {
+				t.Errorf("Values out of order at %d: %v after %v", i, entries[i].Datom.V, entries[i-1].Datom.V)
+			}
+		}
+	})
+
+	t.Run("entity prefix", func(t *testing.T) {
+		entries, err := db.DumpIndex(EAVT, []interface{}{"person:4"}, 0)
+		if err != nil {
+			t.Fatalf("DumpIndex failed: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 entries, got %d", len(entries))
+		}
+		for _, entry := range entries {
+			if !entry.Datom.E.Equal(datalog.NewIdentity("person:4")) {
+				t.Errorf("Unexpected entity %v", entry.Datom.E)
+			}
+		}
+	})
+
+	t.Run("invalid prefix", func(t *testing.T) {
+		if _, err := db.DumpIndex(AVET, []interface{}{":person/age", int64(20), "x", int64(1), "extra"}, 0); err == nil {
+			t.Error("Expected an error for too many components")
+		}
+		if _, err := db.DumpIndex(EAVT, []interface{}{int64(1)}, 0); err == nil {
+			t.Error("Expected an error for a non-entity component")
+		}
+	})
+}
+
+func TestParseIndexType(t *testing.T) {
+	for name, expected := range map[string]IndexType{"eavt": EAVT, "AEVT": AEVT, "Avet": AVET, "vaet": VAET, "taev": TAEV} {
+		idx, err := ParseIndexType(name)
+		if err != nil || idx != expected {
+			t.Errorf("ParseIndexType(%q) = %v, %v; expected %v", name, idx, err, expected)
+		}
+	}
+	if _, err := ParseIndexType("xyz"); err == nil {
+		t.Error("Expected an error for an unknown index")
+	}
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+)
+
+// indexComponents lists the key components of each index in key order
+var indexComponents = map[IndexType]string{
+	EAVT: "EAVT",
+	AEVT: "AEVT",
+	AVET: "AVET",
+	VAET: "VAET",
+	TAEV: "TAEV",
+}
+
+// componentSelectivity is the heuristic fraction of an index left after
+// fixing one key component: an entity holds few datoms and a value is shared
+// by few entities, while an attribute or transaction covers a sizable slice of
+// the database.
+var componentSelectivity = map[byte]float64{
+	'E': 0.001,
+	'A': 0.1,
+	'V': 0.01,
+	'T': 0.1,
+}
+
+// indexUsedWhen describes when chooseIndex picks each index, to explain why a
+// more selective prefix was not used
+var indexUsedWhen = map[IndexType]string{
+	EAVT: "E is bound without A, or nothing is bound",
+	AEVT: "A is bound without V, or E and A are bound",
+	AVET: "A and V are bound without E",
+	VAET: "V is bound without E or A",
+	TAEV: "only T is bound",
+}
+
+// boundComponents returns the positions chooseIndex can build a key prefix
+// from, in EAVT order, e.g. "AV"
+func boundComponents(e, a, v, tx interface{}) string {
+	var bound strings.Builder
+	if _, ok := e.(datalog.Identity); ok {
+		bound.WriteByte('E')
+	}
+	if _, ok := a.(datalog.Keyword); ok {
+		bound.WriteByte('A')
+	}
+	if v != nil {
+		bound.WriteByte('V')
+	}
+	if _, ok := tx.(uint64); ok {
+		bound.WriteByte('T')
+	}
+	return bound.String()
+}
+
+// indexPrefix returns the bound components that form a key prefix of the
+// index: its leading components, up to the first unbound one
+func indexPrefix(index IndexType, bound string) string {
+	components := indexComponents[index]
+	n := 0
+	for n < len(components) && strings.IndexByte(bound, components[n]) >= 0 {
+		n++
+	}
+	return components[:n]
+}
+
+// prefixSelectivity estimates the fraction of an index a scan with the given
+// prefix reads. Components are combined in EAVT order so equal prefixes in
+// different indexes produce identical estimates.
+func prefixSelectivity(prefix string) float64 {
+	selectivity := 1.0
+	for _, c := range []byte("EAVT") {
+		if strings.IndexByte(prefix, c) >= 0 {
+			selectivity *= componentSelectivity[c]
+		}
+	}
+	return selectivity
+}
+
+// explainIndexChoice evaluates every index for a scan with the given bound
+// values against the chosen one, so index selection annotations can say why
+// e.g. EAVT was used instead of AVET
+func explainIndexChoice(e, a, v, tx interface{}, chosen IndexType, pinned bool) (string, []annotations.IndexCandidate) {
+	bound := boundComponents(e, a, v, tx)
+	chosenPrefix := indexPrefix(chosen, bound)
+	chosenSelectivity := prefixSelectivity(chosenPrefix)
+
+	candidates := make([]annotations.IndexCandidate, 0, len(indexComponents))
+	for _, index := range []IndexType{EAVT, AEVT, AVET, VAET, TAEV} {
+		prefix := indexPrefix(index, bound)
+		c := annotations.IndexCandidate{
+			Index:       indexName(index),
+			Prefix:      prefix,
+			Selectivity: prefixSelectivity(prefix),
+			Selected:    index == chosen,
+		}
+
+		switch {
+		case c.Selected && pinned:
+			c.Reason = "pinned by plan"
+		case c.Selected && prefix == "":
+			c.Reason = "nothing usable is bound, full index scan"
+		case c.Selected:
+			c.Reason = fmt.Sprintf("scans bound prefix %s", prefix)
+		case pinned:
+			c.Reason = fmt.Sprintf("index pinned to %s by plan", indexName(chosen))
+		case prefix == "":
+			c.Reason = fmt.Sprintf("%c unbound, full index scan", indexComponents[index][0])
+		case c.Selectivity > chosenSelectivity:
+			c.Reason = fmt.Sprintf("prefix %s is less selective than %s prefix %s", prefix, indexName(chosen), chosenPrefix)
+		case c.Selectivity == chosenSelectivity:
+			c.Reason = fmt.Sprintf("prefix %s is no more selective than %s prefix %s", prefix, indexName(chosen), chosenPrefix)
+		default:
+			c.Reason = fmt.Sprintf("only used when %s", indexUsedWhen[index])
+		}
+		candidates = append(candidates, c)
+	}
+	return bound, candidates
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+func TestExplainIndexChoice(t *testing.T) {
+	alice := datalog.NewIdentity("person:alice")
+	name := datalog.NewKeyword(":person/name")
+
+	tests := []struct {
+		name     string
+		e, a, v  interface{}
+		tx       interface{}
+		chosen   IndexType
+		pinned   bool
+		bound    string
+		expected map[string]string // index -> reason
+	}{
+		{
+			name:   "attribute and value",
+			a:      name,
+			v:      "Alice",
+			chosen: AVET,
+			bound:  "AV",
+			expected: map[string]string{
+				"EAVT": "E unbound, full index scan",
+				"AEVT": "prefix A is less selective than AVET prefix AV",
+				"AVET": "scans bound prefix AV",
+				"VAET": "prefix VA is no more selective than AVET prefix AV",
+				"TAEV": "T unbound, full index scan",
+			},
+		},
+		{
+			name:   "entity only",
+			e:      alice,
+			chosen: EAVT,
+			bound:  "E",
+			expected: map[string]string{
+				"EAVT": "scans bound prefix E",
+				"AEVT": "A unbound, full index scan",
+			},
+		},
+		{
+			name:   "attribute and transaction",
+			a:      name,
+			tx:     uint64(7),
+			chosen: AEVT,
+			bound:  "AT",
+			expected: map[string]string{
+				"AEVT": "scans bound prefix A",
+				"TAEV": "only used when only T is bound",
+			},
+		},
+		{
+			name:   "nothing bound",
+			chosen: EAVT,
+			expected: map[string]string{
+				"EAVT": "nothing usable is bound, full index scan",
+			},
+		},
+		{
+			name:   "pinned",
+			a:      name,
+			v:      "Alice",
+			chosen: AEVT,
+			pinned: true,
+			bound:  "AV",
+			expected: map[string]string{
+				"AEVT": "pinned by plan",
+				"AVET": "index pinned to AEVT by plan",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound, candidates := explainIndexChoice(tt.e, tt.a, tt.v, tt.tx, tt.chosen, tt.pinned)
+			if bound != tt.bound {
+				t.Errorf("bound = %q, want %q", bound, tt.bound)
+			}
+			if len(candidates) != 5 {
+				t.Fatalf("expected 5 candidates, got %d", len(candidates))
+			}
+			for _, c := range candidates {
+				if c.Selected != (c.Index == indexName(tt.chosen)) {
+					t.Errorf("%s: selected = %v", c.Index, c.Selected)
+				}
+				if want, ok := tt.expected[c.Index]; ok && c.Reason != want {
+					t.Errorf("%s: reason = %q, want %q", c.Index, c.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexSelectionAnnotationCandidates(t *testing.T) {
+	dir, err := os.MkdirTemp("", "index-explain-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i := 0; i < 20; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("flag:%d", i))
+		tx.Add(e, datalog.NewKeyword(":flag/name"), fmt.Sprintf("flag-%d", i))
+		tx.Add(e, datalog.NewKeyword(":flag/enabled"), i%4 == 0)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	var selections []annotations.IndexSelectionEvent
+	h := &annotations.TypedHandler{
+		OnIndexSelection: func(_ annotations.Event, sel annotations.IndexSelectionEvent) {
+			selections = append(selections, sel)
+		},
+	}
+
+	matcher := executor.WrapMatcher(NewBadgerMatcher(db.Store()), h.Handle).(executor.PatternMatcher)
+	exec := executor.NewExecutor(matcher)
+	exec.DisableParallelSubqueries()
+
+	q, err := parser.ParseQuery(`[:find ?e :where [?e :flag/enabled true]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if _, err := exec.ExecuteWithContext(executor.NewContext(h.Handle), q); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(selections) == 0 {
+		t.Fatal("expected an index selection event")
+	}
+	sel := selections[0]
+	if sel.Index != "AVET" || sel.Bound != "AV" {
+		t.Errorf("expected AVET with AV bound, got %s with %q bound", sel.Index, sel.Bound)
+	}
+	for _, c := range sel.Candidates {
+		switch c.Index {
+		case "AVET":
+			if !c.Selected || c.Prefix != "AV" {
+				t.Errorf("AVET candidate: %s", c)
+			}
+		case "EAVT":
+			if c.Selected || c.Reason != "E unbound, full index scan" || c.Selectivity != 1 {
+				t.Errorf("EAVT candidate: %s", c)
+			}
+		}
+	}
+}
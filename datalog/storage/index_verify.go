@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// allIndexes lists the indexes every datom is written to
+var allIndexes = []IndexType{EAVT, AEVT, AVET, VAET, TAEV}
+
+// IndexDiscrepancy is a datom held by some indexes but not others, or an
+// index key that could not be decoded
+type IndexDiscrepancy struct {
+	Index       IndexType      // An index holding the datom
+	Key         []byte         // The datom's key in Index
+	Datom       *datalog.Datom // nil if the key could not be decoded
+	Missing     []IndexType    // Indexes without the datom
+	DecodeError error
+}
+
+// String describes the discrepancy with its key in hex
+func (d IndexDiscrepancy) String() string {
+	if d.DecodeError != nil {
+		return fmt.Sprintf("%s key %x: %v", indexName(d.Index), d.Key, d.DecodeError)
+	}
+	missing := make([]string, len(d.Missing))
+	for i, idx := range d.Missing {
+		missing[i] = indexName(idx)
+	}
+	return fmt.Sprintf("%s key %x: %v missing from %s",
+		indexName(d.Index), d.Key, *d.Datom, strings.Join(missing, ", "))
+}
+
+// IndexReport is the result of VerifyIndexes
+type IndexReport struct {
+	Datoms        int               // Distinct datoms found in any index
+	Keys          map[IndexType]int // Keys per index
+	Discrepancies []IndexDiscrepancy
+}
+
+// OK reports whether every index holds the same datoms
+func (r *IndexReport) OK() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// String summarizes the report, listing every discrepancy
+func (r *IndexReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d datoms;", r.Datoms)
+	for _, idx := range allIndexes {
+		fmt.Fprintf(&b, " %s %d", indexName(idx), r.Keys[idx])
+	}
+	if r.OK() {
+		b.WriteString("\nindexes are consistent")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\n%d discrepancies:", len(r.Discrepancies))
+	for _, d := range r.Discrepancies {
+		b.WriteString("\n  ")
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
+
+// VerifyIndexes cross-checks that the EAVT, AEVT, AVET, VAET and TAEV
+// indexes hold the same set of datoms, reading them from one snapshot.
+// Datoms missing from some indexes and keys that fail to decode are
+// reported as discrepancies; an error is only returned if the store can't
+// be read. Every datom's components are held in memory while verifying.
+func (s *BadgerStore) VerifyIndexes() (*IndexReport, error) {
+	report := &IndexReport{Keys: make(map[IndexType]int, len(allIndexes))}
+
+	// Datoms by their components, with the indexes holding them
+	type entry struct {
+		index IndexType
+		key   []byte
+		found uint8
+	}
+	datoms := make(map[string]*entry)
+	var order []string
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		for _, idx := range allIndexes {
+			prefix := s.encoder.EncodePrefix(idx)
+			it := txn.NewIterator(opts)
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				key := it.Item().KeyCopy(nil)
+				report.Keys[idx]++
+
+				e, a, v, tx, err := s.encoder.DecodeKey(idx, key)
+				if err != nil {
+					report.Discrepancies = append(report.Discrepancies,
+						IndexDiscrepancy{Index: idx, Key: key, DecodeError: err})
+					continue
+				}
+				id := string(e) + string(a) + string(tx) + string(v)
+				found, ok := datoms[id]
+				if !ok {
+					found = &entry{index: idx, key: key}
+					datoms[id] = found
+					order = append(order, id)
+				}
+				found.found |= 1 << idx
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("index verification failed: %w", err)
+	}
+
+	report.Datoms = len(datoms)
+	for _, id := range order {
+		found := datoms[id]
+		var missing []IndexType
+		for _, idx := range allIndexes {
+			if found.found&(1<<idx) == 0 {
+				missing = append(missing, idx)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		d := IndexDiscrepancy{Index: found.index, Key: found.key, Missing: missing}
+		d.Datom, d.DecodeError = DatomFromKey(found.index, found.key, s.encoder)
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+	return report, nil
+}
+
+// VerifyIndexes cross-checks that all indexes hold the same datoms. See
+// BadgerStore.VerifyIndexes.
+func (d *Database) VerifyIndexes() (*IndexReport, error) {
+	return d.store.VerifyIndexes()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestVerifyIndexes(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i := 0; i < 10; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		tx.Add(e, datalog.NewKeyword(":person/age"), int64(20+i))
+		tx.Add(e, datalog.NewKeyword(":person/friend"), datalog.NewIdentity(fmt.Sprintf("person:%d", (i+1)%10)))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	report, err := db.VerifyIndexes()
+	if err != nil {
+		t.Fatalf("VerifyIndexes failed: %v", err)
+	}
+	// 20 datoms and the transaction's :db/txInstant
+	if !report.OK() || report.Datoms != 21 {
+		t.Fatalf("Expected 21 consistent datoms, got %s", report)
+	}
+	for _, idx := range allIndexes {
+		if report.Keys[idx] != 21 {
+			t.Errorf("Expected 21 %s keys, got %d", indexName(idx), report.Keys[idx])
+		}
+	}
+
+	// Corrupt the indexes: drop a datom from AVET and VAET, and add a key
+	// only to EAVT
+	entries, err := db.DumpIndex(EAVT, []interface{}{"person:3", ":person/age"}, 0)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one datom to drop, got %d: %v", len(entries), err)
+	}
+	dropped := entries[0].Datom
+	stray := &datalog.Datom{E: datalog.NewIdentity("person:99"), A: datalog.NewKeyword(":person/age"), V: int64(99), Tx: 1}
+	store := db.store
+	err = store.db.Update(func(txn *badger.Txn) error {
+		for _, idx := range []IndexType{AVET, VAET} {
+			if err := txn.Delete(store.encoder.EncodeKey(idx, dropped)); err != nil {
+				return err
+			}
+		}
+		return txn.Set(store.encoder.EncodeKey(EAVT, stray), ToStorageDatom(*stray).Bytes())
+	})
+	if err != nil {
+		t.Fatalf("Failed to corrupt indexes: %v", err)
+	}
+
+	report, err = db.VerifyIndexes()
+	if err != nil {
+		t.Fatalf("VerifyIndexes failed: %v", err)
+	}
+	if report.OK() || len(report.Discrepancies) != 2 {
+		t.Fatalf("Expected 2 discrepancies, got %s", report)
+	}
+	for _, d := range report.Discrepancies {
+		if d.DecodeError != nil || d.Datom == nil {
+			t.Fatalf("Unexpected decode error: %v", d.DecodeError)
+		}
+		switch {
+		case d.Datom.E.Equal(dropped.E):
+			if len(d.Missing) != 2 || d.Missing[0] != AVET || d.Missing[1] != VAET {
+				t.Errorf("Expected the dropped datom missing from AVET and VAET, got %s", d)
+			}
+		case d.Datom.E.Equal(stray.E):
+			if d.Index != EAVT || len(d.Missing) != 4 {
+				t.Errorf("Expected the stray datom missing from all but EAVT, got %s", d)
+			}
+		default:
+			t.Errorf("Unexpected discrepancy %s", d)
+		}
+	}
+	if report.Keys[EAVT] != 22 || report.Keys[AVET] != 20 {
+		t.Errorf("Unexpected key counts %v", report.Keys)
+	}
+}
@@ -1,12 +1,11 @@
 package storage
 
 import (
-	"time"
+	"sync/atomic"
 
 	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/annotations"
 	"github.com/wbrown/janus-datalog/datalog/executor"
-	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
 // Iterator helper functions to reduce code duplication across iterator implementations.
@@ -59,31 +58,33 @@ func validateDatomWithConstraints(
 func emitIteratorStatistics(
 	handler func(annotations.Event),
 	eventName string,
-	pattern *query.DataPattern,
-	index IndexType,
-	datomsScanned int,
-	datomsMatched int,
-	extraData map[string]interface{},
+	scan annotations.PatternScanEvent,
 ) {
 	if handler == nil {
 		return
 	}
 
-	data := map[string]interface{}{
-		"pattern":        pattern.String(),
-		"index":          indexName(index),
-		"datoms.scanned": datomsScanned,
-		"datoms.matched": datomsMatched,
-	}
+	handler(annotations.NewEvent(eventName, scan))
+}
 
-	// Merge in extra data
-	for k, v := range extraData {
-		data[k] = v
+// countingIterator counts the datoms a storage scan reads, adding them to
+// counter when closed
+type countingIterator struct {
+	Iterator
+	counter *atomic.Int64
+	n       int64
+}
+
+func (it *countingIterator) Next() bool {
+	if it.Iterator.Next() {
+		it.n++
+		return true
 	}
+	return false
+}
 
-	handler(annotations.Event{
-		Name:  eventName,
-		Start: time.Now(),
-		Data:  data,
-	})
+func (it *countingIterator) Close() error {
+	it.counter.Add(it.n)
+	it.n = 0
+	return it.Iterator.Close()
 }
@@ -18,8 +18,7 @@ func (e *BinaryKeyEncoder) EncodeKey(index IndexType, d *datalog.Datom) []byte {
 	prefix := []byte{byte(index)}
 
 	// Get value bytes with type prefix (1 byte type + variable length data)
-	vType := byte(datalog.Type(sd.V))
-	vData := datalog.ValueBytes(sd.V)
+	vType, vData := storedValue(sd.V)
 	vBytes := append([]byte{vType}, vData...)
 
 	// Build key based on index type using raw bytes
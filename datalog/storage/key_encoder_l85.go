@@ -25,17 +25,16 @@ func (e *L85KeyEncoder) EncodeKey(index IndexType, d *datalog.Datom) []byte {
 
 	// Get value bytes with type prefix
 	// RefValues are 20-byte entity references and should be L85-encoded
-	vType := byte(datalog.Type(sd.V))
+	vType, vData := storedValue(sd.V)
 	var vBytes []byte
-	if datalog.Type(sd.V) == datalog.TypeReference {
+	if vType == byte(datalog.TypeReference) {
 		// RefValue is exactly 20 bytes, encode it
 		var vArr [20]byte
-		copy(vArr[:], datalog.ValueBytes(sd.V))
+		copy(vArr[:], vData)
 		// Type prefix + L85-encoded reference
 		vBytes = append([]byte{vType}, []byte(codec.EncodeFixed20(vArr))...)
 	} else {
 		// Other values: type prefix + raw bytes
-		vData := datalog.ValueBytes(sd.V)
 		vBytes = append([]byte{vType}, vData...)
 	}
 
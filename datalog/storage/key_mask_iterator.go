@@ -207,24 +207,22 @@ type KeyMaskIterator struct {
 
 // NewKeyMaskIterator creates an iterator that filters using key masks
 func NewKeyMaskIterator(store *BadgerStore, index IndexType, start, end []byte, mask *KeyMaskConstraint) (Iterator, error) {
-	txn := store.db.NewTransaction(false)
-
 	opts := badger.DefaultIteratorOptions
 	opts.PrefetchSize = 10000
 	opts.PrefetchValues = false // Key-only scanning
 
-	it := txn.NewIterator(opts)
+	iter, err := store.openIterator(opts)
+	if err != nil {
+		return nil, err
+	}
+	iter.start = start
+	iter.end = end
+	iter.index = index
 
 	return &KeyMaskIterator{
-		BadgerIterator: &BadgerIterator{
-			txn:   txn,
-			it:    it,
-			start: start,
-			end:   end,
-			index: index,
-		},
-		mask:    mask,
-		encoder: store.encoder,
+		BadgerIterator: iter,
+		mask:           mask,
+		encoder:        store.encoder,
 	}, nil
 }
 
@@ -268,14 +266,17 @@ func (w *KeyMaskFilterWrapper) Next() bool {
 		// Get the key from the base iterator
 		// We need to get the raw key - check for both BadgerIterator and KeyOnlyIterator
 		var key []byte
+		var txn *badger.Txn
 		gotKey := false
 
 		if badgerIter, ok := w.baseIter.(*BadgerIterator); ok {
 			key = badgerIter.it.Item().Key()
+			txn = badgerIter.txn
 			gotKey = true
 		} else if keyOnlyIter, ok := w.baseIter.(*KeyOnlyIterator); ok {
 			// KeyOnlyIterator embeds BadgerIterator
 			key = keyOnlyIter.it.Item().Key()
+			txn = keyOnlyIter.txn
 			gotKey = true
 		}
 
@@ -290,7 +291,7 @@ func (w *KeyMaskFilterWrapper) Next() bool {
 			w.datomsDecoded++
 
 			// Decode the datom
-			w.currentDatom, w.currentError = DatomFromKey(w.index, key, w.encoder)
+			w.currentDatom, w.currentError = datomFromKey(txn, w.index, key, w.encoder)
 			if w.currentError != nil {
 				continue
 			}
@@ -359,7 +360,7 @@ func (i *KeyMaskIterator) Next() bool {
 
 		// Only decode if the mask matches
 		i.datomsDecoded++
-		i.currentDatom, i.currentError = DatomFromKey(i.index, key, i.encoder)
+		i.currentDatom, i.currentError = datomFromKey(i.txn, i.index, key, i.encoder)
 
 		if i.currentError != nil {
 			continue
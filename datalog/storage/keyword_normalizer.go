@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// ErrKeywordRejected is returned by the predefined keyword normalizers for
+// attribute keywords they do not accept
+var ErrKeywordRejected = errors.New("attribute keyword rejected")
+
+// KeywordNormalizer maps an attribute keyword to its canonical form, or
+// returns an error to reject it. A database with a normalizer (see
+// SetKeywordNormalizer) applies it to the attributes of asserted and
+// retracted datoms, lookup refs and query patterns, so that a typo in an
+// attribute's case either finds the canonical attribute or fails, instead of
+// silently creating a parallel attribute.
+type KeywordNormalizer func(datalog.Keyword) (datalog.Keyword, error)
+
+// LowercaseKeywords normalizes attribute keywords to lower case, so that
+// :Person/Name and :person/name are the same attribute
+func LowercaseKeywords(kw datalog.Keyword) (datalog.Keyword, error) {
+	s := kw.String()
+	if lower := strings.ToLower(s); lower != s {
+		return datalog.NewKeyword(lower), nil
+	}
+	return kw, nil
+}
+
+// RejectUppercaseKeywords rejects attribute keywords containing upper case
+// letters, leaving the others unchanged
+func RejectUppercaseKeywords(kw datalog.Keyword) (datalog.Keyword, error) {
+	s := kw.String()
+	if strings.ToLower(s) != s {
+		return kw, fmt.Errorf("%w: %s is not lower case", ErrKeywordRejected, s)
+	}
+	return kw, nil
+}
+
+// SetKeywordNormalizer sets how attribute keywords are normalized on write
+// and query, or disables normalization (if nil). Set it before using the
+// database; datoms already stored are not rewritten.
+func (d *Database) SetKeywordNormalizer(normalizer KeywordNormalizer) {
+	d.keywordsMu.Lock()
+	defer d.keywordsMu.Unlock()
+	d.keywords = normalizer
+}
+
+// keywordNormalizer returns the database's keyword normalizer, nil if none
+func (d *Database) keywordNormalizer() KeywordNormalizer {
+	d.keywordsMu.RLock()
+	defer d.keywordsMu.RUnlock()
+	return d.keywords
+}
+
+// normalizeKeyword applies the database's keyword normalizer, if any
+func (d *Database) normalizeKeyword(kw datalog.Keyword) (datalog.Keyword, error) {
+	normalizer := d.keywordNormalizer()
+	if normalizer == nil {
+		return kw, nil
+	}
+	return normalizer(kw)
+}
+
+// normalizePattern returns the pattern with its attribute constant and
+// attribute set (see query.DataPattern.Attributes) in canonical form per the
+// matcher's keyword normalizer. A pattern needing no change is returned as
+// is; otherwise the pattern is copied, hints included, not modified. Index
+// pins are resolved on the plan's pattern before it reaches the matcher, so
+// they are kept either way.
+func (m *BadgerMatcher) normalizePattern(pattern *query.DataPattern) (*query.DataPattern, error) {
+	if m.keywords == nil || len(pattern.Elements) < 2 {
+		return pattern, nil
+	}
+
+	var attr datalog.Keyword
+	attrChanged := false
+	if c, ok := pattern.Elements[1].(query.Constant); ok {
+		if kw, ok := c.Value.(datalog.Keyword); ok {
+			normalized, err := m.keywords(kw)
+			if err != nil {
+				return nil, err
+			}
+			attr, attrChanged = normalized, normalized != kw
+		}
+	}
+
+	var attrs []datalog.Keyword
+	setChanged := false
+	if len(pattern.Attributes) > 0 {
+		seen := make(map[datalog.Keyword]bool, len(pattern.Attributes))
+		for _, kw := range pattern.Attributes {
+			normalized, err := m.keywords(kw)
+			if err != nil {
+				return nil, err
+			}
+			if normalized != kw {
+				setChanged = true
+			}
+			// Keywords that differ only in form are one attribute
+			if seen[normalized] {
+				setChanged = true
+				continue
+			}
+			seen[normalized] = true
+			attrs = append(attrs, normalized)
+		}
+	}
+
+	if !attrChanged && !setChanged {
+		return pattern, nil
+	}
+	normalized := pattern.Clone()
+	if attrChanged {
+		normalized.Elements[1] = query.Constant{Value: attr}
+	}
+	if setChanged {
+		normalized.Attributes = attrs
+	}
+	return normalized, nil
+}
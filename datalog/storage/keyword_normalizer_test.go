@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+func TestKeywordNormalizer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "keyword-normalizer-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("Lowercase", func(t *testing.T) {
+		db.SetKeywordNormalizer(LowercaseKeywords)
+
+		tx := db.NewTransaction()
+		alice := datalog.NewIdentity("person:alice")
+		tx.Add(alice, datalog.NewKeyword(":Person/Name"), "Alice")
+		tx.Add(alice, datalog.NewKeyword(":person/email"), "alice@example.com")
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+
+		for _, q := range []string{
+			`[:find ?n :where [_ :person/name ?n]]`,
+			`[:find ?n :where [_ :PERSON/name ?n]]`,
+			`[:find ?n :where [[:Person/Email "alice@example.com"] :person/Name ?n]]`,
+		} {
+			rows, err := db.ExecuteQuery(q)
+			if err != nil {
+				t.Fatalf("%s: query failed: %v", q, err)
+			}
+			if len(rows) != 1 || rows[0][0] != "Alice" {
+				t.Errorf("%s: expected Alice, got %v", q, rows)
+			}
+		}
+
+		// The attribute was stored in canonical form
+		db.SetKeywordNormalizer(nil)
+		rows, err := db.ExecuteQuery(`[:find ?n :where [_ :Person/Name ?n]]`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 0 {
+			t.Errorf("Expected no :Person/Name datoms, got %v", rows)
+		}
+	})
+
+	t.Run("RejectUppercase", func(t *testing.T) {
+		db.SetKeywordNormalizer(RejectUppercaseKeywords)
+		defer db.SetKeywordNormalizer(nil)
+
+		tx := db.NewTransaction()
+		defer tx.Rollback()
+		err := tx.Add(datalog.NewIdentity("person:bob"), datalog.NewKeyword(":person/Name"), "Bob")
+		if !errors.Is(err, ErrKeywordRejected) {
+			t.Errorf("Expected ErrKeywordRejected from Add, got %v", err)
+		}
+
+		_, err = db.ExecuteQuery(`[:find ?n :where [_ :person/Name ?n]]`)
+		if !errors.Is(err, ErrKeywordRejected) {
+			t.Errorf("Expected ErrKeywordRejected from the query, got %v", err)
+		}
+		if _, err := db.ExecuteQuery(`[:find ?n :where [_ :person/name ?n]]`); err != nil {
+			t.Errorf("Expected lower case query to succeed, got %v", err)
+		}
+	})
+}
+
+func TestNormalizePattern(t *testing.T) {
+	m := &BadgerMatcher{keywords: LowercaseKeywords}
+	name := datalog.NewKeyword(":person/name")
+	email := datalog.NewKeyword(":person/email")
+
+	t.Run("AttributeSet", func(t *testing.T) {
+		pattern := &query.DataPattern{
+			Elements: []query.PatternElement{
+				query.Variable{Name: "?e"},
+				query.Variable{Name: "?a"},
+				query.Variable{Name: "?v"},
+			},
+			MaxDatoms:  5,
+			Attributes: []datalog.Keyword{datalog.NewKeyword(":Person/Email"), email, name},
+		}
+		normalized, err := m.normalizePattern(pattern)
+		if err != nil {
+			t.Fatalf("normalizePattern failed: %v", err)
+		}
+		if len(normalized.Attributes) != 2 || normalized.Attributes[0] != email || normalized.Attributes[1] != name {
+			t.Errorf("Expected the set [:person/email :person/name], got %v", normalized.Attributes)
+		}
+		if normalized.MaxDatoms != 5 {
+			t.Errorf("Expected :max-datoms 5 to be kept, got %d", normalized.MaxDatoms)
+		}
+		if pattern.Attributes[0].String() != ":Person/Email" {
+			t.Errorf("Expected the original pattern unchanged, got %v", pattern.Attributes)
+		}
+	})
+
+	t.Run("Canonical", func(t *testing.T) {
+		pattern := &query.DataPattern{
+			Elements: []query.PatternElement{
+				query.Variable{Name: "?e"},
+				query.Constant{Value: name},
+				query.Variable{Name: "?v"},
+			},
+			Attributes: []datalog.Keyword{name},
+		}
+		normalized, err := m.normalizePattern(pattern)
+		if err != nil {
+			t.Fatalf("normalizePattern failed: %v", err)
+		}
+		if normalized != pattern {
+			t.Error("Expected a pattern already in canonical form to be returned as is")
+		}
+	})
+}
+
+func TestKeywordNormalizerPinnedIndex(t *testing.T) {
+	dir, err := os.MkdirTemp("", "keyword-normalizer-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	db.SetKeywordNormalizer(LowercaseKeywords)
+
+	tx := db.NewTransaction()
+	for i := 0; i < 10; i++ {
+		tx.Add(datalog.NewIdentity(fmt.Sprintf("person:%d", i)), datalog.NewKeyword(":person/age"), int64(20+i%2))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?e :where [?e :Person/Age 21]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	exec := db.NewExecutor()
+	plan, err := exec.PlanQuery(q)
+	if err != nil {
+		t.Fatalf("PlanQuery failed: %v", err)
+	}
+	for i := range plan.Phases {
+		for _, pattern := range plan.Phases[i].Patterns() {
+			if err := plan.PinIndex(i, pattern, planner.AEVT); err != nil {
+				t.Fatalf("PinIndex failed: %v", err)
+			}
+		}
+	}
+
+	result, err := exec.ExecuteRealized(executor.NewContext(nil), plan, nil)
+	if err != nil {
+		t.Fatalf("ExecuteRealized failed: %v", err)
+	}
+	if result == nil {
+		t.Fatal("Expected people aged 21 through the pinned index, got no result")
+	}
+	count := 0
+	it := result.Iterator()
+	for it.Next() {
+		count++
+	}
+	it.Close()
+	if count != 5 {
+		t.Errorf("Expected 5 people aged 21 through the pinned index, got %d", count)
+	}
+}
+
+func TestSetKeywordNormalizerConcurrently(t *testing.T) {
+	dir, err := os.MkdirTemp("", "keyword-normalizer-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if i%2 == 0 {
+				db.SetKeywordNormalizer(LowercaseKeywords)
+			} else {
+				db.SetKeywordNormalizer(nil)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := db.ExecuteQuery(`[:find ?n :where [_ :person/name ?n]]`); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+	wg.Wait()
+}
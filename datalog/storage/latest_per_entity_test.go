@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+// TestLatestPerEntityScan checks the latest close per symbol computed from an
+// AVET scan of the bar times against the subquery per symbol
+func TestLatestPerEntityScan(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	base := time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)
+	tx := db.NewTransaction()
+	for s := 0; s < 5; s++ {
+		sym := datalog.NewIdentity(fmt.Sprintf("sym:%d", s))
+		tx.Add(sym, datalog.NewKeyword(":symbol/ticker"), fmt.Sprintf("T%d", s))
+		for i := 0; i < 50; i++ {
+			bar := datalog.NewIdentity(fmt.Sprintf("bar:%d:%d", s, i))
+			tx.Add(bar, datalog.NewKeyword(":price/symbol"), sym)
+			tx.Add(bar, datalog.NewKeyword(":price/time"), base.Add(time.Duration(i*5+s)*time.Minute))
+			tx.Add(bar, datalog.NewKeyword(":price/close"), float64(100*s+i))
+		}
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?ticker ?t ?c
+	  :where [?s :symbol/ticker ?ticker]
+	         [?b :price/symbol ?s]
+	         [?b :price/time ?t]
+	         [(q [:find (max ?time) :in $ ?sym
+	              :where [?bar :price/symbol ?sym] [?bar :price/time ?time]] $ ?s) [[?t]]]
+	         [?b :price/close ?c]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	opts := DefaultPlannerOptions()
+	opts.EnableLatestPerEntity = false
+	expected, err := db.NewExecutorWithOptions(opts).Execute(q)
+	if err != nil {
+		t.Fatalf("Subquery form failed: %v", err)
+	}
+
+	var scanEvent *annotations.Event
+	handler := func(event annotations.Event) {
+		if event.Name == "latest_per_entity/scan" {
+			scanEvent = &event
+		}
+	}
+	result, err := db.NewExecutor().ExecuteWithContext(executor.NewContext(handler), q)
+	if err != nil {
+		t.Fatalf("Latest per entity scan failed: %v", err)
+	}
+
+	if scanEvent == nil {
+		t.Fatal("Expected the query to run as a latest per entity scan")
+	}
+	if scanned, _ := scanEvent.Data["scanned"].(int); scanned >= 250 {
+		t.Errorf("Expected the scan to stop before reading all 250 bars, read %d", scanned)
+	}
+
+	want, got := fmt.Sprint(expected.Sorted()), fmt.Sprint(result.Sorted())
+	if expected.Size() != 5 || got != want {
+		t.Errorf("Results differ:\nwant %s\ngot  %s", want, got)
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// ErrLookupRefNotFound is returned when no entity holds a lookup ref's value
+var ErrLookupRefNotFound = errors.New("lookup ref not found")
+
+// ErrLookupRefAmbiguous is returned when several entities hold a lookup ref's
+// value, so the attribute does not identify an entity
+var ErrLookupRefAmbiguous = errors.New("lookup ref is ambiguous")
+
+// ResolveLookupRef returns the entity holding the lookup ref's attribute
+// value. It fails unless exactly one entity holds it.
+func (d *Database) ResolveLookupRef(ref datalog.LookupRef) (datalog.Identity, error) {
+	m := NewBadgerMatcher(d.store)
+	m.keywords = d.keywordNormalizer()
+	entities, err := m.lookupEntities(ref)
+	if err != nil {
+		return datalog.Identity{}, err
+	}
+	switch len(entities) {
+	case 0:
+		return datalog.Identity{}, fmt.Errorf("%w: %s", ErrLookupRefNotFound, ref)
+	case 1:
+		return entities[0], nil
+	default:
+		return datalog.Identity{}, fmt.Errorf("%w: %s is held by %d entities", ErrLookupRefAmbiguous, ref, len(entities))
+	}
+}
+
+// ResolveEntity returns the Identity of an entity ref, resolving lookup refs
+func (d *Database) ResolveEntity(e datalog.EntityRef) (datalog.Identity, error) {
+	switch ref := e.(type) {
+	case datalog.Identity:
+		return ref, nil
+	case *datalog.Identity:
+		return *ref, nil
+	case datalog.LookupRef:
+		return d.ResolveLookupRef(ref)
+	default:
+		return datalog.Identity{}, fmt.Errorf("unsupported entity ref %T", e)
+	}
+}
+
+// resolveInput resolves lookup refs among query input values; other values
+// are returned unchanged
+func (d *Database) resolveInput(v interface{}) (interface{}, error) {
+	if ref, ok := v.(datalog.LookupRef); ok {
+		return d.ResolveLookupRef(ref)
+	}
+	return v, nil
+}
+
+// lookupEntities returns the distinct entities holding the lookup ref's
+// value, scanning AVET as of the matcher's transaction
+func (m *BadgerMatcher) lookupEntities(ref datalog.LookupRef) ([]datalog.Identity, error) {
+	pattern, err := m.normalizePattern(&query.DataPattern{Elements: []query.PatternElement{
+		query.Blank{},
+		query.Constant{Value: ref.Attr},
+		query.Constant{Value: ref.Value},
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("lookup ref %s: %w", ref, err)
+	}
+	datoms, err := m.matchBoundPattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("lookup ref %s: %w", ref, err)
+	}
+
+	var entities []datalog.Identity
+	seen := make(map[[20]byte]bool)
+	for _, d := range datoms {
+		if hash := d.E.Hash(); !seen[hash] {
+			seen[hash] = true
+			entities = append(entities, d.E)
+		}
+	}
+	return entities, nil
+}
+
+// patternLookupRef returns the lookup ref in the pattern's entity position
+func patternLookupRef(pattern *query.DataPattern) (datalog.LookupRef, bool) {
+	if c, ok := pattern.GetE().(query.Constant); ok {
+		ref, ok := c.Value.(datalog.LookupRef)
+		return ref, ok
+	}
+	return datalog.LookupRef{}, false
+}
+
+// matchWithLookupRef resolves the lookup ref in the pattern's entity position
+// and matches the resolved pattern. A lookup ref that resolves to no entity
+// matches nothing, like an entity id with no datoms; an ambiguous one is an
+// error.
+func (m *BadgerMatcher) matchWithLookupRef(pattern *query.DataPattern, ref datalog.LookupRef, match func(*query.DataPattern) (executor.Relation, error)) (executor.Relation, error) {
+	entities, err := m.lookupEntities(ref)
+	if err != nil {
+		return nil, err
+	}
+	switch len(entities) {
+	case 0:
+		return executor.NewMaterializedRelationWithOptions(pattern.ExtractColumns(), nil, m.options), nil
+	case 1:
+		elements := make([]query.PatternElement, len(pattern.Elements))
+		copy(elements, pattern.Elements)
+		elements[0] = query.Constant{Value: entities[0]}
+		return match(&query.DataPattern{Elements: elements, MaxDatoms: pattern.MaxDatoms})
+	default:
+		return nil, fmt.Errorf("%w: %s is held by %d entities", ErrLookupRefAmbiguous, ref, len(entities))
+	}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+func TestLookupRefs(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	email := datalog.NewKeyword(":person/email")
+	name := datalog.NewKeyword(":person/name")
+	team := datalog.NewKeyword(":person/team")
+
+	tx := db.NewTransaction()
+	for i, n := range []string{"alice", "bob", "carol"} {
+		e := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		tx.Add(e, email, n+"@example.com")
+		tx.Add(e, name, n)
+		tx.Add(e, team, "core")
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	t.Run("resolve", func(t *testing.T) {
+		id, err := db.ResolveLookupRef(datalog.NewLookupRef(":person/email", "bob@example.com"))
+		if err != nil {
+			t.Fatalf("ResolveLookupRef failed: %v", err)
+		}
+		if !id.Equal(datalog.NewIdentity("person:1")) {
+			t.Errorf("Resolved to %v, expected person:1", id)
+		}
+
+		_, err = db.ResolveLookupRef(datalog.NewLookupRef(":person/email", "dave@example.com"))
+		if !errors.Is(err, ErrLookupRefNotFound) {
+			t.Errorf("Expected ErrLookupRefNotFound, got %v", err)
+		}
+		_, err = db.ResolveLookupRef(datalog.NewLookupRef(":person/team", "core"))
+		if !errors.Is(err, ErrLookupRefAmbiguous) {
+			t.Errorf("Expected ErrLookupRefAmbiguous, got %v", err)
+		}
+	})
+
+	for _, useQueryExecutor := range []bool{false, true} {
+		t.Run(fmt.Sprintf("pattern/queryExecutor=%v", useQueryExecutor), func(t *testing.T) {
+			exec := db.NewExecutor()
+			exec.SetUseQueryExecutor(useQueryExecutor)
+			run := func(queryStr string) ([][]interface{}, error) {
+				q, err := parser.ParseQuery(queryStr)
+				if err != nil {
+					t.Fatalf("Failed to parse query: %v", err)
+				}
+				result, err := exec.ExecuteWithContext(executor.NewContext(nil), q)
+				if err != nil {
+					return nil, err
+				}
+				return relationToSlice(result)
+			}
+
+			rows, err := run(`[:find ?name :where [[:person/email "carol@example.com"] :person/name ?name]]`)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(rows) != 1 || rows[0][0] != "carol" {
+				t.Errorf("Expected [[carol]], got %v", rows)
+			}
+
+			rows, err = run(`[:find ?name :where [[:person/email "dave@example.com"] :person/name ?name]]`)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(rows) != 0 {
+				t.Errorf("Expected no results for an unknown lookup ref, got %v", rows)
+			}
+
+			if _, err = run(`[:find ?name :where [[:person/team "core"] :person/name ?name]]`); !errors.Is(err, ErrLookupRefAmbiguous) {
+				t.Errorf("Expected ErrLookupRefAmbiguous, got %v", err)
+			}
+		})
+	}
+
+	t.Run("inputs", func(t *testing.T) {
+		rows, err := db.ExecuteQueryWithInputs(`[:find ?name :in $ [?e ...] :where [?e :person/name ?name]]`,
+			[]datalog.LookupRef{
+				datalog.NewLookupRef(":person/email", "alice@example.com"),
+				datalog.NewLookupRef(":person/email", "bob@example.com"),
+			})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 2 {
+			t.Errorf("Expected 2 results, got %v", rows)
+		}
+
+		_, err = db.ExecuteQueryWithInputs(`[:find ?name :in $ ?e :where [?e :person/name ?name]]`,
+			datalog.NewLookupRef(":person/email", "dave@example.com"))
+		if !errors.Is(err, ErrLookupRefNotFound) {
+			t.Errorf("Expected ErrLookupRefNotFound, got %v", err)
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		tx := db.NewTransaction()
+		if err := tx.Add(datalog.NewLookupRef(":person/email", "alice@example.com"), team, "research"); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if err := tx.Add(datalog.NewLookupRef(":person/email", "dave@example.com"), team, "research"); !errors.Is(err, ErrLookupRefNotFound) {
+			t.Errorf("Expected ErrLookupRefNotFound, got %v", err)
+		}
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		rows, err := db.ExecuteQuery(`[:find ?name :where [?e :person/team "research"] [?e :person/name ?name]]`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 1 || rows[0][0] != "alice" {
+			t.Errorf("Expected [[alice]], got %v", rows)
+		}
+	})
+}
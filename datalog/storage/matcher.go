@@ -3,6 +3,7 @@ package storage
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/annotations"
@@ -21,6 +22,8 @@ type BadgerMatcher struct {
 	handler          annotations.Handler      // Set from HandlerProvider for detailed storage events
 	options          executor.ExecutorOptions // Options for creating relations
 	forceJoinStrategy *JoinStrategy           // Override join strategy selection for testing
+	scanned          *atomic.Int64            // Datoms read by closed scans, if counting (see WithScanCounter)
+	keywords         KeywordNormalizer        // Applied to pattern attributes (nil = as written)
 }
 
 // NewBadgerMatcher creates a new pattern matcher for the BadgerStore
@@ -59,9 +62,49 @@ func (m *BadgerMatcher) AsOf(txID uint64) *BadgerMatcher {
 		builderCache: m.builderCache,
 		handler:      m.handler,
 		options:      m.options, // Preserve options
+		scanned:      m.scanned,
+		keywords:     m.keywords,
 	}
 }
 
+// WithScanCounter returns a matcher like m that adds the datoms read by each
+// of its scans to counter when the scan is closed
+func (m *BadgerMatcher) WithScanCounter(counter *atomic.Int64) executor.PatternMatcher {
+	counting := m.AsOf(m.txID)
+	counting.forceJoinStrategy = m.forceJoinStrategy
+	counting.scanned = counter
+	return counting
+}
+
+// BasisTx returns the latest transaction the matcher sees: the latest
+// committed one, or for an as-of matcher the latest at or before its
+// transaction. It is 0 for an empty database.
+func (m *BadgerMatcher) BasisTx() (uint64, error) {
+	var below uint64
+	if m.txID > 0 {
+		below = m.txID + 1
+	}
+	latest, _, err := m.store.latestTxID(below)
+	return latest, err
+}
+
+// scanKeysOnly opens a key-only scan, counted if the matcher counts scans
+func (m *BadgerMatcher) scanKeysOnly(index IndexType, start, end []byte) (Iterator, error) {
+	return m.countScan(m.store.ScanKeysOnly(index, start, end))
+}
+
+// scanKeysOnlyReverse is scanKeysOnly in descending key order
+func (m *BadgerMatcher) scanKeysOnlyReverse(index IndexType, start, end []byte) (Iterator, error) {
+	return m.countScan(m.store.ScanKeysOnlyReverse(index, start, end))
+}
+
+func (m *BadgerMatcher) countScan(it Iterator, err error) (Iterator, error) {
+	if err != nil || m.scanned == nil {
+		return it, err
+	}
+	return &countingIterator{Iterator: it, counter: m.scanned}, nil
+}
+
 // SetHandler configures the handler for detailed storage events.
 // This is called by WrapMatcher during construction.
 func (m *BadgerMatcher) SetHandler(handler annotations.Handler) {
@@ -108,7 +151,7 @@ func (m *BadgerMatcher) ForceJoinStrategy(strategy *JoinStrategy) {
 // bindPattern creates a new pattern with variables replaced by tuple values
 func (m *BadgerMatcher) bindPattern(pattern *query.DataPattern, tuple executor.Tuple, rel executor.Relation) *query.DataPattern {
 	// Get symbol positions in the relation
-	symbols := rel.Columns()
+	symbols := rel.Symbols()
 	symbolIndex := make(map[query.Symbol]int)
 	for i, sym := range symbols {
 		symbolIndex[sym] = i
@@ -194,7 +237,7 @@ func (m *BadgerMatcher) matchBoundPattern(pattern *query.DataPattern) ([]datalog
 
 	// Use key-only scanning since all datom information is encoded in the key
 	// This avoids fetching redundant values from storage
-	iter, err := m.store.ScanKeysOnly(index, start, end)
+	iter, err := m.scanKeysOnly(index, start, end)
 	if err != nil {
 		return nil, fmt.Errorf("scan failed: %w", err)
 	}
@@ -252,7 +295,7 @@ func (m *BadgerMatcher) scanTimeRanges(attr datalog.Keyword, tx interface{}) ([]
 		end := encoder.EncodePrefix(AVET, aStorage[:], endValue)
 
 		// Scan this range
-		iter, err := m.store.ScanKeysOnly(AVET, start, end)
+		iter, err := m.scanKeysOnly(AVET, start, end)
 		if err != nil {
 			return nil, fmt.Errorf("time range scan failed: %w", err)
 		}
@@ -327,6 +370,11 @@ func (m *BadgerMatcher) chooseIndex(e, a, v, tx interface{}) (IndexType, []byte,
 	// 3. AVET - if A and V are bound but not E
 	// 4. VAET - if V is bound but not E or A
 	// 5. TAEV - if only Tx is bound
+
+	// A bound value is found under the form its keys hold
+	if v != nil {
+		v = m.store.indexedValue(v)
+	}
 	// 6. EAVT - full scan if nothing is bound
 
 	encoder := m.store.encoder
@@ -391,18 +439,17 @@ func (m *BadgerMatcher) chooseIndex(e, a, v, tx interface{}) (IndexType, []byte,
 				// Get value bytes with type prefix
 				// Must match how EncodeKey encodes values!
 				sDatom := ToStorageDatom(*dummyDatom)
-				vType := byte(datalog.Type(sDatom.V))
+				vType, vData := storedValue(sDatom.V)
 				var valueBytes []byte
 
 				// Check if we're using L85 encoding and have a reference value
 				if _, isL85 := encoder.(*L85KeyEncoder); isL85 && vType == byte(datalog.TypeReference) {
 					// L85 encoder stores references as type + L85-encoded bytes
 					var vArr [20]byte
-					copy(vArr[:], datalog.ValueBytes(sDatom.V))
+					copy(vArr[:], vData)
 					valueBytes = append([]byte{vType}, []byte(codec.EncodeFixed20(vArr))...)
 				} else {
 					// Binary encoder or non-reference values: type + raw bytes
-					vData := datalog.ValueBytes(sDatom.V)
 					valueBytes = append([]byte{vType}, vData...)
 				}
 
@@ -424,18 +471,17 @@ func (m *BadgerMatcher) chooseIndex(e, a, v, tx interface{}) (IndexType, []byte,
 			Tx: 0,
 		}
 		sDatom := ToStorageDatom(*dummyDatom)
-		vType := byte(datalog.Type(sDatom.V))
+		vType, vData := storedValue(sDatom.V)
 		var valueBytes []byte
 
 		// Check if we're using L85 encoding and have a reference value
 		if _, isL85 := encoder.(*L85KeyEncoder); isL85 && vType == byte(datalog.TypeReference) {
 			// L85 encoder stores references as type + L85-encoded bytes
 			var vArr [20]byte
-			copy(vArr[:], datalog.ValueBytes(sDatom.V))
+			copy(vArr[:], vData)
 			valueBytes = append([]byte{vType}, []byte(codec.EncodeFixed20(vArr))...)
 		} else {
 			// Binary encoder or non-reference values: type + raw bytes
-			vData := datalog.ValueBytes(sDatom.V)
 			valueBytes = append([]byte{vType}, vData...)
 		}
 
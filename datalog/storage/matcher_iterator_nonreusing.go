@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/wbrown/janus-datalog/datalog"
 	"github.com/wbrown/janus-datalog/datalog/executor"
 	"github.com/wbrown/janus-datalog/datalog/query"
@@ -19,6 +21,7 @@ type nonReusingIterator struct {
 	currentIdx   int
 	currentScan  Iterator
 	currentTuple executor.Tuple
+	err          error
 	totalScanned int
 	totalMatched int
 
@@ -30,12 +33,17 @@ type nonReusingIterator struct {
 }
 
 func (it *nonReusingIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
 	// If we have a current scan, check for more results
 	if it.currentScan != nil {
 		for it.currentScan.Next() {
 			datom, err := it.currentScan.Datom()
 			if err != nil {
-				continue
+				it.err = fmt.Errorf("scan of %s failed: %w", it.pattern, err)
+				return false
 			}
 
 			it.totalScanned++
@@ -70,8 +78,9 @@ func (it *nonReusingIterator) Next() bool {
 	index, start, end := it.matcher.chooseIndex(e, a, v, tx)
 
 	var err error
-	it.currentScan, err = it.matcher.store.ScanKeysOnly(index, start, end)
+	it.currentScan, err = it.matcher.scanKeysOnly(index, start, end)
 	if err != nil {
+		it.err = fmt.Errorf("failed to open scan for %s: %w", it.pattern, err)
 		return false
 	}
 
@@ -83,6 +92,10 @@ func (it *nonReusingIterator) Tuple() executor.Tuple {
 	return it.currentTuple
 }
 
+func (it *nonReusingIterator) Err() error {
+	return it.err
+}
+
 func (it *nonReusingIterator) Close() error {
 	if it.currentScan != nil {
 		return it.currentScan.Close()
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
 	"github.com/wbrown/janus-datalog/datalog/executor"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
@@ -21,6 +24,7 @@ type reusingIterator struct {
 	storageIter  Iterator       // The BadgerDB iterator we're reusing
 	currentIdx   int            // Current tuple index
 	currentTuple executor.Tuple // Current result tuple
+	err          error          // Error that ended the scan early
 
 	// Cached bound values for current tuple to avoid recreating pattern
 	currentE, currentA, currentV, currentTx interface{}
@@ -39,6 +43,9 @@ type reusingIterator struct {
 func (it *reusingIterator) Next() bool {
 	// NOTE: We removed the foundForTuple logic because it was wrong!
 	// We need to find ALL matches for each binding value, not just one.
+	if it.err != nil {
+		return false
+	}
 
 	// First call - initialize
 	if it.currentIdx < 0 {
@@ -63,8 +70,9 @@ func (it *reusingIterator) Next() bool {
 		endKey = append(endKey, 0xFF, 0xFF, 0xFF, 0xFF)
 
 		var err error
-		it.storageIter, err = it.matcher.store.ScanKeysOnly(it.index, startKey, endKey)
+		it.storageIter, err = it.matcher.scanKeysOnly(it.index, startKey, endKey)
 		if err != nil {
+			it.err = fmt.Errorf("failed to open scan for %s: %w", it.pattern, err)
 			return false
 		}
 
@@ -83,8 +91,8 @@ func (it *reusingIterator) Next() bool {
 			for hasNext {
 				datom, err := it.storageIter.Datom()
 				if err != nil {
-					hasNext = it.storageIter.Next()
-					continue
+					it.err = fmt.Errorf("scan of %s failed: %w", it.pattern, err)
+					return false
 				}
 
 				// Track datom scan
@@ -217,18 +225,22 @@ func (it *reusingIterator) Tuple() executor.Tuple {
 	return it.currentTuple
 }
 
+func (it *reusingIterator) Err() error {
+	return it.err
+}
+
 func (it *reusingIterator) Close() error {
 	// Emit scan statistics if handler is available
 	emitIteratorStatistics(
 		it.matcher.handler,
 		"pattern/iterator-reuse-complete",
-		it.pattern,
-		it.index,
-		it.datomsScanned,
-		it.datomsMatched,
-		map[string]interface{}{
-			"binding.size": len(it.tuples),
-			"strategy":     "iterator-reuse",
+		annotations.PatternScanEvent{
+			Pattern:       it.pattern.String(),
+			Index:         indexName(it.index),
+			DatomsScanned: it.datomsScanned,
+			DatomsMatched: it.datomsMatched,
+			BindingSize:   len(it.tuples),
+			Strategy:      "iterator-reuse",
 		},
 	)
 
@@ -240,7 +252,7 @@ func (it *reusingIterator) Close() error {
 
 // getColumnIndex returns the index of a symbol in the binding relation columns
 func (it *reusingIterator) getColumnIndex(variable query.Variable) int {
-	columns := it.bindingRel.Columns()
+	columns := it.bindingRel.Symbols()
 	for i, col := range columns {
 		if col == variable.Name {
 			return i
@@ -262,7 +274,7 @@ func (it *reusingIterator) updateBoundPattern(bindingTuple executor.Tuple) {
 // calculateSeekKey calculates the key to seek to based on binding tuple and position
 func (it *reusingIterator) calculateSeekKey(bindingTuple executor.Tuple) ([]byte, []byte) {
 	// Get column mapping
-	columns := it.bindingRel.Columns()
+	columns := it.bindingRel.Symbols()
 	colIndex := make(map[query.Symbol]int)
 	for i, col := range columns {
 		colIndex[col] = i
@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"fmt"
+
+	"github.com/wbrown/janus-datalog/datalog/annotations"
 	"github.com/wbrown/janus-datalog/datalog/executor"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
@@ -17,6 +20,7 @@ type unboundIterator struct {
 
 	storageIter  Iterator
 	currentTuple executor.Tuple
+	err          error
 
 	// Statistics tracking
 	datomsScanned int
@@ -30,7 +34,8 @@ func (it *unboundIterator) Next() bool {
 	for it.storageIter.Next() {
 		datom, err := it.storageIter.Datom()
 		if err != nil {
-			continue
+			it.err = fmt.Errorf("scan of %s failed: %w", it.pattern, err)
+			return false
 		}
 
 		it.datomsScanned++
@@ -55,16 +60,21 @@ func (it *unboundIterator) Tuple() executor.Tuple {
 	return it.currentTuple
 }
 
+func (it *unboundIterator) Err() error {
+	return it.err
+}
+
 func (it *unboundIterator) Close() error {
 	// Emit scan statistics if handler is available
 	emitIteratorStatistics(
 		it.matcher.handler,
-		"pattern/storage-scan",
-		it.pattern,
-		it.index,
-		it.datomsScanned,
-		it.datomsMatched,
-		nil, // no extra data
+		annotations.PatternStorageScan,
+		annotations.PatternScanEvent{
+			Pattern:       it.pattern.String(),
+			Index:         indexName(it.index),
+			DatomsScanned: it.datomsScanned,
+			DatomsMatched: it.datomsMatched,
+		},
 	)
 
 	if it.storageIter != nil {
@@ -86,6 +96,7 @@ type unboundMaskIterator struct {
 
 	storageIter  Iterator
 	currentTuple executor.Tuple
+	err          error
 
 	// Statistics tracking
 	datomsScanned int
@@ -101,7 +112,8 @@ func (it *unboundMaskIterator) Next() bool {
 	for it.storageIter.Next() {
 		datom, err := it.storageIter.Datom()
 		if err != nil {
-			continue
+			it.err = fmt.Errorf("scan of %s failed: %w", it.pattern, err)
+			return false
 		}
 
 		it.datomsScanned++
@@ -136,18 +148,22 @@ func (it *unboundMaskIterator) Tuple() executor.Tuple {
 	return it.currentTuple
 }
 
+func (it *unboundMaskIterator) Err() error {
+	return it.err
+}
+
 func (it *unboundMaskIterator) Close() error {
 	// Emit scan statistics if handler is available
 	emitIteratorStatistics(
 		it.matcher.handler,
-		"pattern/storage-scan",
-		it.pattern,
-		it.index,
-		it.datomsScanned,
-		it.datomsMatched,
-		map[string]interface{}{
-			"keys.filtered": it.keysFiltered,
-			"filter.type":   "key-mask",
+		annotations.PatternStorageScan,
+		annotations.PatternScanEvent{
+			Pattern:       it.pattern.String(),
+			Index:         indexName(it.index),
+			DatomsScanned: it.datomsScanned,
+			DatomsMatched: it.datomsMatched,
+			KeysFiltered:  it.keysFiltered,
+			FilterType:    "key-mask",
 		},
 	)
 
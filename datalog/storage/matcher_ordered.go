@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// Ensure BadgerMatcher implements executor.OrderedMatcher
+var _ executor.OrderedMatcher = (*BadgerMatcher)(nil)
+
+// orderedRange is an AVET key range scanned in one direction
+type orderedRange struct {
+	start, end []byte
+	reverse    bool
+}
+
+// MatchOrdered implements executor.OrderedMatcher by scanning the AVET index
+// of the pattern's attribute.
+//
+// AVET keys sort by the binary value encoding, which matches value order only
+// within one type and sign: negative integers and times sort after positive
+// ones, and negative floats sort after positive ones in reverse. The scan is
+// therefore split into ranges visited in value order. Attributes holding more
+// than one value type, or types whose encoding does not preserve order
+// (strings, keywords, references, bytes), are not supported.
+func (m *BadgerMatcher) MatchOrdered(pattern *query.DataPattern, descending bool) (executor.Relation, error) {
+	if _, ok := m.store.encoder.(*BinaryKeyEncoder); !ok {
+		return nil, executor.ErrOrderedScanUnsupported
+	}
+	pattern, err := m.normalizePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	attr, ok := m.extractValue(pattern.GetA()).(datalog.Keyword)
+	if !ok || m.extractValue(pattern.GetE()) != nil || m.extractValue(pattern.GetV()) != nil {
+		return nil, executor.ErrOrderedScanUnsupported
+	}
+	var tx interface{}
+	if elem := pattern.GetT(); elem != nil {
+		tx = m.extractValue(elem)
+	}
+
+	aStorage := ToStorageDatom(datalog.Datom{A: attr}).A
+	prefix := m.store.encoder.EncodePrefix(AVET, aStorage[:])
+
+	vType, found, err := m.attributeValueType(prefix)
+	if err != nil {
+		return nil, err
+	}
+	columns := pattern.ExtractColumns()
+	if !found {
+		return executor.NewMaterializedRelationWithOptions(columns, nil, m.options), nil
+	}
+
+	ranges, ok := orderedValueRanges(prefix, vType)
+	if !ok {
+		return nil, executor.ErrOrderedScanUnsupported
+	}
+	if descending {
+		for i, j := 0, len(ranges)-1; i < j; i, j = i+1, j-1 {
+			ranges[i], ranges[j] = ranges[j], ranges[i]
+		}
+		for i := range ranges {
+			ranges[i].reverse = !ranges[i].reverse
+		}
+	}
+
+	if m.handler != nil {
+		m.handler(annotations.NewEvent(annotations.PatternIndexSelection, annotations.IndexSelectionEvent{
+			Pattern: pattern.String(),
+			Index:   indexName(AVET),
+		}))
+	}
+
+	iters := make([]*unboundIterator, 0, len(ranges))
+	for _, r := range ranges {
+		var storageIter Iterator
+		if r.reverse {
+			storageIter, err = m.scanKeysOnlyReverse(AVET, r.start, r.end)
+		} else {
+			storageIter, err = m.scanKeysOnly(AVET, r.start, r.end)
+		}
+		if err != nil {
+			for _, it := range iters {
+				it.Close()
+			}
+			return nil, fmt.Errorf("ordered scan failed: %w", err)
+		}
+		iters = append(iters, &unboundIterator{
+			matcher:      m,
+			index:        AVET,
+			start:        r.start,
+			end:          r.end,
+			pattern:      pattern,
+			columns:      columns,
+			a:            attr,
+			tx:           tx,
+			storageIter:  storageIter,
+			tupleBuilder: m.getTupleBuilder(pattern, columns),
+		})
+	}
+
+	return executor.NewStreamingRelationWithOptions(columns, &orderedIterator{iters: iters}, m.options), nil
+}
+
+// attributeValueType returns the value type stored under an AVET attribute
+// prefix. It fails with executor.ErrOrderedScanUnsupported if the attribute
+// holds values of more than one type.
+func (m *BadgerMatcher) attributeValueType(prefix []byte) (datalog.ValueType, bool, error) {
+	var vType datalog.ValueType
+	found := false
+	for t := datalog.TypeString; t <= datalog.TypeKeyword; t++ {
+		start := append(append([]byte{}, prefix...), byte(t))
+		end := append(append([]byte{}, prefix...), byte(t)+1)
+
+		it, err := m.scanKeysOnly(AVET, start, end)
+		if err != nil {
+			return 0, false, fmt.Errorf("ordered scan failed: %w", err)
+		}
+		present := it.Next()
+		it.Close()
+
+		if present {
+			if found {
+				return 0, false, executor.ErrOrderedScanUnsupported
+			}
+			vType, found = t, true
+		}
+	}
+	return vType, found, nil
+}
+
+// orderedValueRanges returns the AVET ranges under an attribute prefix that
+// yield values of type t in ascending order
+func orderedValueRanges(prefix []byte, t datalog.ValueType) ([]orderedRange, bool) {
+	key := func(parts ...byte) []byte {
+		return append(append([]byte{}, prefix...), parts...)
+	}
+	typeStart := key(byte(t))
+	signBit := key(byte(t), 0x80)
+	typeEnd := key(byte(t) + 1)
+
+	switch t {
+	case datalog.TypeInt, datalog.TypeTime:
+		// Two's complement: negatives have the sign bit set
+		return []orderedRange{
+			{start: signBit, end: typeEnd},
+			{start: typeStart, end: signBit},
+		}, true
+	case datalog.TypeFloat:
+		// IEEE 754: negatives have the sign bit set and larger magnitudes
+		// have larger bits, so they are scanned in reverse
+		return []orderedRange{
+			{start: signBit, end: typeEnd, reverse: true},
+			{start: typeStart, end: signBit},
+		}, true
+	case datalog.TypeBool:
+		return []orderedRange{{start: typeStart, end: typeEnd}}, true
+	default:
+		return nil, false
+	}
+}
+
+// orderedIterator streams the tuples of consecutive range iterators
+type orderedIterator struct {
+	iters []*unboundIterator
+	pos   int
+}
+
+func (it *orderedIterator) Next() bool {
+	for it.pos < len(it.iters) {
+		if it.iters[it.pos].Next() {
+			return true
+		}
+		if it.iters[it.pos].Err() != nil {
+			return false
+		}
+		it.pos++
+	}
+	return false
+}
+
+func (it *orderedIterator) Err() error {
+	if it.pos < len(it.iters) {
+		return it.iters[it.pos].Err()
+	}
+	return nil
+}
+
+func (it *orderedIterator) Tuple() executor.Tuple {
+	return it.iters[it.pos].Tuple()
+}
+
+func (it *orderedIterator) Close() error {
+	var firstErr error
+	for _, iter := range it.iters {
+		if err := iter.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/wbrown/janus-datalog/datalog/annotations"
 	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/planner"
 	"github.com/wbrown/janus-datalog/datalog/query"
 )
 
@@ -37,6 +38,9 @@ var _ executor.PatternMatcher = (*BadgerMatcher)(nil)
 // Ensure BadgerMatcher implements executor.PredicateAwareMatcher
 var _ executor.PredicateAwareMatcher = (*BadgerMatcher)(nil)
 
+// Ensure BadgerMatcher implements executor.IndexPinnedMatcher
+var _ executor.IndexPinnedMatcher = (*BadgerMatcher)(nil)
+
 // Match implements PatternMatcher.Match - returns a Relation directly
 func (m *BadgerMatcher) Match(pattern *query.DataPattern, bindings executor.Relations) (executor.Relation, error) {
 	// Default implementation with no constraints
@@ -49,6 +53,16 @@ func (m *BadgerMatcher) MatchWithConstraints(
 	bindings executor.Relations,
 	constraints []executor.StorageConstraint,
 ) (executor.Relation, error) {
+	pattern, err := m.normalizePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if ref, ok := patternLookupRef(pattern); ok {
+		return m.matchWithLookupRef(pattern, ref, func(resolved *query.DataPattern) (executor.Relation, error) {
+			return m.MatchWithConstraints(resolved, bindings, constraints)
+		})
+	}
+
 	// Determine pattern columns
 	columns := pattern.ExtractColumns()
 
@@ -139,8 +153,34 @@ func (m *BadgerMatcher) MatchWithConstraints(
 	}
 }
 
+// MatchWithIndex implements executor.IndexPinnedMatcher - scans the pattern
+// without bindings using the given index instead of the automatically chosen one.
+// The scan range is narrowed by whatever pattern constants form a prefix of the index.
+func (m *BadgerMatcher) MatchWithIndex(pattern *query.DataPattern, index planner.IndexType) (executor.Relation, error) {
+	pinned := IndexType(index)
+	if pinned > TAEV {
+		return nil, fmt.Errorf("invalid index for pattern %s: %d", pattern, index)
+	}
+	pattern, err := m.normalizePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if ref, ok := patternLookupRef(pattern); ok {
+		return m.matchWithLookupRef(pattern, ref, func(resolved *query.DataPattern) (executor.Relation, error) {
+			return m.MatchWithIndex(resolved, index)
+		})
+	}
+	return m.matchUnboundOnIndex(pattern, pattern.ExtractColumns(), nil, &pinned)
+}
+
 // matchUnboundAsRelation matches a pattern without bindings and returns a Relation
 func (m *BadgerMatcher) matchUnboundAsRelation(pattern *query.DataPattern, columns []query.Symbol, constraints []executor.StorageConstraint) (executor.Relation, error) {
+	return m.matchUnboundOnIndex(pattern, columns, constraints, nil)
+}
+
+// matchUnboundOnIndex matches a pattern without bindings. If pinned is nil the
+// index is chosen from the bound pattern positions.
+func (m *BadgerMatcher) matchUnboundOnIndex(pattern *query.DataPattern, columns []query.Symbol, constraints []executor.StorageConstraint, pinned *IndexType) (executor.Relation, error) {
 	// Extract constant values from pattern
 	var e, a, v, tx interface{}
 
@@ -159,17 +199,20 @@ func (m *BadgerMatcher) matchUnboundAsRelation(pattern *query.DataPattern, colum
 
 	// Choose index and create scan range
 	index, start, end := m.chooseIndex(e, a, v, tx)
+	if pinned != nil && *pinned != index {
+		index, start, end = m.chooseIndexForValues(*pinned, e, a, v, tx)
+	}
 
 	// Emit index selection event if handler is available
 	if m.handler != nil {
-		m.handler(annotations.Event{
-			Name:  "pattern/index-selection",
-			Start: time.Now(),
-			Data: map[string]interface{}{
-				"pattern": pattern.String(),
-				"index":   indexName(index),
-			},
-		})
+		bound, candidates := explainIndexChoice(e, a, v, tx, index, pinned != nil)
+		m.handler(annotations.NewEvent(annotations.PatternIndexSelection, annotations.IndexSelectionEvent{
+			Pattern:    pattern.String(),
+			Index:      indexName(index),
+			Pinned:     pinned != nil,
+			Bound:      bound,
+			Candidates: candidates,
+		}))
 	}
 
 	// Always try to convert constraints to key masks first for efficient filtering
@@ -186,11 +229,7 @@ func (m *BadgerMatcher) matchUnboundAsRelation(pattern *query.DataPattern, colum
 	}
 
 	// Create streaming iterator
-	var iter interface {
-		Next() bool
-		Tuple() executor.Tuple
-		Close() error
-	}
+	var iter executor.Iterator
 
 	if keyMask != nil {
 		// Use key mask iterator for efficient filtering
@@ -211,7 +250,7 @@ func (m *BadgerMatcher) matchUnboundAsRelation(pattern *query.DataPattern, colum
 		}
 
 		// Initialize the key mask iterator using the optimized method
-		storageIter, err := m.store.ScanKeysOnlyWithMask(index, start, end, keyMask)
+		storageIter, err := m.countScan(m.store.ScanKeysOnlyWithMask(index, start, end, keyMask))
 		if err != nil {
 			return nil, fmt.Errorf("key mask scan failed: %w", err)
 		}
@@ -235,7 +274,7 @@ func (m *BadgerMatcher) matchUnboundAsRelation(pattern *query.DataPattern, colum
 		}
 
 		// Initialize the storage iterator using key-only scanning
-		storageIter, err := m.store.ScanKeysOnly(index, start, end)
+		storageIter, err := m.scanKeysOnly(index, start, end)
 		if err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
@@ -281,7 +320,7 @@ func (m *BadgerMatcher) matchWithoutIteratorReuse(pattern *query.DataPattern, bi
 		columns:          columns,
 		constraints:      constraints,
 		currentIdx:       -1,
-		patternExtractor: query.NewPatternExtractor(pattern, bindingRel.Columns()),
+		patternExtractor: query.NewPatternExtractor(pattern, bindingRel.Symbols()),
 		tupleBuilder:     m.getTupleBuilder(pattern, columns),
 	}
 
@@ -313,7 +352,7 @@ func (m *BadgerMatcher) matchWithIteratorReuse(
 		columns:          columns,
 		constraints:      constraints,
 		currentIdx:       -1,
-		patternExtractor: query.NewPatternExtractor(pattern, bindingRel.Columns()),
+		patternExtractor: query.NewPatternExtractor(pattern, bindingRel.Symbols()),
 		tupleBuilder:     m.getTupleBuilder(pattern, columns),
 	}
 
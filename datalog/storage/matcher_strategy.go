@@ -67,7 +67,7 @@ func analyzeReuseStrategy(pattern *query.DataPattern, bindingRel executor.Relati
 	// but that's okay - the overhead of reuse with one binding is negligible
 
 	// Count which positions have variables that are bound
-	bindingCols := bindingRel.Columns()
+	bindingCols := bindingRel.Symbols()
 	bindingSet := make(map[query.Symbol]bool)
 	for _, col := range bindingCols {
 		bindingSet[col] = true
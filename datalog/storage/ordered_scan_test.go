@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// TestMatchOrdered verifies that AVET scans produce values in value order,
+// including negative numbers and times before 1970 whose encodings sort last
+func TestMatchOrdered(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	base := time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC)
+	values := map[string]func(i int) interface{}{
+		":item/int":   func(i int) interface{} { return int64(i*7%40 - 20) },
+		":item/float": func(i int) interface{} { return float64(i*7%40-20) / 4 },
+		":item/time":  func(i int) interface{} { return base.Add(time.Duration(i*7%40) * time.Minute) },
+	}
+
+	tx := db.NewTransaction()
+	for i := 0; i < 40; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("item:%d", i))
+		for attr, value := range values {
+			tx.Add(e, datalog.NewKeyword(attr), value(i))
+		}
+		tx.Add(e, datalog.NewKeyword(":item/name"), fmt.Sprintf("item%d", i))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	matcher := db.Matcher().(*BadgerMatcher)
+
+	for attr := range values {
+		for _, descending := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/desc=%v", attr, descending), func(t *testing.T) {
+				pattern := &query.DataPattern{Elements: []query.PatternElement{
+					query.Variable{Name: "?e"},
+					query.Constant{Value: datalog.NewKeyword(attr)},
+					query.Variable{Name: "?v"},
+				}}
+
+				rel, err := matcher.MatchOrdered(pattern, descending)
+				if err != nil {
+					t.Fatalf("MatchOrdered failed: %v", err)
+				}
+
+				var prev interface{}
+				count := 0
+				it := rel.Iterator()
+				defer it.Close()
+				for it.Next() {
+					v := it.Tuple()[1]
+					if prev != nil {
+						cmp := datalog.CompareValues(prev, v)
+						if (!descending && cmp > 0) || (descending && cmp < 0) {
+							t.Fatalf("Value %v out of order after %v", v, prev)
+						}
+					}
+					prev = v
+					count++
+				}
+				if count != 40 {
+					t.Errorf("Expected 40 tuples, got %d", count)
+				}
+			})
+		}
+	}
+
+	t.Run("strings unsupported", func(t *testing.T) {
+		pattern := &query.DataPattern{Elements: []query.PatternElement{
+			query.Variable{Name: "?e"},
+			query.Constant{Value: datalog.NewKeyword(":item/name")},
+			query.Variable{Name: "?v"},
+		}}
+		if _, err := matcher.MatchOrdered(pattern, false); !errors.Is(err, executor.ErrOrderedScanUnsupported) {
+			t.Errorf("Expected ErrOrderedScanUnsupported for strings, got %v", err)
+		}
+	})
+}
+
+// TestOrderedScanQuery verifies that :order-by with :limit stops the scan early
+// and returns the same results as sorting the full result
+func TestOrderedScanQuery(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	start := time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)
+	tx := db.NewTransaction()
+	for i := 0; i < 500; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("bar:%d", i))
+		tx.Add(e, datalog.NewKeyword(":bar/time"), start.Add(time.Duration(i*37%500)*time.Minute))
+		tx.Add(e, datalog.NewKeyword(":bar/close"), float64(100+i%13))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	for _, order := range []string{"?t", "[?t :desc]"} {
+		q, err := parser.ParseQuery(fmt.Sprintf(`{:query [:find ?t ?c
+		                                                  :where [?b :bar/time ?t]
+		                                                         [?b :bar/close ?c]
+		                                                         [(> ?c 105.0)]
+		                                                  :order-by [%s]]
+		                                          :offset 3 :limit 10}`, order))
+		if err != nil {
+			t.Fatalf("Failed to parse query: %v", err)
+		}
+
+		opts := DefaultPlannerOptions()
+		opts.EnableOrderedScan = false
+		expected, err := db.NewExecutorWithOptions(opts).Execute(q)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+
+		var stopped bool
+		handler := func(event annotations.Event) {
+			if event.Name == "ordered_scan/complete" {
+				stopped, _ = event.Data["stopped"].(bool)
+			}
+		}
+		opts.EnableOrderedScan = true
+		result, err := db.NewExecutorWithOptions(opts).ExecuteWithContext(executor.NewContext(handler), q)
+		if err != nil {
+			t.Fatalf("Ordered scan query failed: %v", err)
+		}
+
+		if result.Size() != 10 || expected.Size() != 10 {
+			t.Fatalf("%s: expected 10 results, got %d (sorted: %d)", order, result.Size(), expected.Size())
+		}
+		for i := 0; i < 10; i++ {
+			if fmt.Sprint(result.Get(i)) != fmt.Sprint(expected.Get(i)) {
+				t.Errorf("%s: row %d is %v, expected %v", order, i, result.Get(i), expected.Get(i))
+			}
+		}
+		if !stopped {
+			t.Errorf("%s: expected the ordered scan to stop early", order)
+		}
+	}
+}
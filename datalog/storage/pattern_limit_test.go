@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/annotations"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+// TestPatternMaxDatomsStopsScan verifies that a :max-datoms hint ends the
+// storage scan at the cap rather than filtering a full scan
+func TestPatternMaxDatomsStopsScan(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i := 0; i < 5000; i++ {
+		tx.Add(datalog.NewIdentity(fmt.Sprintf("event:%d", i)), datalog.NewKeyword(":event/seq"), int64(i))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?e ?seq :where [?e :event/seq ?seq {:max-datoms 100}]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	var limit *annotations.PatternLimitEvent
+	scanned := 0
+	handler := &annotations.TypedHandler{
+		OnPatternLimit: func(_ annotations.Event, ev annotations.PatternLimitEvent) {
+			limit = &ev
+		},
+		OnPatternScan: func(_ annotations.Event, ev annotations.PatternScanEvent) {
+			scanned += ev.DatomsScanned
+		},
+	}
+
+	result, err := db.NewExecutor().ExecuteWithContext(executor.NewContext(handler.Handle), q)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	count := 0
+	it := result.Iterator()
+	for it.Next() {
+		count++
+	}
+	it.Close()
+
+	if count != 100 {
+		t.Errorf("Expected 100 results, got %d", count)
+	}
+	if limit == nil || limit.MaxDatoms != 100 {
+		t.Fatalf("Expected a partial-result warning at 100 datoms, got %v", limit)
+	}
+	if scanned == 0 || scanned > 200 {
+		t.Errorf("Expected the scan to stop near 100 datoms, scanned %d", scanned)
+	}
+}
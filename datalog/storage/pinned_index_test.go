@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+// TestPinnedIndexExecution verifies that a plan with pinned indexes produces the
+// same results as the automatically planned query, for every index.
+func TestPinnedIndexExecution(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pinned-index-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i := 0; i < 20; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		tx.Add(e, datalog.NewKeyword(":person/name"), fmt.Sprintf("Person%d", i))
+		tx.Add(e, datalog.NewKeyword(":person/age"), int64(20+i%5))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?name :where [?e :person/age 22] [?e :person/name ?name]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	exec := db.NewExecutor()
+	expected, err := exec.Execute(q)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if expected.Size() != 4 {
+		t.Fatalf("Expected 4 results, got %d", expected.Size())
+	}
+
+	basePlan, err := exec.PlanQuery(q)
+	if err != nil {
+		t.Fatalf("PlanQuery failed: %v", err)
+	}
+
+	for _, idx := range []planner.IndexType{planner.EAVT, planner.AEVT, planner.AVET, planner.VAET, planner.TAEV} {
+		t.Run(idx.String(), func(t *testing.T) {
+			plan := basePlan.Clone()
+			for i := range plan.Phases {
+				for _, pattern := range plan.Phases[i].Patterns() {
+					if err := plan.PinIndex(i, pattern, idx); err != nil {
+						t.Fatalf("PinIndex failed: %v", err)
+					}
+				}
+			}
+			if err := plan.Validate(); err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+
+			result, err := exec.ExecuteRealized(executor.NewContext(nil), plan, nil)
+			if err != nil {
+				t.Fatalf("ExecuteRealized failed: %v", err)
+			}
+			if result == nil || result.Size() != expected.Size() {
+				t.Fatalf("Expected %d results with pinned %s, got %v", expected.Size(), idx, result)
+			}
+
+			names := make(map[interface{}]bool)
+			it := result.Iterator()
+			for it.Next() {
+				names[it.Tuple()[0]] = true
+			}
+			it.Close()
+			for _, name := range []string{"Person2", "Person7", "Person12", "Person17"} {
+				if !names[name] {
+					t.Errorf("Missing %s in pinned %s result", name, idx)
+				}
+			}
+		})
+	}
+}
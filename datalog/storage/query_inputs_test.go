@@ -400,6 +400,57 @@ func TestExecuteQueryWithTimeInput(t *testing.T) {
 	}
 }
 
+// TestExecuteQueryWithComparisonInput tests comparison predicates against
+// inputs and other pattern variables, which must filter instead of being
+// pushed to storage as constraints without a value
+func TestExecuteQueryWithComparisonInput(t *testing.T) {
+	dir, err := os.MkdirTemp("", "query-comparison-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i, name := range []string{"alice", "bob", "carol"} {
+		person := datalog.NewIdentity(name)
+		tx.Add(person, datalog.NewKeyword(":person/age"), int64(10*(i+1)))
+		tx.Add(person, datalog.NewKeyword(":person/min-age"), int64(20))
+		tx.Add(person, datalog.NewKeyword(":person/joined"), time.Date(2025, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		inputs []interface{}
+		want   int
+	}{
+		{"InputRight", `[:find ?age :in $ ?min :where [?p :person/age ?age] [(>= ?age ?min)]]`, []interface{}{int64(20)}, 2},
+		{"InputLeft", `[:find ?age :in $ ?min :where [?p :person/age ?age] [(< ?min ?age)]]`, []interface{}{int64(20)}, 1},
+		{"TimeInput", `[:find ?p :in $ ?from :where [?p :person/joined ?t] [(>= ?t ?from)]]`, []interface{}{time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}, 2},
+		{"PatternVariables", `[:find ?age :where [?p :person/age ?age] [?p :person/min-age ?m] [(>= ?age ?m)]]`, nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := db.ExecuteQueryWithInputs(tt.query, tt.inputs...)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("Expected %d results, got %d: %v", tt.want, len(results), results)
+			}
+		})
+	}
+}
+
 // TestExecuteQueryInputErrors tests error handling for input mismatches
 func TestExecuteQueryInputErrors(t *testing.T) {
 	dir, err := os.MkdirTemp("", "query-error-test-*")
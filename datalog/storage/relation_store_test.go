@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// TestStoreRelationMaterializesAggregates stores daily aggregates computed by
+// a query back into the database and queries them as ordinary facts
+func TestStoreRelationMaterializesAggregates(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	tx := db.NewTransaction()
+	for i := 0; i < 30; i++ {
+		e := datalog.NewIdentity(fmt.Sprintf("trade:%d", i))
+		tx.Add(e, datalog.NewKeyword(":trade/symbol"), []string{"AAPL", "MSFT"}[i%2])
+		tx.Add(e, datalog.NewKeyword(":trade/day"), int64(i%3))
+		tx.Add(e, datalog.NewKeyword(":trade/volume"), int64(100))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	q, err := parser.ParseQuery(`[:find ?symbol ?day (sum ?volume)
+	                              :where [?t :trade/symbol ?symbol]
+	                                     [?t :trade/day ?day]
+	                                     [?t :trade/volume ?volume]]`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	daily, err := db.NewExecutor().Execute(q)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	plan := executor.RelationStorePlan{
+		EntityPrefix: "daily",
+		EntityKey:    []query.Symbol{"?symbol", "?day"},
+		Attributes: map[query.Symbol]datalog.Keyword{
+			"?symbol":       datalog.NewKeyword(":daily/symbol"),
+			"?day":          datalog.NewKeyword(":daily/day"),
+			"(sum ?volume)": datalog.NewKeyword(":daily/volume"),
+		},
+	}
+	tx = db.NewTransaction()
+	n, err := executor.StoreRelation(tx, daily, plan)
+	if err != nil {
+		t.Fatalf("StoreRelation failed: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("Expected 6 daily entities, got %d", n)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	rows, err := db.ExecuteQuery(`[:find ?volume :where [?d :daily/symbol "AAPL"] [?d :daily/day 0] [?d :daily/volume ?volume]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || fmt.Sprint(rows[0][0]) != "500" {
+		t.Errorf("Expected AAPL day 0 volume 500, got %v", rows)
+	}
+
+	rows, err = db.ExecuteQuery(`[:find (count ?d) :where [?d :daily/volume _]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0][0] != int64(6) {
+		t.Errorf("Expected 6 stored aggregates, got %v", rows)
+	}
+}
@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// CapturedQuery is a query recorded by a QueryCapture: its text, its :in
+// bindings after conversion to relations, and how it ran when captured
+type CapturedQuery struct {
+	Time     time.Time
+	Query    string
+	Inputs   []executor.Relation
+	Rows     int
+	Duration time.Duration
+}
+
+// QueryCapture records queries executed with a database's ExecuteQuery
+// methods to a replay file, one JSON object per line, for Replay to run
+// again later. Only queries that succeed are recorded. Set it with
+// Database.SetQueryCapture.
+type QueryCapture struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	closer  io.Closer // The replay file, if the capture created it
+	rate    float64
+	rng     *rand.Rand
+	skipped int // Queries with inputs that can't be recorded
+	err     error
+}
+
+// NewQueryCapture creates a capture that writes to w. Each query is recorded
+// with probability sampleRate: 1 records every query, 0.01 about one in a
+// hundred.
+func NewQueryCapture(w io.Writer, sampleRate float64) *QueryCapture {
+	return &QueryCapture{
+		w:    bufio.NewWriter(w),
+		rate: sampleRate,
+		rng:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// CreateQueryCapture creates a capture writing to a new replay file at path
+// (see NewQueryCapture)
+func CreateQueryCapture(path string, sampleRate float64) (*QueryCapture, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replay file: %w", err)
+	}
+	c := NewQueryCapture(f, sampleRate)
+	c.closer = f
+	return c, nil
+}
+
+// Skipped returns the number of sampled queries that were not recorded
+// because an input held a value with no replay encoding
+func (c *QueryCapture) Skipped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skipped
+}
+
+// Flush writes buffered queries to the replay file
+func (c *QueryCapture) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.w.Flush()
+}
+
+// Close flushes the capture and closes the replay file if the capture
+// created it. It returns the first write error, if any.
+func (c *QueryCapture) Close() error {
+	err := c.Flush()
+	if c.closer != nil {
+		if closeErr := c.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// record writes q to the replay file if it is sampled. Write errors stop the
+// capture and are returned by Flush and Close.
+func (c *QueryCapture) record(q CapturedQuery) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil || c.rng.Float64() >= c.rate {
+		return
+	}
+
+	record, err := encodeCapturedQuery(q)
+	if err != nil {
+		c.skipped++
+		return
+	}
+	line, err := json.Marshal(record)
+	if err == nil {
+		line = append(line, '\n')
+		_, err = c.w.Write(line)
+	}
+	if err != nil {
+		c.err = fmt.Errorf("failed to write replay file: %w", err)
+	}
+}
+
+// SetQueryCapture starts recording the queries executed with ExecuteQuery,
+// ExecuteQueryWithInputs and ExecuteQueryWithMetadata to capture, or stops
+// recording if capture is nil. The caller closes the capture once recording
+// has stopped.
+func (d *Database) SetQueryCapture(capture *QueryCapture) {
+	d.capture.Store(capture)
+}
+
+// captureQuery records a query with the database's capture, if any
+func (d *Database) captureQuery(queryStr string, inputs []executor.Relation, rows int, start time.Time) {
+	if capture := d.capture.Load(); capture != nil {
+		capture.record(CapturedQuery{
+			Time:     start,
+			Query:    queryStr,
+			Inputs:   inputs,
+			Rows:     rows,
+			Duration: time.Since(start),
+		})
+	}
+}
+
+// capturedRecord is the JSON form of a CapturedQuery
+type capturedRecord struct {
+	Time     time.Time       `json:"time"`
+	Query    string          `json:"query"`
+	Inputs   []capturedInput `json:"inputs,omitempty"`
+	Rows     int             `json:"rows"`
+	Duration time.Duration   `json:"duration_ns"`
+}
+
+// capturedInput is the JSON form of an input relation
+type capturedInput struct {
+	Symbols []query.Symbol    `json:"symbols"`
+	Tuples  [][]capturedValue `json:"tuples"`
+}
+
+// capturedValue is a value in its storage encoding, which keeps its type
+type capturedValue struct {
+	Type datalog.ValueType `json:"t"`
+	Data []byte            `json:"v"`
+}
+
+func encodeCapturedQuery(q CapturedQuery) (capturedRecord, error) {
+	record := capturedRecord{Time: q.Time, Query: q.Query, Rows: q.Rows, Duration: q.Duration}
+	for _, rel := range q.Inputs {
+		input := capturedInput{Symbols: rel.Symbols()}
+		err := rel.ForEach(func(tuple executor.Tuple) (bool, error) {
+			values := make([]capturedValue, len(tuple))
+			for i, v := range tuple {
+				value, err := encodeCapturedValue(v)
+				if err != nil {
+					return true, err
+				}
+				values[i] = value
+			}
+			input.Tuples = append(input.Tuples, values)
+			return false, nil
+		})
+		if err != nil {
+			return capturedRecord{}, err
+		}
+		record.Inputs = append(record.Inputs, input)
+	}
+	return record, nil
+}
+
+// encodeCapturedValue encodes a datom value, or a Go integer or float32
+// input, which queries compare as int64 and float64
+func encodeCapturedValue(v interface{}) (capturedValue, error) {
+	switch val := v.(type) {
+	case string, int64, float64, bool, time.Time, []byte, datalog.Identity, *datalog.Identity, datalog.Keyword, *datalog.Keyword:
+	case float32:
+		v = float64(val)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+			v = rv.Int()
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			v = int64(rv.Uint())
+		default:
+			return capturedValue{}, fmt.Errorf("cannot capture %T input", v)
+		}
+	}
+	return capturedValue{Type: datalog.Type(v), Data: datalog.ValueBytes(v)}, nil
+}
+
+// ReadReplayFile reads the queries recorded by a QueryCapture
+func ReadReplayFile(path string) ([]CapturedQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer f.Close()
+
+	var queries []CapturedQuery
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record capturedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("replay file %s line %d: %w", path, line, err)
+		}
+		q := CapturedQuery{Time: record.Time, Query: record.Query, Rows: record.Rows, Duration: record.Duration}
+		for _, input := range record.Inputs {
+			tuples := make([]executor.Tuple, len(input.Tuples))
+			for i, values := range input.Tuples {
+				tuples[i] = make(executor.Tuple, len(values))
+				for j, value := range values {
+					if tuples[i][j], err = datalog.ValueFromBytes(value.Type, value.Data); err != nil {
+						return nil, fmt.Errorf("replay file %s line %d: %w", path, line, err)
+					}
+				}
+			}
+			q.Inputs = append(q.Inputs, executor.NewMaterializedRelation(input.Symbols, tuples))
+		}
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read replay file: %w", err)
+	}
+	return queries, nil
+}
+
+// ReplayOptionSet names a Config to replay queries under
+type ReplayOptionSet struct {
+	Name   string
+	Config Config
+}
+
+// ReplayResult is the outcome of one captured query under each option set
+type ReplayResult struct {
+	Query     string
+	Durations []time.Duration // Per option set
+	Rows      []int           // Per option set (-1 if the query failed)
+	Errors    []error         // Per option set
+	Mismatch  bool            // The option sets returned different results
+}
+
+// ReplayReport is the outcome of a Replay
+type ReplayReport struct {
+	OptionSets []string
+	Results    []ReplayResult
+	Totals     []time.Duration // Total duration per option set
+	Failures   []int           // Failed queries per option set
+	Mismatches int             // Queries whose results differ between option sets
+}
+
+// Replay runs captured queries against db once under each option set, in
+// order, and compares their durations and results. Rows are compared
+// regardless of their order. Run it against a copy of the production database, as of the
+// time the queries were captured, to validate optimizer changes against the
+// actual workload; results that differ from the capture's row counts are
+// expected if the data has changed since.
+func Replay(db *Database, queries []CapturedQuery, optionSets []ReplayOptionSet) (*ReplayReport, error) {
+	if len(optionSets) == 0 {
+		return nil, fmt.Errorf("replay needs at least one option set")
+	}
+
+	report := &ReplayReport{
+		Totals:   make([]time.Duration, len(optionSets)),
+		Failures: make([]int, len(optionSets)),
+	}
+	for _, set := range optionSets {
+		report.OptionSets = append(report.OptionSets, set.Name)
+	}
+
+	for _, captured := range queries {
+		q, err := parser.ParseQuery(captured.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse captured query %s: %w", captured.Query, err)
+		}
+
+		result := ReplayResult{
+			Query:     captured.Query,
+			Durations: make([]time.Duration, len(optionSets)),
+			Rows:      make([]int, len(optionSets)),
+			Errors:    make([]error, len(optionSets)),
+		}
+		var first []string
+		for i, set := range optionSets {
+			exec := db.newExecutor(set.Config)
+			start := time.Now()
+			rows, err := replayRows(exec, q, captured.Inputs)
+			result.Durations[i] = time.Since(start)
+			report.Totals[i] += result.Durations[i]
+			if err != nil {
+				result.Rows[i] = -1
+				result.Errors[i] = err
+				report.Failures[i]++
+				continue
+			}
+			result.Rows[i] = len(rows)
+			if first == nil {
+				first = rows
+			} else if !reflect.DeepEqual(first, rows) {
+				result.Mismatch = true
+			}
+		}
+		if result.Mismatch {
+			report.Mismatches++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// replayRows executes q and returns its rows formatted and sorted for
+// comparison
+func replayRows(exec *executor.Executor, q *query.Query, inputs []executor.Relation) ([]string, error) {
+	result, err := exec.ExecuteWithRelations(executor.NewContext(nil), q, inputs)
+	if err != nil {
+		return nil, err
+	}
+	rows := []string{}
+	if result == nil {
+		return rows, nil
+	}
+	err = result.ForEach(func(tuple executor.Tuple) (bool, error) {
+		rows = append(rows, fmt.Sprint(tuple))
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rows)
+	return rows, nil
+}
+
+// String returns the report as a summary per option set, followed by the
+// queries that failed or whose results differ
+func (r *ReplayReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Replayed %d queries\n", len(r.Results))
+	for i, name := range r.OptionSets {
+		fmt.Fprintf(&sb, "  %-20s total %v, %d failed\n", name, r.Totals[i], r.Failures[i])
+	}
+	fmt.Fprintf(&sb, "  %d queries returned different results\n", r.Mismatches)
+
+	for _, result := range r.Results {
+		failed := false
+		for _, err := range result.Errors {
+			failed = failed || err != nil
+		}
+		if !failed && !result.Mismatch {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s\n", result.Query)
+		for i, name := range r.OptionSets {
+			if err := result.Errors[i]; err != nil {
+				fmt.Fprintf(&sb, "  %-20s error: %v\n", name, err)
+			} else {
+				fmt.Fprintf(&sb, "  %-20s %d rows in %v\n", name, result.Rows[i], result.Durations[i])
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestQueryCaptureReplay(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	name := datalog.NewKeyword(":person/name")
+	age := datalog.NewKeyword(":person/age")
+	friend := datalog.NewKeyword(":person/friend")
+	alice := datalog.NewIdentity("person:alice")
+	bob := datalog.NewIdentity("person:bob")
+	tx := db.NewTransaction()
+	tx.Add(alice, name, "Alice")
+	tx.Add(alice, age, int64(30))
+	tx.Add(bob, name, "Bob")
+	tx.Add(bob, age, int64(25))
+	tx.Add(bob, friend, alice)
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	path := filepath.Join(dir, "queries.jsonl")
+	capture, err := CreateQueryCapture(path, 1)
+	if err != nil {
+		t.Fatalf("CreateQueryCapture failed: %v", err)
+	}
+	db.SetQueryCapture(capture)
+
+	queries := []struct {
+		query  string
+		inputs []interface{}
+	}{
+		{`[:find ?n :where [_ :person/name ?n]]`, nil},
+		{`[:find ?n :in $ ?min :where [?e :person/age ?a] [(>= ?a ?min)] [?e :person/name ?n]]`, []interface{}{26}},
+		{`[:find ?n :in $ [?f ...] :where [?e :person/friend ?f] [?e :person/name ?n]]`, []interface{}{[]interface{}{alice}}},
+	}
+	for _, q := range queries {
+		if _, err := db.ExecuteQueryWithInputs(q.query, q.inputs...); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+	// Failed queries are not captured
+	db.ExecuteQuery(`[:find ?n :where [_ :person/name ?n]`)
+
+	db.SetQueryCapture(nil)
+	db.ExecuteQuery(`[:find ?e :where [?e :person/age _]]`)
+	if err := capture.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	captured, err := ReadReplayFile(path)
+	if err != nil {
+		t.Fatalf("ReadReplayFile failed: %v", err)
+	}
+	if len(captured) != len(queries) {
+		t.Fatalf("Expected %d captured queries, got %d", len(queries), len(captured))
+	}
+	for i, q := range queries {
+		if captured[i].Query != q.query || len(captured[i].Inputs) != len(q.inputs) {
+			t.Errorf("Query %d: expected %s with %d inputs, got %+v", i, q.query, len(q.inputs), captured[i])
+		}
+	}
+	if got := captured[1].Inputs[0].Get(0)[0]; got != int64(26) {
+		t.Errorf("Expected the scalar input to be captured as int64 26, got %v (%T)", got, got)
+	}
+
+	// Replayed under two option sets, results agree with the capture
+	tuned := DefaultConfig()
+	tuned.Planner.EnableSubqueryDecorrelation = false
+	tuned.Planner.EnableDynamicReordering = false
+	report, err := Replay(db, captured, []ReplayOptionSet{
+		{Name: "default", Config: DefaultConfig()},
+		{Name: "tuned", Config: tuned},
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if report.Mismatches != 0 || report.Failures[0] != 0 || report.Failures[1] != 0 {
+		t.Errorf("Expected identical results under both option sets:\n%s", report)
+	}
+	for i, result := range report.Results {
+		if result.Rows[0] != captured[i].Rows || result.Rows[1] != captured[i].Rows {
+			t.Errorf("Query %d: expected %d rows as captured, got %v", i, captured[i].Rows, result.Rows)
+		}
+	}
+	if !strings.Contains(report.String(), "Replayed 3 queries") {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+}
+
+func TestQueryCaptureSampling(t *testing.T) {
+	db, err := NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	var buf bytes.Buffer
+	capture := NewQueryCapture(&buf, 0)
+	db.SetQueryCapture(capture)
+	for i := 0; i < 10; i++ {
+		if _, err := db.ExecuteQuery(`[:find ?e :where [?e :person/name _]]`); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+	if err := capture.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected a sample rate of 0 to capture nothing, got %q", buf.String())
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+)
+
+func TestExecuteQueryWithMetadata(t *testing.T) {
+	dir, err := os.MkdirTemp("", "result-metadata-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	name := datalog.NewKeyword(":person/name")
+	age := datalog.NewKeyword(":person/age")
+	var txIDs []uint64
+	for i := 0; i < 3; i++ {
+		tx := db.NewTransaction()
+		for j := 0; j < 5; j++ {
+			person := datalog.NewIdentity(fmt.Sprintf("person:%d-%d", i, j))
+			tx.Add(person, name, fmt.Sprintf("Person %d-%d", i, j))
+			tx.Add(person, age, int64(20+j))
+		}
+		txID, err := tx.Commit()
+		if err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		txIDs = append(txIDs, txID)
+	}
+
+	q := `[:find ?name :in $ ?age :where [?p :person/age ?age] [?p :person/name ?name]]`
+	rows, md, err := db.ExecuteQueryWithMetadata(q, int64(23))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("Expected 3 rows, got %d", len(rows))
+	}
+	if md.BasisTx != txIDs[2] {
+		t.Errorf("Expected basis transaction %d, got %d", txIDs[2], md.BasisTx)
+	}
+	if md.DatomsScanned < 3 {
+		t.Errorf("Expected at least the 3 matching age datoms scanned, got %d", md.DatomsScanned)
+	}
+	if md.Phases == 0 || md.WallTime <= 0 {
+		t.Errorf("Expected phases and wall time, got %s", md)
+	}
+	if md.PlanCacheHit {
+		t.Error("Expected the first execution to plan the query")
+	}
+
+	// Plans are cached for queries without inputs
+	q = `[:find ?name :where [?p :person/age 24] [?p :person/name ?name]]`
+	for i, cached := range []bool{false, true} {
+		rows, md, err = db.ExecuteQueryWithMetadata(q)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 3 || md.PlanCacheHit != cached {
+			t.Errorf("Execution %d: expected 3 rows and plan cache hit %v, got %d rows and %s", i+1, cached, len(rows), md)
+		}
+	}
+
+	// An as-of matcher's basis is its transaction
+	basis, err := db.AsOf(txIDs[1]).(executor.BasisReporter).BasisTx()
+	if err != nil {
+		t.Fatalf("BasisTx failed: %v", err)
+	}
+	if basis != txIDs[1] {
+		t.Errorf("Expected as-of basis %d, got %d", txIDs[1], basis)
+	}
+}
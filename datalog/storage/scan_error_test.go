@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+var errCorruptDatom = errors.New("corrupt datom")
+
+// corruptIterator is a storage iterator whose every entry fails to decode
+type corruptIterator struct {
+	remaining int
+}
+
+func (it *corruptIterator) Next() bool {
+	if it.remaining == 0 {
+		return false
+	}
+	it.remaining--
+	return true
+}
+
+func (it *corruptIterator) Datom() (*datalog.Datom, error) { return nil, errCorruptDatom }
+func (it *corruptIterator) Close() error                   { return nil }
+func (it *corruptIterator) Seek(key []byte)                {}
+
+func TestScanIteratorsReportDecodeErrors(t *testing.T) {
+	pattern := &query.DataPattern{Elements: []query.PatternElement{
+		query.Variable{Name: "?e"},
+		query.Constant{Value: datalog.NewKeyword(":user/name")},
+		query.Variable{Name: "?name"},
+	}}
+	matcher := &BadgerMatcher{}
+
+	unbound := &unboundIterator{matcher: matcher, pattern: pattern, storageIter: &corruptIterator{remaining: 3}}
+	if unbound.Next() {
+		t.Fatal("Expected unbound scan to stop at the corrupt datom")
+	}
+	if !errors.Is(unbound.Err(), errCorruptDatom) {
+		t.Errorf("Expected unbound scan to report the decode error, got %v", unbound.Err())
+	}
+
+	hashJoin := &hashJoinIterator{matcher: matcher, pattern: pattern, iter: &corruptIterator{remaining: 3}}
+	if hashJoin.Next() {
+		t.Fatal("Expected hash join scan to stop at the corrupt datom")
+	}
+	if !errors.Is(hashJoin.Err(), errCorruptDatom) {
+		t.Errorf("Expected hash join scan to report the decode error, got %v", hashJoin.Err())
+	}
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShutdown is returned for commits, imports, exports and scans started
+// after Shutdown
+var ErrShutdown = errors.New("database is shutting down")
+
+// activity counts operations in progress and, once closed, refuses new ones,
+// so that Shutdown can wait for those already running
+type activity struct {
+	mu     sync.Mutex
+	count  int
+	closed bool
+	idle   chan struct{} // Closed when count reaches zero, for wait
+}
+
+// begin counts an operation starting, or returns ErrShutdown once closed
+func (a *activity) begin() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrShutdown
+	}
+	a.count++
+	return nil
+}
+
+// end counts an operation finishing
+func (a *activity) end() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.count--
+	if a.count == 0 && a.idle != nil {
+		close(a.idle)
+		a.idle = nil
+	}
+}
+
+// close refuses new operations
+func (a *activity) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+}
+
+// active returns the number of operations in progress
+func (a *activity) active() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.count
+}
+
+// wait waits until no operations are in progress, or returns ctx's error if
+// it is done first
+func (a *activity) wait(ctx context.Context) error {
+	a.mu.Lock()
+	if a.count == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	if a.idle == nil {
+		a.idle = make(chan struct{})
+	}
+	idle := a.idle
+	a.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown closes the database once the work in progress has finished, so
+// that the store is closed cleanly instead of needing recovery when it is
+// next opened. It stops accepting new work: queries on the database's
+// executors fail with executor.ErrQueriesClosed, and commits, imports,
+// exports and scans with ErrShutdown. It then waits for the queries,
+// commits, imports and exports running, and for open result iterators to be
+// closed, rolls back open transactions, and closes the store, flushing its
+// writes.
+//
+// The wait is bounded by ctx. If ctx is done first, running queries are
+// canceled and Shutdown returns an error wrapping ctx's, leaving the store
+// open, as closing it would pull it from under them. Call Shutdown again to
+// keep waiting, or Close to close the store once the canceled queries stop,
+// without waiting for commits or iterators.
+func (d *Database) Shutdown(ctx context.Context) error {
+	d.queries.Close()
+	d.ops.close()
+
+	if err := d.queries.Wait(ctx); err != nil {
+		active := d.queries.Active()
+		for _, q := range active {
+			d.queries.Cancel(q.ID)
+		}
+		return fmt.Errorf("shutdown: %d queries still running, canceled: %w", len(active), err)
+	}
+	if err := d.ops.wait(ctx); err != nil {
+		return fmt.Errorf("shutdown: %d commits, imports or exports still running: %w", d.ops.active(), err)
+	}
+
+	// Queries can no longer open scans, but their results may still be
+	// iterating
+	d.store.scans.close()
+	if err := d.store.scans.wait(ctx); err != nil {
+		return fmt.Errorf("shutdown: %d result iterators still open: %w", d.store.scans.active(), err)
+	}
+
+	return d.Close()
+}
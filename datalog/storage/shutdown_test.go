@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+)
+
+func TestShutdown(t *testing.T) {
+	dir, err := os.MkdirTemp("", "shutdown-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	name := datalog.NewKeyword(":person/name")
+	tx := db.NewTransaction()
+	tx.Add(datalog.NewIdentity("person:alice"), name, "Alice")
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	pending := db.NewTransaction()
+	pending.Add(datalog.NewIdentity("person:bob"), name, "Bob")
+
+	// A result still being iterated holds the store open
+	it, err := db.Store().Scan(EAVT, nil, nil)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := db.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected shutdown to time out on the open iterator, got %v", err)
+	}
+	if !it.Next() {
+		t.Error("Expected the open iterator to keep working")
+	}
+
+	// New work is refused meanwhile
+	if _, err := pending.Commit(); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected commits to fail with ErrShutdown, got %v", err)
+	}
+	if _, err := db.Store().Scan(EAVT, nil, nil); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected scans to fail with ErrShutdown, got %v", err)
+	}
+	if _, err := db.ExecuteQuery(`[:find ?n :where [_ :person/name ?n]]`); !errors.Is(err, executor.ErrQueriesClosed) {
+		t.Errorf("Expected queries to fail with ErrQueriesClosed, got %v", err)
+	}
+
+	it.Close()
+	if err := db.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if !db.store.db.IsClosed() {
+		t.Error("Expected the store to be closed")
+	}
+
+	// The data committed before shutdown is there when reopened
+	db, err = NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.ExecuteQuery(`[:find ?n :where [_ :person/name ?n]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0][0] != "Alice" {
+		t.Errorf("Expected only Alice, got %v", rows)
+	}
+}
@@ -54,7 +54,10 @@ func newSimpleBatchScanner(
 // Scan performs the batch scan and collects all results
 func (s *simpleBatchScanner) Scan() error {
 	// Step 1: Build a set of binding values for fast lookup
-	bindingSet := s.buildBindingSet()
+	bindingSet, err := s.buildBindingSet()
+	if err != nil {
+		return fmt.Errorf("failed to read bindings: %w", err)
+	}
 	if len(bindingSet) == 0 {
 		return nil
 	}
@@ -66,26 +69,23 @@ func (s *simpleBatchScanner) Scan() error {
 	}
 
 	// Step 3: Open a single scan for the entire range using key-only scanning
-	iter, err := s.matcher.store.ScanKeysOnly(s.index, startKey, endKey)
+	iter, err := s.matcher.scanKeysOnly(s.index, startKey, endKey)
 	if err != nil {
 		return fmt.Errorf("failed to open scan: %w", err)
 	}
 	defer iter.Close()
 
 	// Step 4: Scan and filter
-	s.results = s.scanAndFilter(iter, bindingSet)
-
-	return nil
+	s.results, err = s.scanAndFilter(iter, bindingSet)
+	return err
 }
 
 // buildBindingSet creates a map of binding values for O(1) lookup
-func (s *simpleBatchScanner) buildBindingSet() map[string]executor.Tuple {
+func (s *simpleBatchScanner) buildBindingSet() (map[string]executor.Tuple, error) {
 	bindingSet := make(map[string]executor.Tuple)
 
 	// Get all tuples from the binding relation
-	it := s.bindingRel.Iterator()
-	for it.Next() {
-		tuple := it.Tuple()
+	err := s.bindingRel.ForEach(func(tuple executor.Tuple) (bool, error) {
 		if s.position < len(tuple) {
 			// Use hash-based key for Identity types
 			key := s.valueToKey(tuple[s.position])
@@ -93,9 +93,10 @@ func (s *simpleBatchScanner) buildBindingSet() map[string]executor.Tuple {
 				bindingSet[key] = tuple
 			}
 		}
-	}
+		return false, nil
+	})
 
-	return bindingSet
+	return bindingSet, err
 }
 
 // valueToKey converts a value to a string key for the binding set
@@ -252,14 +253,14 @@ func (s *simpleBatchScanner) buildKey(value interface{}, constA []byte) []byte {
 }
 
 // scanAndFilter scans the iterator and filters by bindings and constraints
-func (s *simpleBatchScanner) scanAndFilter(iter Iterator, bindingSet map[string]executor.Tuple) []executor.Tuple {
+func (s *simpleBatchScanner) scanAndFilter(iter Iterator, bindingSet map[string]executor.Tuple) ([]executor.Tuple, error) {
 	var results []executor.Tuple
 	datomCount := 0
 
 	for iter.Next() {
 		datom, err := iter.Datom()
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("scan of %s failed: %w", s.pattern, err)
 		}
 		datomCount++
 
@@ -309,7 +310,7 @@ func (s *simpleBatchScanner) scanAndFilter(iter Iterator, bindingSet map[string]
 		}
 	}
 
-	return results
+	return results, nil
 }
 
 // matchesPattern checks if a datom matches the pattern with the given binding
@@ -348,3 +349,8 @@ func (s *simpleBatchScanner) Close() error {
 	// Results are already materialized, nothing to close
 	return nil
 }
+
+func (s *simpleBatchScanner) Err() error {
+	// Scan errors are returned by Scan before iteration starts
+	return nil
+}
@@ -0,0 +1,404 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/planner"
+)
+
+// The database maintains the planner's statistics incrementally as
+// transactions commit: each attribute's datom count, and sketches of its
+// distinct values and of the distinct entities. They are written with the
+// datoms they describe, in the same storage transaction, so they are not
+// rebuilt by scanning the indices as the data changes.
+//
+// A store written before statistics were maintained has none, or only those
+// of the commits made since. Such a store lacks the marker key written with
+// complete statistics, and its statistics are rebuilt once from the AEVT
+// index when it is opened.
+
+// Statistics are kept under their own key prefix, beside the commit records
+// and offloaded values. The entity sketch is stored under the bare prefix and
+// each attribute's statistics under the prefix and the attribute.
+const statsPrefix = 0xF2
+
+// statsCompleteKey marks statistics that cover every datom in the store. No
+// attribute starts with a NUL byte, so it can't collide with one's key.
+var statsCompleteKey = []byte{statsPrefix, 0}
+
+const (
+	hllPrecision = 10
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog estimates the number of distinct items added to it, with a
+// standard error of about 3%
+type hyperLogLog [hllRegisters]uint8
+
+// add records an item by its 64-bit hash
+func (h *hyperLogLog) add(hash uint64) {
+	register := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h[register] {
+		h[register] = rank
+	}
+}
+
+// merge adds the items recorded by another sketch
+func (h *hyperLogLog) merge(other *hyperLogLog) {
+	for i, rank := range other {
+		if rank > h[i] {
+			h[i] = rank
+		}
+	}
+}
+
+// estimate returns the estimated number of distinct items
+func (h *hyperLogLog) estimate() int {
+	m := float64(hllRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, rank := range h {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small sets
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int(estimate + 0.5)
+}
+
+// hashBytes hashes an item for a hyperLogLog
+func hashBytes(data ...[]byte) uint64 {
+	h := fnv.New64a()
+	for _, d := range data {
+		h.Write(d)
+	}
+	// FNV's high bits, which select the register, are poorly mixed
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// attributeStats are the statistics kept for one attribute
+type attributeStats struct {
+	datoms int64
+	values hyperLogLog
+}
+
+func (s *attributeStats) encode() []byte {
+	buf := make([]byte, 8, 8+hllRegisters)
+	binary.BigEndian.PutUint64(buf, uint64(s.datoms))
+	return append(buf, s.values[:]...)
+}
+
+func decodeAttributeStats(data []byte) (*attributeStats, error) {
+	if len(data) != 8+hllRegisters {
+		return nil, fmt.Errorf("malformed attribute statistics of %d bytes", len(data))
+	}
+	s := &attributeStats{datoms: int64(binary.BigEndian.Uint64(data))}
+	copy(s.values[:], data[8:])
+	return s, nil
+}
+
+// statsDelta collects the changes a commit or import makes to the statistics
+type statsDelta struct {
+	attrs    map[string]*attributeStats // datoms is the change in the count
+	entities hyperLogLog
+}
+
+func newStatsDelta() *statsDelta {
+	return &statsDelta{attrs: make(map[string]*attributeStats)}
+}
+
+func (d *statsDelta) attr(a datalog.Keyword) *attributeStats {
+	s, ok := d.attrs[a.String()]
+	if !ok {
+		s = &attributeStats{}
+		d.attrs[a.String()] = s
+	}
+	return s
+}
+
+// assert records an asserted datom
+func (d *statsDelta) assert(datom *datalog.Datom) {
+	s := d.attr(datom.A)
+	s.datoms++
+	s.values.add(hashBytes([]byte{byte(datalog.Type(datom.V))}, datalog.ValueBytes(datom.V)))
+	e := datom.E.Hash()
+	d.entities.add(hashBytes(e[:]))
+}
+
+// retract records a removed datom. The sketches only grow: a retracted value
+// or entity is still counted as distinct.
+func (d *statsDelta) retract(datom *datalog.Datom) {
+	d.attr(datom.A).datoms--
+}
+
+// merge adds the changes collected by another delta
+func (d *statsDelta) merge(other *statsDelta) {
+	for a, o := range other.attrs {
+		s, ok := d.attrs[a]
+		if !ok {
+			s = &attributeStats{}
+			d.attrs[a] = s
+		}
+		s.datoms += o.datoms
+		s.values.merge(&o.values)
+	}
+	d.entities.merge(&other.entities)
+}
+
+// statsUpdate is the statistics after applying a delta, for the attributes it
+// changes
+type statsUpdate struct {
+	attrs    map[string]*attributeStats
+	entities hyperLogLog
+}
+
+// write stores the updated statistics
+func (u *statsUpdate) write(w keyWriter) error {
+	if err := w.Set([]byte{statsPrefix}, u.entities[:]); err != nil {
+		return fmt.Errorf("failed to write entity statistics: %w", err)
+	}
+	for a, s := range u.attrs {
+		if err := w.Set(append([]byte{statsPrefix}, a...), s.encode()); err != nil {
+			return fmt.Errorf("failed to write statistics for %s: %w", a, err)
+		}
+	}
+	return nil
+}
+
+// statCounts are an attribute's datom and distinct value counts
+type statCounts struct {
+	datoms int
+	values int
+}
+
+// dbStatistics holds a database's statistics. Updates are staged against the
+// current statistics and applied once written, which relies on commits being
+// serialized.
+type dbStatistics struct {
+	mu       sync.Mutex
+	attrs    map[string]*attributeStats
+	entities hyperLogLog
+
+	// The epoch changes when an attribute's counts, or the entity count,
+	// have doubled or halved since the last change, so that cached plans
+	// survive small writes but are replanned as the data grows
+	epoch         uint64
+	epochCounts   map[string]statCounts
+	epochEntities int
+
+	snapshot *planner.Statistics // Built on demand, reset by apply
+}
+
+func newDBStatistics() *dbStatistics {
+	return &dbStatistics{
+		attrs:       make(map[string]*attributeStats),
+		epoch:       1,
+		epochCounts: make(map[string]statCounts),
+	}
+}
+
+// load reads the stored statistics, rebuilding them first if they don't
+// cover the whole store
+func (s *dbStatistics) load(store *BadgerStore) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	complete := false
+	err := store.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte{statsPrefix}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if bytes.Equal(it.Item().Key(), statsCompleteKey) {
+				complete = true
+				continue
+			}
+			data, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			attr := string(it.Item().Key()[1:])
+			if attr == "" {
+				if len(data) != hllRegisters {
+					return fmt.Errorf("malformed entity statistics of %d bytes", len(data))
+				}
+				copy(s.entities[:], data)
+				continue
+			}
+			stats, err := decodeAttributeStats(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", attr, err)
+			}
+			s.attrs[attr] = stats
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load statistics: %w", err)
+	}
+	if !complete {
+		if err := s.rebuild(store); err != nil {
+			return fmt.Errorf("failed to rebuild statistics: %w", err)
+		}
+	}
+	for a, stats := range s.attrs {
+		s.epochCounts[a] = statCounts{int(stats.datoms), stats.values.estimate()}
+	}
+	s.epochEntities = s.entities.estimate()
+	return nil
+}
+
+// rebuild replaces the loaded statistics with ones counted from the AEVT
+// index, and stores them marked complete. Statistics left for attributes
+// with no datoms are removed.
+func (s *dbStatistics) rebuild(store *BadgerStore) error {
+	delta := newStatsDelta()
+	err := store.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := store.encoder.EncodePrefix(AEVT)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			datom, err := DatomFromKey(AEVT, it.Item().Key(), store.encoder)
+			if err != nil {
+				return err
+			}
+			delta.assert(datom)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	update := &statsUpdate{attrs: delta.attrs, entities: delta.entities}
+	err = store.db.Update(func(txn *badger.Txn) error {
+		for a := range s.attrs {
+			if _, ok := update.attrs[a]; !ok {
+				if err := txn.Delete(append([]byte{statsPrefix}, a...)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := update.write(txn); err != nil {
+			return err
+		}
+		return txn.Set(statsCompleteKey, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.attrs = update.attrs
+	s.entities = update.entities
+	return nil
+}
+
+// stage returns the statistics after applying delta
+func (s *dbStatistics) stage(delta *statsDelta) *statsUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update := &statsUpdate{attrs: make(map[string]*attributeStats, len(delta.attrs)), entities: s.entities}
+	update.entities.merge(&delta.entities)
+	for a, d := range delta.attrs {
+		stats := &attributeStats{}
+		if current, ok := s.attrs[a]; ok {
+			*stats = *current
+		}
+		stats.datoms += d.datoms
+		if stats.datoms < 0 {
+			stats.datoms = 0
+		}
+		stats.values.merge(&d.values)
+		update.attrs[a] = stats
+	}
+	return update
+}
+
+// apply makes written statistics current
+func (s *dbStatistics) apply(update *statsUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for a, stats := range update.attrs {
+		s.attrs[a] = stats
+		counts := statCounts{int(stats.datoms), stats.values.estimate()}
+		before, ok := s.epochCounts[a]
+		if !ok || doubledOrHalved(before.datoms, counts.datoms) || doubledOrHalved(before.values, counts.values) {
+			s.epochCounts[a] = counts
+			changed = true
+		}
+	}
+	s.entities = update.entities
+	if entities := s.entities.estimate(); doubledOrHalved(s.epochEntities, entities) {
+		s.epochEntities = entities
+		changed = true
+	}
+	if changed {
+		s.epoch++
+	}
+	s.snapshot = nil
+}
+
+func doubledOrHalved(before, after int) bool {
+	return after > 2*before || 2*after < before
+}
+
+// statistics returns the current statistics
+func (s *dbStatistics) statistics() *planner.Statistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		snapshot := &planner.Statistics{
+			AttributeCardinality: make(map[string]int, len(s.attrs)),
+			AttributeDatoms:      make(map[string]int, len(s.attrs)),
+			EntityCount:          s.entities.estimate(),
+			Epoch:                s.epoch,
+		}
+		for a, stats := range s.attrs {
+			snapshot.AttributeCardinality[a] = stats.values.estimate()
+			snapshot.AttributeDatoms[a] = int(stats.datoms)
+		}
+		s.snapshot = snapshot
+	}
+	return s.snapshot
+}
+
+// Statistics returns the planner statistics the database maintains as
+// transactions commit: each attribute's datom count and estimated distinct
+// values, and the estimated number of entities. Retractions lower the datom
+// counts but not the distinct estimates.
+//
+// A store written before statistics were maintained has them rebuilt from
+// its AEVT index, once, when it is next opened.
+//
+// Executors created by the database plan with these statistics. The Epoch
+// changes as the counts grow or shrink by half or more, so cached plans are
+// replanned as the data changes.
+func (d *Database) Statistics() *planner.Statistics {
+	return d.stats.statistics()
+}
+
+// Ensure Database can supply statistics to executors' planners
+var _ planner.StatisticsProvider = (*Database)(nil)
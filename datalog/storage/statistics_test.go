@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestHyperLogLogEstimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		var h hyperLogLog
+		for i := 0; i < n; i++ {
+			h.add(hashBytes([]byte(fmt.Sprintf("value %d", i))))
+			h.add(hashBytes([]byte(fmt.Sprintf("value %d", i)))) // Duplicates aren't counted
+		}
+		if got := h.estimate(); got < n*9/10 || got > n*11/10 {
+			t.Errorf("Expected about %d distinct items, estimated %d", n, got)
+		}
+	}
+}
+
+func TestIncrementalStatistics(t *testing.T) {
+	dir, err := os.MkdirTemp("", "statistics-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	name := datalog.NewKeyword(":person/name")
+	team := datalog.NewKeyword(":person/team")
+	tx := db.NewTransaction()
+	for i := 0; i < 500; i++ {
+		person := datalog.NewIdentity(fmt.Sprintf("person:%d", i))
+		tx.Add(person, name, fmt.Sprintf("Person %d", i))
+		tx.Add(person, team, fmt.Sprintf("Team %d", i%5))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	stats := db.Statistics()
+	if stats.AttributeDatoms[":person/name"] != 500 || stats.AttributeDatoms[":person/team"] != 500 {
+		t.Errorf("Expected 500 datoms per attribute, got %v", stats.AttributeDatoms)
+	}
+	if card := stats.AttributeCardinality[":person/name"]; card < 450 || card > 550 {
+		t.Errorf("Expected about 500 distinct names, estimated %d", card)
+	}
+	if card := stats.AttributeCardinality[":person/team"]; card != 5 {
+		t.Errorf("Expected 5 distinct teams, estimated %d", card)
+	}
+	if stats.EntityCount < 450 || stats.EntityCount > 550 {
+		t.Errorf("Expected about 500 entities (and the transaction), estimated %d", stats.EntityCount)
+	}
+
+	t.Run("SmallWriteKeepsEpoch", func(t *testing.T) {
+		before := db.Statistics()
+		tx := db.NewTransaction()
+		tx.Add(datalog.NewIdentity("person:500"), name, "Person 500")
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		after := db.Statistics()
+		if after.AttributeDatoms[":person/name"] != 501 {
+			t.Errorf("Expected 501 names, got %d", after.AttributeDatoms[":person/name"])
+		}
+		if after.Epoch != before.Epoch {
+			t.Errorf("Expected a small write to keep epoch %d, got %d", before.Epoch, after.Epoch)
+		}
+	})
+
+	t.Run("RetractionLowersCount", func(t *testing.T) {
+		before := db.Statistics()
+		tx := db.NewTransaction()
+		for i := 0; i < 400; i++ {
+			tx.Retract(datalog.NewIdentity(fmt.Sprintf("person:%d", i)), team, fmt.Sprintf("Team %d", i%5))
+		}
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		after := db.Statistics()
+		if after.AttributeDatoms[":person/team"] != 100 {
+			t.Errorf("Expected 100 teams after retraction, got %d", after.AttributeDatoms[":person/team"])
+		}
+		if after.Epoch == before.Epoch {
+			t.Error("Expected the epoch to change when a count falls by more than half")
+		}
+	})
+
+	t.Run("QueriesPlanWithStatistics", func(t *testing.T) {
+		rows, err := db.ExecuteQuery(`[:find ?n :where [?p :person/team "Team 0"] [?p :person/name ?n]]`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(rows) != 20 {
+			t.Errorf("Expected 20 people left in team 0, got %d", len(rows))
+		}
+	})
+
+	// Statistics survive a restart
+	want := db.Statistics()
+	db.Close()
+	db, err = NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	got := db.Statistics()
+	for attr, datoms := range want.AttributeDatoms {
+		if got.AttributeDatoms[attr] != datoms || got.AttributeCardinality[attr] != want.AttributeCardinality[attr] {
+			t.Errorf("Expected %s statistics %d/%d after reopening, got %d/%d", attr,
+				datoms, want.AttributeCardinality[attr], got.AttributeDatoms[attr], got.AttributeCardinality[attr])
+		}
+	}
+	if got.EntityCount != want.EntityCount {
+		t.Errorf("Expected %d entities after reopening, got %d", want.EntityCount, got.EntityCount)
+	}
+
+	// Imported datoms are counted
+	exportDir := filepath.Join(dir, "export")
+	if _, err := db.Export(exportDir, ExportOptions{Shards: 2}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	db.Close()
+	imported, err := NewDatabase(filepath.Join(dir, "imported"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer imported.Close()
+	if _, err := imported.Import(exportDir, ImportOptions{}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats := imported.Statistics(); stats.AttributeDatoms[":person/name"] != 501 || stats.AttributeDatoms[":person/team"] != 100 {
+		t.Errorf("Expected the imported counts, got %v", stats.AttributeDatoms)
+	}
+}
+
+func TestStatisticsRebuild(t *testing.T) {
+	dir, err := os.MkdirTemp("", "statistics-rebuild-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	name := datalog.NewKeyword(":person/name")
+	team := datalog.NewKeyword(":person/team")
+	tx := db.NewTransaction()
+	for i := 0; i < 100; i++ {
+		tx.Add(datalog.NewIdentity(fmt.Sprintf("person:%d", i)), name, fmt.Sprintf("Person %d", i))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// Datoms written before statistics were maintained, which the stored
+	// statistics don't cover
+	var datoms []datalog.Datom
+	for i := 0; i < 200; i++ {
+		datoms = append(datoms, datalog.Datom{
+			E: datalog.NewIdentity(fmt.Sprintf("person:%d", i)), A: team, V: fmt.Sprintf("Team %d", i%4), Tx: 1,
+		})
+	}
+	if err := db.Store().Assert(datoms); err != nil {
+		t.Fatalf("Assert failed: %v", err)
+	}
+	if err := db.Store().db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(statsCompleteKey)
+	}); err != nil {
+		t.Fatalf("Failed to remove the statistics marker: %v", err)
+	}
+	if got := db.Statistics().AttributeDatoms[":person/team"]; got != 0 {
+		t.Fatalf("Expected uncounted teams before reopening, got %d", got)
+	}
+	db.Close()
+
+	// Reopening rebuilds the statistics from the store
+	db, err = NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	stats := db.Statistics()
+	if stats.AttributeDatoms[":person/name"] != 100 || stats.AttributeDatoms[":person/team"] != 200 {
+		t.Errorf("Expected 100 names and 200 teams, got %v", stats.AttributeDatoms)
+	}
+	if card := stats.AttributeCardinality[":person/team"]; card != 4 {
+		t.Errorf("Expected 4 distinct teams, estimated %d", card)
+	}
+	if stats.EntityCount < 180 || stats.EntityCount > 220 {
+		t.Errorf("Expected about 200 entities (and the transaction), estimated %d", stats.EntityCount)
+	}
+	db.Close()
+
+	// The rebuilt statistics are stored and marked complete
+	db, err = NewDatabase(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	err = db.Store().db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(statsCompleteKey)
+		return err
+	})
+	if err != nil {
+		t.Errorf("Expected the statistics marker to be stored: %v", err)
+	}
+	if got := db.Statistics().AttributeDatoms[":person/team"]; got != 200 {
+		t.Errorf("Expected 200 teams after reopening, got %d", got)
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+func TestTimeZoneExtraction(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	// 2025-03-09 22:00 and 2025-03-10 10:00 in New York (EDT, UTC-4), both
+	// on March 10 in UTC
+	tx := db.NewTransaction()
+	for i, ts := range []time.Time{
+		time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC),
+	} {
+		bar := datalog.NewIdentity(string(rune('a' + i)))
+		tx.Add(bar, datalog.NewKeyword(":bar/time"), ts)
+		tx.Add(bar, datalog.NewKeyword(":bar/id"), int64(i))
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	dayQuery := func(zone string) string {
+		return `[:find ?id
+		  :where [?b :bar/time ?t]
+		         [?b :bar/id ?id]
+		         [(year ?t` + zone + `) ?y]
+		         [(month ?t` + zone + `) ?m]
+		         [(day ?t` + zone + `) ?d]
+		         [(= ?y 2025)]
+		         [(= ?m 3)]
+		         [(= ?d 10)]]`
+	}
+	tests := []struct {
+		name  string
+		query string
+		ids   []int64
+	}{
+		{"utc", dayQuery(` "UTC"`), []int64{0, 1}},
+		{"exchange local", dayQuery(` "America/New_York"`), []int64{1}},
+		{"query default", `{:query ` + dayQuery("") + ` :time-zone "America/New_York"}`, []int64{1}},
+		{"explicit over default", `{:query ` + dayQuery(` "UTC"`) + ` :time-zone "America/New_York"}`, []int64{0, 1}},
+	}
+
+	// With semantic rewriting the comparisons become a time range
+	for _, optsCase := range []struct {
+		name     string
+		rewrites bool
+	}{{"default", false}, {"semantic rewriting", true}} {
+		for _, tt := range tests {
+			t.Run(optsCase.name+"/"+tt.name, func(t *testing.T) {
+				q, err := parser.ParseQuery(tt.query)
+				if err != nil {
+					t.Fatalf("Failed to parse query: %v", err)
+				}
+				opts := DefaultPlannerOptions()
+				opts.EnableSemanticRewriting = optsCase.rewrites
+				result, err := db.NewExecutorWithOptions(opts).Execute(q)
+				if err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+				ids := make(map[int64]bool)
+				it := result.Iterator()
+				for it.Next() {
+					ids[it.Tuple()[0].(int64)] = true
+				}
+				it.Close()
+				if len(ids) != len(tt.ids) {
+					t.Fatalf("Expected bars %v, got %v", tt.ids, ids)
+				}
+				for _, id := range tt.ids {
+					if !ids[id] {
+						t.Errorf("Expected bar %d, got %v", id, ids)
+					}
+				}
+			})
+		}
+	}
+}
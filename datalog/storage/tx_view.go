@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/query"
+)
+
+// Executor returns an executor whose queries see the transaction's
+// uncommitted view: the committed database with the datoms staged by Add
+// and Retract applied, as Commit would apply them. This lets an importer
+// check what a batch has already added without committing it.
+//
+// The view is live: each query sees the datoms staged so far. Staged
+// datoms have no transaction until Commit assigns one, so a pattern that
+// matches the transaction position fails with ErrUncommittedTx.
+func (t *Transaction) Executor() *executor.Executor {
+	opts := DefaultPlannerOptions()
+	opts.Cache = t.db.planCache
+	base := t.db.Matcher().(*BadgerMatcher)
+	exec := executor.NewExecutorWithOptions(&txViewMatcher{base: base, tx: t}, opts)
+	exec.SetQueryTracker(t.db.queries)
+	exec.SetStatisticsProvider(t.db)
+	return exec
+}
+
+// ErrUncommittedTx is returned for a pattern matching the transaction
+// position against a transaction's uncommitted view
+var ErrUncommittedTx = errors.New("staged datoms have no transaction")
+
+// txViewMatcher matches patterns against a transaction's uncommitted view
+type txViewMatcher struct {
+	base *BadgerMatcher
+	tx   *Transaction
+
+	// In-memory matchers over the staged datoms, rebuilt when more are staged
+	mu                 sync.Mutex
+	datoms, retracts   int
+	assertions, hidden executor.PatternMatcher
+}
+
+// Fresh variables standing in for blanks, so that a match identifies the
+// datom a retraction hides
+var txViewSymbols = [3]query.Symbol{"?__tx-view-e", "?__tx-view-a", "?__tx-view-v"}
+
+// Match returns the committed matches of the pattern, less those retracted
+// in the transaction, plus the transaction's matching assertions
+func (m *txViewMatcher) Match(pattern *query.DataPattern, bindings executor.Relations) (executor.Relation, error) {
+	pattern, err := m.base.normalizePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(pattern.Elements) > 3 {
+		if _, ok := pattern.Elements[3].(query.Blank); !ok {
+			return nil, fmt.Errorf("%w: cannot match %s", ErrUncommittedTx, pattern)
+		}
+	}
+	assertions, hidden := m.staged()
+
+	// Match with blanks replaced by variables, so every datom position the
+	// pattern doesn't fix is a column. The copy keeps the pattern's hints.
+	full := pattern.Clone()
+	var eav []query.Symbol
+	for i := 0; i < 3 && i < len(full.Elements); i++ {
+		switch elem := full.Elements[i].(type) {
+		case query.Blank:
+			full.Elements[i] = query.Variable{Name: txViewSymbols[i]}
+			eav = append(eav, txViewSymbols[i])
+		case query.Variable:
+			eav = append(eav, elem.Name)
+		}
+	}
+
+	committed, err := m.base.Match(full, bindings)
+	if err != nil {
+		return nil, err
+	}
+	if hidden != nil {
+		// Retractions hide every transaction's copy of a datom. All of the
+		// pattern's retractions are needed, so :max-datoms doesn't apply.
+		eavOnly := full.Clone()
+		eavOnly.Elements = eavOnly.Elements[:3]
+		eavOnly.MaxDatoms = 0
+		retracted, err := hidden.Match(eavOnly, bindings)
+		if err != nil {
+			return nil, err
+		}
+		committed = committed.AntiJoin(retracted, eav)
+	}
+	relations := []executor.Relation{committed}
+	if assertions != nil {
+		added, err := assertions.Match(full, bindings)
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, added)
+	}
+
+	// Project to the pattern's own columns; creating the relation removes
+	// the duplicates of datoms both committed and asserted again
+	columns := pattern.ExtractColumns()
+	var tuples []executor.Tuple
+	for _, rel := range relations {
+		indices := make([]int, len(columns))
+		for i, col := range columns {
+			indices[i] = executor.ColumnIndex(rel, col)
+		}
+		err := rel.ForEach(func(tuple executor.Tuple) (bool, error) {
+			projected := make(executor.Tuple, len(indices))
+			for i, idx := range indices {
+				projected[i] = tuple[idx]
+			}
+			tuples = append(tuples, projected)
+			return false, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return executor.NewMaterializedRelationWithOptions(columns, tuples, m.base.options), nil
+}
+
+// staged returns matchers over the transaction's staged assertions and
+// retractions, nil if there are none
+func (m *txViewMatcher) staged() (assertions, hidden executor.PatternMatcher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tx.mu.Lock()
+	defer m.tx.mu.Unlock()
+
+	// Staged datoms are only appended, so their count tells whether the
+	// matchers are current
+	if len(m.tx.datoms) != m.datoms {
+		m.datoms, m.assertions = len(m.tx.datoms), stagedMatcher(m.tx.datoms)
+	}
+	if len(m.tx.retracts) != m.retracts {
+		m.retracts, m.hidden = len(m.tx.retracts), stagedMatcher(m.tx.retracts)
+	}
+	return m.assertions, m.hidden
+}
+
+// stagedMatcher returns an in-memory matcher over a copy of datoms, or nil
+func stagedMatcher(datoms []datalog.Datom) executor.PatternMatcher {
+	if len(datoms) == 0 {
+		return nil
+	}
+	return executor.NewIndexedMemoryMatcher(append([]datalog.Datom(nil), datoms...))
+}
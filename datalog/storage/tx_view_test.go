@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+	"github.com/wbrown/janus-datalog/datalog/executor"
+	"github.com/wbrown/janus-datalog/datalog/parser"
+)
+
+// txViewNames runs a name query through exec and returns the sorted names
+func txViewNames(t *testing.T, exec *executor.Executor, queryStr string) []string {
+	t.Helper()
+	q, err := parser.ParseQuery(queryStr)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	result, err := exec.Execute(q)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var names []string
+	it := result.Iterator()
+	defer it.Close()
+	for it.Next() {
+		names = append(names, it.Tuple()[0].(string))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTransactionExecutor(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tx-view-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	name := datalog.NewKeyword(":person/name")
+	alice := datalog.NewIdentity("alice")
+	bob := datalog.NewIdentity("bob")
+	carol := datalog.NewIdentity("carol")
+
+	tx := db.NewTransaction()
+	tx.Add(alice, name, "Alice")
+	tx.Add(bob, name, "Bob")
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	const names = `[:find ?name :where [?e :person/name ?name]]`
+	const named = `[:find ?name :where [_ :person/name ?name]]`
+
+	t.Run("ReadYourWrites", func(t *testing.T) {
+		tx := db.NewTransaction()
+		defer tx.Rollback()
+		exec := tx.Executor()
+
+		if got := txViewNames(t, exec, names); !equalNames(got, []string{"Alice", "Bob"}) {
+			t.Errorf("Expected the committed names, got %v", got)
+		}
+
+		tx.Add(carol, name, "Carol")
+		tx.Retract(bob, name, "Bob")
+		want := []string{"Alice", "Carol"}
+		if got := txViewNames(t, exec, names); !equalNames(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if got := txViewNames(t, exec, named); !equalNames(got, want) {
+			t.Errorf("Expected %v with a blank entity, got %v", want, got)
+		}
+
+		// The database itself doesn't see the staged datoms
+		if got := txViewNames(t, db.NewExecutor(), names); !equalNames(got, []string{"Alice", "Bob"}) {
+			t.Errorf("Expected the database unchanged before commit, got %v", got)
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		tx := db.NewTransaction()
+		exec := tx.Executor()
+		tx.Add(carol, name, "Carol")
+		tx.Rollback()
+
+		if got := txViewNames(t, exec, names); !equalNames(got, []string{"Alice", "Bob"}) {
+			t.Errorf("Expected a rolled back view to show only committed names, got %v", got)
+		}
+	})
+
+	t.Run("TransactionPosition", func(t *testing.T) {
+		tx := db.NewTransaction()
+		defer tx.Rollback()
+		tx.Add(carol, name, "Carol")
+
+		// Carol has no transaction to bind ?tx to until the commit
+		q, err := parser.ParseQuery(`[:find ?name ?tx :where [?e :person/name ?name ?tx]]`)
+		if err != nil {
+			t.Fatalf("Failed to parse query: %v", err)
+		}
+		if _, err := tx.Executor().Execute(q); !errors.Is(err, ErrUncommittedTx) {
+			t.Errorf("Expected ErrUncommittedTx, got %v", err)
+		}
+
+		// A blank transaction position doesn't bind anything
+		want := []string{"Alice", "Bob", "Carol"}
+		if got := txViewNames(t, tx.Executor(), `[:find ?name :where [?e :person/name ?name _]]`); !equalNames(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("CommittedRetraction", func(t *testing.T) {
+		tx := db.NewTransaction()
+		tx.Retract(bob, name, "Bob")
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		if got := txViewNames(t, db.NewExecutor(), names); !equalNames(got, []string{"Alice"}) {
+			t.Errorf("Expected the retracted name removed, got %v", got)
+		}
+	})
+}
@@ -129,7 +129,7 @@ func (d StorageDatom) String() string {
 // Bytes returns the serialized form of the storage datom
 // Format: E(20) + A(32) + Tx(20) + VSize(2) + VType(1) + V(variable)
 func (d StorageDatom) Bytes() []byte {
-	vBytes := datalog.ValueBytes(d.V)
+	vType, vBytes := storedValue(d.V)
 	size := 72 + 3 + len(vBytes) // E+A+Tx + size+type + value
 
 	buf := make([]byte, size)
@@ -141,7 +141,7 @@ func (d StorageDatom) Bytes() []byte {
 	binary.BigEndian.PutUint16(buf[72:74], uint16(len(vBytes)))
 
 	// Value type (1 byte)
-	buf[74] = byte(datalog.Type(d.V))
+	buf[74] = vType
 
 	// Value data
 	copy(buf[75:], vBytes)
@@ -200,7 +200,7 @@ func StorageDatomFromBytes(data []byte) (*StorageDatom, error) {
 
 	// Decode value based on type
 	var err error
-	d.V, err = datalog.ValueFromBytes(datalog.ValueType(vType), vData)
+	d.V, err = decodeStoredValue(vType, vData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode value: %w", err)
 	}
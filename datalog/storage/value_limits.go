@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// ErrValueTooLarge is returned when a datom's value exceeds the database's
+// value size limit
+var ErrValueTooLarge = errors.New("value too large")
+
+// maxInlineValueSize is the largest value stored in index keys. It keeps
+// keys under Badger's 65000 byte key limit with either key encoding.
+const maxInlineValueSize = 64000
+
+// ValueLimits bounds the size of datom values and sets which values are
+// stored apart from the indexes. Sizes are in bytes of encoded value, which
+// for strings, byte slices and keywords is their length.
+type ValueLimits struct {
+	// MaxSize is the largest value a transaction may assert; larger values
+	// fail with ErrValueTooLarge. 0 means the largest value the indexes can
+	// hold inline. A MaxSize above that requires OffloadSize.
+	MaxSize int
+
+	// OffloadSize, if set, is the size above which string and byte values
+	// are offloaded: written once to a separate blob keyspace, with the index
+	// keys holding a fixed-size reference in their place. This keeps one
+	// oversized value from bloating the keys every scan of its attribute
+	// reads. Offloaded values are found by equality, but not by a range
+	// scan over the value index. Choose it when creating a database: values
+	// are offloaded as they are written, and lookups assume the current
+	// setting.
+	OffloadSize int
+}
+
+// DefaultValueLimits returns the limits of a new database: values up to the
+// inline size, none offloaded
+func DefaultValueLimits() ValueLimits {
+	return ValueLimits{MaxSize: maxInlineValueSize}
+}
+
+// SetValueLimits sets the database's value size limits. Set them before
+// using the database.
+func (d *Database) SetValueLimits(limits ValueLimits) error {
+	if limits.MaxSize == 0 {
+		limits.MaxSize = maxInlineValueSize
+	}
+	if limits.MaxSize < 0 || limits.OffloadSize < 0 {
+		return fmt.Errorf("value limits must not be negative")
+	}
+	if limits.OffloadSize > maxInlineValueSize {
+		return fmt.Errorf("offload size %d exceeds the %d byte inline limit", limits.OffloadSize, maxInlineValueSize)
+	}
+	if limits.MaxSize > maxInlineValueSize && limits.OffloadSize == 0 {
+		return fmt.Errorf("max value size %d exceeds the %d byte inline limit without an offload size", limits.MaxSize, maxInlineValueSize)
+	}
+	d.limits = limits
+	d.store.offloadSize = limits.OffloadSize
+	return nil
+}
+
+// ValueLimits returns the database's value size limits
+func (d *Database) ValueLimits() ValueLimits {
+	return d.limits
+}
+
+// checkValueSize returns ErrValueTooLarge if v exceeds the database's limit
+func (d *Database) checkValueSize(a datalog.Keyword, v interface{}) error {
+	if size := valueSize(v); size > d.limits.MaxSize {
+		return fmt.Errorf("%w: %s value is %d bytes, limit is %d", ErrValueTooLarge, a, size, d.limits.MaxSize)
+	}
+	return nil
+}
+
+// valueSize returns the encoded size of a variable-size value, or 0 for
+// fixed-size values
+func valueSize(v interface{}) int {
+	switch val := v.(type) {
+	case string:
+		return len(val)
+	case []byte:
+		return len(val)
+	case datalog.Keyword:
+		return len(val.String())
+	}
+	return 0
+}
+
+// Offloaded values are stored under blobPrefix and their content hash. The
+// keyspace sits beside the commit records, apart from the index prefixes.
+const blobPrefix = 0xF1
+
+// typeBlobRef marks a blob reference in an index key or stored datom, in
+// place of the value's type byte. It sorts after every value type.
+const typeBlobRef byte = 0xFF
+
+// blobRef stands in for an offloaded value in index keys
+type blobRef struct {
+	vType datalog.ValueType
+	hash  [20]byte
+}
+
+func blobKey(hash [20]byte) []byte {
+	return append([]byte{blobPrefix}, hash[:]...)
+}
+
+// offload returns the blob reference and contents for a value the store
+// offloads, or false if it is stored inline
+func (s *BadgerStore) offload(v datalog.Value) (blobRef, []byte, bool) {
+	if s.offloadSize == 0 {
+		return blobRef{}, nil, false
+	}
+	var data []byte
+	switch val := v.(type) {
+	case string:
+		data = []byte(val)
+	case []byte:
+		data = val
+	default:
+		return blobRef{}, nil, false
+	}
+	if len(data) <= s.offloadSize {
+		return blobRef{}, nil, false
+	}
+	return blobRef{vType: datalog.Type(v), hash: sha1.Sum(data)}, data, true
+}
+
+// indexedValue returns v as index keys hold it: its blob reference if the
+// store offloads it
+func (s *BadgerStore) indexedValue(v datalog.Value) datalog.Value {
+	if ref, _, ok := s.offload(v); ok {
+		return ref
+	}
+	return v
+}
+
+// storedValue returns a value's type byte and data as written to storage
+func storedValue(v datalog.Value) (byte, []byte) {
+	if ref, ok := v.(blobRef); ok {
+		return typeBlobRef, append([]byte{byte(ref.vType)}, ref.hash[:]...)
+	}
+	return byte(datalog.Type(v)), datalog.ValueBytes(v)
+}
+
+// decodeStoredValue decodes a value written by storedValue, returning a
+// blobRef for an offloaded value
+func decodeStoredValue(vType byte, data []byte) (datalog.Value, error) {
+	if vType != typeBlobRef {
+		return datalog.ValueFromBytes(datalog.ValueType(vType), data)
+	}
+	if len(data) != 21 {
+		return nil, fmt.Errorf("blob reference must be 21 bytes, got %d", len(data))
+	}
+	ref := blobRef{vType: datalog.ValueType(data[0])}
+	copy(ref.hash[:], data[1:])
+	return ref, nil
+}
+
+// resolveBlob replaces an offloaded value in d with the value itself
+func resolveBlob(txn *badger.Txn, d *datalog.Datom) error {
+	ref, ok := d.V.(blobRef)
+	if !ok {
+		return nil
+	}
+	item, err := txn.Get(blobKey(ref.hash))
+	if err != nil {
+		return fmt.Errorf("failed to read offloaded value %x: %w", ref.hash[:8], err)
+	}
+	data, err := item.ValueCopy(nil)
+	if err != nil {
+		return fmt.Errorf("failed to read offloaded value %x: %w", ref.hash[:8], err)
+	}
+	d.V, err = datalog.ValueFromBytes(ref.vType, data)
+	return err
+}
+
+// datomFromKey decodes an index key read in txn, resolving an offloaded value
+func datomFromKey(txn *badger.Txn, index IndexType, key []byte, encoder KeyEncoder) (*datalog.Datom, error) {
+	datom, err := DatomFromKey(index, key, encoder)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveBlob(txn, datom); err != nil {
+		return nil, err
+	}
+	return datom, nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func newValueLimitsDB(t *testing.T) *Database {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "value-limits-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := NewDatabase(dir)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestValueLimits(t *testing.T) {
+	body := datalog.NewKeyword(":doc/body")
+	doc := datalog.NewIdentity("doc")
+
+	t.Run("DefaultLimit", func(t *testing.T) {
+		db := newValueLimitsDB(t)
+		tx := db.NewTransaction()
+		defer tx.Rollback()
+
+		err := tx.Add(doc, body, strings.Repeat("x", maxInlineValueSize+1))
+		if !errors.Is(err, ErrValueTooLarge) {
+			t.Fatalf("Expected ErrValueTooLarge, got %v", err)
+		}
+		if err := tx.Add(doc, body, strings.Repeat("x", maxInlineValueSize)); err != nil {
+			t.Fatalf("Expected a value at the limit to be accepted, got %v", err)
+		}
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit a value at the limit: %v", err)
+		}
+	})
+
+	t.Run("MaxSize", func(t *testing.T) {
+		db := newValueLimitsDB(t)
+		if err := db.SetValueLimits(ValueLimits{MaxSize: 10}); err != nil {
+			t.Fatal(err)
+		}
+		tx := db.NewTransaction()
+		defer tx.Rollback()
+
+		if err := tx.Add(doc, body, []byte("0123456789a")); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("Expected ErrValueTooLarge for 11 bytes, got %v", err)
+		}
+		if err := tx.Add(doc, body, "0123456789"); err != nil {
+			t.Errorf("Expected 10 bytes to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("InvalidLimits", func(t *testing.T) {
+		db := newValueLimitsDB(t)
+		if err := db.SetValueLimits(ValueLimits{MaxSize: 1 << 20}); err == nil {
+			t.Error("Expected a max size above the inline limit to require offloading")
+		}
+		if err := db.SetValueLimits(ValueLimits{OffloadSize: maxInlineValueSize + 1}); err == nil {
+			t.Error("Expected an offload size above the inline limit to be rejected")
+		}
+	})
+
+	t.Run("Offload", func(t *testing.T) {
+		db := newValueLimitsDB(t)
+		if err := db.SetValueLimits(ValueLimits{MaxSize: 1 << 20, OffloadSize: 100}); err != nil {
+			t.Fatal(err)
+		}
+		large := strings.Repeat("large value ", 20000)
+		note := datalog.NewIdentity("note")
+
+		tx := db.NewTransaction()
+		tx.Add(doc, body, large)
+		tx.Add(note, body, "small")
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		// The index keys hold a reference, not the value
+		entries, err := db.DumpIndex(AVET, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if len(entry.Key) > 200 {
+				t.Errorf("Expected short index keys, got %d bytes", len(entry.Key))
+			}
+			if entry.Datom != nil && entry.Datom.E.Hash() == doc.Hash() && entry.Datom.V != large {
+				t.Error("Expected the dumped datom to hold the offloaded value")
+			}
+		}
+
+		results, err := db.ExecuteQuery(`[:find ?e ?body :where [?e :doc/body ?body]]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := map[string]bool{}
+		for _, row := range results {
+			values[row[1].(string)] = true
+		}
+		if len(values) != 2 || !values[large] || !values["small"] {
+			t.Errorf("Expected the large and small values, got %d values", len(values))
+		}
+
+		// Equality lookups find the offloaded value
+		results, err = db.ExecuteQueryWithInputs(`[:find ?e :in $ ?body :where [?e :doc/body ?body]]`, large)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0][0].(*datalog.Identity).Hash() != doc.Hash() {
+			t.Errorf("Expected the lookup to find the document, got %v", results)
+		}
+
+		tx = db.NewTransaction()
+		tx.Retract(doc, body, large)
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		results, err = db.ExecuteQuery(`[:find ?body :where [?e :doc/body ?body]]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0][0] != "small" {
+			t.Errorf("Expected only the small value after retraction, got %d results", len(results))
+		}
+	})
+}
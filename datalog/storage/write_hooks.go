@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+// ErrWriteHookDepth is returned by Commit when write hooks keep deriving
+// datoms that trigger further hooks, more than MaxWriteHookDepth deep
+var ErrWriteHookDepth = errors.New("write hooks nested too deeply")
+
+// MaxWriteHookDepth bounds how many times a datom derived by a write hook
+// may itself trigger hooks that derive more datoms
+const MaxWriteHookDepth = 16
+
+// WriteHook derives data from an asserted datom of the attribute it was
+// registered for (see AddWriteHook). It may add datoms with w.Add, read
+// other attributes of the entity with w.Value, and rewrite the datom's
+// value in place, e.g. to normalize a string. An error aborts the commit.
+type WriteHook func(w *HookWriter, d *datalog.Datom) error
+
+// AddWriteHook registers a hook to run for every datom of attr asserted by
+// a transaction. Hooks run in Commit, once all of the transaction's datoms
+// are staged, so they see the transaction as a whole, and the datoms they
+// add are committed atomically with it. Several hooks may be registered for
+// an attribute; they run in the order they were added.
+//
+// Hooks run while the database's commit lock is held, so they should be
+// quick. They are not run by Import, nor seen by Transaction.Executor.
+func (d *Database) AddWriteHook(attr datalog.Keyword, hook WriteHook) error {
+	attr, err := d.normalizeKeyword(attr)
+	if err != nil {
+		return err
+	}
+
+	d.hooksMu.Lock()
+	defer d.hooksMu.Unlock()
+	// Copy on write, so commits read the hooks without holding the lock
+	hooks := make(map[datalog.Keyword][]WriteHook, len(d.writeHooks)+1)
+	for a, h := range d.writeHooks {
+		hooks[a] = h
+	}
+	hooks[attr] = append(append([]WriteHook(nil), hooks[attr]...), hook)
+	d.writeHooks = hooks
+	return nil
+}
+
+// HookWriter is a write hook's access to the transaction being committed
+type HookWriter struct {
+	tx     *Transaction
+	staged map[string]bool // Keys of the transaction's assertions
+	added  []datalog.Datom
+}
+
+// Add asserts a datom in the transaction. Datoms the transaction already
+// asserts are skipped, so hooks deriving the same datom from different
+// attributes add it once. Added datoms run their attribute's hooks in turn.
+func (w *HookWriter) Add(e datalog.Identity, a datalog.Keyword, v interface{}) error {
+	a, err := w.tx.db.normalizeKeyword(a)
+	if err != nil {
+		return err
+	}
+	if err := w.tx.db.checkValueSize(a, v); err != nil {
+		return err
+	}
+
+	datom := datalog.Datom{E: e, A: a, V: v}
+	key := hookDatomKey(&datom)
+	if w.staged[key] {
+		return nil
+	}
+	w.staged[key] = true
+	w.added = append(w.added, datom)
+	return nil
+}
+
+// Value returns the entity's value of an attribute as the transaction
+// leaves it: its latest assertion in the transaction, including datoms
+// added by hooks, or else the committed value unless the transaction
+// retracts it.
+func (w *HookWriter) Value(e datalog.Identity, a datalog.Keyword) (interface{}, bool, error) {
+	a, err := w.tx.db.normalizeKeyword(a)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := len(w.added) - 1; i >= 0; i-- {
+		if d := &w.added[i]; d.A == a && d.E.Equal(e) {
+			return d.V, true, nil
+		}
+	}
+	for i := len(w.tx.datoms) - 1; i >= 0; i-- {
+		if d := &w.tx.datoms[i]; d.A == a && d.E.Equal(e) {
+			return d.V, true, nil
+		}
+	}
+
+	values, err := w.tx.db.ResolveValues(a, []datalog.Identity{e})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", a, err)
+	}
+	v, ok := values[e]
+	if !ok {
+		return nil, false, nil
+	}
+	for i := range w.tx.retracts {
+		if r := &w.tx.retracts[i]; r.A == a && r.E.Equal(e) && datalog.ValuesEqual(r.V, v) {
+			return nil, false, nil
+		}
+	}
+	return v, true, nil
+}
+
+// runWriteHooks runs the registered write hooks over the transaction's
+// assertions, applying their rewrites and appending the datoms they
+// derive. On error the transaction's datoms are left as they were. The
+// caller holds t.mu.
+func (t *Transaction) runWriteHooks() error {
+	t.db.hooksMu.RLock()
+	hooks := t.db.writeHooks
+	t.db.hooksMu.RUnlock()
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	staged := len(t.datoms)
+	w := &HookWriter{tx: t, staged: make(map[string]bool, len(t.datoms))}
+	for i := range t.datoms {
+		w.staged[hookDatomKey(&t.datoms[i])] = true
+	}
+
+	// Rewritten datoms, to restore if a hook fails
+	type rewrite struct {
+		i     int
+		datom datalog.Datom
+	}
+	var rewrites []rewrite
+	fail := func(err error) error {
+		t.datoms = t.datoms[:staged]
+		for j := len(rewrites) - 1; j >= 0; j-- {
+			if r := rewrites[j]; r.i < staged {
+				t.datoms[r.i] = r.datom
+			}
+		}
+		return err
+	}
+
+	// Derived datoms are appended to t.datoms and visited in turn
+	depths := make([]int, len(t.datoms))
+	for i := 0; i < len(t.datoms); i++ {
+		attr := t.datoms[i].A
+		attrHooks := hooks[attr]
+		if len(attrHooks) == 0 {
+			continue
+		}
+		if depths[i] > MaxWriteHookDepth {
+			return fail(fmt.Errorf("%w: %s derived at depth %d", ErrWriteHookDepth, attr, depths[i]))
+		}
+
+		for _, hook := range attrHooks {
+			datom := t.datoms[i]
+			if err := hook(w, &datom); err != nil {
+				return fail(fmt.Errorf("write hook for %s failed: %w", attr, err))
+			}
+			if datom.A != attr || !datom.E.Equal(t.datoms[i].E) {
+				return fail(fmt.Errorf("write hook for %s changed the datom's entity or attribute", attr))
+			}
+			if datalog.ValuesEqual(datom.V, t.datoms[i].V) {
+				continue
+			}
+			if err := t.db.checkValueSize(attr, datom.V); err != nil {
+				return fail(err)
+			}
+			delete(w.staged, hookDatomKey(&t.datoms[i]))
+			w.staged[hookDatomKey(&datom)] = true
+			rewrites = append(rewrites, rewrite{i, t.datoms[i]})
+			t.datoms[i] = datom
+		}
+
+		for _, added := range w.added {
+			t.datoms = append(t.datoms, added)
+			depths = append(depths, depths[i]+1)
+		}
+		w.added = w.added[:0]
+	}
+	return nil
+}
+
+// hookDatomKey identifies a datom by its entity, attribute and value
+func hookDatomKey(d *datalog.Datom) string {
+	sd := ToStorageDatom(datalog.Datom{E: d.E, A: d.A})
+	return string(sd.E[:]) + string(sd.A[:]) + string([]byte{byte(datalog.Type(d.V))}) + string(datalog.ValueBytes(d.V))
+}
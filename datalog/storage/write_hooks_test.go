@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wbrown/janus-datalog/datalog"
+)
+
+func TestWriteHooks(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	high := datalog.NewKeyword(":bar/high")
+	low := datalog.NewKeyword(":bar/low")
+	rng := datalog.NewKeyword(":bar/range")
+	symbol := datalog.NewKeyword(":bar/symbol")
+
+	// :bar/range is high - low, whichever of them is asserted
+	computeRange := func(w *HookWriter, d *datalog.Datom) error {
+		h, okH, err := w.Value(d.E, high)
+		if err != nil {
+			return err
+		}
+		l, okL, err := w.Value(d.E, low)
+		if err != nil || !okH || !okL {
+			return err
+		}
+		return w.Add(d.E, rng, h.(float64)-l.(float64))
+	}
+	for _, attr := range []datalog.Keyword{high, low} {
+		if err := db.AddWriteHook(attr, computeRange); err != nil {
+			t.Fatalf("AddWriteHook failed: %v", err)
+		}
+	}
+	db.AddWriteHook(symbol, func(w *HookWriter, d *datalog.Datom) error {
+		s, ok := d.V.(string)
+		if !ok {
+			return errors.New("symbol must be a string")
+		}
+		d.V = strings.ToUpper(strings.TrimSpace(s))
+		return nil
+	})
+
+	ranges := func() []float64 {
+		t.Helper()
+		result, err := db.ExecuteQuery(`[:find ?r :where [?b :bar/range ?r]]`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var values []float64
+		for _, row := range result {
+			values = append(values, row[0].(float64))
+		}
+		return values
+	}
+
+	bar := datalog.NewIdentity("bar:1")
+	tx := db.NewTransaction()
+	tx.Add(bar, high, 12.5)
+	tx.Add(bar, low, 10.0)
+	tx.Add(bar, symbol, " aapl ")
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	// Both hooks derive the same range, which is added once
+	if r := ranges(); len(r) != 1 || r[0] != 2.5 {
+		t.Errorf("Expected range 2.5, got %v", r)
+	}
+	result, err := db.ExecuteQuery(`[:find ?s :where [?b :bar/symbol ?s]]`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result) != 1 || result[0][0] != "AAPL" {
+		t.Errorf("Expected the symbol normalized to AAPL, got %v", result)
+	}
+
+	// A new high uses the committed low
+	tx = db.NewTransaction()
+	tx.Add(bar, high, 13.0)
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if r := ranges(); len(r) != 2 || (r[0] != 3.0 && r[1] != 3.0) {
+		t.Errorf("Expected a range of 3 from the committed low, got %v", r)
+	}
+
+	// A failing hook aborts the commit and leaves the transaction unchanged
+	tx = db.NewTransaction()
+	tx.Add(datalog.NewIdentity("bar:2"), symbol, "msft")
+	tx.Add(datalog.NewIdentity("bar:2"), high, 5.0)
+	tx.Add(datalog.NewIdentity("bar:2"), low, 4.0)
+	tx.Add(datalog.NewIdentity("bar:2"), symbol, int64(7))
+	if _, err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "symbol must be a string") {
+		t.Fatalf("Expected the symbol hook to fail, got %v", err)
+	}
+	if len(tx.datoms) != 4 || tx.datoms[0].V != "msft" {
+		t.Errorf("Expected the transaction's 4 datoms unchanged, got %v", tx.datoms)
+	}
+
+	// Hooks that keep deriving datoms are stopped
+	counter := datalog.NewKeyword(":test/counter")
+	db.AddWriteHook(counter, func(w *HookWriter, d *datalog.Datom) error {
+		return w.Add(d.E, counter, d.V.(int64)+1)
+	})
+	tx = db.NewTransaction()
+	tx.Add(datalog.NewIdentity("counter"), counter, int64(0))
+	if _, err := tx.Commit(); !errors.Is(err, ErrWriteHookDepth) {
+		t.Errorf("Expected ErrWriteHookDepth, got %v", err)
+	}
+}
@@ -128,7 +128,7 @@ func runQuery(name string, queryStr string, datoms []datalog.Datom) {
 	}
 
 	// Display results
-	columns := result.Columns()
+	columns := result.Symbols()
 	fmt.Printf("Results (%d):\n", result.Size())
 
 	iter := result.Iterator()
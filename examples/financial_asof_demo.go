@@ -149,11 +149,11 @@ func main() {
 	asOfDate := time.Date(2024, 1, 3, 23, 59, 59, 999999999, time.UTC)
 	fmt.Printf("\nPrices as of %s:\n", asOfDate.Format("2006-01-02"))
 	
-	// Debug: Show what transaction IDs we're looking for
-	fmt.Printf("Looking for transactions <= %d\n", asOfDate.UnixNano())
-	
-	// Use AsOf matcher
-	asOfMatcher := db.AsOf(uint64(asOfDate.UnixNano()))
+	// Use AsOf matcher for the latest transaction at or before asOfDate
+	asOfMatcher, err := db.AsOfTime(asOfDate)
+	if err != nil {
+		log.Fatal(err)
+	}
 	asOfExec := executor.NewExecutor(asOfMatcher)
 	
 	// First check if we can find the security